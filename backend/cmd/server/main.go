@@ -5,8 +5,13 @@ import (
 	"log"
 	"net/http"
 	"bkt/internal/api"
+	"bkt/internal/auth"
 	"bkt/internal/config"
 	"bkt/internal/database"
+	"bkt/internal/middleware"
+	"bkt/internal/security"
+	"bkt/internal/services"
+	"bkt/internal/validation"
 	"os"
 	"os/signal"
 	"syscall"
@@ -18,6 +23,21 @@ func main() {
 	cfg := config.Load()
 	log.Println("Configuration loaded")
 
+	// The content-type allowlist must never include types a browser can execute
+	if err := validation.ValidateTrustedContentTypes(cfg.Storage.TrustedContentTypes); err != nil {
+		log.Fatalf("Invalid TRUSTED_CONTENT_TYPES: %v", err)
+	}
+	if err := validation.ValidateExtensionContentTypes(cfg.Storage.ExtensionContentTypes); err != nil {
+		log.Fatalf("Invalid EXTENSION_CONTENT_TYPES: %v", err)
+	}
+
+	// A broken default bucket policy would otherwise only surface when the next bucket is created
+	if cfg.Auth.DefaultBucketPolicy != "" {
+		if _, err := security.RenderBucketPolicyTemplate(cfg.Auth.DefaultBucketPolicy, "example-bucket"); err != nil {
+			log.Fatalf("Invalid DEFAULT_BUCKET_POLICY: %v", err)
+		}
+	}
+
 	// Wait for database to be ready
 	log.Println("Waiting for database to be ready...")
 	time.Sleep(3 * time.Second)
@@ -32,6 +52,69 @@ func main() {
 		log.Fatalf("Failed to initialize default admin: %v", err)
 	}
 
+	// Restore maintenance mode (persisted across restarts) and keep instances in sync
+	if err := middleware.LoadMaintenanceMode(cfg.Server.MaintenanceMode); err != nil {
+		log.Fatalf("Failed to load maintenance mode: %v", err)
+	}
+	middleware.StartMaintenanceModeSync(10 * time.Second)
+
+	// Load revoked tokens (logged-out sessions), then keep instances in sync and purge expired entries
+	if err := auth.LoadRevokedTokens(); err != nil {
+		log.Fatalf("Failed to load revoked tokens: %v", err)
+	}
+	auth.StartRevokedTokenSync(time.Minute)
+
+	// Periodically remove expired idempotency keys
+	middleware.StartIdempotencyCleanup(time.Hour)
+
+	// Cache users' and buckets' policies for permission checks
+	services.SetPolicyCacheTTL(cfg.Auth.PolicyCacheTTLDuration)
+
+	// Periodically drop expired S3 configs (and their decrypted credentials) from the cache
+	api.StartS3ConfigCacheEviction(time.Minute)
+
+	// Deactivate rotated access keys once their grace period ends
+	api.StartAccessKeyExpiry(time.Minute)
+
+	// Delete objects whose per-object TTL (X-Expires-After) has passed
+	api.StartObjectExpiry(cfg, time.Minute)
+
+	// Purge trashed buckets once their deletion grace period ends
+	api.StartBucketPurge(cfg, 10*time.Minute)
+
+	// Abort S3 multipart uploads left unfinished for a week
+	api.StartMultipartCleanup(cfg, time.Hour)
+
+	// Discard resumable (tus) uploads that received no data for a day, with their staging files
+	api.StartTusUploadCleanup(time.Hour)
+
+	// Persist aggregated bandwidth usage every minute
+	middleware.StartUsageFlush(time.Minute)
+
+	// Persist batched download tracking (last accessed, recent objects) every minute
+	api.StartObjectAccessFlush(time.Minute)
+
+	// Deliver buffered per-bucket access logs as objects into their target buckets
+	api.StartAccessLogDelivery(cfg)
+
+	// Optional scheduled storage/DB reconciliation
+	if cfg.Storage.ReconcileInterval != "" {
+		interval, err := time.ParseDuration(cfg.Storage.ReconcileInterval)
+		if err != nil || interval <= 0 {
+			log.Fatalf("Invalid RECONCILE_INTERVAL %q: must be a positive duration such as 24h", cfg.Storage.ReconcileInterval)
+		}
+		api.StartReconciliation(cfg, interval, cfg.Storage.ReconcileAutoFix)
+	}
+
+	// Optional integrity scrubbing (re-verifies stored SHA256 hashes)
+	if cfg.Storage.ScrubInterval != "" {
+		interval, err := time.ParseDuration(cfg.Storage.ScrubInterval)
+		if err != nil || interval <= 0 {
+			log.Fatalf("Invalid SCRUB_INTERVAL %q: must be a positive duration such as 168h", cfg.Storage.ScrubInterval)
+		}
+		api.StartIntegrityScrub(cfg, interval, cfg.Storage.ScrubBandwidth)
+	}
+
 	// Create storage directory if it doesn't exist
 	if err := os.MkdirAll(cfg.Storage.RootPath, 0755); err != nil {
 		log.Fatalf("Failed to create storage directory: %v", err)
@@ -45,11 +128,37 @@ func main() {
 		log.Fatal("TLS must be enabled. Set TLS_ENABLED=true")
 	}
 
+	// Load certificate (fails fast if the cert/key are missing or mismatched)
+	certReloader, err := security.NewCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificate: %v", err)
+	}
+
+	// Build TLS settings (min version, cipher suites, client certs)
+	tlsConfig, err := security.BuildTLSConfig(cfg.TLS, certReloader)
+	if err != nil {
+		log.Fatalf("Invalid TLS configuration: %v", err)
+	}
+
+	// Reload the certificate on SIGHUP for zero-downtime renewal
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := certReloader.Reload(); err != nil {
+				log.Printf("TLS certificate reload failed, keeping current certificate: %v", err)
+				continue
+			}
+			log.Println("TLS certificate reloaded")
+		}
+	}()
+
 	// Create HTTPS server
 	httpsAddr := cfg.Server.Host + ":9443"
 	httpsServer := &http.Server{
-		Addr:    httpsAddr,
-		Handler: router,
+		Addr:      httpsAddr,
+		Handler:   router,
+		TLSConfig: tlsConfig,
 	}
 
 	// Start HTTPS server
@@ -57,8 +166,10 @@ func main() {
 		log.Printf("Starting HTTPS server on %s", httpsAddr)
 		log.Printf("TLS Certificate: %s", cfg.TLS.CertFile)
 		log.Printf("TLS Key: %s", cfg.TLS.KeyFile)
+		log.Printf("TLS Min Version: %s, Client Auth: %s", cfg.TLS.MinVersion, cfg.TLS.ClientAuth)
 
-		if err := httpsServer.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile); err != nil && err != http.ErrServerClosed {
+		// Certificates are served via TLSConfig.GetCertificate
+		if err := httpsServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start HTTPS server: %v", err)
 		}
 	}()
@@ -77,5 +188,10 @@ func main() {
 		log.Printf("HTTPS server forced to shutdown: %v", err)
 	}
 
+	// Don't lose usage recorded since the last flush
+	middleware.FlushUsage()
+	api.FlushAccessLogs()
+	api.FlushObjectAccess()
+
 	log.Println("Server exited")
 }
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Limits for the recent requests returned by GetAccessKeyActivity
+const (
+	defaultAccessKeyActivityLimit = 50
+	maxAccessKeyActivityLimit     = 500
+)
+
+// accessKeySourceIP summarizes the audited requests made with a key from one address
+type accessKeySourceIP struct {
+	IPAddress string    `json:"ip_address"`
+	Requests  int64     `json:"requests"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// CancelAccessKeyRequestsRequest is the optional body for CancelAccessKeyRequests
+type CancelAccessKeyRequestsRequest struct {
+	Deactivate bool `json:"deactivate"` // Also revoke the key so no new requests are accepted
+}
+
+// GetAccessKeyActivity reports recent usage of an access key for incident response (admin only):
+// last-used time, recent audited S3 requests, the source IPs they came from and the requests
+// still in flight on this instance
+func (h *AccessKeyHandler) GetAccessKeyActivity(c *gin.Context) {
+	accessKeyUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid access key ID",
+		})
+		return
+	}
+
+	limit := defaultAccessKeyActivityLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxAccessKeyActivityLimit {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid limit",
+				Message: "limit must be between 1 and " + strconv.Itoa(maxAccessKeyActivityLimit),
+			})
+			return
+		}
+	}
+
+	var accessKey models.AccessKey
+	if err := database.DB.Preload("User").Where("id = ?", accessKeyUUID).First(&accessKey).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Access key not found",
+		})
+		return
+	}
+
+	recent := make([]models.AuditLog, 0)
+	if err := database.DB.Where("access_key_id = ?", accessKey.ID).
+		Order("created_at DESC").Limit(limit).Find(&recent).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to load access key activity",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	sourceIPs := make([]accessKeySourceIP, 0)
+	if err := database.DB.Model(&models.AuditLog{}).
+		Select("ip_address, COUNT(*) AS requests, MAX(created_at) AS last_seen").
+		Where("access_key_id = ?", accessKey.ID).
+		Group("ip_address").Order("last_seen DESC").Limit(maxAccessKeyActivityLimit).
+		Scan(&sourceIPs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to load access key activity",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":              accessKey.ID,
+		"access_key":      accessKey.AccessKey,
+		"user_id":         accessKey.UserID,
+		"username":        accessKey.User.Username,
+		"is_active":       accessKey.IsActive,
+		"expires_at":      accessKey.ExpiresAt,
+		"last_used_at":    accessKey.LastUsedAt,
+		"source_ips":      sourceIPs,
+		"recent_requests": recent,
+		"active_requests": middleware.ActiveAccessKeyRequests(accessKey.ID),
+	})
+}
+
+// CancelAccessKeyRequests aborts the access key's in-flight S3 requests on this instance (admin only).
+// With {"deactivate": true} the key is revoked first, so the cancelled clients can't simply retry
+func (h *AccessKeyHandler) CancelAccessKeyRequests(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+
+	accessKeyUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid access key ID",
+		})
+		return
+	}
+
+	var req CancelAccessKeyRequestsRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	var accessKey models.AccessKey
+	if err := database.DB.Where("id = ?", accessKeyUUID).First(&accessKey).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Access key not found",
+		})
+		return
+	}
+
+	if req.Deactivate && accessKey.IsActive {
+		if err := database.DB.Model(&accessKey).Update("is_active", false).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to revoke access key",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	cancelled := middleware.CancelAccessKeyRequests(accessKey.ID)
+
+	h.auditService.LogSuccess(c, userID.(uuid.UUID), username.(string),
+		"CancelAccessKeyRequests", "AccessKey", accessKey.ID.String(), accessKey.AccessKey,
+		map[string]interface{}{
+			"cancelled":   cancelled,
+			"deactivated": req.Deactivate,
+		})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "In-flight requests cancelled",
+		"cancelled": cancelled,
+		"is_active": accessKey.IsActive && !req.Deactivate,
+	})
+}
@@ -1,25 +1,33 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"bkt/internal/config"
 	"bkt/internal/database"
+	"bkt/internal/logger"
 	"bkt/internal/models"
 	"bkt/internal/security"
+	"bkt/internal/services"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AccessKeyHandler struct {
-	config *config.Config
+	config       *config.Config
+	auditService *services.AuditService
 }
 
 func NewAccessKeyHandler(cfg *config.Config) *AccessKeyHandler {
-	return &AccessKeyHandler{config: cfg}
+	return &AccessKeyHandler{
+		config:       cfg,
+		auditService: services.NewAuditService(),
+	}
 }
 
 // GenerateAccessKey generates a new access key and secret key pair for the authenticated user
@@ -74,8 +82,13 @@ func (h *AccessKeyHandler) GenerateAccessKey(c *gin.Context) {
 
 	// Use transaction to atomically check limit and create key (prevents TOCTOU race)
 	var newAccessKey models.AccessKey
+	var owner models.User
 	err = database.DB.Transaction(func(tx *gorm.DB) error {
-		// Count active access keys with row lock to prevent concurrent modifications
+		// Lock the user row so concurrent requests can't both pass the limit check
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&owner, "id = ?", userID).Error; err != nil {
+			return err
+		}
+
 		var count int64
 		if err := tx.Model(&models.AccessKey{}).
 			Where("user_id = ? AND is_active = ?", userID, true).
@@ -83,8 +96,8 @@ func (h *AccessKeyHandler) GenerateAccessKey(c *gin.Context) {
 			return err
 		}
 
-		// Check limit (5 per user for security)
-		if count >= 5 {
+		// Per-user limit (DEFAULT_USER_MAX_ACCESS_KEYS unless an admin changed it)
+		if count >= int64(owner.MaxAccessKeys) {
 			return fmt.Errorf("maximum access keys reached")
 		}
 
@@ -104,7 +117,7 @@ func (h *AccessKeyHandler) GenerateAccessKey(c *gin.Context) {
 		if err.Error() == "maximum access keys reached" {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
 				Error:   "Maximum access keys reached",
-				Message: "You can have a maximum of 5 active access keys. Please revoke an existing key first.",
+				Message: fmt.Sprintf("You can have a maximum of %d active access keys. Please revoke an existing key first.", owner.MaxAccessKeys),
 			})
 			return
 		}
@@ -140,8 +153,24 @@ func (h *AccessKeyHandler) ListAccessKeys(c *gin.Context) {
 		return
 	}
 
+	page, ok := bindPageParams(c, 0, maxPageLimit)
+	if !ok {
+		return
+	}
+
 	accessKeys := make([]models.AccessKey, 0)
-	if err := database.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&accessKeys).Error; err != nil {
+	query := database.DB.Model(&models.AccessKey{}).Where("user_id = ?", userID).Session(&gorm.Session{})
+	var total int64
+	if page.envelope {
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to list access keys",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	if err := page.apply(query).Order("created_at DESC").Find(&accessKeys).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to list access keys",
 			Message: err.Error(),
@@ -150,7 +179,7 @@ func (h *AccessKeyHandler) ListAccessKeys(c *gin.Context) {
 	}
 
 	// Never return secret key hashes
-	c.JSON(http.StatusOK, accessKeys)
+	respondPage(c, page, accessKeys, len(accessKeys), total)
 }
 
 // RevokeAccessKey deactivates an access key (soft delete for audit trail)
@@ -207,6 +236,208 @@ func (h *AccessKeyHandler) RevokeAccessKey(c *gin.Context) {
 	})
 }
 
+// maxAccessKeyRotationGrace caps the grace period a caller may request when rotating a key
+const maxAccessKeyRotationGrace = 7 * 24 * time.Hour
+
+// RotateAccessKeyRequest represents the optional request body for rotating an access key
+type RotateAccessKeyRequest struct {
+	GracePeriod string `json:"grace_period"` // e.g. "1h"; defaults to ACCESS_KEY_ROTATION_GRACE, "0s" revokes immediately
+}
+
+// RotateAccessKey replaces an access key with a new key pair in one transaction.
+// The old key keeps validating for the grace period so clients can switch over without downtime,
+// then stops working (and is deactivated by the expiry job). The new secret is returned once
+func (h *AccessKeyHandler) RotateAccessKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	isAdmin, _ := c.Get("is_admin")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Unauthorized",
+		})
+		return
+	}
+
+	accessKeyUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid access key ID",
+		})
+		return
+	}
+
+	var req RotateAccessKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	grace := h.config.Auth.AccessKeyRotationGraceDuration
+	if req.GracePeriod != "" {
+		grace, err = time.ParseDuration(req.GracePeriod)
+		if err != nil || grace < 0 || grace > maxAccessKeyRotationGrace {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid grace period",
+				Message: fmt.Sprintf("grace_period must be a duration between 0s and %s", maxAccessKeyRotationGrace),
+			})
+			return
+		}
+	}
+
+	// Generate and protect the new secret before the transaction (expensive crypto outside row locks)
+	newAccessKeyID, err := security.GenerateAccessKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to generate access key",
+			Message: err.Error(),
+		})
+		return
+	}
+	secretKey, err := security.GenerateSecretKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to generate secret key",
+			Message: err.Error(),
+		})
+		return
+	}
+	secretKeyHash, err := security.HashSecretKey(secretKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to hash secret key",
+			Message: err.Error(),
+		})
+		return
+	}
+	secretKeyEncrypted, err := security.EncryptSecretKey(secretKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to encrypt secret key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var oldKey, newKey models.AccessKey
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the key so concurrent rotations can't both succeed
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", accessKeyUUID).First(&oldKey).Error; err != nil {
+			return errAccessKeyNotFound
+		}
+
+		// Users can only rotate their own keys unless admin
+		if !isAdmin.(bool) && oldKey.UserID != userID.(uuid.UUID) {
+			return errAccessKeyForbidden
+		}
+		if !oldKey.IsActive || oldKey.ExpiresAt != nil {
+			return errAccessKeyNotRotatable
+		}
+
+		newKey = models.AccessKey{
+			UserID:             oldKey.UserID, // An admin rotating a user's key keeps it with that user
+			AccessKey:          newAccessKeyID,
+			SecretKeyHash:      secretKeyHash,
+			SecretKeyEncrypted: secretKeyEncrypted,
+			IsActive:           true,
+			RotatedFromID:      &oldKey.ID,
+		}
+		if err := tx.Create(&newKey).Error; err != nil {
+			return err
+		}
+
+		expiresAt := time.Now().Add(grace)
+		oldKey.ExpiresAt = &expiresAt
+		if grace == 0 {
+			oldKey.IsActive = false
+		}
+		if err := tx.Save(&oldKey).Error; err != nil {
+			return err
+		}
+
+		// Client certificate bindings restricted to the old key follow it to the new one
+		return tx.Model(&models.ClientCertBinding{}).
+			Where("access_key_id = ?", oldKey.ID).
+			Update("access_key_id", newKey.ID).Error
+	})
+
+	switch err {
+	case nil:
+	case errAccessKeyNotFound:
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Access key not found",
+		})
+		return
+	case errAccessKeyForbidden:
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error: "Access denied",
+		})
+		return
+	case errAccessKeyNotRotatable:
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Access key cannot be rotated",
+			Message: "The key is revoked or already being rotated",
+		})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to rotate access key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Return the secret key ONLY ONCE - it will never be shown again
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate, private")
+	c.Header("Pragma", "no-cache")
+	c.Header("Expires", "0")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":            "Access key rotated successfully",
+		"id":                 newKey.ID,
+		"access_key":         newKey.AccessKey,
+		"secret_key":         secretKey, // ONLY TIME this is ever returned
+		"created_at":         newKey.CreatedAt,
+		"old_access_key":     oldKey.AccessKey,
+		"old_key_expires_at": oldKey.ExpiresAt,
+		"warning":            "Save your secret key now. It will not be shown again!",
+	})
+}
+
+// Sentinel errors for RotateAccessKey's transaction
+var (
+	errAccessKeyNotFound     = errors.New("access key not found")
+	errAccessKeyForbidden    = errors.New("access denied")
+	errAccessKeyNotRotatable = errors.New("access key cannot be rotated")
+)
+
+// StartAccessKeyExpiry periodically deactivates rotated keys whose grace period has ended
+// (authentication already rejects them; this keeps is_active and key counts accurate)
+func StartAccessKeyExpiry(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			result := database.DB.Model(&models.AccessKey{}).
+				Where("is_active = ? AND expires_at IS NOT NULL AND expires_at <= ?", true, time.Now()).
+				Update("is_active", false)
+			if result.Error != nil {
+				logger.Warn("Failed to deactivate expired access keys", map[string]interface{}{
+					"error": result.Error.Error(),
+				})
+			} else if result.RowsAffected > 0 {
+				logger.Info("Deactivated expired access keys", map[string]interface{}{
+					"count": result.RowsAffected,
+				})
+			}
+		}
+	}()
+}
+
 // ValidateAccessKey validates an access key and secret key pair
 // This is used for API authentication
 func (h *AccessKeyHandler) ValidateAccessKey(accessKey, secretKey string) (*models.User, error) {
@@ -221,6 +452,7 @@ func (h *AccessKeyHandler) ValidateAccessKey(accessKey, secretKey string) (*mode
 	// Find access key in database
 	var key models.AccessKey
 	if err := database.DB.Where("access_key = ? AND is_active = ?", accessKey, true).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
 		Preload("User").First(&key).Error; err != nil {
 		return nil, fmt.Errorf("access key not found or inactive")
 	}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultRevokedKeyRetention is how long revoked keys are kept when older_than isn't given
+	defaultRevokedKeyRetention = 90 * 24 * time.Hour
+	// minRevokedKeyRetention keeps recently revoked keys around for incident investigation
+	minRevokedKeyRetention = 24 * time.Hour
+
+	// revokedKeyPurgeBatchSize is how many keys are deleted per transaction
+	revokedKeyPurgeBatchSize = 500
+	// maxPurgedKeysAudited caps the access key IDs listed in the purge's audit entry
+	maxPurgedKeysAudited = 1000
+)
+
+// parseRetention parses a retention period: a Go duration ("720h") or a number of days ("90d")
+func parseRetention(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// PurgeRevokedAccessKeys hard-deletes revoked access keys that haven't been touched for
+// older_than (admin only). Revocation time isn't recorded, so a key's age is taken from the
+// latest of its creation, last use and rotation expiry. Active keys are never touched, and
+// keys a client certificate binding is restricted to are kept so the binding isn't widened to
+// all of the user's keys. The deleted key IDs are recorded in one audit entry
+func (h *AccessKeyHandler) PurgeRevokedAccessKeys(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	olderThan := defaultRevokedKeyRetention
+	if raw := c.Query("older_than"); raw != "" {
+		parsed, err := parseRetention(raw)
+		if err != nil || parsed < minRevokedKeyRetention {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid older_than",
+				Message: "older_than must be a duration of at least 24h (e.g. 720h or 90d)",
+			})
+			return
+		}
+		olderThan = parsed
+	}
+	cutoff := time.Now().Add(-olderThan)
+
+	var deleted int64
+	var purgedKeys []string
+	for {
+		var batch []models.AccessKey
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.AccessKey{}).
+				Where("is_active = ?", false).
+				Where("GREATEST(created_at, COALESCE(last_used_at, created_at), COALESCE(expires_at, created_at)) < ?", cutoff).
+				Where("NOT EXISTS (SELECT 1 FROM client_cert_bindings b WHERE b.access_key_id = access_keys.id)").
+				Select("id", "access_key").
+				Limit(revokedKeyPurgeBatchSize).
+				Find(&batch).Error; err != nil {
+				return err
+			}
+			if len(batch) == 0 {
+				return nil
+			}
+
+			ids := make([]uuid.UUID, len(batch))
+			for i, key := range batch {
+				ids[i] = key.ID
+			}
+			// Keys rotated from a purged key lose the link to their predecessor
+			if err := tx.Model(&models.AccessKey{}).
+				Where("rotated_from_id IN ?", ids).
+				Update("rotated_from_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&models.AccessKey{}).Error
+		})
+		if err != nil {
+			h.auditService.LogFailure(c, userUUID, username.(string), "PurgeRevokedAccessKeys", "AccessKey", "", "", err.Error(), map[string]interface{}{
+				"older_than": olderThan.String(),
+				"deleted":    deleted,
+			})
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to purge revoked access keys",
+				Message: err.Error(),
+			})
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		deleted += int64(len(batch))
+		for _, key := range batch {
+			if len(purgedKeys) < maxPurgedKeysAudited {
+				purgedKeys = append(purgedKeys, key.AccessKey)
+			}
+		}
+		if len(batch) < revokedKeyPurgeBatchSize {
+			break
+		}
+	}
+
+	h.auditService.LogSuccess(c, userUUID, username.(string), "PurgeRevokedAccessKeys", "AccessKey", "", "", map[string]interface{}{
+		"older_than":            olderThan.String(),
+		"cutoff":                cutoff.UTC().Format(time.RFC3339),
+		"deleted":               deleted,
+		"access_keys":           purgedKeys,
+		"access_keys_truncated": deleted > int64(len(purgedKeys)),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Revoked access keys purged",
+		"deleted": deleted,
+		"cutoff":  cutoff,
+	})
+}
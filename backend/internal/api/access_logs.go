@@ -0,0 +1,430 @@
+package api
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// accessLogMaxLines caps the lines buffered per source bucket between flushes; further lines are dropped
+const accessLogMaxLines = 100000
+
+// accessLogTargetTTL is how long the middleware caches a bucket's logging target
+const accessLogTargetTTL = 30 * time.Second
+
+// errAccessLogUndeliverable marks delivery failures that retrying won't fix (e.g. the target bucket is gone)
+var errAccessLogUndeliverable = errors.New("access logs cannot be delivered")
+
+// accessLogTarget is a bucket's cached logging configuration
+type accessLogTarget struct {
+	bucket   string // Empty when logging is disabled
+	prefix   string
+	loadedAt time.Time
+}
+
+// accessLogEntry is one logged request
+type accessLogEntry struct {
+	Time          time.Time `json:"time"`
+	Bucket        string    `json:"bucket"`
+	RemoteIP      string    `json:"remote_ip"`
+	Requester     string    `json:"requester"`
+	AccessKeyID   string    `json:"access_key_id,omitempty"`
+	RequestID     string    `json:"request_id"`
+	Operation     string    `json:"operation"`
+	Key           string    `json:"key,omitempty"`
+	Status        int       `json:"status"`
+	BytesSent     int64     `json:"bytes_sent"`
+	BytesReceived int64     `json:"bytes_received"`
+	DurationMs    int64     `json:"duration_ms"`
+	UserAgent     string    `json:"user_agent"`
+}
+
+// Access log lines are buffered in memory per source bucket and delivered as objects periodically
+var (
+	accessLogTargets    sync.Map // Bucket name -> accessLogTarget
+	pendingAccessLogs   = make(map[string][]string)
+	droppedAccessLogs   = make(map[string]int)
+	pendingAccessLogsMu sync.Mutex
+	accessLogDelivery   *BucketHandler // Set by StartAccessLogDelivery
+)
+
+// AccessLoggingRequest represents the request body for configuring a bucket's access logging
+type AccessLoggingRequest struct {
+	TargetBucket string `json:"target_bucket"` // Empty disables logging
+	Prefix       string `json:"prefix"`        // Key prefix of the delivered log objects, e.g. "logs/photos/"
+}
+
+// accessLogMiddleware records REST and S3 requests against buckets that have access logging enabled
+func accessLogMiddleware(cfg *config.Config) gin.HandlerFunc {
+	format := cfg.Storage.AccessLog.Format
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		// Same bucket attribution as usage tracking
+		bucketName := c.GetString(middleware.UsageBucketKey)
+		if bucketName == "" {
+			bucketName = c.Param("name")
+		}
+		if bucketName == "" {
+			bucketName = c.Param("bucket")
+		}
+		if bucketName == "" {
+			return
+		}
+
+		if target := lookupAccessLogTarget(bucketName); target.bucket == "" {
+			return
+		}
+
+		entry := accessLogEntry{
+			Time:       start.UTC(),
+			Bucket:     bucketName,
+			RemoteIP:   c.ClientIP(),
+			Requester:  accessLogRequester(c),
+			RequestID:  c.GetString("request_id"),
+			Operation:  c.Request.Method + " " + c.FullPath(),
+			Key:        strings.TrimPrefix(c.Param("key"), "/"),
+			Status:     c.Writer.Status(),
+			DurationMs: time.Since(start).Milliseconds(),
+			UserAgent:  c.Request.UserAgent(),
+		}
+		if accessKeyID, ok := c.Get("access_key_id"); ok {
+			entry.AccessKeyID = fmt.Sprint(accessKeyID)
+		}
+		if size := c.Writer.Size(); size > 0 {
+			entry.BytesSent = int64(size)
+		}
+		if c.Request.ContentLength > 0 {
+			entry.BytesReceived = c.Request.ContentLength
+		}
+
+		recordAccessLog(bucketName, formatAccessLogEntry(format, &entry))
+	}
+}
+
+// lookupAccessLogTarget returns a bucket's logging target, cached for accessLogTargetTTL
+func lookupAccessLogTarget(bucketName string) accessLogTarget {
+	if cached, ok := accessLogTargets.Load(bucketName); ok {
+		target := cached.(accessLogTarget)
+		if time.Since(target.loadedAt) < accessLogTargetTTL {
+			return target
+		}
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Select("access_log_bucket", "access_log_prefix").
+		Where("name = ?", bucketName).Take(&bucket).Error; err != nil {
+		return accessLogTarget{} // Unknown names aren't cached so arbitrary requests can't grow the cache
+	}
+
+	target := accessLogTarget{
+		bucket:   bucket.AccessLogBucket,
+		prefix:   bucket.AccessLogPrefix,
+		loadedAt: time.Now(),
+	}
+	accessLogTargets.Store(bucketName, target)
+	return target
+}
+
+// accessLogRequester names the caller: the JWT username, the access key's user, or "-" if anonymous
+func accessLogRequester(c *gin.Context) string {
+	if username := c.GetString("username"); username != "" {
+		return username
+	}
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*models.User); ok && u.Username != "" {
+			return u.Username
+		}
+	}
+	return "-"
+}
+
+// formatAccessLogEntry renders one log line. The text format is space-separated with quoted
+// free-form fields, e.g.
+// photos [02/Jan/2006:15:04:05 +0000] 10.0.0.5 alice 6f1c... "GET /s3/:bucket/*key" "a.jpg" 200 5120 0 12 "aws-cli/2.15"
+func formatAccessLogEntry(format string, entry *accessLogEntry) string {
+	if format == "json" {
+		line, _ := json.Marshal(entry)
+		return string(line)
+	}
+
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	key := "-"
+	if entry.Key != "" {
+		key = strconv.Quote(entry.Key)
+	}
+
+	return fmt.Sprintf("%s [%s] %s %s %s %s %s %d %d %d %d %s",
+		entry.Bucket,
+		entry.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		orDash(entry.RemoteIP),
+		orDash(entry.Requester),
+		orDash(entry.RequestID),
+		strconv.Quote(entry.Operation),
+		key,
+		entry.Status,
+		entry.BytesSent,
+		entry.BytesReceived,
+		entry.DurationMs,
+		strconv.Quote(entry.UserAgent),
+	)
+}
+
+// recordAccessLog buffers a line for the next delivery
+func recordAccessLog(bucketName, line string) {
+	pendingAccessLogsMu.Lock()
+	defer pendingAccessLogsMu.Unlock()
+
+	if len(pendingAccessLogs[bucketName]) >= accessLogMaxLines {
+		droppedAccessLogs[bucketName]++
+		return
+	}
+	pendingAccessLogs[bucketName] = append(pendingAccessLogs[bucketName], line)
+}
+
+// StartAccessLogDelivery periodically writes buffered access logs into their target buckets
+func StartAccessLogDelivery(cfg *config.Config) {
+	accessLogDelivery = NewBucketHandler(cfg)
+	go func() {
+		ticker := time.NewTicker(cfg.Storage.AccessLog.FlushIntervalDuration)
+		defer ticker.Stop()
+		for range ticker.C {
+			FlushAccessLogs()
+		}
+	}()
+}
+
+// FlushAccessLogs delivers all buffered access logs, one object per source bucket.
+// Lines that fail to deliver for a transient reason are kept for the next flush
+func FlushAccessLogs() {
+	// Writes are frozen in maintenance mode; logs stay buffered until it ends
+	if accessLogDelivery == nil || middleware.GetMaintenanceMode().Enabled {
+		return
+	}
+
+	pendingAccessLogsMu.Lock()
+	batch, dropped := pendingAccessLogs, droppedAccessLogs
+	pendingAccessLogs = make(map[string][]string)
+	droppedAccessLogs = make(map[string]int)
+	pendingAccessLogsMu.Unlock()
+
+	for bucketName, count := range dropped {
+		logger.Warn("Access log buffer full, lines dropped", map[string]interface{}{
+			"bucket":  bucketName,
+			"dropped": count,
+		})
+	}
+
+	for bucketName, lines := range batch {
+		err := accessLogDelivery.deliverAccessLogs(bucketName, lines)
+		if err == nil {
+			continue
+		}
+
+		logger.Warn("Failed to deliver access logs", map[string]interface{}{
+			"bucket": bucketName,
+			"lines":  len(lines),
+			"error":  err.Error(),
+		})
+		if errors.Is(err, errAccessLogUndeliverable) {
+			continue
+		}
+
+		// Put the lines back ahead of anything recorded meanwhile, within the buffer cap
+		pendingAccessLogsMu.Lock()
+		requeued := append(lines, pendingAccessLogs[bucketName]...)
+		if len(requeued) > accessLogMaxLines {
+			droppedAccessLogs[bucketName] += len(requeued) - accessLogMaxLines
+			requeued = requeued[len(requeued)-accessLogMaxLines:]
+		}
+		pendingAccessLogs[bucketName] = requeued
+		pendingAccessLogsMu.Unlock()
+	}
+}
+
+// deliverAccessLogs writes one log object holding lines for sourceBucket into its target bucket
+func (h *BucketHandler) deliverAccessLogs(sourceBucket string, lines []string) error {
+	var source models.Bucket
+	if err := database.DB.Where("name = ?", sourceBucket).First(&source).Error; err != nil {
+		return fmt.Errorf("%w: source bucket %s not found", errAccessLogUndeliverable, sourceBucket)
+	}
+	if source.AccessLogBucket == "" {
+		return nil // Logging was disabled since these requests were recorded
+	}
+
+	var target models.Bucket
+	if err := database.DB.Where("name = ?", source.AccessLogBucket).First(&target).Error; err != nil {
+		return fmt.Errorf("%w: target bucket %s not found", errAccessLogUndeliverable, source.AccessLogBucket)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	key := target.NormalizeKey(source.AccessLogPrefix +
+		time.Now().UTC().Format("2006-01-02-15-04-05") + "-" + strings.ToUpper(hex.EncodeToString(suffix)))
+
+	content := []byte(strings.Join(lines, "\n") + "\n")
+	contentType := "text/plain; charset=utf-8"
+	if h.config.Storage.AccessLog.Format == "json" {
+		contentType = "application/x-ndjson"
+	}
+
+	storageBackend, err := h.getStorageBackend(&target)
+	if err != nil {
+		return err
+	}
+	if err := storageBackend.PutObject(target.Name, key, bytes.NewReader(content), int64(len(content)), contentType); err != nil {
+		return err
+	}
+
+	md5Sum := md5.Sum(content)
+	sha256Sum := sha256.Sum256(content)
+	object := models.Object{
+		BucketID:    target.ID,
+		Key:         key,
+		Size:        int64(len(content)),
+		ContentType: contentType,
+		ETag:        hex.EncodeToString(md5Sum[:]),
+		SHA256:      hex.EncodeToString(sha256Sum[:]),
+		StoragePath: key,
+	}
+	if err := database.DB.Create(&object).Error; err != nil {
+		storageBackend.DeleteObject(target.Name, key)
+		return fmt.Errorf("failed to save log object metadata: %w", err)
+	}
+
+	return nil
+}
+
+// GetAccessLogging returns a bucket's access logging configuration (admin only)
+func (h *BucketHandler) GetAccessLogging(c *gin.Context) {
+	bucketName := c.Param("name")
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bucket":         bucketName,
+		"enabled":        bucket.AccessLogBucket != "",
+		"target_bucket":  bucket.AccessLogBucket,
+		"prefix":         bucket.AccessLogPrefix,
+		"format":         h.config.Storage.AccessLog.Format,
+		"flush_interval": h.config.Storage.AccessLog.FlushInterval,
+	})
+}
+
+// SetAccessLogging enables, changes or disables a bucket's access logging (admin only)
+func (h *BucketHandler) SetAccessLogging(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req AccessLoggingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	if req.TargetBucket == "" {
+		req.Prefix = ""
+	} else {
+		if req.TargetBucket == bucketName {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid target bucket",
+				Message: "Deliver access logs to a different bucket",
+			})
+			return
+		}
+		if req.Prefix != "" {
+			if err := validation.ValidateObjectKey(req.Prefix); err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid prefix",
+					Message: err.Error(),
+				})
+				return
+			}
+		}
+
+		var target models.Bucket
+		if err := database.DB.Where("name = ?", req.TargetBucket).First(&target).Error; err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "Target bucket not found",
+				Message: fmt.Sprintf("Bucket %s does not exist", req.TargetBucket),
+			})
+			return
+		}
+	}
+
+	if err := database.DB.Model(&bucket).Updates(map[string]interface{}{
+		"access_log_bucket": req.TargetBucket,
+		"access_log_prefix": req.Prefix,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to update bucket",
+			Message: err.Error(),
+		})
+		return
+	}
+	accessLogTargets.Delete(bucketName)
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"SetAccessLogging", "Bucket", bucket.ID.String(), bucketName,
+		map[string]interface{}{"target_bucket": req.TargetBucket, "prefix": req.Prefix})
+
+	message := "Access logging enabled"
+	if req.TargetBucket == "" {
+		message = "Access logging disabled"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       message,
+		"bucket":        bucketName,
+		"enabled":       req.TargetBucket != "",
+		"target_bucket": req.TargetBucket,
+		"prefix":        req.Prefix,
+	})
+}
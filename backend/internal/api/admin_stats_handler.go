@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Dashboard stats are cached briefly so an open overview screen doesn't aggregate every table on each refresh
+var (
+	adminStatsCache    *models.AdminStats
+	adminStatsCacheMu  sync.Mutex
+	adminStatsCacheTTL = 30 * time.Second
+)
+
+// adminStatsTimeout bounds the aggregate queries behind one stats refresh
+const adminStatsTimeout = 15 * time.Second
+
+type AdminStatsHandler struct {
+	config *config.Config
+}
+
+func NewAdminStatsHandler(cfg *config.Config) *AdminStatsHandler {
+	return &AdminStatsHandler{config: cfg}
+}
+
+// GetStats returns aggregate counts for the admin overview screen (admin only)
+func (h *AdminStatsHandler) GetStats(c *gin.Context) {
+	// The lock is held while refreshing so concurrent dashboards share one set of queries
+	adminStatsCacheMu.Lock()
+	defer adminStatsCacheMu.Unlock()
+
+	if adminStatsCache == nil || time.Since(adminStatsCache.GeneratedAt) >= adminStatsCacheTTL {
+		stats, err := loadAdminStats()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to load stats",
+				Message: err.Error(),
+			})
+			return
+		}
+		adminStatsCache = stats
+	}
+
+	c.JSON(http.StatusOK, adminStatsCache)
+}
+
+// loadAdminStats runs one aggregate query per table
+func loadAdminStats() (*models.AdminStats, error) {
+	db, cancel := database.WithTimeout(adminStatsTimeout)
+	defer cancel()
+
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+	stats := &models.AdminStats{
+		AuditEvents24h: make(map[string]int64),
+		GeneratedAt:    now,
+	}
+
+	var users struct {
+		Users           int64
+		LockedUsers     int64
+		ServiceAccounts int64
+	}
+	if err := db.Model(&models.User{}).
+		Select(`COUNT(*) FILTER (WHERE NOT is_service_account) AS users,
+			COUNT(*) FILTER (WHERE is_locked) AS locked_users,
+			COUNT(*) FILTER (WHERE is_service_account) AS service_accounts`).
+		Scan(&users).Error; err != nil {
+		return nil, err
+	}
+	stats.Users, stats.LockedUsers, stats.ServiceAccounts = users.Users, users.LockedUsers, users.ServiceAccounts
+
+	if err := db.Model(&models.Bucket{}).Count(&stats.Buckets).Error; err != nil {
+		return nil, err
+	}
+
+	var objects struct {
+		ObjectCount int64
+		TotalSize   int64
+	}
+	if err := db.Model(&models.Object{}).
+		Select("COUNT(*) AS object_count, COALESCE(SUM(size), 0) AS total_size").
+		Scan(&objects).Error; err != nil {
+		return nil, err
+	}
+	stats.Objects, stats.StorageBytes = objects.ObjectCount, objects.TotalSize
+
+	if err := db.Model(&models.AccessKey{}).
+		Where("is_active = ?", true).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Count(&stats.ActiveAccessKeys).Error; err != nil {
+		return nil, err
+	}
+
+	var uploads struct {
+		InProgress int64
+		Failed     int64
+	}
+	inProgress := []models.UploadStatus{models.UploadStatusPending, models.UploadStatusQueued, models.UploadStatusProcessing}
+	if err := db.Model(&models.Upload{}).
+		Select("COUNT(*) FILTER (WHERE status IN ?) AS in_progress, COUNT(*) FILTER (WHERE status = ? AND updated_at >= ?) AS failed",
+			inProgress, models.UploadStatusFailed, since).
+		Scan(&uploads).Error; err != nil {
+		return nil, err
+	}
+	stats.UploadsInProgress, stats.FailedUploads24h = uploads.InProgress, uploads.Failed
+
+	if err := auditCountsByStatus(db, since, stats.AuditEvents24h); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// auditCountsByStatus fills counts with the number of audit log entries per status since a time
+func auditCountsByStatus(db *gorm.DB, since time.Time, counts map[string]int64) error {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Model(&models.AuditLog{}).
+		Select("status, COUNT(*) AS count").
+		Where("created_at >= ?", since).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return nil
+}
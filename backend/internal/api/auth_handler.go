@@ -2,11 +2,12 @@ package api
 
 import (
 	"net/http"
+	"strings"
 	"bkt/internal/auth"
 	"bkt/internal/config"
 	"bkt/internal/database"
+	"bkt/internal/logger"
 	"bkt/internal/models"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -75,23 +76,23 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	accessTokenDuration, _ := time.ParseDuration(h.config.Auth.AccessTokenExpiry)
-	token, err := auth.GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, accessTokenDuration)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to generate token",
-			Message: "An internal error occurred. Please try again.",
+	// Org-wide onboarding defaults (policies, access key limit)
+	auth.ApplyDefaultUserProfile(&user, &h.config.Auth, false)
+
+	// Accounts without policies don't get tokens until an admin grants access
+	if h.config.Auth.RequirePolicyForLogin && !auth.HasLoginPolicies(&user) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "No permissions",
+			Message: auth.NoPermissionsMessage,
 		})
 		return
 	}
 
-	// Generate refresh token
-	refreshTokenDuration, _ := time.ParseDuration(h.config.Auth.RefreshTokenExpiry)
-	refreshToken, err := auth.GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, refreshTokenDuration)
+	// Generate access and refresh tokens
+	token, refreshToken, err := auth.GenerateTokenPair(user.ID, user.Username, user.IsAdmin, h.config.Auth)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to generate refresh token",
+			Error:   "Failed to generate token",
 			Message: "An internal error occurred. Please try again.",
 		})
 		return
@@ -115,9 +116,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Find user
+	// Find user (service accounts have no password and can never log in)
 	var user models.User
-	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+	if err := database.DB.Where("username = ? AND is_service_account = ?", req.Username, false).First(&user).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Error:   "Invalid credentials",
 			Message: "Username or password is incorrect",
@@ -143,23 +144,20 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	accessTokenDuration, _ := time.ParseDuration(h.config.Auth.AccessTokenExpiry)
-	token, err := auth.GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, accessTokenDuration)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to generate token",
-			Message: "An internal error occurred. Please try again.",
+	// Optionally deny users without any policies
+	if h.config.Auth.RequirePolicyForLogin && !auth.HasLoginPolicies(&user) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "No permissions",
+			Message: auth.NoPermissionsMessage,
 		})
 		return
 	}
 
-	// Generate refresh token
-	refreshTokenDuration, _ := time.ParseDuration(h.config.Auth.RefreshTokenExpiry)
-	refreshToken, err := auth.GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, refreshTokenDuration)
+	// Generate access and refresh tokens
+	token, refreshToken, err := auth.GenerateTokenPair(user.ID, user.Username, user.IsAdmin, h.config.Auth)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to generate refresh token",
+			Error:   "Failed to generate token",
 			Message: "An internal error occurred. Please try again.",
 		})
 		return
@@ -172,13 +170,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
-// RefreshToken generates a new access token using a refresh token
+// RefreshToken generates a new access token using a refresh token.
+// The refresh token comes from the request body, or from the refresh cookie when cookie
+// sessions are enabled; in the latter case the new tokens are returned as cookies only
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req struct {
-		RefreshToken string `json:"refresh_token" binding:"required"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error:   "Invalid request",
 			Message: err.Error(),
@@ -186,9 +186,34 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	fromCookie := false
+	if req.RefreshToken == "" && h.config.Auth.CookieSessions {
+		if cookie, err := c.Cookie(auth.RefreshCookieName); err == nil && cookie != "" {
+			if c.GetHeader("X-Requested-With") == "" {
+				c.JSON(http.StatusForbidden, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: "X-Requested-With header required for cookie-authenticated requests",
+				})
+				return
+			}
+			req.RefreshToken = cookie
+			fromCookie = true
+		}
+	}
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "refresh_token is required",
+		})
+		return
+	}
+
 	// Validate refresh token
 	claims, err := auth.ValidateToken(req.RefreshToken, h.config.Auth.JWTSecret)
 	if err != nil {
+		if fromCookie {
+			auth.ClearSessionCookies(c, h.config.Auth)
+		}
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Error:   "Invalid refresh token",
 			Message: "Please log in again",
@@ -196,6 +221,15 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// Service tokens can't be exchanged for user sessions
+	if claims.ServiceAccount {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error:   "Invalid refresh token",
+			Message: "Service account tokens cannot be refreshed",
+		})
+		return
+	}
+
 	// Get user
 	var user models.User
 	if err := database.DB.First(&user, "id = ?", claims.UserID).Error; err != nil {
@@ -215,9 +249,17 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Generate new access token
-	accessTokenDuration, _ := time.ParseDuration(h.config.Auth.AccessTokenExpiry)
-	newToken, err := auth.GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, accessTokenDuration)
+	// Policies may have been revoked since login
+	if h.config.Auth.RequirePolicyForLogin && !auth.HasLoginPolicies(&user) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "No permissions",
+			Message: auth.NoPermissionsMessage,
+		})
+		return
+	}
+
+	// Generate new access token (and a renewed refresh token with sliding sessions)
+	newToken, newRefreshToken, err := auth.RefreshSession(claims, user.Username, user.IsAdmin, h.config.Auth)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to generate token",
@@ -226,19 +268,118 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	if fromCookie {
+		auth.SetSessionCookies(c, h.config.Auth, newToken, newRefreshToken)
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Session refreshed",
+		})
+		return
+	}
+
+	response := gin.H{
 		"token": newToken,
+	}
+	if newRefreshToken != "" {
+		response["refresh_token"] = newRefreshToken
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateSession exchanges the bearer token of the current request (and optionally a refresh
+// token) for HttpOnly session cookies, so the web UI doesn't have to keep JWTs in script-accessible
+// storage. Only available when cookie sessions are enabled
+func (h *AuthHandler) CreateSession(c *gin.Context) {
+	if !h.config.Auth.CookieSessions {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "Cookie sessions are disabled",
+			Message: "Use the Authorization header with a bearer token",
+		})
+		return
+	}
+
+	// Browser sessions are for people; service accounts keep using their bearer token
+	if c.GetBool("service_account") {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Not available for service accounts",
+			Message: "Service accounts authenticate with their bearer token",
+		})
+		return
+	}
+
+	accessToken := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if accessToken == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "A bearer token is required to create a session",
+		})
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// The refresh token must belong to the same user as the access token
+	if req.RefreshToken != "" {
+		userID, _ := c.Get("user_id")
+		refreshClaims, err := auth.ValidateToken(req.RefreshToken, h.config.Auth.JWTSecret)
+		if err != nil || refreshClaims.UserID != userID {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error:   "Invalid refresh token",
+				Message: "Please log in again",
+			})
+			return
+		}
+	}
+
+	auth.SetSessionCookies(c, h.config.Auth, accessToken, req.RefreshToken)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Session created",
 	})
 }
 
-// Logout invalidates the user's token (in a real implementation, you'd add the token to a blacklist)
+// Logout revokes the current access token and, if supplied (body or cookie), the refresh token,
+// then clears any session cookies
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// In a production system, you would:
-	// 1. Add the token to a Redis blacklist
-	// 2. Or store token JTI in database with expiry
-	// For now, we'll just return success as the client will discard the token
+	if claims, exists := c.Get("token_claims"); exists {
+		h.revokeToken(claims.(*auth.Claims))
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	c.ShouldBindJSON(&req)
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = c.Cookie(auth.RefreshCookieName)
+	}
+	if req.RefreshToken != "" {
+		userID, _ := c.Get("user_id")
+		if refreshClaims, err := auth.ValidateToken(req.RefreshToken, h.config.Auth.JWTSecret); err == nil && refreshClaims.UserID == userID {
+			h.revokeToken(refreshClaims)
+		}
+	}
+
+	auth.ClearSessionCookies(c, h.config.Auth)
 
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Successfully logged out",
 	})
 }
+
+func (h *AuthHandler) revokeToken(claims *auth.Claims) {
+	if err := auth.RevokeToken(claims); err != nil {
+		logger.Error("Failed to revoke token", map[string]interface{}{
+			"user_id": claims.UserID.String(),
+			"error":   err.Error(),
+		})
+	}
+}
@@ -3,10 +3,15 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"bkt/internal/config"
 	"bkt/internal/database"
 	"bkt/internal/logger"
@@ -16,9 +21,11 @@ import (
 	"bkt/internal/storage"
 	"bkt/internal/validation"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -27,8 +34,9 @@ import (
 
 // s3ConfigCacheEntry represents a cached S3 configuration with expiration
 type s3ConfigCacheEntry struct {
-	Config    *s3ConfigData
-	ExpiresAt time.Time
+	Config     *s3ConfigData
+	ExpiresAt  time.Time
+	lastAccess atomic.Int64 // UnixNano of last read, used for LRU eviction without taking the write lock
 }
 
 // s3ConfigData holds decrypted S3 configuration data for caching
@@ -38,21 +46,41 @@ type s3ConfigData struct {
 	AccessKeyID     string // Decrypted
 	SecretAccessKey string // Decrypted
 	BucketPrefix    string
+	ObjectKeyPrefix string
 	UseSSL          bool
 	ForcePathStyle  bool
 }
 
 // Global S3 config cache with 5 minute TTL (reduces database load)
+// Bounded to s3ConfigCacheMaxEntries so decrypted credentials don't accumulate in memory
 var (
-	s3ConfigCache   = make(map[string]*s3ConfigCacheEntry)
-	s3ConfigCacheMu sync.RWMutex
-	s3ConfigCacheTTL = 5 * time.Minute
+	s3ConfigCache           = make(map[string]*s3ConfigCacheEntry)
+	s3ConfigCacheMu         sync.RWMutex
+	s3ConfigCacheTTL        = 5 * time.Minute
+	s3ConfigCacheMaxEntries = 100
+
+	// Cache metrics (read via GetS3ConfigCacheStats)
+	s3ConfigCacheHits      atomic.Uint64
+	s3ConfigCacheMisses    atomic.Uint64
+	s3ConfigCacheEvictions atomic.Uint64
 )
 
+// S3ConfigCacheStats is a snapshot of S3 config cache metrics
+type S3ConfigCacheStats struct {
+	Hits       uint64  `json:"hits"`
+	Misses     uint64  `json:"misses"`
+	Evictions  uint64  `json:"evictions"`
+	HitRate    float64 `json:"hit_rate"`
+	Size       int     `json:"size"`
+	MaxEntries int     `json:"max_entries"`
+	TTLSeconds int     `json:"ttl_seconds"`
+}
+
 type BucketHandler struct {
 	config        *config.Config
 	policyService *services.PolicyService
 	auditService  *services.AuditService
+	uploadScanner security.UploadScanner // nil when upload scanning is disabled
 }
 
 func NewBucketHandler(cfg *config.Config) *BucketHandler {
@@ -60,6 +88,7 @@ func NewBucketHandler(cfg *config.Config) *BucketHandler {
 		config:        cfg,
 		policyService: services.NewPolicyService(),
 		auditService:  services.NewAuditService(),
+		uploadScanner: security.NewUploadScanner(cfg.Storage.Scanner),
 	}
 }
 
@@ -70,26 +99,66 @@ func getS3ConfigFromCache(cacheKey string) (*s3ConfigData, bool) {
 
 	entry, exists := s3ConfigCache[cacheKey]
 	if !exists {
+		s3ConfigCacheMisses.Add(1)
 		return nil, false
 	}
 
-	// Check if entry has expired
-	if time.Now().After(entry.ExpiresAt) {
+	// Check if entry has expired (removed by the eviction routine or the next set)
+	now := time.Now()
+	if now.After(entry.ExpiresAt) {
+		s3ConfigCacheMisses.Add(1)
 		return nil, false
 	}
 
+	entry.lastAccess.Store(now.UnixNano())
+	s3ConfigCacheHits.Add(1)
 	return entry.Config, true
 }
 
 // setS3ConfigInCache stores S3 config in cache with TTL
+// Evicts expired entries, then the least recently used one, when the cache is full
 func setS3ConfigInCache(cacheKey string, config *s3ConfigData) {
 	s3ConfigCacheMu.Lock()
 	defer s3ConfigCacheMu.Unlock()
 
-	s3ConfigCache[cacheKey] = &s3ConfigCacheEntry{
+	now := time.Now()
+	if _, exists := s3ConfigCache[cacheKey]; !exists && len(s3ConfigCache) >= s3ConfigCacheMaxEntries {
+		evictExpiredS3ConfigsLocked(now)
+
+		if len(s3ConfigCache) >= s3ConfigCacheMaxEntries {
+			var lruKey string
+			var lruAccess int64
+			for key, entry := range s3ConfigCache {
+				access := entry.lastAccess.Load()
+				if lruKey == "" || access < lruAccess {
+					lruKey = key
+					lruAccess = access
+				}
+			}
+			delete(s3ConfigCache, lruKey)
+			s3ConfigCacheEvictions.Add(1)
+		}
+	}
+
+	entry := &s3ConfigCacheEntry{
 		Config:    config,
-		ExpiresAt: time.Now().Add(s3ConfigCacheTTL),
+		ExpiresAt: now.Add(s3ConfigCacheTTL),
+	}
+	entry.lastAccess.Store(now.UnixNano())
+	s3ConfigCache[cacheKey] = entry
+}
+
+// evictExpiredS3ConfigsLocked removes expired entries (caller must hold the write lock)
+func evictExpiredS3ConfigsLocked(now time.Time) int {
+	evicted := 0
+	for key, entry := range s3ConfigCache {
+		if now.After(entry.ExpiresAt) {
+			delete(s3ConfigCache, key)
+			evicted++
+		}
 	}
+	s3ConfigCacheEvictions.Add(uint64(evicted))
+	return evicted
 }
 
 // InvalidateS3ConfigCache invalidates cached S3 configurations (called when configs are modified)
@@ -101,6 +170,61 @@ func InvalidateS3ConfigCache() {
 	s3ConfigCache = make(map[string]*s3ConfigCacheEntry)
 }
 
+// StartS3ConfigCacheEviction periodically removes expired S3 configs so decrypted
+// credentials don't linger in memory after their TTL
+func StartS3ConfigCacheEviction(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s3ConfigCacheMu.Lock()
+			evictExpiredS3ConfigsLocked(time.Now())
+			s3ConfigCacheMu.Unlock()
+		}
+	}()
+}
+
+// GetS3ConfigCacheStats returns a snapshot of S3 config cache metrics
+func GetS3ConfigCacheStats() S3ConfigCacheStats {
+	s3ConfigCacheMu.RLock()
+	size := len(s3ConfigCache)
+	s3ConfigCacheMu.RUnlock()
+
+	hits := s3ConfigCacheHits.Load()
+	misses := s3ConfigCacheMisses.Load()
+	hitRate := 0.0
+	if hits+misses > 0 {
+		hitRate = float64(hits) / float64(hits+misses)
+	}
+
+	return S3ConfigCacheStats{
+		Hits:       hits,
+		Misses:     misses,
+		Evictions:  s3ConfigCacheEvictions.Load(),
+		HitRate:    hitRate,
+		Size:       size,
+		MaxEntries: s3ConfigCacheMaxEntries,
+		TTLSeconds: int(s3ConfigCacheTTL.Seconds()),
+	}
+}
+
+// decompressionLimit returns how many bytes gzip content of the given size may inflate to
+func (h *BucketHandler) decompressionLimit(compressedSize int64) int64 {
+	return validation.DecompressionLimit(compressedSize, h.config.Storage.MaxDecompressionRatio, h.config.Storage.MaxDecompressedSize)
+}
+
+// checkContentTypeSize enforces the per content type size limits. Both the detected and the stored
+// type are checked, so a trusted declared type can't lift the limit that applies to the actual content
+func (h *BucketHandler) checkContentTypeSize(size int64, contentTypes ...string) error {
+	for _, contentType := range contentTypes {
+		rule, ok := h.config.Storage.ContentTypeSizeLimit(contentType)
+		if ok && size > rule.MaxSize {
+			return fmt.Errorf("files of type %s are limited to %d bytes", rule.Pattern, rule.MaxSize)
+		}
+	}
+	return nil
+}
+
 // getStorageBackend creates a storage backend instance based on the bucket's configuration
 // Hybrid approach: If bucket has s3_config_id, use that; otherwise use .env config
 func (h *BucketHandler) getStorageBackend(bucket *models.Bucket) (storage.StorageBackend, error) {
@@ -111,11 +235,11 @@ func (h *BucketHandler) getStorageBackend(bucket *models.Bucket) (storage.Storag
 
 	// If not S3, return local storage
 	if backend != "s3" {
-		return storage.NewLocalStorage(h.config.Storage.RootPath), nil
+		return storage.NewLocalStorage(h.config.Storage.RootPath, h.config.Storage.LocalLayout), nil
 	}
 
 	// S3 backend: Load configuration with caching (reduces database load)
-	var endpoint, region, accessKeyID, secretAccessKey, bucketPrefix string
+	var endpoint, region, accessKeyID, secretAccessKey, bucketPrefix, objectKeyPrefix string
 	var useSSL, forcePathStyle bool
 
 	// Determine cache key and load config
@@ -149,6 +273,7 @@ func (h *BucketHandler) getStorageBackend(bucket *models.Bucket) (storage.Storag
 					AccessKeyID:     decryptedAccessKeyID,
 					SecretAccessKey: decryptedSecretAccessKey,
 					BucketPrefix:    s3Config.BucketPrefix,
+					ObjectKeyPrefix: s3Config.ObjectKeyPrefix,
 					UseSSL:          s3Config.UseSSL,
 					ForcePathStyle:  s3Config.ForcePathStyle,
 				}
@@ -161,6 +286,7 @@ func (h *BucketHandler) getStorageBackend(bucket *models.Bucket) (storage.Storag
 					AccessKeyID:     h.config.Storage.S3.AccessKeyID,
 					SecretAccessKey: h.config.Storage.S3.SecretAccessKey,
 					BucketPrefix:    h.config.Storage.S3.BucketPrefix,
+					ObjectKeyPrefix: h.config.Storage.S3.ObjectKeyPrefix,
 					UseSSL:          h.config.Storage.S3.UseSSL,
 					ForcePathStyle:  h.config.Storage.S3.ForcePathStyle,
 				}
@@ -192,6 +318,7 @@ func (h *BucketHandler) getStorageBackend(bucket *models.Bucket) (storage.Storag
 					AccessKeyID:     decryptedAccessKeyID,
 					SecretAccessKey: decryptedSecretAccessKey,
 					BucketPrefix:    defaultConfig.BucketPrefix,
+					ObjectKeyPrefix: defaultConfig.ObjectKeyPrefix,
 					UseSSL:          defaultConfig.UseSSL,
 					ForcePathStyle:  defaultConfig.ForcePathStyle,
 				}
@@ -204,6 +331,7 @@ func (h *BucketHandler) getStorageBackend(bucket *models.Bucket) (storage.Storag
 					AccessKeyID:     h.config.Storage.S3.AccessKeyID,
 					SecretAccessKey: h.config.Storage.S3.SecretAccessKey,
 					BucketPrefix:    h.config.Storage.S3.BucketPrefix,
+					ObjectKeyPrefix: h.config.Storage.S3.ObjectKeyPrefix,
 					UseSSL:          h.config.Storage.S3.UseSSL,
 					ForcePathStyle:  h.config.Storage.S3.ForcePathStyle,
 				}
@@ -217,17 +345,20 @@ func (h *BucketHandler) getStorageBackend(bucket *models.Bucket) (storage.Storag
 	accessKeyID = configData.AccessKeyID
 	secretAccessKey = configData.SecretAccessKey
 	bucketPrefix = configData.BucketPrefix
+	objectKeyPrefix = configData.ObjectKeyPrefix
 	useSSL = configData.UseSSL
 	forcePathStyle = configData.ForcePathStyle
 
 	storageBackend, err := storage.NewStorageBackend(
 		backend,
 		h.config.Storage.RootPath,
+		h.config.Storage.LocalLayout,
 		endpoint,
 		region,
 		accessKeyID,
 		secretAccessKey,
 		bucketPrefix,
+		objectKeyPrefix,
 		useSSL,
 		forcePathStyle,
 	)
@@ -249,7 +380,7 @@ func (h *BucketHandler) getStorageBackend(bucket *models.Bucket) (storage.Storag
 		logger.Info("Falling back to local storage", map[string]interface{}{
 			"bucket": bucket.Name,
 		})
-		return storage.NewLocalStorage(h.config.Storage.RootPath), nil
+		return storage.NewLocalStorage(h.config.Storage.RootPath, h.config.Storage.LocalLayout), nil
 	}
 
 	return storageBackend, nil
@@ -287,7 +418,7 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 	}
 
 	// Check policy permissions
-	allowed, err := h.policyService.CheckBucketAccess(userUUID, req.Name, services.ActionCreateBucket)
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, req.Name, services.ActionCreateBucket)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -303,9 +434,37 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 		return
 	}
 
-	// Check if bucket already exists in our database
+	if req.NoOverwriteMinutes < 0 || req.NoOverwriteMinutes > maxNoOverwriteMinutes {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid overwrite protection window",
+			Message: fmt.Sprintf("no_overwrite_minutes must be between 0 and %d", maxNoOverwriteMinutes),
+		})
+		return
+	}
+
+	onExisting := req.OnExisting
+	switch onExisting {
+	case "":
+		onExisting = models.OnExistingLink
+	case models.OnExistingLink, models.OnExistingFail, models.OnExistingCreateNew:
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid on_existing",
+			Message: "on_existing must be link, fail or create-new",
+		})
+		return
+	}
+
+	// Check if bucket already exists in our database (a bucket in the trash keeps its name)
 	var existing models.Bucket
-	if err := database.DB.Where("name = ?", req.Name).First(&existing).Error; err == nil {
+	if err := database.DB.Unscoped().Where("name = ?", req.Name).First(&existing).Error; err == nil {
+		if existing.DeletedAt.Valid {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error:   "Bucket name is held by a deleted bucket",
+				Message: "A deleted bucket with this name is awaiting purge. Restore it, or purge it with DELETE /api/admin/deleted-buckets/" + req.Name,
+			})
+			return
+		}
 		c.JSON(http.StatusConflict, models.ErrorResponse{
 			Error: "Bucket already exists in this system",
 		})
@@ -319,9 +478,13 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 		IsPublic:       req.IsPublic,
 		Region:         req.Region,
 		StorageBackend: req.StorageBackend,
+
+		CaseInsensitiveKeys: req.CaseInsensitiveKeys,
+		NoOverwriteMinutes:  req.NoOverwriteMinutes,
 	}
 
 	// Set S3 config ID if provided
+	var warnings []string // Reported by dry runs only
 	if req.S3ConfigID != nil && *req.S3ConfigID != "" {
 		configUUID, err := uuid.Parse(*req.S3ConfigID)
 		if err == nil {
@@ -331,6 +494,9 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 				bucket.S3ConfigID = &configUUID
 			}
 		}
+		if bucket.S3ConfigID == nil {
+			warnings = append(warnings, "s3_config_id does not name an existing S3 configuration and would be ignored")
+		}
 	}
 
 	if bucket.Region == "" {
@@ -343,10 +509,21 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 	}
 
 	// Check if bucket already exists in storage backend (S3 or local)
-	// If it exists and we can access it, we'll "link" to it instead of creating a new one
-	var linkedToExisting bool
+	// If it exists and we can access it, on_existing decides whether we link to it instead of creating a new one
+	action := createBucketActionCreate
 	storageBackend, err := h.getStorageBackend(&bucket)
 	if err == nil {
+		// Backends that prefix names must still produce a legal bucket name (fail here, not opaquely at creation)
+		if validator, ok := storageBackend.(storage.BucketNameValidator); ok {
+			if err := validator.ValidateBucketName(bucket.Name); err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid bucket name for storage backend",
+					Message: err.Error(),
+				})
+				return
+			}
+		}
+
 		exists, checkErr := storageBackend.BucketExists(bucket.Name)
 		if checkErr != nil {
 			// Permission issue - bucket might exist but we can't access it
@@ -356,11 +533,74 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 			})
 			return
 		}
-		linkedToExisting = exists
+		if exists {
+			switch onExisting {
+			case models.OnExistingLink:
+				action = createBucketActionLink
+			case models.OnExistingFail:
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Bucket already exists in storage backend",
+					Message: "The storage backend already has a bucket with this name. Use on_existing=link to adopt it and its contents",
+				})
+				return
+			case models.OnExistingCreateNew:
+				empty, emptyErr := storageBucketEmpty(storageBackend, bucket.Name)
+				if emptyErr != nil {
+					c.JSON(http.StatusForbidden, models.ErrorResponse{
+						Error:   "Cannot access bucket in storage backend",
+						Message: emptyErr.Error(),
+					})
+					return
+				}
+				if !empty {
+					c.JSON(http.StatusConflict, models.ErrorResponse{
+						Error:   "Bucket name already in use",
+						Message: "The storage backend already has a bucket with this name that holds objects. Choose another name, or use on_existing=link to adopt it",
+					})
+					return
+				}
+				// An empty leftover bucket is indistinguishable from a new one
+				action = createBucketActionReuseEmpty
+			}
+		}
+	} else {
+		warnings = append(warnings, fmt.Sprintf("Storage backend unavailable (%v); the bucket would be created in storage on first upload", err))
+	}
+
+	// Governance default: the configured policy template becomes the bucket's initial policy
+	var defaultPolicy string
+	if h.config.Auth.DefaultBucketPolicy != "" {
+		defaultPolicy, err = security.RenderBucketPolicyTemplate(h.config.Auth.DefaultBucketPolicy, bucket.Name)
+		if err != nil {
+			logger.Error("Default bucket policy is invalid", map[string]interface{}{
+				"bucket_name": bucket.Name,
+				"error":       err.Error(),
+			})
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to create bucket",
+				Message: "The configured default bucket policy is invalid for this bucket",
+			})
+			return
+		}
+	}
+
+	// Dry run: every check above has passed, report the outcome without writing anything
+	if c.Query("dry_run") == "true" {
+		respondCreateBucketDryRun(c, &bucket, action, onExisting, warnings)
+		return
 	}
 
-	// Create bucket record in database
-	if err := database.DB.Create(&bucket).Error; err != nil {
+	// Create bucket record in database, together with its default policy
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&bucket).Error; err != nil {
+			return err
+		}
+		if defaultPolicy == "" {
+			return nil
+		}
+		return tx.Create(&models.BucketPolicy{BucketID: bucket.ID, PolicyDocument: defaultPolicy}).Error
+	})
+	if err != nil {
 		// Get user info for audit log
 		username, _ := c.Get("username")
 
@@ -379,6 +619,7 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 				"region":          req.Region,
 				"storage_backend": req.StorageBackend,
 				"is_public":       req.IsPublic,
+				"on_existing":     onExisting,
 			},
 		)
 
@@ -389,8 +630,13 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 		return
 	}
 
+	if defaultPolicy != "" {
+		services.InvalidateBucketPolicyCache(bucket.Name)
+	}
+
 	// If bucket doesn't exist in storage backend, create it
-	if !linkedToExisting && storageBackend != nil {
+	linkedToExisting := action == createBucketActionLink
+	if action == createBucketActionCreate && storageBackend != nil {
 		if err := storageBackend.CreateBucket(bucket.Name, bucket.Region); err != nil {
 			logger.Warn("Failed to create bucket in storage backend", map[string]interface{}{
 				"bucket_name":     bucket.Name,
@@ -406,10 +652,11 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 				"region":          bucket.Region,
 			})
 		}
-	} else if linkedToExisting {
+	} else if action != createBucketActionCreate {
 		logger.Info("Bucket linked to existing storage backend bucket", map[string]interface{}{
 			"bucket_name":     bucket.Name,
 			"storage_backend": bucket.StorageBackend,
+			"action":          action,
 		})
 	}
 
@@ -431,6 +678,11 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 			"storage_backend":   bucket.StorageBackend,
 			"is_public":         bucket.IsPublic,
 			"linked_to_existing": linkedToExisting,
+			"on_existing":        onExisting,
+			"action":             action,
+			"case_insensitive_keys": bucket.CaseInsensitiveKeys,
+			"no_overwrite_minutes":  bucket.NoOverwriteMinutes,
+			"default_bucket_policy": defaultPolicy != "",
 		},
 	)
 
@@ -444,6 +696,17 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 		"storage_backend": bucket.StorageBackend,
 		"created_at":      bucket.CreatedAt,
 		"updated_at":      bucket.UpdatedAt,
+		"on_existing":     onExisting,
+		"action":          action,
+	}
+	if bucket.CaseInsensitiveKeys {
+		response["case_insensitive_keys"] = true
+	}
+	if bucket.NoOverwriteMinutes > 0 {
+		response["no_overwrite_minutes"] = bucket.NoOverwriteMinutes
+	}
+	if defaultPolicy != "" {
+		response["default_policy_applied"] = true
 	}
 
 	if linkedToExisting {
@@ -454,13 +717,71 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
+// CreateBucket outcomes, reported as "action"
+const (
+	createBucketActionCreate     = "create"      // New bucket created in storage
+	createBucketActionLink       = "link"        // Existing storage bucket adopted with its contents
+	createBucketActionReuseEmpty = "reuse-empty" // Existing empty storage bucket adopted (on_existing=create-new)
+)
+
+// storageBucketEmpty reports whether a storage backend bucket holds no objects (only the first page is listed)
+func storageBucketEmpty(backend storage.StorageBackend, bucketName string) (bool, error) {
+	errFound := errors.New("found")
+	err := storage.WalkObjects(backend, bucketName, "", func(page []storage.ObjectInfo) error {
+		if len(page) > 0 {
+			return errFound
+		}
+		return nil
+	})
+	if errors.Is(err, errFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// respondCreateBucketDryRun describes what CreateBucket would do with a request that passed validation
+func respondCreateBucketDryRun(c *gin.Context, bucket *models.Bucket, action, onExisting string, warnings []string) {
+	message := "Bucket would be created"
+	switch action {
+	case createBucketActionLink:
+		message = "Bucket would be linked to existing storage; any existing contents would be accessible"
+	case createBucketActionReuseEmpty:
+		message = "Bucket would reuse an existing empty bucket in storage"
+	}
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":     true,
+		"action":      action,
+		"on_existing": onExisting,
+		"message":     message,
+		"bucket": gin.H{
+			"name":                  bucket.Name,
+			"region":                bucket.Region,
+			"storage_backend":       bucket.StorageBackend,
+			"s3_config_id":          bucket.S3ConfigID,
+			"is_public":             bucket.IsPublic,
+			"case_insensitive_keys": bucket.CaseInsensitiveKeys,
+			"no_overwrite_minutes":  bucket.NoOverwriteMinutes,
+		},
+		"warnings": warnings,
+	})
+}
+
 func (h *BucketHandler) ListBuckets(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userUUID := userID.(uuid.UUID)
 	isAdmin, _ := c.Get("is_admin")
 
+	page, ok := bindPageParams(c, 0, maxPageLimit)
+	if !ok {
+		return
+	}
+
 	var allBuckets []models.Bucket
-	query := database.DB.Preload("Owner")
+	query := database.DB.Preload("Owner").Order("name ASC")
 
 	// Fetch all buckets for filtering
 	if err := query.Find(&allBuckets).Error; err != nil {
@@ -473,7 +794,8 @@ func (h *BucketHandler) ListBuckets(c *gin.Context) {
 
 	// Admin bypass - return all buckets
 	if isAdmin.(bool) {
-		c.JSON(http.StatusOK, allBuckets)
+		pageBuckets := paginateSlice(allBuckets, page)
+		respondPage(c, page, pageBuckets, len(pageBuckets), int64(len(allBuckets)))
 		return
 	}
 
@@ -491,7 +813,7 @@ func (h *BucketHandler) ListBuckets(c *gin.Context) {
 
 	// For each action, perform batch check and collect accessible buckets
 	for _, action := range actions {
-		bucketsWithAccess, err := h.policyService.FilterAccessibleBuckets(userUUID, allBuckets, action)
+		bucketsWithAccess, err := h.policyService.FilterAccessibleBuckets(c, userUUID, allBuckets, action)
 		if err != nil {
 			// Log error but continue with other actions
 			continue
@@ -507,8 +829,11 @@ func (h *BucketHandler) ListBuckets(c *gin.Context) {
 	for _, bucket := range accessibleBucketMap {
 		accessibleBuckets = append(accessibleBuckets, bucket)
 	}
+	sort.Slice(accessibleBuckets, func(i, j int) bool { return accessibleBuckets[i].Name < accessibleBuckets[j].Name })
 
-	c.JSON(http.StatusOK, accessibleBuckets)
+	// Permissions are evaluated in memory, so the page is cut from the filtered list
+	pageBuckets := paginateSlice(accessibleBuckets, page)
+	respondPage(c, page, pageBuckets, len(pageBuckets), int64(len(accessibleBuckets)))
 }
 
 func (h *BucketHandler) GetBucket(c *gin.Context) {
@@ -525,7 +850,7 @@ func (h *BucketHandler) GetBucket(c *gin.Context) {
 	}
 
 	// Check policy permissions
-	allowed, err := h.policyService.CheckBucketAccess(userUUID, bucketName, services.ActionGetBucketLocation)
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionGetBucketLocation)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -544,6 +869,112 @@ func (h *BucketHandler) GetBucket(c *gin.Context) {
 	c.JSON(http.StatusOK, bucket)
 }
 
+// HeadBucket returns bucket region and object count/size as headers without a body
+// Lightweight existence-plus-summary probe (mirrors S3 HeadBucket)
+func (h *BucketHandler) HeadBucket(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionListBucket)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	setBucketSummaryHeaders(c, &bucket)
+	c.Status(http.StatusOK)
+}
+
+// bucketStatsCacheEntry caches a bucket's object count and total size
+type bucketStatsCacheEntry struct {
+	ObjectCount int64
+	TotalSize   int64
+	ExpiresAt   time.Time
+}
+
+// Bucket stats are cached briefly so frequent HEAD probes don't aggregate the objects table each time
+var (
+	bucketStatsCache    = make(map[uuid.UUID]*bucketStatsCacheEntry)
+	bucketStatsCacheMu  sync.RWMutex
+	bucketStatsCacheTTL = 30 * time.Second
+)
+
+// bucketStatsTimeout bounds the aggregate over a bucket's objects; on very large buckets the
+// HEAD probe then answers without count headers rather than holding a connection
+const bucketStatsTimeout = 10 * time.Second
+
+// getBucketStats returns the object count and total size of a bucket (cached)
+func getBucketStats(bucketID uuid.UUID) (int64, int64, error) {
+	bucketStatsCacheMu.RLock()
+	entry, exists := bucketStatsCache[bucketID]
+	bucketStatsCacheMu.RUnlock()
+	if exists && time.Now().Before(entry.ExpiresAt) {
+		return entry.ObjectCount, entry.TotalSize, nil
+	}
+
+	var result struct {
+		ObjectCount int64
+		TotalSize   int64
+	}
+	db, cancel := database.WithTimeout(bucketStatsTimeout)
+	defer cancel()
+	if err := db.Model(&models.Object{}).
+		Select("COUNT(*) AS object_count, COALESCE(SUM(size), 0) AS total_size").
+		Where("bucket_id = ?", bucketID).
+		Scan(&result).Error; err != nil {
+		return 0, 0, err
+	}
+
+	bucketStatsCacheMu.Lock()
+	bucketStatsCache[bucketID] = &bucketStatsCacheEntry{
+		ObjectCount: result.ObjectCount,
+		TotalSize:   result.TotalSize,
+		ExpiresAt:   time.Now().Add(bucketStatsCacheTTL),
+	}
+	bucketStatsCacheMu.Unlock()
+
+	return result.ObjectCount, result.TotalSize, nil
+}
+
+// bucketRegion returns the region reported for a bucket (same value GetBucketLocation would use)
+func bucketRegion(bucket *models.Bucket) string {
+	if bucket.Region == "" {
+		return "us-east-1"
+	}
+	return bucket.Region
+}
+
+// setBucketSummaryHeaders sets the region and object count/size headers used by HEAD bucket
+// Count headers are omitted if the stats query fails; the probe itself still succeeds
+func setBucketSummaryHeaders(c *gin.Context, bucket *models.Bucket) {
+	c.Header("x-amz-bucket-region", bucketRegion(bucket))
+	if bucket.MaxObjects > 0 {
+		c.Header("X-Bkt-Max-Objects", strconv.FormatInt(bucket.MaxObjects, 10))
+	}
+
+	count, size, err := getBucketStats(bucket.ID)
+	if err != nil {
+		logger.Warn("Failed to compute bucket stats", map[string]interface{}{
+			"bucket": bucket.Name,
+			"error":  err.Error(),
+		})
+		return
+	}
+	c.Header("X-Bkt-Object-Count", strconv.FormatInt(count, 10))
+	c.Header("X-Bkt-Bytes-Used", strconv.FormatInt(size, 10))
+}
+
 func (h *BucketHandler) DeleteBucket(c *gin.Context) {
 	bucketName := c.Param("name")
 	userID, _ := c.Get("user_id")
@@ -559,7 +990,7 @@ func (h *BucketHandler) DeleteBucket(c *gin.Context) {
 	}
 
 	// Check policy permissions
-	allowed, err := h.policyService.CheckBucketAccess(userUUID, bucketName, services.ActionDeleteBucket)
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionDeleteBucket)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -575,62 +1006,13 @@ func (h *BucketHandler) DeleteBucket(c *gin.Context) {
 		return
 	}
 
-	// Get storage backend for this bucket
-	storageBackend, err := h.getStorageBackend(&bucket)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to get storage backend",
-			Message: err.Error(),
-		})
-		return
-	}
-
-	// Get all objects in the bucket
-	var objects []models.Object
-	if err := database.DB.Where("bucket_id = ?", bucket.ID).Find(&objects).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to list bucket objects",
-			Message: err.Error(),
-		})
+	// With a grace period the bucket only moves to the trash; force=true deletes it right away
+	if h.config.Storage.BucketDeleteGraceDuration > 0 && c.Query("force") != "true" {
+		h.trashBucket(c, &bucket)
 		return
 	}
 
-	// Delete all objects from storage first
-	var storageErrors []string
-	for _, obj := range objects {
-		if err := storageBackend.DeleteObject(bucketName, obj.Key); err != nil {
-			// Log error but continue - we'll still try to delete the rest
-			storageErrors = append(storageErrors, fmt.Sprintf("%s: %v", obj.Key, err))
-		}
-	}
-
-	// Delete the bucket from storage backend (after objects are removed)
-	if err := storageBackend.DeleteBucket(bucketName); err != nil {
-		storageErrors = append(storageErrors, fmt.Sprintf("bucket deletion: %v", err))
-	}
-
-	// Use transaction to delete all objects and the bucket from database
-	err = database.DB.Transaction(func(tx *gorm.DB) error {
-		// Delete all objects from database
-		if len(objects) > 0 {
-			if err := tx.Where("bucket_id = ?", bucket.ID).Delete(&models.Object{}).Error; err != nil {
-				return fmt.Errorf("failed to delete objects: %w", err)
-			}
-		}
-
-		// Delete any bucket policies
-		if err := tx.Where("bucket_id = ?", bucket.ID).Delete(&models.BucketPolicy{}).Error; err != nil {
-			return fmt.Errorf("failed to delete bucket policies: %w", err)
-		}
-
-		// Delete the bucket
-		if err := tx.Delete(&bucket).Error; err != nil {
-			return fmt.Errorf("failed to delete bucket: %w", err)
-		}
-
-		return nil
-	})
-
+	objectCount, storageErrors, err := h.purgeBucket(&bucket)
 	if err != nil {
 		// Log failure
 		h.auditService.LogFailure(
@@ -645,7 +1027,7 @@ func (h *BucketHandler) DeleteBucket(c *gin.Context) {
 			map[string]interface{}{
 				"bucket_name":    bucket.Name,
 				"owner_id":       bucket.OwnerID.String(),
-				"objects_count":  len(objects),
+				"objects_count":  objectCount,
 				"storage_errors": storageErrors,
 			},
 		)
@@ -657,6 +1039,8 @@ func (h *BucketHandler) DeleteBucket(c *gin.Context) {
 		return
 	}
 
+	services.InvalidateBucketPolicyCache(bucket.Name)
+
 	// Log success
 	h.auditService.LogSuccess(
 		c,
@@ -669,12 +1053,13 @@ func (h *BucketHandler) DeleteBucket(c *gin.Context) {
 		map[string]interface{}{
 			"bucket_name":     bucket.Name,
 			"owner_id":        bucket.OwnerID.String(),
-			"objects_deleted": len(objects),
+			"objects_deleted": objectCount,
+			"force":           c.Query("force") == "true",
 		},
 	)
 
 	c.JSON(http.StatusOK, models.SuccessResponse{
-		Message: fmt.Sprintf("Bucket deleted successfully (%d objects removed)", len(objects)),
+		Message: fmt.Sprintf("Bucket deleted successfully (%d objects removed)", objectCount),
 	})
 }
 
@@ -684,7 +1069,7 @@ func (h *BucketHandler) SetBucketPolicy(c *gin.Context) {
 	userUUID := userID.(uuid.UUID)
 
 	// Check policy permissions - must have PutBucketPolicy permission
-	allowed, err := h.policyService.CheckBucketAccess(userUUID, bucketName, services.ActionPutBucketPolicy)
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionPutBucketPolicy)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -725,13 +1110,49 @@ func (h *BucketHandler) SetBucketPolicy(c *gin.Context) {
 	})
 }
 
+// DeleteBucketPolicy removes a bucket's policy, e.g. the default policy it was created with
+func (h *BucketHandler) DeleteBucketPolicy(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	// Removing a policy is a policy change - same permission as setting one
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionPutBucketPolicy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to delete bucket policy",
+		})
+		return
+	}
+
+	if err := h.policyService.DeleteBucketPolicy(bucketName); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "Failed to delete bucket policy",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Bucket policy deleted successfully",
+	})
+}
+
 func (h *BucketHandler) GetBucketPolicy(c *gin.Context) {
 	bucketName := c.Param("name")
 	userID, _ := c.Get("user_id")
 	userUUID := userID.(uuid.UUID)
 
 	// Check policy permissions - must have GetBucketPolicy permission
-	allowed, err := h.policyService.CheckBucketAccess(userUUID, bucketName, services.ActionGetBucketPolicy)
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionGetBucketPolicy)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -777,7 +1198,7 @@ func (h *BucketHandler) ListObjects(c *gin.Context) {
 	}
 
 	// Check policy permissions
-	allowed, err := h.policyService.CheckBucketAccess(userUUID, bucketName, services.ActionListBucket)
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionListBucket)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -794,14 +1215,73 @@ func (h *BucketHandler) ListObjects(c *gin.Context) {
 	}
 
 	// Query parameters for pagination and filtering
-	prefix := c.DefaultQuery("prefix", "")
+	prefix := bucket.NormalizeKey(c.DefaultQuery("prefix", ""))
 	maxKeys := 1000
-	if mk := c.Query("max-keys"); mk != "" {
+	mk := c.Query("max-keys")
+	if mk == "" {
+		mk = c.Query("limit") // Name used by the other list endpoints
+	}
+	if mk != "" {
 		if parsed, err := strconv.Atoi(mk); err == nil && parsed > 0 && parsed <= 1000 {
 			maxKeys = parsed
 		}
 	}
 
+	// Optional last-modified and size filters
+	filter, err := parseObjectListFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid filter",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Optional ordering by size or last-modified instead of key
+	listSort, err := parseObjectListSort(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid sort",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Keyset pagination: the next page starts after the last key of the previous one
+	// ("marker" is accepted as an alias, matching S3 ListObjects v1)
+	startAfter := c.Query("start-after")
+	if startAfter == "" {
+		startAfter = c.Query("marker")
+	}
+	startAfter = bucket.NormalizeKey(startAfter)
+
+	// Other sorts can't resume from a key, so they page by offset
+	offset := 0
+	if value := c.Query("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid offset",
+				Message: "offset must be a non-negative number",
+			})
+			return
+		}
+	}
+	if listSort.keyset() && offset > 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid pagination",
+			Message: "Sorting by key pages with start-after; offset only applies to sort=size or sort=modified",
+		})
+		return
+	}
+	if !listSort.keyset() && startAfter != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid pagination",
+			Message: "start-after only applies to sort=key; use offset with sort=size or sort=modified",
+		})
+		return
+	}
+
 	// Get objects from database
 	query := database.DB.Where("bucket_id = ?", bucket.ID)
 	if prefix != "" {
@@ -809,15 +1289,60 @@ func (h *BucketHandler) ListObjects(c *gin.Context) {
 		escapedPrefix := validation.EscapeLikeWildcards(prefix)
 		query = query.Where("key LIKE ?", escapedPrefix+"%")
 	}
+	query = filter.apply(query)
+
+	// The enveloped response reports the number of matching objects across all pages
+	envelope := c.Query("envelope") == "true"
+	var total int64
+	if envelope {
+		if err := query.Session(&gorm.Session{}).Model(&models.Object{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to list objects",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	if startAfter != "" {
+		if listSort.desc {
+			query = query.Where("key < ?", startAfter)
+		} else {
+			query = query.Where("key > ?", startAfter)
+		}
+	}
 
+	// Fetch one extra row to know whether another page follows
 	var objects []models.Object
-	if err := query.Limit(maxKeys).Order("key ASC").Find(&objects).Error; err != nil {
+	if err := query.Limit(maxKeys + 1).Offset(offset).Order(listSort.orderClause()).Find(&objects).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to list objects",
 			Message: err.Error(),
 		})
 		return
 	}
+	hasMore := len(objects) > maxKeys
+	if hasMore {
+		objects = objects[:maxKeys]
+	}
+
+	// Cursor for the next page; callers pass it back as start-after while has_more is true
+	lastKey := ""
+	if len(objects) > 0 {
+		lastKey = objects[len(objects)-1].Key
+	}
+
+	// Keys outside this page's range belong to other pages and must not be merged in by the S3 sync.
+	// Page ranges are only known for the default key ascending order
+	inPage := func(key string) bool {
+		if listSort.field != objectSortKey || listSort.desc {
+			return false
+		}
+		if startAfter != "" && key <= startAfter {
+			return false
+		}
+		return !hasMore || key <= lastKey
+	}
 
 	// Sync with actual storage backend (S3 or local)
 	// This handles both:
@@ -877,6 +1402,14 @@ func (h *BucketHandler) ListObjects(c *gin.Context) {
 				// Batch size of 100 balances memory usage vs query count
 				const batchSize = 100
 				if len(newObjects) > 0 {
+					// Only objects matching the filters are returned, but all are synced
+					matching := make([]models.Object, 0, len(newObjects))
+					for _, obj := range newObjects {
+						if filter.matches(&obj) && inPage(obj.Key) {
+							matching = append(matching, obj)
+						}
+					}
+
 					go func(objs []models.Object) {
 						// Process in batches to avoid huge queries
 						for i := 0; i < len(objs); i += batchSize {
@@ -905,7 +1438,7 @@ func (h *BucketHandler) ListObjects(c *gin.Context) {
 					}(newObjects)
 
 					// Add to response immediately (don't wait for DB)
-					objects = append(objects, newObjects...)
+					objects = append(objects, matching...)
 				}
 
 				// Find objects in database but not in S3 (need to remove)
@@ -935,16 +1468,301 @@ func (h *BucketHandler) ListObjects(c *gin.Context) {
 				}
 
 				objects = validObjects
+				sort.Slice(objects, func(i, j int) bool { return listSort.less(&objects[i], &objects[j]) })
 			}
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"bucket":  bucketName,
-		"objects": objects,
-		"count":   len(objects),
-	})
-}
+	// On the final page the S3 sync may have added keys past the last database row
+	if !hasMore && len(objects) > 0 {
+		lastKey = objects[len(objects)-1].Key
+	}
+
+	response := gin.H{
+		"bucket":   bucketName,
+		"objects":  objects,
+		"count":    len(objects),
+		"last_key": lastKey,
+		"has_more": hasMore,
+	}
+
+	// With a delimiter, keys below the next delimiter collapse into folder entries (like S3 common prefixes)
+	if delimiter := c.Query("delimiter"); delimiter != "" {
+		entries := groupObjectListing(objects, prefix, delimiter)
+		response["prefix"] = prefix
+		response["delimiter"] = delimiter
+		response["objects"] = entries
+		response["count"] = len(entries)
+	}
+
+	// The envelope uses the shared list fields (see PaginatedResponse) and keeps the
+	// object-list extras; total counts objects, before any delimiter grouping
+	if envelope {
+		response["items"] = response["objects"]
+		response["total"] = total
+		response["limit"] = maxKeys
+		response["offset"] = offset
+		delete(response, "objects")
+		delete(response, "count")
+	}
+
+	// Offset-paged sorts continue from next_offset instead of last_key
+	if !listSort.keyset() && hasMore {
+		response["next_offset"] = offset + maxKeys
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListObjects sort fields
+const (
+	objectSortKey      = "key"
+	objectSortSize     = "size"
+	objectSortModified = "modified"
+)
+
+// objectListSort is the ListObjects ordering chosen with ?sort and ?order
+type objectListSort struct {
+	field string
+	desc  bool
+}
+
+// parseObjectListSort reads ?sort (key, size or modified) and ?order (asc or desc). Key sorts
+// default to ascending, size and modified sorts to descending (largest or newest first)
+func parseObjectListSort(c *gin.Context) (*objectListSort, error) {
+	listSort := &objectListSort{field: c.DefaultQuery("sort", objectSortKey)}
+	switch listSort.field {
+	case objectSortKey:
+	case objectSortSize, objectSortModified:
+		listSort.desc = true
+	default:
+		return nil, fmt.Errorf("sort must be key, size or modified")
+	}
+
+	switch c.Query("order") {
+	case "":
+	case "asc":
+		listSort.desc = false
+	case "desc":
+		listSort.desc = true
+	default:
+		return nil, fmt.Errorf("order must be asc or desc")
+	}
+
+	return listSort, nil
+}
+
+// keyset reports whether pages are resumed from the last key (start-after) rather than an offset
+func (s *objectListSort) keyset() bool {
+	return s.field == objectSortKey
+}
+
+// orderClause is the SQL ORDER BY over indexed columns; key breaks ties so offset pages are stable
+func (s *objectListSort) orderClause() string {
+	direction := " ASC"
+	if s.desc {
+		direction = " DESC"
+	}
+	switch s.field {
+	case objectSortSize:
+		return "size" + direction + ", key ASC"
+	case objectSortModified:
+		return "updated_at" + direction + ", key ASC"
+	default:
+		return "key" + direction
+	}
+}
+
+// less orders two objects the same way as orderClause
+func (s *objectListSort) less(a, b *models.Object) bool {
+	switch {
+	case s.field == objectSortSize && a.Size != b.Size:
+		return (a.Size < b.Size) != s.desc
+	case s.field == objectSortModified && !a.UpdatedAt.Equal(b.UpdatedAt):
+		return a.UpdatedAt.Before(b.UpdatedAt) != s.desc
+	case s.field == objectSortKey:
+		return (a.Key < b.Key) != s.desc
+	default:
+		return a.Key < b.Key
+	}
+}
+
+// folderMarkerName is the zero-byte object that keeps an otherwise empty folder visible
+const folderMarkerName = ".keep"
+
+// objectListEntry is a delimited listing entry: a file (with its object fields) or a folder
+type objectListEntry struct {
+	Type string `json:"type"` // "file" or "folder"
+	Key  string `json:"key"`  // Folder keys end with the delimiter
+	*models.Object
+}
+
+// groupObjectListing collapses keys into pseudo-directories at the next delimiter after prefix
+// Folders that only contain a .keep marker still appear; the markers themselves are hidden
+func groupObjectListing(objects []models.Object, prefix, delimiter string) []objectListEntry {
+	entries := make([]objectListEntry, 0, len(objects))
+	seenFolders := make(map[string]bool)
+
+	for i := range objects {
+		obj := &objects[i]
+		rest := strings.TrimPrefix(obj.Key, prefix)
+
+		if idx := strings.Index(rest, delimiter); idx >= 0 {
+			folder := prefix + rest[:idx+len(delimiter)]
+			if !seenFolders[folder] {
+				seenFolders[folder] = true
+				entries = append(entries, objectListEntry{Type: "folder", Key: folder})
+			}
+			continue
+		}
+
+		// Marker of the folder being listed
+		if rest == folderMarkerName {
+			continue
+		}
+
+		entries = append(entries, objectListEntry{Type: "file", Key: obj.Key, Object: obj})
+	}
+
+	return entries
+}
+
+// objectListMetadataPrefix marks a ListObjects query parameter as a custom metadata filter (?meta.project=alpha)
+const objectListMetadataPrefix = "meta."
+
+// maxObjectListMetadataFilters caps the number of metadata filters in one listing
+const maxObjectListMetadataFilters = 10
+
+// objectListFilter holds the optional last-modified, size and metadata filters for ListObjects
+type objectListFilter struct {
+	modifiedSince  *time.Time
+	modifiedBefore *time.Time
+	minSize        *int64
+	maxSize        *int64
+	metadata       map[string]string // All must match (AND)
+}
+
+// parseObjectListFilter reads ?modified-since, ?modified-before, ?min-size, ?max-size and ?meta.<key>
+// Timestamps accept RFC3339 or YYYY-MM-DD, sizes are in bytes
+func parseObjectListFilter(c *gin.Context) (*objectListFilter, error) {
+	filter := &objectListFilter{}
+
+	parseTime := func(name string) (*time.Time, error) {
+		value := c.Query(name)
+		if value == "" {
+			return nil, nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return &t, nil
+		}
+		if t, err := time.Parse("2006-01-02", value); err == nil {
+			return &t, nil
+		}
+		return nil, fmt.Errorf("%s must be an RFC3339 timestamp or YYYY-MM-DD date", name)
+	}
+
+	parseSize := func(name string) (*int64, error) {
+		value := c.Query(name)
+		if value == "" {
+			return nil, nil
+		}
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative number of bytes", name)
+		}
+		return &size, nil
+	}
+
+	var err error
+	if filter.modifiedSince, err = parseTime("modified-since"); err != nil {
+		return nil, err
+	}
+	if filter.modifiedBefore, err = parseTime("modified-before"); err != nil {
+		return nil, err
+	}
+	if filter.minSize, err = parseSize("min-size"); err != nil {
+		return nil, err
+	}
+	if filter.maxSize, err = parseSize("max-size"); err != nil {
+		return nil, err
+	}
+
+	if filter.minSize != nil && filter.maxSize != nil && *filter.minSize > *filter.maxSize {
+		return nil, fmt.Errorf("min-size cannot be greater than max-size")
+	}
+
+	for name, values := range c.Request.URL.Query() {
+		if !strings.HasPrefix(name, objectListMetadataPrefix) {
+			continue
+		}
+		metaKey := strings.TrimPrefix(name, objectListMetadataPrefix)
+		if metaKey == "" {
+			return nil, fmt.Errorf("%s needs a metadata key, e.g. meta.project=alpha", name)
+		}
+		if len(values) > 1 {
+			return nil, fmt.Errorf("%s can only be given once", name)
+		}
+		if filter.metadata == nil {
+			filter.metadata = make(map[string]string)
+		}
+		filter.metadata[metaKey] = values[0]
+	}
+	if len(filter.metadata) > maxObjectListMetadataFilters {
+		return nil, fmt.Errorf("at most %d metadata filters are allowed", maxObjectListMetadataFilters)
+	}
+
+	return filter, nil
+}
+
+// apply adds the filters to the objects query WHERE clause
+func (f *objectListFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.modifiedSince != nil {
+		query = query.Where("updated_at >= ?", *f.modifiedSince)
+	}
+	if f.modifiedBefore != nil {
+		query = query.Where("updated_at < ?", *f.modifiedBefore)
+	}
+	if f.minSize != nil {
+		query = query.Where("size >= ?", *f.minSize)
+	}
+	if f.maxSize != nil {
+		query = query.Where("size <= ?", *f.maxSize)
+	}
+	if len(f.metadata) > 0 {
+		// A single containment test covers every filter and can use the GIN index on metadata
+		contained, _ := json.Marshal(f.metadata)
+		query = query.Where("metadata @> ?::jsonb", string(contained))
+	}
+	return query
+}
+
+// matches checks an object not loaded from the database (e.g. discovered during S3 sync)
+func (f *objectListFilter) matches(obj *models.Object) bool {
+	if f.modifiedSince != nil && obj.UpdatedAt.Before(*f.modifiedSince) {
+		return false
+	}
+	if f.modifiedBefore != nil && !obj.UpdatedAt.Before(*f.modifiedBefore) {
+		return false
+	}
+	if f.minSize != nil && obj.Size < *f.minSize {
+		return false
+	}
+	if f.maxSize != nil && obj.Size > *f.maxSize {
+		return false
+	}
+	if len(f.metadata) > 0 {
+		var metadata map[string]interface{}
+		if obj.Metadata == nil || json.Unmarshal([]byte(*obj.Metadata), &metadata) != nil {
+			return false
+		}
+		for key, want := range f.metadata {
+			if got, ok := metadata[key].(string); !ok || got != want {
+				return false
+			}
+		}
+	}
+	return true
+}
 
 func (h *BucketHandler) UploadObject(c *gin.Context) {
 	bucketName := c.Param("name")
@@ -981,8 +1799,56 @@ func (h *BucketHandler) UploadObject(c *gin.Context) {
 		return
 	}
 
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Auto-date-prefix buckets store uploads under the upload date (e.g. 2026/10/16/)
+	objectKey, err := applyAutoDatePrefix(&bucket, objectKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid object key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Governance key rules (e.g. only logs/*.gz), checked against the stored key
+	if !respondKeyRules(c, &bucket, objectKey) {
+		return
+	}
+
+	// Object ACL (defaults to inheriting the bucket setting)
+	acl, err := objectACLFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid ACL",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Optional per-object TTL (X-Expires-After header or expires_at form field)
+	expiresAt, err := objectExpiryFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid expiration",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// User metadata (x-amz-meta-* headers), replacing any the object had
+	metadata, err := objectMetadataFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid metadata",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	// Check policy permissions
-	allowed, err := h.policyService.CheckObjectAccess(userUUID, bucketName, objectKey, services.ActionPutObject)
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, objectKey, services.ActionPutObject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -998,6 +1864,36 @@ func (h *BucketHandler) UploadObject(c *gin.Context) {
 		return
 	}
 
+	// Serialize concurrent writes to the same key so bytes and metadata always match (last writer wins)
+	unlockKey, err := lockObjectKey(bucket.ID, objectKey, objectKeyLockTimeout)
+	if err != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Upload in progress",
+			Message: err.Error(),
+		})
+		return
+	}
+	releaseKey := true
+	defer func() {
+		if releaseKey {
+			unlockKey()
+		}
+	}()
+
+	// Recently created objects may be protected from overwrites
+	if err := checkOverwriteWindow(c, &bucket, objectKey); err != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Object is overwrite-protected",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// New keys can't push the bucket past its object count cap
+	if !respondObjectLimit(c, &bucket, objectKey) {
+		return
+	}
+
 	// Get uploaded file
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
@@ -1057,6 +1953,7 @@ func (h *BucketHandler) UploadObject(c *gin.Context) {
 		})
 		return
 	}
+	detectedType = validation.RefineContentType(detectedType, firstBytes, objectKey, h.config.Storage.ExtensionContentTypes)
 
 	// Validate content type is safe
 	if !validation.IsSafeContentType(detectedType) {
@@ -1067,12 +1964,31 @@ func (h *BucketHandler) UploadObject(c *gin.Context) {
 		return
 	}
 
-	// Use detected content type (from magic numbers, not from client header)
-	contentType := detectedType
+	// Use detected content type (from magic numbers), unless the client declared a trusted type
+	contentType := validation.ResolveContentType(detectedType, fileHeader.Header.Get("Content-Type"), h.config.Storage.TrustedContentTypes)
+
+	if err := h.checkContentTypeSize(fileHeader.Size, detectedType, contentType); err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error:   "File too large for its type",
+			Message: err.Error(),
+		})
+		return
+	}
 
 	// Create MultiReader to prepend the first bytes back to the stream
 	combinedReader := io.MultiReader(bytes.NewReader(firstBytes), file)
 
+	// Bound how far gzip content may expand when decompressed (decompression bomb guard)
+	if validation.IsGzipContentType(detectedType) {
+		guard := validation.NewGzipGuardReader(combinedReader, h.decompressionLimit(fileHeader.Size))
+		defer guard.Close()
+		combinedReader = guard
+	}
+
+	// Hash the content inline as it streams to the backend (avoids a second full read)
+	hasher := sha256.New()
+	combinedReader = io.TeeReader(combinedReader, hasher)
+
 	// Get storage backend for this bucket
 	storageBackend, err := h.getStorageBackend(&bucket)
 	if err != nil {
@@ -1083,6 +1999,29 @@ func (h *BucketHandler) UploadObject(c *gin.Context) {
 		return
 	}
 
+	// Fail fast when the backend can't hold the file
+	if err := storage.CheckSpace(storageBackend, bucketName, fileHeader.Size); err != nil {
+		respondStorageWriteError(c, bucketName, objectKey, err, "Failed to save object")
+		return
+	}
+
+	// Versioned buckets keep the content being replaced as a previous version, which is put
+	// back as the live object if the write or the metadata save fails
+	previous, err := archiveCurrentVersion(storageBackend, &bucket, objectKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to keep the previous version",
+			Message: err.Error(),
+		})
+		return
+	}
+	keepPrevious := false
+	defer func() {
+		if previous != nil && !keepPrevious {
+			restoreArchivedVersion(storageBackend, bucketName, previous)
+		}
+	}()
+
 	// Save object using storage backend with timeout (prevents indefinite blocking on large uploads)
 	// Use 10 minute timeout for uploads (configurable based on max file size)
 	uploadTimeout := 10 * time.Minute
@@ -1103,14 +2042,27 @@ func (h *BucketHandler) UploadObject(c *gin.Context) {
 	// Wait for upload or timeout
 	select {
 	case result := <-resultChan:
-		if result.err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to save object",
+		// A rejected write leaves the key as it was (PutObject never stores partial content)
+		if errors.Is(result.err, validation.ErrDecompressionLimit) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+				Error:   "Compressed content expands too far",
 				Message: result.err.Error(),
 			})
 			return
 		}
+		if result.err != nil {
+			respondStorageWriteError(c, bucketName, objectKey, result.err, "Failed to save object")
+			return
+		}
 	case <-ctx.Done():
+		// The abandoned write may still replace the content, so the previous version is kept
+		keepPrevious = true
+		// Keep the key locked until the abandoned write actually finishes
+		releaseKey = false
+		go func() {
+			<-resultChan
+			unlockKey()
+		}()
 		c.JSON(http.StatusRequestTimeout, models.ErrorResponse{
 			Error:   "Upload timeout",
 			Message: fmt.Sprintf("Upload exceeded timeout of %v", uploadTimeout),
@@ -1137,36 +2089,51 @@ func (h *BucketHandler) UploadObject(c *gin.Context) {
 		ContentType: objectInfo.ContentType,
 		ETag:        objectInfo.ETag,
 		StoragePath: objectKey,
-		SHA256:      "",
+		SHA256:      hex.EncodeToString(hasher.Sum(nil)),
+		ACL:         acl,
+		UploadedBy:  &userUUID,
+		ExpiresAt:   expiresAt,
+		Metadata:    metadata,
+		VersionID:   newVersionID(&bucket),
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 
 	// PostgreSQL UPSERT: INSERT with ON CONFLICT UPDATE
 	// This reduces 2 queries (SELECT + INSERT/UPDATE) to 1 query
-	err = database.DB.Exec(`
-		INSERT INTO objects (id, bucket_id, key, size, content_type, e_tag, storage_path, sha256, created_at, updated_at)
-		VALUES (gen_random_uuid(), ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT (bucket_id, key)
-		DO UPDATE SET
-			size = EXCLUDED.size,
-			content_type = EXCLUDED.content_type,
-			e_tag = EXCLUDED.e_tag,
-			storage_path = EXCLUDED.storage_path,
-			sha256 = EXCLUDED.sha256,
-			updated_at = EXCLUDED.updated_at
-	`, object.BucketID, object.Key, object.Size, object.ContentType, object.ETag,
-		object.StoragePath, object.SHA256, object.CreatedAt, object.UpdatedAt).Error
-
+	// The stored file is removed if the metadata write doesn't commit (unless it replaced an object)
+	err = runStorageTxn(func(tx *gorm.DB, st *storageTxn) error {
+		st.onRollback(func() { discardUnsavedObject(storageBackend, bucket.ID, bucketName, objectKey) })
+
+		return tx.Exec(`
+			INSERT INTO objects (id, bucket_id, key, size, content_type, e_tag, storage_path, sha256, acl, uploaded_by, expires_at, metadata, version_id, created_at, updated_at)
+			VALUES (gen_random_uuid(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (bucket_id, key)
+			DO UPDATE SET
+				size = EXCLUDED.size,
+				content_type = EXCLUDED.content_type,
+				e_tag = EXCLUDED.e_tag,
+				storage_path = EXCLUDED.storage_path,
+				sha256 = EXCLUDED.sha256,
+				checksum_algorithm = '',
+				checksum = '',
+				acl = EXCLUDED.acl,
+				uploaded_by = EXCLUDED.uploaded_by,
+				expires_at = EXCLUDED.expires_at,
+				metadata = EXCLUDED.metadata,
+				version_id = EXCLUDED.version_id,
+				updated_at = EXCLUDED.updated_at
+		`, object.BucketID, object.Key, object.Size, object.ContentType, object.ETag,
+			object.StoragePath, object.SHA256, object.ACL, object.UploadedBy, object.ExpiresAt, object.Metadata, object.VersionID, object.CreatedAt, object.UpdatedAt).Error
+	})
 	if err != nil {
-		// Clean up file if database operation fails
-		storageBackend.DeleteObject(bucketName, objectKey)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to save object metadata",
 			Message: err.Error(),
 		})
 		return
 	}
+	keepPrevious = true
 
 	// Retrieve the object to get the ID and timestamps for response
 	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
@@ -1174,14 +2141,130 @@ func (h *BucketHandler) UploadObject(c *gin.Context) {
 		// The file is successfully stored, just return success without full details
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message":      "Object uploaded successfully",
 		"bucket":       bucketName,
 		"key":          objectKey,
 		"size":         objectInfo.Size,
 		"etag":         objectInfo.ETag,
 		"content_type": objectInfo.ContentType,
-	})
+		"acl":          acl,
+		"expires_at":   expiresAt,
+	}
+	if object.VersionID != "" {
+		response["version_id"] = object.VersionID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// objectACLFromRequest reads the object ACL from the "acl" form field or x-amz-acl header
+func objectACLFromRequest(c *gin.Context) (string, error) {
+	value := c.PostForm("acl")
+	if value == "" {
+		value = c.GetHeader("x-amz-acl")
+	}
+	return models.ParseObjectACL(value)
+}
+
+// responseHeaderOverrides reads the S3-style response-content-type and
+// response-content-disposition query parameters, validating both. On a presigned URL they
+// are part of the signature (PresignedURLMiddleware), so only session callers and the
+// URL's issuer can choose them
+func responseHeaderOverrides(c *gin.Context) (contentType, disposition string, err error) {
+	contentType = c.Query("response-content-type")
+	if contentType != "" {
+		if err := validation.ValidateContentTypeOverride(contentType); err != nil {
+			return "", "", err
+		}
+	}
+
+	disposition = c.Query("response-content-disposition")
+	if disposition != "" {
+		if err := validation.ValidateContentDisposition(disposition); err != nil {
+			return "", "", err
+		}
+	}
+
+	return contentType, disposition, nil
+}
+
+// objectSandboxPolicy keeps object content from running script or loading resources
+// with the API origin's privileges if it is ever rendered by a browser
+const objectSandboxPolicy = "sandbox; default-src 'none'"
+
+// applyObjectSecurityHeaders hardens a response serving object content. Active types (HTML, SVG,
+// XML, JavaScript) are forced to download and sandboxed, even when an inline disposition was
+// requested. Returns the Content-Disposition to send (empty leaves it unset)
+func applyObjectSecurityHeaders(c *gin.Context, contentType, disposition, objectKey string) string {
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	if !validation.IsActiveContentType(contentType) {
+		// Passive content (images, PDFs, text, ...) may render inline; the API-wide CSP would break viewers
+		c.Writer.Header().Del("Content-Security-Policy")
+		return disposition
+	}
+
+	c.Header("Content-Security-Policy", objectSandboxPolicy)
+	if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(disposition)), "attachment") {
+		disposition = attachmentDisposition(objectKey)
+	}
+	return disposition
+}
+
+// attachmentDisposition builds an attachment Content-Disposition for an object, named after the
+// last segment of its key. Keys are user-controlled, so control and bidi override characters are
+// dropped, "." and ".." fall back to a generic name and, per RFC 6266, the quoted filename is an
+// ASCII fallback (non-ASCII replaced, quotes and backslashes escaped) with the real name
+// percent-encoded in filename*
+func attachmentDisposition(objectKey string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r) {
+			return -1 // CR/LF would split the header; bidi overrides can disguise the extension
+		}
+		return r
+	}, filepath.Base(objectKey))
+	if name == "." || name == ".." || name == "/" {
+		name = ""
+	}
+
+	var fallback strings.Builder
+	ascii := true
+	for _, r := range name {
+		switch {
+		case r == '"' || r == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(r)
+		case r > 0x7e:
+			ascii = false
+			fallback.WriteByte('_')
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+	if fallback.Len() == 0 {
+		fallback.WriteString("download")
+	}
+
+	disposition := `attachment; filename="` + fallback.String() + `"`
+	if !ascii {
+		disposition += "; filename*=UTF-8''" + encodeRFC5987(name)
+	}
+	return disposition
+}
+
+// encodeRFC5987 percent-encodes a value for an ext-value (RFC 5987 attr-char is left as is)
+func encodeRFC5987(value string) string {
+	const attrChars = "!#$&+-.^_`|~"
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		b := value[i]
+		if (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || strings.IndexByte(attrChars, b) >= 0 {
+			encoded.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&encoded, "%%%02X", b)
+	}
+	return encoded.String()
 }
 
 func (h *BucketHandler) DownloadObject(c *gin.Context) {
@@ -1199,44 +2282,70 @@ func (h *BucketHandler) DownloadObject(c *gin.Context) {
 		return
 	}
 
-	// Check policy permissions
-	allowed, err := h.policyService.CheckObjectAccess(userUUID, bucketName, objectKey, services.ActionGetObject)
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Check policy permissions and load the object (denials are reported per OBJECT_DENIAL_MODE)
+	objectRecord, access, err := h.resolveObjectAccess(c, userUUID, &bucket, objectKey, services.ActionGetObject)
+	if !h.respondObjectAccess(c, access, err, "You don't have permission to download this object") {
+		return
+	}
+	object := *objectRecord
+
+	// Optional response header overrides (e.g. friendly filename for shared links)
+	contentTypeOverride, dispositionOverride, err := responseHeaderOverrides(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Policy check failed",
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid response header override",
 			Message: err.Error(),
 		})
 		return
 	}
-	if !allowed {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{
-			Error:   "Permission denied",
-			Message: "You don't have permission to download this object",
+
+	// Get storage backend for this bucket
+	storageBackend, err := h.getStorageBackend(&bucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to initialize storage backend",
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// Get object metadata from database
-	var object models.Object
-	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error: "Object not found",
+	// Resolve a Range request (resumed downloads); If-Range falls back to the full object when it changed
+	objRange, err := requestedRange(c, object.Size, object.ETag, object.UpdatedAt)
+	if err != nil {
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", object.Size))
+		c.JSON(http.StatusRequestedRangeNotSatisfiable, models.ErrorResponse{
+			Error:   "Range not satisfiable",
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// Get storage backend for this bucket
-	storageBackend, err := h.getStorageBackend(&bucket)
+	// Bound how many large downloads one user streams at once
+	length := object.Size
+	if objRange != nil {
+		length = objRange.length
+	}
+	releaseSlot, err := acquireDownloadSlot(c, &h.config.Storage, length)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to initialize storage backend",
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+			Error:   "Too many concurrent downloads",
 			Message: err.Error(),
 		})
 		return
 	}
+	defer releaseSlot()
 
 	// Get object from storage backend
-	file, err := storageBackend.GetObject(bucketName, objectKey)
+	var file io.ReadCloser
+	if objRange != nil {
+		file, err = storageBackend.GetObjectRange(bucketName, objectKey, objRange.start, objRange.length)
+	} else {
+		file, err = storageBackend.GetObject(bucketName, objectKey)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to retrieve object",
@@ -1246,23 +2355,40 @@ func (h *BucketHandler) DownloadObject(c *gin.Context) {
 	}
 	defer file.Close()
 
+	contentType := object.ContentType
+	if contentTypeOverride != "" {
+		contentType = contentTypeOverride
+	}
+
+	status := http.StatusOK
+	if objRange != nil {
+		status = http.StatusPartialContent
+		c.Header("Content-Range", objRange.contentRange(object.Size))
+	}
+
 	// Set response headers
-	c.Header("Content-Type", object.ContentType)
-	c.Header("Content-Length", strconv.FormatInt(object.Size, 10))
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.FormatInt(length, 10))
 	c.Header("ETag", fmt.Sprintf("\"%s\"", object.ETag))
 	c.Header("Last-Modified", object.UpdatedAt.UTC().Format(http.TimeFormat))
 	c.Header("Accept-Ranges", "bytes")
+	setObjectMetadataHeaders(c, &object)
 
-	// Set content disposition based on query parameter
-	if c.Query("download") == "true" {
-		filename := filepath.Base(objectKey)
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
-	} else {
-		c.Header("Content-Disposition", "inline")
+	// Set content disposition based on override or query parameter
+	disposition := "inline"
+	if dispositionOverride != "" {
+		disposition = dispositionOverride
+	} else if c.Query("download") == "true" {
+		disposition = attachmentDisposition(objectKey)
 	}
+	c.Header("Content-Disposition", applyObjectSecurityHeaders(c, contentType, disposition, objectKey))
+	setContentSHA256Header(c, &object, objRange != nil)
+
+	// Feeds the caller's recent objects (batched, off the download path)
+	recordObjectAccess(userUUID, object.ID)
 
 	// Stream file to response
-	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, file, nil)
+	c.DataFromReader(status, length, contentType, file, nil)
 }
 
 func (h *BucketHandler) DeleteObject(c *gin.Context) {
@@ -1280,31 +2406,15 @@ func (h *BucketHandler) DeleteObject(c *gin.Context) {
 		return
 	}
 
-	// Check policy permissions
-	allowed, err := h.policyService.CheckObjectAccess(userUUID, bucketName, objectKey, services.ActionDeleteObject)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Policy check failed",
-			Message: err.Error(),
-		})
-		return
-	}
-	if !allowed {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{
-			Error:   "Permission denied",
-			Message: "You don't have permission to delete this object",
-		})
-		return
-	}
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
 
-	// Get object metadata from database
-	var object models.Object
-	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error: "Object not found",
-		})
+	// Check policy permissions and load the object (denials are reported per OBJECT_DENIAL_MODE)
+	objectRecord, access, err := h.resolveObjectAccess(c, userUUID, &bucket, objectKey, services.ActionDeleteObject)
+	if !h.respondObjectAccess(c, access, err, "You don't have permission to delete this object") {
 		return
 	}
+	object := *objectRecord
 
 	// Get storage backend for this bucket
 	storageBackend, err := h.getStorageBackend(&bucket)
@@ -1316,8 +2426,21 @@ func (h *BucketHandler) DeleteObject(c *gin.Context) {
 		return
 	}
 
+	// Versioned buckets keep the deleted content as a previous version
+	previous, err := archiveObjectVersion(storageBackend, &bucket, &object)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to keep the previous version",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	// Delete file from storage backend
 	if err := storageBackend.DeleteObject(bucketName, objectKey); err != nil {
+		if previous != nil {
+			discardObjectVersion(storageBackend, bucketName, previous)
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to delete object from storage",
 			Message: err.Error(),
@@ -1334,6 +2457,15 @@ func (h *BucketHandler) DeleteObject(c *gin.Context) {
 		return
 	}
 
+	// The deletion itself becomes the key's latest version
+	if _, err := createDeleteMarker(&bucket, objectKey); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create delete marker",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Object deleted successfully",
 	})
@@ -1352,16 +2484,19 @@ func (h *BucketHandler) HeadObject(c *gin.Context) {
 		return
 	}
 
-	// Check policy permissions
-	allowed, err := h.policyService.CheckObjectAccess(userUUID, bucketName, objectKey, services.ActionHeadObject)
-	if err != nil || !allowed {
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Check policy permissions and load the object (denials are reported per OBJECT_DENIAL_MODE)
+	object, access, err := h.resolveObjectAccess(c, userUUID, &bucket, objectKey, services.ActionHeadObject)
+	switch {
+	case err != nil:
+		c.Status(http.StatusInternalServerError)
+		return
+	case access == objectAccessDenied:
 		c.Status(http.StatusForbidden)
 		return
-	}
-
-	// Get object metadata from database
-	var object models.Object
-	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
+	case access != objectAccessOK:
 		c.Status(http.StatusNotFound)
 		return
 	}
@@ -1372,6 +2507,9 @@ func (h *BucketHandler) HeadObject(c *gin.Context) {
 	c.Header("ETag", fmt.Sprintf("\"%s\"", object.ETag))
 	c.Header("Last-Modified", object.UpdatedAt.UTC().Format(http.TimeFormat))
 	c.Header("Accept-Ranges", "bytes")
+	setObjectExpiryHeaders(c, object)
+	setContentSHA256Header(c, object, false)
+	setObjectMetadataHeaders(c, object)
 
 	c.Status(http.StatusOK)
 }
@@ -1419,8 +2557,21 @@ func (h *BucketHandler) MoveObject(c *gin.Context) {
 		return
 	}
 
+	// Case-insensitive buckets store keys lowercased; a case-only move is a no-op there
+	req.SourceKey = bucket.NormalizeKey(req.SourceKey)
+	req.DestinationKey = bucket.NormalizeKey(req.DestinationKey)
+	if req.SourceKey == req.DestinationKey {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Source and destination keys are the same in this case-insensitive bucket",
+		})
+		return
+	}
+	if !respondKeyRules(c, &bucket, req.DestinationKey) {
+		return
+	}
+
 	// Check permission to read source object
-	allowed, err := h.policyService.CheckObjectAccess(userUUID, bucketName, req.SourceKey, services.ActionGetObject)
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, req.SourceKey, services.ActionGetObject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -1437,7 +2588,7 @@ func (h *BucketHandler) MoveObject(c *gin.Context) {
 	}
 
 	// Check permission to write destination object
-	allowed, err = h.policyService.CheckObjectAccess(userUUID, bucketName, req.DestinationKey, services.ActionPutObject)
+	allowed, err = h.policyService.CheckObjectAccess(c, userUUID, bucketName, req.DestinationKey, services.ActionPutObject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -1454,7 +2605,7 @@ func (h *BucketHandler) MoveObject(c *gin.Context) {
 	}
 
 	// Check permission to delete source object
-	allowed, err = h.policyService.CheckObjectAccess(userUUID, bucketName, req.SourceKey, services.ActionDeleteObject)
+	allowed, err = h.policyService.CheckObjectAccess(c, userUUID, bucketName, req.SourceKey, services.ActionDeleteObject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -1498,32 +2649,47 @@ func (h *BucketHandler) MoveObject(c *gin.Context) {
 		return
 	}
 
-	// Copy object in storage backend
-	if err := storageBackend.CopyObject(bucketName, req.SourceKey, req.DestinationKey); err != nil {
+	// In a versioned bucket the source key keeps the content as a previous version behind a
+	// delete marker, as if the object had been copied and then deleted
+	previous, err := archiveObjectVersion(storageBackend, &bucket, &sourceObject)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to copy object",
+			Error:   "Failed to keep the previous version",
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Delete source from storage backend
-	if err := storageBackend.DeleteObject(bucketName, req.SourceKey); err != nil {
-		// Try to rollback - delete the copy
-		storageBackend.DeleteObject(bucketName, req.DestinationKey)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to delete source object",
-			Message: err.Error(),
-		})
-		return
-	}
+	// Copy, then point the record at the new key; the source is only deleted once that's committed
+	failure := "Failed to update object metadata"
+	err = runStorageTxn(func(tx *gorm.DB, st *storageTxn) error {
+		if err := storageBackend.CopyObject(bucketName, req.SourceKey, req.DestinationKey); err != nil {
+			failure = "Failed to copy object"
+			return err
+		}
+		st.onRollback(func() { storageBackend.DeleteObject(bucketName, req.DestinationKey) })
+
+		sourceObject.Key = req.DestinationKey
+		sourceObject.VersionID = newVersionID(&bucket)
+		sourceObject.UpdatedAt = time.Now()
+		if err := tx.Save(&sourceObject).Error; err != nil {
+			return err
+		}
+		if _, err := createDeleteMarkerTx(tx, &bucket, req.SourceKey); err != nil {
+			return err
+		}
 
-	// Update database record with new key
-	sourceObject.Key = req.DestinationKey
-	sourceObject.UpdatedAt = time.Now()
-	if err := database.DB.Save(&sourceObject).Error; err != nil {
+		st.deferUntilCommit("delete "+bucketName+"/"+req.SourceKey+" after move", func() error {
+			return storageBackend.DeleteObject(bucketName, req.SourceKey)
+		})
+		return nil
+	})
+	if err != nil {
+		if previous != nil {
+			discardObjectVersion(storageBackend, bucketName, previous)
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to update object metadata",
+			Error:   failure,
 			Message: err.Error(),
 		})
 		return
@@ -1582,8 +2748,21 @@ func (h *BucketHandler) RenameObject(c *gin.Context) {
 		return
 	}
 
+	// Case-insensitive buckets store keys lowercased; a case-only rename is a no-op there
+	req.SourceKey = bucket.NormalizeKey(req.SourceKey)
+	destinationKey = bucket.NormalizeKey(destinationKey)
+	if req.SourceKey == destinationKey {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "New name is the same as the current name in this case-insensitive bucket",
+		})
+		return
+	}
+	if !respondKeyRules(c, &bucket, destinationKey) {
+		return
+	}
+
 	// Check permission to read source object
-	allowed, err := h.policyService.CheckObjectAccess(userUUID, bucketName, req.SourceKey, services.ActionGetObject)
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, req.SourceKey, services.ActionGetObject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -1600,7 +2779,7 @@ func (h *BucketHandler) RenameObject(c *gin.Context) {
 	}
 
 	// Check permission to write destination
-	allowed, err = h.policyService.CheckObjectAccess(userUUID, bucketName, destinationKey, services.ActionPutObject)
+	allowed, err = h.policyService.CheckObjectAccess(c, userUUID, bucketName, destinationKey, services.ActionPutObject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -1617,7 +2796,7 @@ func (h *BucketHandler) RenameObject(c *gin.Context) {
 	}
 
 	// Check permission to delete source
-	allowed, err = h.policyService.CheckObjectAccess(userUUID, bucketName, req.SourceKey, services.ActionDeleteObject)
+	allowed, err = h.policyService.CheckObjectAccess(c, userUUID, bucketName, req.SourceKey, services.ActionDeleteObject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -1661,32 +2840,47 @@ func (h *BucketHandler) RenameObject(c *gin.Context) {
 		return
 	}
 
-	// Copy object in storage backend
-	if err := storageBackend.CopyObject(bucketName, req.SourceKey, destinationKey); err != nil {
+	// In a versioned bucket the source key keeps the content as a previous version behind a
+	// delete marker, as if the object had been copied and then deleted
+	previous, err := archiveObjectVersion(storageBackend, &bucket, &sourceObject)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to copy object",
+			Error:   "Failed to keep the previous version",
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Delete source from storage backend
-	if err := storageBackend.DeleteObject(bucketName, req.SourceKey); err != nil {
-		// Try to rollback - delete the copy
-		storageBackend.DeleteObject(bucketName, destinationKey)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to delete source object",
-			Message: err.Error(),
-		})
-		return
-	}
+	// Copy, then point the record at the new key; the source is only deleted once that's committed
+	failure := "Failed to update object metadata"
+	err = runStorageTxn(func(tx *gorm.DB, st *storageTxn) error {
+		if err := storageBackend.CopyObject(bucketName, req.SourceKey, destinationKey); err != nil {
+			failure = "Failed to copy object"
+			return err
+		}
+		st.onRollback(func() { storageBackend.DeleteObject(bucketName, destinationKey) })
 
-	// Update database record with new key
-	sourceObject.Key = destinationKey
-	sourceObject.UpdatedAt = time.Now()
-	if err := database.DB.Save(&sourceObject).Error; err != nil {
+		sourceObject.Key = destinationKey
+		sourceObject.VersionID = newVersionID(&bucket)
+		sourceObject.UpdatedAt = time.Now()
+		if err := tx.Save(&sourceObject).Error; err != nil {
+			return err
+		}
+		if _, err := createDeleteMarkerTx(tx, &bucket, req.SourceKey); err != nil {
+			return err
+		}
+
+		st.deferUntilCommit("delete "+bucketName+"/"+req.SourceKey+" after rename", func() error {
+			return storageBackend.DeleteObject(bucketName, req.SourceKey)
+		})
+		return nil
+	})
+	if err != nil {
+		if previous != nil {
+			discardObjectVersion(storageBackend, bucketName, previous)
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to update object metadata",
+			Error:   failure,
 			Message: err.Error(),
 		})
 		return
@@ -1704,6 +2898,10 @@ type MoveFolderRequest struct {
 	DestinationPrefix string `json:"destination_prefix" binding:"required"`
 }
 
+// moveFolderCheckBatch bounds the keys per destination collision query (Postgres allows 65535
+// bind parameters per statement)
+const moveFolderCheckBatch = 1000
+
 func (h *BucketHandler) MoveFolder(c *gin.Context) {
 	bucketName := c.Param("name")
 	userID, _ := c.Get("user_id")
@@ -1743,16 +2941,32 @@ func (h *BucketHandler) MoveFolder(c *gin.Context) {
 		return
 	}
 
+	req.SourcePrefix = bucket.NormalizeKey(req.SourcePrefix)
+	req.DestinationPrefix = bucket.NormalizeKey(req.DestinationPrefix)
+	if req.SourcePrefix == req.DestinationPrefix {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Source and destination prefixes are the same in this case-insensitive bucket",
+		})
+		return
+	}
+
 	// Check bucket ownership or admin status
 	isAdmin, _ := c.Get("is_admin")
 	if bucket.OwnerID != userUUID && isAdmin != true {
-		// Check policy for source folder access
-		allowed, err := h.policyService.CheckObjectAccess(userUUID, bucketName, req.SourcePrefix+"*", services.ActionGetObject)
-		if err != nil || !allowed {
-			c.JSON(http.StatusForbidden, models.ErrorResponse{
-				Error: "Permission denied",
-			})
-			return
+		// A move reads and deletes the source folder and writes the destination folder
+		checks := []struct{ resource, action string }{
+			{req.SourcePrefix + "*", services.ActionGetObject},
+			{req.SourcePrefix + "*", services.ActionDeleteObject},
+			{req.DestinationPrefix + "*", services.ActionPutObject},
+		}
+		for _, check := range checks {
+			allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, check.resource, check.action)
+			if err != nil || !allowed {
+				c.JSON(http.StatusForbidden, models.ErrorResponse{
+					Error: "Permission denied",
+				})
+				return
+			}
 		}
 	}
 
@@ -1773,6 +2987,33 @@ func (h *BucketHandler) MoveFolder(c *gin.Context) {
 		return
 	}
 
+	// Check that no destination key already exists; copying over one would replace its content
+	// before the metadata update could fail on it
+	destinationKeys := make([]string, len(sourceObjects))
+	for i, obj := range sourceObjects {
+		destinationKeys[i] = req.DestinationPrefix + strings.TrimPrefix(obj.Key, req.SourcePrefix)
+	}
+	var existingKeys []string
+	for start := 0; start < len(destinationKeys) && len(existingKeys) == 0; start += moveFolderCheckBatch {
+		batch := destinationKeys[start:min(start+moveFolderCheckBatch, len(destinationKeys))]
+		if err := database.DB.Model(&models.Object{}).
+			Where("bucket_id = ? AND key IN ?", bucket.ID, batch).
+			Limit(10).Pluck("key", &existingKeys).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to check destination objects",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	if len(existingKeys) > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Objects with those names already exist",
+			Message: "Already in the destination folder: " + strings.Join(existingKeys, ", "),
+		})
+		return
+	}
+
 	// Get storage backend
 	storageBackend, err := h.getStorageBackend(&bucket)
 	if err != nil {
@@ -1783,48 +3024,84 @@ func (h *BucketHandler) MoveFolder(c *gin.Context) {
 		return
 	}
 
-	// Move each object
-	movedCount := 0
-	for _, obj := range sourceObjects {
-		// Calculate new key by replacing source prefix with destination prefix
-		newKey := req.DestinationPrefix + strings.TrimPrefix(obj.Key, req.SourcePrefix)
+	// Copy every object before opening the transaction, so a large folder doesn't hold it open
+	// across the storage work. In a versioned bucket each source key also keeps its content as a
+	// previous version behind a delete marker, as if the folder had been copied and then deleted.
+	// A failure removes the copies and versions already made
+	newKeys := make([]string, 0, len(sourceObjects))
+	var archived []*models.ObjectVersion
+	undoCopies := func() {
+		for _, copied := range newKeys {
+			storageBackend.DeleteObject(bucketName, copied)
+		}
+		for _, version := range archived {
+			discardObjectVersion(storageBackend, bucketName, version)
+		}
+	}
+	for i := range sourceObjects {
+		obj := &sourceObjects[i]
+		newKey := destinationKeys[i]
 
-		// Copy object in storage backend
-		if err := storageBackend.CopyObject(bucketName, obj.Key, newKey); err != nil {
+		previous, err := archiveObjectVersion(storageBackend, &bucket, obj)
+		if err != nil {
+			undoCopies()
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to copy object",
-				Message: fmt.Sprintf("Failed to copy %s: %v", obj.Key, err),
+				Error:   "Failed to keep the previous version",
+				Message: fmt.Errorf("failed to keep %s: %w", obj.Key, err).Error(),
 			})
 			return
 		}
+		if previous != nil {
+			archived = append(archived, previous)
+		}
 
-		// Delete source from storage backend
-		if err := storageBackend.DeleteObject(bucketName, obj.Key); err != nil {
-			// Try to rollback - delete the copy
-			storageBackend.DeleteObject(bucketName, newKey)
+		if err := storageBackend.CopyObject(bucketName, obj.Key, newKey); err != nil {
+			undoCopies()
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to delete source object",
-				Message: fmt.Sprintf("Failed to delete %s: %v", obj.Key, err),
+				Error:   "Failed to copy object",
+				Message: fmt.Errorf("failed to copy %s: %w", obj.Key, err).Error(),
 			})
 			return
 		}
+		newKeys = append(newKeys, newKey)
+	}
 
-		// Update database record with new key
-		obj.Key = newKey
-		obj.UpdatedAt = time.Now()
-		if err := database.DB.Save(&obj).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to update object metadata",
-				Message: err.Error(),
+	// Update all records in one transaction; sources are deleted once the new keys are
+	// committed, so a failure part-way leaves the whole folder where it was
+	err = runStorageTxn(func(tx *gorm.DB, st *storageTxn) error {
+		st.onRollback(undoCopies)
+
+		now := time.Now()
+		for i := range sourceObjects {
+			obj := &sourceObjects[i]
+			oldKey := obj.Key
+
+			obj.Key = newKeys[i]
+			obj.VersionID = newVersionID(&bucket)
+			obj.UpdatedAt = now
+			if err := tx.Save(obj).Error; err != nil {
+				return err
+			}
+			if _, err := createDeleteMarkerTx(tx, &bucket, oldKey); err != nil {
+				return err
+			}
+
+			st.deferUntilCommit("delete "+bucketName+"/"+oldKey+" after folder move", func() error {
+				return storageBackend.DeleteObject(bucketName, oldKey)
 			})
-			return
 		}
-
-		movedCount++
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to update object metadata",
+			Message: err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "Folder moved successfully",
-		"moved_count": movedCount,
+		"moved_count": len(sourceObjects),
 	})
 }
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/storage"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AppendModeRequest represents the request body for enabling or disabling a bucket's append mode
+type AppendModeRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// SetAppendMode enables or disables object appends for a bucket (admin only).
+// Appends change object semantics (an object's bytes can grow after upload), so they are opt-in
+func (h *BucketHandler) SetAppendMode(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req AppendModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	if *req.Enabled {
+		storageBackend, err := h.getStorageBackend(&bucket)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to initialize storage backend",
+				Message: err.Error(),
+			})
+			return
+		}
+		if !storage.SupportsAppend(storageBackend) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Append not supported",
+				Message: fmt.Sprintf("%s: bucket uses the %s backend", storage.ErrAppendNotSupported, bucket.StorageBackend),
+			})
+			return
+		}
+	}
+
+	previous := bucket.AllowAppend
+	if err := database.DB.Model(&bucket).Update("allow_append", *req.Enabled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to update bucket",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"SetAppendMode", "Bucket", bucket.ID.String(), bucketName,
+		map[string]interface{}{"allow_append": *req.Enabled, "previous": previous})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Append mode updated",
+		"bucket":       bucketName,
+		"allow_append": *req.Enabled,
+	})
+}
+
+// AppendObject adds the raw request body to the end of an existing object (append-only logs).
+// The bucket must have append mode enabled and the object must already exist; size, ETag and
+// SHA256 are refreshed afterwards
+func (h *BucketHandler) AppendObject(c *gin.Context) {
+	bucketName := c.Param("name")
+	objectKey := strings.TrimPrefix(c.Param("key"), "/")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	if err := validation.ValidateObjectKey(objectKey); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid object key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	if !respondKeyRules(c, &bucket, objectKey) {
+		return
+	}
+
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, objectKey, services.ActionPutObject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to write objects in this bucket",
+		})
+		return
+	}
+
+	if !bucket.AllowAppend {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Append not enabled",
+			Message: "Appends are disabled for this bucket; an admin can enable them via PUT /api/buckets/" + bucketName + "/append-mode",
+		})
+		return
+	}
+
+	// The appended length must be known up front so the object size limit can be enforced
+	size := c.Request.ContentLength
+	if size < 0 {
+		c.JSON(http.StatusLengthRequired, models.ErrorResponse{
+			Error: "Content-Length is required",
+		})
+		return
+	}
+	if size == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Nothing to append",
+		})
+		return
+	}
+
+	storageBackend, err := h.getStorageBackend(&bucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to initialize storage backend",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !storage.SupportsAppend(storageBackend) {
+		c.JSON(http.StatusNotImplemented, models.ErrorResponse{
+			Error:   "Append not supported",
+			Message: storage.ErrAppendNotSupported.Error(),
+		})
+		return
+	}
+
+	// Appends to one key are serialized with each other and with uploads
+	unlockKey, err := lockObjectKey(bucket.ID, objectKey, objectKeyLockTimeout)
+	if err != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Upload in progress",
+			Message: err.Error(),
+		})
+		return
+	}
+	defer unlockKey()
+
+	var object models.Object
+	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "Object not found",
+			Message: "Upload the object before appending to it",
+		})
+		return
+	}
+
+	if object.Size+size > h.config.Storage.MaxFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error:   "Object too large",
+			Message: fmt.Sprintf("Maximum object size is %d bytes", h.config.Storage.MaxFileSize),
+		})
+		return
+	}
+	if err := h.checkContentTypeSize(object.Size+size, object.ContentType); err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error:   "Object too large for its type",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := storage.CheckSpace(storageBackend, bucketName, size); err != nil {
+		respondStorageWriteError(c, bucketName, objectKey, err, "Failed to append to object")
+		return
+	}
+
+	// In a versioned bucket an append makes a new version; the content before it is kept as a
+	// previous version, which is put back as the live object if the append fails
+	previous, err := archiveObjectVersion(storageBackend, &bucket, &object)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to keep the previous version",
+			Message: err.Error(),
+		})
+		return
+	}
+	stored := false
+	defer func() {
+		if previous != nil && !stored {
+			restoreArchivedVersion(storageBackend, bucketName, previous)
+		}
+	}()
+
+	if err := storage.AppendObject(storageBackend, bucketName, objectKey, c.Request.Body, size); err != nil {
+		if errors.Is(err, storage.ErrAppendNotSupported) {
+			c.JSON(http.StatusNotImplemented, models.ErrorResponse{
+				Error:   "Failed to append to object",
+				Message: err.Error(),
+			})
+			return
+		}
+		respondStorageWriteError(c, bucketName, objectKey, err, "Failed to append to object")
+		return
+	}
+
+	objectInfo, err := storageBackend.GetObjectInfo(bucketName, objectKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to get object info",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	checksum, err := objectSHA256(storageBackend, bucketName, objectKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to hash object",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(&object).Updates(map[string]interface{}{
+		"size":        objectInfo.Size,
+		"e_tag":       objectInfo.ETag,
+		"sha256":      checksum,
+		"uploaded_by": userUUID,
+		"version_id":  newVersionID(&bucket),
+		"updated_at":  now,
+
+		// The S3 checksum covered the old content
+		"checksum_algorithm": "",
+		"checksum":           "",
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to save object metadata",
+			Message: err.Error(),
+		})
+		return
+	}
+	stored = true
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Data appended successfully",
+		"bucket":   bucketName,
+		"key":      objectKey,
+		"appended": size,
+		"size":     objectInfo.Size,
+		"etag":     objectInfo.ETag,
+	})
+}
+
+// objectSHA256 hashes an object's full content as currently stored
+func objectSHA256(storageBackend storage.StorageBackend, bucketName, objectKey string) (string, error) {
+	reader, err := storageBackend.GetObject(bucketName, objectKey)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
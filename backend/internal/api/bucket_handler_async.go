@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,11 +14,14 @@ import (
 	"bkt/internal/database"
 	"bkt/internal/logger"
 	"bkt/internal/models"
+	"bkt/internal/security"
 	"bkt/internal/services"
+	"bkt/internal/storage"
 	"bkt/internal/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // ProgressReader wraps an io.ReadSeeker and tracks upload progress in real-time
@@ -26,6 +31,7 @@ type ProgressReader struct {
 	totalSize     int64
 	bytesRead     int64
 	lastUpdate    time.Time
+	lastEvent     time.Time
 	updateMutex   sync.Mutex
 	minUpdateInterval time.Duration
 }
@@ -49,8 +55,20 @@ func (pr *ProgressReader) Read(p []byte) (n int, err error) {
 		pr.updateMutex.Lock()
 		pr.bytesRead += int64(n)
 
-		// Update database periodically to avoid too many writes
+		// Push live progress to event stream subscribers more often than the DB is updated
 		now := time.Now()
+		if now.Sub(pr.lastEvent) >= uploadEventInterval {
+			pr.lastEvent = now
+			publishUploadEvent(uploadEvent{
+				UploadID:     pr.uploadID,
+				Status:       models.UploadStatusProcessing,
+				TotalSize:    pr.totalSize,
+				UploadedSize: pr.bytesRead,
+				ProgressPct:  progressPercent(pr.bytesRead, pr.totalSize),
+			})
+		}
+
+		// Update database periodically to avoid too many writes
 		if now.Sub(pr.lastUpdate) >= pr.minUpdateInterval {
 			pr.lastUpdate = now
 
@@ -121,8 +139,46 @@ func (h *BucketHandler) UploadObjectAsync(c *gin.Context) {
 		return
 	}
 
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Auto-date-prefix buckets store uploads under the upload date (e.g. 2026/10/16/)
+	objectKey, err := applyAutoDatePrefix(&bucket, objectKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid object key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Governance key rules (e.g. only logs/*.gz), checked against the stored key
+	if !respondKeyRules(c, &bucket, objectKey) {
+		return
+	}
+
+	// Object ACL (defaults to inheriting the bucket setting)
+	acl, err := objectACLFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid ACL",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Optional per-object TTL (X-Expires-After header or expires_at form field)
+	expiresAt, err := objectExpiryFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid expiration",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	// Check policy permissions
-	allowed, err := h.policyService.CheckObjectAccess(userUUID, bucketName, objectKey, services.ActionPutObject)
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, objectKey, services.ActionPutObject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Policy check failed",
@@ -138,6 +194,20 @@ func (h *BucketHandler) UploadObjectAsync(c *gin.Context) {
 		return
 	}
 
+	// Recently created objects may be protected from overwrites
+	if err := checkOverwriteWindow(c, &bucket, objectKey); err != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Object is overwrite-protected",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// New keys can't push the bucket past its object count cap
+	if !respondObjectLimit(c, &bucket, objectKey) {
+		return
+	}
+
 	// Get uploaded file
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
@@ -176,7 +246,7 @@ func (h *BucketHandler) UploadObjectAsync(c *gin.Context) {
 	}
 
 	// Detect content type
-	detectedType, _, err := validation.DetectContentType(file)
+	detectedType, firstBytes, err := validation.DetectContentType(file)
 	file.Close()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -185,6 +255,7 @@ func (h *BucketHandler) UploadObjectAsync(c *gin.Context) {
 		})
 		return
 	}
+	detectedType = validation.RefineContentType(detectedType, firstBytes, objectKey, h.config.Storage.ExtensionContentTypes)
 
 	// Validate content type
 	if !validation.IsSafeContentType(detectedType) {
@@ -195,13 +266,29 @@ func (h *BucketHandler) UploadObjectAsync(c *gin.Context) {
 		return
 	}
 
+	contentType := validation.ResolveContentType(detectedType, fileHeader.Header.Get("Content-Type"), h.config.Storage.TrustedContentTypes)
+	if err := h.checkContentTypeSize(fileHeader.Size, detectedType, contentType); err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error:   "File too large for its type",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Don't accept a file the bucket's backend has no room for
+	if !h.checkStorageSpace(c, &bucket, objectKey, fileHeader.Size) {
+		return
+	}
+
 	// Create upload record
 	upload := models.Upload{
 		UserID:      userUUID,
 		BucketName:  bucketName,
 		ObjectKey:   objectKey,
 		Filename:    fileHeader.Filename,
-		ContentType: detectedType,
+		ContentType: contentType,
+		ACL:         acl,
+		ExpiresAt:   expiresAt,
 		TotalSize:   fileHeader.Size,
 		Status:      models.UploadStatusPending,
 	}
@@ -233,14 +320,31 @@ func (h *BucketHandler) UploadObjectAsync(c *gin.Context) {
 		return
 	}
 
-	// Start background upload processing
-	go h.processAsyncUpload(upload.ID, tempFilePath, &bucket)
+	// Start background upload processing (queued when all upload workers are busy)
+	position := h.enqueueAsyncUpload(&upload, tempFilePath, &bucket)
 
 	// Return upload ID immediately
-	c.JSON(http.StatusAccepted, gin.H{
+	response := gin.H{
 		"upload_id": upload.ID,
 		"status":    upload.Status,
 		"message":   "Upload initiated. Use /api/uploads/" + upload.ID.String() + "/status to check progress.",
+	}
+	if position > 0 {
+		response["queue_position"] = position
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
+// enqueueAsyncUpload hands an upload to the bounded background worker pool.
+// If no worker is free the upload is marked queued and starts once capacity frees up.
+// Returns the queue position, or 0 if processing started immediately
+func (h *BucketHandler) enqueueAsyncUpload(upload *models.Upload, tempFilePath string, bucket *models.Bucket) int {
+	queue := getAsyncUploadQueue(h.config.Storage.MaxConcurrentUploads)
+	return queue.submit(upload.ID, func() {
+		h.processAsyncUpload(upload.ID, tempFilePath, bucket)
+	}, func() {
+		upload.Status = models.UploadStatusQueued
+		database.DB.Model(upload).Update("status", models.UploadStatusQueued)
 	})
 }
 
@@ -266,6 +370,17 @@ func (h *BucketHandler) processAsyncUpload(uploadID uuid.UUID, tempFilePath stri
 	upload.Status = models.UploadStatusProcessing
 	upload.UploadedSize = 0 // Start at 0%
 	database.DB.Save(&upload)
+	publishUploadEvent(newUploadEvent(&upload))
+
+	// Every exit path leaves the record completed or failed; tell event stream subscribers
+	var object models.Object
+	defer func() {
+		event := newUploadEvent(&upload)
+		if upload.Status == models.UploadStatusCompleted {
+			event.Object = &object
+		}
+		publishUploadEvent(event)
+	}()
 
 	// Open temp file
 	file, err := os.Open(tempFilePath)
@@ -278,17 +393,53 @@ func (h *BucketHandler) processAsyncUpload(uploadID uuid.UUID, tempFilePath stri
 	defer file.Close()
 
 	// Re-detect content type from file
-	detectedType, _, err := validation.DetectContentType(file)
+	detectedType, firstBytes, err := validation.DetectContentType(file)
 	if err != nil {
 		upload.Status = models.UploadStatusFailed
 		upload.ErrorMessage = fmt.Sprintf("Failed to detect content type: %v", err)
 		database.DB.Save(&upload)
 		return
 	}
+	detectedType = validation.RefineContentType(detectedType, firstBytes, upload.ObjectKey, h.config.Storage.ExtensionContentTypes)
+
+	// Validate content type (resumable uploads only see the content once assembled)
+	if !validation.IsSafeContentType(detectedType) {
+		upload.Status = models.UploadStatusFailed
+		upload.ErrorMessage = fmt.Sprintf("File type '%s' is not allowed", detectedType)
+		database.DB.Save(&upload)
+		return
+	}
+
+	// Reject gzip content that inflates beyond the decompression limit (decompression bomb guard)
+	if validation.IsGzipContentType(detectedType) {
+		file.Seek(0, 0)
+		if err := validation.CheckGzipExpansion(file, h.decompressionLimit(upload.TotalSize)); err != nil {
+			upload.Status = models.UploadStatusFailed
+			upload.ErrorMessage = err.Error()
+			database.DB.Save(&upload)
+			return
+		}
+	}
 
 	// Reset file position after reading (file is seekable so no need for MultiReader)
 	file.Seek(0, 0)
 
+	// A trusted type declared at upload time is re-checked against the assembled content
+	contentType := validation.ResolveContentType(detectedType, upload.ContentType, h.config.Storage.TrustedContentTypes)
+
+	// Resumable uploads only learn their type once assembled, so the per-type size limit is (re)checked here
+	if err := h.checkContentTypeSize(upload.TotalSize, detectedType, contentType); err != nil {
+		upload.Status = models.UploadStatusFailed
+		upload.ErrorMessage = err.Error()
+		database.DB.Save(&upload)
+		return
+	}
+
+	// Malware scan runs before anything reaches storage; rejected content is discarded with the temp file
+	if !h.scanAsyncUpload(&upload, file) {
+		return
+	}
+
 	// Get storage backend
 	storageBackend, err := h.getStorageBackend(bucket)
 	if err != nil {
@@ -298,17 +449,48 @@ func (h *BucketHandler) processAsyncUpload(uploadID uuid.UUID, tempFilePath stri
 		return
 	}
 
+	// Serialize with other writes to the same key (held until the metadata is saved)
+	unlockKey, err := lockObjectKey(bucket.ID, upload.ObjectKey, objectKeyLockTimeout)
+	if err != nil {
+		upload.Status = models.UploadStatusFailed
+		upload.ErrorMessage = err.Error()
+		database.DB.Save(&upload)
+		return
+	}
+	defer unlockKey()
+
+	// Versioned buckets keep the content being replaced as a previous version, which is put
+	// back as the live object if the write or the metadata save fails
+	previous, err := archiveCurrentVersion(storageBackend, bucket, upload.ObjectKey)
+	if err != nil {
+		upload.Status = models.UploadStatusFailed
+		upload.ErrorMessage = fmt.Sprintf("Failed to keep the previous version: %v", err)
+		database.DB.Save(&upload)
+		return
+	}
+	stored := false
+	defer func() {
+		if previous != nil && !stored {
+			restoreArchivedVersion(storageBackend, bucket.Name, previous)
+		}
+	}()
+
 	// Upload to storage with real-time progress tracking
 	// ProgressReader will update uploaded_size as bytes are transferred
 	startTime := time.Now()
 
-	// Wrap file with progress tracker for real-time updates
-	// File implements io.ReadSeeker, so ProgressReader will be seekable for AWS SDK retries
-	progressReader := NewProgressReader(file, upload.ID, upload.TotalSize)
+	// Wrap file with progress tracker for real-time updates, hashing the content as it streams
+	// File implements io.ReadSeeker, so both readers stay seekable for AWS SDK retries
+	hashingReader := validation.NewHashingReader(file)
+	progressReader := NewProgressReader(hashingReader, upload.ID, upload.TotalSize)
 
-	if err := storageBackend.PutObject(bucket.Name, upload.ObjectKey, progressReader, upload.TotalSize, detectedType); err != nil {
+	if err := storageBackend.PutObject(bucket.Name, upload.ObjectKey, progressReader, upload.TotalSize, contentType); err != nil {
 		upload.Status = models.UploadStatusFailed
 		upload.ErrorMessage = fmt.Sprintf("Failed to upload to storage: %v", err)
+		if errors.Is(err, storage.ErrInsufficientStorage) {
+			logInsufficientStorage(bucket.Name, upload.ObjectKey, err)
+			upload.ErrorMessage = insufficientStorageMessage
+		}
 		database.DB.Save(&upload)
 		return
 	}
@@ -318,28 +500,11 @@ func (h *BucketHandler) processAsyncUpload(uploadID uuid.UUID, tempFilePath stri
 
 	uploadDuration := time.Since(startTime)
 
-	// Calculate SHA256 hash of the uploaded file
-	file.Seek(0, 0)
-
-	sha256Hash, err := validation.CalculateSHA256(file)
-	if err != nil {
-		logger.Warn("Failed to calculate SHA256 hash", map[string]interface{}{
-			"upload_id": uploadID,
-			"error":     err.Error(),
-		})
-		sha256Hash = "" // Continue without hash
-	}
-
-	// Calculate ETag (MD5)
-	file.Seek(0, 0)
-
-	etag, err := validation.CalculateMD5(file)
+	// SHA256 and ETag (MD5) were computed while the storage write consumed the file
+	sha256Hash, etag, err := hashingReader.Sums(upload.TotalSize)
 	if err != nil {
-		logger.Warn("Failed to calculate ETag", map[string]interface{}{
-			"upload_id": uploadID,
-			"error":     err.Error(),
-		})
-		etag = ""
+		// The backend didn't read the content in one pass; fall back to hashing the temp file
+		sha256Hash, etag = hashTempFile(file, uploadID)
 	}
 
 	// Create object record in database
@@ -348,22 +513,39 @@ func (h *BucketHandler) processAsyncUpload(uploadID uuid.UUID, tempFilePath stri
 		storagePath = fmt.Sprintf("s3://%s/%s", bucket.Name, upload.ObjectKey)
 	}
 
-	object := models.Object{
+	object = models.Object{
 		BucketID:    bucket.ID,
 		Key:         upload.ObjectKey,
 		Size:        upload.TotalSize,
-		ContentType: detectedType,
+		ContentType: contentType,
 		ETag:        etag,
 		SHA256:      sha256Hash,
 		StoragePath: storagePath,
+		ACL:         upload.ACL,
+		UploadedBy:  &upload.UserID,
+		ExpiresAt:   upload.ExpiresAt,
+		VersionID:   newVersionID(bucket),
 	}
 
-	if err := database.DB.Create(&object).Error; err != nil {
+	// Overwrite existing object metadata for the same key. The stored file is removed if the
+	// metadata write doesn't commit (unless it replaced an object)
+	err = runStorageTxn(func(tx *gorm.DB, st *storageTxn) error {
+		st.onRollback(func() { discardUnsavedObject(storageBackend, bucket.ID, bucket.Name, upload.ObjectKey) })
+
+		var existing models.Object
+		if tx.Where("bucket_id = ? AND key = ?", bucket.ID, upload.ObjectKey).First(&existing).Error == nil {
+			object.ID = existing.ID
+			object.CreatedAt = existing.CreatedAt
+		}
+		return tx.Save(&object).Error
+	})
+	if err != nil {
 		upload.Status = models.UploadStatusFailed
 		upload.ErrorMessage = fmt.Sprintf("Failed to create object record: %v", err)
 		database.DB.Save(&upload)
 		return
 	}
+	stored = true
 
 	// Update upload status to completed
 	now := time.Now()
@@ -382,6 +564,86 @@ func (h *BucketHandler) processAsyncUpload(uploadID uuid.UUID, tempFilePath stri
 	})
 }
 
+// hashTempFile re-reads a buffered upload to compute its SHA256 and ETag (MD5). A hash that
+// can't be computed is left empty rather than failing the upload
+func hashTempFile(file *os.File, uploadID uuid.UUID) (string, string) {
+	file.Seek(0, 0)
+	sha256Hash, err := validation.CalculateSHA256(file)
+	if err != nil {
+		logger.Warn("Failed to calculate SHA256 hash", map[string]interface{}{
+			"upload_id": uploadID,
+			"error":     err.Error(),
+		})
+		sha256Hash = "" // Continue without hash
+	}
+
+	file.Seek(0, 0)
+	etag, err := validation.CalculateMD5(file)
+	if err != nil {
+		logger.Warn("Failed to calculate ETag", map[string]interface{}{
+			"upload_id": uploadID,
+			"error":     err.Error(),
+		})
+		etag = ""
+	}
+	return sha256Hash, etag
+}
+
+// scanAsyncUpload runs the configured malware scan over a buffered upload and records the verdict.
+// Returns false if the upload must not be published: infected content is quarantined, and a scan
+// that fails marks the upload failed unless the scanner is configured to fail open
+func (h *BucketHandler) scanAsyncUpload(upload *models.Upload, file *os.File) bool {
+	if h.uploadScanner == nil {
+		return true
+	}
+
+	file.Seek(0, 0)
+	defer file.Seek(0, 0) // Leave the file rewound for the storage write
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Storage.Scanner.TimeoutDuration)
+	defer cancel()
+	result, err := h.uploadScanner.Scan(ctx, file)
+
+	if err != nil {
+		failOpen := h.config.Storage.Scanner.FailOpen
+		logger.Warn("Upload malware scan failed", map[string]interface{}{
+			"upload_id": upload.ID,
+			"bucket":    upload.BucketName,
+			"key":       upload.ObjectKey,
+			"fail_open": failOpen,
+			"error":     err.Error(),
+		})
+		upload.ScanVerdict = security.ScanVerdictError
+		if failOpen {
+			database.DB.Model(upload).Update("scan_verdict", upload.ScanVerdict)
+			return true
+		}
+		upload.Status = models.UploadStatusFailed
+		upload.ErrorMessage = fmt.Sprintf("Malware scan failed: %v", err)
+		database.DB.Save(upload)
+		return false
+	}
+
+	if result.Infected {
+		logger.Warn("Malware detected in upload", map[string]interface{}{
+			"upload_id": upload.ID,
+			"user_id":   upload.UserID,
+			"bucket":    upload.BucketName,
+			"key":       upload.ObjectKey,
+			"signature": result.Signature,
+		})
+		upload.Status = models.UploadStatusQuarantined
+		upload.ScanVerdict = security.ScanVerdictInfected
+		upload.ScanSignature = result.Signature
+		upload.ErrorMessage = "Malware detected; the file was discarded"
+		database.DB.Save(upload)
+		return false
+	}
+
+	upload.ScanVerdict = security.ScanVerdictClean
+	database.DB.Model(upload).Update("scan_verdict", upload.ScanVerdict)
+	return true
+}
+
 // GetUploadStatus returns the current status of an upload
 func (h *BucketHandler) GetUploadStatus(c *gin.Context) {
 	uploadIDStr := c.Param("id")
@@ -413,17 +675,22 @@ func (h *BucketHandler) GetUploadStatus(c *gin.Context) {
 
 	// Build response
 	response := models.UploadStatusResponse{
-		ID:           upload.ID,
-		Status:       upload.Status,
-		Filename:     upload.Filename,
-		ObjectKey:    upload.ObjectKey,
-		TotalSize:    upload.TotalSize,
-		UploadedSize: upload.UploadedSize,
-		ProgressPct:  progressPct,
-		ErrorMessage: upload.ErrorMessage,
-		ObjectID:     upload.ObjectID,
-		CreatedAt:    upload.CreatedAt,
-		CompletedAt:  upload.CompletedAt,
+		ID:            upload.ID,
+		Status:        upload.Status,
+		Filename:      upload.Filename,
+		ObjectKey:     upload.ObjectKey,
+		TotalSize:     upload.TotalSize,
+		UploadedSize:  upload.UploadedSize,
+		ProgressPct:   progressPct,
+		ErrorMessage:  upload.ErrorMessage,
+		ScanVerdict:   upload.ScanVerdict,
+		ScanSignature: upload.ScanSignature,
+		ObjectID:      upload.ObjectID,
+		CreatedAt:     upload.CreatedAt,
+		CompletedAt:   upload.CompletedAt,
+	}
+	if upload.Status == models.UploadStatusQueued {
+		response.QueuePosition = getAsyncUploadQueue(h.config.Storage.MaxConcurrentUploads).position(upload.ID)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -436,21 +703,30 @@ func (h *BucketHandler) ListUploads(c *gin.Context) {
 
 	// Optional query parameters for filtering
 	status := c.Query("status") // e.g., "pending", "processing", "completed", "failed"
-	limit := 50                  // Default limit
-	if limitStr := c.Query("limit"); limitStr != "" {
-		fmt.Sscanf(limitStr, "%d", &limit)
-		if limit > 100 {
-			limit = 100 // Max 100 results
-		}
+	page, ok := bindPageParams(c, 50, 100)
+	if !ok {
+		return
 	}
 
-	query := database.DB.Where("user_id = ?", userUUID)
+	query := database.DB.Model(&models.Upload{}).Where("user_id = ?", userUUID)
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if page.envelope {
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to fetch uploads",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
 
 	var uploads []models.Upload
-	if err := query.Order("created_at DESC").Limit(limit).Find(&uploads).Error; err != nil {
+	if err := page.apply(query).Order("created_at DESC").Find(&uploads).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to fetch uploads",
 			Message: err.Error(),
@@ -467,19 +743,21 @@ func (h *BucketHandler) ListUploads(c *gin.Context) {
 		}
 
 		responses[i] = models.UploadStatusResponse{
-			ID:           upload.ID,
-			Status:       upload.Status,
-			Filename:     upload.Filename,
-			ObjectKey:    upload.ObjectKey,
-			TotalSize:    upload.TotalSize,
-			UploadedSize: upload.UploadedSize,
-			ProgressPct:  progressPct,
-			ErrorMessage: upload.ErrorMessage,
-			ObjectID:     upload.ObjectID,
-			CreatedAt:    upload.CreatedAt,
-			CompletedAt:  upload.CompletedAt,
+			ID:            upload.ID,
+			Status:        upload.Status,
+			Filename:      upload.Filename,
+			ObjectKey:     upload.ObjectKey,
+			TotalSize:     upload.TotalSize,
+			UploadedSize:  upload.UploadedSize,
+			ProgressPct:   progressPct,
+			ErrorMessage:  upload.ErrorMessage,
+			ScanVerdict:   upload.ScanVerdict,
+			ScanSignature: upload.ScanSignature,
+			ObjectID:      upload.ObjectID,
+			CreatedAt:     upload.CreatedAt,
+			CompletedAt:   upload.CompletedAt,
 		}
 	}
 
-	c.JSON(http.StatusOK, responses)
+	respondPage(c, page, responses, len(responses), total)
 }
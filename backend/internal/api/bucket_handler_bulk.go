@@ -0,0 +1,510 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/storage"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxBulkEntries caps the files (or archive entries) one bulk upload may store
+const maxBulkEntries = 1000
+
+// Bulk upload entry statuses
+const (
+	bulkStatusUploaded = "uploaded"
+	bulkStatusFailed   = "failed"
+	bulkStatusSkipped  = "skipped" // Directories, links and other non-file archive entries
+)
+
+// errBulkTooManyEntries stops an archive with more than maxBulkEntries files
+var errBulkTooManyEntries = fmt.Errorf("a bulk upload may contain at most %d files", maxBulkEntries)
+
+// BulkUploadResult is the outcome of one file in a bulk upload
+type BulkUploadResult struct {
+	Key         string `json:"key"`
+	Status      string `json:"status"`
+	Size        int64  `json:"size,omitempty"`
+	ETag        string `json:"etag,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// bulkUpload carries the settings shared by every entry of one bulk upload
+type bulkUpload struct {
+	c         *gin.Context
+	bucket    *models.Bucket
+	backend   storage.StorageBackend
+	userID    uuid.UUID
+	prefix    string
+	acl       string
+	expiresAt *time.Time
+	results   []BulkUploadResult
+}
+
+// UploadObjectsBulk handles POST /api/buckets/:name/objects/bulk. The form carries either many
+// "file" parts (optionally with one "key" value per file) or a single "archive" part (tar,
+// tar.gz or zip) that is expanded into one object per file. Keys are placed under the optional
+// "prefix". Every entry goes through the same checks as a single upload, and one failing entry
+// doesn't stop the others
+func (h *BucketHandler) UploadObjectsBulk(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid multipart form",
+			Message: err.Error(),
+		})
+		return
+	}
+	files := form.File["file"]
+	archives := form.File["archive"]
+	if (len(files) == 0) == (len(archives) == 0) || len(archives) > 1 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid bulk upload",
+			Message: "Send either one or more 'file' parts or a single 'archive' part",
+		})
+		return
+	}
+	if len(files) > maxBulkEntries {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Too many files",
+			Message: errBulkTooManyEntries.Error(),
+		})
+		return
+	}
+	keys := form.Value["key"]
+	if len(keys) > 0 && len(keys) != len(files) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid bulk upload",
+			Message: "When 'key' values are sent there must be one per 'file' part, in the same order",
+		})
+		return
+	}
+
+	prefix := c.PostForm("prefix")
+	if prefix != "" {
+		if err := validation.ValidateObjectKey(prefix); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid prefix",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	acl, err := objectACLFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid ACL",
+			Message: err.Error(),
+		})
+		return
+	}
+	expiresAt, err := objectExpiryFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid expiration",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	storageBackend, err := h.getStorageBackend(&bucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to initialize storage backend",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	upload := &bulkUpload{
+		c:         c,
+		bucket:    &bucket,
+		backend:   storageBackend,
+		userID:    userUUID,
+		prefix:    prefix,
+		acl:       acl,
+		expiresAt: expiresAt,
+	}
+
+	// An archive that can't be read any further stops the upload; entries stored so far are kept
+	var abortErr error
+	if len(archives) == 1 {
+		abortErr = h.extractBulkArchive(upload, archives[0])
+	} else {
+		for i, fileHeader := range files {
+			key := fileHeader.Filename
+			if len(keys) > 0 {
+				key = keys[i]
+			}
+			h.storeBulkFile(upload, key, fileHeader)
+		}
+	}
+
+	uploaded, failed := 0, 0
+	for _, result := range upload.results {
+		switch result.Status {
+		case bulkStatusUploaded:
+			uploaded++
+		case bulkStatusFailed:
+			failed++
+		}
+	}
+
+	status := http.StatusOK
+	response := gin.H{
+		"bucket":   bucketName,
+		"uploaded": uploaded,
+		"failed":   failed,
+		"results":  upload.results,
+	}
+	switch {
+	case errors.Is(abortErr, validation.ErrDecompressionLimit):
+		status = http.StatusRequestEntityTooLarge
+		response["error"] = abortErr.Error()
+	case abortErr != nil:
+		status = http.StatusBadRequest
+		response["error"] = abortErr.Error()
+	case failed > 0:
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, response)
+}
+
+// storeBulkFile stores one "file" part of a bulk upload
+func (h *BucketHandler) storeBulkFile(upload *bulkUpload, key string, fileHeader *multipart.FileHeader) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		upload.fail(upload.prefix+key, fmt.Errorf("failed to open file: %w", err))
+		return
+	}
+	defer file.Close()
+	h.storeBulkEntry(upload, key, file, fileHeader.Size, fileHeader.Header.Get("Content-Type"))
+}
+
+// extractBulkArchive stores every regular file of a tar, gzip-compressed tar or zip archive.
+// Extraction streams entry by entry; the total expanded size is bounded by the decompression
+// limits that apply to a single compressed upload of the archive's size
+func (h *BucketHandler) extractBulkArchive(upload *bulkUpload, fileHeader *multipart.FileHeader) error {
+	archive, err := fileHeader.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer archive.Close()
+
+	magic := make([]byte, 512)
+	n, _ := io.ReadFull(archive, magic)
+	magic = magic[:n]
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	limit := h.decompressionLimit(fileHeader.Size)
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return h.extractBulkZip(upload, archive, fileHeader.Size, limit)
+	case bytes.HasPrefix(magic, []byte("\x1f\x8b")):
+		gz, err := gzip.NewReader(archive)
+		if err != nil {
+			return fmt.Errorf("invalid gzip archive: %w", err)
+		}
+		defer gz.Close()
+		return h.extractBulkTar(upload, validation.NewDecompressionLimitReader(gz, limit))
+	case len(magic) >= 262 && string(magic[257:262]) == "ustar":
+		return h.extractBulkTar(upload, archive)
+	default:
+		return errors.New("unsupported archive format (use tar, tar.gz or zip)")
+	}
+}
+
+// extractBulkTar stores the regular files of a tar stream
+func (h *BucketHandler) extractBulkTar(upload *bulkUpload, r io.Reader) error {
+	tr := tar.NewReader(r)
+	files := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return bulkArchiveError(err)
+		}
+
+		name := bulkArchiveEntryName(header.Name)
+		if header.Typeflag != tar.TypeReg || name == "" {
+			if header.Typeflag != tar.TypeDir && name != "" {
+				upload.skip(name, "not a regular file")
+			}
+			continue
+		}
+		if files++; files > maxBulkEntries {
+			return errBulkTooManyEntries
+		}
+
+		h.storeBulkEntry(upload, name, io.LimitReader(tr, header.Size), header.Size, "")
+	}
+}
+
+// extractBulkZip stores the regular files of a zip archive. The declared sizes are checked
+// against the limit up front, and each entry is bounded while it inflates, since the
+// declared sizes can't be trusted
+func (h *BucketHandler) extractBulkZip(upload *bulkUpload, archive io.ReaderAt, size, limit int64) error {
+	zr, err := zip.NewReader(archive, size)
+	if err != nil {
+		return bulkArchiveError(err)
+	}
+
+	var declared uint64
+	files := 0
+	for _, entry := range zr.File {
+		if entry.Mode().IsRegular() {
+			declared += entry.UncompressedSize64
+			files++
+		}
+	}
+	if files > maxBulkEntries {
+		return errBulkTooManyEntries
+	}
+	if limit >= 0 && declared > uint64(limit) {
+		return validation.ErrDecompressionLimit
+	}
+
+	remaining := limit
+	for _, entry := range zr.File {
+		name := bulkArchiveEntryName(entry.Name)
+		if !entry.Mode().IsRegular() || name == "" {
+			if !entry.Mode().IsDir() && name != "" {
+				upload.skip(name, "not a regular file")
+			}
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			upload.fail(upload.prefix+name, fmt.Errorf("failed to read archive entry: %w", err))
+			continue
+		}
+		entrySize := int64(entry.UncompressedSize64)
+		h.storeBulkEntry(upload, name, validation.NewDecompressionLimitReader(rc, remaining), entrySize, "")
+		rc.Close()
+		if remaining >= 0 {
+			remaining -= entrySize
+		}
+	}
+	return nil
+}
+
+// bulkArchiveEntryName turns an archive entry name into a key suffix: "./" and leading slashes
+// are dropped. Directory entries yield an empty name
+func bulkArchiveEntryName(name string) string {
+	name = strings.TrimLeft(strings.TrimPrefix(name, "./"), "/")
+	if strings.HasSuffix(name, "/") {
+		return ""
+	}
+	return name
+}
+
+// bulkArchiveError wraps an error reading an archive; decompression limit errors are kept
+// recognizable
+func bulkArchiveError(err error) error {
+	if errors.Is(err, validation.ErrDecompressionLimit) {
+		return err
+	}
+	return fmt.Errorf("invalid archive: %w", err)
+}
+
+// storeBulkEntry stores one file of a bulk upload under prefix+name, with the checks of a
+// single upload (key rules, permissions, overwrite protection, object cap, size and content
+// type limits, storage space)
+func (h *BucketHandler) storeBulkEntry(upload *bulkUpload, name string, r io.Reader, size int64, declaredType string) {
+	bucket := upload.bucket
+	objectKey := upload.prefix + name
+
+	if err := validation.ValidateObjectKey(objectKey); err != nil {
+		upload.fail(objectKey, err)
+		return
+	}
+	objectKey = bucket.NormalizeKey(objectKey)
+	objectKey, err := applyAutoDatePrefix(bucket, objectKey)
+	if err != nil {
+		upload.fail(objectKey, err)
+		return
+	}
+	if err := bucket.CheckKeyRules(objectKey); err != nil {
+		upload.fail(objectKey, err)
+		return
+	}
+
+	allowed, err := h.policyService.CheckObjectAccess(upload.c, upload.userID, bucket.Name, objectKey, services.ActionPutObject)
+	if err != nil {
+		upload.fail(objectKey, fmt.Errorf("policy check failed: %w", err))
+		return
+	}
+	if !allowed {
+		upload.fail(objectKey, errors.New("permission denied"))
+		return
+	}
+
+	if size < 0 || size > h.config.Storage.MaxFileSize {
+		upload.fail(objectKey, fmt.Errorf("file too large (maximum file size is %d bytes)", h.config.Storage.MaxFileSize))
+		return
+	}
+
+	unlockKey, err := lockObjectKey(bucket.ID, objectKey, objectKeyLockTimeout)
+	if err != nil {
+		upload.fail(objectKey, err)
+		return
+	}
+	defer unlockKey()
+
+	if err := checkOverwriteWindow(upload.c, bucket, objectKey); err != nil {
+		upload.fail(objectKey, err)
+		return
+	}
+	if err := checkObjectLimit(bucket, objectKey); err != nil {
+		upload.fail(objectKey, err)
+		return
+	}
+
+	// Content type from the content itself, as for single uploads
+	detectedType, firstBytes, err := validation.DetectContentType(r)
+	if err != nil {
+		upload.fail(objectKey, fmt.Errorf("failed to detect content type: %w", err))
+		return
+	}
+	detectedType = validation.RefineContentType(detectedType, firstBytes, objectKey, h.config.Storage.ExtensionContentTypes)
+	if !validation.IsSafeContentType(detectedType) {
+		upload.fail(objectKey, fmt.Errorf("file type '%s' is not allowed", detectedType))
+		return
+	}
+	contentType := validation.ResolveContentType(detectedType, declaredType, h.config.Storage.TrustedContentTypes)
+	if err := h.checkContentTypeSize(size, detectedType, contentType); err != nil {
+		upload.fail(objectKey, err)
+		return
+	}
+
+	reader := io.MultiReader(bytes.NewReader(firstBytes), r)
+	if validation.IsGzipContentType(detectedType) {
+		guard := validation.NewGzipGuardReader(reader, h.decompressionLimit(size))
+		defer guard.Close()
+		reader = guard
+	}
+	hasher := sha256.New()
+	reader = io.TeeReader(reader, hasher)
+
+	if err := storage.CheckSpace(upload.backend, bucket.Name, size); err != nil {
+		logInsufficientStorage(bucket.Name, objectKey, err)
+		upload.fail(objectKey, errors.New(insufficientStorageMessage))
+		return
+	}
+
+	// Versioned buckets keep the content being replaced as a previous version, which is put
+	// back as the live object if the write or the metadata save fails
+	previous, err := archiveCurrentVersion(upload.backend, bucket, objectKey)
+	if err != nil {
+		upload.fail(objectKey, fmt.Errorf("failed to keep the previous version: %w", err))
+		return
+	}
+	stored := false
+	defer func() {
+		if previous != nil && !stored {
+			restoreArchivedVersion(upload.backend, bucket.Name, previous)
+		}
+	}()
+
+	// A rejected write (e.g. a decompression bomb) leaves the key as it was
+	if err := upload.backend.PutObject(bucket.Name, objectKey, reader, size, contentType); err != nil {
+		if errors.Is(err, storage.ErrInsufficientStorage) {
+			logInsufficientStorage(bucket.Name, objectKey, err)
+			err = errors.New(insufficientStorageMessage)
+		}
+		upload.fail(objectKey, err)
+		return
+	}
+
+	objectInfo, err := upload.backend.GetObjectInfo(bucket.Name, objectKey)
+	if err != nil {
+		upload.fail(objectKey, fmt.Errorf("failed to get object info: %w", err))
+		return
+	}
+
+	now := time.Now()
+	err = runStorageTxn(func(tx *gorm.DB, st *storageTxn) error {
+		st.onRollback(func() { discardUnsavedObject(upload.backend, bucket.ID, bucket.Name, objectKey) })
+
+		return tx.Exec(`
+			INSERT INTO objects (id, bucket_id, key, size, content_type, e_tag, storage_path, sha256, acl, uploaded_by, expires_at, version_id, created_at, updated_at)
+			VALUES (gen_random_uuid(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (bucket_id, key)
+			DO UPDATE SET
+				size = EXCLUDED.size,
+				content_type = EXCLUDED.content_type,
+				e_tag = EXCLUDED.e_tag,
+				storage_path = EXCLUDED.storage_path,
+				sha256 = EXCLUDED.sha256,
+				checksum_algorithm = '',
+				checksum = '',
+				acl = EXCLUDED.acl,
+				uploaded_by = EXCLUDED.uploaded_by,
+				expires_at = EXCLUDED.expires_at,
+				version_id = EXCLUDED.version_id,
+				updated_at = EXCLUDED.updated_at
+		`, bucket.ID, objectKey, objectInfo.Size, objectInfo.ContentType, objectInfo.ETag,
+			objectKey, hex.EncodeToString(hasher.Sum(nil)), upload.acl, upload.userID, upload.expiresAt, newVersionID(bucket), now, now).Error
+	})
+	if err != nil {
+		upload.fail(objectKey, fmt.Errorf("failed to save object metadata: %w", err))
+		return
+	}
+	stored = true
+
+	upload.results = append(upload.results, BulkUploadResult{
+		Key:         objectKey,
+		Status:      bulkStatusUploaded,
+		Size:        objectInfo.Size,
+		ETag:        objectInfo.ETag,
+		ContentType: objectInfo.ContentType,
+	})
+}
+
+// fail records a failed entry
+func (u *bulkUpload) fail(key string, err error) {
+	u.results = append(u.results, BulkUploadResult{Key: key, Status: bulkStatusFailed, Error: err.Error()})
+}
+
+// skip records an archive entry that isn't stored
+func (u *bulkUpload) skip(name, reason string) {
+	u.results = append(u.results, BulkUploadResult{Key: u.prefix + name, Status: bulkStatusSkipped, Error: reason})
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// sha256HexPattern matches a hex-encoded SHA256 digest
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// byHashLookupLimit caps how many objects the cross-bucket hash lookup returns
+const byHashLookupLimit = 1000
+
+// parseSHA256Param reads the :sha256 path parameter, writing the error response if it is not a valid digest
+func parseSHA256Param(c *gin.Context) (string, bool) {
+	digest := strings.ToLower(c.Param("sha256"))
+	if !sha256HexPattern.MatchString(digest) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid SHA256",
+			Message: "Expected 64 hexadecimal characters",
+		})
+		return "", false
+	}
+	return digest, true
+}
+
+// DownloadObjectByHash serves the object in a bucket whose content has the given SHA256.
+// When several keys share the content, the first readable one (by key) is served. Objects
+// the caller can't read are reported as not found so hashes don't reveal hidden content
+func (h *BucketHandler) DownloadObjectByHash(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	digest, ok := parseSHA256Param(c)
+	if !ok {
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	var keys []string
+	if err := database.DB.Model(&models.Object{}).
+		Where("bucket_id = ? AND sha256 = ?", bucket.ID, digest).
+		Order("key ASC").Pluck("key", &keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to look up object",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for _, key := range keys {
+		allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, key, services.ActionGetObject)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Policy check failed",
+				Message: err.Error(),
+			})
+			return
+		}
+		if !allowed {
+			continue
+		}
+
+		// Serve it exactly like GET /objects/*key (ranges, conditional requests, header overrides)
+		c.Header("X-Bkt-Object-Key", key)
+		setParam(c, "key", "/"+key)
+		h.DownloadObject(c)
+		return
+	}
+
+	c.JSON(http.StatusNotFound, models.ErrorResponse{
+		Error: "Object not found",
+	})
+}
+
+// FindObjectsByHash lists every object, across all buckets, whose content has the given SHA256 (admin only)
+func (h *BucketHandler) FindObjectsByHash(c *gin.Context) {
+	digest, ok := parseSHA256Param(c)
+	if !ok {
+		return
+	}
+
+	objects := make([]models.Object, 0)
+	if err := database.DB.Preload("Bucket").Scopes(models.InLiveBuckets).Where("sha256 = ?", digest).
+		Order("bucket_id ASC, key ASC").Limit(byHashLookupLimit).Find(&objects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to look up objects",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sha256":  digest,
+		"objects": objects, // Each includes its bucket
+		"count":   len(objects),
+	})
+}
+
+// setParam sets or replaces a route parameter, so a handler can delegate to another one
+func setParam(c *gin.Context, key, value string) {
+	for i := range c.Params {
+		if c.Params[i].Key == key {
+			c.Params[i].Value = value
+			return
+		}
+	}
+	c.Params = append(c.Params, gin.Param{Key: key, Value: value})
+}
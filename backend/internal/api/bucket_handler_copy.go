@@ -0,0 +1,295 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/storage"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CopyToBucketRequest represents the request body for copying an object to another bucket
+type CopyToBucketRequest struct {
+	SourceKey    string `json:"source_key" binding:"required"`
+	TargetBucket string `json:"target_bucket" binding:"required"`
+	TargetKey    string `json:"target_key"` // Defaults to the source key
+	Overwrite    bool   `json:"overwrite"`
+}
+
+// CopyObjectToBucket copies an object from this bucket into another bucket
+// Uses a server-side copy when both buckets share a storage backend, otherwise streams the data
+func (h *BucketHandler) CopyObjectToBucket(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req CopyToBucketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.TargetKey == "" {
+		req.TargetKey = req.SourceKey
+	}
+	if c.Query("overwrite") == "true" {
+		req.Overwrite = true
+	}
+
+	if err := validation.ValidateObjectKey(req.TargetKey); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid target key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Get source and target buckets
+	var srcBucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&srcBucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	var dstBucket models.Bucket
+	if err := database.DB.Where("name = ?", req.TargetBucket).First(&dstBucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Target bucket not found",
+		})
+		return
+	}
+
+	// Each side follows its own bucket's key case mode
+	req.SourceKey = srcBucket.NormalizeKey(req.SourceKey)
+	req.TargetKey = dstBucket.NormalizeKey(req.TargetKey)
+
+	if req.TargetBucket == bucketName && req.TargetKey == req.SourceKey {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Source and target cannot be the same object",
+		})
+		return
+	}
+	if !respondKeyRules(c, &dstBucket, req.TargetKey) {
+		return
+	}
+
+	// Check permission to read source object
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, req.SourceKey, services.ActionGetObject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to read the source object",
+		})
+		return
+	}
+
+	// Check permission to write target object
+	allowed, err = h.policyService.CheckObjectAccess(c, userUUID, dstBucket.Name, req.TargetKey, services.ActionPutObject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to write to the target bucket",
+		})
+		return
+	}
+
+	// Get source object from database
+	var sourceObject models.Object
+	if err := database.DB.Where("bucket_id = ? AND key = ?", srcBucket.ID, req.SourceKey).First(&sourceObject).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Source object not found",
+		})
+		return
+	}
+
+	// Reject if the target exists unless overwriting
+	var targetObject models.Object
+	targetExists := database.DB.Where("bucket_id = ? AND key = ?", dstBucket.ID, req.TargetKey).First(&targetObject).Error == nil
+	if targetExists && !req.Overwrite {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Target object already exists",
+			Message: "Set overwrite=true to replace it",
+		})
+		return
+	}
+	if targetExists {
+		if err := checkOverwriteWindow(c, &dstBucket, req.TargetKey); err != nil {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error:   "Target object is overwrite-protected",
+				Message: err.Error(),
+			})
+			return
+		}
+	} else if !respondObjectLimit(c, &dstBucket, req.TargetKey) {
+		return
+	}
+
+	srcBackend, err := h.getStorageBackend(&srcBucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to initialize storage backend",
+			Message: err.Error(),
+		})
+		return
+	}
+	dstBackend, err := h.getStorageBackend(&dstBucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to initialize storage backend",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !h.checkStorageSpace(c, &dstBucket, req.TargetKey, sourceObject.Size) {
+		return
+	}
+
+	// Versioned buckets keep the target being replaced as a previous version, which is put
+	// back as the live object if the copy or the metadata save fails
+	var previous *models.ObjectVersion
+	if targetExists {
+		previous, err = archiveObjectVersion(dstBackend, &dstBucket, &targetObject)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to keep the previous version",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	stored := false
+	defer func() {
+		if previous != nil && !stored {
+			restoreArchivedVersion(dstBackend, dstBucket.Name, previous)
+		}
+	}()
+
+	// Server-side copy is only possible when both buckets resolve to the same backend instance
+	serverSide := false
+	if sameStorageBackend(&srcBucket, &dstBucket) {
+		serverSide = true
+		err = srcBackend.CopyObjectCrossBucket(srcBucket.Name, req.SourceKey, dstBucket.Name, req.TargetKey)
+	} else {
+		err = streamCopyObject(srcBackend, dstBackend, srcBucket.Name, dstBucket.Name, &sourceObject, req.TargetKey)
+	}
+
+	auditMeta := map[string]interface{}{
+		"source_bucket": srcBucket.Name,
+		"source_key":    req.SourceKey,
+		"target_bucket": dstBucket.Name,
+		"target_key":    req.TargetKey,
+		"size":          sourceObject.Size,
+		"server_side":   serverSide,
+		"overwrite":     targetExists,
+	}
+
+	if err != nil {
+		h.auditService.LogFailure(c, userUUID, username.(string),
+			"CopyObjectToBucket", "Object", sourceObject.ID.String(),
+			fmt.Sprintf("%s/%s", dstBucket.Name, req.TargetKey), err.Error(), auditMeta)
+		respondStorageWriteError(c, dstBucket.Name, req.TargetKey, err, "Failed to copy object")
+		return
+	}
+
+	// Create or replace the target object record
+	now := time.Now()
+	if !targetExists {
+		targetObject = models.Object{
+			BucketID:  dstBucket.ID,
+			Key:       req.TargetKey,
+			CreatedAt: now,
+		}
+	}
+	targetObject.Size = sourceObject.Size
+	targetObject.ContentType = sourceObject.ContentType
+	targetObject.ETag = sourceObject.ETag
+	targetObject.SHA256 = sourceObject.SHA256
+	targetObject.ChecksumAlgorithm = sourceObject.ChecksumAlgorithm
+	targetObject.Checksum = sourceObject.Checksum
+	targetObject.StoragePath = req.TargetKey
+	targetObject.Metadata = sourceObject.Metadata
+	targetObject.UploadedBy = &userUUID
+	targetObject.VersionID = newVersionID(&dstBucket)
+	targetObject.UpdatedAt = now
+
+	if err := database.DB.Save(&targetObject).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to save object metadata",
+			Message: err.Error(),
+		})
+		return
+	}
+	stored = true
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"CopyObjectToBucket", "Object", targetObject.ID.String(),
+		fmt.Sprintf("%s/%s", dstBucket.Name, req.TargetKey), auditMeta)
+
+	status := http.StatusCreated
+	if targetExists {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{
+		"message": "Object copied successfully",
+		"object":  targetObject,
+	})
+}
+
+// sameStorageBackend reports whether two buckets resolve to the same backend instance
+// (both local, or both S3 with the same configuration)
+func sameStorageBackend(a, b *models.Bucket) bool {
+	backendA, backendB := a.StorageBackend, b.StorageBackend
+	if backendA == "" {
+		backendA = "local"
+	}
+	if backendB == "" {
+		backendB = "local"
+	}
+	if backendA != backendB {
+		return false
+	}
+	if backendA != "s3" {
+		return true
+	}
+	if a.S3ConfigID == nil || b.S3ConfigID == nil {
+		return a.S3ConfigID == nil && b.S3ConfigID == nil
+	}
+	return *a.S3ConfigID == *b.S3ConfigID
+}
+
+// streamCopyObject copies an object between backends by streaming it through the server
+func streamCopyObject(src, dst storage.StorageBackend, srcBucket, dstBucket string, object *models.Object, dstKey string) error {
+	reader, err := src.GetObject(srcBucket, object.Key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return dst.PutObject(dstBucket, dstKey, reader, object.Size, object.ContentType)
+}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateFolderRequest represents the request body for creating an empty folder
+type CreateFolderRequest struct {
+	Prefix string `json:"prefix" binding:"required"` // e.g. "photos/2024/" (trailing slash optional)
+}
+
+// normalizeFolderPrefix ensures a folder prefix ends with a slash and applies the bucket's key case mode
+func normalizeFolderPrefix(bucket *models.Bucket, prefix string) (string, error) {
+	prefix = strings.TrimPrefix(prefix, "/")
+	if prefix == "" {
+		return "", fmt.Errorf("prefix cannot be empty")
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if err := validation.ValidateObjectKey(prefix + folderMarkerName); err != nil {
+		return "", err
+	}
+	return bucket.NormalizeKey(prefix), nil
+}
+
+// CreateFolder creates an empty folder by writing its zero-byte .keep marker
+func (h *BucketHandler) CreateFolder(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	prefix, err := normalizeFolderPrefix(&bucket, req.Prefix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid folder prefix",
+			Message: err.Error(),
+		})
+		return
+	}
+	markerKey := prefix + folderMarkerName
+
+	// Check policy permissions
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, markerKey, services.ActionPutObject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to create folders here",
+		})
+		return
+	}
+
+	var existing models.Object
+	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, markerKey).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Folder already exists",
+		})
+		return
+	}
+
+	storageBackend, err := h.getStorageBackend(&bucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to initialize storage backend",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := storageBackend.PutObject(bucketName, markerKey, bytes.NewReader(nil), 0, "application/octet-stream"); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create folder",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var etag string
+	if info, err := storageBackend.GetObjectInfo(bucketName, markerKey); err == nil {
+		etag = info.ETag
+	}
+
+	// Insert the marker row; a concurrent create wins the unique index and this one reports a conflict
+	now := time.Now()
+	result := database.DB.Exec(`
+		INSERT INTO objects (id, bucket_id, key, size, content_type, e_tag, storage_path, sha256, acl, uploaded_by, created_at, updated_at)
+		VALUES (gen_random_uuid(), ?, ?, 0, 'application/octet-stream', ?, ?, '', ?, ?, ?, ?)
+		ON CONFLICT (bucket_id, key) DO NOTHING
+	`, bucket.ID, markerKey, etag, markerKey, models.ObjectACLInherit, userUUID, now, now)
+	if result.Error != nil {
+		storageBackend.DeleteObject(bucketName, markerKey)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to save folder metadata",
+			Message: result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Folder already exists",
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"CreateFolder", "Bucket", bucket.ID.String(), bucketName,
+		map[string]interface{}{"prefix": prefix})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Folder created successfully",
+		"type":    "folder",
+		"key":     prefix,
+	})
+}
+
+// DeleteFolder removes a folder marker, or with ?recursive=true the folder and everything under it
+// Non-recursive deletes refuse folders that still contain objects
+func (h *BucketHandler) DeleteFolder(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+	recursive := c.Query("recursive") == "true"
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	prefix, err := normalizeFolderPrefix(&bucket, c.Query("prefix"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid folder prefix",
+			Message: err.Error(),
+		})
+		return
+	}
+	markerKey := prefix + folderMarkerName
+
+	var objects []models.Object
+	escapedPrefix := validation.EscapeLikeWildcards(prefix)
+	if err := database.DB.Where("bucket_id = ? AND key LIKE ?", bucket.ID, escapedPrefix+"%").Order("key ASC").Find(&objects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to list folder contents",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if len(objects) == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Folder not found",
+		})
+		return
+	}
+
+	if !recursive && (len(objects) > 1 || objects[0].Key != markerKey) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Folder is not empty",
+			Message: "Use recursive=true to delete the folder and its contents",
+		})
+		return
+	}
+
+	// Every object must be deletable before anything is removed
+	for _, obj := range objects {
+		allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, obj.Key, services.ActionDeleteObject)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Policy check failed",
+				Message: err.Error(),
+			})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "Permission denied",
+				Message: fmt.Sprintf("You don't have permission to delete %s", obj.Key),
+			})
+			return
+		}
+	}
+
+	storageBackend, err := h.getStorageBackend(&bucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to initialize storage backend",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	deletedCount := 0
+	for i := range objects {
+		obj := &objects[i]
+		// In a versioned bucket each object is kept as a previous version behind a delete marker
+		if _, err := deleteObjectVersioned(storageBackend, &bucket, obj); err != nil {
+			h.auditService.LogFailure(c, userUUID, username.(string),
+				"DeleteFolder", "Bucket", bucket.ID.String(), bucketName, err.Error(),
+				map[string]interface{}{"prefix": prefix, "deleted_count": deletedCount})
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to delete object",
+				Message: fmt.Sprintf("Failed to delete %s: %v", obj.Key, err),
+			})
+			return
+		}
+		deletedCount++
+	}
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"DeleteFolder", "Bucket", bucket.ID.String(), bucketName,
+		map[string]interface{}{"prefix": prefix, "recursive": recursive, "deleted_count": deletedCount})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Folder deleted successfully",
+		"deleted_count": deletedCount,
+	})
+}
+
+// folderSize is the aggregated size of one immediate sub-prefix
+type folderSize struct {
+	Prefix      string `json:"prefix"`
+	TotalSize   int64  `json:"total_size"`
+	ObjectCount int64  `json:"object_count"` // Folder .keep markers are not counted
+}
+
+// GetFolderSizes returns each immediate sub-prefix under ?prefix with the total size and object
+// count of everything beneath it. Prefix and delimiter behave as in ListObjects (delimiter defaults to "/")
+func (h *BucketHandler) GetFolderSizes(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionListBucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to list objects in this bucket",
+		})
+		return
+	}
+
+	prefix := bucket.NormalizeKey(c.DefaultQuery("prefix", ""))
+	delimiter := c.DefaultQuery("delimiter", "/")
+	if delimiter == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Delimiter cannot be empty",
+		})
+		return
+	}
+
+	// The sub-prefix is the key up to and including the first delimiter after prefix.
+	// Postgres string functions count characters, not bytes
+	prefixLen := utf8.RuneCountInString(prefix)
+
+	db, cancel := database.WithTimeout(bucketStatsTimeout)
+	defer cancel()
+	query := db.Model(&models.Object{}).
+		Select("LEFT(key, ? + STRPOS(SUBSTRING(key FROM ?), ?) - 1 + ?) AS prefix, "+
+			"COALESCE(SUM(size), 0) AS total_size, "+
+			"SUM(CASE WHEN key LIKE ? THEN 0 ELSE 1 END) AS object_count",
+			prefixLen, prefixLen+1, delimiter, utf8.RuneCountInString(delimiter), "%/"+folderMarkerName).
+		Where("bucket_id = ?", bucket.ID).
+		Where("STRPOS(SUBSTRING(key FROM ?), ?) > 0", prefixLen+1, delimiter)
+	if prefix != "" {
+		query = query.Where("key LIKE ?", validation.EscapeLikeWildcards(prefix)+"%")
+	}
+
+	folders := make([]folderSize, 0)
+	if err := query.Group("1").Order("1 ASC").Scan(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to compute folder sizes",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bucket":    bucketName,
+		"prefix":    prefix,
+		"delimiter": delimiter,
+		"folders":   folders,
+		"count":     len(folders),
+	})
+}
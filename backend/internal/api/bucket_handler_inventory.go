@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/storage"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// inventoryBatchSize is how many object rows are loaded per query while streaming an inventory
+const inventoryBatchSize = 1000
+
+// inventoryColumns is the CSV header row (NDJSON uses the same field names)
+var inventoryColumns = []string{"key", "size", "etag", "content_type", "last_modified"}
+
+// inventoryEntry is one NDJSON manifest line
+type inventoryEntry struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag"`
+	ContentType  string `json:"content_type"`
+	LastModified string `json:"last_modified"`
+	InStorage    *bool  `json:"in_storage,omitempty"` // Only with verify=true
+}
+
+// ExportInventory streams a manifest of every object in a bucket as CSV or NDJSON.
+// Objects are read from the database in key order using keyset pagination, so memory use
+// stays constant regardless of bucket size. With ?verify=true each object is also checked
+// against the storage backend and an in_storage column is added
+func (h *BucketHandler) ExportInventory(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid format",
+			Message: "format must be csv or ndjson",
+		})
+		return
+	}
+	verify := c.Query("verify") == "true"
+
+	// Get bucket from database
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	// Check policy permissions
+	allowed, err := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionListBucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to list objects in this bucket",
+		})
+		return
+	}
+
+	prefix := bucket.NormalizeKey(c.Query("prefix"))
+
+	var storageBackend storage.StorageBackend
+	if verify {
+		storageBackend, err = h.getStorageBackend(&bucket)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to initialize storage backend",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("%s-inventory-%s.%s", bucketName, time.Now().UTC().Format("20060102T150405Z"), format)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Status(http.StatusOK)
+
+	csvWriter := csv.NewWriter(c.Writer)
+	jsonEncoder := json.NewEncoder(c.Writer)
+	if format == "csv" {
+		header := inventoryColumns
+		if verify {
+			header = append(append([]string{}, inventoryColumns...), "in_storage")
+		}
+		csvWriter.Write(header)
+	}
+
+	count := 0
+	lastKey := ""
+	for {
+		query := database.DB.Where("bucket_id = ? AND key > ?", bucket.ID, lastKey)
+		if prefix != "" {
+			query = query.Where("key LIKE ?", validation.EscapeLikeWildcards(prefix)+"%")
+		}
+
+		var batch []models.Object
+		if err := query.Order("key ASC").Limit(inventoryBatchSize).Find(&batch).Error; err != nil {
+			// Headers are already sent; truncate the stream and log the failure
+			logger.Error("Inventory export failed", map[string]interface{}{
+				"bucket": bucketName,
+				"rows":   count,
+				"error":  err.Error(),
+			})
+			break
+		}
+
+		for _, obj := range batch {
+			entry := inventoryEntry{
+				Key:          obj.Key,
+				Size:         obj.Size,
+				ETag:         obj.ETag,
+				ContentType:  obj.ContentType,
+				LastModified: obj.UpdatedAt.UTC().Format(time.RFC3339),
+			}
+			if verify {
+				exists, err := storageBackend.ObjectExists(bucketName, obj.Key)
+				inStorage := err == nil && exists
+				entry.InStorage = &inStorage
+			}
+
+			if format == "csv" {
+				record := []string{entry.Key, strconv.FormatInt(entry.Size, 10), entry.ETag, entry.ContentType, entry.LastModified}
+				if entry.InStorage != nil {
+					record = append(record, strconv.FormatBool(*entry.InStorage))
+				}
+				csvWriter.Write(record)
+			} else {
+				jsonEncoder.Encode(entry)
+			}
+		}
+		count += len(batch)
+
+		// Push each batch to the client so large inventories stream instead of buffering
+		csvWriter.Flush()
+		c.Writer.Flush()
+
+		if len(batch) < inventoryBatchSize || c.Request.Context().Err() != nil {
+			break
+		}
+		lastKey = batch[len(batch)-1].Key
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxObjectsRequest represents the request body for setting a bucket's object count cap
+type MaxObjectsRequest struct {
+	MaxObjects *int64 `json:"max_objects" binding:"required"` // 0 disables
+}
+
+// errObjectLimit marks an upload rejected by the bucket's object count cap
+var errObjectLimit = errors.New("bucket object limit reached")
+
+// countBucketObjects counts a bucket's objects, stopping at limit (0: no limit). The count is an
+// index-only scan on bucket_id, so a capped bucket is never counted past its cap
+func countBucketObjects(bucketID uuid.UUID, limit int64) (int64, error) {
+	var count int64
+	query := database.DB.Model(&models.Object{}).Select("1").Where("bucket_id = ?", bucketID)
+	if limit > 0 {
+		query = query.Limit(int(limit))
+	}
+	err := database.DB.Raw("SELECT COUNT(*) FROM (?) AS capped", query).Scan(&count).Error
+	return count, err
+}
+
+// checkObjectLimit returns an error wrapping errObjectLimit if writing objectKey would create a
+// new object in a bucket already at its MaxObjects. Overwriting an existing key is always allowed.
+// Concurrent uploads of new keys can overshoot the cap by the number in flight
+func checkObjectLimit(bucket *models.Bucket, objectKey string) error {
+	if bucket.MaxObjects <= 0 {
+		return nil
+	}
+
+	var existing models.Object
+	err := database.DB.Select("id").Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&existing).Error
+	if err == nil {
+		return nil // Overwrite
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	count, err := countBucketObjects(bucket.ID, bucket.MaxObjects)
+	if err != nil {
+		return err
+	}
+	if count >= bucket.MaxObjects {
+		return fmt.Errorf("%w: bucket %s already holds its maximum of %d objects", errObjectLimit, bucket.Name, bucket.MaxObjects)
+	}
+	return nil
+}
+
+// respondObjectLimit writes the error for a failed checkObjectLimit. Returns true when the
+// upload may proceed
+func respondObjectLimit(c *gin.Context, bucket *models.Bucket, objectKey string) bool {
+	err := checkObjectLimit(bucket, objectKey)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errObjectLimit):
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Bucket object limit reached",
+			Message: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to check bucket object limit",
+			Message: err.Error(),
+		})
+	}
+	return false
+}
+
+// SetMaxObjects sets a bucket's object count cap (admin only). Lowering it below the current
+// count keeps existing objects; only new keys are rejected until the bucket shrinks
+func (h *BucketHandler) SetMaxObjects(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req MaxObjectsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+	if *req.MaxObjects < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid object limit",
+			Message: "max_objects must be 0 (unlimited) or more",
+		})
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	previous := bucket.MaxObjects
+	if err := database.DB.Model(&bucket).Update("max_objects", *req.MaxObjects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to update bucket",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"SetMaxObjects", "Bucket", bucket.ID.String(), bucketName,
+		map[string]interface{}{"max_objects": *req.MaxObjects, "previous": previous})
+
+	response := gin.H{
+		"message":     "Object limit updated",
+		"bucket":      bucketName,
+		"max_objects": *req.MaxObjects,
+	}
+	if count, _, err := getBucketStats(bucket.ID); err == nil {
+		response["object_count"] = count
+	}
+	c.JSON(http.StatusOK, response)
+}
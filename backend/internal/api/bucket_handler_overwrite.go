@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"bkt/internal/database"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxNoOverwriteMinutes caps the overwrite protection window (30 days)
+const maxNoOverwriteMinutes = 30 * 24 * 60
+
+// OverwriteProtectionRequest represents the request body for setting a bucket's overwrite protection window
+type OverwriteProtectionRequest struct {
+	Minutes *int `json:"no_overwrite_minutes" binding:"required"` // 0 disables
+}
+
+// checkOverwriteWindow returns an error if objectKey exists and is still inside the bucket's
+// overwrite protection window. The original uploader and admins may always overwrite
+func checkOverwriteWindow(c *gin.Context, bucket *models.Bucket, objectKey string) error {
+	if bucket.NoOverwriteMinutes <= 0 {
+		return nil
+	}
+
+	var existing models.Object
+	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&existing).Error; err != nil {
+		return nil // Nothing to overwrite
+	}
+
+	until, protected := bucket.OverwriteProtectedUntil(&existing)
+	if !protected {
+		return nil
+	}
+
+	if isAdmin, _ := c.Get("is_admin"); isAdmin == true {
+		return nil
+	}
+	userID, _ := c.Get("user_id")
+	if existing.UploadedBy != nil && userID == *existing.UploadedBy {
+		return nil
+	}
+
+	return fmt.Errorf("object %s was created less than %d minutes ago and cannot be overwritten until %s",
+		objectKey, bucket.NoOverwriteMinutes, until.UTC().Format(time.RFC3339))
+}
+
+// SetOverwriteProtection sets a bucket's overwrite protection window (admin only)
+func (h *BucketHandler) SetOverwriteProtection(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req OverwriteProtectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+	if *req.Minutes < 0 || *req.Minutes > maxNoOverwriteMinutes {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid overwrite protection window",
+			Message: fmt.Sprintf("no_overwrite_minutes must be between 0 and %d", maxNoOverwriteMinutes),
+		})
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	previous := bucket.NoOverwriteMinutes
+	if err := database.DB.Model(&bucket).Update("no_overwrite_minutes", *req.Minutes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to update bucket",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"SetOverwriteProtection", "Bucket", bucket.ID.String(), bucketName,
+		map[string]interface{}{"no_overwrite_minutes": *req.Minutes, "previous": previous})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Overwrite protection updated",
+		"bucket":               bucketName,
+		"no_overwrite_minutes": *req.Minutes,
+	})
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Presigned URL lifetimes
+const (
+	presignURLDefaultExpiry = time.Hour
+	presignURLMaxExpiry     = 7 * 24 * time.Hour
+)
+
+// PresignURLRequest represents the request body for issuing a presigned object URL
+type PresignURLRequest struct {
+	Operation string `json:"operation"`  // "GET" (download, the default) or "PUT" (upload the raw body)
+	ExpiresIn int64  `json:"expires_in"` // Seconds; defaults to 1 hour, at most 7 days
+
+	// Signed response header overrides (GET only)
+	ResponseContentType        string `json:"response_content_type"`
+	ResponseContentDisposition string `json:"response_content_disposition"`
+}
+
+// presignedObjectPath returns the path of an object under /api/presigned, escaping each key segment
+func presignedObjectPath(bucketName, objectKey string) string {
+	segments := strings.Split(objectKey, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/api/presigned/" + bucketName + "/" + strings.Join(segments, "/")
+}
+
+// PresignObjectURL handles POST /api/buckets/:name/presign/*key: issues a URL that lets anyone
+// holding it download (GET) or upload (PUT) that one object until it expires, without credentials.
+// The request runs as the issuer, so the issuer must be allowed the operation now and when it's used
+func (h *BucketHandler) PresignObjectURL(c *gin.Context) {
+	bucketName := c.Param("name")
+	objectKey := strings.TrimPrefix(c.Param("key"), "/")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req PresignURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	operation := strings.ToUpper(req.Operation)
+	if operation == "" {
+		operation = http.MethodGet
+	}
+	if operation != http.MethodGet && operation != http.MethodPut {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid operation",
+			Message: "operation must be GET or PUT",
+		})
+		return
+	}
+
+	if operation == http.MethodPut && (req.ResponseContentType != "" || req.ResponseContentDisposition != "") {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "Response header overrides only apply to GET URLs",
+		})
+		return
+	}
+	if req.ResponseContentType != "" {
+		if err := validation.ValidateContentTypeOverride(req.ResponseContentType); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid response_content_type",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	if req.ResponseContentDisposition != "" {
+		if err := validation.ValidateContentDisposition(req.ResponseContentDisposition); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid response_content_disposition",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	expiry := presignURLDefaultExpiry
+	if req.ExpiresIn != 0 {
+		expiry = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if expiry <= 0 || expiry > presignURLMaxExpiry {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid expiry",
+			Message: fmt.Sprintf("expires_in must be between 1 and %d seconds", int64(presignURLMaxExpiry.Seconds())),
+		})
+		return
+	}
+
+	if err := validation.ValidateObjectKey(objectKey); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid object key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	if operation == http.MethodGet {
+		// Denials are reported per OBJECT_DENIAL_MODE, like a download
+		if _, access, err := h.resolveObjectAccess(c, userUUID, &bucket, objectKey, services.ActionGetObject); !h.respondObjectAccess(c, access, err, "You don't have permission to download this object") {
+			return
+		}
+	} else {
+		allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, objectKey, services.ActionPutObject)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Policy check failed",
+				Message: err.Error(),
+			})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "Permission denied",
+				Message: "You don't have permission to upload this object",
+			})
+			return
+		}
+	}
+
+	expiresAt := time.Now().Add(expiry).UTC().Truncate(time.Second)
+	query := url.Values{}
+	query.Set(middleware.PresignedCredentialParam, userUUID.String())
+	query.Set(middleware.PresignedExpiresParam, strconv.FormatInt(expiresAt.Unix(), 10))
+	if req.ResponseContentType != "" {
+		query.Set(middleware.PresignedContentTypeParam, req.ResponseContentType)
+	}
+	if req.ResponseContentDisposition != "" {
+		query.Set(middleware.PresignedDispositionParam, req.ResponseContentDisposition)
+	}
+	query.Set(middleware.PresignedSignatureParam, middleware.SignPresignedURL(
+		h.config.Auth.JWTSecret, operation, bucketName, objectKey, userUUID, expiresAt.Unix(),
+		req.ResponseContentType, req.ResponseContentDisposition))
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"PresignObjectURL", "Object", bucket.ID.String(), bucketName+"/"+objectKey,
+		map[string]interface{}{"operation": operation, "expires_at": expiresAt})
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        presignedObjectPath(bucketName, objectKey) + "?" + query.Encode(),
+		"method":     operation,
+		"expires_at": expiresAt,
+	})
+}
@@ -0,0 +1,426 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Presigned POST lifetimes
+const (
+	presignPostDefaultExpiry = time.Hour
+	presignPostMaxExpiry     = 7 * 24 * time.Hour
+)
+
+// presignPostFilenameVar is replaced with the uploaded file's name in the key field (as in S3)
+const presignPostFilenameVar = "${filename}"
+
+// presignPostFormFields are the only form values a presigned POST may carry besides the file
+var presignPostFormFields = map[string]bool{
+	"key":             true,
+	"policy":          true,
+	"x-bkt-signature": true,
+	"Content-Type":    true,
+}
+
+// PresignPostRequest represents the request body for issuing a presigned POST policy
+type PresignPostRequest struct {
+	Key         string `json:"key"`          // Exact key; may contain ${filename}
+	KeyPrefix   string `json:"key_prefix"`   // Or: any key under this prefix
+	ContentType string `json:"content_type"` // Exact media type, or a prefix ending in "/" (e.g. "image/")
+	MinSize     int64  `json:"min_size"`     // Bytes
+	MaxSize     int64  `json:"max_size"`     // Bytes; defaults to the server maximum
+	ExpiresIn   int64  `json:"expires_in"`   // Seconds; defaults to 1 hour, at most 7 days
+}
+
+// presignPostPolicy is the signed policy document, in S3's expiration + conditions shape
+type presignPostPolicy struct {
+	Expiration string          `json:"expiration"`
+	Conditions json.RawMessage `json:"conditions"`
+}
+
+// presignPostConditions are the parsed policy conditions a form upload is checked against
+type presignPostConditions struct {
+	bucket            string
+	key               string // Exact key (may contain ${filename})
+	keyPrefix         string
+	contentType       string
+	contentTypePrefix string
+	minSize, maxSize  int64
+	issuer            uuid.UUID
+}
+
+// signPresignPostPolicy signs a base64-encoded policy with a key derived from the JWT secret
+func (h *BucketHandler) signPresignPostPolicy(encodedPolicy string) string {
+	mac := hmac.New(sha256.New, []byte(h.config.Auth.JWTSecret))
+	mac.Write([]byte("bkt-presigned-post\n" + encodedPolicy))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PresignPost issues a signed POST policy that lets a browser form upload straight to the bucket
+// without a bkt session. The upload is performed as the issuing user when it is received
+func (h *BucketHandler) PresignPost(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req PresignPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	if (req.Key == "") == (req.KeyPrefix == "") {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "Set exactly one of key or key_prefix",
+		})
+		return
+	}
+	keyOrPrefix := bucket.NormalizeKey(req.Key + req.KeyPrefix)
+	if err := validation.ValidateObjectKey(strings.ReplaceAll(keyOrPrefix, presignPostFilenameVar, "file")); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid object key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.MaxSize == 0 || req.MaxSize > h.config.Storage.MaxFileSize {
+		req.MaxSize = h.config.Storage.MaxFileSize
+	}
+	if req.MinSize < 0 || req.MinSize > req.MaxSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid size range",
+			Message: fmt.Sprintf("min_size must be between 0 and max_size (%d)", req.MaxSize),
+		})
+		return
+	}
+
+	expiry := presignPostDefaultExpiry
+	if req.ExpiresIn != 0 {
+		expiry = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if expiry <= 0 || expiry > presignPostMaxExpiry {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid expiry",
+			Message: fmt.Sprintf("expires_in must be between 1 and %d seconds", int64(presignPostMaxExpiry.Seconds())),
+		})
+		return
+	}
+
+	// The issuer must be able to upload there now; the actual key is checked again on receipt
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, keyOrPrefix, services.ActionPutObject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to upload objects to this bucket",
+		})
+		return
+	}
+
+	fields := map[string]string{}
+	conditions := []interface{}{
+		map[string]string{"bucket": bucketName},
+		map[string]string{"x-bkt-credential": userUUID.String()},
+		[]interface{}{"content-length-range", req.MinSize, req.MaxSize},
+	}
+	if req.Key != "" {
+		fields["key"] = keyOrPrefix
+		conditions = append(conditions, map[string]string{"key": keyOrPrefix})
+	} else {
+		fields["key"] = keyOrPrefix + presignPostFilenameVar
+		conditions = append(conditions, []interface{}{"starts-with", "$key", keyOrPrefix})
+	}
+	if req.ContentType != "" {
+		if strings.HasSuffix(req.ContentType, "/") {
+			conditions = append(conditions, []interface{}{"starts-with", "$Content-Type", req.ContentType})
+		} else {
+			fields["Content-Type"] = req.ContentType
+			conditions = append(conditions, map[string]string{"Content-Type": req.ContentType})
+		}
+	}
+
+	expiresAt := time.Now().Add(expiry).UTC()
+	conditionsJSON, _ := json.Marshal(conditions)
+	policyJSON, _ := json.Marshal(presignPostPolicy{
+		Expiration: expiresAt.Format(time.RFC3339),
+		Conditions: conditionsJSON,
+	})
+	encodedPolicy := base64.StdEncoding.EncodeToString(policyJSON)
+	fields["policy"] = encodedPolicy
+	fields["x-bkt-signature"] = h.signPresignPostPolicy(encodedPolicy)
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"PresignPost", "Bucket", bucket.ID.String(), bucketName,
+		map[string]interface{}{"key": keyOrPrefix, "prefix": req.Key == "", "expires_at": expiresAt})
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        "/api/presigned-post/" + bucketName,
+		"fields":     fields,
+		"expires_at": expiresAt,
+		"conditions": json.RawMessage(conditionsJSON),
+	})
+}
+
+// PresignedPostUpload receives a browser form upload authorized by a presigned POST policy.
+// After the signature and every policy condition check out, it runs as a regular upload by
+// the policy's issuer, so permissions, quotas and content checks still apply
+func (h *BucketHandler) PresignedPostUpload(c *gin.Context) {
+	bucketName := c.Param("name")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Failed to get file",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Every value must be covered by the policy (e.g. no acl or expires_at smuggled in)
+	form := c.Request.MultipartForm
+	for name := range form.Value {
+		if !presignPostFormFields[name] {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid form",
+				Message: fmt.Sprintf("Form field %q is not allowed in a presigned POST", name),
+			})
+			return
+		}
+	}
+	for name := range form.File {
+		if name != "file" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid form",
+				Message: "Only one file, in the \"file\" field, may be uploaded",
+			})
+			return
+		}
+	}
+	c.Request.Header.Del("X-Expires-After")
+	c.Request.Header.Del("x-amz-acl")
+
+	encodedPolicy := c.PostForm("policy")
+	signature := c.PostForm("x-bkt-signature")
+	if encodedPolicy == "" || !hmac.Equal([]byte(signature), []byte(h.signPresignPostPolicy(encodedPolicy))) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Invalid signature",
+			Message: "The policy signature does not match",
+		})
+		return
+	}
+
+	conditions, err := parsePresignPostPolicy(encodedPolicy)
+	if err != nil {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Invalid policy",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil || conditions.bucket != bucketName {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Policy condition failed",
+			Message: "The policy does not cover this bucket",
+		})
+		return
+	}
+
+	// Resolve ${filename} the way S3 does, then hold the key to the policy
+	key := strings.ReplaceAll(c.PostForm("key"), presignPostFilenameVar, path.Base(fileHeader.Filename))
+	key = bucket.NormalizeKey(key)
+	if conditions.key != "" && key != bucket.NormalizeKey(strings.ReplaceAll(conditions.key, presignPostFilenameVar, path.Base(fileHeader.Filename))) ||
+		conditions.key == "" && !strings.HasPrefix(key, conditions.keyPrefix) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Policy condition failed",
+			Message: "The key is not allowed by the policy",
+		})
+		return
+	}
+	c.Request.PostForm.Set("key", key)
+
+	if fileHeader.Size < conditions.minSize || fileHeader.Size > conditions.maxSize {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Policy condition failed",
+			Message: fmt.Sprintf("The file size must be between %d and %d bytes", conditions.minSize, conditions.maxSize),
+		})
+		return
+	}
+
+	if conditions.contentType != "" || conditions.contentTypePrefix != "" {
+		contentType, err := h.presignPostContentType(c, fileHeader, key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to detect content type",
+				Message: err.Error(),
+			})
+			return
+		}
+		if conditions.contentType != "" && contentType != conditions.contentType ||
+			conditions.contentTypePrefix != "" && !strings.HasPrefix(contentType, conditions.contentTypePrefix) {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "Policy condition failed",
+				Message: fmt.Sprintf("Content type %s is not allowed by the policy", contentType),
+			})
+			return
+		}
+	}
+
+	// Upload as the issuer, provided they still exist and aren't locked
+	var issuer models.User
+	if err := database.DB.Where("id = ?", conditions.issuer).First(&issuer).Error; err != nil || issuer.IsLocked {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Invalid policy",
+			Message: "The user who issued this policy can no longer upload",
+		})
+		return
+	}
+	c.Set("user_id", issuer.ID)
+	c.Set("username", issuer.Username)
+	c.Set("is_admin", issuer.IsAdmin)
+
+	h.UploadObject(c)
+}
+
+// presignPostContentType is the type UploadObject would store for the file: the detected type,
+// or the declared one when it is trusted
+func (h *BucketHandler) presignPostContentType(c *gin.Context, fileHeader *multipart.FileHeader, key string) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	detectedType, firstBytes, err := validation.DetectContentType(file)
+	if err != nil {
+		return "", err
+	}
+	detectedType = validation.RefineContentType(detectedType, firstBytes, key, h.config.Storage.ExtensionContentTypes)
+
+	declared := c.PostForm("Content-Type")
+	if declared == "" {
+		declared = fileHeader.Header.Get("Content-Type")
+	}
+	return validation.ResolveContentType(detectedType, declared, h.config.Storage.TrustedContentTypes), nil
+}
+
+// parsePresignPostPolicy decodes a signed policy, rejecting expired ones and unknown conditions
+func parsePresignPostPolicy(encodedPolicy string) (*presignPostConditions, error) {
+	policyJSON, err := base64.StdEncoding.DecodeString(encodedPolicy)
+	if err != nil {
+		return nil, errors.New("policy is not valid base64")
+	}
+
+	var policy presignPostPolicy
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return nil, errors.New("policy is not valid JSON")
+	}
+
+	expiration, err := time.Parse(time.RFC3339, policy.Expiration)
+	if err != nil {
+		return nil, errors.New("policy has no valid expiration")
+	}
+	if time.Now().After(expiration) {
+		return nil, errors.New("policy expired")
+	}
+
+	var rawConditions []json.RawMessage
+	if err := json.Unmarshal(policy.Conditions, &rawConditions); err != nil {
+		return nil, errors.New("policy conditions are not a list")
+	}
+
+	conditions := &presignPostConditions{}
+	for _, raw := range rawConditions {
+		var exact map[string]string
+		if err := json.Unmarshal(raw, &exact); err == nil {
+			for name, value := range exact {
+				switch name {
+				case "bucket":
+					conditions.bucket = value
+				case "key":
+					conditions.key = value
+				case "Content-Type":
+					conditions.contentType = value
+				case "x-bkt-credential":
+					if conditions.issuer, err = uuid.Parse(value); err != nil {
+						return nil, errors.New("policy has an invalid credential")
+					}
+				default:
+					return nil, fmt.Errorf("unsupported policy condition %q", name)
+				}
+			}
+			continue
+		}
+
+		var rule []interface{}
+		if err := json.Unmarshal(raw, &rule); err != nil || len(rule) != 3 {
+			return nil, errors.New("malformed policy condition")
+		}
+		switch rule[0] {
+		case "starts-with":
+			value, _ := rule[2].(string)
+			switch rule[1] {
+			case "$key":
+				conditions.keyPrefix = value
+			case "$Content-Type":
+				conditions.contentTypePrefix = value
+			default:
+				return nil, fmt.Errorf("unsupported policy condition %v", rule[1])
+			}
+		case "content-length-range":
+			minSize, okMin := rule[1].(float64)
+			maxSize, okMax := rule[2].(float64)
+			if !okMin || !okMax {
+				return nil, errors.New("malformed content-length-range")
+			}
+			conditions.minSize, conditions.maxSize = int64(minSize), int64(maxSize)
+		default:
+			return nil, fmt.Errorf("unsupported policy condition %v", rule[0])
+		}
+	}
+
+	if conditions.bucket == "" || conditions.issuer == uuid.Nil || conditions.maxSize == 0 ||
+		(conditions.key == "" && conditions.keyPrefix == "") {
+		return nil, errors.New("policy is missing required conditions")
+	}
+	return conditions, nil
+}
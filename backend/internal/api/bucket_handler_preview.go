@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// hexPreviewMaxBytes caps the hex view of binary objects (a hex dump is ~4x the input)
+const hexPreviewMaxBytes = 4096
+
+// PreviewObject returns the first bytes of a text-like object for quick inspection in the UI.
+// Only the previewed range is read from storage. Binary objects are rejected unless
+// ?format=hex is given, in which case a hex dump of the head is returned
+func (h *BucketHandler) PreviewObject(c *gin.Context) {
+	bucketName := c.Param("name")
+	objectKey := strings.TrimPrefix(c.Param("key"), "/")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	hexView := c.Query("format") == "hex"
+
+	// Get bucket from database
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Check policy permissions
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, objectKey, services.ActionGetObject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to read this object",
+		})
+		return
+	}
+
+	var object models.Object
+	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Object not found",
+		})
+		return
+	}
+
+	if !hexView && !validation.IsTextContentType(object.ContentType) {
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{
+			Error:   "Preview not available",
+			Message: "Object is not a text type; use format=hex for a hex view",
+		})
+		return
+	}
+
+	// Preview size: server cap, optionally lowered with ?bytes=
+	limit := h.config.Storage.PreviewMaxBytes
+	if hexView && limit > hexPreviewMaxBytes {
+		limit = hexPreviewMaxBytes
+	}
+	if bytesStr := c.Query("bytes"); bytesStr != "" {
+		requested, err := strconv.ParseInt(bytesStr, 10, 64)
+		if err != nil || requested <= 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid bytes parameter",
+			})
+			return
+		}
+		if requested < limit {
+			limit = requested
+		}
+	}
+	if object.Size < limit {
+		limit = object.Size
+	}
+
+	var data []byte
+	if limit > 0 {
+		storageBackend, err := h.getStorageBackend(&bucket)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to initialize storage backend",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		reader, err := storageBackend.GetObjectRange(bucketName, objectKey, 0, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to retrieve object",
+				Message: err.Error(),
+			})
+			return
+		}
+		defer reader.Close()
+
+		data, err = io.ReadAll(io.LimitReader(reader, limit))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to read object",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	truncated := int64(len(data)) < object.Size
+
+	response := gin.H{
+		"bucket":       bucketName,
+		"key":          objectKey,
+		"content_type": object.ContentType,
+		"size":         object.Size,
+		"truncated":    truncated,
+	}
+
+	if hexView {
+		response["format"] = "hex"
+		response["bytes"] = len(data)
+		response["content"] = hex.Dump(data)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// The stored type is text-like, but the bytes must agree before they're returned as a string
+	if truncated {
+		data = validation.TrimPartialRune(data)
+	}
+	if !validation.LooksLikeText(data) {
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{
+			Error:   "Preview not available",
+			Message: "Object content is not valid UTF-8 text; use format=hex for a hex view",
+		})
+		return
+	}
+
+	response["format"] = "text"
+	response["bytes"] = len(data)
+	response["content"] = string(data)
+	c.JSON(http.StatusOK, response)
+}
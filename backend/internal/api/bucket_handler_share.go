@@ -0,0 +1,382 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bkt/internal/auth"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Share link lifetimes
+const (
+	shareLinkDefaultExpiry = 7 * 24 * time.Hour
+	shareLinkMaxExpiry     = 90 * 24 * time.Hour
+)
+
+// shareLinkTokenBytes is the entropy of a share token (URL-safe base64 encoded)
+const shareLinkTokenBytes = 32
+
+// shareLinkPasswordHeader carries the password of a protected link on GET requests
+const shareLinkPasswordHeader = "X-Share-Password"
+
+// CreateShareLinkRequest represents the request body for sharing an object
+type CreateShareLinkRequest struct {
+	Key          string `json:"key" binding:"required"`
+	ExpiresIn    int64  `json:"expires_in"`    // Seconds; defaults to 7 days, at most 90 days
+	MaxDownloads int    `json:"max_downloads"` // 0 = unlimited
+	Password     string `json:"password"`      // Optional; downloads must present it
+}
+
+// ShareLinkResponse is a share link as returned to its creator. Token and URL are only set on
+// creation, since only the token's hash is stored
+type ShareLinkResponse struct {
+	models.ShareLink
+	BucketName  string `json:"bucket"`
+	HasPassword bool   `json:"has_password"`
+	Token       string `json:"token,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// hashShareToken is the stored (and looked-up) form of a share token
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateShareLink handles POST /api/buckets/:name/shares. The caller must be able to download
+// the object; the link keeps working only while they still can
+func (h *BucketHandler) CreateShareLink(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+	objectKey := bucket.NormalizeKey(req.Key)
+
+	expiry := shareLinkDefaultExpiry
+	if req.ExpiresIn != 0 {
+		expiry = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if expiry <= 0 || expiry > shareLinkMaxExpiry {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid expiry",
+			Message: fmt.Sprintf("expires_in must be between 1 and %d seconds", int64(shareLinkMaxExpiry.Seconds())),
+		})
+		return
+	}
+	if req.MaxDownloads < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid download limit",
+			Message: "max_downloads must be 0 (unlimited) or more",
+		})
+		return
+	}
+
+	// Denials are reported per OBJECT_DENIAL_MODE, like a download
+	if _, access, err := h.resolveObjectAccess(c, userUUID, &bucket, objectKey, services.ActionGetObject); !h.respondObjectAccess(c, access, err, "You don't have permission to share this object") {
+		return
+	}
+
+	link := models.ShareLink{
+		BucketID:     bucket.ID,
+		ObjectKey:    objectKey,
+		CreatedBy:    userUUID,
+		ExpiresAt:    time.Now().Add(expiry).UTC(),
+		MaxDownloads: req.MaxDownloads,
+	}
+	if req.Password != "" {
+		passwordHash, err := auth.HashPassword(req.Password, h.config.Auth.BcryptCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to create share link",
+				Message: "An internal error occurred. Please try again.",
+			})
+			return
+		}
+		link.PasswordHash = passwordHash
+	}
+
+	tokenBytes := make([]byte, shareLinkTokenBytes)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create share link",
+			Message: "An internal error occurred. Please try again.",
+		})
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+	link.TokenHash = hashShareToken(token)
+
+	if err := database.DB.Create(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create share link",
+			Message: "An internal error occurred. Please try again.",
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"CreateShareLink", "Object", link.ID.String(), bucketName+"/"+objectKey,
+		map[string]interface{}{
+			"expires_at":    link.ExpiresAt,
+			"max_downloads": link.MaxDownloads,
+			"has_password":  link.HasPassword(),
+		})
+
+	c.JSON(http.StatusCreated, ShareLinkResponse{
+		ShareLink:   link,
+		BucketName:  bucketName,
+		HasPassword: link.HasPassword(),
+		Token:       token,
+		URL:         "/api/share/" + token,
+	})
+}
+
+// ListShareLinks handles GET /api/shares: the caller's links, newest first. Revoked and expired
+// links are included (?active=true leaves them out) so their download counts stay visible
+func (h *BucketHandler) ListShareLinks(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	page, ok := bindPageParams(c, 0, maxPageLimit)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.ShareLink{}).Where("created_by = ?", userUUID)
+	if c.Query("active") == "true" {
+		query = query.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if page.envelope {
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to fetch share links",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	var links []models.ShareLink
+	if err := page.apply(query).Preload("Bucket").Order("created_at DESC").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to fetch share links",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	responses := make([]ShareLinkResponse, len(links))
+	for i, link := range links {
+		responses[i] = ShareLinkResponse{
+			ShareLink:   link,
+			BucketName:  link.Bucket.Name,
+			HasPassword: link.HasPassword(),
+		}
+	}
+
+	respondPage(c, page, responses, len(responses), total)
+}
+
+// RevokeShareLink handles DELETE /api/shares/:id (the creator or an admin). The link stops
+// working immediately and stays listed as revoked
+func (h *BucketHandler) RevokeShareLink(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+	isAdmin, _ := c.Get("is_admin")
+
+	linkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid share link ID",
+		})
+		return
+	}
+
+	var link models.ShareLink
+	if err := database.DB.Preload("Bucket").First(&link, "id = ?", linkID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Share link not found",
+		})
+		return
+	}
+	if link.CreatedBy != userUUID && isAdmin != true {
+		// Other users' links are reported as missing
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Share link not found",
+		})
+		return
+	}
+
+	if link.RevokedAt == nil {
+		now := time.Now()
+		if err := database.DB.Model(&link).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to revoke share link",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		h.auditService.LogSuccess(c, userUUID, username.(string),
+			"RevokeShareLink", "Object", link.ID.String(), link.Bucket.Name+"/"+link.ObjectKey,
+			map[string]interface{}{"download_count": link.DownloadCount})
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Share link revoked",
+	})
+}
+
+// DownloadShareLink handles GET /api/share/:token (and POST, for password forms). No session is
+// needed: the token, expiry, download limit and password are checked, then the object is
+// streamed with the creator's permissions. Every successful request counts as one download
+func (h *BucketHandler) DownloadShareLink(c *gin.Context) {
+	var link models.ShareLink
+	if err := database.DB.Preload("Bucket").Where("token_hash = ?", hashShareToken(c.Param("token"))).First(&link).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Share link not found",
+		})
+		return
+	}
+
+	switch {
+	case link.RevokedAt != nil:
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Share link has been revoked"})
+		return
+	case time.Now().After(link.ExpiresAt):
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Share link has expired"})
+		return
+	case link.MaxDownloads > 0 && link.DownloadCount >= link.MaxDownloads:
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Share link download limit reached"})
+		return
+	}
+
+	if link.HasPassword() {
+		password := c.GetHeader(shareLinkPasswordHeader)
+		if c.Request.Method == http.MethodPost {
+			password = c.PostForm("password")
+		}
+		if password == "" {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error:   "Password required",
+				Message: "Send the password in the " + shareLinkPasswordHeader + " header, or POST it as the password form field",
+			})
+			return
+		}
+		if !auth.CheckPassword(password, link.PasswordHash) {
+			logger.Warn("Share link password rejected", map[string]interface{}{
+				"share_link_id": link.ID.String(),
+				"ip":            c.ClientIP(),
+			})
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error: "Invalid password",
+			})
+			return
+		}
+	}
+
+	// The creator must still be allowed to download the object; otherwise it's as if it's gone.
+	// Buckets in the trash don't preload, so their links resolve to nothing
+	bucketName := link.Bucket.Name
+	allowed, err := h.policyService.CheckObjectAccess(c, link.CreatedBy, bucketName, link.ObjectKey, services.ActionGetObject)
+	var object models.Object
+	if err == nil && allowed && link.Bucket.ID != uuid.Nil {
+		err = database.DB.Where("bucket_id = ? AND key = ?", link.BucketID, link.ObjectKey).First(&object).Error
+	}
+	if err != nil || !allowed {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Shared object not found",
+		})
+		return
+	}
+
+	storageBackend, err := h.getStorageBackend(&link.Bucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to initialize storage backend",
+		})
+		return
+	}
+	file, err := storageBackend.GetObject(bucketName, object.Key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to retrieve object",
+		})
+		return
+	}
+	defer file.Close()
+
+	// Claim the download atomically, so concurrent requests can't exceed max_downloads
+	result := database.DB.Model(&models.ShareLink{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ? AND (max_downloads = 0 OR download_count < max_downloads)", link.ID, time.Now()).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to record download",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Share link is no longer available"})
+		return
+	}
+
+	// Bandwidth and access logs are attributed to the creator
+	c.Set("user_id", link.CreatedBy)
+	c.Set(middleware.UsageBucketKey, bucketName)
+
+	logger.Info("Shared object downloaded", map[string]interface{}{
+		"share_link_id": link.ID.String(),
+		"bucket":        bucketName,
+		"key":           object.Key,
+		"ip":            c.ClientIP(),
+	})
+
+	c.Header("Content-Disposition", applyObjectSecurityHeaders(c, object.ContentType, attachmentDisposition(object.Key), object.Key))
+	c.Header("Content-Length", strconv.FormatInt(object.Size, 10))
+	c.Header("ETag", fmt.Sprintf(`"%s"`, object.ETag))
+	c.Header("Last-Modified", object.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "private, no-store")
+	setContentSHA256Header(c, &object, false)
+
+	// Anonymous: counts towards the object's access stats, not the creator's recent objects
+	recordObjectAccess(uuid.Nil, object.ID)
+
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, file, nil)
+}
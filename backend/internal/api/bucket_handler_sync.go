@@ -0,0 +1,349 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Bucket sync job states
+const (
+	bucketSyncRunning   = "running"
+	bucketSyncCompleted = "completed"
+	bucketSyncFailed    = "failed"
+)
+
+// BucketSyncJob reports the progress of a full storage-to-database sync of one bucket
+type BucketSyncJob struct {
+	ID          uuid.UUID  `json:"id"`
+	Bucket      string     `json:"bucket"`
+	Status      string     `json:"status"`
+	Phase       string     `json:"phase"`   // "scanning" storage, then "pruning" stale rows
+	Scanned     int        `json:"scanned"` // Storage objects seen so far
+	Added       int        `json:"added"`
+	Updated     int        `json:"updated"`
+	Removed     int        `json:"removed"`
+	Error       string     `json:"error,omitempty"`
+	StartedBy   string     `json:"started_by"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Latest sync job per bucket (per instance); a bucket has at most one running job
+var (
+	bucketSyncJobs   = make(map[string]*BucketSyncJob)
+	bucketSyncJobsMu sync.Mutex
+)
+
+// snapshot copies a job under the registry lock so it can be serialized safely
+func (j *BucketSyncJob) snapshot() BucketSyncJob {
+	bucketSyncJobsMu.Lock()
+	defer bucketSyncJobsMu.Unlock()
+	return *j
+}
+
+func (j *BucketSyncJob) update(fn func(j *BucketSyncJob)) {
+	bucketSyncJobsMu.Lock()
+	defer bucketSyncJobsMu.Unlock()
+	fn(j)
+}
+
+// canSyncBucket reports whether the caller is an admin or the bucket owner
+func canSyncBucket(c *gin.Context, bucket *models.Bucket) bool {
+	if isAdmin, _ := c.Get("is_admin"); isAdmin == true {
+		return true
+	}
+	userID, _ := c.Get("user_id")
+	return userID.(uuid.UUID) == bucket.OwnerID
+}
+
+// SyncBucket starts a complete reconcile of the bucket's storage backend into the objects table
+// (admin or bucket owner). Unlike the best-effort sync in ListObjects it is not capped per
+// request: storage is walked page by page, new objects are added, changed sizes/ETags updated
+// and rows whose file is gone removed. Runs in the background; poll GET for progress
+func (h *BucketHandler) SyncBucket(c *gin.Context) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	if !canSyncBucket(c, &bucket) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "Only admins and the bucket owner can sync a bucket",
+		})
+		return
+	}
+
+	storageBackend, err := h.getStorageBackend(&bucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to initialize storage backend",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	bucketSyncJobsMu.Lock()
+	if existing, exists := bucketSyncJobs[bucketName]; exists && existing.Status == bucketSyncRunning {
+		job := *existing
+		bucketSyncJobsMu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "A sync is already running for this bucket",
+			"job":   job,
+		})
+		return
+	}
+	job := &BucketSyncJob{
+		ID:        uuid.New(),
+		Bucket:    bucketName,
+		Status:    bucketSyncRunning,
+		Phase:     "scanning",
+		StartedBy: username.(string),
+		StartedAt: time.Now(),
+	}
+	bucketSyncJobs[bucketName] = job
+	bucketSyncJobsMu.Unlock()
+
+	h.auditService.LogSuccess(c, userID.(uuid.UUID), username.(string),
+		"SyncBucket", "Bucket", bucket.ID.String(), bucketName,
+		map[string]interface{}{
+			"job_id": job.ID.String(),
+		})
+
+	go runBucketSync(job, bucket, storageBackend)
+
+	c.JSON(http.StatusAccepted, job.snapshot())
+}
+
+// GetBucketSync returns the progress of the bucket's most recent sync job
+func (h *BucketHandler) GetBucketSync(c *gin.Context) {
+	bucketName := c.Param("name")
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	if !canSyncBucket(c, &bucket) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "Only admins and the bucket owner can view bucket syncs",
+		})
+		return
+	}
+
+	bucketSyncJobsMu.Lock()
+	job, exists := bucketSyncJobs[bucketName]
+	bucketSyncJobsMu.Unlock()
+	if !exists {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "No sync has run for this bucket",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job.snapshot())
+}
+
+// runBucketSync performs the sync and records the outcome on the job
+func runBucketSync(job *BucketSyncJob, bucket models.Bucket, storageBackend storage.StorageBackend) {
+	err := syncBucketObjects(job, &bucket, storageBackend)
+
+	now := time.Now()
+	job.update(func(j *BucketSyncJob) {
+		j.CompletedAt = &now
+		j.Status = bucketSyncCompleted
+		if err != nil {
+			j.Status = bucketSyncFailed
+			j.Error = err.Error()
+		}
+	})
+
+	result := job.snapshot()
+	logger.Info("Bucket sync finished", map[string]interface{}{
+		"bucket":  bucket.Name,
+		"status":  result.Status,
+		"scanned": result.Scanned,
+		"added":   result.Added,
+		"updated": result.Updated,
+		"removed": result.Removed,
+		"error":   result.Error,
+	})
+}
+
+// syncBatchTimeout bounds each bulk statement of a bucket sync. The sync as a whole may run for
+// hours on large buckets, so only the individual batches are given a deadline
+const syncBatchTimeout = 2 * time.Minute
+
+func syncBucketObjects(job *BucketSyncJob, bucket *models.Bucket, storageBackend storage.StorageBackend) error {
+	// Keys seen in storage, used afterwards to find rows whose file is gone
+	seen := make(map[string]struct{})
+
+	err := storage.WalkObjects(storageBackend, bucket.Name, "", func(page []storage.ObjectInfo) error {
+		added, updated, err := syncObjectPage(bucket, page)
+		if err != nil {
+			return err
+		}
+		for _, obj := range page {
+			seen[obj.Key] = struct{}{}
+		}
+		job.update(func(j *BucketSyncJob) {
+			j.Scanned += len(page)
+			j.Added += added
+			j.Updated += updated
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan storage: %w", err)
+	}
+
+	job.update(func(j *BucketSyncJob) { j.Phase = "pruning" })
+
+	// Walk the rows in key order and remove those without a file. Objects uploaded after the
+	// scan passed their key are confirmed with a direct existence check before removal
+	const batchSize = 1000
+	lastKey := ""
+	for {
+		var rows []struct {
+			ID  uuid.UUID
+			Key string
+		}
+		if err := syncBatch(func(db *gorm.DB) error {
+			return db.Model(&models.Object{}).Select("id, key").
+				Where("bucket_id = ? AND key > ?", bucket.ID, lastKey).
+				Order("key ASC").Limit(batchSize).Scan(&rows).Error
+		}); err != nil {
+			return fmt.Errorf("failed to load objects: %w", err)
+		}
+
+		staleIDs := make([]uuid.UUID, 0)
+		for _, row := range rows {
+			if _, ok := seen[row.Key]; ok {
+				continue
+			}
+			exists, err := storageBackend.ObjectExists(bucket.Name, row.Key)
+			if err != nil || exists {
+				continue
+			}
+			staleIDs = append(staleIDs, row.ID)
+		}
+		if len(staleIDs) > 0 {
+			var removed int64
+			if err := syncBatch(func(db *gorm.DB) error {
+				res := db.Where("id IN ?", staleIDs).Delete(&models.Object{})
+				removed = res.RowsAffected
+				return res.Error
+			}); err != nil {
+				return fmt.Errorf("failed to remove stale rows: %w", err)
+			}
+			job.update(func(j *BucketSyncJob) { j.Removed += int(removed) })
+		}
+
+		if len(rows) < batchSize {
+			return nil
+		}
+		lastKey = rows[len(rows)-1].Key
+	}
+}
+
+// syncObjectPage inserts rows for untracked storage objects and refreshes the size/ETag of
+// rows that no longer match storage. Returns the added and updated counts
+func syncObjectPage(bucket *models.Bucket, page []storage.ObjectInfo) (int, int, error) {
+	if len(page) == 0 {
+		return 0, 0, nil
+	}
+
+	keys := make([]string, len(page))
+	for i, obj := range page {
+		keys[i] = obj.Key
+	}
+
+	var existing []models.Object
+	if err := syncBatch(func(db *gorm.DB) error {
+		return db.Select("id, key, size, e_tag").
+			Where("bucket_id = ? AND key IN ?", bucket.ID, keys).Find(&existing).Error
+	}); err != nil {
+		return 0, 0, fmt.Errorf("failed to load objects: %w", err)
+	}
+	byKey := make(map[string]models.Object, len(existing))
+	for _, obj := range existing {
+		byKey[obj.Key] = obj
+	}
+
+	added, updated := 0, 0
+	valueStrings := make([]string, 0, len(page))
+	valueArgs := make([]interface{}, 0, len(page)*8)
+	for _, obj := range page {
+		row, tracked := byKey[obj.Key]
+		if tracked {
+			if row.Size == obj.Size && row.ETag == obj.ETag {
+				continue
+			}
+			res := database.DB.Model(&models.Object{}).Where("id = ?", row.ID).
+				Updates(map[string]interface{}{"size": obj.Size, "e_tag": obj.ETag, "updated_at": time.Now()})
+			if res.Error != nil {
+				return added, updated, fmt.Errorf("failed to update %s: %w", obj.Key, res.Error)
+			}
+			updated += int(res.RowsAffected)
+			continue
+		}
+
+		lastModified := time.Now()
+		if obj.LastModified != "" {
+			if parsed, err := time.Parse(time.RFC3339, obj.LastModified); err == nil {
+				lastModified = parsed
+			}
+		}
+		valueStrings = append(valueStrings, "(gen_random_uuid(), ?, ?, ?, ?, ?, ?, '', ?, ?)")
+		valueArgs = append(valueArgs, bucket.ID, obj.Key, obj.Size, obj.ContentType, obj.ETag, obj.Key, lastModified, lastModified)
+	}
+
+	if len(valueStrings) > 0 {
+		query := fmt.Sprintf(`
+			INSERT INTO objects (id, bucket_id, key, size, content_type, e_tag, storage_path, sha256, created_at, updated_at)
+			VALUES %s
+			ON CONFLICT (bucket_id, key) DO NOTHING
+		`, strings.Join(valueStrings, ","))
+		var inserted int64
+		if err := syncBatch(func(db *gorm.DB) error {
+			res := db.Exec(query, valueArgs...)
+			inserted = res.RowsAffected
+			return res.Error
+		}); err != nil {
+			return added, updated, fmt.Errorf("failed to add objects: %w", err)
+		}
+		added = int(inserted)
+	}
+
+	return added, updated, nil
+}
+
+// syncBatch runs one bulk sync statement under syncBatchTimeout instead of the default query timeout
+func syncBatch(fn func(db *gorm.DB) error) error {
+	db, cancel := database.WithTimeout(syncBatchTimeout)
+	defer cancel()
+	return fn(db)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ObjectTagsRequest represents the request body for replacing an object's tags
+type ObjectTagsRequest struct {
+	Tags map[string]string `json:"tags"`
+}
+
+// loadObjectTags returns an object's tags as a map
+func loadObjectTags(objectID uuid.UUID) (map[string]string, error) {
+	var rows []models.ObjectTag
+	if err := database.DB.Where("object_id = ?", objectID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(rows))
+	for _, tag := range rows {
+		tags[tag.Key] = tag.Value
+	}
+	return tags, nil
+}
+
+// replaceObjectTags replaces an object's whole tag set in one transaction (an empty set clears it)
+func replaceObjectTags(objectID uuid.UUID, tags map[string]string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("object_id = ?", objectID).Delete(&models.ObjectTag{}).Error; err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		rows := make([]models.ObjectTag, 0, len(tags))
+		for key, value := range tags {
+			rows = append(rows, models.ObjectTag{ObjectID: objectID, Key: key, Value: value})
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// loadObjectForTags loads the bucket and object named by the request and verifies the caller may
+// perform action on the object, writing the JSON error response otherwise
+func (h *BucketHandler) loadObjectForTags(c *gin.Context, action, deniedMessage string) (*models.Bucket, *models.Object, bool) {
+	bucketName := c.Param("name")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return nil, nil, false
+	}
+
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey := bucket.NormalizeKey(strings.TrimPrefix(c.Param("key"), "/"))
+
+	// Denials are reported per OBJECT_DENIAL_MODE
+	object, access, err := h.resolveObjectAccess(c, userUUID, &bucket, objectKey, action)
+	if !h.respondObjectAccess(c, access, err, deniedMessage) {
+		return nil, nil, false
+	}
+
+	return &bucket, object, true
+}
+
+// GetObjectTags handles GET /api/buckets/:name/tags/*key
+func (h *BucketHandler) GetObjectTags(c *gin.Context) {
+	_, object, ok := h.loadObjectForTags(c, services.ActionGetObjectTagging, "You don't have permission to read this object's tags")
+	if !ok {
+		return
+	}
+
+	tags, err := loadObjectTags(object.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to load tags",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"key":  object.Key,
+		"tags": tags,
+	})
+}
+
+// SetObjectTags handles PUT /api/buckets/:name/tags/*key: replaces the object's tags (up to 10).
+// The object's content and Last-Modified time are unchanged
+func (h *BucketHandler) SetObjectTags(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+
+	bucket, object, ok := h.loadObjectForTags(c, services.ActionPutObjectTagging, "You don't have permission to tag this object")
+	if !ok {
+		return
+	}
+
+	var req ObjectTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := validation.ValidateObjectTags(req.Tags); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid tags",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := replaceObjectTags(object.ID, req.Tags); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to save tags",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, userID.(uuid.UUID), username.(string),
+		"PutObjectTagging", "Object", object.ID.String(), bucket.Name+"/"+object.Key,
+		map[string]interface{}{"tags": len(req.Tags)})
+
+	if req.Tags == nil {
+		req.Tags = map[string]string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"key":  object.Key,
+		"tags": req.Tags,
+	})
+}
+
+// DeleteObjectTags handles DELETE /api/buckets/:name/tags/*key: removes all of the object's tags
+func (h *BucketHandler) DeleteObjectTags(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+
+	bucket, object, ok := h.loadObjectForTags(c, services.ActionDeleteObjectTagging, "You don't have permission to untag this object")
+	if !ok {
+		return
+	}
+
+	if err := replaceObjectTags(object.ID, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to delete tags",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, userID.(uuid.UUID), username.(string),
+		"DeleteObjectTagging", "Object", object.ID.String(), bucket.Name+"/"+object.Key, nil)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Tags deleted successfully",
+	})
+}
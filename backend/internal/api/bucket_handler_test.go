@@ -0,0 +1,45 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAttachmentDisposition(t *testing.T) {
+	tests := []struct {
+		name      string
+		objectKey string
+		want      string
+	}{
+		{"plain key", "reports/q1.pdf", `attachment; filename="q1.pdf"`},
+		{"path traversal", "../../etc/passwd", `attachment; filename="passwd"`},
+		{"dot-dot segment", "reports/..", `attachment; filename="download"`},
+		{"dot", ".", `attachment; filename="download"`},
+		{"root", "/", `attachment; filename="download"`},
+		{"empty", "", `attachment; filename="download"`},
+		{"folder marker", "reports/", `attachment; filename="reports"`},
+		{"CRLF header injection", "evil\r\nSet-Cookie: session=x.txt", `attachment; filename="evilSet-Cookie: session=x.txt"`},
+		{"NUL, tab and DEL", "a\x00b\tc\x7f.txt", `attachment; filename="abc.txt"`},
+		{"C1 control", "a\u0085b.txt", `attachment; filename="ab.txt"`},
+		{"only control characters", "\r\n", `attachment; filename="download"`},
+		{"quotes", `say "hi".txt`, `attachment; filename="say \"hi\".txt"`},
+		{"backslash", `a\b.txt`, `attachment; filename="a\\b.txt"`},
+		{"quoted-string breakout", `x.txt"; filename="evil.exe`, `attachment; filename="x.txt\"; filename=\"evil.exe"`},
+		{"bidi override", "invoice\u202Efdp.exe", `attachment; filename="invoicefdp.exe"`},
+		{"non-ASCII", "résumé.pdf", `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`},
+		{"emoji", "📄 notes.txt", `attachment; filename="_ notes.txt"; filename*=UTF-8''%F0%9F%93%84%20notes.txt`},
+		{"parameter injection in filename*", "ü;filename=x.exe", `attachment; filename="_;filename=x.exe"; filename*=UTF-8''%C3%BC%3Bfilename%3Dx.exe`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := attachmentDisposition(tt.objectKey)
+			if got != tt.want {
+				t.Errorf("attachmentDisposition(%q) = %s, want %s", tt.objectKey, got, tt.want)
+			}
+			if strings.ContainsAny(got, "\r\n\x00") {
+				t.Errorf("attachmentDisposition(%q) contains a control character: %q", tt.objectKey, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// purgeBucket permanently deletes a bucket (live or in the trash): its objects in storage, the
+// storage bucket, and every database row belonging to it. Storage errors don't stop the purge;
+// they're returned for the audit log
+func (h *BucketHandler) purgeBucket(bucket *models.Bucket) (int, []string, error) {
+	storageBackend, err := h.getStorageBackend(bucket)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get storage backend: %w", err)
+	}
+
+	// Get all objects in the bucket
+	var objects []models.Object
+	if err := database.DB.Where("bucket_id = ?", bucket.ID).Find(&objects).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to list bucket objects: %w", err)
+	}
+
+	// Delete all objects from storage first
+	var storageErrors []string
+	for _, obj := range objects {
+		if err := storageBackend.DeleteObject(bucket.Name, obj.Key); err != nil {
+			// Log error but continue - we'll still try to delete the rest
+			storageErrors = append(storageErrors, fmt.Sprintf("%s: %v", obj.Key, err))
+		}
+	}
+
+	// Unfinished multipart uploads are staged outside the bucket's objects
+	var uploads []models.MultipartUpload
+	if err := database.DB.Where("bucket_id = ?", bucket.ID).Find(&uploads).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to list multipart uploads: %w", err)
+	}
+	for i := range uploads {
+		if err := abortMultipartUpload(storageBackend, bucket.Name, &uploads[i]); err != nil {
+			storageErrors = append(storageErrors, fmt.Sprintf("multipart upload %s: %v", uploads[i].Key, err))
+		}
+	}
+
+	// Previous object versions are stored apart from the objects
+	var versions []models.ObjectVersion
+	if err := database.DB.Where("bucket_id = ? AND is_delete_marker = ?", bucket.ID, false).Find(&versions).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to list object versions: %w", err)
+	}
+	if len(versions) > 0 {
+		versioner, err := storage.Versioner(storageBackend)
+		if err != nil {
+			return 0, nil, err
+		}
+		for i := range versions {
+			if err := versioner.DeleteObjectVersion(bucket.Name, versions[i].StorageID()); err != nil {
+				storageErrors = append(storageErrors, fmt.Sprintf("%s (version %s): %v", versions[i].Key, versions[i].VersionID, err))
+			}
+		}
+	}
+
+	// Delete the bucket from storage backend (after objects are removed)
+	if err := storageBackend.DeleteBucket(bucket.Name); err != nil {
+		storageErrors = append(storageErrors, fmt.Sprintf("bucket deletion: %v", err))
+	}
+
+	// Use transaction to delete all objects and the bucket from database
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Delete all objects from database
+		if len(objects) > 0 {
+			if err := tx.Where("bucket_id = ?", bucket.ID).Delete(&models.Object{}).Error; err != nil {
+				return fmt.Errorf("failed to delete objects: %w", err)
+			}
+		}
+
+		if err := tx.Where("bucket_id = ?", bucket.ID).Delete(&models.ObjectVersion{}).Error; err != nil {
+			return fmt.Errorf("failed to delete object versions: %w", err)
+		}
+
+		// Delete any bucket policies
+		if err := tx.Where("bucket_id = ?", bucket.ID).Delete(&models.BucketPolicy{}).Error; err != nil {
+			return fmt.Errorf("failed to delete bucket policies: %w", err)
+		}
+
+		// Stop serving the bucket as a website
+		if err := tx.Where("bucket_id = ?", bucket.ID).Delete(&models.BucketWebsite{}).Error; err != nil {
+			return fmt.Errorf("failed to delete website configuration: %w", err)
+		}
+
+		// Share links would otherwise resolve if a bucket with the same ID reappeared on import
+		if err := tx.Where("bucket_id = ?", bucket.ID).Delete(&models.ShareLink{}).Error; err != nil {
+			return fmt.Errorf("failed to delete share links: %w", err)
+		}
+
+		// Delete the bucket row itself, not just its trash marker
+		if err := tx.Unscoped().Delete(bucket).Error; err != nil {
+			return fmt.Errorf("failed to delete bucket: %w", err)
+		}
+
+		return nil
+	})
+
+	return len(objects), storageErrors, err
+}
+
+// trashBucket soft-deletes a bucket: it disappears from listings and stops accepting requests,
+// but its objects are kept until the grace period (BUCKET_DELETE_GRACE) ends
+func (h *BucketHandler) trashBucket(c *gin.Context, bucket *models.Bucket) {
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+
+	if err := database.DB.Delete(bucket).Error; err != nil {
+		h.auditService.LogFailure(
+			c,
+			userID.(uuid.UUID),
+			username.(string),
+			"DeleteBucket",
+			"Bucket",
+			bucket.ID.String(),
+			bucket.Name,
+			err.Error(),
+			map[string]interface{}{
+				"bucket_name":  bucket.Name,
+				"soft_deleted": true,
+			},
+		)
+
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to delete bucket",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	services.InvalidateBucketPolicyCache(bucket.Name)
+
+	purgeAt := time.Now().Add(h.config.Storage.BucketDeleteGraceDuration)
+	h.auditService.LogSuccess(
+		c,
+		userID.(uuid.UUID),
+		username.(string),
+		"DeleteBucket",
+		"Bucket",
+		bucket.ID.String(),
+		bucket.Name,
+		map[string]interface{}{
+			"bucket_name":  bucket.Name,
+			"owner_id":     bucket.OwnerID.String(),
+			"soft_deleted": true,
+			"purge_at":     purgeAt,
+		},
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Bucket moved to the trash. An admin can restore it until it is purged",
+		"bucket":   bucket.Name,
+		"purge_at": purgeAt,
+	})
+}
+
+// DeletedBucket is a bucket in the trash
+type DeletedBucket struct {
+	models.Bucket
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// ListDeletedBuckets handles GET /api/admin/deleted-buckets: soft-deleted buckets awaiting purge,
+// most recently deleted first
+func (h *BucketHandler) ListDeletedBuckets(c *gin.Context) {
+	var buckets []models.Bucket
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&buckets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to list deleted buckets",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	deleted := make([]DeletedBucket, len(buckets))
+	for i, bucket := range buckets {
+		deleted[i] = DeletedBucket{
+			Bucket:  bucket,
+			PurgeAt: bucket.DeletedAt.Time.Add(h.config.Storage.BucketDeleteGraceDuration),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"buckets": deleted,
+		"count":   len(deleted),
+	})
+}
+
+// findDeletedBucket loads a bucket in the trash by name, writing a 404 if there is none
+func findDeletedBucket(c *gin.Context) (*models.Bucket, bool) {
+	var bucket models.Bucket
+	if err := database.DB.Unscoped().Where("name = ? AND deleted_at IS NOT NULL", c.Param("name")).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Deleted bucket not found",
+		})
+		return nil, false
+	}
+	return &bucket, true
+}
+
+// RestoreBucket handles POST /api/admin/deleted-buckets/:name/restore: takes a bucket out of the
+// trash with its objects, policy and settings as they were
+func (h *BucketHandler) RestoreBucket(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+
+	bucket, ok := findDeletedBucket(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Unscoped().Model(bucket).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to restore bucket",
+			Message: err.Error(),
+		})
+		return
+	}
+	services.InvalidateBucketPolicyCache(bucket.Name)
+
+	h.auditService.LogSuccess(
+		c,
+		userID.(uuid.UUID),
+		username.(string),
+		"RestoreBucket",
+		"Bucket",
+		bucket.ID.String(),
+		bucket.Name,
+		map[string]interface{}{
+			"bucket_name": bucket.Name,
+			"owner_id":    bucket.OwnerID.String(),
+		},
+	)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Bucket restored successfully",
+	})
+}
+
+// PurgeDeletedBucket handles DELETE /api/admin/deleted-buckets/:name: permanently deletes a
+// bucket in the trash without waiting for its grace period
+func (h *BucketHandler) PurgeDeletedBucket(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+
+	bucket, ok := findDeletedBucket(c)
+	if !ok {
+		return
+	}
+
+	objectCount, storageErrors, err := h.purgeBucket(bucket)
+	if err != nil {
+		h.auditService.LogFailure(
+			c,
+			userID.(uuid.UUID),
+			username.(string),
+			"PurgeBucket",
+			"Bucket",
+			bucket.ID.String(),
+			bucket.Name,
+			err.Error(),
+			map[string]interface{}{
+				"bucket_name":    bucket.Name,
+				"objects_count":  objectCount,
+				"storage_errors": storageErrors,
+			},
+		)
+
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to purge bucket",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(
+		c,
+		userID.(uuid.UUID),
+		username.(string),
+		"PurgeBucket",
+		"Bucket",
+		bucket.ID.String(),
+		bucket.Name,
+		map[string]interface{}{
+			"bucket_name":     bucket.Name,
+			"owner_id":        bucket.OwnerID.String(),
+			"objects_deleted": objectCount,
+		},
+	)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: fmt.Sprintf("Bucket purged successfully (%d objects removed)", objectCount),
+	})
+}
+
+// StartBucketPurge periodically purges trashed buckets whose grace period has ended. It also
+// runs when soft deletes are disabled, so buckets trashed under an earlier setting still go
+func StartBucketPurge(cfg *config.Config, interval time.Duration) {
+	h := NewBucketHandler(cfg)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// Writes are frozen in maintenance mode; due buckets are purged once it ends
+			if middleware.GetMaintenanceMode().Enabled {
+				continue
+			}
+			h.purgeExpiredBuckets()
+		}
+	}()
+}
+
+// purgeExpiredBuckets permanently deletes every trashed bucket past its grace period
+func (h *BucketHandler) purgeExpiredBuckets() {
+	cutoff := time.Now().Add(-h.config.Storage.BucketDeleteGraceDuration)
+
+	var due []models.Bucket
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at <= ?", cutoff).Find(&due).Error; err != nil {
+		logger.Warn("Failed to load deleted buckets", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	for i := range due {
+		bucket := &due[i]
+		objectCount, storageErrors, err := h.purgeBucket(bucket)
+		if err != nil {
+			logger.Warn("Failed to purge deleted bucket", map[string]interface{}{
+				"bucket_name": bucket.Name,
+				"error":       err.Error(),
+			})
+			continue
+		}
+		logger.Info("Purged deleted bucket", map[string]interface{}{
+			"bucket_name":     bucket.Name,
+			"objects_deleted": objectCount,
+			"storage_errors":  len(storageErrors),
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/security"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ClientCertHandler struct {
+	config       *config.Config
+	auditService *services.AuditService
+}
+
+func NewClientCertHandler(cfg *config.Config) *ClientCertHandler {
+	return &ClientCertHandler{
+		config:       cfg,
+		auditService: services.NewAuditService(),
+	}
+}
+
+// ListClientCertBindings lists all client certificate bindings (admin only)
+func (h *ClientCertHandler) ListClientCertBindings(c *gin.Context) {
+	var bindings []models.ClientCertBinding
+	if err := database.DB.Preload("User").Order("created_at DESC").Find(&bindings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to list client certificate bindings",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, bindings)
+}
+
+// CreateClientCertBinding binds a client certificate to a user or access key (admin only)
+func (h *ClientCertHandler) CreateClientCertBinding(c *gin.Context) {
+	var req models.CreateClientCertBindingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid user ID",
+		})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "User not found",
+		})
+		return
+	}
+
+	binding := models.ClientCertBinding{
+		UserID:      userID,
+		Fingerprint: strings.ToLower(strings.ReplaceAll(strings.TrimSpace(req.Fingerprint), ":", "")),
+		Subject:     strings.TrimSpace(req.Subject),
+		Description: req.Description,
+		IsActive:    true,
+	}
+
+	// Derive fingerprint and subject from the PEM certificate if provided
+	if req.Certificate != "" {
+		block, _ := pem.Decode([]byte(req.Certificate))
+		if block == nil || block.Type != "CERTIFICATE" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid certificate",
+				Message: "Certificate must be PEM-encoded",
+			})
+			return
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid certificate",
+				Message: err.Error(),
+			})
+			return
+		}
+		binding.Fingerprint = security.CertificateFingerprint(cert)
+		binding.Subject = cert.Subject.String()
+	}
+
+	if binding.Fingerprint == "" && binding.Subject == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Certificate, fingerprint, or subject is required",
+		})
+		return
+	}
+
+	// Optional access key restriction must belong to the same user
+	if req.AccessKeyID != nil && *req.AccessKeyID != "" {
+		keyID, err := uuid.Parse(*req.AccessKeyID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid access key ID",
+			})
+			return
+		}
+		var key models.AccessKey
+		if err := database.DB.Where("id = ? AND user_id = ?", keyID, userID).First(&key).Error; err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Access key not found for this user",
+			})
+			return
+		}
+		binding.AccessKeyID = &keyID
+	}
+
+	// Prevent the same certificate from being bound twice
+	var existing models.ClientCertBinding
+	query := database.DB.Where("is_active = ?", true)
+	if binding.Fingerprint != "" {
+		query = query.Where("fingerprint = ?", binding.Fingerprint)
+	} else {
+		query = query.Where("(fingerprint = '' OR fingerprint IS NULL) AND subject = ?", binding.Subject)
+	}
+	if err := query.First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "This certificate is already bound",
+		})
+		return
+	}
+
+	adminUserID, _ := c.Get("user_id")
+	adminUsername, _ := c.Get("username")
+
+	if err := database.DB.Create(&binding).Error; err != nil {
+		h.auditService.LogFailure(c, adminUserID.(uuid.UUID), adminUsername.(string),
+			"CreateClientCertBinding", "ClientCertBinding", "", user.Username, err.Error(), nil)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create client certificate binding",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, adminUserID.(uuid.UUID), adminUsername.(string),
+		"CreateClientCertBinding", "ClientCertBinding", binding.ID.String(), user.Username,
+		map[string]interface{}{
+			"fingerprint": binding.Fingerprint,
+			"subject":     binding.Subject,
+		})
+
+	c.JSON(http.StatusCreated, binding)
+}
+
+// DeleteClientCertBinding removes a client certificate binding (admin only)
+func (h *ClientCertHandler) DeleteClientCertBinding(c *gin.Context) {
+	bindingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid binding ID",
+		})
+		return
+	}
+
+	var binding models.ClientCertBinding
+	if err := database.DB.First(&binding, "id = ?", bindingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Client certificate binding not found",
+		})
+		return
+	}
+
+	if err := database.DB.Delete(&binding).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to delete client certificate binding",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	adminUserID, _ := c.Get("user_id")
+	adminUsername, _ := c.Get("username")
+	h.auditService.LogSuccess(c, adminUserID.(uuid.UUID), adminUsername.(string),
+		"DeleteClientCertBinding", "ClientCertBinding", binding.ID.String(), binding.Subject,
+		map[string]interface{}{
+			"fingerprint": binding.Fingerprint,
+			"user_id":     binding.UserID.String(),
+		})
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Client certificate binding deleted successfully",
+	})
+}
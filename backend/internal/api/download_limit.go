@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+
+	"bkt/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// downloadSlots counts the object downloads each principal is streaming on this instance
+type downloadSlots struct {
+	mu     sync.Mutex
+	active map[string]int
+}
+
+var activeDownloads = &downloadSlots{active: make(map[string]int)}
+
+// acquire takes one of principal's limit slots, reporting false when all are in use
+func (s *downloadSlots) acquire(principal string, limit int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active[principal] >= limit {
+		return false
+	}
+	s.active[principal]++
+	return true
+}
+
+func (s *downloadSlots) release(principal string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active[principal] <= 1 {
+		delete(s.active, principal)
+		return
+	}
+	s.active[principal]--
+}
+
+// acquireDownloadSlot reserves a concurrent download for the caller (and, for S3 requests, the
+// access key that signed it) before length bytes are streamed. Returns the func that frees the
+// slot once the stream finishes, or an error naming the limit that was hit. Downloads below
+// DownloadLimitMinSize aren't counted
+func acquireDownloadSlot(c *gin.Context, cfg *config.StorageConfig, length int64) (func(), error) {
+	if length < cfg.DownloadLimitMinSize {
+		return func() {}, nil
+	}
+
+	var held []string
+	release := func() {
+		for _, principal := range held {
+			activeDownloads.release(principal)
+		}
+	}
+
+	if userID, ok := c.Get("user_id"); ok && cfg.MaxConcurrentDownloads > 0 {
+		principal := "user:" + userID.(uuid.UUID).String()
+		if !activeDownloads.acquire(principal, cfg.MaxConcurrentDownloads) {
+			return nil, fmt.Errorf("at most %d downloads per user can run at once", cfg.MaxConcurrentDownloads)
+		}
+		held = append(held, principal)
+	}
+
+	if keyID, ok := c.Get("access_key_id"); ok && cfg.MaxConcurrentDownloadsPerKey > 0 {
+		principal := "key:" + keyID.(uuid.UUID).String()
+		if !activeDownloads.acquire(principal, cfg.MaxConcurrentDownloadsPerKey) {
+			release()
+			return nil, fmt.Errorf("at most %d downloads per access key can run at once", cfg.MaxConcurrentDownloadsPerKey)
+		}
+		held = append(held, principal)
+	}
+
+	return release, nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"bkt/internal/database"
+	"bkt/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -42,6 +43,13 @@ func HealthHandler(c *gin.Context) {
 		overallStatus = "unhealthy"
 	}
 
+	// Maintenance mode doesn't make the service unhealthy; reads keep working
+	if middleware.GetMaintenanceMode().Enabled {
+		checks["maintenance"] = "enabled"
+	} else {
+		checks["maintenance"] = "disabled"
+	}
+
 	response := HealthResponse{
 		Status:    overallStatus,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// scrubBatchSize is how many objects are loaded per query during a scrub pass
+	scrubBatchSize = 100
+	// scrubCheckInterval is how often the scrubber looks for objects due for verification
+	scrubCheckInterval = time.Hour
+	// maxIntegrityReportObjects caps the objects listed by the integrity report
+	maxIntegrityReportObjects = 1000
+)
+
+// ScrubReport summarizes an integrity scrub pass
+type ScrubReport struct {
+	StartedAt       time.Time  `json:"started_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"` // Unset while the pass is running
+	ObjectsVerified int        `json:"objects_verified"`
+	BytesRead       int64      `json:"bytes_read"`
+	Mismatches      int        `json:"mismatches"`
+	Missing         int        `json:"missing"`
+	Error           string     `json:"error,omitempty"` // Why the pass stopped early
+}
+
+// IntegrityIssue is an object whose last scrub failed
+type IntegrityIssue struct {
+	ObjectID        uuid.UUID `json:"object_id"`
+	Bucket          string    `json:"bucket"`
+	Key             string    `json:"key"`
+	Size            int64     `json:"size"`
+	SHA256          string    `json:"sha256"`
+	IntegrityStatus string    `json:"integrity_status"`
+	LastVerifiedAt  time.Time `json:"last_verified_at"`
+}
+
+// Only one scrub pass runs at a time; the latest report is kept for GET /reconcile/integrity
+var (
+	scrubMu         sync.Mutex
+	lastScrubReport *ScrubReport
+	lastScrubMu     sync.RWMutex
+)
+
+// StartIntegrityScrub re-verifies each object's stored SHA256 once per interval. Passes look for
+// due objects hourly (or every interval, if shorter): those never verified or changed since
+// their last check first, then the longest-unverified ones. Reads are throttled to bandwidth
+// bytes per second (0 = unthrottled)
+func StartIntegrityScrub(cfg *config.Config, interval time.Duration, bandwidth int64) {
+	h := NewBucketHandler(cfg)
+	go func() {
+		ticker := time.NewTicker(min(interval, scrubCheckInterval))
+		defer ticker.Stop()
+		for range ticker.C {
+			// Scrub results are written to the objects table, which is frozen in maintenance mode
+			if middleware.GetMaintenanceMode().Enabled {
+				continue
+			}
+			h.runScrubPass(interval, bandwidth)
+		}
+	}()
+}
+
+// runScrubPass verifies every object that is due: never verified, modified since its last
+// check, or last verified more than interval ago
+func (h *BucketHandler) runScrubPass(interval time.Duration, bandwidth int64) {
+	if !scrubMu.TryLock() {
+		return
+	}
+	defer scrubMu.Unlock()
+
+	report := &ScrubReport{StartedAt: time.Now()}
+	dueBefore := report.StartedAt.Add(-interval)
+
+	published := false
+	throttle := newByteThrottle(bandwidth)
+	for {
+		// Objects modified during the pass are left for the next one
+		var batch []models.Object
+		if err := database.DB.Preload("Bucket").Scopes(models.InLiveBuckets).
+			Where("sha256 <> ''").
+			Where("updated_at < ?", report.StartedAt).
+			Where("last_verified_at IS NULL OR last_verified_at < updated_at OR last_verified_at < ?", dueBefore).
+			Order("CASE WHEN last_verified_at IS NULL OR last_verified_at < updated_at THEN 0 ELSE 1 END").
+			Order("last_verified_at ASC NULLS FIRST").
+			Limit(scrubBatchSize).
+			Find(&batch).Error; err != nil {
+			report.stop(err.Error())
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if !published {
+			// Passes with nothing due keep the previous report
+			setLastScrubReport(report)
+			published = true
+		}
+
+		for i := range batch {
+			if err := h.scrubObject(&batch[i], report, throttle); err != nil {
+				// Storage errors other than a missing object are usually backend-wide; retry next pass
+				report.stop(err.Error())
+				break
+			}
+			if middleware.GetMaintenanceMode().Enabled {
+				report.stop("stopped for maintenance mode")
+				break
+			}
+		}
+		if report.stopped() {
+			break
+		}
+	}
+
+	if !published {
+		if report.Error != "" {
+			logger.Warn("Integrity scrub failed to load objects", map[string]interface{}{
+				"error": report.Error,
+			})
+		}
+		return
+	}
+
+	completed := time.Now()
+	lastScrubMu.Lock()
+	report.CompletedAt = &completed
+	lastScrubMu.Unlock()
+
+	fields := map[string]interface{}{
+		"objects_verified": report.ObjectsVerified,
+		"bytes_read":       report.BytesRead,
+		"mismatches":       report.Mismatches,
+		"missing":          report.Missing,
+		"duration":         completed.Sub(report.StartedAt).String(),
+	}
+	if report.Error != "" {
+		fields["error"] = report.Error
+		logger.Warn("Integrity scrub stopped early", fields)
+		return
+	}
+	logger.Info("Integrity scrub completed", fields)
+}
+
+// scrubObject re-hashes one object and records the result. Returns an error only when the pass
+// should stop
+func (h *BucketHandler) scrubObject(object *models.Object, report *ScrubReport, throttle *byteThrottle) error {
+	storageBackend, err := h.getStorageBackend(&object.Bucket)
+	if err != nil {
+		return err
+	}
+
+	status := models.IntegrityOK
+	var actual string
+	var read int64
+
+	file, err := storageBackend.GetObject(object.Bucket.Name, object.Key)
+	if err != nil {
+		exists, existsErr := storageBackend.ObjectExists(object.Bucket.Name, object.Key)
+		if existsErr != nil || exists {
+			return err
+		}
+		status = models.IntegrityMissing
+	} else {
+		hasher := sha256.New()
+		read, err = io.Copy(hasher, throttle.reader(file))
+		file.Close()
+		if err != nil {
+			return err
+		}
+		actual = hex.EncodeToString(hasher.Sum(nil))
+		if actual != object.SHA256 {
+			status = models.IntegrityMismatch
+		}
+	}
+
+	// Skip the result if the object was rewritten while it was being read
+	now := time.Now()
+	result := database.DB.Model(&models.Object{}).
+		Where("id = ? AND updated_at = ? AND sha256 = ?", object.ID, object.UpdatedAt, object.SHA256).
+		UpdateColumns(map[string]interface{}{
+			"last_verified_at": now,
+			"integrity_status": status,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	lastScrubMu.Lock()
+	report.BytesRead += read
+	if result.RowsAffected > 0 {
+		report.ObjectsVerified++
+		switch status {
+		case models.IntegrityMismatch:
+			report.Mismatches++
+		case models.IntegrityMissing:
+			report.Missing++
+		}
+	}
+	lastScrubMu.Unlock()
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	if status != models.IntegrityOK {
+		logger.Error("Object failed integrity check", map[string]interface{}{
+			"bucket":          object.Bucket.Name,
+			"key":             object.Key,
+			"object_id":       object.ID.String(),
+			"status":          status,
+			"expected_sha256": object.SHA256,
+			"actual_sha256":   actual,
+		})
+	}
+	return nil
+}
+
+// stop records why the pass ended early. Report fields are guarded by lastScrubMu because the
+// integrity report reads them while the pass runs
+func (r *ScrubReport) stop(reason string) {
+	lastScrubMu.Lock()
+	r.Error = reason
+	lastScrubMu.Unlock()
+}
+
+func (r *ScrubReport) stopped() bool {
+	lastScrubMu.RLock()
+	defer lastScrubMu.RUnlock()
+	return r.Error != ""
+}
+
+func setLastScrubReport(report *ScrubReport) {
+	lastScrubMu.Lock()
+	lastScrubReport = report
+	lastScrubMu.Unlock()
+}
+
+// GetIntegrityReport lists objects whose latest scrub found corrupt or missing content, with
+// the state of the latest pass (admin only). Objects modified since their scrub aren't listed
+func (h *ReconcileHandler) GetIntegrityReport(c *gin.Context) {
+	limit := maxIntegrityReportObjects
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxIntegrityReportObjects {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid limit",
+				Message: "limit must be between 1 and " + strconv.Itoa(maxIntegrityReportObjects),
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	bucketName := c.Query("bucket")
+	failed := func(db *gorm.DB) *gorm.DB {
+		db = db.Table("objects").
+			Joins("JOIN buckets ON buckets.id = objects.bucket_id").
+			Where("objects.integrity_status IN ?", []string{models.IntegrityMismatch, models.IntegrityMissing}).
+			Where("objects.last_verified_at >= objects.updated_at")
+		if bucketName != "" {
+			db = db.Where("buckets.name = ?", bucketName)
+		}
+		return db
+	}
+
+	var total int64
+	if err := database.DB.Scopes(failed).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to load integrity report",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	issues := []IntegrityIssue{}
+	if err := database.DB.Scopes(failed).
+		Select("objects.id AS object_id, buckets.name AS bucket, objects.key, objects.size, objects.sha256, objects.integrity_status, objects.last_verified_at").
+		Order("objects.last_verified_at DESC").
+		Limit(limit).
+		Scan(&issues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to load integrity report",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var lastPass *ScrubReport
+	lastScrubMu.RLock()
+	if lastScrubReport != nil {
+		snapshot := *lastScrubReport
+		lastPass = &snapshot
+	}
+	lastScrubMu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":   h.config.Storage.ScrubInterval != "",
+		"last_pass": lastPass,
+		"total":     total,
+		"objects":   issues,
+	})
+}
+
+// byteThrottle limits the combined read rate of the readers it wraps
+type byteThrottle struct {
+	rate  int64 // Bytes per second; 0 = unlimited
+	start time.Time
+	read  int64
+}
+
+func newByteThrottle(rate int64) *byteThrottle {
+	return &byteThrottle{rate: rate, start: time.Now()}
+}
+
+func (t *byteThrottle) reader(r io.Reader) io.Reader {
+	if t.rate <= 0 {
+		return r
+	}
+	return &throttledReader{r: r, throttle: t}
+}
+
+// wait sleeps until n more bytes fit within the rate
+func (t *byteThrottle) wait(n int) {
+	t.read += int64(n)
+	due := t.start.Add(time.Duration(float64(t.read) / float64(t.rate) * float64(time.Second)))
+	if delay := time.Until(due); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+type throttledReader struct {
+	r        io.Reader
+	throttle *byteThrottle
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.throttle.wait(n)
+	}
+	return n, err
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+
+	"bkt/internal/config"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MaintenanceRequest toggles the server-wide read-only mode
+type MaintenanceRequest struct {
+	Enabled    *bool  `json:"enabled" binding:"required"`
+	Message    string `json:"message"`     // Optional message returned with rejected writes
+	RetryAfter int    `json:"retry_after"` // Seconds (default 300)
+}
+
+type MaintenanceHandler struct {
+	config       *config.Config
+	auditService *services.AuditService
+}
+
+func NewMaintenanceHandler(cfg *config.Config) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		config:       cfg,
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetMaintenanceMode returns the current maintenance state
+func (h *MaintenanceHandler) GetMaintenanceMode(c *gin.Context) {
+	c.JSON(http.StatusOK, middleware.GetMaintenanceMode())
+}
+
+// SetMaintenanceMode turns maintenance mode on or off (admin only)
+func (h *MaintenanceHandler) SetMaintenanceMode(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	var req MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.RetryAfter < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "retry_after cannot be negative",
+		})
+		return
+	}
+
+	if err := middleware.SetMaintenanceMode(*req.Enabled, req.Message, req.RetryAfter, &userUUID); err != nil {
+		h.auditService.LogFailure(c, userUUID, username.(string),
+			"SetMaintenanceMode", "System", "", "maintenance_mode", err.Error(),
+			map[string]interface{}{"enabled": *req.Enabled})
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to update maintenance mode",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	state := middleware.GetMaintenanceMode()
+	h.auditService.LogSuccess(c, userUUID, username.(string),
+		"SetMaintenanceMode", "System", "", "maintenance_mode",
+		map[string]interface{}{"enabled": state.Enabled, "message": state.Message, "retry_after": state.RetryAfter})
+
+	c.JSON(http.StatusOK, state)
+}
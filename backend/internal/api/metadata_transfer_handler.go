@@ -0,0 +1,488 @@
+package api
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// metadataExportFormat and metadataExportVersion identify the export stream in its header line
+	metadataExportFormat  = "bkt-metadata"
+	metadataExportVersion = 1
+
+	// metadataExportFlushRows is how many rows are written between flushes of the export stream
+	metadataExportFlushRows = 1000
+)
+
+// metadataTable describes how one table is exported and re-imported
+type metadataTable struct {
+	name          string
+	idColumn      string            // Surrogate primary key other tables reference; empty for keyless/join tables
+	naturalKey    string            // Unique column matched against existing rows: a match is reused instead of inserted
+	refs          map[string]string // Column -> table whose IDs it holds, remapped on import
+	parent        string            // Ref column naming the row this one belongs to; skipped with it when that row wasn't imported
+	orderBy       string
+	skipConflicts bool // Rows that already exist are skipped rather than treated as errors
+	confirmMerge  bool // Reusing an existing row with the same natural key requires the import's merge flag
+}
+
+// metadataTables lists the exported tables in dependency order: every table only references
+// tables before it (access keys reference their predecessors, so they are ordered by age).
+// Object versions keep their IDs, which name their content in storage. Audit logs, usage stats,
+// uploads, download tracking, idempotency keys and revocations are operational history and are
+// not part of the export
+var metadataTables = []metadataTable{
+	{name: "users", idColumn: "id", naturalKey: "username", orderBy: "created_at", confirmMerge: true},
+	{name: "policies", idColumn: "id", naturalKey: "name", orderBy: "created_at"},
+	{name: "user_policies", refs: map[string]string{"user_id": "users", "policy_id": "policies"}, orderBy: "user_id, policy_id", skipConflicts: true},
+	{name: "s3_configurations", idColumn: "id", naturalKey: "name", orderBy: "created_at"},
+	{name: "buckets", idColumn: "id", naturalKey: "name", refs: map[string]string{"owner_id": "users", "s3_config_id": "s3_configurations"}, orderBy: "created_at"},
+	{name: "bucket_policies", refs: map[string]string{"bucket_id": "buckets"}, orderBy: "bucket_id", skipConflicts: true},
+	{name: "bucket_websites", refs: map[string]string{"bucket_id": "buckets"}, orderBy: "bucket_id", skipConflicts: true},
+	{name: "access_keys", idColumn: "id", naturalKey: "access_key", refs: map[string]string{"user_id": "users", "rotated_from_id": "access_keys"}, orderBy: "created_at"},
+	{name: "client_cert_bindings", idColumn: "id", refs: map[string]string{"user_id": "users", "access_key_id": "access_keys"}, orderBy: "created_at"},
+	{name: "system_settings", refs: map[string]string{"updated_by": "users"}, orderBy: "key", skipConflicts: true},
+	{name: "objects", idColumn: "id", refs: map[string]string{"bucket_id": "buckets", "uploaded_by": "users"}, orderBy: "bucket_id, key", skipConflicts: true},
+	{name: "object_versions", idColumn: "id", refs: map[string]string{"bucket_id": "buckets", "uploaded_by": "users"}, orderBy: "bucket_id, key, created_at", skipConflicts: true},
+	{name: "object_tags", refs: map[string]string{"object_id": "objects"}, parent: "object_id", orderBy: "object_id, key", skipConflicts: true},
+	{name: "share_links", idColumn: "id", refs: map[string]string{"bucket_id": "buckets", "created_by": "users"}, orderBy: "created_at"},
+}
+
+// metadataLine is one line of the NDJSON export stream
+type metadataLine struct {
+	Type       string           `json:"type"` // "header", "row" or "footer"
+	Format     string           `json:"format,omitempty"`
+	Version    int              `json:"version,omitempty"`
+	ExportedAt *time.Time       `json:"exported_at,omitempty"`
+	Tables     []string         `json:"tables,omitempty"`
+	Table      string           `json:"table,omitempty"`
+	Row        json.RawMessage  `json:"row,omitempty"`
+	Rows       map[string]int64 `json:"rows,omitempty"` // Footer: row count per table
+}
+
+// MetadataImportTableResult summarizes what an import did with one table's rows
+type MetadataImportTableResult struct {
+	Inserted       int64    `json:"inserted"`
+	Mapped         int64    `json:"mapped"`  // Matched an existing row by its natural key; references are redirected to it
+	Skipped        int64    `json:"skipped"` // Already present
+	IgnoredColumns []string `json:"ignored_columns,omitempty"`
+}
+
+type MetadataTransferHandler struct {
+	config       *config.Config
+	auditService *services.AuditService
+}
+
+func NewMetadataTransferHandler(cfg *config.Config) *MetadataTransferHandler {
+	return &MetadataTransferHandler{
+		config:       cfg,
+		auditService: services.NewAuditService(),
+	}
+}
+
+// confirmTransfer checks the X-Confirmation-Token header against METADATA_TRANSFER_TOKEN.
+// Exports contain password hashes and encrypted secrets, and imports rewrite the whole
+// server, so an admin session alone isn't enough
+func (h *MetadataTransferHandler) confirmTransfer(c *gin.Context) bool {
+	expected := h.config.Auth.MetadataTransferToken
+	if expected == "" {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Metadata transfer disabled",
+			Message: "Set METADATA_TRANSFER_TOKEN to enable metadata export and import",
+		})
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Confirmation-Token")), []byte(expected)) != 1 {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Invalid confirmation token",
+			Message: "X-Confirmation-Token must match METADATA_TRANSFER_TOKEN",
+		})
+		return false
+	}
+	return true
+}
+
+// Export streams every metadata table as NDJSON: a header line, one line per row and a footer
+// with the row counts (admin only). All tables are read from a single repeatable-read snapshot,
+// so the export is consistent while the server keeps running. Secrets are exported as stored
+// (bcrypt hashes and values encrypted with ENCRYPTION_KEY), so the importing server needs the
+// same ENCRYPTION_KEY
+func (h *MetadataTransferHandler) Export(c *gin.Context) {
+	if !h.confirmTransfer(c) {
+		return
+	}
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+
+	now := time.Now().UTC()
+	tables := make([]string, len(metadataTables))
+	for i, table := range metadataTables {
+		tables[i] = table.name
+	}
+
+	filename := fmt.Sprintf("bkt-metadata-%s.ndjson", now.Format("20060102T150405Z"))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	encoder.Encode(metadataLine{
+		Type:       "header",
+		Format:     metadataExportFormat,
+		Version:    metadataExportVersion,
+		ExportedAt: &now,
+		Tables:     tables,
+	})
+
+	counts := make(map[string]int64, len(metadataTables))
+	err := database.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		for _, table := range metadataTables {
+			rows, err := tx.Raw(fmt.Sprintf(`SELECT row_to_json(t)::text FROM %q t ORDER BY %s`, table.name, table.orderBy)).Rows()
+			if err != nil {
+				return fmt.Errorf("%s: %w", table.name, err)
+			}
+
+			var count int64
+			for rows.Next() {
+				var row string
+				if err := rows.Scan(&row); err != nil {
+					rows.Close()
+					return fmt.Errorf("%s: %w", table.name, err)
+				}
+				if err := encoder.Encode(metadataLine{Type: "row", Table: table.name, Row: json.RawMessage(row)}); err != nil {
+					rows.Close()
+					return err
+				}
+				count++
+				if count%metadataExportFlushRows == 0 {
+					c.Writer.Flush()
+				}
+			}
+			err = rows.Err()
+			rows.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", table.name, err)
+			}
+			counts[table.name] = count
+			c.Writer.Flush()
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+
+	if err != nil {
+		// Headers are already sent; without the footer the importer rejects the truncated stream
+		logger.Error("Metadata export failed", map[string]interface{}{
+			"rows":  counts,
+			"error": err.Error(),
+		})
+		h.auditService.LogFailure(c, userUUID, username.(string), "ExportMetadata", "system", "", "metadata", err.Error(), map[string]interface{}{
+			"rows": counts,
+		})
+		return
+	}
+
+	encoder.Encode(metadataLine{Type: "footer", Rows: counts})
+	c.Writer.Flush()
+
+	h.auditService.LogSuccess(c, userUUID, username.(string), "ExportMetadata", "system", "", "metadata", map[string]interface{}{
+		"rows": counts,
+	})
+}
+
+// Import loads an export produced by Export into this server's database (admin only). The whole
+// stream is applied in one transaction and only committed once the footer's row counts match,
+// so a truncated or failed import changes nothing. Rows whose natural key (policy, S3
+// configuration or bucket name, access key) already exists are not duplicated: references to
+// them are redirected to the existing row. A username that belongs to a different user here is
+// an error unless ?merge_users=true, since merging hands the imported user's buckets and keys to
+// that account. Other rows that already exist are skipped, so importing the same export twice
+// is harmless
+func (h *MetadataTransferHandler) Import(c *gin.Context) {
+	if !h.confirmTransfer(c) {
+		return
+	}
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+	username, _ := c.Get("username")
+	mergeUsers := c.Query("merge_users") == "true"
+
+	var results map[string]*MetadataImportTableResult
+	err := database.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		results, err = importMetadata(tx, c.Request.Body, mergeUsers)
+		return err
+	})
+	if err != nil {
+		h.auditService.LogFailure(c, userUUID, username.(string), "ImportMetadata", "system", "", "metadata", err.Error(), map[string]interface{}{
+			"merge_users": mergeUsers,
+		})
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Import failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Imported S3 configurations, policy attachments and bucket policies may replace cached ones
+	InvalidateS3ConfigCache()
+	services.InvalidatePolicyCache()
+
+	summary := make(map[string]interface{}, len(results))
+	for table, result := range results {
+		summary[table] = map[string]int64{"inserted": result.Inserted, "mapped": result.Mapped, "skipped": result.Skipped}
+	}
+	summary["merge_users"] = mergeUsers
+	h.auditService.LogSuccess(c, userUUID, username.(string), "ImportMetadata", "system", "", "metadata", summary)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Metadata imported",
+		"tables":  results,
+	})
+}
+
+// metadataImporter holds the state of one import: the target's columns and the ID remapping
+type metadataImporter struct {
+	tx      *gorm.DB
+	merge   bool                         // Tables marked confirmMerge may reuse existing rows
+	columns map[string]map[string]bool   // Table -> columns that exist on this server
+	ids     map[string]map[string]string // Table -> exported ID -> ID on this server
+	results map[string]*MetadataImportTableResult
+}
+
+// importMetadata reads an export stream and applies it inside tx. merge allows imported users to
+// be merged into existing users with the same username
+func importMetadata(tx *gorm.DB, body io.Reader, merge bool) (map[string]*MetadataImportTableResult, error) {
+	decoder := json.NewDecoder(body)
+
+	var header metadataLine
+	if err := decoder.Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if header.Type != "header" || header.Format != metadataExportFormat {
+		return nil, errors.New("not a bkt metadata export")
+	}
+	if header.Version != metadataExportVersion {
+		return nil, fmt.Errorf("unsupported export version %d (expected %d)", header.Version, metadataExportVersion)
+	}
+
+	importer := &metadataImporter{
+		tx:      tx,
+		merge:   merge,
+		columns: make(map[string]map[string]bool),
+		ids:     make(map[string]map[string]string),
+		results: make(map[string]*MetadataImportTableResult),
+	}
+	tableIndex := make(map[string]int, len(metadataTables))
+	for i, table := range metadataTables {
+		tableIndex[table.name] = i
+		importer.ids[table.name] = make(map[string]string)
+		importer.results[table.name] = &MetadataImportTableResult{}
+	}
+
+	counts := make(map[string]int64)
+	current := 0
+	for {
+		var line metadataLine
+		if err := decoder.Decode(&line); err != nil {
+			if err == io.EOF {
+				return nil, errors.New("export is truncated: footer missing")
+			}
+			return nil, fmt.Errorf("failed to read line %d: %w", sumCounts(counts)+2, err)
+		}
+
+		if line.Type == "footer" {
+			for _, table := range metadataTables {
+				if line.Rows[table.name] != counts[table.name] {
+					return nil, fmt.Errorf("row count mismatch for %s: footer says %d, stream has %d", table.name, line.Rows[table.name], counts[table.name])
+				}
+			}
+			if decoder.More() {
+				return nil, errors.New("unexpected data after footer")
+			}
+			return importer.results, nil
+		}
+		if line.Type != "row" {
+			return nil, fmt.Errorf("unexpected line type %q", line.Type)
+		}
+
+		index, ok := tableIndex[line.Table]
+		if !ok {
+			return nil, fmt.Errorf("unknown table %q", line.Table)
+		}
+		if index < current {
+			return nil, fmt.Errorf("table %s is out of order", line.Table)
+		}
+		current = index
+
+		if err := importer.importRow(metadataTables[index], line.Row); err != nil {
+			return nil, fmt.Errorf("%s row %d: %w", line.Table, counts[line.Table]+1, err)
+		}
+		counts[line.Table]++
+	}
+}
+
+func sumCounts(counts map[string]int64) int64 {
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// importRow remaps one row's references and inserts it unless it already exists
+func (m *metadataImporter) importRow(table metadataTable, raw json.RawMessage) error {
+	// UseNumber keeps large integers (sizes, counters) exact
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var row map[string]interface{}
+	if err := decoder.Decode(&row); err != nil {
+		return err
+	}
+
+	for column, refTable := range table.refs {
+		if value, ok := row[column].(string); ok {
+			if mapped, ok := m.ids[refTable][value]; ok {
+				row[column] = mapped
+			}
+		}
+	}
+
+	result := m.results[table.name]
+	if table.parent != "" {
+		// The row it belongs to was skipped in favor of a different existing row, which keeps its own
+		if parentID, _ := row[table.parent].(string); m.ids[table.refs[table.parent]][parentID] == "" {
+			result.Skipped++
+			return nil
+		}
+	}
+
+	var oldID string
+	if table.idColumn != "" {
+		oldID, _ = row[table.idColumn].(string)
+		if oldID == "" {
+			return fmt.Errorf("missing %s", table.idColumn)
+		}
+	}
+
+	// An existing row with the same natural key (e.g. the bootstrap admin) stands in for this one
+	if table.naturalKey != "" {
+		var existingID string
+		if err := m.tx.Raw(fmt.Sprintf(`SELECT %q::text FROM %q WHERE %q = ?`, table.idColumn, table.name, table.naturalKey), row[table.naturalKey]).
+			Scan(&existingID).Error; err != nil {
+			return err
+		}
+		if existingID != "" {
+			if existingID != oldID && table.confirmMerge && !m.merge {
+				return fmt.Errorf("%s %v already exists here as a different %s; import with merge_users=true to merge them",
+					table.naturalKey, row[table.naturalKey], table.name)
+			}
+			m.ids[table.name][oldID] = existingID
+			if existingID == oldID {
+				result.Skipped++
+			} else {
+				result.Mapped++
+			}
+			return nil
+		}
+	}
+
+	known, err := m.tableColumns(table.name)
+	if err != nil {
+		return err
+	}
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		if known[column] {
+			columns = append(columns, column)
+		} else if !slices.Contains(result.IgnoredColumns, column) {
+			// Exported by a newer version of bkt; dropped rather than failing the import
+			result.IgnoredColumns = append(result.IgnoredColumns, column)
+		}
+	}
+	sort.Strings(columns)
+
+	quoted := ""
+	for i, column := range columns {
+		if i > 0 {
+			quoted += ", "
+		}
+		quoted += fmt.Sprintf("%q", column)
+	}
+	values, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+
+	// json_populate_record converts each value to the column's type, so rows round-trip without a model per table
+	insert := m.tx.Exec(fmt.Sprintf(`INSERT INTO %q (%s) SELECT %s FROM json_populate_record(NULL::%q, ?::json) ON CONFLICT DO NOTHING`,
+		table.name, quoted, quoted, table.name), string(values))
+	if insert.Error != nil {
+		return insert.Error
+	}
+
+	if insert.RowsAffected > 0 {
+		if table.idColumn != "" {
+			m.ids[table.name][oldID] = oldID
+		}
+		result.Inserted++
+		return nil
+	}
+	if table.idColumn == "" {
+		result.Skipped++
+		return nil
+	}
+
+	// Nothing was inserted: fine if this exact row is already here, otherwise another
+	// unique column (such as a user's email) collides with a different row
+	var exists bool
+	if err := m.tx.Raw(fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %q WHERE %q::text = ?)`, table.name, table.idColumn), oldID).
+		Scan(&exists).Error; err != nil {
+		return err
+	}
+	if exists {
+		m.ids[table.name][oldID] = oldID
+	} else if !table.skipConflicts {
+		return fmt.Errorf("%s %s conflicts with an existing row", table.idColumn, oldID)
+	}
+	result.Skipped++
+	return nil
+}
+
+// tableColumns returns the columns a table has on this server
+func (m *metadataImporter) tableColumns(table string) (map[string]bool, error) {
+	if columns, ok := m.columns[table]; ok {
+		return columns, nil
+	}
+
+	var names []string
+	if err := m.tx.Raw(`SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ?`, table).
+		Scan(&names).Error; err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool, len(names))
+	for _, name := range names {
+		columns[name] = true
+	}
+	m.columns[table] = columns
+	return columns, nil
+}
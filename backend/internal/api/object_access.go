@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// objectAccess is the outcome of resolveObjectAccess
+type objectAccess int
+
+const (
+	objectAccessOK       objectAccess = iota // Allowed and the object exists
+	objectAccessMissing                      // Allowed, but there is no such object (callers apply their usual not-found handling)
+	objectAccessNotFound                     // Denied, and answered with 404
+	objectAccessDenied                       // Denied, and answered with 403 (precise mode, existing object)
+)
+
+// resolveObjectAccess checks whether a user may perform action on an object and loads it, ordering
+// the two checks so the response follows OBJECT_DENIAL_MODE:
+//   - precise (default): a missing object gets 404 and an existing one the caller can't access gets 403
+//   - hide: a denied request gets 404 without the object being looked up, so unauthorized callers
+//     can't tell whether it exists
+//
+// Only objectAccessOK returns the object
+func (h *BucketHandler) resolveObjectAccess(c *gin.Context, userID uuid.UUID, bucket *models.Bucket, objectKey, action string) (*models.Object, objectAccess, error) {
+	allowed, err := h.policyService.CheckObjectAccess(c, userID, bucket.Name, objectKey, action)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !allowed && h.config.Auth.ObjectDenialMode == "hide" {
+		return nil, objectAccessNotFound, nil
+	}
+
+	var object models.Object
+	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, err
+		}
+		if !allowed {
+			return nil, objectAccessNotFound, nil
+		}
+		return nil, objectAccessMissing, nil
+	}
+	if !allowed {
+		return nil, objectAccessDenied, nil
+	}
+	return &object, objectAccessOK, nil
+}
+
+// respondObjectAccess writes the JSON error for a resolveObjectAccess outcome other than
+// objectAccessOK. Returns true when the request may proceed
+func (h *BucketHandler) respondObjectAccess(c *gin.Context, access objectAccess, err error, deniedMessage string) bool {
+	switch {
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return false
+	case access == objectAccessDenied:
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: deniedMessage,
+		})
+		return false
+	case access != objectAccessOK:
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Object not found",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// objectExpiryBatchSize is how many expired objects one sweep deletes at most
+const objectExpiryBatchSize = 500
+
+// objectExpiryRuleID identifies per-object TTLs in the S3 x-amz-expiration header
+const objectExpiryRuleID = "object-ttl"
+
+// parseObjectExpiry resolves a per-object TTL. expiresAfter is a duration ("24h") or a number of
+// seconds; expiresAt is an RFC 3339 timestamp. Returns nil when neither is set
+func parseObjectExpiry(expiresAfter, expiresAt string) (*time.Time, error) {
+	if expiresAfter != "" && expiresAt != "" {
+		return nil, errors.New("set either X-Expires-After or expires_at, not both")
+	}
+
+	var expiry time.Time
+	switch {
+	case expiresAfter != "":
+		ttl, err := time.ParseDuration(expiresAfter)
+		if err != nil {
+			seconds, convErr := strconv.ParseInt(expiresAfter, 10, 64)
+			if convErr != nil {
+				return nil, fmt.Errorf("invalid X-Expires-After %q: use a duration such as 24h or a number of seconds", expiresAfter)
+			}
+			ttl = time.Duration(seconds) * time.Second
+		}
+		if ttl <= 0 {
+			return nil, errors.New("X-Expires-After must be positive")
+		}
+		expiry = time.Now().Add(ttl)
+	case expiresAt != "":
+		parsed, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at %q: use an RFC 3339 timestamp", expiresAt)
+		}
+		if !parsed.After(time.Now()) {
+			return nil, errors.New("expires_at must be in the future")
+		}
+		expiry = parsed
+	default:
+		return nil, nil
+	}
+
+	expiry = expiry.UTC()
+	return &expiry, nil
+}
+
+// objectExpiryFromRequest reads the per-object TTL from the X-Expires-After header or expires_at form field
+func objectExpiryFromRequest(c *gin.Context) (*time.Time, error) {
+	return parseObjectExpiry(c.GetHeader("X-Expires-After"), c.PostForm("expires_at"))
+}
+
+// setObjectExpiryHeaders reports an object's TTL on metadata responses
+func setObjectExpiryHeaders(c *gin.Context, object *models.Object) {
+	if object.ExpiresAt == nil {
+		return
+	}
+	remaining := time.Until(*object.ExpiresAt)
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.Header("X-Bkt-Expires-At", object.ExpiresAt.UTC().Format(time.RFC3339))
+	c.Header("X-Bkt-Expires-In", strconv.FormatInt(int64(remaining.Seconds()), 10))
+	c.Header("x-amz-expiration", fmt.Sprintf(`expiry-date="%s", rule-id="%s"`,
+		object.ExpiresAt.UTC().Format(http.TimeFormat), objectExpiryRuleID))
+}
+
+// StartObjectExpiry periodically deletes objects whose per-object TTL has passed
+func StartObjectExpiry(cfg *config.Config, interval time.Duration) {
+	h := NewBucketHandler(cfg)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// Writes are frozen in maintenance mode; expired objects are removed once it ends
+			if middleware.GetMaintenanceMode().Enabled {
+				continue
+			}
+			h.deleteExpiredObjects()
+		}
+	}()
+}
+
+// deleteExpiredObjects removes one batch of expired objects from storage and the database
+func (h *BucketHandler) deleteExpiredObjects() {
+	var expired []models.Object
+	if err := database.DB.Preload("Bucket").Scopes(models.InLiveBuckets).
+		Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).
+		Order("expires_at ASC").Limit(objectExpiryBatchSize).Find(&expired).Error; err != nil {
+		logger.Warn("Failed to load expired objects", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	deleted := 0
+	for i := range expired {
+		if h.deleteExpiredObject(&expired[i]) {
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		logger.Info("Deleted expired objects", map[string]interface{}{
+			"count": deleted,
+		})
+	}
+}
+
+// deleteExpiredObject deletes one object, skipping it if a concurrent upload replaced it
+func (h *BucketHandler) deleteExpiredObject(object *models.Object) bool {
+	// Skip keys that stay busy; the next sweep sees the new upload's own TTL (if any)
+	unlockKey, err := lockObjectKey(object.BucketID, object.Key, time.Second)
+	if err != nil {
+		return false
+	}
+	defer unlockKey()
+
+	var current models.Object
+	if err := database.DB.Where("id = ? AND expires_at IS NOT NULL AND expires_at <= ?", object.ID, time.Now()).
+		First(&current).Error; err != nil {
+		return false // Overwritten with a new TTL or deleted meanwhile
+	}
+
+	storageBackend, err := h.getStorageBackend(&object.Bucket)
+	if err != nil {
+		logger.Warn("Failed to delete expired object", map[string]interface{}{
+			"bucket": object.Bucket.Name,
+			"key":    object.Key,
+			"error":  err.Error(),
+		})
+		return false
+	}
+
+	// As with S3 lifecycle expiration, a versioned bucket keeps the expired content as a
+	// previous version behind a delete marker
+	if object.Bucket.VersioningEnabled || current.VersionID != "" {
+		if _, err := deleteObjectVersioned(storageBackend, &object.Bucket, &current); err != nil {
+			logger.Warn("Failed to delete expired object", map[string]interface{}{
+				"bucket": object.Bucket.Name,
+				"key":    object.Key,
+				"error":  err.Error(),
+			})
+			return false
+		}
+		return true
+	}
+
+	if err := storageBackend.DeleteObject(object.Bucket.Name, object.Key); err != nil {
+		if exists, existsErr := storageBackend.ObjectExists(object.Bucket.Name, object.Key); existsErr != nil || exists {
+			logger.Warn("Failed to delete expired object", map[string]interface{}{
+				"bucket": object.Bucket.Name,
+				"key":    object.Key,
+				"error":  err.Error(),
+			})
+			return false
+		}
+	}
+
+	if err := database.DB.Delete(&current).Error; err != nil {
+		logger.Warn("Failed to delete expired object metadata", map[string]interface{}{
+			"bucket": object.Bucket.Name,
+			"key":    object.Key,
+			"error":  err.Error(),
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// objectKeyLockTimeout is how long a write waits for another write to the same key to finish
+const objectKeyLockTimeout = 30 * time.Second
+
+var errObjectKeyLocked = errors.New("another upload to this key is in progress; retry later")
+
+// objectKeyLock serializes writes to one bucket/key. The channel holds a token while locked
+type objectKeyLock struct {
+	ch   chan struct{}
+	refs int // Holder plus waiters; the entry is dropped when it reaches zero
+}
+
+// Per-process locks keyed by "<bucket id>/<key>" (writes through other instances aren't covered)
+var (
+	objectKeyLocks   = make(map[string]*objectKeyLock)
+	objectKeyLocksMu sync.Mutex
+)
+
+// lockObjectKey waits up to timeout for exclusive write access to a key, so the storage write
+// and the metadata update of concurrent uploads can't interleave. Returns errObjectKeyLocked
+// on timeout. The returned unlock func is safe to call more than once
+func lockObjectKey(bucketID uuid.UUID, key string, timeout time.Duration) (func(), error) {
+	id := bucketID.String() + "/" + key
+
+	objectKeyLocksMu.Lock()
+	lock, exists := objectKeyLocks[id]
+	if !exists {
+		lock = &objectKeyLock{ch: make(chan struct{}, 1)}
+		objectKeyLocks[id] = lock
+	}
+	lock.refs++
+	objectKeyLocksMu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case lock.ch <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				<-lock.ch
+				releaseObjectKeyLock(id, lock)
+			})
+		}, nil
+	case <-timer.C:
+		releaseObjectKeyLock(id, lock)
+		return nil, errObjectKeyLocked
+	}
+}
+
+func releaseObjectKeyLock(id string, lock *objectKeyLock) {
+	objectKeyLocksMu.Lock()
+	defer objectKeyLocksMu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(objectKeyLocks, id)
+	}
+}
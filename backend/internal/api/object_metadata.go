@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"bkt/internal/models"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// amzMetaPrefix starts the request and response headers that carry an object's user metadata
+const amzMetaPrefix = "x-amz-meta-"
+
+// objectMetadataFromRequest collects the request's x-amz-meta-* headers as the JSON stored in
+// Object.Metadata. Names are lowercased, since header names are case-insensitive, and a repeated
+// header's values are joined with commas. Returns nil when the request has none
+func objectMetadataFromRequest(c *gin.Context) (*string, error) {
+	metadata := make(map[string]string)
+	for name, values := range c.Request.Header {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, amzMetaPrefix) {
+			continue
+		}
+		metadata[strings.TrimPrefix(name, amzMetaPrefix)] = strings.Join(values, ",")
+	}
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	if err := validation.ValidateObjectMetadata(metadata); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	document := string(encoded)
+	return &document, nil
+}
+
+// requestMetadata reads an S3 request's user metadata (see objectMetadataFromRequest), writing
+// the S3 error response when it's invalid
+func (h *S3APIHandler) requestMetadata(c *gin.Context, objectKey string) (*string, bool) {
+	metadata, err := objectMetadataFromRequest(c)
+	if errors.Is(err, validation.ErrObjectMetadataTooLarge) {
+		h.s3Error(c, "MetadataTooLarge", "Your metadata headers exceed the maximum allowed metadata size", objectKey, http.StatusBadRequest)
+		return nil, false
+	}
+	if err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return nil, false
+	}
+	return metadata, true
+}
+
+// setObjectMetadataHeaders sends an object's user metadata as x-amz-meta-* headers. Entries that
+// can't be sent as a header (e.g. from imported metadata) are left out
+func setObjectMetadataHeaders(c *gin.Context, object *models.Object) {
+	if object.Metadata == nil {
+		return
+	}
+	var metadata map[string]interface{}
+	if json.Unmarshal([]byte(*object.Metadata), &metadata) != nil {
+		return
+	}
+	for name, raw := range metadata {
+		value, ok := raw.(string)
+		if !ok || validation.ValidateObjectMetadataEntry(name, value) != nil {
+			continue
+		}
+		c.Header(amzMetaPrefix+name, value)
+	}
+}
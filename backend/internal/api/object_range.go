@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errRangeNotSatisfiable = errors.New("requested range not satisfiable")
+
+// byteRange is a single resolved byte range of an object
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+// contentRange formats the Content-Range header value for a partial response
+func (r *byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// requestedRange resolves the request's Range header against an object, honoring If-Range.
+// Returns nil when the full object should be served: no Range header, a Range the server
+// ignores (multiple ranges, malformed), or an If-Range validator that no longer matches, so
+// a resuming client restarts cleanly instead of splicing bytes from two object versions.
+// Returns errRangeNotSatisfiable when the range starts beyond the end of the object
+func requestedRange(c *gin.Context, size int64, etag string, lastModified time.Time) (*byteRange, error) {
+	header := c.GetHeader("Range")
+	if header == "" {
+		return nil, nil
+	}
+	if !ifRangeMatches(c.GetHeader("If-Range"), etag, lastModified) {
+		return nil, nil
+	}
+	return parseByteRange(header, size)
+}
+
+// ifRangeMatches compares an If-Range validator with the object's current ETag or Last-Modified.
+// ETags use strong comparison (weak validators never match); dates must match exactly
+func ifRangeMatches(validator, etag string, lastModified time.Time) bool {
+	validator = strings.TrimSpace(validator)
+	if validator == "" {
+		return true
+	}
+	if strings.HasPrefix(validator, "W/") {
+		return false
+	}
+	if strings.HasPrefix(validator, `"`) {
+		return validator == fmt.Sprintf(`"%s"`, etag)
+	}
+
+	date, err := http.ParseTime(validator)
+	if err != nil {
+		return false
+	}
+	return date.Equal(lastModified.UTC().Truncate(time.Second))
+}
+
+// parseByteRange parses a single "bytes=" range (start-end, start- or -suffix)
+func parseByteRange(header string, size int64) (*byteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, nil // Other units and multipart ranges are ignored; the full object is served
+	}
+
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, nil
+	}
+
+	if startStr == "" {
+		// Suffix range: the last N bytes
+		suffix, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffix < 0 {
+			return nil, nil
+		}
+		if suffix == 0 || size == 0 {
+			return nil, errRangeNotSatisfiable
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return &byteRange{start: size - suffix, length: suffix}, nil
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return nil, nil
+	}
+	if start >= size {
+		return nil, errRangeNotSatisfiable
+	}
+
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return nil, nil
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return &byteRange{start: start, length: end - start + 1}, nil
+}
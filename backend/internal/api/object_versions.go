@@ -0,0 +1,242 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// newVersionID returns an opaque ID for an object version written now, or "" (the "null"
+// version) when the bucket isn't versioned
+func newVersionID(bucket *models.Bucket) string {
+	if !bucket.VersioningEnabled {
+		return ""
+	}
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// objectVersionID returns the version ID S3 clients see for an object's current version
+func objectVersionID(object *models.Object) string {
+	if object.VersionID == "" {
+		return models.NullVersionID
+	}
+	return object.VersionID
+}
+
+// archiveObjectVersion saves an object's current content and metadata as a previous version,
+// before it is overwritten or deleted. Nothing is saved for "null" versions in buckets without
+// versioning, so those behave as if versioning never existed; an object written while
+// versioning was enabled is still kept after it's suspended
+func archiveObjectVersion(backend storage.StorageBackend, bucket *models.Bucket, object *models.Object) (*models.ObjectVersion, error) {
+	if !bucket.VersioningEnabled && object.VersionID == "" {
+		return nil, nil
+	}
+	versioner, err := storage.Versioner(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &models.ObjectVersion{
+		ID:           uuid.New(),
+		BucketID:     bucket.ID,
+		Key:          object.Key,
+		VersionID:    objectVersionID(object),
+		Size:         object.Size,
+		ContentType:  object.ContentType,
+		ETag:         object.ETag,
+		SHA256:       object.SHA256,
+		Metadata:     object.Metadata,
+		ACL:          object.ACL,
+		UploadedBy:   object.UploadedBy,
+		LastModified: object.UpdatedAt,
+	}
+	if err := versioner.SaveObjectVersion(bucket.Name, object.Key, version.StorageID()); err != nil {
+		return nil, err
+	}
+
+	// As on S3, there is only ever one "null" version of a key
+	if version.VersionID == models.NullVersionID {
+		var previous models.ObjectVersion
+		if err := database.DB.Where("bucket_id = ? AND key = ? AND version_id = ?", bucket.ID, object.Key, models.NullVersionID).First(&previous).Error; err == nil {
+			if err := discardObjectVersion(backend, bucket.Name, &previous); err != nil {
+				versioner.DeleteObjectVersion(bucket.Name, version.StorageID())
+				return nil, err
+			}
+		}
+	}
+
+	if err := database.DB.Create(version).Error; err != nil {
+		versioner.DeleteObjectVersion(bucket.Name, version.StorageID())
+		return nil, err
+	}
+	return version, nil
+}
+
+// archiveCurrentVersion archives the object stored under objectKey, if any, before it is
+// overwritten. Returns nil when there was nothing to keep
+func archiveCurrentVersion(backend storage.StorageBackend, bucket *models.Bucket, objectKey string) (*models.ObjectVersion, error) {
+	var object models.Object
+	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return archiveObjectVersion(backend, bucket, &object)
+}
+
+// createDeleteMarker records the deletion of a key in a versioned bucket. Returns nil when
+// versioning is off
+func createDeleteMarker(bucket *models.Bucket, objectKey string) (*models.ObjectVersion, error) {
+	return createDeleteMarkerTx(database.DB, bucket, objectKey)
+}
+
+// createDeleteMarkerTx is createDeleteMarker within a transaction (moves record the marker with
+// the object's new key)
+func createDeleteMarkerTx(tx *gorm.DB, bucket *models.Bucket, objectKey string) (*models.ObjectVersion, error) {
+	if !bucket.VersioningEnabled {
+		return nil, nil
+	}
+	now := time.Now()
+	marker := &models.ObjectVersion{
+		ID:             uuid.New(),
+		BucketID:       bucket.ID,
+		Key:            objectKey,
+		VersionID:      newVersionID(bucket),
+		IsDeleteMarker: true,
+		LastModified:   now,
+		CreatedAt:      now,
+	}
+	if err := tx.Create(marker).Error; err != nil {
+		return nil, err
+	}
+	return marker, nil
+}
+
+// discardObjectVersion permanently deletes a previous version (or delete marker) and its content
+func discardObjectVersion(backend storage.StorageBackend, bucketName string, version *models.ObjectVersion) error {
+	if !version.IsDeleteMarker {
+		versioner, err := storage.Versioner(backend)
+		if err != nil {
+			return err
+		}
+		if err := versioner.DeleteObjectVersion(bucketName, version.StorageID()); err != nil {
+			return err
+		}
+	}
+	return database.DB.Delete(version).Error
+}
+
+// restoreArchivedVersion undoes archiveCurrentVersion after the overwrite that followed it
+// failed. Backends may write in place, so the live content can already be truncated: the
+// archived copy is put back, then dropped as a version since it's the current version again
+func restoreArchivedVersion(backend storage.StorageBackend, bucketName string, version *models.ObjectVersion) {
+	versioner, err := storage.Versioner(backend)
+	if err == nil {
+		err = versioner.RestoreObjectVersion(bucketName, version.Key, version.StorageID())
+	}
+	if err != nil {
+		// Keep the archive: it may be the only intact copy of the object
+		logger.Error("Failed to restore object after a failed overwrite", map[string]interface{}{
+			"bucket":     bucketName,
+			"key":        version.Key,
+			"version_id": version.VersionID,
+			"error":      err.Error(),
+		})
+		return
+	}
+	discardObjectVersion(backend, bucketName, version)
+}
+
+// deleteObjectVersioned deletes an object's content and record. In a versioned bucket the
+// content is kept as a previous version and a delete marker takes its place (returned; nil
+// when versioning is off)
+func deleteObjectVersioned(backend storage.StorageBackend, bucket *models.Bucket, object *models.Object) (*models.ObjectVersion, error) {
+	previous, err := archiveObjectVersion(backend, bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delete from storage first - MUST succeed before database delete (prevents inconsistency)
+	if err := backend.DeleteObject(bucket.Name, object.Key); err != nil {
+		if previous != nil {
+			discardObjectVersion(backend, bucket.Name, previous)
+		}
+		return nil, err
+	}
+	if err := database.DB.Delete(object).Error; err != nil {
+		return nil, err
+	}
+	return createDeleteMarker(bucket, object.Key)
+}
+
+// promoteLatestVersion makes a key's newest remaining version current again once the current
+// version or a delete marker has been permanently deleted, as S3 does. Nothing changes while the
+// key has a current object or when its newest version is itself a delete marker. Returns the
+// promoted object, or nil
+func promoteLatestVersion(backend storage.StorageBackend, bucket *models.Bucket, objectKey string) (*models.Object, error) {
+	var current models.Object
+	err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&current).Error
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var latest models.ObjectVersion
+	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).
+		Order("last_modified DESC, created_at DESC").First(&latest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if latest.IsDeleteMarker {
+		return nil, nil
+	}
+
+	versioner, err := storage.Versioner(backend)
+	if err != nil {
+		return nil, err
+	}
+	if err := versioner.RestoreObjectVersion(bucket.Name, objectKey, latest.StorageID()); err != nil {
+		return nil, err
+	}
+
+	versionID := latest.VersionID
+	if versionID == models.NullVersionID {
+		versionID = ""
+	}
+	object := &models.Object{
+		BucketID:    bucket.ID,
+		Key:         objectKey,
+		Size:        latest.Size,
+		ContentType: latest.ContentType,
+		ETag:        latest.ETag,
+		SHA256:      latest.SHA256,
+		StoragePath: objectKey,
+		Metadata:    latest.Metadata,
+		ACL:         latest.ACL,
+		UploadedBy:  latest.UploadedBy,
+		VersionID:   versionID,
+		CreatedAt:   latest.LastModified,
+		UpdatedAt:   latest.LastModified,
+	}
+	if err := database.DB.Create(object).Error; err != nil {
+		backend.DeleteObject(bucket.Name, objectKey)
+		return nil, err
+	}
+
+	// The content now lives under the key again
+	discardObjectVersion(backend, bucket.Name, &latest)
+	return object, nil
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultPageLimit is the page size for enveloped lists that don't pass limit
+	defaultPageLimit = 100
+	// maxPageLimit caps limit on list endpoints
+	maxPageLimit = 1000
+)
+
+// pageParams are a list request's ?limit, ?offset and ?envelope parameters. Lists keep their
+// original shape (a bare array) unless envelope=true is passed, so existing clients are
+// unaffected; limit and offset apply in both cases
+type pageParams struct {
+	limit    int // 0: unlimited
+	offset   int
+	envelope bool
+}
+
+// parsePageParams reads the pagination parameters. defaultLimit applies when limit isn't
+// passed; with 0, lists stay unlimited unless the envelope is requested. Limits above
+// maxLimit are lowered to it
+func parsePageParams(c *gin.Context, defaultLimit, maxLimit int) (pageParams, error) {
+	params := pageParams{limit: defaultLimit, envelope: c.Query("envelope") == "true"}
+	if params.limit == 0 && params.envelope {
+		params.limit = min(defaultPageLimit, maxLimit)
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return params, errors.New("limit must be a positive number")
+		}
+		params.limit = min(limit, maxLimit)
+	}
+	if raw := c.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return params, errors.New("offset must be a non-negative number")
+		}
+		params.offset = offset
+	}
+	return params, nil
+}
+
+// bindPageParams parses the pagination parameters, writing a 400 response when they are invalid
+func bindPageParams(c *gin.Context, defaultLimit, maxLimit int) (pageParams, bool) {
+	params, err := parsePageParams(c, defaultLimit, maxLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid pagination",
+			Message: err.Error(),
+		})
+		return params, false
+	}
+	return params, true
+}
+
+// apply limits a query to the requested page
+func (p pageParams) apply(query *gorm.DB) *gorm.DB {
+	if p.limit > 0 {
+		query = query.Limit(p.limit)
+	}
+	if p.offset > 0 {
+		query = query.Offset(p.offset)
+	}
+	return query
+}
+
+// paginateSlice returns the requested page of an in-memory list
+func paginateSlice[T any](items []T, p pageParams) []T {
+	if p.offset >= len(items) {
+		return []T{}
+	}
+	items = items[p.offset:]
+	if p.limit > 0 && len(items) > p.limit {
+		items = items[:p.limit]
+	}
+	return items
+}
+
+// respondPage writes a page of a list: the bare items, or the envelope when requested.
+// total is the number of items across all pages and count the number in this page
+func respondPage(c *gin.Context, p pageParams, items interface{}, count int, total int64) {
+	if !p.envelope {
+		c.JSON(http.StatusOK, items)
+		return
+	}
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Items:   items,
+		Total:   total,
+		Limit:   p.limit,
+		Offset:  p.offset,
+		HasMore: int64(p.offset+count) < total,
+	})
+}
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"bkt/internal/config"
 	"bkt/internal/database"
 	"bkt/internal/models"
 	"bkt/internal/security"
+	"bkt/internal/services"
+	"bkt/internal/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -28,31 +31,50 @@ func (h *PolicyHandler) ListPolicies(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	isAdmin, _ := c.Get("is_admin")
 
-	policies := make([]models.Policy, 0)
+	page, ok := bindPageParams(c, 0, maxPageLimit)
+	if !ok {
+		return
+	}
 
 	if isAdmin.(bool) {
 		// Admins can see all policies
-		if err := database.DB.Find(&policies).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to list policies",
-				Message: err.Error(),
-			})
-			return
+		policies := make([]models.Policy, 0)
+		query := database.DB.Model(&models.Policy{}).Session(&gorm.Session{})
+		var total int64
+		if page.envelope {
+			if err := query.Count(&total).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+					Error:   "Failed to list policies",
+					Message: err.Error(),
+				})
+				return
+			}
 		}
-	} else {
-		// Regular users can only see their attached policies
-		var user models.User
-		if err := database.DB.Preload("Policies").Where("id = ?", userID).First(&user).Error; err != nil {
+		if err := page.apply(query).Order("name ASC").Find(&policies).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to fetch user policies",
+				Error:   "Failed to list policies",
 				Message: err.Error(),
 			})
 			return
 		}
-		policies = user.Policies
+		respondPage(c, page, policies, len(policies), total)
+		return
 	}
 
-	c.JSON(http.StatusOK, policies)
+	// Regular users can only see their attached policies
+	var user models.User
+	if err := database.DB.Preload("Policies").Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to fetch user policies",
+			Message: err.Error(),
+		})
+		return
+	}
+	policies := user.Policies
+
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	pagePolicies := paginateSlice(policies, page)
+	respondPage(c, page, pagePolicies, len(pagePolicies), int64(len(policies)))
 }
 
 // CreatePolicy creates a new policy (admin only)
@@ -86,6 +108,11 @@ func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
 		return
 	}
 
+	h.savePolicy(c, req.Name, req.Description, policyDoc)
+}
+
+// savePolicy stores a validated policy document under a new name and writes the response
+func (h *PolicyHandler) savePolicy(c *gin.Context, name, description string, policyDoc *security.PolicyDocument) {
 	// Re-serialize validated policy (prevents injection attacks)
 	validatedDoc, err := json.Marshal(policyDoc)
 	if err != nil {
@@ -98,7 +125,7 @@ func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
 
 	// Check if policy with same name already exists
 	var existingPolicy models.Policy
-	if err := database.DB.Where("name = ?", req.Name).First(&existingPolicy).Error; err == nil {
+	if err := database.DB.Where("name = ?", name).First(&existingPolicy).Error; err == nil {
 		c.JSON(http.StatusConflict, models.ErrorResponse{
 			Error: "Policy with this name already exists",
 		})
@@ -107,8 +134,8 @@ func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
 
 	// Create policy
 	policy := models.Policy{
-		Name:        req.Name,
-		Description: req.Description,
+		Name:        name,
+		Description: description,
 		Document:    string(validatedDoc),
 	}
 
@@ -123,6 +150,101 @@ func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
 	c.JSON(http.StatusCreated, policy)
 }
 
+// ListPolicyTemplates returns the catalog of predefined policies (admin only). Each entry
+// includes an example document, scoped to the ${bucket} placeholder where a bucket applies
+func (h *PolicyHandler) ListPolicyTemplates(c *gin.Context) {
+	templates := security.ListPolicyTemplates()
+	catalog := make([]gin.H, 0, len(templates))
+	for _, template := range templates {
+		document, err := template.Render(security.BucketPlaceholder)
+		if err != nil {
+			continue
+		}
+		catalog = append(catalog, gin.H{
+			"name":        template.Name,
+			"description": template.Description,
+			"parameters":  template.Parameters,
+			"document":    document,
+		})
+	}
+
+	c.JSON(http.StatusOK, catalog)
+}
+
+// CreatePolicyFromTemplate instantiates a predefined policy with the given parameters and saves it (admin only)
+func (h *PolicyHandler) CreatePolicyFromTemplate(c *gin.Context) {
+	var req models.CreatePolicyFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	template, ok := security.GetPolicyTemplate(req.Template)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Unknown policy template",
+			Message: "See GET /api/policies/templates for the available templates",
+		})
+		return
+	}
+
+	for param := range req.Parameters {
+		if param != "bucket" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid template parameter",
+				Message: fmt.Sprintf("unknown parameter '%s'", param),
+			})
+			return
+		}
+	}
+	bucket := req.Parameters["bucket"]
+	if bucket != "" {
+		if err := validation.ValidateBucketName(bucket); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid template parameter",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	rendered, err := template.Render(bucket)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid template parameter",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Templates go through the same validation as hand-written documents
+	renderedJSON, err := json.Marshal(rendered)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to serialize policy document",
+			Message: err.Error(),
+		})
+		return
+	}
+	policyDoc, err := security.ValidatePolicyDocument(string(renderedJSON))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid policy document",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	description := req.Description
+	if description == "" {
+		description = template.Description
+	}
+	h.savePolicy(c, req.Name, description, policyDoc)
+}
+
 // GetPolicy gets a specific policy
 func (h *PolicyHandler) GetPolicy(c *gin.Context) {
 	policyID := c.Param("id")
@@ -232,6 +354,9 @@ func (h *PolicyHandler) UpdatePolicy(c *gin.Context) {
 		return
 	}
 
+	// Every user the policy is attached to is affected
+	services.InvalidatePolicyCache()
+
 	c.JSON(http.StatusOK, policy)
 }
 
@@ -282,6 +407,8 @@ func (h *PolicyHandler) DeletePolicy(c *gin.Context) {
 		return
 	}
 
+	services.InvalidatePolicyCache()
+
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Policy deleted successfully",
 	})
@@ -363,6 +490,8 @@ func (h *PolicyHandler) AttachPolicyToUser(c *gin.Context) {
 		return
 	}
 
+	services.InvalidateUserPolicyCache(userUUID)
+
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Policy attached successfully",
 	})
@@ -435,6 +564,8 @@ func (h *PolicyHandler) DetachPolicyFromUser(c *gin.Context) {
 		return
 	}
 
+	services.InvalidateUserPolicyCache(userUUID)
+
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Policy detached successfully",
 	})
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// objectAccessMaxPending caps the user/object pairs buffered between flushes; downloads of
+	// further pairs aren't tracked until the next flush
+	objectAccessMaxPending = 100000
+	// objectAccessRetention is how long a download stays in a user's recent objects
+	objectAccessRetention = 30 * 24 * time.Hour
+	// recentObjectsScanLimit is how many of a user's most recent downloads are considered for
+	// the list, before objects they can no longer read are filtered out
+	recentObjectsScanLimit = 500
+)
+
+// objectAccessKey identifies one aggregated access row. userID is uuid.Nil for anonymous
+// downloads (share links), which only update the object's own counters
+type objectAccessKey struct {
+	userID   uuid.UUID
+	objectID uuid.UUID
+}
+
+type objectAccessCounters struct {
+	count          int64
+	lastAccessedAt time.Time
+}
+
+// Downloads are aggregated in memory and flushed periodically, so reads never wait on a write
+var (
+	pendingObjectAccess   = make(map[objectAccessKey]*objectAccessCounters)
+	pendingObjectAccessMu sync.Mutex
+)
+
+// recordObjectAccess notes a download of objectID by userID
+func recordObjectAccess(userID, objectID uuid.UUID) {
+	key := objectAccessKey{userID: userID, objectID: objectID}
+	now := time.Now()
+
+	pendingObjectAccessMu.Lock()
+	defer pendingObjectAccessMu.Unlock()
+
+	counters, exists := pendingObjectAccess[key]
+	if !exists {
+		if len(pendingObjectAccess) >= objectAccessMaxPending {
+			return
+		}
+		counters = &objectAccessCounters{}
+		pendingObjectAccess[key] = counters
+	}
+	counters.count++
+	counters.lastAccessedAt = now
+}
+
+// FlushObjectAccess writes aggregated downloads to objects and user_object_accesses.
+// Failed pairs are put back so they're retried on the next flush
+func FlushObjectAccess() {
+	pendingObjectAccessMu.Lock()
+	batch := pendingObjectAccess
+	pendingObjectAccess = make(map[objectAccessKey]*objectAccessCounters)
+	pendingObjectAccessMu.Unlock()
+
+	for key, counters := range batch {
+		err := database.DB.Exec(`
+			UPDATE objects SET
+				last_accessed_at = GREATEST(COALESCE(last_accessed_at, ?), ?),
+				access_count = access_count + ?
+			WHERE id = ?
+		`, counters.lastAccessedAt, counters.lastAccessedAt, counters.count, key.objectID).Error
+		if err == nil && key.userID != uuid.Nil {
+			err = database.DB.Exec(`
+				INSERT INTO user_object_accesses (id, user_id, object_id, last_accessed_at, access_count)
+				SELECT gen_random_uuid(), ?, id, ?, ? FROM objects WHERE id = ?
+				ON CONFLICT (user_id, object_id) DO UPDATE SET
+					last_accessed_at = GREATEST(user_object_accesses.last_accessed_at, EXCLUDED.last_accessed_at),
+					access_count = user_object_accesses.access_count + EXCLUDED.access_count
+			`, key.userID, counters.lastAccessedAt, counters.count, key.objectID).Error
+		}
+		if err != nil {
+			logger.Warn("Failed to flush object access tracking", map[string]interface{}{
+				"object_id": key.objectID.String(),
+				"error":     err.Error(),
+			})
+			pendingObjectAccessMu.Lock()
+			existing, exists := pendingObjectAccess[key]
+			if !exists {
+				pendingObjectAccess[key] = counters
+			} else {
+				existing.count += counters.count
+				if counters.lastAccessedAt.After(existing.lastAccessedAt) {
+					existing.lastAccessedAt = counters.lastAccessedAt
+				}
+			}
+			pendingObjectAccessMu.Unlock()
+		}
+	}
+}
+
+// pruneObjectAccess drops recent-object rows past the retention period or for deleted objects
+func pruneObjectAccess() {
+	err := database.DB.Exec(`
+		DELETE FROM user_object_accesses
+		WHERE last_accessed_at < ?
+			OR NOT EXISTS (SELECT 1 FROM objects WHERE objects.id = user_object_accesses.object_id)
+	`, time.Now().Add(-objectAccessRetention)).Error
+	if err != nil {
+		logger.Warn("Failed to prune recent object accesses", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// StartObjectAccessFlush periodically persists download tracking in the background, and prunes
+// stale recent-object rows hourly
+func StartObjectAccessFlush(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		lastPrune := time.Now()
+		for range ticker.C {
+			FlushObjectAccess()
+			if time.Since(lastPrune) >= time.Hour {
+				pruneObjectAccess()
+				lastPrune = time.Now()
+			}
+		}
+	}()
+}
+
+// RecentObject is one entry of a user's recently downloaded objects
+type RecentObject struct {
+	BucketName     string        `json:"bucket"`
+	Object         models.Object `json:"object"`
+	LastAccessedAt time.Time     `json:"last_accessed_at"` // The caller's last download
+	AccessCount    int64         `json:"access_count"`     // The caller's downloads
+}
+
+// ListRecentObjects handles GET /api/users/me/recent-objects: the objects the caller downloaded
+// most recently, newest first. Objects they can no longer read are left out
+func (h *BucketHandler) ListRecentObjects(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	page, ok := bindPageParams(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	var accesses []models.UserObjectAccess
+	if err := database.DB.Where("user_id = ? AND last_accessed_at > ?", userUUID, time.Now().Add(-objectAccessRetention)).
+		Order("last_accessed_at DESC").Limit(recentObjectsScanLimit).Find(&accesses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to fetch recent objects",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	objectIDs := make([]uuid.UUID, len(accesses))
+	for i, access := range accesses {
+		objectIDs[i] = access.ObjectID
+	}
+	var objects []models.Object
+	if len(objectIDs) > 0 {
+		if err := database.DB.Preload("Bucket").Scopes(models.InLiveBuckets).Where("id IN ?", objectIDs).Find(&objects).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to fetch recent objects",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	objectsByID := make(map[uuid.UUID]models.Object, len(objects))
+	for _, object := range objects {
+		objectsByID[object.ID] = object
+	}
+
+	recent := make([]RecentObject, 0, len(accesses))
+	for _, access := range accesses {
+		object, exists := objectsByID[access.ObjectID]
+		if !exists {
+			continue // Deleted since; pruned later
+		}
+		allowed, err := h.policyService.CheckObjectAccess(c, userUUID, object.Bucket.Name, object.Key, services.ActionGetObject)
+		if err != nil || !allowed {
+			continue
+		}
+		bucketName := object.Bucket.Name
+		object.Bucket = models.Bucket{}
+		recent = append(recent, RecentObject{
+			BucketName:     bucketName,
+			Object:         object,
+			LastAccessedAt: access.LastAccessedAt,
+			AccessCount:    access.AccessCount,
+		})
+	}
+
+	items := paginateSlice(recent, page)
+	respondPage(c, page, items, len(items), int64(len(recent)))
+}
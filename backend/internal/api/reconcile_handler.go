@@ -0,0 +1,377 @@
+package api
+
+import (
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// reconcileConcurrency bounds how many buckets are reconciled in parallel
+	reconcileConcurrency = 4
+	// reconcileSampleSize caps the keys listed per category in a report
+	reconcileSampleSize = 100
+)
+
+// ReconcileRequest controls a reconciliation run
+type ReconcileRequest struct {
+	Bucket string `json:"bucket"` // Optional: reconcile a single bucket
+	Fix    bool   `json:"fix"`    // Repair discrepancies instead of only reporting them
+}
+
+// BucketReconcileReport describes the differences found in one bucket
+type BucketReconcileReport struct {
+	Bucket           string   `json:"bucket"`
+	StorageBackend   string   `json:"storage_backend"`
+	StorageObjects   int      `json:"storage_objects"`
+	DBObjects        int      `json:"db_objects"`
+	MissingInStorage int      `json:"missing_in_storage"` // DB rows with no backing file
+	UntrackedInDB    int      `json:"untracked_in_db"`    // Storage files with no DB row
+	RowsDeleted      int      `json:"rows_deleted"`
+	RowsCreated      int      `json:"rows_created"`
+	MissingKeys      []string `json:"missing_keys,omitempty"`   // Sample, capped at reconcileSampleSize
+	UntrackedKeys    []string `json:"untracked_keys,omitempty"` // Sample, capped at reconcileSampleSize
+	Error            string   `json:"error,omitempty"`
+}
+
+// ReconcileReport summarizes a reconciliation run across buckets
+type ReconcileReport struct {
+	StartedAt        time.Time               `json:"started_at"`
+	CompletedAt      time.Time               `json:"completed_at"`
+	Fix              bool                    `json:"fix"`
+	BucketsScanned   int                     `json:"buckets_scanned"`
+	BucketsFailed    int                     `json:"buckets_failed"`
+	MissingInStorage int                     `json:"missing_in_storage"`
+	UntrackedInDB    int                     `json:"untracked_in_db"`
+	RowsDeleted      int                     `json:"rows_deleted"`
+	RowsCreated      int                     `json:"rows_created"`
+	Buckets          []BucketReconcileReport `json:"buckets"`
+}
+
+// Only one reconciliation runs at a time; the last report is kept for GET /reconcile/last
+var (
+	reconcileMu         sync.Mutex
+	lastReconcileReport *ReconcileReport
+	lastReconcileMu     sync.RWMutex
+)
+
+type ReconcileHandler struct {
+	config        *config.Config
+	auditService  *services.AuditService
+	bucketHandler *BucketHandler
+}
+
+func NewReconcileHandler(cfg *config.Config) *ReconcileHandler {
+	return &ReconcileHandler{
+		config:        cfg,
+		auditService:  services.NewAuditService(),
+		bucketHandler: NewBucketHandler(cfg),
+	}
+}
+
+// RunReconciliation diffs storage against the objects table (admin only)
+// Reports by default; repairs both directions when "fix" is true
+func (h *ReconcileHandler) RunReconciliation(c *gin.Context) {
+	var req ReconcileRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	if !reconcileMu.TryLock() {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "A reconciliation is already running",
+		})
+		return
+	}
+	defer reconcileMu.Unlock()
+
+	var buckets []models.Bucket
+	query := database.DB.Order("name ASC")
+	if req.Bucket != "" {
+		query = query.Where("name = ?", req.Bucket)
+	}
+	if err := query.Find(&buckets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to load buckets",
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.Bucket != "" && len(buckets) == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	report := h.bucketHandler.reconcileBuckets(buckets, req.Fix)
+
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+	h.auditService.LogSuccess(c, userID.(uuid.UUID), username.(string),
+		"RunReconciliation", "Bucket", "", req.Bucket,
+		map[string]interface{}{
+			"fix":                req.Fix,
+			"buckets_scanned":    report.BucketsScanned,
+			"missing_in_storage": report.MissingInStorage,
+			"untracked_in_db":    report.UntrackedInDB,
+			"rows_deleted":       report.RowsDeleted,
+			"rows_created":       report.RowsCreated,
+		})
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetLastReconciliation returns the report of the most recent run (admin only)
+func (h *ReconcileHandler) GetLastReconciliation(c *gin.Context) {
+	lastReconcileMu.RLock()
+	report := lastReconcileReport
+	lastReconcileMu.RUnlock()
+
+	if report == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "No reconciliation has run yet",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// StartReconciliation runs reconciliation across all buckets on a fixed interval
+func StartReconciliation(cfg *config.Config, interval time.Duration, fix bool) {
+	h := NewBucketHandler(cfg)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !reconcileMu.TryLock() {
+				continue // A manual run is in progress
+			}
+
+			var buckets []models.Bucket
+			if err := database.DB.Order("name ASC").Find(&buckets).Error; err != nil {
+				reconcileMu.Unlock()
+				logger.Warn("Scheduled reconciliation failed to load buckets", map[string]interface{}{
+					"error": err.Error(),
+				})
+				continue
+			}
+
+			// Don't repair while writes are frozen; report only
+			runFix := fix && !middleware.GetMaintenanceMode().Enabled
+			report := h.reconcileBuckets(buckets, runFix)
+			reconcileMu.Unlock()
+
+			logger.Info("Scheduled reconciliation completed", map[string]interface{}{
+				"fix":                runFix,
+				"buckets_scanned":    report.BucketsScanned,
+				"buckets_failed":     report.BucketsFailed,
+				"missing_in_storage": report.MissingInStorage,
+				"untracked_in_db":    report.UntrackedInDB,
+				"rows_deleted":       report.RowsDeleted,
+				"rows_created":       report.RowsCreated,
+			})
+		}
+	}()
+}
+
+// reconcileBuckets reconciles buckets with bounded concurrency and stores the report
+func (h *BucketHandler) reconcileBuckets(buckets []models.Bucket, fix bool) *ReconcileReport {
+	report := &ReconcileReport{
+		StartedAt: time.Now(),
+		Fix:       fix,
+		Buckets:   make([]BucketReconcileReport, len(buckets)),
+	}
+
+	sem := make(chan struct{}, reconcileConcurrency)
+	var wg sync.WaitGroup
+	for i := range buckets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			report.Buckets[i] = h.reconcileBucket(&buckets[i], fix)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, b := range report.Buckets {
+		report.BucketsScanned++
+		if b.Error != "" {
+			report.BucketsFailed++
+		}
+		report.MissingInStorage += b.MissingInStorage
+		report.UntrackedInDB += b.UntrackedInDB
+		report.RowsDeleted += b.RowsDeleted
+		report.RowsCreated += b.RowsCreated
+	}
+	report.CompletedAt = time.Now()
+
+	lastReconcileMu.Lock()
+	lastReconcileReport = report
+	lastReconcileMu.Unlock()
+
+	return report
+}
+
+// reconcileBucket diffs one bucket's storage listing against its DB rows
+func (h *BucketHandler) reconcileBucket(bucket *models.Bucket, fix bool) BucketReconcileReport {
+	result := BucketReconcileReport{
+		Bucket:         bucket.Name,
+		StorageBackend: bucket.StorageBackend,
+	}
+
+	storageBackend, err := h.getStorageBackend(bucket)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	storageObjects, err := storageBackend.ListObjects(bucket.Name, "")
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to list storage: %v", err)
+		return result
+	}
+	result.StorageObjects = len(storageObjects)
+
+	var rows []struct {
+		ID  uuid.UUID
+		Key string
+	}
+	if err := database.DB.Model(&models.Object{}).Select("id, key").
+		Where("bucket_id = ?", bucket.ID).Scan(&rows).Error; err != nil {
+		result.Error = fmt.Sprintf("failed to load objects: %v", err)
+		return result
+	}
+	result.DBObjects = len(rows)
+
+	storageKeys := make(map[string]bool, len(storageObjects))
+	for _, obj := range storageObjects {
+		storageKeys[obj.Key] = true
+	}
+	dbKeys := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		dbKeys[row.Key] = true
+	}
+
+	// DB rows with no backing file
+	// Listings can be truncated (S3 caps at 10,000 keys) and uploads may land mid-scan,
+	// so each candidate is confirmed with a direct existence check before being reported
+	missingIDs := make([]uuid.UUID, 0)
+	for _, row := range rows {
+		if storageKeys[row.Key] {
+			continue
+		}
+		exists, err := storageBackend.ObjectExists(bucket.Name, row.Key)
+		if err != nil || exists {
+			continue
+		}
+		missingIDs = append(missingIDs, row.ID)
+		if len(result.MissingKeys) < reconcileSampleSize {
+			result.MissingKeys = append(result.MissingKeys, row.Key)
+		}
+	}
+	result.MissingInStorage = len(missingIDs)
+
+	// Storage files with no DB row
+	untracked := make([]models.Object, 0)
+	for _, obj := range storageObjects {
+		if dbKeys[obj.Key] {
+			continue
+		}
+		lastModified := time.Now()
+		if obj.LastModified != "" {
+			if parsed, err := time.Parse(time.RFC3339, obj.LastModified); err == nil {
+				lastModified = parsed
+			}
+		}
+		untracked = append(untracked, models.Object{
+			BucketID:    bucket.ID,
+			Key:         obj.Key,
+			Size:        obj.Size,
+			ContentType: obj.ContentType,
+			ETag:        obj.ETag,
+			StoragePath: obj.Key,
+			CreatedAt:   lastModified,
+			UpdatedAt:   lastModified,
+		})
+		if len(result.UntrackedKeys) < reconcileSampleSize {
+			result.UntrackedKeys = append(result.UntrackedKeys, obj.Key)
+		}
+	}
+	result.UntrackedInDB = len(untracked)
+
+	if !fix {
+		return result
+	}
+
+	// Repair in batches of 100 (same batching as the ListObjects sync)
+	const batchSize = 100
+	for i := 0; i < len(missingIDs); i += batchSize {
+		end := i + batchSize
+		if end > len(missingIDs) {
+			end = len(missingIDs)
+		}
+		res := database.DB.Where("id IN ?", missingIDs[i:end]).Delete(&models.Object{})
+		if res.Error != nil {
+			result.Error = fmt.Sprintf("failed to delete orphaned rows: %v", res.Error)
+			return result
+		}
+		result.RowsDeleted += int(res.RowsAffected)
+	}
+
+	for i := 0; i < len(untracked); i += batchSize {
+		end := i + batchSize
+		if end > len(untracked) {
+			end = len(untracked)
+		}
+		batch := untracked[i:end]
+
+		valueStrings := make([]string, 0, len(batch))
+		valueArgs := make([]interface{}, 0, len(batch)*8)
+		for _, obj := range batch {
+			valueStrings = append(valueStrings, "(gen_random_uuid(), ?, ?, ?, ?, ?, ?, '', ?, ?)")
+			valueArgs = append(valueArgs, obj.BucketID, obj.Key, obj.Size, obj.ContentType, obj.ETag, obj.StoragePath, obj.CreatedAt, obj.UpdatedAt)
+		}
+
+		query := fmt.Sprintf(`
+			INSERT INTO objects (id, bucket_id, key, size, content_type, e_tag, storage_path, sha256, created_at, updated_at)
+			VALUES %s
+			ON CONFLICT (bucket_id, key) DO NOTHING
+		`, strings.Join(valueStrings, ","))
+
+		res := database.DB.Exec(query, valueArgs...)
+		if res.Error != nil {
+			result.Error = fmt.Sprintf("failed to create rows for untracked files: %v", res.Error)
+			return result
+		}
+		result.RowsCreated += int(res.RowsAffected)
+	}
+
+	logger.Info("Reconciled bucket", map[string]interface{}{
+		"bucket":       bucket.Name,
+		"rows_deleted": result.RowsDeleted,
+		"rows_created": result.RowsCreated,
+	})
+
+	return result
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"time"
 
 	authpkg "bkt/internal/auth"
@@ -14,19 +15,46 @@ import (
 func SetupRouter(cfg *config.Config) *gin.Engine {
 	router := gin.Default()
 
+	// c.ClientIP() (audit logs, access logs, rate limits, the aws:SourceIp policy condition) only
+	// believes X-Forwarded-For from TRUSTED_PROXIES; otherwise any client could choose its own address
+	trustedProxies := make([]string, len(cfg.Server.TrustedProxyNets))
+	for i, network := range cfg.Server.TrustedProxyNets {
+		trustedProxies[i] = network.String()
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		panic(fmt.Sprintf("Invalid TRUSTED_PROXIES: %v", err))
+	}
+
 	// Request ID middleware - adds unique ID to each request for tracing
 	router.Use(middleware.RequestIDMiddleware())
 
+	// Overall request deadline (larger budget for transfers) and slow-request logging
+	router.Use(middleware.RequestTimeoutMiddleware(cfg.Server.RequestTimeoutDuration,
+		cfg.Server.TransferRequestTimeoutDuration, cfg.Server.SlowRequestThresholdDuration))
+
+	// Reject HTTP methods outside ALLOWED_HTTP_METHODS
+	router.Use(middleware.AllowedMethodsMiddleware(cfg.Security.AllowedMethods))
+
+	// Security headers (nosniff, HSTS, X-Frame-Options, CSP, Referrer-Policy)
+	router.Use(middleware.SecurityHeadersMiddleware(cfg.Security))
+
+	// Static websites on <bucket>.<WEBSITE_DOMAIN> hosts are served before any API handling
+	websiteHandler := NewWebsiteHandler(cfg)
+	router.Use(websiteHandler.HostMiddleware())
+
 	// User-Agent validation - prevents malformed requests
 	router.Use(middleware.UserAgentValidationMiddleware())
 
+	// Maintenance mode - rejects writes with 503 while enabled (reads and auth stay available)
+	router.Use(middleware.MaintenanceMiddleware())
+
 	// CORS configuration - loaded from environment for security (CORS_ALLOWED_ORIGINS)
 	// Defaults to development origins if not set. In production, always set explicitly.
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORS.AllowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Amz-Date", "X-Amz-Content-Sha256", "X-Request-ID", "Idempotency-Key"},
-		ExposeHeaders:    []string{"Content-Length", "ETag", "X-Amz-Request-Id", "X-Request-ID"},
+		AllowMethods:     cfg.Security.AllowedMethods,
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Requested-With", "X-Amz-Date", "X-Amz-Content-Sha256", "X-Request-ID", "Idempotency-Key", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata", "X-Expires-After"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "X-Amz-Request-Id", "X-Request-ID", "Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "X-Amz-Bucket-Region", "X-Bkt-Object-Count", "X-Bkt-Bytes-Used", "X-Bkt-Expires-At", "X-Bkt-Expires-In", "X-Content-SHA256", "Retry-After"},
 		AllowCredentials: cfg.CORS.AllowCredentials,
 	}))
 
@@ -37,6 +65,9 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 
 	// API routes group
 	api := router.Group("/api")
+	if cfg.Server.ErrorNegotiation {
+		api.Use(middleware.ErrorNegotiationMiddleware()) // XML errors for clients that Accept XML
+	}
 	{
 		// Auth routes (no authentication required)
 		authHandler := NewAuthHandler(cfg)
@@ -69,6 +100,10 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 			auth.GET("/vault/callback", vaultOIDCHandler.HandleVaultCallback)
 		}
 
+		// Static website path endpoint (anonymous; for buckets with a website configuration)
+		api.GET("/website/:bucket/*path", websiteHandler.ServeWebsitePath)
+		api.HEAD("/website/:bucket/*path", websiteHandler.ServeWebsitePath)
+
 		// Protected routes (require authentication)
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware(cfg.Auth.JWTSecret))
@@ -85,6 +120,8 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 				users.DELETE("/:id", middleware.AdminMiddleware(), userHandler.DeleteUser)
 				users.POST("/:id/lock", middleware.AdminMiddleware(), userHandler.LockUser)
 				users.POST("/:id/unlock", middleware.AdminMiddleware(), userHandler.UnlockUser)
+				users.PUT("/:id/role", middleware.AdminMiddleware(), userHandler.UpdateUserRole)
+				users.PUT("/:id/limits", middleware.AdminMiddleware(), userHandler.UpdateUserLimits)
 				users.GET("/:id/access-keys", middleware.AdminMiddleware(), userHandler.ListUserAccessKeys)
 				users.DELETE("/:id/access-keys/:key_id", middleware.AdminMiddleware(), userHandler.DeleteUserAccessKey)
 			}
@@ -96,37 +133,112 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 				accessKeys.GET("", accessKeyHandler.ListAccessKeys)
 				accessKeys.POST("", accessKeyHandler.GenerateAccessKey)
 				accessKeys.DELETE("/:id", accessKeyHandler.RevokeAccessKey)
+				accessKeys.POST("/:id/rotate", accessKeyHandler.RotateAccessKey) // Zero-downtime rotation with grace period
 				accessKeys.GET("/stats", accessKeyHandler.GetAccessKeyStats)
 			}
 
+			// Access key incident response (admin only)
+			admin := protected.Group("/admin")
+			admin.Use(middleware.AdminMiddleware())
+			{
+				admin.GET("/access-keys/:id/activity", accessKeyHandler.GetAccessKeyActivity)
+				admin.POST("/access-keys/:id/cancel", accessKeyHandler.CancelAccessKeyRequests)
+				admin.DELETE("/access-keys/revoked", accessKeyHandler.PurgeRevokedAccessKeys)
+			}
+
+			// Service accounts: non-login machine identities (admin only)
+			serviceAccountHandler := NewServiceAccountHandler(cfg)
+			serviceAccounts := protected.Group("/service-accounts")
+			serviceAccounts.Use(middleware.AdminMiddleware())
+			{
+				serviceAccounts.GET("", serviceAccountHandler.ListServiceAccounts)
+				serviceAccounts.POST("", serviceAccountHandler.CreateServiceAccount)
+				serviceAccounts.POST("/:id/token", serviceAccountHandler.IssueServiceAccountToken)
+				serviceAccounts.POST("/:id/revoke", serviceAccountHandler.RevokeServiceAccount)
+			}
+
 			// Bucket routes
 			bucketHandler := NewBucketHandler(cfg)
 			buckets := protected.Group("/buckets")
+			buckets.Use(middleware.UsageMiddleware(), accessLogMiddleware(cfg))
 			{
 				buckets.GET("", bucketHandler.ListBuckets)
 				buckets.POST("", middleware.AdminMiddleware(), bucketHandler.CreateBucket) // Admin only
 				buckets.GET("/:name", bucketHandler.GetBucket)
+				buckets.HEAD("/:name", bucketHandler.HeadBucket)
 				buckets.DELETE("/:name", middleware.AdminMiddleware(), bucketHandler.DeleteBucket) // Admin only
 				buckets.PUT("/:name/policy", middleware.AdminMiddleware(), bucketHandler.SetBucketPolicy) // Admin only
 				buckets.GET("/:name/policy", bucketHandler.GetBucketPolicy)
+				buckets.DELETE("/:name/policy", middleware.AdminMiddleware(), bucketHandler.DeleteBucketPolicy) // Admin only
+				buckets.PUT("/:name/overwrite-protection", middleware.AdminMiddleware(), bucketHandler.SetOverwriteProtection) // Admin only
+				buckets.PUT("/:name/append-mode", middleware.AdminMiddleware(), bucketHandler.SetAppendMode) // Admin only
+				buckets.PUT("/:name/auto-date-prefix", middleware.AdminMiddleware(), bucketHandler.SetAutoDatePrefix) // Admin only
+				buckets.PUT("/:name/max-objects", middleware.AdminMiddleware(), bucketHandler.SetMaxObjects) // Admin only
+				buckets.GET("/:name/key-rules", bucketHandler.GetKeyRules)
+				buckets.PUT("/:name/key-rules", middleware.AdminMiddleware(), bucketHandler.SetKeyRules) // Admin only
+				buckets.GET("/:name/access-logging", middleware.AdminMiddleware(), bucketHandler.GetAccessLogging) // Admin only
+				buckets.PUT("/:name/access-logging", middleware.AdminMiddleware(), bucketHandler.SetAccessLogging) // Admin only
+				buckets.GET("/:name/inventory", bucketHandler.ExportInventory) // CSV/NDJSON object manifest
+				buckets.POST("/:name/sync", bucketHandler.SyncBucket)         // Admin or owner: full storage-to-DB sync (background)
+				buckets.GET("/:name/sync", bucketHandler.GetBucketSync)       // Sync progress
 
 				// Object routes within a bucket - use :name to match the bucket parameter above
 				buckets.GET("/:name/objects", bucketHandler.ListObjects)
+				buckets.GET("/:name/folder-sizes", bucketHandler.GetFolderSizes) // Size and object count per sub-prefix
 				buckets.POST("/:name/objects", bucketHandler.UploadObject)
+				buckets.POST("/:name/presign-post", bucketHandler.PresignPost) // Signed browser form upload policy
+				buckets.POST("/:name/presign/*key", bucketHandler.PresignObjectURL) // Time-limited GET/PUT URL for one object
+				buckets.POST("/:name/shares", bucketHandler.CreateShareLink)   // Public download link for an object
 				buckets.POST("/:name/objects/async", bucketHandler.UploadObjectAsync) // Async upload
+				buckets.POST("/:name/objects/bulk", bucketHandler.UploadObjectsBulk)  // Many files or a tar/zip archive
 				buckets.POST("/:name/objects/move", bucketHandler.MoveObject)         // Move object
 				buckets.POST("/:name/objects/rename", bucketHandler.RenameObject)     // Rename object
+				buckets.POST("/:name/objects/copy-to", bucketHandler.CopyObjectToBucket) // Copy object to another bucket
+				buckets.POST("/:name/folders", bucketHandler.CreateFolder)            // Create empty folder (.keep marker)
+				buckets.DELETE("/:name/folders", bucketHandler.DeleteFolder)          // Delete folder (?prefix=, ?recursive=true)
 				buckets.POST("/:name/folders/move", bucketHandler.MoveFolder)         // Move folder recursively
+				buckets.GET("/:name/preview/*key", bucketHandler.PreviewObject)         // Text preview of the object head
+				buckets.POST("/:name/append/*key", bucketHandler.AppendObject)          // Append to an object (append-mode buckets)
+				buckets.GET("/:name/tags/*key", bucketHandler.GetObjectTags)            // Object tags
+				buckets.PUT("/:name/tags/*key", bucketHandler.SetObjectTags)            // Replace object tags
+				buckets.DELETE("/:name/tags/*key", bucketHandler.DeleteObjectTags)      // Remove object tags
+				buckets.GET("/:name/by-hash/:sha256", bucketHandler.DownloadObjectByHash) // Content-addressed download
 				buckets.GET("/:name/objects/*key", bucketHandler.DownloadObject)
 				buckets.DELETE("/:name/objects/*key", bucketHandler.DeleteObject)
 				buckets.HEAD("/:name/objects/*key", bucketHandler.HeadObject)
 			}
 
+			// The caller's share links
+			shares := protected.Group("/shares")
+			{
+				shares.GET("", bucketHandler.ListShareLinks)
+				shares.DELETE("/:id", bucketHandler.RevokeShareLink) // Creator or admin
+			}
+
+			// The caller's recently downloaded objects
+			users.GET("/me/recent-objects", bucketHandler.ListRecentObjects)
+
+			// Cross-bucket content hash lookup (admin only)
+			admin.GET("/objects/by-hash/:sha256", bucketHandler.FindObjectsByHash)
+
+			// Buckets in the trash (BUCKET_DELETE_GRACE), admin only
+			admin.GET("/deleted-buckets", bucketHandler.ListDeletedBuckets)
+			admin.POST("/deleted-buckets/:name/restore", bucketHandler.RestoreBucket)
+			admin.DELETE("/deleted-buckets/:name", bucketHandler.PurgeDeletedBucket)
+
 			// Upload status routes (for async uploads)
 			uploads := protected.Group("/uploads")
+			uploads.Use(middleware.UsageMiddleware(), accessLogMiddleware(cfg))
 			{
 				uploads.GET("", bucketHandler.ListUploads)
 				uploads.GET("/:id/status", bucketHandler.GetUploadStatus)
+				uploads.GET("/:id/events", bucketHandler.StreamUploadEvents) // Live progress (server-sent events)
+
+				// Resumable uploads (tus protocol)
+				uploads.POST("/tus", bucketHandler.TusCreateUpload)
+				uploads.HEAD("/tus/:id", bucketHandler.TusHeadUpload)
+				uploads.PATCH("/tus/:id", bucketHandler.TusPatchUpload)
+				uploads.DELETE("/tus/:id", bucketHandler.TusDeleteUpload)
 			}
 
 			// Policy routes
@@ -135,6 +247,8 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 			{
 				policies.GET("", policyHandler.ListPolicies) // Regular users see their policies, admins see all
 				policies.POST("", middleware.AdminMiddleware(), policyHandler.CreatePolicy) // Admin only
+				policies.GET("/templates", middleware.AdminMiddleware(), policyHandler.ListPolicyTemplates) // Admin only
+				policies.POST("/from-template", middleware.AdminMiddleware(), policyHandler.CreatePolicyFromTemplate) // Admin only
 				policies.GET("/:id", middleware.AdminMiddleware(), policyHandler.GetPolicy) // Admin only
 				policies.PUT("/:id", middleware.AdminMiddleware(), policyHandler.UpdatePolicy) // Admin only
 				policies.DELETE("/:id", middleware.AdminMiddleware(), policyHandler.DeletePolicy) // Admin only
@@ -149,35 +263,107 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 			{
 				s3Configs.GET("", s3ConfigHandler.ListS3Configs)
 				s3Configs.POST("", s3ConfigHandler.CreateS3Config)
+				s3Configs.GET("/cache-stats", s3ConfigHandler.GetS3ConfigCacheStats)
 				s3Configs.GET("/:id", s3ConfigHandler.GetS3Config)
 				s3Configs.PUT("/:id", s3ConfigHandler.UpdateS3Config)
 				s3Configs.DELETE("/:id", s3ConfigHandler.DeleteS3Config)
 			}
+
+			// Client certificate bindings for S3 mTLS (admin only)
+			clientCertHandler := NewClientCertHandler(cfg)
+			clientCerts := protected.Group("/client-certs")
+			clientCerts.Use(middleware.AdminMiddleware())
+			{
+				clientCerts.GET("", clientCertHandler.ListClientCertBindings)
+				clientCerts.POST("", clientCertHandler.CreateClientCertBinding)
+				clientCerts.DELETE("/:id", clientCertHandler.DeleteClientCertBinding)
+			}
+
+			// Bandwidth usage reports
+			usageHandler := NewUsageHandler(cfg)
+			usage := protected.Group("/usage")
+			{
+				usage.GET("", middleware.AdminMiddleware(), usageHandler.GetUsage) // Admin only
+				usage.GET("/me", usageHandler.GetMyUsage)
+			}
+
+			// Storage/DB reconciliation (admin only)
+			reconcileHandler := NewReconcileHandler(cfg)
+			reconcile := protected.Group("/reconcile")
+			reconcile.Use(middleware.AdminMiddleware())
+			{
+				reconcile.POST("", reconcileHandler.RunReconciliation)
+				reconcile.GET("/last", reconcileHandler.GetLastReconciliation)
+				reconcile.GET("/integrity", reconcileHandler.GetIntegrityReport)
+			}
+
+			// Dashboard overview counts (admin only)
+			adminStatsHandler := NewAdminStatsHandler(cfg)
+			admin.GET("/stats", adminStatsHandler.GetStats)
+
+			// Metadata backup and migration (admin only, also require METADATA_TRANSFER_TOKEN)
+			metadataTransferHandler := NewMetadataTransferHandler(cfg)
+			admin.GET("/export", metadataTransferHandler.Export)
+			admin.POST("/import", metadataTransferHandler.Import)
+
+			// Server-wide maintenance (read-only) mode
+			maintenanceHandler := NewMaintenanceHandler(cfg)
+			maintenance := protected.Group("/maintenance")
+			{
+				maintenance.GET("", maintenanceHandler.GetMaintenanceMode)
+				maintenance.PUT("", middleware.AdminMiddleware(), maintenanceHandler.SetMaintenanceMode) // Admin only
+			}
 		}
 
-		// Logout (requires authentication)
+		// tus capability discovery (no authentication required)
+		api.OPTIONS("/uploads/tus", NewBucketHandler(cfg).TusOptions)
+
+		// Share link downloads (anonymous; POST submits a protected link's password form)
+		shareRateLimit := middleware.RateLimitMiddleware(30, time.Minute)
+		shareHandler := NewBucketHandler(cfg)
+		api.GET("/share/:token", shareRateLimit, middleware.UsageMiddleware(), accessLogMiddleware(cfg), shareHandler.DownloadShareLink)
+		api.POST("/share/:token", shareRateLimit, middleware.UsageMiddleware(), accessLogMiddleware(cfg), shareHandler.DownloadShareLink)
+
+		// Browser form uploads authorized by a presigned POST policy instead of a session
+		api.POST("/presigned-post/:name", middleware.UsageMiddleware(), accessLogMiddleware(cfg), NewBucketHandler(cfg).PresignedPostUpload)
+
+		// Object downloads and raw-body uploads authorized by a presigned URL instead of a session.
+		// They run as the URL's issuer; uploads go through the S3 PutObject path with only the body
+		// and Content-Type taken from the request
+		api.GET("/presigned/:name/*key", middleware.PresignedURLMiddleware(cfg.Auth.JWTSecret, "name"), middleware.UsageMiddleware(), accessLogMiddleware(cfg), NewBucketHandler(cfg).DownloadObject)
+		api.PUT("/presigned/:bucket/*key", middleware.PresignedURLMiddleware(cfg.Auth.JWTSecret, "bucket"), middleware.UsageMiddleware(), accessLogMiddleware(cfg), NewS3APIHandler(cfg).PresignedPutObject)
+
+		// Logout and cookie session exchange (require authentication)
 		api.POST("/auth/logout", middleware.AuthMiddleware(cfg.Auth.JWTSecret), authHandler.Logout)
+		api.POST("/auth/session", middleware.AuthMiddleware(cfg.Auth.JWTSecret), authHandler.CreateSession)
 	}
 
-	// S3-compatible API routes (authenticated with AWS Signature V4)
+	// S3-compatible API routes (authenticated with AWS Signature V4 and/or client certificates)
 	// These routes enable s3fs-fuse and other S3 clients to mount buckets
 	s3Handler := NewS3APIHandler(cfg)
 	s3 := router.Group("")
-	s3.Use(middleware.S3AuthMiddleware())
+	if cfg.Server.ErrorNegotiation {
+		s3.Use(middleware.ErrorNegotiationMiddleware()) // Middleware's JSON auth errors become XML for XML clients
+	}
+	s3.Use(middleware.S3AuthMiddleware(cfg.TLS, cfg.Server.TrustedProxyNets, cfg.Auth.AuditS3Requests))
+	s3.Use(middleware.UsageMiddleware(), accessLogMiddleware(cfg))
 	{
 		// Service-level operations
 		s3.GET("/", s3Handler.ListBuckets)
 
 		// Bucket-level operations
 		s3.HEAD("/:bucket", s3Handler.HeadBucket)
-		s3.GET("/:bucket", s3Handler.ListObjects)
-		s3.PUT("/:bucket", s3Handler.CreateBucket) // Currently disabled
+		s3.GET("/:bucket", s3Handler.GetBucket)       // ListObjects, or ?location / ?encryption / ?object-lock / ?website / ?acl / ?versioning / ?versions
+		s3.PUT("/:bucket", s3Handler.PutBucket)       // CreateBucket (currently disabled), or ?encryption / ?object-lock / ?website / ?acl / ?versioning
+		s3.DELETE("/:bucket", s3Handler.DeleteBucket) // ?website
+		s3.POST("/:bucket", s3Handler.PostBucket)     // DeleteObjects (?delete)
 
 		// Object-level operations
 		s3.HEAD("/:bucket/*key", s3Handler.HeadObject)
-		s3.GET("/:bucket/*key", s3Handler.GetObject)
-		s3.PUT("/:bucket/*key", s3Handler.PutObject)
-		s3.DELETE("/:bucket/*key", s3Handler.DeleteObject)
+		s3.GET("/:bucket/*key", s3Handler.GetObject)       // or ?acl / ?tagging, or a previous version (?versionId)
+		s3.PUT("/:bucket/*key", s3Handler.PutObject)       // or ?acl / ?tagging, UploadPart (?partNumber&uploadId) or CopyObject (x-amz-copy-source)
+		s3.POST("/:bucket/*key", s3Handler.PostObject)     // CreateMultipartUpload (?uploads) / CompleteMultipartUpload (?uploadId)
+		s3.DELETE("/:bucket/*key", s3Handler.DeleteObject) // or ?tagging, AbortMultipartUpload (?uploadId) or DeleteObjectVersion (?versionId)
 	}
 
 	return router
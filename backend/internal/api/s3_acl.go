@@ -0,0 +1,316 @@
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ACL subresources (?acl on buckets and objects). bkt has no grant lists; access comes from user
+// and bucket policies. Only the canned ACLs that map onto bkt's settings are accepted:
+//   - buckets: private and public-read toggle the bucket's is_public flag
+//   - objects: private sets the object's private ACL, bucket-owner-full-control the inherit ACL,
+//     and public-read the inherit ACL when the bucket itself is public-read
+//
+// Reads describe those settings as S3 grants: the bucket owner's FULL_CONTROL, plus READ for
+// AllUsers when the bucket (and, for objects, the ACL) is public
+
+const (
+	// maxACLPolicySize caps a PUT ?acl body
+	maxACLPolicySize = 64 * 1024
+
+	aclCannedPrivate    = "private"
+	aclCannedPublicRead = "public-read"
+
+	aclGranteeCanonicalUser = "CanonicalUser"
+	aclGranteeGroup         = "Group"
+	aclAllUsersURI          = "http://acs.amazonaws.com/groups/global/AllUsers"
+	aclPermissionFull       = "FULL_CONTROL"
+	aclPermissionRead       = "READ"
+	xmlSchemaInstanceNS     = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// aclGrantHeaders are the explicit grant headers, which have no equivalent in bkt
+var aclGrantHeaders = []string{
+	"x-amz-grant-full-control",
+	"x-amz-grant-read",
+	"x-amz-grant-read-acp",
+	"x-amz-grant-write",
+	"x-amz-grant-write-acp",
+}
+
+// AccessControlPolicy is the S3 ?acl document
+type AccessControlPolicy struct {
+	XMLName           xml.Name          `xml:"AccessControlPolicy"`
+	Xmlns             string            `xml:"xmlns,attr,omitempty"`
+	Owner             Owner             `xml:"Owner"`
+	AccessControlList AccessControlList `xml:"AccessControlList"`
+}
+
+type AccessControlList struct {
+	Grant []ACLGrant `xml:"Grant"`
+}
+
+type ACLGrant struct {
+	Grantee    ACLGrantee `xml:"Grantee"`
+	Permission string     `xml:"Permission"`
+}
+
+// ACLGrantee is written with an xsi:type attribute. Parsing resolves the xsi prefix away, so
+// incoming documents set Type instead of XsiType
+type ACLGrantee struct {
+	XmlnsXsi     string `xml:"xmlns:xsi,attr,omitempty"`
+	XsiType      string `xml:"xsi:type,attr,omitempty"`
+	Type         string `xml:"type,attr,omitempty"`
+	ID           string `xml:"ID,omitempty"`
+	DisplayName  string `xml:"DisplayName,omitempty"`
+	URI          string `xml:"URI,omitempty"`
+	EmailAddress string `xml:"EmailAddress,omitempty"`
+}
+
+// GetBucketAcl handles GET /{bucket}?acl
+func (h *S3APIHandler) GetBucketAcl(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	bucket, ok := h.loadBucketForConfig(c, bucketName, services.ActionGetBucketAcl)
+	if !ok {
+		return
+	}
+
+	c.XML(http.StatusOK, aclPolicy(bucket, bucket.IsPublic))
+}
+
+// PutBucketAcl handles PUT /{bucket}?acl: public-read makes the bucket public, private clears it
+func (h *S3APIHandler) PutBucketAcl(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	bucket, ok := h.loadBucketForConfig(c, bucketName, services.ActionPutBucketAcl)
+	if !ok {
+		return
+	}
+
+	canned, ok := h.requestedCannedACL(c, bucket, bucketName)
+	if !ok {
+		return
+	}
+
+	var public bool
+	switch canned {
+	case aclCannedPrivate:
+		public = false
+	case aclCannedPublicRead:
+		public = true
+	default:
+		h.s3Error(c, "NotImplemented", fmt.Sprintf("Canned ACL %q is not supported on buckets (supported: private, public-read)", canned), bucketName, http.StatusNotImplemented)
+		return
+	}
+
+	if public != bucket.IsPublic {
+		if err := database.DB.Model(bucket).Update("is_public", public).Error; err != nil {
+			h.s3Error(c, "InternalError", "Failed to update bucket ACL", bucketName, http.StatusInternalServerError)
+			return
+		}
+		services.InvalidateBucketPolicyCache(bucketName)
+
+		logger.Info("Bucket ACL updated", map[string]interface{}{
+			"bucket":    bucketName,
+			"is_public": public,
+		})
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// GetObjectAcl handles GET /{bucket}/{key+}?acl
+func (h *S3APIHandler) GetObjectAcl(c *gin.Context) {
+	bucket, object, ok := h.loadObjectForACL(c, services.ActionGetObjectAcl)
+	if !ok {
+		return
+	}
+
+	c.XML(http.StatusOK, aclPolicy(bucket, bucket.IsPublic && object.ACL != models.ObjectACLPrivate))
+}
+
+// PutObjectAcl handles PUT /{bucket}/{key+}?acl. The ACL is stored without touching the object's
+// content or Last-Modified time
+func (h *S3APIHandler) PutObjectAcl(c *gin.Context) {
+	bucket, object, ok := h.loadObjectForACL(c, services.ActionPutObjectAcl)
+	if !ok {
+		return
+	}
+
+	canned, ok := h.requestedCannedACL(c, bucket, object.Key)
+	if !ok {
+		return
+	}
+	acl, err := parseS3ObjectACL(canned, bucket)
+	if err != nil {
+		h.s3Error(c, "NotImplemented", err.Error(), object.Key, http.StatusNotImplemented)
+		return
+	}
+
+	if acl != object.ACL {
+		if err := database.DB.Model(&models.Object{}).Where("id = ?", object.ID).UpdateColumn("acl", acl).Error; err != nil {
+			h.s3Error(c, "InternalError", "Failed to update object ACL", object.Key, http.StatusInternalServerError)
+			return
+		}
+
+		logger.Info("Object ACL updated", map[string]interface{}{
+			"bucket": bucket.Name,
+			"key":    object.Key,
+			"acl":    acl,
+		})
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// loadObjectForACL loads the bucket and object named by the request and verifies the caller may
+// perform action on the object, writing the S3 error response otherwise
+func (h *S3APIHandler) loadObjectForACL(c *gin.Context, action string) (*models.Bucket, *models.Object, bool) {
+	bucketName := c.Param("bucket")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		h.s3Error(c, "NoSuchBucket", "The specified bucket does not exist", bucketName, http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	objectKey := bucket.NormalizeKey(strings.TrimPrefix(c.Param("key"), "/"))
+	object, access, err := h.bucketHandler.resolveObjectAccess(c, userUUID, &bucket, objectKey, action)
+	if !h.respondObjectAccess(c, access, err, objectKey) {
+		return nil, nil, false
+	}
+
+	return &bucket, object, true
+}
+
+// requestedCannedACL reads the ACL a PUT ?acl asks for: the x-amz-acl header, or an
+// AccessControlPolicy body whose grants match a canned ACL. Writes the S3 error response when
+// the request uses grants bkt can't represent
+func (h *S3APIHandler) requestedCannedACL(c *gin.Context, bucket *models.Bucket, resource string) (string, bool) {
+	for _, header := range aclGrantHeaders {
+		if c.GetHeader(header) != "" {
+			h.s3Error(c, "NotImplemented", "Grant headers are not supported; use a canned ACL (x-amz-acl: private or public-read)", resource, http.StatusNotImplemented)
+			return "", false
+		}
+	}
+
+	if canned := c.GetHeader("x-amz-acl"); canned != "" {
+		return strings.ToLower(strings.TrimSpace(canned)), true
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxACLPolicySize+1))
+	if err != nil {
+		h.s3Error(c, "IncompleteBody", "Failed to read request body", resource, http.StatusBadRequest)
+		return "", false
+	}
+	if len(body) == 0 {
+		h.s3Error(c, "MissingSecurityHeader", "An x-amz-acl header or an AccessControlPolicy body is required", resource, http.StatusBadRequest)
+		return "", false
+	}
+	if len(body) > maxACLPolicySize {
+		h.s3Error(c, "MalformedACLError", "The ACL document is too large", resource, http.StatusBadRequest)
+		return "", false
+	}
+
+	var policy AccessControlPolicy
+	if err := xml.Unmarshal(body, &policy); err != nil {
+		h.s3Error(c, "MalformedACLError", "The XML you provided was not well-formed", resource, http.StatusBadRequest)
+		return "", false
+	}
+
+	canned, err := cannedACLFromPolicy(&policy, bucket.OwnerID.String())
+	if err != nil {
+		h.s3Error(c, "NotImplemented", err.Error(), resource, http.StatusNotImplemented)
+		return "", false
+	}
+	return canned, true
+}
+
+// cannedACLFromPolicy maps an AccessControlPolicy onto a canned ACL. Only the bucket owner's
+// FULL_CONTROL and AllUsers READ grants can be expressed in bkt
+func cannedACLFromPolicy(policy *AccessControlPolicy, ownerID string) (string, error) {
+	canned := aclCannedPrivate
+	for _, grant := range policy.AccessControlList.Grant {
+		grantee := grant.Grantee
+		granteeType := grantee.Type
+		if granteeType == "" {
+			granteeType = grantee.XsiType
+		}
+
+		switch granteeType {
+		case aclGranteeCanonicalUser:
+			if grantee.ID != ownerID {
+				return "", fmt.Errorf("grants to users other than the bucket owner are not supported; use bucket or user policies")
+			}
+			if grant.Permission != aclPermissionFull {
+				return "", fmt.Errorf("the bucket owner's grant must be FULL_CONTROL")
+			}
+		case aclGranteeGroup:
+			if grantee.URI != aclAllUsersURI || grant.Permission != aclPermissionRead {
+				return "", fmt.Errorf("only READ for the AllUsers group is supported (canned ACL public-read)")
+			}
+			canned = aclCannedPublicRead
+		default:
+			return "", fmt.Errorf("grantee type %q is not supported (supported: CanonicalUser for the bucket owner, Group for AllUsers)", granteeType)
+		}
+	}
+	return canned, nil
+}
+
+// parseS3ObjectACL maps a canned ACL onto an object ACL. Objects have no public setting of their
+// own, so public-read is accepted only where inheriting makes the object public
+func parseS3ObjectACL(canned string, bucket *models.Bucket) (string, error) {
+	if strings.ToLower(strings.TrimSpace(canned)) == aclCannedPublicRead {
+		if !bucket.IsPublic {
+			return "", fmt.Errorf("public-read objects require a public-read bucket; bkt has no per-object public access")
+		}
+		return models.ObjectACLInherit, nil
+	}
+	return models.ParseObjectACL(canned)
+}
+
+// aclPolicy describes a bucket's or object's access as S3 grants
+func aclPolicy(bucket *models.Bucket, public bool) *AccessControlPolicy {
+	owner := Owner{ID: bucket.OwnerID.String()}
+	var user models.User
+	if err := database.DB.Select("username").Where("id = ?", bucket.OwnerID).First(&user).Error; err == nil {
+		owner.DisplayName = user.Username
+	}
+
+	policy := &AccessControlPolicy{
+		Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/",
+		Owner: owner,
+	}
+	policy.AccessControlList.Grant = append(policy.AccessControlList.Grant, ACLGrant{
+		Grantee: ACLGrantee{
+			XmlnsXsi:    xmlSchemaInstanceNS,
+			XsiType:     aclGranteeCanonicalUser,
+			ID:          owner.ID,
+			DisplayName: owner.DisplayName,
+		},
+		Permission: aclPermissionFull,
+	})
+	if public {
+		policy.AccessControlList.Grant = append(policy.AccessControlList.Grant, ACLGrant{
+			Grantee: ACLGrantee{
+				XmlnsXsi: xmlSchemaInstanceNS,
+				XsiType:  aclGranteeGroup,
+				URI:      aclAllUsersURI,
+			},
+			Permission: aclPermissionRead,
+		})
+	}
+	return policy
+}
@@ -3,11 +3,14 @@ package api
 import (
 	"bkt/internal/config"
 	"bkt/internal/database"
+	"bkt/internal/middleware"
 	"bkt/internal/models"
 	"bkt/internal/services"
+	"bkt/internal/storage"
 	"bkt/internal/validation"
 	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -17,6 +20,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // S3APIHandler handles S3-compatible API requests
@@ -26,6 +30,10 @@ type S3APIHandler struct {
 	bucketHandler *BucketHandler
 }
 
+// restErrorsKey marks a request served by the S3 handlers on an /api route (e.g. a presigned
+// upload), so s3Error answers with the REST error shape
+const restErrorsKey = "rest_errors"
+
 func NewS3APIHandler(cfg *config.Config) *S3APIHandler {
 	return &S3APIHandler{
 		config:        cfg,
@@ -52,8 +60,15 @@ type Buckets struct {
 }
 
 type BucketInfo struct {
-	Name         string    `xml:"Name"`
-	CreationDate time.Time `xml:"CreationDate"`
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"` // s3Timestamp format
+	BucketRegion string `xml:"BucketRegion,omitempty"`
+}
+
+// s3Timestamp formats a time the way S3 XML responses do: UTC with millisecond precision,
+// e.g. 2026-10-16T09:30:00.000Z
+func s3Timestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
 }
 
 type ListBucketResult struct {
@@ -80,14 +95,6 @@ type CommonPrefix struct {
 	Prefix string `xml:"Prefix"`
 }
 
-type Error struct {
-	XMLName   xml.Name `xml:"Error"`
-	Code      string   `xml:"Code"`
-	Message   string   `xml:"Message"`
-	Resource  string   `xml:"Resource,omitempty"`
-	RequestID string   `xml:"RequestId,omitempty"`
-}
-
 // ListBuckets handles GET / (list all buckets)
 func (h *S3APIHandler) ListBuckets(c *gin.Context) {
 	userID, _ := c.Get("user_id")
@@ -108,14 +115,15 @@ func (h *S3APIHandler) ListBuckets(c *gin.Context) {
 	} else {
 		// Batch check which buckets user can list
 		var err error
-		accessibleBuckets, err = h.policyService.FilterAccessibleBuckets(userUUID, allBuckets, services.ActionListBucket)
+		accessibleBuckets, err = h.policyService.FilterAccessibleBuckets(c, userUUID, allBuckets, services.ActionListBucket)
 		if err != nil {
 			h.s3Error(c, "InternalError", "Failed to check bucket permissions", "", http.StatusInternalServerError)
 			return
 		}
 	}
 
-	// Build XML response
+	// Build XML response. The schema has a single Owner for the whole list; like S3 it is the
+	// requesting account, even though here the list also covers buckets other users own
 	user, _ := c.Get("user")
 	userModel := user.(*models.User)
 
@@ -123,7 +131,8 @@ func (h *S3APIHandler) ListBuckets(c *gin.Context) {
 	for i, bucket := range accessibleBuckets {
 		bucketInfos[i] = BucketInfo{
 			Name:         bucket.Name,
-			CreationDate: bucket.CreatedAt,
+			CreationDate: s3Timestamp(bucket.CreatedAt),
+			BucketRegion: bucket.Region,
 		}
 	}
 
@@ -155,14 +164,14 @@ func (h *S3APIHandler) ListObjects(c *gin.Context) {
 	}
 
 	// Check permissions
-	allowed, _ := h.policyService.CheckBucketAccess(userUUID, bucketName, services.ActionListBucket)
+	allowed, _ := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionListBucket)
 	if !allowed {
 		h.s3Error(c, "AccessDenied", "Access Denied", bucketName, http.StatusForbidden)
 		return
 	}
 
 	// Parse query parameters
-	prefix := c.DefaultQuery("prefix", "")
+	prefix := bucket.NormalizeKey(c.DefaultQuery("prefix", ""))
 	delimiter := c.Query("delimiter")
 	maxKeys := 1000
 	if mk := c.Query("max-keys"); mk != "" {
@@ -251,6 +260,18 @@ func (h *S3APIHandler) GetObject(c *gin.Context) {
 		h.ListObjects(c)
 		return
 	}
+	if hasSubresource(c, "acl") {
+		h.GetObjectAcl(c)
+		return
+	}
+	if hasSubresource(c, "tagging") {
+		h.GetObjectTagging(c)
+		return
+	}
+	// A previous version; the current one is served below
+	if versionID := c.Query("versionId"); versionID != "" && h.GetObjectVersion(c, versionID) {
+		return
+	}
 
 	userID, _ := c.Get("user_id")
 	userUUID := userID.(uuid.UUID)
@@ -262,17 +283,21 @@ func (h *S3APIHandler) GetObject(c *gin.Context) {
 		return
 	}
 
-	// Check permissions
-	allowed, _ := h.policyService.CheckObjectAccess(userUUID, bucketName, objectKey, services.ActionGetObject)
-	if !allowed {
-		h.s3Error(c, "AccessDenied", "Access Denied", objectKey, http.StatusForbidden)
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Check permissions and get object metadata (denials are reported per OBJECT_DENIAL_MODE)
+	objectRecord, access, err := h.bucketHandler.resolveObjectAccess(c, userUUID, &bucket, objectKey, services.ActionGetObject)
+	if !h.respondObjectAccess(c, access, err, objectKey) {
 		return
 	}
+	object := *objectRecord
 
-	// Get object metadata
-	var object models.Object
-	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
-		h.s3Error(c, "NoSuchKey", "The specified key does not exist", objectKey, http.StatusNotFound)
+	// response-content-type / response-content-disposition overrides
+	// (covered by the SigV4 canonical query string, so clients can't alter them after signing)
+	contentTypeOverride, dispositionOverride, err := responseHeaderOverrides(c)
+	if err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
 		return
 	}
 
@@ -283,28 +308,92 @@ func (h *S3APIHandler) GetObject(c *gin.Context) {
 		return
 	}
 
+	// Range / If-Range (ranged GETs from multipart downloaders and resumed transfers)
+	objRange, err := requestedRange(c, object.Size, object.ETag, object.UpdatedAt)
+	if err != nil {
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", object.Size))
+		h.s3Error(c, "InvalidRange", "The requested range is not satisfiable", objectKey, http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	// Bound how many large downloads one user and one access key stream at once
+	length := object.Size
+	if objRange != nil {
+		length = objRange.length
+	}
+	releaseSlot, err := acquireDownloadSlot(c, &h.config.Storage, length)
+	if err != nil {
+		h.s3Error(c, "SlowDown", "Too many concurrent downloads: "+err.Error(), objectKey, http.StatusServiceUnavailable)
+		return
+	}
+	defer releaseSlot()
+
 	// Get object from storage
-	file, err := storageBackend.GetObject(bucketName, objectKey)
+	var file io.ReadCloser
+	if objRange != nil {
+		file, err = storageBackend.GetObjectRange(bucketName, objectKey, objRange.start, objRange.length)
+	} else {
+		file, err = storageBackend.GetObject(bucketName, objectKey)
+	}
 	if err != nil {
 		h.s3Error(c, "InternalError", "Failed to retrieve object", objectKey, http.StatusInternalServerError)
 		return
 	}
 	defer file.Close()
 
+	contentType := object.ContentType
+	if contentTypeOverride != "" {
+		contentType = contentTypeOverride
+	}
+
+	status := http.StatusOK
+	if objRange != nil {
+		status = http.StatusPartialContent
+		c.Header("Content-Range", objRange.contentRange(object.Size))
+	}
+
 	// Set S3-compatible headers
-	c.Header("Content-Type", object.ContentType)
-	c.Header("Content-Length", strconv.FormatInt(object.Size, 10))
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.FormatInt(length, 10))
 	c.Header("ETag", fmt.Sprintf(`"%s"`, object.ETag))
 	c.Header("Last-Modified", object.UpdatedAt.UTC().Format(http.TimeFormat))
 	c.Header("Accept-Ranges", "bytes")
 	c.Header("x-amz-request-id", uuid.New().String())
+	if object.VersionID != "" {
+		c.Header("x-amz-version-id", object.VersionID)
+	}
+	if disposition := applyObjectSecurityHeaders(c, contentType, dispositionOverride, objectKey); disposition != "" {
+		c.Header("Content-Disposition", disposition)
+	}
+	setObjectChecksumHeaders(c, &object, objRange != nil)
+	setObjectMetadataHeaders(c, &object)
+
+	// Feeds the caller's recent objects (batched, off the download path)
+	recordObjectAccess(userUUID, object.ID)
 
 	// Stream file
-	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, file, nil)
+	c.DataFromReader(status, length, contentType, file, nil)
 }
 
 // PutObject handles PUT /{bucket}/{key+} (upload object)
 func (h *S3APIHandler) PutObject(c *gin.Context) {
+	if hasSubresource(c, "acl") {
+		h.PutObjectAcl(c)
+		return
+	}
+	if hasSubresource(c, "tagging") {
+		h.PutObjectTagging(c)
+		return
+	}
+	if hasSubresource(c, "uploadId") {
+		h.UploadPart(c)
+		return
+	}
+	if c.GetHeader("x-amz-copy-source") != "" {
+		h.CopyObject(c)
+		return
+	}
+
 	bucketName := c.Param("bucket")
 	objectKey := strings.TrimPrefix(c.Param("key"), "/")
 	userID, _ := c.Get("user_id")
@@ -323,17 +412,75 @@ func (h *S3APIHandler) PutObject(c *gin.Context) {
 		return
 	}
 
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Auto-date-prefix buckets store uploads under the upload date; the key is returned in X-Bkt-Object-Key
+	objectKey, err := applyAutoDatePrefix(&bucket, objectKey)
+	if err != nil {
+		h.s3Error(c, "KeyTooLongError", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	// Governance key rules (e.g. only logs/*.gz), checked against the stored key
+	if err := bucket.CheckKeyRules(objectKey); err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
 	// Check permissions
-	allowed, _ := h.policyService.CheckObjectAccess(userUUID, bucketName, objectKey, services.ActionPutObject)
+	allowed, _ := h.policyService.CheckObjectAccess(c, userUUID, bucketName, objectKey, services.ActionPutObject)
 	if !allowed {
 		h.s3Error(c, "AccessDenied", "Access Denied", objectKey, http.StatusForbidden)
 		return
 	}
 
-	// Get content length
-	contentLength := c.Request.ContentLength
-	if contentLength < 0 {
-		h.s3Error(c, "MissingContentLength", "You must provide the Content-Length HTTP header", objectKey, http.StatusLengthRequired)
+	// Serialize concurrent writes to the same key so bytes and metadata always match (last writer wins)
+	unlockKey, err := lockObjectKey(bucket.ID, objectKey, objectKeyLockTimeout)
+	if err != nil {
+		h.s3Error(c, "OperationAborted", err.Error(), objectKey, http.StatusConflict)
+		return
+	}
+	defer unlockKey()
+
+	// Recently created objects may be protected from overwrites
+	if err := checkOverwriteWindow(c, &bucket, objectKey); err != nil {
+		h.s3Error(c, "OperationAborted", err.Error(), objectKey, http.StatusConflict)
+		return
+	}
+
+	// New keys can't push the bucket past its object count cap
+	if err := checkObjectLimit(&bucket, objectKey); err != nil {
+		if errors.Is(err, errObjectLimit) {
+			h.s3Error(c, "QuotaExceeded", err.Error(), objectKey, http.StatusForbidden)
+		} else {
+			h.s3Error(c, "InternalError", "Failed to check bucket object limit", objectKey, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Canned ACL header (only ACLs that map onto bkt's object ACL are accepted)
+	acl, err := parseS3ObjectACL(c.GetHeader("x-amz-acl"), &bucket)
+	if err != nil {
+		h.s3Error(c, "NotImplemented", err.Error(), objectKey, http.StatusNotImplemented)
+		return
+	}
+
+	// Optional per-object TTL
+	expiresAt, err := parseObjectExpiry(c.GetHeader("X-Expires-After"), "")
+	if err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	// User metadata (x-amz-meta-* headers), replacing any the object had
+	metadata, ok := h.requestMetadata(c, objectKey)
+	if !ok {
+		return
+	}
+
+	body, contentLength, chunked, verifier, ok := h.uploadBody(c, objectKey)
+	if !ok {
 		return
 	}
 
@@ -344,11 +491,16 @@ func (h *S3APIHandler) PutObject(c *gin.Context) {
 	}
 
 	// Detect actual content type from file magic numbers (don't trust client)
-	detectedType, firstBytes, err := validation.DetectContentType(c.Request.Body)
+	detectedType, firstBytes, err := validation.DetectContentType(body)
 	if err != nil {
+		if code, message, ok := s3UploadBodyError(verifier, chunked); ok {
+			h.s3Error(c, code, message, objectKey, http.StatusBadRequest)
+			return
+		}
 		h.s3Error(c, "InternalError", "Failed to detect content type", objectKey, http.StatusInternalServerError)
 		return
 	}
+	detectedType = validation.RefineContentType(detectedType, firstBytes, objectKey, h.config.Storage.ExtensionContentTypes)
 
 	// Validate content type is safe
 	if !validation.IsSafeContentType(detectedType) {
@@ -356,11 +508,23 @@ func (h *S3APIHandler) PutObject(c *gin.Context) {
 		return
 	}
 
-	// Use detected content type (from magic numbers, not from client header)
-	contentType := detectedType
+	// Use detected content type (from magic numbers), unless the client declared a trusted type
+	contentType := validation.ResolveContentType(detectedType, c.GetHeader("Content-Type"), h.config.Storage.TrustedContentTypes)
+
+	if err := h.bucketHandler.checkContentTypeSize(contentLength, detectedType, contentType); err != nil {
+		h.s3Error(c, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed size for its type: "+err.Error(), objectKey, http.StatusRequestEntityTooLarge)
+		return
+	}
 
 	// Create MultiReader to prepend the first bytes back to the stream
-	combinedReader := io.MultiReader(bytes.NewReader(firstBytes), c.Request.Body)
+	combinedReader := io.MultiReader(bytes.NewReader(firstBytes), body)
+
+	// Bound how far gzip content may expand when decompressed (decompression bomb guard)
+	if validation.IsGzipContentType(detectedType) {
+		guard := validation.NewGzipGuardReader(combinedReader, h.bucketHandler.decompressionLimit(contentLength))
+		defer guard.Close()
+		combinedReader = guard
+	}
 
 	// Get storage backend
 	storageBackend, err := h.bucketHandler.getStorageBackend(&bucket)
@@ -369,9 +533,44 @@ func (h *S3APIHandler) PutObject(c *gin.Context) {
 		return
 	}
 
+	// Fail fast when the backend can't hold the object
+	if err := storage.CheckSpace(storageBackend, bucketName, contentLength); err != nil {
+		logInsufficientStorage(bucketName, objectKey, err)
+		h.s3Error(c, "InsufficientStorage", insufficientStorageMessage, objectKey, http.StatusInsufficientStorage)
+		return
+	}
+
+	// Versioned buckets keep the content being replaced as a previous version, which is put
+	// back as the live object if the write or the metadata save fails
+	previous, err := archiveCurrentVersion(storageBackend, &bucket, objectKey)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to keep the previous version", objectKey, http.StatusInternalServerError)
+		return
+	}
+	stored := false
+	defer func() {
+		if previous != nil && !stored {
+			restoreArchivedVersion(storageBackend, bucketName, previous)
+		}
+	}()
+
 	// Save object (use combinedReader that includes first 512 bytes)
+	// A failed or rejected write leaves the key as it was (PutObject never stores partial content)
 	err = storageBackend.PutObject(bucketName, objectKey, combinedReader, contentLength, contentType)
 	if err != nil {
+		if errors.Is(err, validation.ErrDecompressionLimit) {
+			h.s3Error(c, "EntityTooLarge", "Compressed content expands beyond the allowed decompression limit", objectKey, http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, storage.ErrInsufficientStorage) {
+			logInsufficientStorage(bucketName, objectKey, err)
+			h.s3Error(c, "InsufficientStorage", insufficientStorageMessage, objectKey, http.StatusInsufficientStorage)
+			return
+		}
+		if code, message, ok := s3UploadBodyError(verifier, chunked); ok {
+			h.s3Error(c, code, message, objectKey, http.StatusBadRequest)
+			return
+		}
 		h.s3Error(c, "InternalError", "Failed to save object", objectKey, http.StatusInternalServerError)
 		return
 	}
@@ -383,19 +582,35 @@ func (h *S3APIHandler) PutObject(c *gin.Context) {
 		return
 	}
 
-	// Create or update object metadata in database
+	// Verified checksum to store with the object (cleared when the upload didn't send one)
+	checksumAlgorithm, checksumValue := "", ""
+	if verifier != nil {
+		checksumAlgorithm, checksumValue = verifier.checksum.algorithm, verifier.computed
+	}
+
+	// Create or update object metadata in database. The stored content is removed if the metadata
+	// write doesn't commit (unless it replaced an object)
 	var object models.Object
-	result := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object)
-
-	if result.Error == nil {
-		// Update existing object
-		object.Size = objectInfo.Size
-		object.ContentType = objectInfo.ContentType
-		object.ETag = objectInfo.ETag
-		object.StoragePath = objectKey
-		object.UpdatedAt = time.Now()
-		database.DB.Save(&object)
-	} else {
+	err = runStorageTxn(func(tx *gorm.DB, st *storageTxn) error {
+		st.onRollback(func() { discardUnsavedObject(storageBackend, bucket.ID, bucketName, objectKey) })
+
+		if tx.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error == nil {
+			// Update existing object
+			object.Size = objectInfo.Size
+			object.ContentType = objectInfo.ContentType
+			object.ETag = objectInfo.ETag
+			object.ChecksumAlgorithm = checksumAlgorithm
+			object.Checksum = checksumValue
+			object.StoragePath = objectKey
+			object.ACL = acl
+			object.UploadedBy = &userUUID
+			object.ExpiresAt = expiresAt
+			object.Metadata = metadata
+			object.VersionID = newVersionID(&bucket)
+			object.UpdatedAt = time.Now()
+			return tx.Save(&object).Error
+		}
+
 		// Create new object
 		object = models.Object{
 			BucketID:    bucket.ID,
@@ -404,22 +619,91 @@ func (h *S3APIHandler) PutObject(c *gin.Context) {
 			ContentType: objectInfo.ContentType,
 			ETag:        objectInfo.ETag,
 			StoragePath: objectKey,
+			ACL:         acl,
+			UploadedBy:  &userUUID,
+			ExpiresAt:   expiresAt,
+			Metadata:    metadata,
+			VersionID:   newVersionID(&bucket),
+
+			ChecksumAlgorithm: checksumAlgorithm,
+			Checksum:          checksumValue,
 		}
-		if err := database.DB.Create(&object).Error; err != nil {
-			storageBackend.DeleteObject(bucketName, objectKey)
-			h.s3Error(c, "InternalError", "Failed to create object metadata", objectKey, http.StatusInternalServerError)
-			return
-		}
+		return tx.Create(&object).Error
+	})
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to save object metadata", objectKey, http.StatusInternalServerError)
+		return
 	}
+	stored = true
 
-	// Return success with ETag
+	// Return success with ETag (and the verified checksum, as S3 does)
 	c.Header("ETag", fmt.Sprintf(`"%s"`, object.ETag))
+	if checksumValue != "" {
+		c.Header(s3ChecksumHeader(checksumAlgorithm), checksumValue)
+	}
+	if bucket.AutoDatePrefix != "" {
+		c.Header("X-Bkt-Object-Key", objectKey)
+	}
+	if object.VersionID != "" {
+		c.Header("x-amz-version-id", object.VersionID)
+	}
 	c.Header("x-amz-request-id", uuid.New().String())
 	c.Status(http.StatusOK)
 }
 
+// uploadBody returns the data of an S3 PUT and its length. SDK streaming uploads wrap the data in
+// aws-chunked framing, which is decoded, and a declared x-amz-checksum-* value is verified while the
+// body streams to storage. Writes the S3 error and returns ok=false for an unusable request
+func (h *S3APIHandler) uploadBody(c *gin.Context, objectKey string) (body io.Reader, contentLength int64, chunked *awsChunkedReader, verifier *s3ChecksumReader, ok bool) {
+	contentLength = c.Request.ContentLength
+	body = c.Request.Body
+
+	if isAWSChunkedUpload(c) {
+		decodedLength, err := strconv.ParseInt(c.GetHeader("x-amz-decoded-content-length"), 10, 64)
+		if err != nil || decodedLength < 0 {
+			h.s3Error(c, "MissingContentLength", "You must provide the x-amz-decoded-content-length header with an aws-chunked body", objectKey, http.StatusLengthRequired)
+			return nil, 0, nil, nil, false
+		}
+		contentLength = decodedLength
+		chunked = newAWSChunkedReader(c.Request.Body, decodedLength)
+		body = chunked
+	}
+
+	if contentLength < 0 {
+		h.s3Error(c, "MissingContentLength", "You must provide the Content-Length HTTP header", objectKey, http.StatusLengthRequired)
+		return nil, 0, nil, nil, false
+	}
+
+	if h.config.Storage.S3ChecksumValidation {
+		checksum, err := parseS3UploadChecksum(c, chunked != nil)
+		if err != nil {
+			h.s3Error(c, "InvalidRequest", err.Error(), objectKey, http.StatusBadRequest)
+			return nil, 0, nil, nil, false
+		}
+		if checksum != nil {
+			verifier = newS3ChecksumReader(body, checksum, chunked)
+			body = verifier
+		}
+	}
+
+	return body, contentLength, chunked, verifier, true
+}
+
 // DeleteObject handles DELETE /{bucket}/{key+} (delete object)
 func (h *S3APIHandler) DeleteObject(c *gin.Context) {
+	if hasSubresource(c, "uploadId") {
+		h.AbortMultipartUpload(c)
+		return
+	}
+	if hasSubresource(c, "tagging") {
+		h.DeleteObjectTagging(c)
+		return
+	}
+	if hasSubresource(c, "versionId") {
+		h.DeleteObjectVersion(c)
+		return
+	}
+
 	bucketName := c.Param("bucket")
 	objectKey := strings.TrimPrefix(c.Param("key"), "/")
 	userID, _ := c.Get("user_id")
@@ -433,20 +717,20 @@ func (h *S3APIHandler) DeleteObject(c *gin.Context) {
 		return
 	}
 
-	// Check permissions
-	allowed, _ := h.policyService.CheckObjectAccess(userUUID, bucketName, objectKey, services.ActionDeleteObject)
-	if !allowed {
-		h.s3Error(c, "AccessDenied", "Access Denied", objectKey, http.StatusForbidden)
-		return
-	}
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
 
-	// Get object metadata
-	var object models.Object
-	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
+	// Check permissions and get object metadata (denials are reported per OBJECT_DENIAL_MODE)
+	objectRecord, access, err := h.bucketHandler.resolveObjectAccess(c, userUUID, &bucket, objectKey, services.ActionDeleteObject)
+	if access == objectAccessMissing && err == nil {
 		// S3 returns 204 even if object doesn't exist
 		c.Status(http.StatusNoContent)
 		return
 	}
+	if !h.respondObjectAccess(c, access, err, objectKey) {
+		return
+	}
+	object := *objectRecord
 
 	// Get storage backend
 	storageBackend, err := h.bucketHandler.getStorageBackend(&bucket)
@@ -455,8 +739,18 @@ func (h *S3APIHandler) DeleteObject(c *gin.Context) {
 		return
 	}
 
+	// Versioned buckets keep the deleted content as a previous version
+	previous, err := archiveObjectVersion(storageBackend, &bucket, &object)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to keep the previous version", objectKey, http.StatusInternalServerError)
+		return
+	}
+
 	// Delete from storage first - MUST succeed before database delete (prevents inconsistency)
 	if err := storageBackend.DeleteObject(bucketName, objectKey); err != nil {
+		if previous != nil {
+			discardObjectVersion(storageBackend, bucketName, previous)
+		}
 		h.s3Error(c, "InternalError", "Failed to delete object from storage", objectKey, http.StatusInternalServerError)
 		return
 	}
@@ -468,6 +762,17 @@ func (h *S3APIHandler) DeleteObject(c *gin.Context) {
 		return
 	}
 
+	// The deletion itself becomes the key's latest version
+	marker, err := createDeleteMarker(&bucket, objectKey)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to create delete marker", objectKey, http.StatusInternalServerError)
+		return
+	}
+	if marker != nil {
+		c.Header("x-amz-delete-marker", "true")
+		c.Header("x-amz-version-id", marker.VersionID)
+	}
+
 	c.Header("x-amz-request-id", uuid.New().String())
 	c.Status(http.StatusNoContent)
 }
@@ -486,20 +791,26 @@ func (h *S3APIHandler) HeadObject(c *gin.Context) {
 		return
 	}
 
-	// Check permissions
-	allowed, _ := h.policyService.CheckObjectAccess(userUUID, bucketName, objectKey, services.ActionGetObject)
-	if !allowed {
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Check permissions and get object metadata (denials are reported per OBJECT_DENIAL_MODE)
+	object, access, err := h.bucketHandler.resolveObjectAccess(c, userUUID, &bucket, objectKey, services.ActionGetObject)
+	switch {
+	case err != nil:
+		c.Status(http.StatusInternalServerError)
+		return
+	case access == objectAccessDenied:
 		c.Status(http.StatusForbidden)
 		return
+	case access == objectAccessNotFound:
+		c.Status(http.StatusNotFound)
+		return
 	}
 
-	// Get object metadata
-	var object models.Object
-	err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error
-
 	// If exact match not found and key ends with /, it might be a folder
 	// Check if any objects exist with this prefix
-	if err != nil && strings.HasSuffix(objectKey, "/") {
+	if access == objectAccessMissing && strings.HasSuffix(objectKey, "/") {
 		var count int64
 		database.DB.Model(&models.Object{}).Where("bucket_id = ? AND key LIKE ?", bucket.ID, objectKey+"%").Count(&count)
 		if count > 0 {
@@ -513,7 +824,7 @@ func (h *S3APIHandler) HeadObject(c *gin.Context) {
 		}
 	}
 
-	if err != nil {
+	if access == objectAccessMissing {
 		c.Status(http.StatusNotFound)
 		return
 	}
@@ -525,6 +836,12 @@ func (h *S3APIHandler) HeadObject(c *gin.Context) {
 	c.Header("Last-Modified", object.UpdatedAt.UTC().Format(http.TimeFormat))
 	c.Header("Accept-Ranges", "bytes")
 	c.Header("x-amz-request-id", uuid.New().String())
+	if object.VersionID != "" {
+		c.Header("x-amz-version-id", object.VersionID)
+	}
+	setObjectExpiryHeaders(c, object)
+	setObjectChecksumHeaders(c, object, false)
+	setObjectMetadataHeaders(c, object)
 
 	c.Status(http.StatusOK)
 }
@@ -543,19 +860,47 @@ func (h *S3APIHandler) HeadBucket(c *gin.Context) {
 	}
 
 	// Check permissions
-	allowed, _ := h.policyService.CheckBucketAccess(userUUID, bucketName, services.ActionListBucket)
+	allowed, _ := h.policyService.CheckBucketAccess(c, userUUID, bucketName, services.ActionListBucket)
 	if !allowed {
 		c.Status(http.StatusForbidden)
 		return
 	}
 
 	c.Header("x-amz-request-id", uuid.New().String())
+	setBucketSummaryHeaders(c, &bucket)
 	c.Status(http.StatusOK)
 }
 
+// respondObjectAccess writes the S3 error for a resolveObjectAccess outcome other than
+// objectAccessOK. Returns true when the request may proceed
+func (h *S3APIHandler) respondObjectAccess(c *gin.Context, access objectAccess, err error, objectKey string) bool {
+	switch {
+	case err != nil:
+		h.s3Error(c, "InternalError", "Failed to check object access", objectKey, http.StatusInternalServerError)
+		return false
+	case access == objectAccessDenied:
+		h.s3Error(c, "AccessDenied", "Access Denied", objectKey, http.StatusForbidden)
+		return false
+	case access != objectAccessOK:
+		h.s3Error(c, "NoSuchKey", "The specified key does not exist", objectKey, http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
 // s3Error sends an S3-compatible XML error response
 func (h *S3APIHandler) s3Error(c *gin.Context, code, message, resource string, status int) {
-	errorResponse := Error{
+	// Requests on /api routes, and clients that ask for JSON (Accept: application/json), get the
+	// REST error shape
+	if c.GetBool(restErrorsKey) || h.config.Server.ErrorNegotiation && !middleware.PrefersXML(c, true) {
+		c.JSON(status, models.ErrorResponse{
+			Error:   code,
+			Message: message,
+		})
+		return
+	}
+
+	errorResponse := middleware.S3Error{
 		Code:      code,
 		Message:   message,
 		Resource:  resource,
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Bucket configuration subresources (?location, ?encryption, ?object-lock; ?website is in s3_website.go,
+// ?acl in s3_acl.go and ?versioning in s3_versioning.go). bkt has no server-side encryption or
+// object lock settings, so reads answer the way S3 does for a bucket where the feature was
+// never configured, and writes are NotImplemented. IaC tools (e.g. Terraform) treat those
+// responses as "feature off" instead of failing on a ListBucketResult
+
+// hasSubresource reports whether a bare subresource flag (e.g. "?encryption") is present
+func hasSubresource(c *gin.Context, names ...string) bool {
+	for _, name := range names {
+		if _, ok := c.GetQuery(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBucket dispatches GET /{bucket}: configuration subresources first, otherwise ListObjects
+func (h *S3APIHandler) GetBucket(c *gin.Context) {
+	switch {
+	case hasSubresource(c, "location"):
+		h.GetBucketLocation(c)
+	case hasSubresource(c, "encryption"):
+		h.GetBucketEncryption(c)
+	case hasSubresource(c, "object-lock", "object-lock-configuration"):
+		h.GetObjectLockConfiguration(c)
+	case hasSubresource(c, "website"):
+		h.GetBucketWebsite(c)
+	case hasSubresource(c, "acl"):
+		h.GetBucketAcl(c)
+	case hasSubresource(c, "versioning"):
+		h.GetBucketVersioning(c)
+	case hasSubresource(c, "versions"):
+		h.ListObjectVersions(c)
+	default:
+		h.ListObjects(c)
+	}
+}
+
+// PutBucket dispatches PUT /{bucket}: configuration subresources first, otherwise CreateBucket
+func (h *S3APIHandler) PutBucket(c *gin.Context) {
+	switch {
+	case hasSubresource(c, "encryption"):
+		h.putUnsupportedBucketConfig(c, "Server-side encryption configuration is not supported")
+	case hasSubresource(c, "object-lock", "object-lock-configuration"):
+		h.putUnsupportedBucketConfig(c, "Object lock configuration is not supported")
+	case hasSubresource(c, "website"):
+		h.PutBucketWebsite(c)
+	case hasSubresource(c, "acl"):
+		h.PutBucketAcl(c)
+	case hasSubresource(c, "versioning"):
+		h.PutBucketVersioning(c)
+	default:
+		h.CreateBucket(c)
+	}
+}
+
+// DeleteBucket dispatches DELETE /{bucket}: configuration subresources. Deleting the bucket
+// itself isn't available over the S3 API (like creating one)
+func (h *S3APIHandler) DeleteBucket(c *gin.Context) {
+	switch {
+	case hasSubresource(c, "website"):
+		h.DeleteBucketWebsite(c)
+	default:
+		h.s3Error(c, "AccessDenied", "Bucket deletion via S3 API is not supported. Use web UI.", c.Param("bucket"), http.StatusForbidden)
+	}
+}
+
+// PostBucket dispatches POST /{bucket}: ?delete deletes objects in bulk
+func (h *S3APIHandler) PostBucket(c *gin.Context) {
+	switch {
+	case hasSubresource(c, "delete"):
+		h.DeleteObjects(c)
+	default:
+		h.s3Error(c, "NotImplemented", "A header or query you provided implies functionality that is not implemented", c.Param("bucket"), http.StatusNotImplemented)
+	}
+}
+
+// LocationConstraint is the S3 ?location document
+type LocationConstraint struct {
+	XMLName xml.Name `xml:"LocationConstraint"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Region  string   `xml:",chardata"`
+}
+
+// GetBucketLocation handles GET /{bucket}?location. SDKs call it to find the bucket's region
+// before signing other requests. As on S3, us-east-1 is reported as an empty constraint
+func (h *S3APIHandler) GetBucketLocation(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	bucket, ok := h.loadBucketForConfig(c, bucketName, services.ActionGetBucketLocation)
+	if !ok {
+		return
+	}
+
+	location := LocationConstraint{Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/"}
+	if bucket.Region != "us-east-1" {
+		location.Region = bucket.Region
+	}
+
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.XML(http.StatusOK, location)
+}
+
+// GetBucketEncryption handles GET /{bucket}?encryption
+func (h *S3APIHandler) GetBucketEncryption(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	if !h.checkBucketConfigAccess(c, bucketName) {
+		return
+	}
+
+	h.s3Error(c, "ServerSideEncryptionConfigurationNotFoundError",
+		"The server side encryption configuration was not found", bucketName, http.StatusNotFound)
+}
+
+// GetObjectLockConfiguration handles GET /{bucket}?object-lock
+func (h *S3APIHandler) GetObjectLockConfiguration(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	if !h.checkBucketConfigAccess(c, bucketName) {
+		return
+	}
+
+	h.s3Error(c, "ObjectLockConfigurationNotFoundError",
+		"Object Lock configuration does not exist for this bucket", bucketName, http.StatusNotFound)
+}
+
+func (h *S3APIHandler) putUnsupportedBucketConfig(c *gin.Context, message string) {
+	bucketName := c.Param("bucket")
+	if !h.checkBucketConfigAccess(c, bucketName) {
+		return
+	}
+
+	h.s3Error(c, "NotImplemented", message, bucketName, http.StatusNotImplemented)
+}
+
+// checkBucketConfigAccess verifies the bucket exists and the caller may list it,
+// writing the S3 error response otherwise
+func (h *S3APIHandler) checkBucketConfigAccess(c *gin.Context, bucketName string) bool {
+	_, ok := h.loadBucketForConfig(c, bucketName, services.ActionListBucket)
+	return ok
+}
+
+// loadBucketForConfig loads the bucket and verifies the caller may perform action on it,
+// writing the S3 error response otherwise
+func (h *S3APIHandler) loadBucketForConfig(c *gin.Context, bucketName, action string) (*models.Bucket, bool) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		h.s3Error(c, "NoSuchBucket", "The specified bucket does not exist", bucketName, http.StatusNotFound)
+		return nil, false
+	}
+
+	allowed, _ := h.policyService.CheckBucketAccess(c, userUUID, bucketName, action)
+	if !allowed {
+		h.s3Error(c, "AccessDenied", "Access Denied", bucketName, http.StatusForbidden)
+		return nil, false
+	}
+
+	return &bucket, true
+}
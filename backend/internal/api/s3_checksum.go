@@ -0,0 +1,331 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errS3ChecksumMismatch  = errors.New("checksum does not match the uploaded data")
+	errAWSChunkedMalformed = errors.New("malformed aws-chunked body")
+)
+
+// crc64NVMETable is the reflected CRC-64/NVME polynomial used by S3's CRC64NVME checksums
+var crc64NVMETable = crc64.MakeTable(0x9a6c9329ac4bc9b5)
+
+// s3ChecksumAlgorithms are the S3 additional checksum algorithms accepted on PUT
+var s3ChecksumAlgorithms = map[string]func() hash.Hash{
+	"CRC32":     func() hash.Hash { return crc32.NewIEEE() },
+	"CRC32C":    func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) },
+	"CRC64NVME": func() hash.Hash { return crc64.New(crc64NVMETable) },
+	"SHA1":      sha1.New,
+	"SHA256":    sha256.New,
+}
+
+// s3ChecksumHeader returns the header (or trailer) carrying an algorithm's checksum, e.g. x-amz-checksum-crc32
+func s3ChecksumHeader(algorithm string) string {
+	return "x-amz-checksum-" + strings.ToLower(algorithm)
+}
+
+// s3UploadChecksum is the checksum an S3 PUT declared, either as a header or as an aws-chunked trailer
+type s3UploadChecksum struct {
+	algorithm string
+	expected  string // Base64; empty when it arrives in the trailer
+	trailer   bool
+}
+
+// parseS3UploadChecksum reads the declared checksum from x-amz-sdk-checksum-algorithm, the
+// x-amz-checksum-* headers and x-amz-trailer. Returns nil when the request carries none
+func parseS3UploadChecksum(c *gin.Context, chunked bool) (*s3UploadChecksum, error) {
+	var checksum *s3UploadChecksum
+	for algorithm := range s3ChecksumAlgorithms {
+		value := c.GetHeader(s3ChecksumHeader(algorithm))
+		if value == "" {
+			continue
+		}
+		if checksum != nil {
+			return nil, errors.New("Expecting a single x-amz-checksum- header. Multiple checksum types are not allowed.")
+		}
+		if err := validateS3ChecksumValue(algorithm, value); err != nil {
+			return nil, err
+		}
+		checksum = &s3UploadChecksum{algorithm: algorithm, expected: value}
+	}
+
+	if trailer := strings.TrimSpace(c.GetHeader("x-amz-trailer")); trailer != "" {
+		if checksum != nil {
+			return nil, errors.New("A checksum can't be sent both as a header and in x-amz-trailer")
+		}
+		if !chunked {
+			return nil, errors.New("x-amz-trailer requires an aws-chunked request body")
+		}
+		for algorithm := range s3ChecksumAlgorithms {
+			if strings.EqualFold(trailer, s3ChecksumHeader(algorithm)) {
+				checksum = &s3UploadChecksum{algorithm: algorithm, trailer: true}
+			}
+		}
+		if checksum == nil {
+			return nil, errors.New("The value specified in the x-amz-trailer header is not supported")
+		}
+	}
+
+	if declared := strings.ToUpper(c.GetHeader("x-amz-sdk-checksum-algorithm")); declared != "" {
+		if _, ok := s3ChecksumAlgorithms[declared]; !ok {
+			return nil, fmt.Errorf("Checksum algorithm %s is not supported", declared)
+		}
+		if checksum == nil {
+			return nil, errors.New("x-amz-sdk-checksum-algorithm specified, but no corresponding x-amz-checksum-* or x-amz-trailer headers were found.")
+		}
+		if checksum.algorithm != declared {
+			return nil, errors.New("Value for x-amz-sdk-checksum-algorithm header is invalid.")
+		}
+	}
+
+	return checksum, nil
+}
+
+// validateS3ChecksumValue checks that a checksum is base64 of the algorithm's digest size
+func validateS3ChecksumValue(algorithm, value string) error {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(decoded) != s3ChecksumAlgorithms[algorithm]().Size() {
+		return fmt.Errorf("Value for %s header is invalid.", s3ChecksumHeader(algorithm))
+	}
+	return nil
+}
+
+// s3ChecksumReader hashes an upload as it is read. At the end of the body it fails with
+// errS3ChecksumMismatch instead of returning io.EOF when the data doesn't match, so the
+// storage write is aborted rather than completed with bad data
+type s3ChecksumReader struct {
+	r        io.Reader
+	checksum *s3UploadChecksum
+	chunked  *awsChunkedReader // Source of the trailer; nil for header checksums
+	hash     hash.Hash
+	computed string // Base64, set once the body has been read and verified
+	err      error  // Verification failure
+}
+
+func newS3ChecksumReader(r io.Reader, checksum *s3UploadChecksum, chunked *awsChunkedReader) *s3ChecksumReader {
+	return &s3ChecksumReader{
+		r:        r,
+		checksum: checksum,
+		chunked:  chunked,
+		hash:     s3ChecksumAlgorithms[checksum.algorithm](),
+	}
+}
+
+func (r *s3ChecksumReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if r.err = r.verify(); r.err != nil {
+			return n, r.err
+		}
+	}
+	return n, err
+}
+
+// verify compares the computed checksum with the declared one (from the trailer if it was deferred)
+func (r *s3ChecksumReader) verify() error {
+	expected := r.checksum.expected
+	if r.checksum.trailer {
+		header := s3ChecksumHeader(r.checksum.algorithm)
+		expected = r.chunked.trailer.Get(header)
+		if expected == "" {
+			return fmt.Errorf("The %s trailer declared in x-amz-trailer was not sent", header)
+		}
+		if err := validateS3ChecksumValue(r.checksum.algorithm, expected); err != nil {
+			return err
+		}
+	}
+
+	computed := base64.StdEncoding.EncodeToString(r.hash.Sum(nil))
+	if computed != expected {
+		return errS3ChecksumMismatch
+	}
+	r.computed = computed
+	return nil
+}
+
+// isAWSChunkedUpload reports whether the request body uses aws-chunked framing (SDK streaming uploads)
+func isAWSChunkedUpload(c *gin.Context) bool {
+	return strings.HasPrefix(c.GetHeader("X-Amz-Content-Sha256"), "STREAMING-") ||
+		strings.Contains(c.GetHeader("Content-Encoding"), "aws-chunked")
+}
+
+// awsChunkedReader decodes an aws-chunked body into the object data and collects its trailing headers.
+// Chunk and trailer signatures are not verified (payload hashes aren't checked for plain bodies either);
+// declared checksums are what protect the data
+type awsChunkedReader struct {
+	r         *bufio.Reader
+	expected  int64 // x-amz-decoded-content-length
+	decoded   int64
+	remaining int64 // Data bytes left in the current chunk
+	trailer   http.Header
+	err       error // Sticky; io.EOF once the final chunk and trailer were read
+}
+
+func newAWSChunkedReader(r io.Reader, decodedLength int64) *awsChunkedReader {
+	return &awsChunkedReader{
+		r:        bufio.NewReaderSize(r, 4096),
+		expected: decodedLength,
+		trailer:  http.Header{},
+	}
+}
+
+func (r *awsChunkedReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.remaining == 0 {
+		if r.err = r.nextChunk(); r.err != nil {
+			return 0, r.err
+		}
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+	r.decoded += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil && r.remaining == 0 {
+		// Chunk data is followed by CRLF
+		if line, lineErr := r.readLine(); lineErr == io.EOF {
+			err = io.ErrUnexpectedEOF
+		} else if lineErr != nil {
+			err = lineErr
+		} else if line != "" {
+			err = errAWSChunkedMalformed
+		}
+	}
+	r.err = err
+	return n, err
+}
+
+// nextChunk reads the next chunk header. The zero-length final chunk is followed by the trailer
+func (r *awsChunkedReader) nextChunk() error {
+	line, err := r.readLine()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	// "<hex size>[;chunk-signature=<signature>]"
+	sizeHex, _, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeHex), 16, 64)
+	if err != nil || size < 0 || r.decoded+size > r.expected {
+		return errAWSChunkedMalformed
+	}
+	if size > 0 {
+		r.remaining = size
+		return nil
+	}
+
+	if r.decoded != r.expected {
+		return fmt.Errorf("%w: %d bytes received, x-amz-decoded-content-length is %d", errAWSChunkedMalformed, r.decoded, r.expected)
+	}
+	for {
+		line, err := r.readLine()
+		if err == io.EOF || (err == nil && line == "") {
+			return io.EOF
+		}
+		if err != nil {
+			return err
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return errAWSChunkedMalformed
+		}
+		r.trailer.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+}
+
+// readLine reads one CRLF-terminated line without its terminator. Returns io.EOF only when the
+// body ends cleanly before the line starts
+func (r *awsChunkedReader) readLine() (string, error) {
+	line, err := r.r.ReadSlice('\n')
+	switch {
+	case err == bufio.ErrBufferFull:
+		return "", errAWSChunkedMalformed
+	case err == io.EOF && len(line) == 0:
+		return "", io.EOF
+	case err == io.EOF:
+		return "", io.ErrUnexpectedEOF
+	case err != nil:
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
+
+// s3UploadBodyError returns the S3 error for an upload that failed because of its body: a checksum
+// mismatch or broken aws-chunked framing. Storage clients may wrap or replace the reader's error,
+// so the readers' own state decides. ok is false for any other failure
+func s3UploadBodyError(verifier *s3ChecksumReader, chunked *awsChunkedReader) (code, message string, ok bool) {
+	if verifier != nil && verifier.err != nil {
+		if errors.Is(verifier.err, errS3ChecksumMismatch) {
+			return "BadDigest", fmt.Sprintf("The %s you specified did not match the calculated checksum.", verifier.checksum.algorithm), true
+		}
+		return "InvalidRequest", verifier.err.Error(), true
+	}
+	if chunked != nil && chunked.err != nil && chunked.err != io.EOF {
+		return "IncompleteBody", "The aws-chunked request body is malformed or incomplete: " + chunked.err.Error(), true
+	}
+	return "", "", false
+}
+
+// setObjectChecksumHeaders returns an object's stored checksum to clients that ask for it with
+// x-amz-checksum-mode: ENABLED. Objects uploaded without an additional checksum report their
+// content SHA256 instead, when it is known. It covers the whole object, so ranged responses leave it out
+func setObjectChecksumHeaders(c *gin.Context, object *models.Object, ranged bool) {
+	if ranged || !strings.EqualFold(c.GetHeader("x-amz-checksum-mode"), "ENABLED") {
+		return
+	}
+	switch {
+	case object.Checksum != "":
+		c.Header(s3ChecksumHeader(object.ChecksumAlgorithm), object.Checksum)
+	case object.SHA256 != "":
+		sum, err := hex.DecodeString(object.SHA256)
+		if err != nil || len(sum) != sha256.Size {
+			return
+		}
+		c.Header(s3ChecksumHeader("SHA256"), base64.StdEncoding.EncodeToString(sum))
+	default:
+		return
+	}
+	c.Header("x-amz-checksum-type", "FULL_OBJECT")
+}
+
+// setContentSHA256Header sends an object's stored SHA256 (hex) as X-Content-SHA256 so REST
+// clients can verify a download without a separate HEAD. Objects whose hash isn't known yet,
+// and ranged responses, get no header
+func setContentSHA256Header(c *gin.Context, object *models.Object, ranged bool) {
+	if object.SHA256 == "" || ranged {
+		return
+	}
+	c.Header("X-Content-SHA256", object.SHA256)
+}
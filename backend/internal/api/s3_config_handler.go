@@ -6,6 +6,7 @@ import (
 	"bkt/internal/database"
 	"bkt/internal/models"
 	"bkt/internal/security"
+	"bkt/internal/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -72,6 +73,17 @@ func (h *S3ConfigHandler) CreateS3Config(c *gin.Context) {
 		return
 	}
 
+	// Object key prefix must be a safe key fragment (no traversal, no leading slash)
+	if req.ObjectKeyPrefix != "" {
+		if err := validation.ValidateObjectKey(req.ObjectKeyPrefix); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid object key prefix",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
 	// Set default values for booleans if not provided
 	useSSL := true
 	if req.UseSSL != nil {
@@ -110,6 +122,7 @@ func (h *S3ConfigHandler) CreateS3Config(c *gin.Context) {
 		AccessKeyID:     encryptedAccessKeyID,     // Encrypted for database storage
 		SecretAccessKey: encryptedSecretAccessKey, // Encrypted for database storage
 		BucketPrefix:    req.BucketPrefix,
+		ObjectKeyPrefix: req.ObjectKeyPrefix,
 		UseSSL:          useSSL,
 		ForcePathStyle:  forcePathStyle,
 		IsDefault:       req.IsDefault,
@@ -249,6 +262,16 @@ func (h *S3ConfigHandler) UpdateS3Config(c *gin.Context) {
 	if req.BucketPrefix != "" {
 		s3Config.BucketPrefix = req.BucketPrefix
 	}
+	if req.ObjectKeyPrefix != "" {
+		if err := validation.ValidateObjectKey(req.ObjectKeyPrefix); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid object key prefix",
+				Message: err.Error(),
+			})
+			return
+		}
+		s3Config.ObjectKeyPrefix = req.ObjectKeyPrefix
+	}
 	if req.UseSSL != nil {
 		s3Config.UseSSL = *req.UseSSL
 	}
@@ -342,3 +365,8 @@ func (h *S3ConfigHandler) DeleteS3Config(c *gin.Context) {
 		Message: "S3 configuration deleted successfully",
 	})
 }
+
+// GetS3ConfigCacheStats returns S3 config cache metrics (admin only)
+func (h *S3ConfigHandler) GetS3ConfigCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, GetS3ConfigCacheStats())
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/storage"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CopyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	ETag         string   `xml:"ETag"`
+	LastModified string   `xml:"LastModified"` // s3Timestamp format
+}
+
+// parseCopySource splits an x-amz-copy-source header ("bucket/key", optionally with a leading
+// slash, URL-encoded) into the source bucket and key
+func parseCopySource(header string) (string, string, error) {
+	source := strings.TrimPrefix(header, "/")
+	if strings.Contains(source, "?") {
+		return "", "", errors.New("copying a specific source version is not supported")
+	}
+	source, err := url.PathUnescape(source)
+	if err != nil {
+		return "", "", errors.New("x-amz-copy-source is not a valid URL-encoded bucket/key")
+	}
+	bucketName, objectKey, ok := strings.Cut(source, "/")
+	if !ok || bucketName == "" || objectKey == "" {
+		return "", "", errors.New("x-amz-copy-source must be of the form bucket/key")
+	}
+	return bucketName, objectKey, nil
+}
+
+// CopyObject handles PUT /{bucket}/{key+} with an x-amz-copy-source header: copies an object,
+// possibly from another bucket, as `aws s3 cp s3://src/a s3://dst/b` does. Requires s3:GetObject
+// on the source and s3:PutObject on the destination. The copy keeps the source's content type,
+// checksums, metadata and tags; only the COPY metadata directive is supported
+func (h *S3APIHandler) CopyObject(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	objectKey := strings.TrimPrefix(c.Param("key"), "/")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	srcBucketName, srcKey, err := parseCopySource(c.GetHeader("x-amz-copy-source"))
+	if err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+	if directive := strings.ToUpper(c.GetHeader("x-amz-metadata-directive")); directive != "" && directive != "COPY" {
+		h.s3Error(c, "NotImplemented", "Only the COPY metadata directive is supported", objectKey, http.StatusNotImplemented)
+		return
+	}
+
+	// Validate object key to prevent path traversal and other attacks
+	if err := validation.ValidateObjectKey(objectKey); err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	var dstBucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&dstBucket).Error; err != nil {
+		h.s3Error(c, "NoSuchBucket", "The specified bucket does not exist", bucketName, http.StatusNotFound)
+		return
+	}
+	var srcBucket models.Bucket
+	if err := database.DB.Where("name = ?", srcBucketName).First(&srcBucket).Error; err != nil {
+		h.s3Error(c, "NoSuchBucket", "The specified bucket does not exist", srcBucketName, http.StatusNotFound)
+		return
+	}
+
+	// Each side follows its own bucket's key case mode
+	srcKey = srcBucket.NormalizeKey(srcKey)
+	objectKey = dstBucket.NormalizeKey(objectKey)
+
+	// Auto-date-prefix buckets store copies under the copy date, like uploads
+	objectKey, err = applyAutoDatePrefix(&dstBucket, objectKey)
+	if err != nil {
+		h.s3Error(c, "KeyTooLongError", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+	if err := dstBucket.CheckKeyRules(objectKey); err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	// As on S3, an object can't be copied onto itself without changing its metadata
+	if srcBucket.ID == dstBucket.ID && srcKey == objectKey {
+		h.s3Error(c, "InvalidRequest", "This copy request is illegal because it is trying to copy an object to itself without changing the object's metadata", objectKey, http.StatusBadRequest)
+		return
+	}
+
+	// Check permission to write the destination before revealing anything about the source
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, dstBucket.Name, objectKey, services.ActionPutObject)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to check object access", objectKey, http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		h.s3Error(c, "AccessDenied", "Access Denied", objectKey, http.StatusForbidden)
+		return
+	}
+
+	// Check permission to read the source (denials are reported per OBJECT_DENIAL_MODE)
+	sourceRecord, access, err := h.bucketHandler.resolveObjectAccess(c, userUUID, &srcBucket, srcKey, services.ActionGetObject)
+	if !h.respondObjectAccess(c, access, err, srcKey) {
+		return
+	}
+	sourceObject := *sourceRecord
+
+	if sourceObject.Size > h.config.Storage.MaxFileSize {
+		h.s3Error(c, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size", objectKey, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Serialize concurrent writes to the same key so bytes and metadata always match (last writer wins)
+	unlockKey, err := lockObjectKey(dstBucket.ID, objectKey, objectKeyLockTimeout)
+	if err != nil {
+		h.s3Error(c, "OperationAborted", err.Error(), objectKey, http.StatusConflict)
+		return
+	}
+	defer unlockKey()
+
+	// Recently created objects may be protected from overwrites
+	if err := checkOverwriteWindow(c, &dstBucket, objectKey); err != nil {
+		h.s3Error(c, "OperationAborted", err.Error(), objectKey, http.StatusConflict)
+		return
+	}
+
+	// New keys can't push the bucket past its object count cap
+	if err := checkObjectLimit(&dstBucket, objectKey); err != nil {
+		if errors.Is(err, errObjectLimit) {
+			h.s3Error(c, "QuotaExceeded", err.Error(), objectKey, http.StatusForbidden)
+		} else {
+			h.s3Error(c, "InternalError", "Failed to check bucket object limit", objectKey, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Canned ACL header (only ACLs that map onto bkt's object ACL are accepted)
+	acl, err := parseS3ObjectACL(c.GetHeader("x-amz-acl"), &dstBucket)
+	if err != nil {
+		h.s3Error(c, "NotImplemented", err.Error(), objectKey, http.StatusNotImplemented)
+		return
+	}
+
+	// Optional per-object TTL
+	expiresAt, err := parseObjectExpiry(c.GetHeader("X-Expires-After"), "")
+	if err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	sourceTags, err := loadObjectTags(sourceObject.ID)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to load source tags", srcKey, http.StatusInternalServerError)
+		return
+	}
+
+	srcBackend, err := h.bucketHandler.getStorageBackend(&srcBucket)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to initialize storage", srcKey, http.StatusInternalServerError)
+		return
+	}
+	dstBackend, err := h.bucketHandler.getStorageBackend(&dstBucket)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to initialize storage", objectKey, http.StatusInternalServerError)
+		return
+	}
+
+	// Fail fast when the backend can't hold the object
+	if err := storage.CheckSpace(dstBackend, dstBucket.Name, sourceObject.Size); err != nil {
+		logInsufficientStorage(dstBucket.Name, objectKey, err)
+		h.s3Error(c, "InsufficientStorage", insufficientStorageMessage, objectKey, http.StatusInsufficientStorage)
+		return
+	}
+
+	// Versioned buckets keep the content being replaced as a previous version, which is put
+	// back as the live object if the write or the metadata save fails
+	previous, err := archiveCurrentVersion(dstBackend, &dstBucket, objectKey)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to keep the previous version", objectKey, http.StatusInternalServerError)
+		return
+	}
+	stored := false
+	defer func() {
+		if previous != nil && !stored {
+			restoreArchivedVersion(dstBackend, dstBucket.Name, previous)
+		}
+	}()
+
+	// Server-side copy is only possible when both buckets resolve to the same backend instance
+	if sameStorageBackend(&srcBucket, &dstBucket) {
+		err = srcBackend.CopyObjectCrossBucket(srcBucket.Name, srcKey, dstBucket.Name, objectKey)
+	} else {
+		err = streamCopyObject(srcBackend, dstBackend, srcBucket.Name, dstBucket.Name, &sourceObject, objectKey)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrInsufficientStorage) {
+			logInsufficientStorage(dstBucket.Name, objectKey, err)
+			h.s3Error(c, "InsufficientStorage", insufficientStorageMessage, objectKey, http.StatusInsufficientStorage)
+			return
+		}
+		h.s3Error(c, "InternalError", "Failed to copy object", objectKey, http.StatusInternalServerError)
+		return
+	}
+
+	// Create or replace the destination object record
+	now := time.Now()
+	var object models.Object
+	if err := database.DB.Where("bucket_id = ? AND key = ?", dstBucket.ID, objectKey).First(&object).Error; err != nil {
+		object = models.Object{
+			BucketID:  dstBucket.ID,
+			Key:       objectKey,
+			CreatedAt: now,
+		}
+	}
+	object.Size = sourceObject.Size
+	object.ContentType = sourceObject.ContentType
+	object.ETag = sourceObject.ETag
+	object.SHA256 = sourceObject.SHA256
+	object.ChecksumAlgorithm = sourceObject.ChecksumAlgorithm
+	object.Checksum = sourceObject.Checksum
+	object.StoragePath = objectKey
+	object.Metadata = sourceObject.Metadata
+	object.ACL = acl
+	object.UploadedBy = &userUUID
+	object.ExpiresAt = expiresAt
+	object.VersionID = newVersionID(&dstBucket)
+	object.UpdatedAt = now
+
+	if err := database.DB.Save(&object).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to save object metadata", objectKey, http.StatusInternalServerError)
+		return
+	}
+	stored = true
+
+	// As with S3's default tagging directive, the copy gets the source's tags
+	if err := replaceObjectTags(object.ID, sourceTags); err != nil {
+		h.s3Error(c, "InternalError", "Failed to copy tags", objectKey, http.StatusInternalServerError)
+		return
+	}
+
+	if dstBucket.AutoDatePrefix != "" {
+		c.Header("X-Bkt-Object-Key", objectKey)
+	}
+	if sourceObject.VersionID != "" {
+		c.Header("x-amz-copy-source-version-id", sourceObject.VersionID)
+	}
+	if object.VersionID != "" {
+		c.Header("x-amz-version-id", object.VersionID)
+	}
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.XML(http.StatusOK, CopyObjectResult{
+		Xmlns:        "http://s3.amazonaws.com/doc/2006-03-01/",
+		ETag:         fmt.Sprintf(`"%s"`, object.ETag),
+		LastModified: s3Timestamp(now),
+	})
+}
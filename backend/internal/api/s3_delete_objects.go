@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeleteObjects limits (S3 accepts at most 1000 keys per request)
+const (
+	maxDeleteObjectsKeys = 1000
+
+	// maxDeleteObjectsSize caps a DeleteObjects body (1000 keys of the maximum length fit comfortably)
+	maxDeleteObjectsSize = 2 * 1024 * 1024
+)
+
+type DeleteObjectsRequest struct {
+	XMLName xml.Name                     `xml:"Delete"`
+	Quiet   bool                         `xml:"Quiet"`
+	Objects []DeleteObjectsRequestObject `xml:"Object"`
+}
+
+type DeleteObjectsRequestObject struct {
+	Key string `xml:"Key"`
+}
+
+type DeleteResult struct {
+	XMLName xml.Name             `xml:"DeleteResult"`
+	Xmlns   string               `xml:"xmlns,attr"`
+	Deleted []DeletedObject      `xml:"Deleted"`
+	Errors  []DeleteObjectsError `xml:"Error"`
+}
+
+type DeletedObject struct {
+	Key                   string `xml:"Key"`
+	DeleteMarker          bool   `xml:"DeleteMarker,omitempty"`
+	DeleteMarkerVersionID string `xml:"DeleteMarkerVersionId,omitempty"`
+}
+
+type DeleteObjectsError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// DeleteObjects handles POST /{bucket}?delete: deletes up to 1000 keys in one request. Each key
+// is checked and deleted on its own, so keys the caller can't delete are reported as AccessDenied
+// while the rest go ahead. As on S3, keys that don't exist count as deleted, and Quiet mode
+// only reports the errors
+func (h *S3APIHandler) DeleteObjects(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		h.s3Error(c, "NoSuchBucket", "The specified bucket does not exist", bucketName, http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxDeleteObjectsSize+1))
+	if err != nil {
+		h.s3Error(c, "IncompleteBody", "Failed to read request body", bucketName, http.StatusBadRequest)
+		return
+	}
+	var request DeleteObjectsRequest
+	if len(body) > maxDeleteObjectsSize || xml.Unmarshal(body, &request) != nil || len(request.Objects) == 0 {
+		h.s3Error(c, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", bucketName, http.StatusBadRequest)
+		return
+	}
+	if len(request.Objects) > maxDeleteObjectsKeys {
+		h.s3Error(c, "MalformedXML", fmt.Sprintf("A DeleteObjects request may list at most %d keys", maxDeleteObjectsKeys), bucketName, http.StatusBadRequest)
+		return
+	}
+
+	storageBackend, err := h.bucketHandler.getStorageBackend(&bucket)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to get storage backend", bucketName, http.StatusInternalServerError)
+		return
+	}
+
+	result := DeleteResult{
+		Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/",
+	}
+	for _, entry := range request.Objects {
+		marker, code, message := h.deleteObjectForBatch(c, storageBackend, userUUID, &bucket, entry.Key)
+		if code != "" {
+			result.Errors = append(result.Errors, DeleteObjectsError{Key: entry.Key, Code: code, Message: message})
+		} else if !request.Quiet {
+			deleted := DeletedObject{Key: entry.Key}
+			if marker != nil {
+				deleted.DeleteMarker = true
+				deleted.DeleteMarkerVersionID = marker.VersionID
+			}
+			result.Deleted = append(result.Deleted, deleted)
+		}
+	}
+
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.XML(http.StatusOK, result)
+}
+
+// deleteObjectForBatch deletes one key of a DeleteObjects request, returning the S3 error code
+// and message for its <Error> entry, or an empty code once the key is gone (with the delete
+// marker created for it in a versioned bucket)
+func (h *S3APIHandler) deleteObjectForBatch(c *gin.Context, storageBackend storage.StorageBackend, userID uuid.UUID, bucket *models.Bucket, objectKey string) (*models.ObjectVersion, string, string) {
+	if objectKey == "" {
+		return nil, "InvalidArgument", "The key must not be empty"
+	}
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// The policy is checked before the object is looked up, so a denial doesn't reveal whether it exists
+	allowed, err := h.policyService.CheckObjectAccess(c, userID, bucket.Name, objectKey, services.ActionDeleteObject)
+	if err != nil {
+		return nil, "InternalError", "Failed to check object access"
+	}
+	if !allowed {
+		return nil, "AccessDenied", "Access Denied"
+	}
+
+	var object models.Object
+	if err := database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ""
+		}
+		return nil, "InternalError", "Failed to load object metadata"
+	}
+
+	// Versioned buckets keep the deleted content as a previous version
+	previous, err := archiveObjectVersion(storageBackend, bucket, &object)
+	if err != nil {
+		return nil, "InternalError", "Failed to keep the previous version"
+	}
+
+	// Delete from storage first - MUST succeed before database delete (prevents inconsistency)
+	if err := storageBackend.DeleteObject(bucket.Name, objectKey); err != nil {
+		if previous != nil {
+			discardObjectVersion(storageBackend, bucket.Name, previous)
+		}
+		return nil, "InternalError", "Failed to delete object from storage"
+	}
+	if err := database.DB.Delete(&object).Error; err != nil {
+		return nil, "InternalError", "Failed to delete object metadata"
+	}
+	marker, err := createDeleteMarker(bucket, objectKey)
+	if err != nil {
+		return nil, "InternalError", "Failed to create delete marker"
+	}
+	return marker, "", ""
+}
@@ -0,0 +1,654 @@
+package api
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/storage"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// S3 multipart upload limits (https://docs.aws.amazon.com/AmazonS3/latest/userguide/qfacts.html)
+const (
+	maxMultipartParts    = 10000
+	maxMultipartPartSize = 5 * 1024 * 1024 * 1024 // 5 GiB
+	minMultipartPartSize = 5 * 1024 * 1024        // Every part but the last
+
+	// maxCompleteMultipartSize caps a CompleteMultipartUpload body (10000 parts fit comfortably)
+	maxCompleteMultipartSize = 2 * 1024 * 1024
+
+	// maxPendingMultipartUploads limits how many unfinished multipart uploads a user can hold
+	maxPendingMultipartUploads = 100
+
+	// multipartUploadMaxAge is how long an upload may stay unfinished before its parts are discarded
+	multipartUploadMaxAge = 7 * 24 * time.Hour
+)
+
+type InitiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type CompleteMultipartUpload struct {
+	XMLName xml.Name                      `xml:"CompleteMultipartUpload"`
+	Parts   []CompleteMultipartUploadPart `xml:"Part"`
+}
+
+type CompleteMultipartUploadPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type CompleteMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+// PostObject dispatches POST /{bucket}/{key+}: ?uploads starts a multipart upload and ?uploadId completes one
+func (h *S3APIHandler) PostObject(c *gin.Context) {
+	switch {
+	case hasSubresource(c, "uploads"):
+		h.CreateMultipartUpload(c)
+	case hasSubresource(c, "uploadId"):
+		h.CompleteMultipartUpload(c)
+	default:
+		h.s3Error(c, "NotImplemented", "A header or query you provided implies functionality that is not implemented", strings.TrimPrefix(c.Param("key"), "/"), http.StatusNotImplemented)
+	}
+}
+
+// CreateMultipartUpload handles POST /{bucket}/{key+}?uploads. The key, ACL and expiry are fixed
+// here; parts are uploaded with UploadPart and become the object on CompleteMultipartUpload
+func (h *S3APIHandler) CreateMultipartUpload(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	objectKey := strings.TrimPrefix(c.Param("key"), "/")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	// Validate object key to prevent path traversal and other attacks
+	if err := validation.ValidateObjectKey(objectKey); err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		h.s3Error(c, "NoSuchBucket", "The specified bucket does not exist", bucketName, http.StatusNotFound)
+		return
+	}
+
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Auto-date-prefix buckets store uploads under the date the upload started
+	objectKey, err := applyAutoDatePrefix(&bucket, objectKey)
+	if err != nil {
+		h.s3Error(c, "KeyTooLongError", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	// Governance key rules (e.g. only logs/*.gz), checked against the stored key
+	if err := bucket.CheckKeyRules(objectKey); err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	allowed, _ := h.policyService.CheckObjectAccess(c, userUUID, bucketName, objectKey, services.ActionPutObject)
+	if !allowed {
+		h.s3Error(c, "AccessDenied", "Access Denied", objectKey, http.StatusForbidden)
+		return
+	}
+
+	// Checked again on completion; failing here saves uploading parts that can't be used
+	if err := checkOverwriteWindow(c, &bucket, objectKey); err != nil {
+		h.s3Error(c, "OperationAborted", err.Error(), objectKey, http.StatusConflict)
+		return
+	}
+	if err := checkObjectLimit(&bucket, objectKey); err != nil {
+		if errors.Is(err, errObjectLimit) {
+			h.s3Error(c, "QuotaExceeded", err.Error(), objectKey, http.StatusForbidden)
+		} else {
+			h.s3Error(c, "InternalError", "Failed to check bucket object limit", objectKey, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	acl, err := parseS3ObjectACL(c.GetHeader("x-amz-acl"), &bucket)
+	if err != nil {
+		h.s3Error(c, "NotImplemented", err.Error(), objectKey, http.StatusNotImplemented)
+		return
+	}
+
+	expiresAt, err := parseObjectExpiry(c.GetHeader("X-Expires-After"), "")
+	if err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	metadata, ok := h.requestMetadata(c, objectKey)
+	if !ok {
+		return
+	}
+
+	// Limit unfinished uploads per user so staged parts can't pile up
+	var pending int64
+	if err := database.DB.Model(&models.MultipartUpload{}).Where("initiated_by = ?", userUUID).Count(&pending).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to count pending uploads", objectKey, http.StatusInternalServerError)
+		return
+	}
+	if pending >= maxPendingMultipartUploads {
+		h.s3Error(c, "SlowDown", fmt.Sprintf("You have %d unfinished multipart uploads; complete or abort some first", pending), objectKey, http.StatusServiceUnavailable)
+		return
+	}
+
+	storageBackend, err := h.bucketHandler.getStorageBackend(&bucket)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to initialize storage", objectKey, http.StatusInternalServerError)
+		return
+	}
+	uploader, err := storage.Multipart(storageBackend)
+	if err != nil {
+		h.s3Error(c, "NotImplemented", err.Error(), objectKey, http.StatusNotImplemented)
+		return
+	}
+
+	declaredType := c.GetHeader("Content-Type")
+	backendType := declaredType
+	if backendType == "" {
+		backendType = "application/octet-stream"
+	}
+	storageUploadID, err := uploader.CreateMultipartUpload(bucketName, objectKey, backendType)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to start multipart upload", objectKey, http.StatusInternalServerError)
+		return
+	}
+
+	upload := models.MultipartUpload{
+		BucketID:        bucket.ID,
+		Key:             objectKey,
+		StorageUploadID: storageUploadID,
+		ContentType:     declaredType,
+		ACL:             acl,
+		ExpiresAt:       expiresAt,
+		Metadata:        metadata,
+		InitiatedBy:     userUUID,
+	}
+	if err := database.DB.Create(&upload).Error; err != nil {
+		uploader.AbortMultipartUpload(bucketName, objectKey, storageUploadID)
+		h.s3Error(c, "InternalError", "Failed to record multipart upload", objectKey, http.StatusInternalServerError)
+		return
+	}
+
+	if bucket.AutoDatePrefix != "" {
+		c.Header("X-Bkt-Object-Key", objectKey)
+	}
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.XML(http.StatusOK, InitiateMultipartUploadResult{
+		Xmlns:    "http://s3.amazonaws.com/doc/2006-03-01/",
+		Bucket:   bucketName,
+		Key:      objectKey,
+		UploadID: upload.ID.String(),
+	})
+}
+
+// multipartUploadFor loads the upload named by ?uploadId, checking that it belongs to the requested
+// bucket and key and that the caller may still write the key. Writes the S3 error otherwise
+func (h *S3APIHandler) multipartUploadFor(c *gin.Context) (*models.Bucket, *models.MultipartUpload, bool) {
+	bucketName := c.Param("bucket")
+	objectKey := strings.TrimPrefix(c.Param("key"), "/")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		h.s3Error(c, "NoSuchBucket", "The specified bucket does not exist", bucketName, http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	var upload models.MultipartUpload
+	uploadID, err := uuid.Parse(c.Query("uploadId"))
+	if err == nil {
+		err = database.DB.Where("id = ? AND bucket_id = ?", uploadID, bucket.ID).First(&upload).Error
+	}
+	if err != nil || !multipartKeyMatches(&bucket, &upload, objectKey) {
+		h.s3Error(c, "NoSuchUpload", "The specified upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed.", objectKey, http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	allowed, _ := h.policyService.CheckObjectAccess(c, userUUID, bucketName, upload.Key, services.ActionPutObject)
+	if !allowed {
+		h.s3Error(c, "AccessDenied", "Access Denied", upload.Key, http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	return &bucket, &upload, true
+}
+
+// multipartKeyMatches reports whether a request's key names the upload's key. On auto-date-prefix
+// buckets clients may keep sending the key without the prefix the upload was given
+func multipartKeyMatches(bucket *models.Bucket, upload *models.MultipartUpload, objectKey string) bool {
+	objectKey = bucket.NormalizeKey(objectKey)
+	if upload.Key == objectKey {
+		return true
+	}
+	return bucket.AutoDatePrefix != "" && strings.HasSuffix(upload.Key, objectKey)
+}
+
+// UploadPart handles PUT /{bucket}/{key+}?partNumber=N&uploadId=X. Uploading a part number
+// again replaces the earlier part
+func (h *S3APIHandler) UploadPart(c *gin.Context) {
+	objectKey := strings.TrimPrefix(c.Param("key"), "/")
+
+	partNumber, err := strconv.Atoi(c.Query("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxMultipartParts {
+		h.s3Error(c, "InvalidArgument", fmt.Sprintf("Part number must be an integer between 1 and %d, inclusive", maxMultipartParts), objectKey, http.StatusBadRequest)
+		return
+	}
+
+	bucket, upload, ok := h.multipartUploadFor(c)
+	if !ok {
+		return
+	}
+	objectKey = upload.Key
+
+	body, contentLength, chunked, verifier, ok := h.uploadBody(c, objectKey)
+	if !ok {
+		return
+	}
+	if contentLength > maxMultipartPartSize || contentLength > h.config.Storage.MaxFileSize {
+		h.s3Error(c, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size", objectKey, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Only the first part's type matters, but parts can arrive in any order, so every part records its own
+	detectedType, firstBytes, err := validation.DetectContentType(body)
+	if err != nil {
+		if code, message, ok := s3UploadBodyError(verifier, chunked); ok {
+			h.s3Error(c, code, message, objectKey, http.StatusBadRequest)
+			return
+		}
+		h.s3Error(c, "InternalError", "Failed to detect content type", objectKey, http.StatusInternalServerError)
+		return
+	}
+	detectedType = validation.RefineContentType(detectedType, firstBytes, objectKey, h.config.Storage.ExtensionContentTypes)
+
+	// The part ETag is its MD5, which CompleteMultipartUpload also needs for the object ETag
+	hasher := md5.New()
+	data := io.TeeReader(io.MultiReader(bytes.NewReader(firstBytes), body), hasher)
+
+	storageBackend, err := h.bucketHandler.getStorageBackend(bucket)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to initialize storage", objectKey, http.StatusInternalServerError)
+		return
+	}
+	uploader, err := storage.Multipart(storageBackend)
+	if err != nil {
+		h.s3Error(c, "NotImplemented", err.Error(), objectKey, http.StatusNotImplemented)
+		return
+	}
+
+	if err := storage.CheckSpace(storageBackend, bucket.Name, contentLength); err != nil {
+		logInsufficientStorage(bucket.Name, objectKey, err)
+		h.s3Error(c, "InsufficientStorage", insufficientStorageMessage, objectKey, http.StatusInsufficientStorage)
+		return
+	}
+
+	storageETag, err := uploader.UploadPart(bucket.Name, objectKey, upload.StorageUploadID, partNumber, data, contentLength)
+	if err != nil {
+		if errors.Is(err, storage.ErrInsufficientStorage) {
+			logInsufficientStorage(bucket.Name, objectKey, err)
+			h.s3Error(c, "InsufficientStorage", insufficientStorageMessage, objectKey, http.StatusInsufficientStorage)
+			return
+		}
+		if code, message, ok := s3UploadBodyError(verifier, chunked); ok {
+			h.s3Error(c, code, message, objectKey, http.StatusBadRequest)
+			return
+		}
+		h.s3Error(c, "InternalError", "Failed to save part", objectKey, http.StatusInternalServerError)
+		return
+	}
+
+	part := models.MultipartPart{
+		UploadID:     upload.ID,
+		PartNumber:   partNumber,
+		Size:         contentLength,
+		ETag:         hex.EncodeToString(hasher.Sum(nil)),
+		StorageETag:  storageETag,
+		DetectedType: detectedType,
+	}
+	if err := database.DB.Save(&part).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to record part", objectKey, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("ETag", fmt.Sprintf(`"%s"`, part.ETag))
+	if verifier != nil {
+		c.Header(s3ChecksumHeader(verifier.checksum.algorithm), verifier.computed)
+	}
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.Status(http.StatusOK)
+}
+
+// CompleteMultipartUpload handles POST /{bucket}/{key+}?uploadId=X: the listed parts, in
+// ascending part number order, become the object. As on S3, its ETag is the MD5 of the parts'
+// binary MD5s followed by the part count, e.g. "3858f62230ac3c915f300c664312c11f-9"
+func (h *S3APIHandler) CompleteMultipartUpload(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	bucket, upload, ok := h.multipartUploadFor(c)
+	if !ok {
+		return
+	}
+	objectKey := upload.Key
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCompleteMultipartSize+1))
+	if err != nil {
+		h.s3Error(c, "IncompleteBody", "Failed to read request body", objectKey, http.StatusBadRequest)
+		return
+	}
+	var request CompleteMultipartUpload
+	if len(body) > maxCompleteMultipartSize || xml.Unmarshal(body, &request) != nil || len(request.Parts) == 0 {
+		h.s3Error(c, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", objectKey, http.StatusBadRequest)
+		return
+	}
+
+	var stored []models.MultipartPart
+	if err := database.DB.Where("upload_id = ?", upload.ID).Find(&stored).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to load parts", objectKey, http.StatusInternalServerError)
+		return
+	}
+	partsByNumber := make(map[int]models.MultipartPart, len(stored))
+	for _, part := range stored {
+		partsByNumber[part.PartNumber] = part
+	}
+
+	completed := make([]storage.CompletedPart, len(request.Parts))
+	partDigests := md5.New()
+	var totalSize int64
+	for i, requested := range request.Parts {
+		if i > 0 && requested.PartNumber <= request.Parts[i-1].PartNumber {
+			h.s3Error(c, "InvalidPartOrder", "The list of parts was not in ascending order. The parts list must be specified in order by part number.", objectKey, http.StatusBadRequest)
+			return
+		}
+		part, exists := partsByNumber[requested.PartNumber]
+		if !exists || strings.Trim(requested.ETag, `"`) != part.ETag {
+			h.s3Error(c, "InvalidPart", "One or more of the specified parts could not be found. The part may not have been uploaded, or the specified entity tag may not match the part's entity tag.", objectKey, http.StatusBadRequest)
+			return
+		}
+		if i < len(request.Parts)-1 && part.Size < minMultipartPartSize {
+			h.s3Error(c, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed object size.", objectKey, http.StatusBadRequest)
+			return
+		}
+
+		digest, _ := hex.DecodeString(part.ETag)
+		partDigests.Write(digest)
+		totalSize += part.Size
+		completed[i] = storage.CompletedPart{PartNumber: part.PartNumber, ETag: part.StorageETag}
+	}
+	etag := fmt.Sprintf("%s-%d", hex.EncodeToString(partDigests.Sum(nil)), len(request.Parts))
+
+	if totalSize > h.config.Storage.MaxFileSize {
+		h.s3Error(c, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size", objectKey, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// The object's type comes from the start of its first part, as for a single PUT
+	detectedType := partsByNumber[request.Parts[0].PartNumber].DetectedType
+	if !validation.IsSafeContentType(detectedType) {
+		h.s3Error(c, "InvalidRequest", fmt.Sprintf("File type '%s' is not allowed", detectedType), objectKey, http.StatusBadRequest)
+		return
+	}
+	contentType := validation.ResolveContentType(detectedType, upload.ContentType, h.config.Storage.TrustedContentTypes)
+	if err := h.bucketHandler.checkContentTypeSize(totalSize, detectedType, contentType); err != nil {
+		h.s3Error(c, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed size for its type: "+err.Error(), objectKey, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Serialize concurrent writes to the same key so bytes and metadata always match (last writer wins)
+	unlockKey, err := lockObjectKey(bucket.ID, objectKey, objectKeyLockTimeout)
+	if err != nil {
+		h.s3Error(c, "OperationAborted", err.Error(), objectKey, http.StatusConflict)
+		return
+	}
+	defer unlockKey()
+
+	// The key may have been written since the upload started
+	if err := checkOverwriteWindow(c, bucket, objectKey); err != nil {
+		h.s3Error(c, "OperationAborted", err.Error(), objectKey, http.StatusConflict)
+		return
+	}
+	if err := checkObjectLimit(bucket, objectKey); err != nil {
+		if errors.Is(err, errObjectLimit) {
+			h.s3Error(c, "QuotaExceeded", err.Error(), objectKey, http.StatusForbidden)
+		} else {
+			h.s3Error(c, "InternalError", "Failed to check bucket object limit", objectKey, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	storageBackend, err := h.bucketHandler.getStorageBackend(bucket)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to initialize storage", objectKey, http.StatusInternalServerError)
+		return
+	}
+	uploader, err := storage.Multipart(storageBackend)
+	if err != nil {
+		h.s3Error(c, "NotImplemented", err.Error(), objectKey, http.StatusNotImplemented)
+		return
+	}
+
+	// Local assembly writes a full copy of the parts before they are removed
+	if err := storage.CheckSpace(storageBackend, bucket.Name, totalSize); err != nil {
+		logInsufficientStorage(bucket.Name, objectKey, err)
+		h.s3Error(c, "InsufficientStorage", insufficientStorageMessage, objectKey, http.StatusInsufficientStorage)
+		return
+	}
+
+	// Versioned buckets keep the content being replaced as a previous version, which is put
+	// back as the live object if the write or the metadata save fails
+	previous, err := archiveCurrentVersion(storageBackend, bucket, objectKey)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to keep the previous version", objectKey, http.StatusInternalServerError)
+		return
+	}
+	committed := false
+	defer func() {
+		if previous != nil && !committed {
+			restoreArchivedVersion(storageBackend, bucket.Name, previous)
+		}
+	}()
+
+	if err := uploader.CompleteMultipartUpload(bucket.Name, objectKey, upload.StorageUploadID, completed); err != nil {
+		if errors.Is(err, storage.ErrInsufficientStorage) {
+			logInsufficientStorage(bucket.Name, objectKey, err)
+			h.s3Error(c, "InsufficientStorage", insufficientStorageMessage, objectKey, http.StatusInsufficientStorage)
+			return
+		}
+		h.s3Error(c, "InternalError", "Failed to assemble object", objectKey, http.StatusInternalServerError)
+		return
+	}
+
+	// The object row replaces the upload in one transaction, so a completed upload can't be completed again
+	var object models.Object
+	created := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&object).Error; err == nil {
+			object.Size = totalSize
+			object.ContentType = contentType
+			object.ETag = etag
+			object.SHA256 = "" // Not known for assembled objects
+			object.ChecksumAlgorithm = ""
+			object.Checksum = ""
+			object.StoragePath = objectKey
+			object.ACL = upload.ACL
+			object.UploadedBy = &userUUID
+			object.ExpiresAt = upload.ExpiresAt
+			object.Metadata = upload.Metadata
+			object.VersionID = newVersionID(bucket)
+			object.UpdatedAt = time.Now()
+			if err := tx.Save(&object).Error; err != nil {
+				return err
+			}
+		} else {
+			object = models.Object{
+				BucketID:    bucket.ID,
+				Key:         objectKey,
+				Size:        totalSize,
+				ContentType: contentType,
+				ETag:        etag,
+				StoragePath: objectKey,
+				ACL:         upload.ACL,
+				UploadedBy:  &userUUID,
+				ExpiresAt:   upload.ExpiresAt,
+				Metadata:    upload.Metadata,
+				VersionID:   newVersionID(bucket),
+			}
+			if err := tx.Create(&object).Error; err != nil {
+				return err
+			}
+			created = true
+		}
+
+		if err := tx.Where("upload_id = ?", upload.ID).Delete(&models.MultipartPart{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(upload).Error
+	})
+	if err != nil {
+		if created {
+			storageBackend.DeleteObject(bucket.Name, objectKey)
+		}
+		h.s3Error(c, "InternalError", "Failed to create object metadata", objectKey, http.StatusInternalServerError)
+		return
+	}
+	committed = true
+
+	if bucket.AutoDatePrefix != "" {
+		c.Header("X-Bkt-Object-Key", objectKey)
+	}
+	if object.VersionID != "" {
+		c.Header("x-amz-version-id", object.VersionID)
+	}
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.XML(http.StatusOK, CompleteMultipartUploadResult{
+		Xmlns:    "http://s3.amazonaws.com/doc/2006-03-01/",
+		Location: fmt.Sprintf("https://%s/%s/%s", c.Request.Host, bucket.Name, objectKey),
+		Bucket:   bucket.Name,
+		Key:      objectKey,
+		ETag:     fmt.Sprintf(`"%s"`, etag),
+	})
+}
+
+// AbortMultipartUpload handles DELETE /{bucket}/{key+}?uploadId=X, discarding the stored parts
+func (h *S3APIHandler) AbortMultipartUpload(c *gin.Context) {
+	bucket, upload, ok := h.multipartUploadFor(c)
+	if !ok {
+		return
+	}
+
+	storageBackend, err := h.bucketHandler.getStorageBackend(bucket)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to initialize storage", upload.Key, http.StatusInternalServerError)
+		return
+	}
+
+	if err := abortMultipartUpload(storageBackend, bucket.Name, upload); err != nil {
+		h.s3Error(c, "InternalError", "Failed to abort multipart upload", upload.Key, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.Status(http.StatusNoContent)
+}
+
+// abortMultipartUpload deletes an upload's staged parts from storage, then its database rows
+func abortMultipartUpload(storageBackend storage.StorageBackend, bucketName string, upload *models.MultipartUpload) error {
+	if uploader, err := storage.Multipart(storageBackend); err == nil {
+		if err := uploader.AbortMultipartUpload(bucketName, upload.Key, upload.StorageUploadID); err != nil {
+			return err
+		}
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("upload_id = ?", upload.ID).Delete(&models.MultipartPart{}).Error; err != nil {
+			return fmt.Errorf("failed to delete parts: %w", err)
+		}
+		if err := tx.Delete(upload).Error; err != nil {
+			return fmt.Errorf("failed to delete upload: %w", err)
+		}
+		return nil
+	})
+}
+
+// StartMultipartCleanup periodically aborts multipart uploads left unfinished for longer than
+// multipartUploadMaxAge, so abandoned parts don't hold storage forever
+func StartMultipartCleanup(cfg *config.Config, interval time.Duration) {
+	h := NewBucketHandler(cfg)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// Writes are frozen in maintenance mode; stale uploads are aborted once it ends
+			if middleware.GetMaintenanceMode().Enabled {
+				continue
+			}
+			h.abortStaleMultipartUploads()
+		}
+	}()
+}
+
+// abortStaleMultipartUploads aborts every upload older than multipartUploadMaxAge
+func (h *BucketHandler) abortStaleMultipartUploads() {
+	var stale []models.MultipartUpload
+	if err := database.DB.Preload("Bucket").Where("created_at < ?", time.Now().Add(-multipartUploadMaxAge)).Find(&stale).Error; err != nil {
+		logger.Warn("Failed to load stale multipart uploads", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	for i := range stale {
+		upload := &stale[i]
+		if upload.Bucket.ID == uuid.Nil {
+			continue // Bucket is in the trash; its uploads go when it is purged
+		}
+
+		storageBackend, err := h.getStorageBackend(&upload.Bucket)
+		if err == nil {
+			err = abortMultipartUpload(storageBackend, upload.Bucket.Name, upload)
+		}
+		if err != nil {
+			logger.Warn("Failed to abort stale multipart upload", map[string]interface{}{
+				"bucket_name": upload.Bucket.Name,
+				"key":         upload.Key,
+				"error":       err.Error(),
+			})
+		}
+	}
+}
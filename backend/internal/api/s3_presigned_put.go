@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// presignedPutIgnoredHeaders are S3 PutObject headers a presigned upload drops: the signature
+// covers none of them, so the URL's holder doesn't get to choose the ACL, TTL or checksums
+var presignedPutIgnoredHeaders = []string{
+	"x-amz-acl",
+	"X-Expires-After",
+	"x-amz-sdk-checksum-algorithm",
+	"x-amz-trailer",
+}
+
+// PresignedPutObject handles PUT /api/presigned/:bucket/*key: a raw-body upload authorized by a
+// presigned URL (see PresignedURLMiddleware), run as the URL's issuer through the S3 PutObject
+// path. Only the body and Content-Type come from the holder. A copy source or an aws-chunked body
+// is rejected, and ACL, TTL, metadata and checksum headers are dropped. Errors are JSON, like the
+// rest of /api
+func (h *S3APIHandler) PresignedPutObject(c *gin.Context) {
+	if c.GetHeader("x-amz-copy-source") != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid presigned upload",
+			Message: "A presigned URL can't copy another object; send the content as the request body",
+		})
+		return
+	}
+	if isAWSChunkedUpload(c) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid presigned upload",
+			Message: "aws-chunked bodies aren't accepted with a presigned URL",
+		})
+		return
+	}
+
+	for _, name := range presignedPutIgnoredHeaders {
+		c.Request.Header.Del(name)
+	}
+	for name := range c.Request.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, amzMetaPrefix) || strings.HasPrefix(lower, "x-amz-checksum-") {
+			c.Request.Header.Del(name)
+		}
+	}
+
+	c.Set(restErrorsKey, true)
+	h.PutObject(c)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bkt/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPresignedPutObjectRejectsHeaderDrivenOperations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+	}{
+		{"copy source", "x-amz-copy-source", "/other-bucket/secret.txt"},
+		{"aws-chunked encoding", "Content-Encoding", "aws-chunked"},
+		{"streaming payload", "X-Amz-Content-Sha256", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPut, "/api/presigned/my-bucket/upload.txt", strings.NewReader("data"))
+			c.Request.Header.Set(tt.header, tt.value)
+			c.Params = gin.Params{{Key: "bucket", Value: "my-bucket"}, {Key: "key", Value: "/upload.txt"}}
+
+			// Rejected before any bucket lookup or storage access
+			h := &S3APIHandler{config: &config.Config{}}
+			h.PresignedPutObject(c)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+				t.Errorf("Content-Type = %q, want a JSON error", contentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"sort"
+
+	"bkt/internal/services"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxTaggingSize caps a PUT ?tagging body (10 tags of the maximum size fit comfortably)
+const maxTaggingSize = 64 * 1024
+
+// Tagging is the S3 ?tagging document
+type Tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	TagSet  TagSet   `xml:"TagSet"`
+}
+
+type TagSet struct {
+	Tags []Tag `xml:"Tag"`
+}
+
+type Tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// GetObjectTagging handles GET /{bucket}/{key+}?tagging
+func (h *S3APIHandler) GetObjectTagging(c *gin.Context) {
+	_, object, ok := h.loadObjectForACL(c, services.ActionGetObjectTagging)
+	if !ok {
+		return
+	}
+
+	tags, err := loadObjectTags(object.ID)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to load tags", object.Key, http.StatusInternalServerError)
+		return
+	}
+
+	response := Tagging{Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/"}
+	for key, value := range tags {
+		response.TagSet.Tags = append(response.TagSet.Tags, Tag{Key: key, Value: value})
+	}
+	sort.Slice(response.TagSet.Tags, func(i, j int) bool { return response.TagSet.Tags[i].Key < response.TagSet.Tags[j].Key })
+
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.XML(http.StatusOK, response)
+}
+
+// PutObjectTagging handles PUT /{bucket}/{key+}?tagging: replaces the object's tag set. The
+// object's content and Last-Modified time are unchanged
+func (h *S3APIHandler) PutObjectTagging(c *gin.Context) {
+	_, object, ok := h.loadObjectForACL(c, services.ActionPutObjectTagging)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxTaggingSize+1))
+	if err != nil {
+		h.s3Error(c, "IncompleteBody", "Failed to read request body", object.Key, http.StatusBadRequest)
+		return
+	}
+	var request Tagging
+	if len(body) > maxTaggingSize || xml.Unmarshal(body, &request) != nil {
+		h.s3Error(c, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", object.Key, http.StatusBadRequest)
+		return
+	}
+
+	tags := make(map[string]string, len(request.TagSet.Tags))
+	for _, tag := range request.TagSet.Tags {
+		if _, exists := tags[tag.Key]; exists {
+			h.s3Error(c, "InvalidTag", "Cannot provide multiple tags with the same key", object.Key, http.StatusBadRequest)
+			return
+		}
+		tags[tag.Key] = tag.Value
+	}
+	if err := validation.ValidateObjectTags(tags); err != nil {
+		h.s3Error(c, "InvalidTag", err.Error(), object.Key, http.StatusBadRequest)
+		return
+	}
+
+	if err := replaceObjectTags(object.ID, tags); err != nil {
+		h.s3Error(c, "InternalError", "Failed to save tags", object.Key, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.Status(http.StatusOK)
+}
+
+// DeleteObjectTagging handles DELETE /{bucket}/{key+}?tagging
+func (h *S3APIHandler) DeleteObjectTagging(c *gin.Context) {
+	_, object, ok := h.loadObjectForACL(c, services.ActionDeleteObjectTagging)
+	if !ok {
+		return
+	}
+
+	if err := replaceObjectTags(object.ID, nil); err != nil {
+		h.s3Error(c, "InternalError", "Failed to delete tags", object.Key, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,425 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/storage"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxVersioningConfigSize caps a PutBucketVersioning body
+const maxVersioningConfigSize = 64 * 1024
+
+// S3 versioning states
+const (
+	versioningEnabled   = "Enabled"
+	versioningSuspended = "Suspended"
+)
+
+type VersioningConfiguration struct {
+	XMLName xml.Name `xml:"VersioningConfiguration"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Status  string   `xml:"Status,omitempty"` // Omitted for buckets that were never versioned
+}
+
+type ListVersionsResult struct {
+	XMLName       xml.Name            `xml:"ListVersionsResult"`
+	Xmlns         string              `xml:"xmlns,attr"`
+	Name          string              `xml:"Name"`
+	Prefix        string              `xml:"Prefix"`
+	KeyMarker     string              `xml:"KeyMarker"`
+	NextKeyMarker string              `xml:"NextKeyMarker,omitempty"`
+	MaxKeys       int                 `xml:"MaxKeys"`
+	IsTruncated   bool                `xml:"IsTruncated"`
+	Versions      []ObjectVersionInfo `xml:"Version"`
+	DeleteMarkers []DeleteMarkerInfo  `xml:"DeleteMarker"`
+}
+
+type ObjectVersionInfo struct {
+	Key          string    `xml:"Key"`
+	VersionID    string    `xml:"VersionId"`
+	IsLatest     bool      `xml:"IsLatest"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+	StorageClass string    `xml:"StorageClass"`
+}
+
+type DeleteMarkerInfo struct {
+	Key          string    `xml:"Key"`
+	VersionID    string    `xml:"VersionId"`
+	IsLatest     bool      `xml:"IsLatest"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// GetBucketVersioning handles GET /{bucket}?versioning
+func (h *S3APIHandler) GetBucketVersioning(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	bucket, ok := h.loadBucketForConfig(c, bucketName, services.ActionGetBucketVersioning)
+	if !ok {
+		return
+	}
+
+	config := VersioningConfiguration{Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/"}
+	if bucket.VersioningEnabled {
+		config.Status = versioningEnabled
+	} else {
+		// A bucket holding versions has been versioned before
+		var count int64
+		if err := database.DB.Model(&models.ObjectVersion{}).Where("bucket_id = ?", bucket.ID).Limit(1).Count(&count).Error; err != nil {
+			h.s3Error(c, "InternalError", "Failed to load versioning configuration", bucketName, http.StatusInternalServerError)
+			return
+		}
+		if count > 0 {
+			config.Status = versioningSuspended
+		}
+	}
+
+	c.XML(http.StatusOK, config)
+}
+
+// PutBucketVersioning handles PUT /{bucket}?versioning: Enabled starts keeping previous
+// versions of overwritten and deleted objects, Suspended stops (versions already kept remain)
+func (h *S3APIHandler) PutBucketVersioning(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	bucket, ok := h.loadBucketForConfig(c, bucketName, services.ActionPutBucketVersioning)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxVersioningConfigSize+1))
+	if err != nil {
+		h.s3Error(c, "IncompleteBody", "Failed to read request body", bucketName, http.StatusBadRequest)
+		return
+	}
+	var config VersioningConfiguration
+	if len(body) > maxVersioningConfigSize || xml.Unmarshal(body, &config) != nil {
+		h.s3Error(c, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", bucketName, http.StatusBadRequest)
+		return
+	}
+	if config.Status != versioningEnabled && config.Status != versioningSuspended {
+		h.s3Error(c, "IllegalVersioningConfigurationException", "The versioning status must be Enabled or Suspended", bucketName, http.StatusBadRequest)
+		return
+	}
+
+	enabled := config.Status == versioningEnabled
+	if enabled {
+		storageBackend, err := h.bucketHandler.getStorageBackend(bucket)
+		if err != nil {
+			h.s3Error(c, "InternalError", "Failed to initialize storage", bucketName, http.StatusInternalServerError)
+			return
+		}
+		if _, err := storage.Versioner(storageBackend); err != nil {
+			h.s3Error(c, "NotImplemented", err.Error(), bucketName, http.StatusNotImplemented)
+			return
+		}
+	}
+
+	if err := database.DB.Model(bucket).Update("versioning_enabled", enabled).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to update versioning configuration", bucketName, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.Status(http.StatusOK)
+}
+
+// ListObjectVersions handles GET /{bucket}?versions: every version of the keys under prefix,
+// the current one first, then previous versions and delete markers newest first. A page
+// ends on a key boundary, so max-keys may be exceeded by the versions of its first key
+func (h *S3APIHandler) ListObjectVersions(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	bucket, ok := h.loadBucketForConfig(c, bucketName, services.ActionListBucketVersions)
+	if !ok {
+		return
+	}
+
+	prefix := bucket.NormalizeKey(c.Query("prefix"))
+	keyMarker := bucket.NormalizeKey(c.Query("key-marker"))
+	maxKeys := 1000
+	if mk := c.Query("max-keys"); mk != "" {
+		if parsed, err := strconv.Atoi(mk); err == nil && parsed > 0 && parsed < maxKeys {
+			maxKeys = parsed
+		}
+	}
+
+	// Keys with a current version, previous versions or both, in order
+	pattern := validation.EscapeLikeWildcards(prefix) + "%"
+	var keys []string
+	if err := database.DB.Raw(`
+		SELECT key FROM objects WHERE bucket_id = ? AND key LIKE ? AND key > ?
+		UNION
+		SELECT key FROM object_versions WHERE bucket_id = ? AND key LIKE ? AND key > ?
+		ORDER BY key ASC
+		LIMIT ?
+	`, bucket.ID, pattern, keyMarker, bucket.ID, pattern, keyMarker, maxKeys+1).Scan(&keys).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to list object versions", bucketName, http.StatusInternalServerError)
+		return
+	}
+
+	response := ListVersionsResult{
+		Xmlns:     "http://s3.amazonaws.com/doc/2006-03-01/",
+		Name:      bucketName,
+		Prefix:    prefix,
+		KeyMarker: keyMarker,
+		MaxKeys:   maxKeys,
+	}
+	if len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+		response.IsTruncated = true
+	}
+	if len(keys) == 0 {
+		c.XML(http.StatusOK, response)
+		return
+	}
+
+	var objects []models.Object
+	if err := database.DB.Where("bucket_id = ? AND key IN ?", bucket.ID, keys).Find(&objects).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to list object versions", bucketName, http.StatusInternalServerError)
+		return
+	}
+	var versions []models.ObjectVersion
+	if err := database.DB.Where("bucket_id = ? AND key IN ?", bucket.ID, keys).Order("created_at DESC").Find(&versions).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to list object versions", bucketName, http.StatusInternalServerError)
+		return
+	}
+
+	current := make(map[string]*models.Object, len(objects))
+	for i := range objects {
+		current[objects[i].Key] = &objects[i]
+	}
+	previous := make(map[string][]models.ObjectVersion, len(keys))
+	for _, version := range versions {
+		previous[version.Key] = append(previous[version.Key], version)
+	}
+
+	entries := 0
+	for i, key := range keys {
+		count := len(previous[key])
+		if current[key] != nil {
+			count++
+		}
+		// Stop before a key whose versions don't fit, unless it's the first
+		if i > 0 && entries+count > maxKeys {
+			response.IsTruncated = true
+			break
+		}
+		entries += count
+		response.NextKeyMarker = key
+
+		latest := true
+		if object := current[key]; object != nil {
+			response.Versions = append(response.Versions, ObjectVersionInfo{
+				Key:          key,
+				VersionID:    objectVersionID(object),
+				IsLatest:     true,
+				LastModified: object.UpdatedAt,
+				ETag:         `"` + object.ETag + `"`,
+				Size:         object.Size,
+				StorageClass: "STANDARD",
+			})
+			latest = false
+		}
+		for _, version := range previous[key] {
+			if version.IsDeleteMarker {
+				response.DeleteMarkers = append(response.DeleteMarkers, DeleteMarkerInfo{
+					Key:          key,
+					VersionID:    version.VersionID,
+					IsLatest:     latest,
+					LastModified: version.LastModified,
+				})
+			} else {
+				response.Versions = append(response.Versions, ObjectVersionInfo{
+					Key:          key,
+					VersionID:    version.VersionID,
+					IsLatest:     latest,
+					LastModified: version.LastModified,
+					ETag:         `"` + version.ETag + `"`,
+					Size:         version.Size,
+					StorageClass: "STANDARD",
+				})
+			}
+			latest = false
+		}
+	}
+	if !response.IsTruncated {
+		response.NextKeyMarker = ""
+	}
+
+	// Versions were gathered per key; S3 lists each kind in key order
+	sort.SliceStable(response.Versions, func(i, j int) bool { return response.Versions[i].Key < response.Versions[j].Key })
+	sort.SliceStable(response.DeleteMarkers, func(i, j int) bool { return response.DeleteMarkers[i].Key < response.DeleteMarkers[j].Key })
+
+	c.XML(http.StatusOK, response)
+}
+
+// findObjectVersion loads the bucket and one of a key's versions for the ?versionId requests.
+// Returns the current object when versionID names it, otherwise the previous version; writes
+// the S3 error and returns ok=false when the caller may not perform action or there's no such version
+func (h *S3APIHandler) findObjectVersion(c *gin.Context, action, versionID string) (bucket *models.Bucket, object *models.Object, version *models.ObjectVersion, ok bool) {
+	bucketName := c.Param("bucket")
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	bucket = &models.Bucket{}
+	if err := database.DB.Where("name = ?", bucketName).First(bucket).Error; err != nil {
+		h.s3Error(c, "NoSuchBucket", "The specified bucket does not exist", bucketName, http.StatusNotFound)
+		return nil, nil, nil, false
+	}
+	objectKey := bucket.NormalizeKey(strings.TrimPrefix(c.Param("key"), "/"))
+
+	// The policy is checked before the versions are looked up, so a denial doesn't reveal them
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, objectKey, action)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to check object access", objectKey, http.StatusInternalServerError)
+		return nil, nil, nil, false
+	}
+	if !allowed {
+		h.s3Error(c, "AccessDenied", "Access Denied", objectKey, http.StatusForbidden)
+		return nil, nil, nil, false
+	}
+
+	var current models.Object
+	err = database.DB.Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).First(&current).Error
+	if err == nil && objectVersionID(&current) == versionID {
+		return bucket, &current, nil, true
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		h.s3Error(c, "InternalError", "Failed to load object", objectKey, http.StatusInternalServerError)
+		return nil, nil, nil, false
+	}
+
+	version = &models.ObjectVersion{}
+	if err := database.DB.Where("bucket_id = ? AND key = ? AND version_id = ?", bucket.ID, objectKey, versionID).First(version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			h.s3Error(c, "NoSuchVersion", "The specified version does not exist", objectKey, http.StatusNotFound)
+		} else {
+			h.s3Error(c, "InternalError", "Failed to load object version", objectKey, http.StatusInternalServerError)
+		}
+		return nil, nil, nil, false
+	}
+	return bucket, nil, version, true
+}
+
+// GetObjectVersion serves GET /{bucket}/{key+}?versionId=X for a previous version. Returns
+// false, without writing a response, when X is the current version so GetObject serves it
+// (with ranges and the usual headers)
+func (h *S3APIHandler) GetObjectVersion(c *gin.Context, versionID string) bool {
+	bucket, current, version, ok := h.findObjectVersion(c, services.ActionGetObjectVersion, versionID)
+	if !ok {
+		return true
+	}
+	if current != nil {
+		return false
+	}
+	objectKey := version.Key
+
+	if version.IsDeleteMarker {
+		c.Header("x-amz-delete-marker", "true")
+		c.Header("x-amz-version-id", version.VersionID)
+		h.s3Error(c, "MethodNotAllowed", "The specified method is not allowed against a delete marker", objectKey, http.StatusMethodNotAllowed)
+		return true
+	}
+
+	storageBackend, err := h.bucketHandler.getStorageBackend(bucket)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to initialize storage", objectKey, http.StatusInternalServerError)
+		return true
+	}
+	versioner, err := storage.Versioner(storageBackend)
+	if err != nil {
+		h.s3Error(c, "NotImplemented", err.Error(), objectKey, http.StatusNotImplemented)
+		return true
+	}
+
+	releaseSlot, err := acquireDownloadSlot(c, &h.config.Storage, version.Size)
+	if err != nil {
+		h.s3Error(c, "SlowDown", "Too many concurrent downloads: "+err.Error(), objectKey, http.StatusServiceUnavailable)
+		return true
+	}
+	defer releaseSlot()
+
+	file, err := versioner.GetObjectVersion(bucket.Name, version.StorageID())
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to retrieve object version", objectKey, http.StatusInternalServerError)
+		return true
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", version.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(version.Size, 10))
+	c.Header("ETag", fmt.Sprintf(`"%s"`, version.ETag))
+	c.Header("Last-Modified", version.LastModified.UTC().Format(http.TimeFormat))
+	c.Header("x-amz-version-id", version.VersionID)
+	c.Header("x-amz-request-id", uuid.New().String())
+	if disposition := applyObjectSecurityHeaders(c, version.ContentType, "", objectKey); disposition != "" {
+		c.Header("Content-Disposition", disposition)
+	}
+
+	c.DataFromReader(http.StatusOK, version.Size, version.ContentType, file, nil)
+	return true
+}
+
+// DeleteObjectVersion handles DELETE /{bucket}/{key+}?versionId=X: permanently deletes that
+// version, without a delete marker. As on S3, deleting the current version or the delete marker
+// that hides the object makes the newest remaining version current again
+func (h *S3APIHandler) DeleteObjectVersion(c *gin.Context) {
+	versionID := c.Query("versionId")
+	bucket, current, version, ok := h.findObjectVersion(c, services.ActionDeleteObjectVersion, versionID)
+	if !ok {
+		return
+	}
+
+	storageBackend, err := h.bucketHandler.getStorageBackend(bucket)
+	if err != nil {
+		h.s3Error(c, "InternalError", "Failed to get storage backend", c.Param("key"), http.StatusInternalServerError)
+		return
+	}
+
+	objectKey := ""
+	if current != nil {
+		objectKey = current.Key
+		// Delete from storage first - MUST succeed before database delete (prevents inconsistency)
+		if err := storageBackend.DeleteObject(bucket.Name, current.Key); err != nil {
+			h.s3Error(c, "InternalError", "Failed to delete object from storage", current.Key, http.StatusInternalServerError)
+			return
+		}
+		if err := database.DB.Delete(current).Error; err != nil {
+			h.s3Error(c, "InternalError", "Failed to delete object metadata", current.Key, http.StatusInternalServerError)
+			return
+		}
+	} else {
+		objectKey = version.Key
+		if err := discardObjectVersion(storageBackend, bucket.Name, version); err != nil {
+			h.s3Error(c, "InternalError", "Failed to delete object version", version.Key, http.StatusInternalServerError)
+			return
+		}
+		if version.IsDeleteMarker {
+			c.Header("x-amz-delete-marker", "true")
+		}
+	}
+
+	if _, err := promoteLatestVersion(storageBackend, bucket, objectKey); err != nil {
+		h.s3Error(c, "InternalError", "Failed to restore the previous version", objectKey, http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("x-amz-version-id", versionID)
+	c.Header("x-amz-request-id", uuid.New().String())
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxWebsiteConfigSize caps a PUT ?website body
+	maxWebsiteConfigSize = 64 * 1024
+	// maxWebsiteRoutingRules matches S3's limit on routing rules per bucket
+	maxWebsiteRoutingRules = 50
+)
+
+// WebsiteConfiguration is the S3 ?website document
+type WebsiteConfiguration struct {
+	XMLName               xml.Name               `xml:"WebsiteConfiguration"`
+	Xmlns                 string                 `xml:"xmlns,attr,omitempty"`
+	IndexDocument         *WebsiteIndexDocument  `xml:"IndexDocument,omitempty"`
+	ErrorDocument         *WebsiteErrorDocument  `xml:"ErrorDocument,omitempty"`
+	RedirectAllRequestsTo *WebsiteRedirectAll    `xml:"RedirectAllRequestsTo,omitempty"`
+	RoutingRules          *WebsiteRoutingRuleSet `xml:"RoutingRules,omitempty"`
+}
+
+type WebsiteIndexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+type WebsiteErrorDocument struct {
+	Key string `xml:"Key"`
+}
+
+type WebsiteRedirectAll struct {
+	HostName string `xml:"HostName"`
+	Protocol string `xml:"Protocol,omitempty"`
+}
+
+type WebsiteRoutingRuleSet struct {
+	RoutingRule []WebsiteRoutingRuleXML `xml:"RoutingRule"`
+}
+
+type WebsiteRoutingRuleXML struct {
+	Condition *WebsiteRoutingCondition `xml:"Condition,omitempty"`
+	Redirect  WebsiteRoutingRedirect   `xml:"Redirect"`
+}
+
+type WebsiteRoutingCondition struct {
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+}
+
+type WebsiteRoutingRedirect struct {
+	HostName             string `xml:"HostName,omitempty"`
+	HttpRedirectCode     string `xml:"HttpRedirectCode,omitempty"`
+	Protocol             string `xml:"Protocol,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+}
+
+// GetBucketWebsite handles GET /{bucket}?website
+func (h *S3APIHandler) GetBucketWebsite(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	bucket, ok := h.loadBucketForConfig(c, bucketName, services.ActionGetBucketWebsite)
+	if !ok {
+		return
+	}
+
+	var website models.BucketWebsite
+	if err := database.DB.Where("bucket_id = ?", bucket.ID).First(&website).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			h.s3Error(c, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration", bucketName, http.StatusNotFound)
+			return
+		}
+		h.s3Error(c, "InternalError", "Failed to load website configuration", bucketName, http.StatusInternalServerError)
+		return
+	}
+
+	c.XML(http.StatusOK, websiteToXML(&website))
+}
+
+// PutBucketWebsite handles PUT /{bucket}?website. Once configured, the bucket's objects (other
+// than private-ACL ones) are served anonymously at the website endpoints
+func (h *S3APIHandler) PutBucketWebsite(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	bucket, ok := h.loadBucketForConfig(c, bucketName, services.ActionPutBucketWebsite)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebsiteConfigSize+1))
+	if err != nil {
+		h.s3Error(c, "IncompleteBody", "Failed to read request body", bucketName, http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxWebsiteConfigSize {
+		h.s3Error(c, "MalformedXML", "The website configuration is too large", bucketName, http.StatusBadRequest)
+		return
+	}
+
+	var config WebsiteConfiguration
+	if err := xml.Unmarshal(body, &config); err != nil {
+		h.s3Error(c, "MalformedXML", "The XML you provided was not well-formed", bucketName, http.StatusBadRequest)
+		return
+	}
+
+	website, err := websiteFromXML(&config)
+	if err != nil {
+		h.s3Error(c, "InvalidArgument", err.Error(), bucketName, http.StatusBadRequest)
+		return
+	}
+	website.BucketID = bucket.ID
+	website.UpdatedAt = time.Now()
+
+	if err := database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(website).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to save website configuration", bucketName, http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Bucket website configuration updated", map[string]interface{}{
+		"bucket":         bucketName,
+		"index_document": website.IndexDocument,
+		"redirect_all":   website.RedirectHostName,
+	})
+
+	c.Status(http.StatusOK)
+}
+
+// DeleteBucketWebsite handles DELETE /{bucket}?website
+func (h *S3APIHandler) DeleteBucketWebsite(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	bucket, ok := h.loadBucketForConfig(c, bucketName, services.ActionDeleteBucketWebsite)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Where("bucket_id = ?", bucket.ID).Delete(&models.BucketWebsite{}).Error; err != nil {
+		h.s3Error(c, "InternalError", "Failed to delete website configuration", bucketName, http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Bucket website configuration deleted", map[string]interface{}{
+		"bucket": bucketName,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// websiteFromXML validates an S3 website document and converts it to the stored configuration
+func websiteFromXML(config *WebsiteConfiguration) (*models.BucketWebsite, error) {
+	website := &models.BucketWebsite{RoutingRules: "[]"}
+
+	if config.RedirectAllRequestsTo != nil {
+		if config.IndexDocument != nil || config.ErrorDocument != nil || config.RoutingRules != nil {
+			return nil, fmt.Errorf("RedirectAllRequestsTo cannot be combined with other website settings")
+		}
+		if config.RedirectAllRequestsTo.HostName == "" {
+			return nil, fmt.Errorf("RedirectAllRequestsTo requires a HostName")
+		}
+		if err := validateRedirectProtocol(config.RedirectAllRequestsTo.Protocol); err != nil {
+			return nil, err
+		}
+		website.RedirectHostName = config.RedirectAllRequestsTo.HostName
+		website.RedirectProtocol = config.RedirectAllRequestsTo.Protocol
+		return website, nil
+	}
+
+	if config.IndexDocument == nil || config.IndexDocument.Suffix == "" {
+		return nil, fmt.Errorf("IndexDocument Suffix is required")
+	}
+	if strings.Contains(config.IndexDocument.Suffix, "/") {
+		return nil, fmt.Errorf("IndexDocument Suffix cannot contain a slash")
+	}
+	website.IndexDocument = config.IndexDocument.Suffix
+
+	if config.ErrorDocument != nil {
+		if config.ErrorDocument.Key == "" {
+			return nil, fmt.Errorf("ErrorDocument Key cannot be empty")
+		}
+		website.ErrorDocument = strings.TrimPrefix(config.ErrorDocument.Key, "/")
+	}
+
+	if config.RoutingRules != nil {
+		if len(config.RoutingRules.RoutingRule) > maxWebsiteRoutingRules {
+			return nil, fmt.Errorf("at most %d routing rules are allowed", maxWebsiteRoutingRules)
+		}
+
+		rules := make([]models.WebsiteRoutingRule, 0, len(config.RoutingRules.RoutingRule))
+		for i, r := range config.RoutingRules.RoutingRule {
+			rule := models.WebsiteRoutingRule{
+				HostName:             r.Redirect.HostName,
+				Protocol:             r.Redirect.Protocol,
+				ReplaceKeyPrefixWith: r.Redirect.ReplaceKeyPrefixWith,
+				ReplaceKeyWith:       r.Redirect.ReplaceKeyWith,
+				HTTPRedirectCode:     r.Redirect.HttpRedirectCode,
+			}
+			if r.Condition != nil {
+				rule.KeyPrefixEquals = r.Condition.KeyPrefixEquals
+				rule.HTTPErrorCodeReturnedEquals = r.Condition.HttpErrorCodeReturnedEquals
+			}
+			if err := validateRoutingRule(&rule); err != nil {
+				return nil, fmt.Errorf("routing rule %d: %w", i+1, err)
+			}
+			rules = append(rules, rule)
+		}
+
+		encoded, err := json.Marshal(rules)
+		if err != nil {
+			return nil, err
+		}
+		website.RoutingRules = string(encoded)
+	}
+
+	return website, nil
+}
+
+func validateRoutingRule(rule *models.WebsiteRoutingRule) error {
+	if rule.ReplaceKeyWith != "" && rule.ReplaceKeyPrefixWith != "" {
+		return fmt.Errorf("ReplaceKeyWith and ReplaceKeyPrefixWith are mutually exclusive")
+	}
+	if rule.HostName == "" && rule.Protocol == "" && rule.ReplaceKeyWith == "" &&
+		rule.ReplaceKeyPrefixWith == "" && rule.HTTPRedirectCode == "" {
+		return fmt.Errorf("Redirect must set at least one field")
+	}
+	if err := validateRedirectProtocol(rule.Protocol); err != nil {
+		return err
+	}
+	if rule.HTTPRedirectCode != "" {
+		code, err := strconv.Atoi(rule.HTTPRedirectCode)
+		if err != nil || code < 300 || code > 308 {
+			return fmt.Errorf("HttpRedirectCode must be a redirect status code (300-308)")
+		}
+	}
+	if rule.HTTPErrorCodeReturnedEquals != "" {
+		code, err := strconv.Atoi(rule.HTTPErrorCodeReturnedEquals)
+		if err != nil || code < 400 || code > 599 {
+			return fmt.Errorf("HttpErrorCodeReturnedEquals must be a 4xx or 5xx status code")
+		}
+	}
+	return nil
+}
+
+func validateRedirectProtocol(protocol string) error {
+	switch protocol {
+	case "", "http", "https":
+		return nil
+	default:
+		return fmt.Errorf("Protocol must be http or https")
+	}
+}
+
+// websiteToXML converts the stored configuration back to the S3 document
+func websiteToXML(website *models.BucketWebsite) *WebsiteConfiguration {
+	config := &WebsiteConfiguration{Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/"}
+
+	if website.RedirectHostName != "" {
+		config.RedirectAllRequestsTo = &WebsiteRedirectAll{
+			HostName: website.RedirectHostName,
+			Protocol: website.RedirectProtocol,
+		}
+		return config
+	}
+
+	config.IndexDocument = &WebsiteIndexDocument{Suffix: website.IndexDocument}
+	if website.ErrorDocument != "" {
+		config.ErrorDocument = &WebsiteErrorDocument{Key: website.ErrorDocument}
+	}
+
+	if rules := website.Rules(); len(rules) > 0 {
+		config.RoutingRules = &WebsiteRoutingRuleSet{}
+		for _, rule := range rules {
+			r := WebsiteRoutingRuleXML{
+				Redirect: WebsiteRoutingRedirect{
+					HostName:             rule.HostName,
+					HttpRedirectCode:     rule.HTTPRedirectCode,
+					Protocol:             rule.Protocol,
+					ReplaceKeyPrefixWith: rule.ReplaceKeyPrefixWith,
+					ReplaceKeyWith:       rule.ReplaceKeyWith,
+				},
+			}
+			if rule.KeyPrefixEquals != "" || rule.HTTPErrorCodeReturnedEquals != "" {
+				r.Condition = &WebsiteRoutingCondition{
+					KeyPrefixEquals:             rule.KeyPrefixEquals,
+					HttpErrorCodeReturnedEquals: rule.HTTPErrorCodeReturnedEquals,
+				}
+			}
+			config.RoutingRules.RoutingRule = append(config.RoutingRules.RoutingRule, r)
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"bkt/internal/auth"
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/models"
+	"bkt/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// serviceAccountNamePattern restricts service account names to a simple slug
+var serviceAccountNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// serviceAccountEmailDomain gives service accounts a unique placeholder email that no SSO
+// provider can ever assert (.invalid is reserved)
+const serviceAccountEmailDomain = "service-account.invalid"
+
+var errServiceAccountPolicyNotFound = errors.New("policy not found")
+
+// ServiceAccountHandler manages service accounts: non-login principals for automation that
+// authenticate with a long-lived service token and get access only through attached policies
+type ServiceAccountHandler struct {
+	config       *config.Config
+	auditService *services.AuditService
+}
+
+func NewServiceAccountHandler(cfg *config.Config) *ServiceAccountHandler {
+	return &ServiceAccountHandler{
+		config:       cfg,
+		auditService: services.NewAuditService(),
+	}
+}
+
+// ListServiceAccounts lists all service accounts with their policies (admin only)
+func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
+	accounts := make([]models.User, 0)
+	if err := database.DB.Preload("Policies").Where("is_service_account = ?", true).
+		Order("username").Find(&accounts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to fetch service accounts",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// CreateServiceAccount creates a service account, attaches the requested policies and returns
+// its token. The token is only shown once (admin only)
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	adminUserID, _ := c.Get("user_id")
+	adminUsername, _ := c.Get("username")
+
+	var req models.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !serviceAccountNamePattern.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid service account name",
+			Message: "Use letters, digits, '.', '_' and '-', starting with a letter or digit",
+		})
+		return
+	}
+
+	var existing models.User
+	if err := database.DB.Where("username = ?", req.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Name already taken",
+			Message: "A user or service account with this name already exists",
+		})
+		return
+	}
+
+	account := models.User{
+		Username:         req.Name,
+		Email:            fmt.Sprintf("%s@%s", req.Name, serviceAccountEmailDomain),
+		IsServiceAccount: true,
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&account).Error; err != nil {
+			return err
+		}
+		if len(req.PolicyIDs) == 0 {
+			return nil
+		}
+
+		var policies []models.Policy
+		if err := tx.Where("id IN ?", req.PolicyIDs).Find(&policies).Error; err != nil {
+			return err
+		}
+		if len(policies) != len(req.PolicyIDs) {
+			return errServiceAccountPolicyNotFound
+		}
+		return tx.Model(&account).Association("Policies").Append(&policies)
+	})
+	if errors.Is(err, errServiceAccountPolicyNotFound) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Policy not found",
+			Message: "One or more policy_ids do not exist",
+		})
+		return
+	}
+	if err != nil {
+		h.auditService.LogFailure(c, adminUserID.(uuid.UUID), adminUsername.(string),
+			"CreateServiceAccount", "ServiceAccount", "", req.Name, err.Error(), nil)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create service account",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	token, expiresAt, err := auth.GenerateServiceAccountToken(account.ID, account.Username, h.config.Auth)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to generate token",
+			Message: "The service account was created; issue a token via POST /api/service-accounts/" + account.ID.String() + "/token",
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, adminUserID.(uuid.UUID), adminUsername.(string),
+		"CreateServiceAccount", "ServiceAccount", account.ID.String(), account.Username,
+		map[string]interface{}{"policy_ids": req.PolicyIDs})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"service_account": account,
+		"token":           token,
+		"expires_at":      expiresAt,
+		"message":         "Save this token now. It will not be shown again.",
+	})
+}
+
+// IssueServiceAccountToken rotates a service account's token: every token issued so far is
+// revoked and a new one is returned (admin only)
+func (h *ServiceAccountHandler) IssueServiceAccountToken(c *gin.Context) {
+	adminUserID, _ := c.Get("user_id")
+	adminUsername, _ := c.Get("username")
+
+	account, ok := h.loadServiceAccount(c)
+	if !ok {
+		return
+	}
+	if account.IsLocked {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Service account revoked",
+			Message: "Revoked service accounts can't be issued new tokens",
+		})
+		return
+	}
+
+	if err := auth.RevokeUserTokens(account.ID, h.config.Auth.ServiceAccountTokenDuration); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to revoke previous tokens",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	token, expiresAt, err := auth.GenerateServiceAccountToken(account.ID, account.Username, h.config.Auth)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to generate token",
+			Message: "An internal error occurred. Please try again.",
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, adminUserID.(uuid.UUID), adminUsername.(string),
+		"IssueServiceAccountToken", "ServiceAccount", account.ID.String(), account.Username, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+		"message":    "Previous tokens were revoked. Save this token now. It will not be shown again.",
+	})
+}
+
+// RevokeServiceAccount disables a service account: its tokens stop working immediately and its
+// access keys are deactivated. The account row is kept so audit history stays attributable (admin only)
+func (h *ServiceAccountHandler) RevokeServiceAccount(c *gin.Context) {
+	adminUserID, _ := c.Get("user_id")
+	adminUsername, _ := c.Get("username")
+
+	account, ok := h.loadServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account).Update("is_locked", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.AccessKey{}).Where("user_id = ?", account.ID).Update("is_active", false).Error
+	})
+	if err == nil {
+		err = auth.RevokeUserTokens(account.ID, h.config.Auth.ServiceAccountTokenDuration)
+	}
+	if err != nil {
+		h.auditService.LogFailure(c, adminUserID.(uuid.UUID), adminUsername.(string),
+			"RevokeServiceAccount", "ServiceAccount", account.ID.String(), account.Username, err.Error(), nil)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to revoke service account",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(c, adminUserID.(uuid.UUID), adminUsername.(string),
+		"RevokeServiceAccount", "ServiceAccount", account.ID.String(), account.Username, nil)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Service account revoked",
+	})
+}
+
+// loadServiceAccount looks up the service account named by the :id parameter, writing the error response if it fails
+func (h *ServiceAccountHandler) loadServiceAccount(c *gin.Context) (models.User, bool) {
+	var account models.User
+
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid service account ID",
+		})
+		return account, false
+	}
+
+	if err := database.DB.Where("id = ? AND is_service_account = ?", accountID, true).First(&account).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Service account not found",
+		})
+		return account, false
+	}
+	return account, true
+}
@@ -21,6 +21,9 @@ type SSOConfigResponse struct {
 	GoogleAuthURL string `json:"google_auth_url,omitempty"`
 	VaultEnabled  bool   `json:"vault_enabled"`
 	VaultAuthURL  string `json:"vault_auth_url,omitempty"`
+
+	// The web UI should exchange its tokens for HttpOnly cookies after login
+	CookieSessions bool `json:"cookie_sessions"`
 }
 
 // GetSSOConfig returns the SSO configuration for the frontend
@@ -31,6 +34,8 @@ func (h *SSOConfigHandler) GetSSOConfig(c *gin.Context) {
 	response := SSOConfigResponse{
 		GoogleEnabled: h.config.GoogleSSO.OIDCEnabled,
 		VaultEnabled:  vaultEnabled,
+
+		CookieSessions: h.config.Auth.CookieSessions,
 	}
 
 	// Only include auth URL if Google OIDC is enabled
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// insufficientStorageMessage tells clients what they can do about a full or over-quota backend
+const insufficientStorageMessage = "The bucket's storage backend is out of space or over its quota. " +
+	"Delete objects you no longer need or ask an administrator to add capacity, then retry."
+
+// logInsufficientStorage records a write rejected for lack of space at warning level, for alerting
+func logInsufficientStorage(bucketName, objectKey string, err error) {
+	logger.Warn("Storage backend out of space", map[string]interface{}{
+		"bucket": bucketName,
+		"key":    objectKey,
+		"error":  err.Error(),
+	})
+}
+
+// checkStorageSpace rejects a write of a known size up front when the bucket's backend reports it
+// can't hold it. Returns false after writing a 507 response
+func (h *BucketHandler) checkStorageSpace(c *gin.Context, bucket *models.Bucket, objectKey string, size int64) bool {
+	storageBackend, err := h.getStorageBackend(bucket)
+	if err != nil {
+		return true // Backend errors are reported by the write itself
+	}
+	if err := storage.CheckSpace(storageBackend, bucket.Name, size); err != nil {
+		logInsufficientStorage(bucket.Name, objectKey, err)
+		c.JSON(http.StatusInsufficientStorage, models.ErrorResponse{
+			Error:   "Insufficient storage",
+			Message: insufficientStorageMessage,
+		})
+		return false
+	}
+	return true
+}
+
+// respondStorageWriteError answers a failed storage write: out-of-space and quota failures get a 507
+// with an actionable message, anything else a 500 with the given error title
+func respondStorageWriteError(c *gin.Context, bucketName, objectKey string, err error, title string) {
+	if errors.Is(err, storage.ErrInsufficientStorage) {
+		logInsufficientStorage(bucketName, objectKey, err)
+		c.JSON(http.StatusInsufficientStorage, models.ErrorResponse{
+			Error:   "Insufficient storage",
+			Message: insufficientStorageMessage,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:   title,
+		Message: err.Error(),
+	})
+}
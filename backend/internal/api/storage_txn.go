@@ -0,0 +1,76 @@
+package api
+
+import (
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// storageTxn ties a handler's storage writes to the database transaction holding its metadata
+// changes. Storage writes can't be rolled back, so handlers order them around the commit:
+//   - additive writes (copies, new content) happen inside the transaction, or before it when
+//     there are many of them, each registering an undo that runs if the transaction fails
+//   - destructive writes (deleting the old copy) are deferred with afterCommit, so they only
+//     happen once the database points at the new data
+//
+// A failure therefore leaves either the old state or the new one. The worst case is a leftover
+// source copy when a deferred delete fails, which is logged
+type storageTxn struct {
+	undos       []func()
+	afterCommit []storageTxnStep
+}
+
+type storageTxnStep struct {
+	description string
+	run         func() error
+}
+
+// onRollback registers the compensation for a storage write that has already happened
+func (t *storageTxn) onRollback(undo func()) {
+	t.undos = append(t.undos, undo)
+}
+
+// deferUntilCommit queues a storage write to run after the transaction commits
+func (t *storageTxn) deferUntilCommit(description string, run func() error) {
+	t.afterCommit = append(t.afterCommit, storageTxnStep{description: description, run: run})
+}
+
+// runStorageTxn runs fn in a database transaction. If fn fails or the commit does, registered
+// compensations run in reverse order; otherwise the deferred storage writes run in order
+func runStorageTxn(fn func(tx *gorm.DB, st *storageTxn) error) error {
+	st := &storageTxn{}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(tx, st)
+	}); err != nil {
+		for i := len(st.undos) - 1; i >= 0; i-- {
+			st.undos[i]()
+		}
+		return err
+	}
+
+	for _, step := range st.afterCommit {
+		if err := step.run(); err != nil {
+			logger.Warn("Storage cleanup after commit failed", map[string]interface{}{
+				"step":  step.description,
+				"error": err.Error(),
+			})
+		}
+	}
+	return nil
+}
+
+// discardUnsavedObject is the rollback for a write whose metadata save failed: the new content is
+// deleted only if the key had no object before. An overwritten object's record still points at
+// the key, so its content is left in place rather than deleting the object outright (versioned
+// buckets put the previous content back from the archived version)
+func discardUnsavedObject(backend storage.StorageBackend, bucketID uuid.UUID, bucketName, objectKey string) {
+	var count int64
+	if err := database.DB.Model(&models.Object{}).Where("bucket_id = ? AND key = ?", bucketID, objectKey).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	backend.DeleteObject(bucketName, objectKey)
+}
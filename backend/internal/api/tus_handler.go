@@ -0,0 +1,625 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/middleware"
+	"bkt/internal/models"
+	"bkt/internal/services"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// tus resumable upload protocol (https://tus.io/protocols/resumable-upload)
+// Supports the core protocol plus the creation, termination and expiration extensions
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+
+	// maxPendingTusUploads limits how many unfinished resumable uploads a user can hold
+	// (each reserves a staging file on disk)
+	maxPendingTusUploads = 20
+
+	// tusUploadExpiry is how long an unfinished upload is kept after it last received data;
+	// StartTusUploadCleanup discards it and its staging file after that
+	tusUploadExpiry = 24 * time.Hour
+)
+
+// tusLocks prevents concurrent PATCH requests from writing to the same upload
+var (
+	tusLocks   = make(map[uuid.UUID]bool)
+	tusLocksMu sync.Mutex
+)
+
+func acquireTusLock(id uuid.UUID) bool {
+	tusLocksMu.Lock()
+	defer tusLocksMu.Unlock()
+	if tusLocks[id] {
+		return false
+	}
+	tusLocks[id] = true
+	return true
+}
+
+func releaseTusLock(id uuid.UUID) {
+	tusLocksMu.Lock()
+	defer tusLocksMu.Unlock()
+	delete(tusLocks, id)
+}
+
+// TusOptions advertises server capabilities (OPTIONS /api/uploads/tus)
+func (h *BucketHandler) TusOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.config.Storage.MaxFileSize, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// TusCreateUpload creates a new resumable upload (POST /api/uploads/tus)
+// Upload-Metadata must include "bucket" and "key" (or "filename"), and may include "acl" and
+// "filetype" (the declared content type, honored only if it is in TRUSTED_CONTENT_TYPES)
+func (h *BucketHandler) TusCreateUpload(c *gin.Context) {
+	if !h.checkTusVersion(c) {
+		return
+	}
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	// Deferred length is not supported - the total size must be known up front
+	totalSize, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || totalSize < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid Upload-Length",
+			Message: "Upload-Length header must be a non-negative integer",
+		})
+		return
+	}
+
+	if totalSize > h.config.Storage.MaxFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error:   "File too large",
+			Message: fmt.Sprintf("Maximum file size is %d bytes", h.config.Storage.MaxFileSize),
+		})
+		return
+	}
+
+	metadata, err := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid Upload-Metadata",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	bucketName := metadata["bucket"]
+	objectKey := metadata["key"]
+	if objectKey == "" {
+		objectKey = metadata["filename"]
+	}
+	if bucketName == "" || objectKey == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Upload-Metadata must include bucket and key",
+		})
+		return
+	}
+
+	// Get bucket from database
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	// Validate object key
+	if err := validation.ValidateObjectKey(objectKey); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid object key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Case-insensitive buckets fold keys to lowercase
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	// Auto-date-prefix buckets store uploads under the upload date (e.g. 2026/10/16/)
+	objectKey, err = applyAutoDatePrefix(&bucket, objectKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid object key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Governance key rules (e.g. only logs/*.gz), checked against the stored key
+	if !respondKeyRules(c, &bucket, objectKey) {
+		return
+	}
+
+	acl, err := models.ParseObjectACL(metadata["acl"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid ACL",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Optional per-object TTL ("expires_after" / "expires_at" metadata)
+	expiresAt, err := parseObjectExpiry(metadata["expires_after"], metadata["expires_at"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid expiration",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Check policy permissions
+	allowed, err := h.policyService.CheckObjectAccess(c, userUUID, bucketName, objectKey, services.ActionPutObject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Policy check failed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Permission denied",
+			Message: "You don't have permission to upload objects to this bucket",
+		})
+		return
+	}
+
+	// Recently created objects may be protected from overwrites
+	if err := checkOverwriteWindow(c, &bucket, objectKey); err != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Object is overwrite-protected",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// New keys can't push the bucket past its object count cap
+	if !respondObjectLimit(c, &bucket, objectKey) {
+		return
+	}
+
+	// Don't accept an upload the bucket's backend has no room for
+	if !h.checkStorageSpace(c, &bucket, objectKey, totalSize) {
+		return
+	}
+
+	// Limit unfinished uploads per user so staging files can't exhaust disk
+	var pending int64
+	database.DB.Model(&models.Upload{}).
+		Where("user_id = ? AND status = ? AND temp_path <> ''", userUUID, models.UploadStatusPending).
+		Count(&pending)
+	if pending >= maxPendingTusUploads {
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+			Error:   "Too many pending uploads",
+			Message: fmt.Sprintf("Complete or cancel existing uploads first (limit %d)", maxPendingTusUploads),
+		})
+		return
+	}
+
+	filename := metadata["filename"]
+	if filename == "" {
+		filename = filepath.Base(objectKey)
+	}
+
+	upload := models.Upload{
+		ID:          uuid.New(),
+		UserID:      userUUID,
+		BucketName:  bucketName,
+		ObjectKey:   objectKey,
+		Filename:    filename,
+		ContentType: metadata["filetype"], // Resolved against the detected type once assembled
+		ACL:         acl,
+		ExpiresAt:   expiresAt,
+		TotalSize:   totalSize,
+		Status:      models.UploadStatusPending,
+	}
+
+	// Create empty staging file (same temp layout as async uploads)
+	tempDir := filepath.Join(os.TempDir(), "bkt-uploads", upload.ID.String())
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create temporary directory",
+			Message: err.Error(),
+		})
+		return
+	}
+	upload.TempPath = filepath.Join(tempDir, "data")
+	if err := os.WriteFile(upload.TempPath, nil, 0600); err != nil {
+		os.RemoveAll(tempDir)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create staging file",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := database.DB.Create(&upload).Error; err != nil {
+		os.RemoveAll(tempDir)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create upload record",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Zero-length uploads are complete immediately
+	if totalSize == 0 {
+		h.finishTusUpload(&upload, &bucket)
+	}
+
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Location", "/api/uploads/tus/"+upload.ID.String())
+	c.Header("Upload-Offset", "0")
+	if upload.Status == models.UploadStatusPending {
+		setTusUploadExpires(c, &upload)
+	}
+	c.Status(http.StatusCreated)
+}
+
+// TusHeadUpload returns the current offset of an upload (HEAD /api/uploads/tus/:id)
+func (h *BucketHandler) TusHeadUpload(c *gin.Context) {
+	if !h.checkTusVersion(c) {
+		return
+	}
+
+	upload, ok := h.getTusUpload(c)
+	if !ok {
+		return
+	}
+
+	// Once the data is fully received, the upload offset is the total size
+	// (UploadedSize is reused for storage progress while processing)
+	offset := upload.UploadedSize
+	if upload.Status != models.UploadStatusPending {
+		offset = upload.TotalSize
+	}
+
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.TotalSize, 10))
+	if upload.Status == models.UploadStatusPending {
+		setTusUploadExpires(c, upload)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// TusPatchUpload appends a chunk at the given offset (PATCH /api/uploads/tus/:id)
+func (h *BucketHandler) TusPatchUpload(c *gin.Context) {
+	if !h.checkTusVersion(c) {
+		return
+	}
+
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{
+			Error: "Content-Type must be application/offset+octet-stream",
+		})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid Upload-Offset",
+		})
+		return
+	}
+
+	upload, ok := h.getTusUpload(c)
+	if !ok {
+		return
+	}
+	c.Set(middleware.UsageBucketKey, upload.BucketName)
+
+	// Only one writer per upload at a time
+	if !acquireTusLock(upload.ID) {
+		c.JSON(http.StatusLocked, models.ErrorResponse{
+			Error: "Upload is already being written by another request",
+		})
+		return
+	}
+	defer releaseTusLock(upload.ID)
+
+	// Re-read under lock so the offset is current
+	if err := database.DB.First(upload, "id = ?", upload.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Upload not found",
+		})
+		return
+	}
+
+	if upload.Status != models.UploadStatusPending {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Upload is no longer accepting data",
+		})
+		return
+	}
+
+	if offset != upload.UploadedSize {
+		c.Header("Upload-Offset", strconv.FormatInt(upload.UploadedSize, 10))
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Offset mismatch",
+			Message: fmt.Sprintf("Expected Upload-Offset %d", upload.UploadedSize),
+		})
+		return
+	}
+
+	remaining := upload.TotalSize - offset
+	if c.Request.ContentLength > remaining {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error:   "Chunk exceeds Upload-Length",
+			Message: fmt.Sprintf("Only %d bytes remain", remaining),
+		})
+		return
+	}
+
+	file, err := os.OpenFile(upload.TempPath, os.O_WRONLY, 0600)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to open staging file",
+			Message: err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	// Discard any bytes beyond the recorded offset (e.g. from an interrupted write)
+	if err := file.Truncate(offset); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to prepare staging file",
+			Message: err.Error(),
+		})
+		return
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to prepare staging file",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Write as much as arrives - a dropped connection keeps the bytes received so far
+	written, copyErr := io.Copy(file, io.LimitReader(c.Request.Body, remaining))
+	if err := file.Sync(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+
+	// Receiving data pushes the upload's expiry back
+	newOffset := offset + written
+	upload.UploadedSize = newOffset
+	upload.UpdatedAt = time.Now()
+	database.DB.Model(upload).Updates(map[string]interface{}{"uploaded_size": newOffset, "updated_at": upload.UpdatedAt})
+
+	if copyErr != nil {
+		logger.Warn("Resumable upload chunk interrupted", map[string]interface{}{
+			"upload_id": upload.ID,
+			"offset":    newOffset,
+			"error":     copyErr.Error(),
+		})
+		c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Upload interrupted, resume from Upload-Offset",
+		})
+		return
+	}
+
+	// All bytes received - assemble into the storage backend
+	if newOffset == upload.TotalSize {
+		var bucket models.Bucket
+		if err := database.DB.Where("name = ?", upload.BucketName).First(&bucket).Error; err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Bucket not found",
+			})
+			return
+		}
+		h.finishTusUpload(upload, &bucket)
+	}
+
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	if upload.Status == models.UploadStatusPending {
+		setTusUploadExpires(c, upload)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TusDeleteUpload cancels an unfinished upload (DELETE /api/uploads/tus/:id)
+func (h *BucketHandler) TusDeleteUpload(c *gin.Context) {
+	if !h.checkTusVersion(c) {
+		return
+	}
+
+	upload, ok := h.getTusUpload(c)
+	if !ok {
+		return
+	}
+
+	if !acquireTusLock(upload.ID) {
+		c.JSON(http.StatusLocked, models.ErrorResponse{
+			Error: "Upload is being written by another request",
+		})
+		return
+	}
+	defer releaseTusLock(upload.ID)
+
+	if upload.Status != models.UploadStatusPending {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Upload has already completed or is being processed",
+		})
+		return
+	}
+
+	os.RemoveAll(filepath.Dir(upload.TempPath))
+	database.DB.Delete(upload)
+
+	c.Header("Tus-Resumable", tusVersion)
+	c.Status(http.StatusNoContent)
+}
+
+// finishTusUpload hands the assembled staging file to the async upload pipeline
+// (content-type validation, storage upload, hashing, object record)
+func (h *BucketHandler) finishTusUpload(upload *models.Upload, bucket *models.Bucket) {
+	upload.Status = models.UploadStatusProcessing
+	database.DB.Model(upload).Update("status", models.UploadStatusProcessing)
+
+	h.enqueueAsyncUpload(upload, upload.TempPath, bucket)
+}
+
+// getTusUpload loads a resumable upload owned by the current user
+func (h *BucketHandler) getTusUpload(c *gin.Context) (*models.Upload, bool) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	uploadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Upload not found",
+		})
+		return nil, false
+	}
+
+	var upload models.Upload
+	if err := database.DB.Where("id = ? AND user_id = ? AND temp_path <> ''", uploadID, userUUID).First(&upload).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Upload not found",
+		})
+		return nil, false
+	}
+
+	// Expired uploads are gone as far as the client is concerned, even before the sweeper runs
+	if upload.Status == models.UploadStatusPending && time.Now().After(tusUploadExpires(&upload)) {
+		c.JSON(http.StatusGone, models.ErrorResponse{
+			Error:   "Upload expired",
+			Message: "The upload received no data for too long; start a new one",
+		})
+		return nil, false
+	}
+
+	return &upload, true
+}
+
+// tusUploadExpires returns when an unfinished upload expires: tusUploadExpiry after it last received data
+func tusUploadExpires(upload *models.Upload) time.Time {
+	return upload.UpdatedAt.Add(tusUploadExpiry)
+}
+
+// setTusUploadExpires sends the Upload-Expires header of the expiration extension
+func setTusUploadExpires(c *gin.Context, upload *models.Upload) {
+	c.Header("Upload-Expires", tusUploadExpires(upload).UTC().Format(http.TimeFormat))
+}
+
+// StartTusUploadCleanup periodically discards unfinished resumable uploads that received no data
+// for tusUploadExpiry, with their staging files, so abandoned uploads don't hold disk forever
+func StartTusUploadCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// Writes are frozen in maintenance mode; expired uploads are discarded once it ends
+			if middleware.GetMaintenanceMode().Enabled {
+				continue
+			}
+			discardExpiredTusUploads()
+		}
+	}()
+}
+
+// discardExpiredTusUploads deletes every pending tus upload past its expiry and its staging file
+func discardExpiredTusUploads() {
+	cutoff := time.Now().Add(-tusUploadExpiry)
+	var expired []models.Upload
+	if err := database.DB.Where("status = ? AND temp_path <> '' AND updated_at < ?", models.UploadStatusPending, cutoff).
+		Find(&expired).Error; err != nil {
+		logger.Warn("Failed to load expired resumable uploads", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	for i := range expired {
+		upload := &expired[i]
+		// An upload being written right now isn't abandoned; the next sweep rechecks it
+		if !acquireTusLock(upload.ID) {
+			continue
+		}
+		// Only delete the staging file if the row was still pending and stale under the lock
+		result := database.DB.Where("id = ? AND status = ? AND updated_at < ?", upload.ID, models.UploadStatusPending, cutoff).
+			Delete(&models.Upload{})
+		if result.Error != nil {
+			logger.Warn("Failed to discard expired resumable upload", map[string]interface{}{
+				"upload_id": upload.ID,
+				"error":     result.Error.Error(),
+			})
+		} else if result.RowsAffected == 1 {
+			os.RemoveAll(filepath.Dir(upload.TempPath))
+		}
+		releaseTusLock(upload.ID)
+	}
+}
+
+// checkTusVersion validates the Tus-Resumable header
+func (h *BucketHandler) checkTusVersion(c *gin.Context) bool {
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.JSON(http.StatusPreconditionFailed, models.ErrorResponse{
+			Error:   "Unsupported tus version",
+			Message: fmt.Sprintf("Tus-Resumable must be %s", tusVersion),
+		})
+		return false
+	}
+	return true
+}
+
+// parseTusMetadata decodes the Upload-Metadata header ("key base64value,key2 base64value2")
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if header == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, " ", 2)
+		if len(parts) == 1 {
+			metadata[parts[0]] = ""
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("metadata value for %q is not valid base64", parts[0])
+		}
+		metadata[parts[0]] = string(value)
+	}
+
+	return metadata, nil
+}
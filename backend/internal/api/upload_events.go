@@ -0,0 +1,175 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// uploadEventInterval is the minimum time between progress events for one upload
+const uploadEventInterval = 100 * time.Millisecond
+
+// uploadEventKeepalive is how often an idle event stream sends a comment to keep proxies from closing it
+const uploadEventKeepalive = 15 * time.Second
+
+// uploadEvent is pushed to subscribers of an upload's event stream
+type uploadEvent struct {
+	UploadID     uuid.UUID           `json:"upload_id"`
+	Status       models.UploadStatus `json:"status"`
+	TotalSize    int64               `json:"total_size"`
+	UploadedSize int64               `json:"uploaded_size"`
+	ProgressPct  float64             `json:"progress_percent"`
+	ErrorMessage string              `json:"error_message,omitempty"`
+	Object       *models.Object      `json:"object,omitempty"` // Set on the completed event
+}
+
+// final reports whether no further events follow this one
+func (e uploadEvent) final() bool {
+	return e.Status == models.UploadStatusCompleted || e.Status == models.UploadStatusFailed ||
+		e.Status == models.UploadStatusQuarantined
+}
+
+// newUploadEvent builds an event from an upload record
+func newUploadEvent(upload *models.Upload) uploadEvent {
+	return uploadEvent{
+		UploadID:     upload.ID,
+		Status:       upload.Status,
+		TotalSize:    upload.TotalSize,
+		UploadedSize: upload.UploadedSize,
+		ProgressPct:  progressPercent(upload.UploadedSize, upload.TotalSize),
+		ErrorMessage: upload.ErrorMessage,
+	}
+}
+
+func progressPercent(uploaded, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(uploaded) / float64(total) * 100
+}
+
+// In-memory pub/sub of upload events keyed by upload ID (events are per-instance;
+// clients connected to another instance fall back to polling the status endpoint)
+var (
+	uploadSubscribers   = make(map[uuid.UUID]map[chan uploadEvent]struct{})
+	uploadSubscribersMu sync.Mutex
+)
+
+// subscribeUploadEvents registers a listener for an upload; call the returned func to unsubscribe
+func subscribeUploadEvents(uploadID uuid.UUID) (chan uploadEvent, func()) {
+	ch := make(chan uploadEvent, 16)
+
+	uploadSubscribersMu.Lock()
+	subs, exists := uploadSubscribers[uploadID]
+	if !exists {
+		subs = make(map[chan uploadEvent]struct{})
+		uploadSubscribers[uploadID] = subs
+	}
+	subs[ch] = struct{}{}
+	uploadSubscribersMu.Unlock()
+
+	return ch, func() {
+		uploadSubscribersMu.Lock()
+		defer uploadSubscribersMu.Unlock()
+		delete(subs, ch)
+		if len(subs) == 0 {
+			delete(uploadSubscribers, uploadID)
+		}
+	}
+}
+
+// publishUploadEvent delivers an event without blocking the upload. A slow subscriber misses
+// intermediate progress events, but the final event always replaces the oldest queued one
+func publishUploadEvent(event uploadEvent) {
+	uploadSubscribersMu.Lock()
+	defer uploadSubscribersMu.Unlock()
+
+	for ch := range uploadSubscribers[event.UploadID] {
+		select {
+		case ch <- event:
+			continue
+		default:
+		}
+		if !event.final() {
+			continue
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// StreamUploadEvents pushes progress for an upload as server-sent events until it completes or fails.
+// The first event is the current state; the stream ends after the final completed/failed event
+func (h *BucketHandler) StreamUploadEvents(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	uploadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid upload ID",
+		})
+		return
+	}
+
+	// Subscribe before reading the current state so no event falls in between
+	events, unsubscribe := subscribeUploadEvents(uploadID)
+	defer unsubscribe()
+
+	var upload models.Upload
+	if err := database.DB.Where("id = ? AND user_id = ?", uploadID, userUUID).First(&upload).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Upload not found",
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable nginx response buffering
+
+	current := newUploadEvent(&upload)
+	if current.Status == models.UploadStatusCompleted && upload.ObjectID != nil {
+		var object models.Object
+		if database.DB.First(&object, *upload.ObjectID).Error == nil {
+			current.Object = &object
+		}
+	}
+	c.SSEvent(string(current.Status), current)
+	c.Writer.Flush()
+	if current.final() {
+		return
+	}
+
+	keepalive := time.NewTicker(uploadEventKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			c.SSEvent(string(event.Status), event)
+			c.Writer.Flush()
+			if event.final() {
+				return
+			}
+		case <-keepalive.C:
+			c.Writer.Write([]byte(": keepalive\n\n"))
+			c.Writer.Flush()
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// asyncUploadJob is a background upload waiting for (or holding) a worker slot
+type asyncUploadJob struct {
+	id  uuid.UUID
+	run func()
+}
+
+// asyncUploadQueue bounds how many async/resumable uploads are pushed to storage at once.
+// Excess uploads wait in FIFO order and start as running ones finish
+type asyncUploadQueue struct {
+	mu      sync.Mutex
+	limit   int // <= 0 means unlimited
+	active  int
+	pending []asyncUploadJob
+}
+
+// Shared by every BucketHandler so the limit is server-wide
+var (
+	asyncUploads     *asyncUploadQueue
+	asyncUploadsOnce sync.Once
+)
+
+// getAsyncUploadQueue returns the server-wide upload queue, sizing it on first use
+func getAsyncUploadQueue(limit int) *asyncUploadQueue {
+	asyncUploadsOnce.Do(func() {
+		asyncUploads = &asyncUploadQueue{limit: limit}
+	})
+	return asyncUploads
+}
+
+// submit starts the job if a slot is free, otherwise queues it. onQueued runs (under the
+// queue lock, before the job can be dequeued) only when the job has to wait.
+// Returns the job's 1-based queue position, or 0 if it started immediately
+func (q *asyncUploadQueue) submit(id uuid.UUID, run func(), onQueued func()) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job := asyncUploadJob{id: id, run: run}
+	if q.limit <= 0 || q.active < q.limit {
+		q.active++
+		go q.execute(job)
+		return 0
+	}
+
+	if onQueued != nil {
+		onQueued()
+	}
+	q.pending = append(q.pending, job)
+	return len(q.pending)
+}
+
+// execute runs a job and hands its slot to the next queued job
+func (q *asyncUploadQueue) execute(job asyncUploadJob) {
+	defer q.release()
+	job.run()
+}
+
+func (q *asyncUploadQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) == 0 {
+		q.active--
+		return
+	}
+
+	// The slot passes straight to the next job, so active stays the same
+	next := q.pending[0]
+	q.pending = q.pending[1:]
+	go q.execute(next)
+}
+
+// position returns the 1-based queue position of an upload, or 0 if it isn't waiting
+func (q *asyncUploadQueue) position(id uuid.UUID) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, job := range q.pending {
+		if job.id == id {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxUsageRangeDays bounds the reporting window to keep queries cheap
+const maxUsageRangeDays = 366
+
+type UsageHandler struct {
+	config *config.Config
+}
+
+func NewUsageHandler(cfg *config.Config) *UsageHandler {
+	return &UsageHandler{config: cfg}
+}
+
+// GetUsage returns bandwidth usage filtered by user, bucket and date range (admin only)
+// Defaults to the current calendar month
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	var userID *uuid.UUID
+	if value := c.Query("user_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid user ID",
+			})
+			return
+		}
+		userID = &parsed
+	}
+
+	h.respondWithUsage(c, userID)
+}
+
+// GetMyUsage returns bandwidth usage for the current user
+func (h *UsageHandler) GetMyUsage(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userUUID := userID.(uuid.UUID)
+
+	h.respondWithUsage(c, &userUUID)
+}
+
+// respondWithUsage aggregates usage_stats rows for the requested window
+func (h *UsageHandler) respondWithUsage(c *gin.Context, userID *uuid.UUID) {
+	from, to, err := parseUsageRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid date range",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	query := database.DB.Model(&models.UsageStat{}).Where("day >= ? AND day <= ?", from, to)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	if bucket := c.Query("bucket"); bucket != "" {
+		query = query.Where("bucket_name = ?", bucket)
+	}
+
+	var stats []models.UsageStat
+	if err := query.Order("day ASC, bucket_name ASC").Find(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to load usage",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	summary := models.UsageSummary{
+		From:  from.Format("2006-01-02"),
+		To:    to.Format("2006-01-02"),
+		Daily: stats,
+	}
+	for _, stat := range stats {
+		summary.BytesIn += stat.BytesIn
+		summary.BytesOut += stat.BytesOut
+		summary.Requests += stat.Requests
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// parseUsageRange parses from/to dates (YYYY-MM-DD), defaulting to the current month
+func parseUsageRange(fromValue, toValue string) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if fromValue != "" {
+		parsed, err := time.Parse("2006-01-02", fromValue)
+		if err != nil {
+			return from, to, err
+		}
+		from = parsed
+	}
+	if toValue != "" {
+		parsed, err := time.Parse("2006-01-02", toValue)
+		if err != nil {
+			return from, to, err
+		}
+		to = parsed
+	}
+
+	if to.Before(from) {
+		return from, to, fmt.Errorf("'to' must not be before 'from'")
+	}
+	if to.Sub(from) > maxUsageRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("range cannot exceed %d days", maxUsageRangeDays)
+	}
+
+	return from, to, nil
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"bkt/internal/auth"
@@ -11,6 +13,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserHandler struct {
@@ -176,6 +180,9 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
+	// Org-wide onboarding defaults (policies, access key limit); adjustable per user afterwards
+	auth.ApplyDefaultUserProfile(&user, &h.config.Auth, false)
+
 	// Get admin user info for audit log
 	adminUserID, _ := c.Get("user_id")
 	adminUsername, _ := c.Get("username")
@@ -193,6 +200,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 			"target_username": user.Username,
 			"target_email":    user.Email,
 			"is_admin":        user.IsAdmin,
+			"max_access_keys": user.MaxAccessKeys,
 		},
 	)
 
@@ -202,10 +210,27 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 }
 
 func (h *UserHandler) ListUsers(c *gin.Context) {
+	page, ok := bindPageParams(c, 0, maxPageLimit)
+	if !ok {
+		return
+	}
+
 	users := make([]models.User, 0)
 	// Don't preload Policies to avoid memory issues when there are many users
 	// Use dedicated policy endpoints if policy details are needed
-	if err := database.DB.Find(&users).Error; err != nil {
+	// Service accounts are listed separately (GET /api/service-accounts)
+	query := database.DB.Model(&models.User{}).Where("is_service_account = ?", false).Session(&gorm.Session{})
+	var total int64
+	if page.envelope {
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to fetch users",
+				Message: "An internal error occurred. Please try again.",
+			})
+			return
+		}
+	}
+	if err := page.apply(query).Order("created_at ASC").Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to fetch users",
 			Message: "An internal error occurred. Please try again.",
@@ -213,7 +238,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	respondPage(c, page, users, len(users), total)
 }
 
 func (h *UserHandler) DeleteUser(c *gin.Context) {
@@ -235,7 +260,48 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&models.User{}, "id = ?", userID).Error; err != nil {
+	// Delete in a transaction that locks the admin rows, so two concurrent deletes
+	// can't each see the other admin and remove both
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if targetUser.IsAdmin {
+			if err := ensureAnotherAdmin(tx, userID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("created_by = ?", userID).Delete(&models.ShareLink{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserObjectAccess{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, "id = ?", userID).Error
+	})
+	if err == errLastAdmin {
+		adminUserID, _ := c.Get("user_id")
+		adminUsername, _ := c.Get("username")
+
+		h.auditService.LogDenied(
+			c,
+			adminUserID.(uuid.UUID),
+			adminUsername.(string),
+			"DeleteUser",
+			"User",
+			userID.String(),
+			targetUser.Username,
+			"Cannot delete the last admin user",
+			map[string]interface{}{
+				"target_username": targetUser.Username,
+				"is_admin":        true,
+			},
+		)
+
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Cannot delete the last admin user",
+			Message: "Create or promote another admin before deleting this one",
+		})
+		return
+	}
+	if err != nil {
 		// Get admin user info for audit log
 		adminUserID, _ := c.Get("user_id")
 		adminUsername, _ := c.Get("username")
@@ -263,6 +329,8 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	services.InvalidateUserPolicyCache(userID)
+
 	// Get admin user info for audit log
 	adminUserID, _ := c.Get("user_id")
 	adminUsername, _ := c.Get("username")
@@ -287,7 +355,31 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	})
 }
 
-// LockUser locks a user account to prevent login
+// errLastAdmin is returned when a change would leave no active admin
+var errLastAdmin = errors.New("cannot remove the last admin user")
+
+// errServiceAccountAdmin is returned when promoting a service account; their access comes from policies only
+var errServiceAccountAdmin = errors.New("service accounts cannot be admins")
+
+// ensureAnotherAdmin verifies that an active (unlocked) admin other than userID exists.
+// All admin rows are locked (in ID order, so concurrent callers don't deadlock) for the rest
+// of the transaction; run this before deleting or demoting an admin in the same transaction
+func ensureAnotherAdmin(tx *gorm.DB, userID uuid.UUID) error {
+	var admins []models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, is_locked").
+		Where("is_admin = ?", true).Order("id").
+		Find(&admins).Error; err != nil {
+		return err
+	}
+	for _, admin := range admins {
+		if admin.ID != userID && !admin.IsLocked {
+			return nil
+		}
+	}
+	return errLastAdmin
+}
+
+// LockUser locks a user account to prevent login and ends the sessions the user already holds
 func (h *UserHandler) LockUser(c *gin.Context) {
 	userIDStr := c.Param("id")
 	userID, err := uuid.Parse(userIDStr)
@@ -363,6 +455,9 @@ func (h *UserHandler) LockUser(c *gin.Context) {
 		return
 	}
 
+	// Locked users can't log in or refresh, but also end the sessions they already hold
+	tokensRevoked := auth.RevokeUserSessions(user.ID, h.config.Auth)
+
 	// Get admin user info for audit log
 	adminUserID, _ := c.Get("user_id")
 	adminUsername, _ := c.Get("username")
@@ -378,11 +473,13 @@ func (h *UserHandler) LockUser(c *gin.Context) {
 		user.Username,
 		map[string]interface{}{
 			"target_username": user.Username,
+			"tokens_revoked":  tokensRevoked,
 		},
 	)
 
-	c.JSON(http.StatusOK, models.SuccessResponse{
-		Message: "User locked successfully",
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "User locked successfully",
+		"tokens_revoked": tokensRevoked,
 	})
 }
 
@@ -456,6 +553,225 @@ func (h *UserHandler) UnlockUser(c *gin.Context) {
 	})
 }
 
+// UpdateUserRoleRequest represents the request body for changing a user's admin flag
+type UpdateUserRoleRequest struct {
+	IsAdmin *bool `json:"is_admin" binding:"required"`
+}
+
+// UpdateUserRole promotes or demotes a user (admin only). The last active admin can't be demoted,
+// and the user's existing tokens are revoked so the new privilege level applies immediately
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	adminUserID, _ := c.Get("user_id")
+	adminUsername, _ := c.Get("username")
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid user ID",
+		})
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var user models.User
+	wasAdmin := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		wasAdmin = user.IsAdmin
+		if wasAdmin == *req.IsAdmin {
+			return nil
+		}
+		if user.IsServiceAccount {
+			return errServiceAccountAdmin
+		}
+
+		// Demoting must leave another active admin (this also stops an admin demoting themselves into lockout)
+		if wasAdmin {
+			if err := ensureAnotherAdmin(tx, userID); err != nil {
+				return err
+			}
+		}
+		user.IsAdmin = *req.IsAdmin
+		return tx.Model(&user).Update("is_admin", user.IsAdmin).Error
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "User not found",
+		})
+		return
+	case err == errServiceAccountAdmin:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Service accounts cannot be admins",
+			Message: "Attach policies to grant a service account access",
+		})
+		return
+	case err == errLastAdmin:
+		h.auditService.LogDenied(
+			c,
+			adminUserID.(uuid.UUID),
+			adminUsername.(string),
+			"UpdateUserRole",
+			"User",
+			userID.String(),
+			user.Username,
+			"Cannot demote the last admin user",
+			map[string]interface{}{
+				"target_username": user.Username,
+				"self":            userID == adminUserID.(uuid.UUID),
+			},
+		)
+
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Cannot demote the last admin user",
+			Message: "Promote another admin before demoting this one",
+		})
+		return
+	default:
+		h.auditService.LogFailure(
+			c,
+			adminUserID.(uuid.UUID),
+			adminUsername.(string),
+			"UpdateUserRole",
+			"User",
+			userID.String(),
+			user.Username,
+			err.Error(),
+			map[string]interface{}{
+				"target_username": user.Username,
+				"is_admin":        *req.IsAdmin,
+			},
+		)
+
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to update user role",
+			Message: "An internal error occurred. Please try again.",
+		})
+		return
+	}
+
+	if wasAdmin == user.IsAdmin {
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "User role unchanged",
+			"is_admin": user.IsAdmin,
+		})
+		return
+	}
+
+	// Permission checks read the admin flag from the (cached) user record
+	services.InvalidateUserPolicyCache(userID)
+
+	// Tokens carry the admin flag, so end the user's current sessions
+	tokensRevoked := auth.RevokeUserSessions(user.ID, h.config.Auth)
+
+	h.auditService.LogSuccess(
+		c,
+		adminUserID.(uuid.UUID),
+		adminUsername.(string),
+		"UpdateUserRole",
+		"User",
+		userID.String(),
+		user.Username,
+		map[string]interface{}{
+			"target_username": user.Username,
+			"before":          map[string]bool{"is_admin": wasAdmin},
+			"after":           map[string]bool{"is_admin": user.IsAdmin},
+			"tokens_revoked":  tokensRevoked,
+		},
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "User role updated successfully",
+		"is_admin":       user.IsAdmin,
+		"tokens_revoked": tokensRevoked,
+	})
+}
+
+// UpdateUserLimitsRequest represents the request body for changing a user's limits
+type UpdateUserLimitsRequest struct {
+	MaxAccessKeys *int `json:"max_access_keys" binding:"required"`
+}
+
+// UpdateUserLimits overrides the limits a user got from the default user profile (admin only).
+// Lowering the access key limit doesn't revoke existing keys; it only blocks new ones
+func (h *UserHandler) UpdateUserLimits(c *gin.Context) {
+	adminUserID, _ := c.Get("user_id")
+	adminUsername, _ := c.Get("username")
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid user ID",
+		})
+		return
+	}
+
+	var req UpdateUserLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+	if *req.MaxAccessKeys < 1 || *req.MaxAccessKeys > config.MaxAccessKeysPerUser {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid access key limit",
+			Message: fmt.Sprintf("max_access_keys must be between 1 and %d", config.MaxAccessKeysPerUser),
+		})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "User not found",
+		})
+		return
+	}
+
+	before := user.MaxAccessKeys
+	if err := database.DB.Model(&user).Update("max_access_keys", *req.MaxAccessKeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to update user limits",
+			Message: "An internal error occurred. Please try again.",
+		})
+		return
+	}
+
+	h.auditService.LogSuccess(
+		c,
+		adminUserID.(uuid.UUID),
+		adminUsername.(string),
+		"UpdateUserLimits",
+		"User",
+		userID.String(),
+		user.Username,
+		map[string]interface{}{
+			"target_username": user.Username,
+			"before":          map[string]int{"max_access_keys": before},
+			"after":           map[string]int{"max_access_keys": user.MaxAccessKeys},
+		},
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "User limits updated successfully",
+		"max_access_keys": user.MaxAccessKeys,
+	})
+}
+
 // ListUserAccessKeys lists all access keys for a specific user (admin only)
 func (h *UserHandler) ListUserAccessKeys(c *gin.Context) {
 	userIDStr := c.Param("id")
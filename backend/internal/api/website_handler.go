@@ -0,0 +1,297 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// websiteSandboxPolicy lets pages served from the path endpoint run their own scripts while
+// giving them an opaque origin, so they can't read the API origin's storage or call it with
+// the user's credentials
+const websiteSandboxPolicy = "sandbox allow-scripts allow-forms allow-popups allow-modals"
+
+// websitePathPrefix is where the path endpoint serves websites
+const websitePathPrefix = "/api/website/"
+
+// WebsiteHandler serves buckets that have a website configuration (S3 ?website). Requests are
+// anonymous: every object in the bucket except private-ACL ones can be fetched
+type WebsiteHandler struct {
+	config        *config.Config
+	bucketHandler *BucketHandler
+}
+
+func NewWebsiteHandler(cfg *config.Config) *WebsiteHandler {
+	return &WebsiteHandler{
+		config:        cfg,
+		bucketHandler: NewBucketHandler(cfg),
+	}
+}
+
+// HostMiddleware serves requests for <bucket>.<WEBSITE_DOMAIN> and lets everything else
+// through. Website hosts are separate origins, so content is served inline without a sandbox
+func (h *WebsiteHandler) HostMiddleware() gin.HandlerFunc {
+	suffix := "." + h.config.Server.WebsiteDomain
+
+	return func(c *gin.Context) {
+		if h.config.Server.WebsiteDomain == "" {
+			c.Next()
+			return
+		}
+
+		host := strings.ToLower(c.Request.Host)
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		bucketName, ok := strings.CutSuffix(host, suffix)
+		if !ok || bucketName == "" {
+			c.Next()
+			return
+		}
+
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Header("Allow", "GET, HEAD")
+			c.String(http.StatusMethodNotAllowed, "Method Not Allowed")
+			c.Abort()
+			return
+		}
+
+		h.serve(c, bucketName, c.Request.URL.Path, false)
+		c.Abort()
+	}
+}
+
+// ServeWebsitePath handles GET/HEAD /api/website/:bucket/*path, for deployments without a
+// wildcard website domain. Pages share the API origin, so active content is sandboxed
+func (h *WebsiteHandler) ServeWebsitePath(c *gin.Context) {
+	h.serve(c, c.Param("bucket"), c.Param("path"), true)
+}
+
+// serve resolves a website request path to an object: routing rules, then the index document
+// for directory requests, then the error document (or a plain 404) when nothing matches
+func (h *WebsiteHandler) serve(c *gin.Context, bucketName, requestPath string, pathMode bool) {
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		c.String(http.StatusNotFound, "404 Not Found: no such bucket")
+		return
+	}
+
+	var website models.BucketWebsite
+	if err := database.DB.Where("bucket_id = ?", bucket.ID).First(&website).Error; err != nil {
+		c.String(http.StatusNotFound, "404 Not Found: bucket is not configured as a website")
+		return
+	}
+
+	key := strings.TrimPrefix(requestPath, "/")
+
+	if website.RedirectHostName != "" {
+		protocol := website.RedirectProtocol
+		if protocol == "" {
+			protocol = requestScheme(c)
+		}
+		c.Redirect(http.StatusMovedPermanently, fmt.Sprintf("%s://%s%s", protocol, website.RedirectHostName, escapeKeyPath(key)))
+		return
+	}
+
+	rules := website.Rules()
+	if rule := matchRoutingRule(rules, key, 0); rule != nil {
+		h.redirect(c, rule, bucketName, key, pathMode)
+		return
+	}
+
+	objectKey := key
+	directory := key == "" || strings.HasSuffix(key, "/")
+	if directory {
+		objectKey += website.IndexDocument
+	}
+	objectKey = bucket.NormalizeKey(objectKey)
+
+	object, err := findWebsiteObject(&bucket, objectKey)
+	if err != nil {
+		logger.Error("Website object lookup failed", map[string]interface{}{
+			"bucket": bucketName,
+			"key":    objectKey,
+			"error":  err.Error(),
+		})
+		c.String(http.StatusInternalServerError, "500 Internal Server Error")
+		return
+	}
+
+	if object == nil && !directory {
+		// "docs" with a "docs/index.html" object: redirect so relative links resolve under docs/
+		index, err := findWebsiteObject(&bucket, bucket.NormalizeKey(key+"/"+website.IndexDocument))
+		if err == nil && index != nil {
+			c.Redirect(http.StatusFound, websiteURL(bucketName, key+"/", pathMode))
+			return
+		}
+	}
+
+	if object == nil {
+		if rule := matchRoutingRule(rules, key, http.StatusNotFound); rule != nil {
+			h.redirect(c, rule, bucketName, key, pathMode)
+			return
+		}
+		h.serveNotFound(c, &bucket, &website, pathMode)
+		return
+	}
+
+	h.serveObject(c, &bucket, object, http.StatusOK, pathMode)
+}
+
+// serveNotFound answers 404 with the bucket's error document, if it has one that exists
+func (h *WebsiteHandler) serveNotFound(c *gin.Context, bucket *models.Bucket, website *models.BucketWebsite, pathMode bool) {
+	if website.ErrorDocument != "" {
+		errorDoc, err := findWebsiteObject(bucket, bucket.NormalizeKey(website.ErrorDocument))
+		if err == nil && errorDoc != nil {
+			h.serveObject(c, bucket, errorDoc, http.StatusNotFound, pathMode)
+			return
+		}
+	}
+	c.String(http.StatusNotFound, "404 Not Found")
+}
+
+// serveObject writes an object inline with its stored content type
+func (h *WebsiteHandler) serveObject(c *gin.Context, bucket *models.Bucket, object *models.Object, status int, pathMode bool) {
+	etag := fmt.Sprintf(`"%s"`, object.ETag)
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", object.UpdatedAt.UTC().Format(http.TimeFormat))
+	if pathMode && validation.IsActiveContentType(object.ContentType) {
+		c.Header("Content-Security-Policy", websiteSandboxPolicy)
+	} else {
+		// The API-wide CSP would stop the site's own stylesheets, scripts and images from loading
+		c.Writer.Header().Del("Content-Security-Policy")
+	}
+
+	if status == http.StatusOK && object.ETag != "" && c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if c.Request.Method == http.MethodHead {
+		c.Header("Content-Type", object.ContentType)
+		c.Header("Content-Length", strconv.FormatInt(object.Size, 10))
+		c.Status(status)
+		return
+	}
+
+	storageBackend, err := h.bucketHandler.getStorageBackend(bucket)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "500 Internal Server Error")
+		return
+	}
+	file, err := storageBackend.GetObject(bucket.Name, object.Key)
+	if err != nil {
+		logger.Error("Failed to read website object", map[string]interface{}{
+			"bucket": bucket.Name,
+			"key":    object.Key,
+			"error":  err.Error(),
+		})
+		c.String(http.StatusInternalServerError, "500 Internal Server Error")
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(status, object.Size, object.ContentType, file, nil)
+}
+
+// redirect answers a matched routing rule
+func (h *WebsiteHandler) redirect(c *gin.Context, rule *models.WebsiteRoutingRule, bucketName, key string, pathMode bool) {
+	switch {
+	case rule.ReplaceKeyWith != "":
+		key = rule.ReplaceKeyWith
+	case rule.ReplaceKeyPrefixWith != "":
+		key = rule.ReplaceKeyPrefixWith + strings.TrimPrefix(key, rule.KeyPrefixEquals)
+	}
+	key = strings.TrimPrefix(key, "/")
+
+	code := http.StatusMovedPermanently
+	if rule.HTTPRedirectCode != "" {
+		code, _ = strconv.Atoi(rule.HTTPRedirectCode)
+	}
+
+	protocol := rule.Protocol
+	if protocol == "" {
+		protocol = requestScheme(c)
+	}
+
+	var target string
+	switch {
+	case rule.HostName != "":
+		target = fmt.Sprintf("%s://%s%s", protocol, rule.HostName, escapeKeyPath(key))
+	case rule.Protocol != "":
+		target = protocol + "://" + c.Request.Host + websiteURL(bucketName, key, pathMode)
+	default:
+		target = websiteURL(bucketName, key, pathMode)
+	}
+	c.Redirect(code, target)
+}
+
+// matchRoutingRule returns the first rule matching key. With status 0 only rules without an
+// error code condition are considered (they apply before the object lookup); otherwise only
+// rules whose condition names that status
+func matchRoutingRule(rules []models.WebsiteRoutingRule, key string, status int) *models.WebsiteRoutingRule {
+	for i := range rules {
+		rule := &rules[i]
+		if status == 0 {
+			if rule.HTTPErrorCodeReturnedEquals != "" {
+				continue
+			}
+		} else if rule.HTTPErrorCodeReturnedEquals != strconv.Itoa(status) {
+			continue
+		}
+		if strings.HasPrefix(key, rule.KeyPrefixEquals) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// findWebsiteObject loads an object that may be served publicly. Private-ACL objects are
+// treated as missing. Returns nil without an error when there is no such object
+func findWebsiteObject(bucket *models.Bucket, key string) (*models.Object, error) {
+	var object models.Object
+	err := database.DB.Where("bucket_id = ? AND key = ? AND acl <> ?", bucket.ID, key, models.ObjectACLPrivate).
+		First(&object).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// websiteURL is the path of key on the website endpoint the request came in on
+func websiteURL(bucketName, key string, pathMode bool) string {
+	if pathMode {
+		return websitePathPrefix + url.PathEscape(bucketName) + escapeKeyPath(key)
+	}
+	return escapeKeyPath(key)
+}
+
+// escapeKeyPath turns an object key into an escaped absolute URL path
+func escapeKeyPath(key string) string {
+	return (&url.URL{Path: "/" + key}).EscapedPath()
+}
+
+func requestScheme(c *gin.Context) string {
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		return "https"
+	}
+	return "http"
+}
@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
 	"bkt/internal/config"
 	"bkt/internal/database"
@@ -23,10 +22,14 @@ import (
 type GoogleOAuthHandler struct {
 	config           *config.Config
 	workspaceService *GoogleWorkspaceService
+	httpClient       *http.Client // Token and user info calls to Google
 }
 
 func NewGoogleOAuthHandler(cfg *config.Config) *GoogleOAuthHandler {
-	handler := &GoogleOAuthHandler{config: cfg}
+	handler := &GoogleOAuthHandler{
+		config:     cfg,
+		httpClient: newSSOHTTPClient(cfg.SSOClient, "google"),
+	}
 
 	// Initialize workspace service if enabled
 	if cfg.GoogleSSO.WorkspaceEnabled {
@@ -125,16 +128,16 @@ func (h *GoogleOAuthHandler) HandleGoogleCallback(c *gin.Context) {
 	}
 
 	// Exchange code for token
-	token, err := h.exchangeCodeForToken(code)
+	token, err := h.exchangeCodeForToken(c.Request.Context(), code)
 	if err != nil {
-		h.redirectWithError(c, "token_exchange_failed", err.Error())
+		h.redirectWithError(c, "token_exchange_failed", ssoErrorDescription("Google", err))
 		return
 	}
 
 	// Get user info from Google
-	userInfo, err := h.getUserInfo(token.AccessToken)
+	userInfo, err := h.getUserInfo(c.Request.Context(), token.AccessToken)
 	if err != nil {
-		h.redirectWithError(c, "user_info_failed", err.Error())
+		h.redirectWithError(c, "user_info_failed", ssoErrorDescription("Google", err))
 		return
 	}
 
@@ -179,17 +182,14 @@ func (h *GoogleOAuthHandler) HandleGoogleCallback(c *gin.Context) {
 		}
 	}
 
-	// Generate JWT token for our system
-	accessTokenDuration, _ := time.ParseDuration(h.config.Auth.AccessTokenExpiry)
-	jwtToken, err := GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, accessTokenDuration)
-	if err != nil {
-		h.redirectWithError(c, "token_generation_failed", err.Error())
+	// Optionally deny users without any policies
+	if h.config.Auth.RequirePolicyForLogin && !HasLoginPolicies(user) {
+		h.redirectWithError(c, "no_permissions", NoPermissionsMessage)
 		return
 	}
 
-	// Generate refresh token
-	refreshTokenDuration, _ := time.ParseDuration(h.config.Auth.RefreshTokenExpiry)
-	refreshToken, err := GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, refreshTokenDuration)
+	// Generate our JWT tokens
+	jwtToken, refreshToken, err := GenerateTokenPair(user.ID, user.Username, user.IsAdmin, h.config.Auth)
 	if err != nil {
 		h.redirectWithError(c, "token_generation_failed", err.Error())
 		return
@@ -222,7 +222,7 @@ func (h *GoogleOAuthHandler) findOrCreateUser(userInfo *GoogleUserInfo) (*models
 		return &user, false, nil
 	}
 
-	// User doesn't exist - create new user (MinIO approach: no policies by default)
+	// User doesn't exist - create new user (no policies unless SSO_DEFAULT_POLICIES is set)
 	user = models.User{
 		ID:          uuid.New(),
 		Username:    generateUsernameFromEmail(userInfo.Email),
@@ -238,14 +238,16 @@ func (h *GoogleOAuthHandler) findOrCreateUser(userInfo *GoogleUserInfo) (*models
 		return nil, false, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Reload user with policies (will be empty)
+	ApplyDefaultUserProfile(&user, &h.config.Auth, true)
+
+	// Reload user with policies (only the defaults, if any)
 	database.DB.Preload("Policies").First(&user, user.ID)
 
 	return &user, true, nil
 }
 
 // exchangeCodeForToken exchanges an authorization code for an access token
-func (h *GoogleOAuthHandler) exchangeCodeForToken(code string) (*GoogleTokenResponse, error) {
+func (h *GoogleOAuthHandler) exchangeCodeForToken(ctx context.Context, code string) (*GoogleTokenResponse, error) {
 	tokenURL := "https://oauth2.googleapis.com/token"
 
 	data := url.Values{}
@@ -255,7 +257,13 @@ func (h *GoogleOAuthHandler) exchangeCodeForToken(code string) (*GoogleTokenResp
 	data.Set("redirect_uri", h.config.GoogleSSO.RedirectURL)
 	data.Set("grant_type", "authorization_code")
 
-	resp, err := http.PostForm(tokenURL, data)
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := h.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -275,17 +283,17 @@ func (h *GoogleOAuthHandler) exchangeCodeForToken(code string) (*GoogleTokenResp
 }
 
 // getUserInfo fetches user information from Google
-func (h *GoogleOAuthHandler) getUserInfo(accessToken string) (*GoogleUserInfo, error) {
+func (h *GoogleOAuthHandler) getUserInfo(ctx context.Context, accessToken string) (*GoogleUserInfo, error) {
 	userInfoURL := "https://www.googleapis.com/oauth2/v2/userinfo"
 
-	req, err := http.NewRequestWithContext(context.Background(), "GET", userInfoURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", userInfoURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := h.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 
 	"bkt/internal/config"
 	"bkt/internal/database"
 	"bkt/internal/models"
+	"bkt/internal/services"
 
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	admin "google.golang.org/api/admin/directory/v1"
 	"google.golang.org/api/option"
@@ -18,12 +21,16 @@ import (
 
 // GoogleWorkspaceService handles Google Workspace API interactions
 type GoogleWorkspaceService struct {
-	config *config.Config
+	config     *config.Config
+	httpClient *http.Client // Base client for the Admin SDK and its token requests
 }
 
 // NewGoogleWorkspaceService creates a new Google Workspace service
 func NewGoogleWorkspaceService(cfg *config.Config) *GoogleWorkspaceService {
-	return &GoogleWorkspaceService{config: cfg}
+	return &GoogleWorkspaceService{
+		config:     cfg,
+		httpClient: newSSOHTTPClient(cfg.SSOClient, "google-workspace"),
+	}
 }
 
 // GetUserGroups fetches all groups a user belongs to via Google Workspace Admin SDK
@@ -49,8 +56,8 @@ func (s *GoogleWorkspaceService) GetUserGroups(ctx context.Context, userEmail st
 	// Set the subject (admin user to impersonate)
 	jwtConfig.Subject = s.config.GoogleSSO.WorkspaceAdminEmail
 
-	// Create the Admin SDK client
-	client := jwtConfig.Client(ctx)
+	// Create the Admin SDK client; the oauth2 transport sends its token and API calls through our client
+	client := jwtConfig.Client(context.WithValue(ctx, oauth2.HTTPClient, s.httpClient))
 	adminService, err := admin.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Admin SDK client: %w", err)
@@ -66,7 +73,7 @@ func (s *GoogleWorkspaceService) GetUserGroups(ctx context.Context, userEmail st
 			call = call.PageToken(pageToken)
 		}
 
-		result, err := call.Do()
+		result, err := call.Context(ctx).Do()
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch groups for user %s: %w", userEmail, err)
 		}
@@ -144,7 +151,9 @@ func (s *GoogleWorkspaceService) SyncUserPoliciesFromGroups(user *models.User, p
 	}
 
 	// Replace user's policies with those from Google Workspace groups
-	if err := database.DB.Model(user).Association("Policies").Replace(policies); err != nil {
+	err := database.DB.Model(user).Association("Policies").Replace(policies)
+	services.InvalidateUserPolicyCache(user.ID)
+	if err != nil {
 		return fmt.Errorf("failed to sync policies: %w", err)
 	}
 
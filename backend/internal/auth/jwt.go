@@ -2,8 +2,11 @@ package auth
 
 import (
 	"errors"
+	"strings"
 	"time"
 
+	"bkt/internal/config"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
@@ -11,23 +14,35 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
 type Claims struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
 	IsAdmin  bool      `json:"is_admin"`
+	// SessionStart is when the user originally logged in (refresh tokens only)
+	// Sliding sessions never extend past SessionStart + SessionMaxLifetime
+	SessionStart *jwt.NumericDate `json:"session_start,omitempty"`
+	// ServiceAccount marks a service token (non-login machine identity); never set for user sessions
+	ServiceAccount bool `json:"service_account,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateToken creates a new JWT token for a user
 func GenerateToken(userID uuid.UUID, username string, isAdmin bool, secret string, duration time.Duration) (string, error) {
+	return generateToken(userID, username, isAdmin, secret, time.Now().Add(duration), nil)
+}
+
+func generateToken(userID uuid.UUID, username string, isAdmin bool, secret string, expiresAt time.Time, sessionStart *jwt.NumericDate) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		IsAdmin:  isAdmin,
+		UserID:       userID,
+		Username:     username,
+		IsAdmin:      isAdmin,
+		SessionStart: sessionStart,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+			ID:        uuid.NewString(), // jti, used to revoke individual tokens (logout)
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -37,6 +52,89 @@ func GenerateToken(userID uuid.UUID, username string, isAdmin bool, secret strin
 	return token.SignedString([]byte(secret))
 }
 
+// serviceTokenIDPrefix keeps service token IDs (jti) in their own namespace, apart from user session tokens
+const serviceTokenIDPrefix = "svc-"
+
+// GenerateServiceAccountToken creates the long-lived token a service account authenticates with
+func GenerateServiceAccountToken(accountID uuid.UUID, name string, cfg config.AuthConfig) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(cfg.ServiceAccountTokenDuration)
+	claims := Claims{
+		UserID:         accountID,
+		Username:       name,
+		ServiceAccount: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        serviceTokenIDPrefix + uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.JWTSecret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// GenerateTokenPair creates the access and refresh tokens for a new login session
+func GenerateTokenPair(userID uuid.UUID, username string, isAdmin bool, cfg config.AuthConfig) (string, string, error) {
+	accessToken, err := GenerateToken(userID, username, isAdmin, cfg.JWTSecret, cfg.AccessTokenDuration)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	refreshToken, err := generateToken(userID, username, isAdmin, cfg.JWTSecret, now.Add(cfg.RefreshTokenDuration), jwt.NewNumericDate(now))
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// RefreshSession issues a new access token from validated refresh token claims
+// With sliding sessions enabled, it also returns a renewed refresh token whose expiry is
+// capped at the session's absolute lifetime; otherwise the returned refresh token is empty
+// and the client keeps using its current one
+func RefreshSession(claims *Claims, username string, isAdmin bool, cfg config.AuthConfig) (string, string, error) {
+	accessToken, err := GenerateToken(claims.UserID, username, isAdmin, cfg.JWTSecret, cfg.AccessTokenDuration)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !cfg.SlidingSessions {
+		return accessToken, "", nil
+	}
+
+	// Tokens issued before sliding sessions existed have no session start; fall back to issue time
+	sessionStart := claims.SessionStart
+	if sessionStart == nil {
+		sessionStart = claims.IssuedAt
+	}
+	if sessionStart == nil {
+		return accessToken, "", nil
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(cfg.RefreshTokenDuration)
+	sessionEnd := sessionStart.Time.Add(cfg.SessionMaxDuration)
+	if expiresAt.After(sessionEnd) {
+		expiresAt = sessionEnd
+	}
+	if !expiresAt.After(now) {
+		return accessToken, "", nil // Absolute lifetime reached; no further extension
+	}
+
+	refreshToken, err := generateToken(claims.UserID, username, isAdmin, cfg.JWTSecret, expiresAt, sessionStart)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
@@ -64,5 +162,14 @@ func ValidateToken(tokenString string, secret string) (*Claims, error) {
 		return nil, ErrExpiredToken
 	}
 
+	if IsTokenRevoked(claims.ID) || isUserTokenRevoked(claims) {
+		return nil, ErrRevokedToken
+	}
+
+	// Service tokens live in their own jti namespace; a flag/namespace mismatch is malformed
+	if claims.ServiceAccount != strings.HasPrefix(claims.ID, serviceTokenIDPrefix) {
+		return nil, ErrInvalidToken
+	}
+
 	return claims, nil
 }
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"slices"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+	"bkt/internal/services"
+)
+
+// NoPermissionsMessage is returned by every login path when a user is denied for having no policies
+const NoPermissionsMessage = "Your account has no permissions. Please contact your administrator to grant access."
+
+// HasLoginPolicies reports whether a user holds at least one policy (admins always pass)
+// Used to enforce RequirePolicyForLogin consistently across local, Google and Vault logins
+func HasLoginPolicies(user *models.User) bool {
+	if user.IsAdmin {
+		return true
+	}
+	return database.DB.Model(user).Association("Policies").Count() > 0
+}
+
+// AttachDefaultPolicies gives a newly created user the configured baseline policies.
+// Names that don't match an existing policy are skipped with a warning, so a typo in the
+// config never blocks sign-up; the user simply gets fewer policies
+func AttachDefaultPolicies(user *models.User, policyNames []string) {
+	if len(policyNames) == 0 {
+		return
+	}
+
+	var policies []models.Policy
+	if err := database.DB.Where("name IN ?", policyNames).Find(&policies).Error; err != nil {
+		logger.Warn("Failed to load default policies", map[string]interface{}{
+			"user":  user.Username,
+			"error": err.Error(),
+		})
+		return
+	}
+	if len(policies) < len(policyNames) {
+		logger.Warn("Some default policies do not exist", map[string]interface{}{
+			"configured": policyNames,
+			"found":      len(policies),
+		})
+	}
+	if len(policies) == 0 {
+		return
+	}
+
+	if err := database.DB.Model(user).Association("Policies").Append(policies); err != nil {
+		logger.Warn("Failed to attach default policies", map[string]interface{}{
+			"user":  user.Username,
+			"error": err.Error(),
+		})
+	}
+	services.InvalidateUserPolicyCache(user.ID)
+}
+
+// ApplyDefaultUserProfile gives a newly created account the default user profile: the access key
+// limit and DEFAULT_USER_POLICIES, plus SSO_DEFAULT_POLICIES for accounts created by an SSO login
+func ApplyDefaultUserProfile(user *models.User, authCfg *config.AuthConfig, sso bool) {
+	if user.MaxAccessKeys != authCfg.DefaultUserMaxAccessKeys {
+		if err := database.DB.Model(user).Update("max_access_keys", authCfg.DefaultUserMaxAccessKeys).Error; err != nil {
+			logger.Warn("Failed to apply default access key limit", map[string]interface{}{
+				"user":  user.Username,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	policyNames := append([]string{}, authCfg.DefaultUserPolicies...)
+	if sso {
+		for _, name := range authCfg.SSODefaultPolicies {
+			if !slices.Contains(policyNames, name) {
+				policyNames = append(policyNames, name)
+			}
+		}
+	}
+	AttachDefaultPolicies(user, policyNames)
+}
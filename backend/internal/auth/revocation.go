@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"bkt/internal/config"
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// Revoked token IDs cached in memory so request authentication never hits the database;
+// the revoked_tokens table is the source of truth shared by all instances
+var (
+	revokedTokens   = make(map[string]time.Time)    // jti -> token expiry
+	revokedUsers    = make(map[uuid.UUID]time.Time) // user ID -> tokens issued before this are revoked
+	revokedTokensMu sync.RWMutex
+)
+
+// RevokeToken blacklists a token until it expires. Tokens without an ID (issued before
+// token IDs existed) can't be revoked individually and are ignored
+func RevokeToken(claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	revoked := models.RevokedToken{
+		JTI:       claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error; err != nil {
+		return err
+	}
+
+	revokedTokensMu.Lock()
+	revokedTokens[claims.ID] = claims.ExpiresAt.Time
+	revokedTokensMu.Unlock()
+	return nil
+}
+
+// RevokeUserTokens invalidates every token issued to a user so far, so changes to their
+// privileges take effect immediately. maxLifetime is the longest any of those tokens can
+// remain valid (the refresh token lifetime); the revocation is kept that long
+func RevokeUserTokens(userID uuid.UUID, maxLifetime time.Duration) error {
+	now := time.Now()
+	revocation := models.UserTokenRevocation{
+		UserID:    userID,
+		RevokedAt: now,
+		ExpiresAt: now.Add(maxLifetime),
+	}
+	if err := database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&revocation).Error; err != nil {
+		return err
+	}
+
+	revokedTokensMu.Lock()
+	revokedUsers[userID] = now
+	revokedTokensMu.Unlock()
+	return nil
+}
+
+// RevokeUserSessions ends every session a user holds, for as long as any of their tokens could
+// still be valid under cfg. A failure is logged and reported as false rather than returned: the
+// change that called for it (a lock or role change) stands, and the sessions last until they expire
+func RevokeUserSessions(userID uuid.UUID, cfg config.AuthConfig) bool {
+	maxLifetime := cfg.RefreshTokenDuration
+	if cfg.AccessTokenDuration > maxLifetime {
+		maxLifetime = cfg.AccessTokenDuration
+	}
+	if err := RevokeUserTokens(userID, maxLifetime); err != nil {
+		logger.Error("Failed to revoke user tokens", map[string]interface{}{
+			"user_id": userID.String(),
+			"error":   err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// isUserTokenRevoked reports whether the token predates a revocation of all the user's tokens.
+// Token issue times have one-second precision, so tokens issued within the revocation's own
+// second (typically the fresh login that follows it) stay valid
+func isUserTokenRevoked(claims *Claims) bool {
+	revokedTokensMu.RLock()
+	revokedAt, exists := revokedUsers[claims.UserID]
+	revokedTokensMu.RUnlock()
+	if !exists {
+		return false
+	}
+	if claims.IssuedAt == nil {
+		return true
+	}
+	return claims.IssuedAt.Time.Before(revokedAt.Truncate(time.Second))
+}
+
+// IsTokenRevoked reports whether a token ID has been blacklisted
+func IsTokenRevoked(jti string) bool {
+	if jti == "" {
+		return false
+	}
+	revokedTokensMu.RLock()
+	defer revokedTokensMu.RUnlock()
+	_, revoked := revokedTokens[jti]
+	return revoked
+}
+
+// LoadRevokedTokens replaces the in-memory blacklists with the unexpired rows from the database
+func LoadRevokedTokens() error {
+	var rows []models.RevokedToken
+	if err := database.DB.Where("expires_at > ?", time.Now()).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	loaded := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		loaded[row.JTI] = row.ExpiresAt
+	}
+
+	var userRows []models.UserTokenRevocation
+	if err := database.DB.Where("expires_at > ?", time.Now()).Find(&userRows).Error; err != nil {
+		return err
+	}
+	loadedUsers := make(map[uuid.UUID]time.Time, len(userRows))
+	for _, row := range userRows {
+		loadedUsers[row.UserID] = row.RevokedAt
+	}
+
+	revokedTokensMu.Lock()
+	revokedTokens = loaded
+	revokedUsers = loadedUsers
+	revokedTokensMu.Unlock()
+	return nil
+}
+
+// StartRevokedTokenSync periodically reloads the blacklist (so logouts on one instance reach
+// the others) and deletes rows for tokens that have expired
+func StartRevokedTokenSync(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			database.DB.Where("expires_at <= ?", time.Now()).Delete(&models.RevokedToken{})
+			database.DB.Where("expires_at <= ?", time.Now()).Delete(&models.UserTokenRevocation{})
+			if err := LoadRevokedTokens(); err != nil {
+				logger.Warn("Failed to refresh revoked tokens", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"bkt/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Cookie session names. The access cookie is sent to every API route; the refresh cookie
+// only to the auth routes that consume it
+const (
+	SessionCookieName = "bkt_session"
+	RefreshCookieName = "bkt_refresh"
+
+	sessionCookiePath = "/api"
+	refreshCookiePath = "/api/auth"
+)
+
+func cookieSameSite(cfg config.AuthConfig) http.SameSite {
+	switch cfg.CookieSameSite {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
+
+func setAuthCookie(c *gin.Context, cfg config.AuthConfig, name, value, path string, expires time.Time) {
+	maxAge := int(time.Until(expires).Seconds())
+	if value == "" {
+		maxAge = -1
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: true,
+		SameSite: cookieSameSite(cfg),
+	})
+}
+
+// SetSessionCookies stores the access token (and the refresh token, if given) in HttpOnly
+// cookies. Each cookie lives exactly as long as the token inside it
+func SetSessionCookies(c *gin.Context, cfg config.AuthConfig, accessToken, refreshToken string) {
+	setAuthCookie(c, cfg, SessionCookieName, accessToken, sessionCookiePath, tokenExpiry(accessToken))
+	if refreshToken != "" {
+		setAuthCookie(c, cfg, RefreshCookieName, refreshToken, refreshCookiePath, tokenExpiry(refreshToken))
+	}
+}
+
+// tokenExpiry reads the expiry of a token this server just issued (no signature check needed)
+func tokenExpiry(tokenString string) time.Time {
+	var claims Claims
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &claims); err != nil || claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.ExpiresAt.Time
+}
+
+// ClearSessionCookies expires both session cookies
+func ClearSessionCookies(c *gin.Context, cfg config.AuthConfig) {
+	setAuthCookie(c, cfg, SessionCookieName, "", sessionCookiePath, time.Time{})
+	setAuthCookie(c, cfg, RefreshCookieName, "", refreshCookiePath, time.Time{})
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"bkt/internal/config"
+	"bkt/internal/logger"
+)
+
+// ErrIdPUnavailable is returned, without contacting the identity provider, while its circuit breaker is open
+var ErrIdPUnavailable = errors.New("identity provider is temporarily unavailable")
+
+// ssoRetryBackoff is the wait before the first retry; it doubles for each further attempt
+const ssoRetryBackoff = 250 * time.Millisecond
+
+// newSSOHTTPClient returns the client used for every outbound call to one identity provider.
+// Each attempt is bounded by the configured timeout, transient failures are retried, and the
+// provider's circuit breaker fails calls fast while it is known to be down
+func newSSOHTTPClient(cfg config.SSOClientConfig, provider string) *http.Client {
+	timeout := cfg.TimeoutDuration
+	base := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   4,
+	}
+
+	var breaker *circuitBreaker
+	if cfg.BreakerThreshold > 0 {
+		breaker = &circuitBreaker{
+			provider:  provider,
+			threshold: cfg.BreakerThreshold,
+			cooldown:  cfg.BreakerCooldownDuration,
+		}
+	}
+
+	// Overall cap: every attempt plus the backoffs between them
+	total := time.Duration(cfg.MaxRetries+1)*timeout + ssoRetryBackoff<<cfg.MaxRetries
+	return &http.Client{
+		Timeout: total,
+		Transport: &ssoTransport{
+			base:       base,
+			maxRetries: cfg.MaxRetries,
+			breaker:    breaker,
+		},
+	}
+}
+
+// ssoTransport retries transient failures and reports outcomes to the provider's circuit breaker
+type ssoTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	breaker    *circuitBreaker // nil when disabled
+}
+
+func (t *ssoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		return nil, ErrIdPUnavailable
+	}
+
+	// A request body can only be sent again if it can be recreated
+	retries := t.maxRetries
+	if req.Body != nil && req.GetBody == nil {
+		retries = 0
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err = t.base.RoundTrip(attemptReq)
+		if !isTransientSSOFailure(resp, err) || attempt >= retries || req.Context().Err() != nil {
+			break
+		}
+
+		// Drain and discard the failed response so its connection can be reused
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(ssoRetryBackoff << attempt):
+		case <-req.Context().Done():
+			t.breaker.record(false)
+			return nil, req.Context().Err()
+		}
+	}
+
+	t.breaker.record(!isTransientSSOFailure(resp, err))
+	return resp, err
+}
+
+// isTransientSSOFailure reports whether a call failed in a way worth retrying: a network error or
+// a gateway/unavailable status. Other statuses (e.g. a rejected authorization code) are answers
+func isTransientSSOFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// circuitBreaker stops calling a provider after repeated failures. Once the cooldown has passed,
+// one call is let through: success closes the breaker, failure opens it for another cooldown
+type circuitBreaker struct {
+	provider  string
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int // Consecutive failed calls
+	openUntil time.Time
+	probing   bool // A trial call is in flight
+}
+
+// allow reports whether a call may be made now
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record reports the outcome of a call allowed by allow
+func (b *circuitBreaker) record(success bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		if b.failures >= b.threshold {
+			logger.Info("SSO provider reachable again, circuit breaker closed", map[string]interface{}{
+				"provider": b.provider,
+			})
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		logger.Warn("SSO provider unreachable, circuit breaker open", map[string]interface{}{
+			"provider":             b.provider,
+			"consecutive_failures": b.failures,
+			"retry_after":          b.cooldown.String(),
+		})
+	}
+}
+
+// ssoErrorDescription turns an outbound IdP call failure into the message shown on the login page
+func ssoErrorDescription(provider string, err error) string {
+	if errors.Is(err, ErrIdPUnavailable) {
+		return fmt.Sprintf("%s sign-in is temporarily unavailable. Please try again in a few minutes", provider)
+	}
+	return err.Error()
+}
@@ -8,6 +8,7 @@ import (
 	"bkt/internal/config"
 	"bkt/internal/database"
 	"bkt/internal/models"
+	"bkt/internal/services"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,11 +17,15 @@ import (
 )
 
 type VaultJWTHandler struct {
-	config *config.Config
+	config     *config.Config
+	httpClient *http.Client // JWKS fetches from Vault
 }
 
 func NewVaultJWTHandler(cfg *config.Config) *VaultJWTHandler {
-	return &VaultJWTHandler{config: cfg}
+	return &VaultJWTHandler{
+		config:     cfg,
+		httpClient: newSSOHTTPClient(cfg.SSOClient, "vault-jwt"),
+	}
 }
 
 // VaultJWTClaims represents the claims in a Vault JWT
@@ -125,18 +130,17 @@ func (h *VaultJWTHandler) LoginWithVaultJWT(c *gin.Context) {
 	}
 
 	// MinIO-style: Check if user has any policies
-	// If no policies, deny access with clear message
-	if !user.IsAdmin && len(user.Policies) == 0 {
+	// Vault JWT always enforces this (SSO claims are the source of truth); other methods follow RequirePolicyForLogin
+	if !HasLoginPolicies(user) {
 		c.JSON(http.StatusForbidden, models.ErrorResponse{
 			Error:   "No permissions",
-			Message: "Your account has been created but has no permissions. Please contact your administrator to grant access.",
+			Message: NoPermissionsMessage,
 		})
 		return
 	}
 
-	// Generate JWT token for our system
-	accessTokenDuration, _ := time.ParseDuration(h.config.Auth.AccessTokenExpiry)
-	jwtToken, err := GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, accessTokenDuration)
+	// Generate JWT tokens for our system
+	jwtToken, refreshToken, err := GenerateTokenPair(user.ID, user.Username, user.IsAdmin, h.config.Auth)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to generate token",
@@ -145,17 +149,6 @@ func (h *VaultJWTHandler) LoginWithVaultJWT(c *gin.Context) {
 		return
 	}
 
-	// Generate refresh token
-	refreshTokenDuration, _ := time.ParseDuration(h.config.Auth.RefreshTokenExpiry)
-	refreshToken, err := GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, refreshTokenDuration)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to generate refresh token",
-			Message: err.Error(),
-		})
-		return
-	}
-
 	// Return success response
 	response := struct {
 		Token        string       `json:"token"`
@@ -233,7 +226,7 @@ func (h *VaultJWTHandler) findOrCreateVaultUser(vaultID, email, name string) (*m
 		return &user, false, nil
 	}
 
-	// User doesn't exist - create new user (MinIO approach: no policies by default)
+	// User doesn't exist - create new user (no policies unless SSO_DEFAULT_POLICIES is set)
 	username := name
 	if username == "" {
 		username = generateUsernameFromEmail(email)
@@ -254,7 +247,9 @@ func (h *VaultJWTHandler) findOrCreateVaultUser(vaultID, email, name string) (*m
 		return nil, false, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Reload user with policies (will be empty)
+	ApplyDefaultUserProfile(&user, &h.config.Auth, true)
+
+	// Reload user with policies (only the defaults, if any)
 	database.DB.Preload("Policies").First(&user, user.ID)
 
 	return &user, true, nil
@@ -264,7 +259,7 @@ func (h *VaultJWTHandler) findOrCreateVaultUser(vaultID, email, name string) (*m
 func (h *VaultJWTHandler) GetVaultJWKS() (*VaultJWKS, error) {
 	jwksURL := fmt.Sprintf("%s/v1/%s/.well-known/jwks.json", h.config.VaultSSO.Address, h.config.VaultSSO.JWTPath)
 
-	resp, err := http.Get(jwksURL)
+	resp, err := h.httpClient.Get(jwksURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
 	}
@@ -300,7 +295,9 @@ func (h *VaultJWTHandler) syncUserPoliciesFromClaims(user *models.User, policyNa
 
 	// Replace user's policies with those from SSO
 	// This uses GORM's Replace association mode which clears existing and sets new
-	if err := database.DB.Model(user).Association("Policies").Replace(policies); err != nil {
+	err := database.DB.Model(user).Association("Policies").Replace(policies)
+	services.InvalidateUserPolicyCache(user.ID)
+	if err != nil {
 		return fmt.Errorf("failed to sync policies: %w", err)
 	}
 
@@ -11,11 +11,11 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
 	"bkt/internal/config"
 	"bkt/internal/database"
 	"bkt/internal/models"
+	"bkt/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -23,11 +23,15 @@ import (
 )
 
 type VaultOIDCHandler struct {
-	config *config.Config
+	config     *config.Config
+	httpClient *http.Client // Token calls to Vault
 }
 
 func NewVaultOIDCHandler(cfg *config.Config) *VaultOIDCHandler {
-	return &VaultOIDCHandler{config: cfg}
+	return &VaultOIDCHandler{
+		config:     cfg,
+		httpClient: newSSOHTTPClient(cfg.SSOClient, "vault-oidc"),
+	}
 }
 
 // VaultTokenResponse represents the token response from Vault OIDC
@@ -130,9 +134,9 @@ func (h *VaultOIDCHandler) HandleVaultCallback(c *gin.Context) {
 	}
 
 	// Exchange code for tokens using PKCE
-	tokenResp, err := h.exchangeCodeForToken(code, codeVerifier)
+	tokenResp, err := h.exchangeCodeForToken(c.Request.Context(), code, codeVerifier)
 	if err != nil {
-		h.redirectWithError(c, "token_exchange_failed", err.Error())
+		h.redirectWithError(c, "token_exchange_failed", ssoErrorDescription("Vault", err))
 		return
 	}
 
@@ -162,16 +166,14 @@ func (h *VaultOIDCHandler) HandleVaultCallback(c *gin.Context) {
 		database.DB.Preload("Policies").First(user, user.ID)
 	}
 
-	// Generate our JWT tokens
-	accessTokenDuration, _ := time.ParseDuration(h.config.Auth.AccessTokenExpiry)
-	jwtToken, err := GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, accessTokenDuration)
-	if err != nil {
-		h.redirectWithError(c, "token_generation_failed", err.Error())
+	// Optionally deny users without any policies
+	if h.config.Auth.RequirePolicyForLogin && !HasLoginPolicies(user) {
+		h.redirectWithError(c, "no_permissions", NoPermissionsMessage)
 		return
 	}
 
-	refreshTokenDuration, _ := time.ParseDuration(h.config.Auth.RefreshTokenExpiry)
-	refreshToken, err := GenerateToken(user.ID, user.Username, user.IsAdmin, h.config.Auth.JWTSecret, refreshTokenDuration)
+	// Generate our JWT tokens
+	jwtToken, refreshToken, err := GenerateTokenPair(user.ID, user.Username, user.IsAdmin, h.config.Auth)
 	if err != nil {
 		h.redirectWithError(c, "token_generation_failed", err.Error())
 		return
@@ -207,7 +209,7 @@ func (h *VaultOIDCHandler) buildAuthURL(state, codeChallenge string) string {
 }
 
 // exchangeCodeForToken exchanges authorization code for tokens using PKCE
-func (h *VaultOIDCHandler) exchangeCodeForToken(code, codeVerifier string) (*VaultTokenResponse, error) {
+func (h *VaultOIDCHandler) exchangeCodeForToken(ctx context.Context, code, codeVerifier string) (*VaultTokenResponse, error) {
 	// Use provider URL + /token
 	tokenEndpoint := strings.TrimSuffix(h.config.VaultSSO.ProviderURL, "/") + "/token"
 
@@ -219,13 +221,13 @@ func (h *VaultOIDCHandler) exchangeCodeForToken(code, codeVerifier string) (*Vau
 	// PKCE: send the original code_verifier (no client_secret needed for public clients)
 	data.Set("code_verifier", codeVerifier)
 
-	req, err := http.NewRequestWithContext(context.Background(), "POST", tokenEndpoint, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenEndpoint, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := h.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code: %w", err)
 	}
@@ -270,7 +272,7 @@ func (h *VaultOIDCHandler) findOrCreateUser(claims *VaultIDTokenClaims) (*models
 		return &user, nil
 	}
 
-	// Create new user
+	// Create new user (no policies unless SSO_DEFAULT_POLICIES is set)
 	username := claims.Name
 	if username == "" {
 		username = claims.Email
@@ -299,6 +301,8 @@ func (h *VaultOIDCHandler) findOrCreateUser(claims *VaultIDTokenClaims) (*models
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	ApplyDefaultUserProfile(&user, &h.config.Auth, true)
+
 	database.DB.Preload("Policies").First(&user, user.ID)
 	return &user, nil
 }
@@ -314,6 +318,7 @@ func (h *VaultOIDCHandler) syncUserPolicies(user *models.User, policyNames []str
 
 	if len(policies) > 0 {
 		database.DB.Model(user).Association("Policies").Replace(policies)
+		services.InvalidateUserPolicyCache(user.ID)
 	}
 }
 
@@ -2,8 +2,13 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"net"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -13,8 +18,10 @@ type Config struct {
 	Storage    StorageConfig
 	TLS        TLSConfig
 	CORS       CORSConfig
+	Security   SecurityHeadersConfig
 	GoogleSSO  GoogleSSOConfig
 	VaultSSO   VaultSSOConfig
+	SSOClient  SSOClientConfig
 }
 
 type DatabaseConfig struct {
@@ -24,19 +31,60 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// Connection pool and query limits
+	MaxOpenConns    int    // Upper bound on open connections
+	MaxIdleConns    int    // Idle connections kept for reuse
+	ConnMaxLifetime string // Connections are recycled after this long (picks up DNS/failover changes)
+	QueryTimeout    string // Default per-statement deadline, including the wait for a pooled connection; 0 disables
+
+	ConnMaxLifetimeDuration time.Duration // Parsed at startup
+	QueryTimeoutDuration    time.Duration
 }
 
 type ServerConfig struct {
 	Port        string
 	Host        string
 	FrontendURL string // URL where frontend is served (for SSO redirects)
+
+	MaintenanceMode bool // Start in maintenance (read-only) mode; otherwise the persisted state applies
+
+	// Honor the Accept header for error bodies: S3-style XML on /api when XML is requested,
+	// JSON on the S3 routes when JSON is requested (defaults are unchanged without Accept)
+	ErrorNegotiation bool
+
+	// Overall request deadlines (0 disables). Uploads, downloads and event streams get the larger
+	// transfer budget; requests slower than SlowRequestThreshold are logged
+	RequestTimeout                 string
+	TransferRequestTimeout         string
+	SlowRequestThreshold           string
+	RequestTimeoutDuration         time.Duration // Parsed at startup
+	TransferRequestTimeoutDuration time.Duration
+	SlowRequestThresholdDuration   time.Duration
+
+	// Buckets with a website configuration are served at <bucket>.<WebsiteDomain> (empty: only the
+	// /api/website/<bucket>/ path endpoint is available)
+	WebsiteDomain string
+
+	// Reverse proxies (IPs or CIDRs) whose forwarding headers are believed: X-Forwarded-For for
+	// the client address (c.ClientIP()) and X-Forwarded-Proto for S3_REQUIRE_TLS. Empty trusts none
+	TrustedProxies   []string
+	TrustedProxyNets []*net.IPNet // Parsed at startup
 }
 
 type TLSConfig struct {
-	Enabled  bool
-	CertFile string
-	KeyFile  string
-	CAFile   string
+	Enabled          bool
+	CertFile         string
+	KeyFile          string
+	CAFile           string
+	MinVersion       string   // "1.2" or "1.3"
+	CipherSuites     []string // IANA cipher suite names (only applies to TLS 1.2; TLS 1.3 suites are fixed)
+	ClientAuth       string   // "none", "request", "verify_if_given", or "require"
+	S3ClientCertAuth string   // "disabled", "cert" (certificate replaces SigV4), or "combined" (certificate + SigV4)
+
+	// Reject S3 API requests that didn't reach bkt (or a trusted proxy, see
+	// ServerConfig.TrustedProxies) over TLS
+	S3RequireTLS bool
 }
 
 type AuthConfig struct {
@@ -48,13 +96,140 @@ type AuthConfig struct {
 	AdminPassword        string
 	AdminEmail           string
 	AllowRegistration    bool
+	SlidingSessions      bool          // Refreshing also renews the refresh token, up to SessionMaxLifetime
+	SessionMaxLifetime   string        // Absolute session cap when sliding sessions are enabled
+	AccessTokenDuration  time.Duration // Parsed at startup; use these instead of re-parsing the strings
+	RefreshTokenDuration time.Duration
+	SessionMaxDuration   time.Duration
+
+	// Deny login (all methods) to non-admin users without any policies
+	RequirePolicyForLogin bool
+
+	// Policy names attached to SSO users when their account is first created (empty = none)
+	SSODefaultPolicies []string
+
+	// Default user profile applied to every new account (local, registered and SSO): attached
+	// policy names, and the active access key limit (admins can change both per user afterwards)
+	DefaultUserPolicies      []string
+	DefaultUserMaxAccessKeys int
+
+	// How long a rotated access key keeps working alongside its replacement
+	AccessKeyRotationGrace         string
+	AccessKeyRotationGraceDuration time.Duration
+
+	// Record every S3 request signed with an access key in the audit log (per-key activity)
+	AuditS3Requests bool
+
+	// Lifetime of service account tokens (non-login machine identities)
+	ServiceAccountTokenExpiry   string
+	ServiceAccountTokenDuration time.Duration
+
+	// Optional HttpOnly cookie sessions for the web UI (bearer tokens keep working)
+	CookieSessions bool
+	CookieSecure   bool
+	CookieSameSite string // "strict", "lax" or "none"
+
+	// Confirmation token the metadata export/import endpoints require; empty disables them
+	MetadataTransferToken string
+
+	// How object reads/deletes the caller isn't allowed to make are answered: "precise" (default)
+	// returns 404 for missing objects and 403 for existing ones, "hide" returns 404 as if the
+	// object didn't exist
+	ObjectDenialMode string
+
+	// How long users' and buckets' policies are cached for permission checks (0 disables). Other
+	// instances see policy changes once their entries expire
+	PolicyCacheTTL         string
+	PolicyCacheTTLDuration time.Duration
+
+	// Bucket policy template stored as the initial policy of every new bucket, with ${bucket}
+	// replaced by the bucket name (empty = new buckets start without a policy)
+	DefaultBucketPolicy string
 }
 
 type StorageConfig struct {
-	Backend     string // "local" or "s3"
-	RootPath    string // For local storage
-	MaxFileSize int64
-	S3          S3Config
+	Backend           string // "local" or "s3"
+	RootPath          string // For local storage
+	LocalLayout       string // "flat" or "fanout"; applies to local buckets created afterwards
+	MaxFileSize       int64
+	S3                S3Config
+	ReconcileInterval string // e.g. "24h"; empty disables scheduled storage/DB reconciliation
+
+	// Deleted buckets stay in the trash (restorable by admins) this long before they are purged,
+	// e.g. "72h"; empty or 0 deletes immediately. DELETE ?force=true always deletes immediately
+	BucketDeleteGrace         string
+	BucketDeleteGraceDuration time.Duration
+	ReconcileAutoFix  bool   // Scheduled runs repair discrepancies instead of only reporting them
+
+	// Integrity scrubbing: objects are re-read and their SHA256 re-verified once per ScrubInterval
+	// (e.g. "168h"; empty disables), reading at most ScrubBandwidth bytes per second
+	ScrubInterval  string
+	ScrubBandwidth int64
+
+	// Decompression abuse guard for compressed (gzip) uploads; 0 disables the respective bound
+	MaxDecompressionRatio int64 // Max decompressed/compressed size ratio
+	MaxDecompressedSize   int64 // Max decompressed size in bytes
+
+	MaxConcurrentUploads int // Background (async/resumable) uploads processed at once; 0 = unlimited
+
+	// Object downloads (REST and S3 GETs) one user, and one access key, may stream at once; 0 =
+	// unlimited. Downloads smaller than DownloadLimitMinSize bytes aren't counted
+	MaxConcurrentDownloads       int
+	MaxConcurrentDownloadsPerKey int
+	DownloadLimitMinSize         int64
+
+	PreviewMaxBytes int64 // Max bytes returned by the object preview endpoint
+
+	// Client-declared content types honored over magic-number detection (never active/dangerous types)
+	TrustedContentTypes []string
+
+	// Extension (".json") -> type used when magic-number detection only finds text/plain or
+	// application/octet-stream. Parsed from EXTENSION_CONTENT_TYPES
+	ExtensionContentTypes map[string]string
+
+	// Verify x-amz-checksum-* values sent with S3 PUTs (headers or aws-chunked trailers) and return them on reads
+	S3ChecksumValidation bool
+
+	// Per content type size limits, checked in order (first matching pattern wins); they can only
+	// tighten MaxFileSize. Parsed from CONTENT_TYPE_SIZE_LIMITS
+	ContentTypeSizeLimits []ContentTypeSizeLimit
+
+	Scanner ScannerConfig
+
+	AccessLog AccessLogConfig
+}
+
+// ContentTypeSizeLimit caps the size of uploads whose media type matches Pattern
+type ContentTypeSizeLimit struct {
+	Pattern string // Media type or glob, e.g. "image/*" or "application/zip"
+	MaxSize int64  // Bytes
+}
+
+// ContentTypeSizeLimit returns the first size rule matching a content type (parameters are ignored)
+func (s *StorageConfig) ContentTypeSizeLimit(contentType string) (ContentTypeSizeLimit, bool) {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, rule := range s.ContentTypeSizeLimits {
+		if matched, _ := path.Match(rule.Pattern, mediaType); matched {
+			return rule, true
+		}
+	}
+	return ContentTypeSizeLimit{}, false
+}
+
+// ScannerConfig configures the optional malware scan of background (async/resumable) uploads
+type ScannerConfig struct {
+	Mode            string // "" (disabled), "clamav" or "http"
+	Address         string // clamd host:port for clamav, scan endpoint URL for http
+	Timeout         string // Per-file scan timeout, e.g. "2m"
+	TimeoutDuration time.Duration
+	FailOpen        bool // Publish uploads that couldn't be scanned instead of failing them
+}
+
+// AccessLogConfig controls delivery of per-bucket server access logs
+type AccessLogConfig struct {
+	FlushInterval         string // How often buffered lines are written as log objects, e.g. "5m"
+	FlushIntervalDuration time.Duration
+	Format                string // "text" (space-separated, S3 style) or "json" (one object per line)
 }
 
 type S3Config struct {
@@ -64,6 +239,7 @@ type S3Config struct {
 	AccessKeyID     string
 	SecretAccessKey string
 	BucketPrefix    string // Prefix for all bucket names
+	ObjectKeyPrefix string // Prefix for all object keys (namespaces logical buckets in a shared backend bucket)
 	UseSSL          bool
 	ForcePathStyle  bool   // Required for MinIO
 }
@@ -96,11 +272,45 @@ type VaultSSOConfig struct {
 	Scopes      string // space-separated, e.g., "openid profile"
 }
 
+// SSOClientConfig bounds the outbound calls made to SSO identity providers during login
+type SSOClientConfig struct {
+	Timeout         string // Per attempt, e.g. "10s"
+	TimeoutDuration time.Duration
+	MaxRetries      int // Extra attempts after a transient failure (network error, 502/503/504)
+
+	// After BreakerThreshold consecutive failed calls to a provider, its logins fail fast for
+	// BreakerCooldown before a single call is let through to probe it; 0 disables the breaker
+	BreakerThreshold        int
+	BreakerCooldown         string
+	BreakerCooldownDuration time.Duration
+}
+
 type CORSConfig struct {
 	AllowedOrigins   []string
 	AllowCredentials bool
 }
 
+// SecurityHeadersConfig controls the browser security headers and accepted HTTP methods
+type SecurityHeadersConfig struct {
+	Enabled               bool
+	HSTSMaxAge            int64    // Strict-Transport-Security max-age in seconds (HTTPS only); 0 disables
+	FrameOptions          string   // X-Frame-Options; empty disables
+	ContentSecurityPolicy string   // CSP for API responses; empty disables
+	ReferrerPolicy        string   // Referrer-Policy; empty disables
+	AllowedMethods        []string // Requests with other methods get 405
+}
+
+// MaxAccessKeysPerUser bounds the per-user active access key limit (default and per-user overrides)
+const MaxAccessKeysPerUser = 100
+
+// defaultTLSCipherSuites restricts TLS 1.2 to forward-secret AEAD suites
+const defaultTLSCipherSuites = "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384," +
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384," +
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256," +
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256," +
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256," +
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"
+
 func Load() *Config {
 	cfg := &Config{
 		Database: DatabaseConfig{
@@ -110,11 +320,29 @@ func Load() *Config {
 			Password: getEnv("DB_PASSWORD", "objectstore_dev_password"),
 			DBName:   getEnv("DB_NAME", "objectstore"),
 			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
+
+			MaxOpenConns:    int(getEnvInt64("DB_MAX_OPEN_CONNS", 25)),
+			MaxIdleConns:    int(getEnvInt64("DB_MAX_IDLE_CONNS", 10)),
+			ConnMaxLifetime: getEnv("DB_CONN_MAX_LIFETIME", "1h"),
+			QueryTimeout:    getEnv("DB_QUERY_TIMEOUT", "30s"),
 		},
 		Server: ServerConfig{
 			Port:        getEnv("SERVER_PORT", "9000"),
 			Host:        getEnv("SERVER_HOST", "0.0.0.0"),
 			FrontendURL: getEnv("FRONTEND_URL", "https://localhost"),
+
+			MaintenanceMode: getEnv("MAINTENANCE_MODE", "false") == "true",
+
+			ErrorNegotiation: getEnv("ERROR_CONTENT_NEGOTIATION", "true") == "true",
+
+			RequestTimeout:         getEnv("REQUEST_TIMEOUT", "5m"),
+			TransferRequestTimeout: getEnv("TRANSFER_REQUEST_TIMEOUT", "2h"),
+			SlowRequestThreshold:   getEnv("SLOW_REQUEST_THRESHOLD", "10s"),
+
+			WebsiteDomain: strings.ToLower(strings.Trim(getEnv("WEBSITE_DOMAIN", ""), ". ")),
+
+			// S3_TRUSTED_PROXIES is the older name, from when the list only applied to S3_REQUIRE_TLS
+			TrustedProxies: splitAndTrim(getEnv("TRUSTED_PROXIES", getEnv("S3_TRUSTED_PROXIES", "")), ","),
 		},
 		Auth: AuthConfig{
 			JWTSecret:          getEnv("JWT_SECRET", "dev_jwt_secret_change_in_production"),
@@ -125,10 +353,36 @@ func Load() *Config {
 			AdminPassword:      getEnv("ADMIN_PASSWORD", ""),
 			AdminEmail:         getEnv("ADMIN_EMAIL", "admin@localhost"),
 			AllowRegistration:  getEnv("ALLOW_REGISTRATION", "false") == "true",
+			SlidingSessions:    getEnv("SLIDING_SESSIONS", "false") == "true",
+			SessionMaxLifetime: getEnv("SESSION_MAX_LIFETIME", "720h"), // 30 days
+
+			RequirePolicyForLogin: getEnv("REQUIRE_POLICY_FOR_LOGIN", "false") == "true",
+			SSODefaultPolicies:    splitAndTrim(getEnv("SSO_DEFAULT_POLICIES", ""), ","),
+
+			DefaultUserPolicies:      splitAndTrim(getEnv("DEFAULT_USER_POLICIES", ""), ","),
+			DefaultUserMaxAccessKeys: int(getEnvInt64("DEFAULT_USER_MAX_ACCESS_KEYS", 5)),
+
+			AccessKeyRotationGrace: getEnv("ACCESS_KEY_ROTATION_GRACE", "24h"),
+			AuditS3Requests:        getEnv("AUDIT_S3_REQUESTS", "true") == "true",
+
+			ServiceAccountTokenExpiry: getEnv("SERVICE_ACCOUNT_TOKEN_EXPIRY", "8760h"), // 1 year
+
+			CookieSessions: getEnv("COOKIE_SESSIONS_ENABLED", "false") == "true",
+			CookieSecure:   getEnv("COOKIE_SECURE", "true") == "true",
+			CookieSameSite: getEnv("COOKIE_SAMESITE", "strict"),
+
+			MetadataTransferToken: getEnv("METADATA_TRANSFER_TOKEN", ""),
+
+			ObjectDenialMode: strings.ToLower(getEnv("OBJECT_DENIAL_MODE", "precise")),
+
+			PolicyCacheTTL: getEnv("POLICY_CACHE_TTL", "30s"),
+
+			DefaultBucketPolicy: strings.TrimSpace(getEnv("DEFAULT_BUCKET_POLICY", "")),
 		},
 		Storage: StorageConfig{
 			Backend:     getEnv("STORAGE_BACKEND", "local"), // "local" or "s3"
 			RootPath:    getEnv("STORAGE_ROOT", "/data/buckets"),
+			LocalLayout: getEnv("LOCAL_STORAGE_LAYOUT", "flat"),
 			MaxFileSize: 5 * 1024 * 1024 * 1024, // 5GB
 			S3: S3Config{
 				Enabled:         getEnv("S3_ENABLED", "false") == "true",
@@ -137,17 +391,63 @@ func Load() *Config {
 				AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
 				SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
 				BucketPrefix:    getEnv("S3_BUCKET_PREFIX", ""),
+				ObjectKeyPrefix: getEnv("S3_OBJECT_KEY_PREFIX", ""),
 				UseSSL:          getEnv("S3_USE_SSL", "true") == "true",
 				ForcePathStyle:  getEnv("S3_FORCE_PATH_STYLE", "false") == "true",
 			},
+			ReconcileInterval: getEnv("RECONCILE_INTERVAL", ""),
+			BucketDeleteGrace: getEnv("BUCKET_DELETE_GRACE", "0"),
+			ReconcileAutoFix:  getEnv("RECONCILE_AUTO_FIX", "false") == "true",
+
+			ScrubInterval:  getEnv("SCRUB_INTERVAL", ""),
+			ScrubBandwidth: getEnvInt64("SCRUB_BANDWIDTH", 10*1024*1024), // 10MB/s
+
+			MaxDecompressionRatio: getEnvInt64("MAX_DECOMPRESSION_RATIO", 100),
+			MaxDecompressedSize:   getEnvInt64("MAX_DECOMPRESSED_SIZE", 10*1024*1024*1024), // 10GB
+
+			MaxConcurrentUploads: int(getEnvInt64("MAX_CONCURRENT_UPLOADS", 4)),
+
+			MaxConcurrentDownloads:       int(getEnvInt64("MAX_CONCURRENT_DOWNLOADS", 32)),
+			MaxConcurrentDownloadsPerKey: int(getEnvInt64("MAX_CONCURRENT_DOWNLOADS_PER_KEY", 16)),
+			DownloadLimitMinSize:         getEnvInt64("DOWNLOAD_LIMIT_MIN_SIZE", 1024*1024), // 1MB
+
+			PreviewMaxBytes: getEnvInt64("PREVIEW_MAX_BYTES", 64*1024), // 64KB
+
+			TrustedContentTypes: splitAndTrim(strings.ToLower(getEnv("TRUSTED_CONTENT_TYPES", "")), ","),
+
+			S3ChecksumValidation: getEnv("S3_CHECKSUM_VALIDATION", "true") == "true",
+
+			Scanner: ScannerConfig{
+				Mode:     strings.ToLower(getEnv("UPLOAD_SCANNER", "")),
+				Address:  getEnv("UPLOAD_SCANNER_ADDRESS", ""),
+				Timeout:  getEnv("UPLOAD_SCANNER_TIMEOUT", "2m"),
+				FailOpen: getEnv("UPLOAD_SCANNER_FAIL_OPEN", "false") == "true",
+			},
+			AccessLog: AccessLogConfig{
+				FlushInterval: getEnv("ACCESS_LOG_FLUSH_INTERVAL", "5m"),
+				Format:        strings.ToLower(getEnv("ACCESS_LOG_FORMAT", "text")),
+			},
 		},
 		TLS: TLSConfig{
-			Enabled:  getEnv("TLS_ENABLED", "false") == "true",
-			CertFile: getEnv("TLS_CERT_FILE", ""),
-			KeyFile:  getEnv("TLS_KEY_FILE", ""),
-			CAFile:   getEnv("TLS_CA_FILE", ""),
+			Enabled:          getEnv("TLS_ENABLED", "false") == "true",
+			CertFile:         getEnv("TLS_CERT_FILE", ""),
+			KeyFile:          getEnv("TLS_KEY_FILE", ""),
+			CAFile:           getEnv("TLS_CA_FILE", ""),
+			MinVersion:       getEnv("TLS_MIN_VERSION", "1.2"),
+			CipherSuites:     splitAndTrim(getEnv("TLS_CIPHER_SUITES", defaultTLSCipherSuites), ","),
+			ClientAuth:       getEnv("TLS_CLIENT_AUTH", "none"),
+			S3ClientCertAuth: getEnv("S3_CLIENT_CERT_AUTH", "disabled"),
+			S3RequireTLS:     getEnv("S3_REQUIRE_TLS", "true") == "true",
 		},
 		CORS: loadCORSConfig(),
+		Security: SecurityHeadersConfig{
+			Enabled:               getEnv("SECURITY_HEADERS_ENABLED", "true") == "true",
+			HSTSMaxAge:            getEnvInt64("HSTS_MAX_AGE", 31536000), // 1 year
+			FrameOptions:          getEnv("X_FRAME_OPTIONS", "DENY"),
+			ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+			ReferrerPolicy:        getEnv("REFERRER_POLICY", "no-referrer"),
+			AllowedMethods:        splitAndTrim(strings.ToUpper(getEnv("ALLOWED_HTTP_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS,HEAD")), ","),
+		},
 		GoogleSSO: GoogleSSOConfig{
 			OIDCEnabled:             getEnv("GOOGLE_OIDC_ENABLED", "false") == "true",
 			ClientID:                getEnv("GOOGLE_CLIENT_ID", ""),
@@ -171,6 +471,83 @@ func Load() *Config {
 			RedirectURL: getEnv("VAULT_OIDC_REDIRECT_URL", "https://localhost:9443/api/auth/vault/callback"),
 			Scopes:      getEnv("VAULT_OIDC_SCOPES", "openid profile"),
 		},
+		SSOClient: SSOClientConfig{
+			Timeout:          getEnv("SSO_HTTP_TIMEOUT", "10s"),
+			MaxRetries:       int(getEnvInt64("SSO_HTTP_MAX_RETRIES", 2)),
+			BreakerThreshold: int(getEnvInt64("SSO_CIRCUIT_BREAKER_THRESHOLD", 5)),
+			BreakerCooldown:  getEnv("SSO_CIRCUIT_BREAKER_COOLDOWN", "30s"),
+		},
+	}
+
+	// Token durations are validated in every environment (a bad value would issue instantly-expired tokens)
+	if err := cfg.parseAuthDurations(); err != nil {
+		panic(fmt.Sprintf("Invalid token expiry configuration: %v", err))
+	}
+
+	if err := cfg.parseDatabaseLimits(); err != nil {
+		panic(fmt.Sprintf("Invalid database configuration: %v", err))
+	}
+
+	if err := cfg.parseRequestDurations(); err != nil {
+		panic(fmt.Sprintf("Invalid request timeout configuration: %v", err))
+	}
+
+	if err := cfg.parseContentTypeSizeLimits(getEnv("CONTENT_TYPE_SIZE_LIMITS", "")); err != nil {
+		panic(fmt.Sprintf("Invalid upload size configuration: %v", err))
+	}
+
+	if err := cfg.parseExtensionContentTypes(getEnv("EXTENSION_CONTENT_TYPES", ".json=application/json,.csv=text/csv,.md=text/markdown")); err != nil {
+		panic(fmt.Sprintf("Invalid content type configuration: %v", err))
+	}
+
+	if err := cfg.parseScannerConfig(); err != nil {
+		panic(fmt.Sprintf("Invalid upload scanner configuration: %v", err))
+	}
+
+	if err := cfg.parseAccessLogConfig(); err != nil {
+		panic(fmt.Sprintf("Invalid access log configuration: %v", err))
+	}
+
+	if err := cfg.parseSSOClientConfig(); err != nil {
+		panic(fmt.Sprintf("Invalid SSO client configuration: %v", err))
+	}
+
+	if err := cfg.parseTrustedProxies(); err != nil {
+		panic(fmt.Sprintf("Invalid trusted proxy configuration: %v", err))
+	}
+
+	if cfg.Auth.DefaultUserMaxAccessKeys < 1 || cfg.Auth.DefaultUserMaxAccessKeys > MaxAccessKeysPerUser {
+		panic(fmt.Sprintf("DEFAULT_USER_MAX_ACCESS_KEYS=%d is invalid (use 1 to %d)", cfg.Auth.DefaultUserMaxAccessKeys, MaxAccessKeysPerUser))
+	}
+
+	grace, err := time.ParseDuration(cfg.Storage.BucketDeleteGrace)
+	if err != nil || grace < 0 {
+		panic(fmt.Sprintf("BUCKET_DELETE_GRACE=%q is not a valid non-negative duration (use e.g. 72h, or 0 to delete immediately)", cfg.Storage.BucketDeleteGrace))
+	}
+	cfg.Storage.BucketDeleteGraceDuration = grace
+
+	switch cfg.Storage.LocalLayout {
+	case "flat", "fanout":
+	default:
+		panic(fmt.Sprintf("LOCAL_STORAGE_LAYOUT=%q is invalid (use flat or fanout)", cfg.Storage.LocalLayout))
+	}
+
+	switch cfg.Auth.ObjectDenialMode {
+	case "precise", "hide":
+	default:
+		panic(fmt.Sprintf("OBJECT_DENIAL_MODE=%q is invalid (use precise or hide)", cfg.Auth.ObjectDenialMode))
+	}
+
+	cfg.Auth.CookieSameSite = strings.ToLower(cfg.Auth.CookieSameSite)
+	switch cfg.Auth.CookieSameSite {
+	case "strict", "lax":
+	case "none":
+		// Browsers drop SameSite=None cookies that aren't also Secure
+		if !cfg.Auth.CookieSecure {
+			panic("COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+		}
+	default:
+		panic(fmt.Sprintf("COOKIE_SAMESITE=%q is invalid (use strict, lax or none)", cfg.Auth.CookieSameSite))
 	}
 
 	// Validate critical secrets in production
@@ -238,6 +615,269 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// parseAuthDurations parses and validates the token and session durations
+func (c *Config) parseAuthDurations() error {
+	var err error
+
+	c.Auth.AccessTokenDuration, err = parsePositiveDuration("ACCESS_TOKEN_EXPIRY", c.Auth.AccessTokenExpiry)
+	if err != nil {
+		return err
+	}
+
+	c.Auth.RefreshTokenDuration, err = parsePositiveDuration("REFRESH_TOKEN_EXPIRY", c.Auth.RefreshTokenExpiry)
+	if err != nil {
+		return err
+	}
+
+	if c.Auth.RefreshTokenDuration < c.Auth.AccessTokenDuration {
+		return fmt.Errorf("REFRESH_TOKEN_EXPIRY (%s) must not be shorter than ACCESS_TOKEN_EXPIRY (%s)",
+			c.Auth.RefreshTokenExpiry, c.Auth.AccessTokenExpiry)
+	}
+
+	c.Auth.AccessKeyRotationGraceDuration, err = time.ParseDuration(c.Auth.AccessKeyRotationGrace)
+	if err != nil || c.Auth.AccessKeyRotationGraceDuration < 0 {
+		return fmt.Errorf("ACCESS_KEY_ROTATION_GRACE=%q is not a valid non-negative duration (use e.g. 1h or 24h)", c.Auth.AccessKeyRotationGrace)
+	}
+
+	c.Auth.ServiceAccountTokenDuration, err = parsePositiveDuration("SERVICE_ACCOUNT_TOKEN_EXPIRY", c.Auth.ServiceAccountTokenExpiry)
+	if err != nil {
+		return err
+	}
+
+	c.Auth.PolicyCacheTTLDuration, err = time.ParseDuration(c.Auth.PolicyCacheTTL)
+	if err != nil || c.Auth.PolicyCacheTTLDuration < 0 {
+		return fmt.Errorf("POLICY_CACHE_TTL=%q is not a valid non-negative duration (use e.g. 30s, or 0 to disable)", c.Auth.PolicyCacheTTL)
+	}
+
+	if c.Auth.SlidingSessions {
+		c.Auth.SessionMaxDuration, err = parsePositiveDuration("SESSION_MAX_LIFETIME", c.Auth.SessionMaxLifetime)
+		if err != nil {
+			return err
+		}
+		if c.Auth.SessionMaxDuration < c.Auth.RefreshTokenDuration {
+			return fmt.Errorf("SESSION_MAX_LIFETIME (%s) must not be shorter than REFRESH_TOKEN_EXPIRY (%s)",
+				c.Auth.SessionMaxLifetime, c.Auth.RefreshTokenExpiry)
+		}
+	}
+
+	return nil
+}
+
+// parseDatabaseLimits validates the connection pool settings and parses the pool/query durations
+func (c *Config) parseDatabaseLimits() error {
+	var err error
+
+	if c.Database.MaxOpenConns < 1 {
+		return fmt.Errorf("DB_MAX_OPEN_CONNS=%d must be at least 1", c.Database.MaxOpenConns)
+	}
+	if c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS (%d) must not exceed DB_MAX_OPEN_CONNS (%d)",
+			c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+
+	c.Database.ConnMaxLifetimeDuration, err = parsePositiveDuration("DB_CONN_MAX_LIFETIME", c.Database.ConnMaxLifetime)
+	if err != nil {
+		return err
+	}
+
+	c.Database.QueryTimeoutDuration, err = time.ParseDuration(c.Database.QueryTimeout)
+	if err != nil || c.Database.QueryTimeoutDuration < 0 {
+		return fmt.Errorf("DB_QUERY_TIMEOUT=%q is not a valid non-negative duration (use e.g. 30s, or 0 to disable)", c.Database.QueryTimeout)
+	}
+
+	return nil
+}
+
+// parseRequestDurations parses the request deadline and slow-request threshold (each may be 0 to disable)
+func (c *Config) parseRequestDurations() error {
+	durations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"REQUEST_TIMEOUT", c.Server.RequestTimeout, &c.Server.RequestTimeoutDuration},
+		{"TRANSFER_REQUEST_TIMEOUT", c.Server.TransferRequestTimeout, &c.Server.TransferRequestTimeoutDuration},
+		{"SLOW_REQUEST_THRESHOLD", c.Server.SlowRequestThreshold, &c.Server.SlowRequestThresholdDuration},
+	}
+	for _, d := range durations {
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("%s=%q is not a valid non-negative duration (use e.g. 5m, or 0 to disable)", d.name, d.value)
+		}
+		*d.dest = parsed
+	}
+	return nil
+}
+
+// parseContentTypeSizeLimits parses comma-separated "pattern=size" rules, e.g. "image/*=10MB,application/zip=5GB"
+func (c *Config) parseContentTypeSizeLimits(value string) error {
+	for _, entry := range splitAndTrim(value, ",") {
+		pattern, size, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("CONTENT_TYPE_SIZE_LIMITS entry %q must be pattern=size", entry)
+		}
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if _, err := path.Match(pattern, ""); err != nil || !strings.Contains(pattern, "/") {
+			return fmt.Errorf("CONTENT_TYPE_SIZE_LIMITS pattern %q is not a media type or glob like image/*", pattern)
+		}
+		maxSize, err := parseByteSize(strings.TrimSpace(size))
+		if err != nil {
+			return fmt.Errorf("CONTENT_TYPE_SIZE_LIMITS size for %q: %w", pattern, err)
+		}
+		c.Storage.ContentTypeSizeLimits = append(c.Storage.ContentTypeSizeLimits, ContentTypeSizeLimit{
+			Pattern: pattern,
+			MaxSize: maxSize,
+		})
+	}
+	return nil
+}
+
+// parseExtensionContentTypes parses comma-separated "extension=type" pairs, e.g. ".json=application/json".
+// "none" disables extension refinement
+func (c *Config) parseExtensionContentTypes(value string) error {
+	c.Storage.ExtensionContentTypes = make(map[string]string)
+	if strings.EqualFold(strings.TrimSpace(value), "none") {
+		return nil
+	}
+	for _, entry := range splitAndTrim(value, ",") {
+		ext, contentType, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("EXTENSION_CONTENT_TYPES entry %q must be extension=type", entry)
+		}
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		c.Storage.ExtensionContentTypes[ext] = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return nil
+}
+
+// parseByteSize parses a size in bytes with an optional binary unit suffix (KB, MB, GB, TB)
+func parseByteSize(value string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	number, multiplier := strings.ToUpper(value), int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, multiplier = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix)), unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("%q is not a valid size (use e.g. 10MB or 5GB)", value)
+	}
+	return n * multiplier, nil
+}
+
+// parseScannerConfig validates the upload scanner settings when scanning is enabled
+func (c *Config) parseScannerConfig() error {
+	scanner := &c.Storage.Scanner
+	switch scanner.Mode {
+	case "":
+		return nil
+	case "clamav", "http":
+	default:
+		return fmt.Errorf("UPLOAD_SCANNER=%q is invalid (use clamav or http, or leave empty to disable)", scanner.Mode)
+	}
+
+	if scanner.Address == "" {
+		return fmt.Errorf("UPLOAD_SCANNER=%s requires UPLOAD_SCANNER_ADDRESS", scanner.Mode)
+	}
+	if scanner.Mode == "http" && !strings.HasPrefix(scanner.Address, "http://") && !strings.HasPrefix(scanner.Address, "https://") {
+		return fmt.Errorf("UPLOAD_SCANNER_ADDRESS=%q must be an http(s) URL", scanner.Address)
+	}
+
+	var err error
+	scanner.TimeoutDuration, err = parsePositiveDuration("UPLOAD_SCANNER_TIMEOUT", scanner.Timeout)
+	return err
+}
+
+// parseAccessLogConfig validates the access log format and parses the flush interval
+func (c *Config) parseAccessLogConfig() error {
+	accessLog := &c.Storage.AccessLog
+	switch accessLog.Format {
+	case "text", "json":
+	default:
+		return fmt.Errorf("ACCESS_LOG_FORMAT=%q is invalid (use text or json)", accessLog.Format)
+	}
+
+	var err error
+	accessLog.FlushIntervalDuration, err = parsePositiveDuration("ACCESS_LOG_FLUSH_INTERVAL", accessLog.FlushInterval)
+	return err
+}
+
+// parseTrustedProxies parses TRUSTED_PROXIES; a bare IP is treated as a single-address network.
+// The router and the S3 TLS check both use the parsed networks, so they agree on every peer
+func (c *Config) parseTrustedProxies() error {
+	c.Server.TrustedProxyNets = nil
+	for _, entry := range c.Server.TrustedProxies {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return fmt.Errorf("TRUSTED_PROXIES entry %q is not an IP address or CIDR", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			c.Server.TrustedProxyNets = append(c.Server.TrustedProxyNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("TRUSTED_PROXIES entry %q is not an IP address or CIDR", entry)
+		}
+		c.Server.TrustedProxyNets = append(c.Server.TrustedProxyNets, network)
+	}
+	return nil
+}
+
+// parseSSOClientConfig validates the retry and circuit breaker settings and parses their durations
+func (c *Config) parseSSOClientConfig() error {
+	var err error
+	sso := &c.SSOClient
+
+	sso.TimeoutDuration, err = parsePositiveDuration("SSO_HTTP_TIMEOUT", sso.Timeout)
+	if err != nil {
+		return err
+	}
+
+	if sso.MaxRetries < 0 || sso.MaxRetries > 10 {
+		return fmt.Errorf("SSO_HTTP_MAX_RETRIES=%d must be between 0 and 10", sso.MaxRetries)
+	}
+
+	if sso.BreakerThreshold < 0 {
+		return fmt.Errorf("SSO_CIRCUIT_BREAKER_THRESHOLD=%d must not be negative", sso.BreakerThreshold)
+	}
+
+	sso.BreakerCooldownDuration, err = parsePositiveDuration("SSO_CIRCUIT_BREAKER_COOLDOWN", sso.BreakerCooldown)
+	return err
+}
+
+// parsePositiveDuration parses a Go duration string (e.g. "15m", "168h") that must be greater than zero
+func parsePositiveDuration(name, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s=%q is not a valid duration (use e.g. 15m or 168h): %w", name, value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s=%q must be greater than zero", name, value)
+	}
+	return d, nil
+}
+
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
@@ -257,6 +897,19 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt64 reads a non-negative integer from the environment, panicking on invalid values
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		panic(fmt.Sprintf("Invalid configuration: %s=%q must be a non-negative integer", key, value))
+	}
+	return n
+}
+
 // loadCORSConfig loads CORS configuration from environment or uses secure defaults
 func loadCORSConfig() CORSConfig {
 	// Check if custom origins are set via environment variable (comma-separated)
@@ -2,7 +2,7 @@ package database
 
 import (
 	"fmt"
-	"time"
+
 	"bkt/internal/config"
 	"bkt/internal/logger"
 	"bkt/internal/models"
@@ -34,19 +34,27 @@ func Initialize(cfg *config.Config) error {
 	}
 
 	// Set maximum number of open connections (prevents exhausting database resources)
-	sqlDB.SetMaxOpenConns(25)
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
 
 	// Set maximum number of idle connections (reduces overhead)
-	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
 
 	// Set maximum lifetime of a connection (prevents stale connections)
-	// 1 hour - forces connection refresh to pick up DNS/network changes
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	// Forces connection refresh to pick up DNS/network changes
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetimeDuration)
+
+	// Default per-statement deadline so slow queries can't pile up pooled connections
+	if err := registerQueryTimeout(DB, cfg.Database.QueryTimeoutDuration); err != nil {
+		return fmt.Errorf("failed to register query timeout: %w", err)
+	}
 
 	logger.Info("Database connection established", map[string]interface{}{
 		"host": cfg.Database.Host,
 		"port": cfg.Database.Port,
 		"db":   cfg.Database.DBName,
+
+		"max_open_conns": cfg.Database.MaxOpenConns,
+		"query_timeout":  cfg.Database.QueryTimeoutDuration.String(),
 	})
 
 	// Run auto migrations
@@ -61,6 +69,18 @@ func Initialize(cfg *config.Config) error {
 		&models.AuditLog{},
 		&models.IdempotencyKey{},
 		&models.Upload{},
+		&models.ClientCertBinding{},
+		&models.UsageStat{},
+		&models.SystemSetting{},
+		&models.RevokedToken{},
+		&models.UserTokenRevocation{},
+		&models.BucketWebsite{},
+		&models.ShareLink{},
+		&models.UserObjectAccess{},
+		&models.MultipartUpload{},
+		&models.MultipartPart{},
+		&models.ObjectVersion{},
+		&models.ObjectTag{},
 	)
 
 	if err != nil {
@@ -85,6 +105,24 @@ func Initialize(cfg *config.Config) error {
 		logger.Info("Performance indexes created", nil)
 	}
 
+	// GIN index for ListObjects metadata filters (?meta.key=value, a jsonb containment query)
+	err = DB.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_objects_metadata
+		ON objects USING gin (metadata jsonb_path_ops)
+	`).Error
+	if err != nil {
+		logger.Warn("Failed to create metadata index", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	// Idempotency keys are unique per user (idx_idempotency_user_key); drop the legacy global unique index
+	if err := DB.Exec(`DROP INDEX IF EXISTS idx_idempotency_keys_key`).Error; err != nil {
+		logger.Warn("Failed to drop legacy idempotency key index", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
 	return nil
 }
 
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Statement settings keys used to undo the per-query deadline once the statement finishes
+const (
+	queryTimeoutCancelKey = "bkt:query_timeout_cancel"
+	queryTimeoutParentKey = "bkt:query_timeout_parent"
+)
+
+// registerQueryTimeout gives every statement without a deadline of its own a default one.
+// The deadline also bounds the wait for a pooled connection, so a request fails fast with
+// context.DeadlineExceeded instead of queueing indefinitely when the pool is exhausted.
+// Long-running jobs opt out by passing their own context via DB.WithContext
+func registerQueryTimeout(db *gorm.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+
+	start := func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if _, hasDeadline := ctx.Deadline(); hasDeadline {
+			return
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		db.Statement.Settings.Store(queryTimeoutParentKey, ctx)
+		db.Statement.Settings.Store(queryTimeoutCancelKey, cancel)
+		db.Statement.Context = timeoutCtx
+	}
+
+	// finish restores the caller's context so a reused query chain doesn't inherit a spent deadline
+	finish := func(release bool) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			if parent, ok := db.Statement.Settings.LoadAndDelete(queryTimeoutParentKey); ok {
+				db.Statement.Context = parent.(context.Context)
+			}
+			cancel, ok := db.Statement.Settings.LoadAndDelete(queryTimeoutCancelKey)
+			if ok && release {
+				cancel.(context.CancelFunc)()
+			}
+		}
+	}
+
+	// Row/Rows results are read after the callbacks return, so their deadline is left to expire on its own
+	cb := db.Callback()
+	registrations := []func() error{
+		func() error { return cb.Create().Before("*").Register("bkt:query_timeout_start", start) },
+		func() error { return cb.Create().After("*").Register("bkt:query_timeout_finish", finish(true)) },
+		func() error { return cb.Query().Before("*").Register("bkt:query_timeout_start", start) },
+		func() error { return cb.Query().After("*").Register("bkt:query_timeout_finish", finish(true)) },
+		func() error { return cb.Update().Before("*").Register("bkt:query_timeout_start", start) },
+		func() error { return cb.Update().After("*").Register("bkt:query_timeout_finish", finish(true)) },
+		func() error { return cb.Delete().Before("*").Register("bkt:query_timeout_start", start) },
+		func() error { return cb.Delete().After("*").Register("bkt:query_timeout_finish", finish(true)) },
+		func() error { return cb.Raw().Before("*").Register("bkt:query_timeout_start", start) },
+		func() error { return cb.Raw().After("*").Register("bkt:query_timeout_finish", finish(true)) },
+		func() error { return cb.Row().Before("*").Register("bkt:query_timeout_start", start) },
+		func() error { return cb.Row().After("*").Register("bkt:query_timeout_finish", finish(false)) },
+	}
+	for _, register := range registrations {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithTimeout returns a session whose statements share a deadline of their own, for work that is
+// expected to outlast the default query timeout (bulk sync batches, aggregates over large tables)
+func WithTimeout(timeout time.Duration) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	return DB.WithContext(ctx), cancel
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ActiveRequest is an in-flight S3 request signed with an access key
+type ActiveRequest struct {
+	ID        uuid.UUID `json:"id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	IPAddress string    `json:"ip_address"`
+	StartedAt time.Time `json:"started_at"`
+
+	cancel context.CancelFunc
+}
+
+// In-flight requests per access key ID (per instance), so a compromised key's
+// running transfers can be listed and aborted
+var (
+	activeKeyRequests   = make(map[uuid.UUID]map[uuid.UUID]*ActiveRequest)
+	activeKeyRequestsMu sync.Mutex
+)
+
+// cancelableBody fails reads once the request is cancelled, aborting uploads that are
+// still streaming their body to storage
+type cancelableBody struct {
+	io.ReadCloser
+	ctx context.Context
+}
+
+func (b *cancelableBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return b.ReadCloser.Read(p)
+}
+
+// trackAccessKeyRequest registers the request under its access key and makes it cancellable.
+// Call the returned func when the request finishes
+func trackAccessKeyRequest(c *gin.Context, accessKeyID uuid.UUID) func() {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	c.Request = c.Request.WithContext(ctx)
+	if c.Request.Body != nil {
+		c.Request.Body = &cancelableBody{ReadCloser: c.Request.Body, ctx: ctx}
+	}
+
+	req := &ActiveRequest{
+		ID:        uuid.New(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		IPAddress: c.ClientIP(),
+		StartedAt: time.Now(),
+		cancel:    cancel,
+	}
+
+	activeKeyRequestsMu.Lock()
+	reqs, exists := activeKeyRequests[accessKeyID]
+	if !exists {
+		reqs = make(map[uuid.UUID]*ActiveRequest)
+		activeKeyRequests[accessKeyID] = reqs
+	}
+	reqs[req.ID] = req
+	activeKeyRequestsMu.Unlock()
+
+	return func() {
+		activeKeyRequestsMu.Lock()
+		delete(reqs, req.ID)
+		if len(reqs) == 0 {
+			delete(activeKeyRequests, accessKeyID)
+		}
+		activeKeyRequestsMu.Unlock()
+		cancel()
+	}
+}
+
+// ActiveAccessKeyRequests lists the key's in-flight requests on this instance, oldest first
+func ActiveAccessKeyRequests(accessKeyID uuid.UUID) []ActiveRequest {
+	activeKeyRequestsMu.Lock()
+	defer activeKeyRequestsMu.Unlock()
+
+	requests := make([]ActiveRequest, 0, len(activeKeyRequests[accessKeyID]))
+	for _, req := range activeKeyRequests[accessKeyID] {
+		requests = append(requests, *req)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].StartedAt.Before(requests[j].StartedAt)
+	})
+	return requests
+}
+
+// CancelAccessKeyRequests cancels the key's in-flight requests on this instance and returns how many.
+// Uploads fail on their next body read; handlers watching the request context stop early
+func CancelAccessKeyRequests(accessKeyID uuid.UUID) int {
+	activeKeyRequestsMu.Lock()
+	defer activeKeyRequestsMu.Unlock()
+
+	for _, req := range activeKeyRequests[accessKeyID] {
+		req.cancel()
+	}
+	return len(activeKeyRequests[accessKeyID])
+}
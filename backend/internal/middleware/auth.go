@@ -8,25 +8,36 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens
+// AuthMiddleware validates JWT tokens from the Authorization header or, for the web UI,
+// the HttpOnly session cookie
 func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var token string
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		if authHeader != "" {
+			// Expected format: "Bearer <token>"
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+				c.Abort()
+				return
+			}
+			token = parts[1]
+		} else if cookie, err := c.Cookie(auth.SessionCookieName); err == nil && cookie != "" {
+			// Cookies are sent automatically, so state-changing requests must also carry a header
+			// that cross-site forms can't set (CSRF protection on top of SameSite)
+			if !isSafeMethod(c.Request.Method) && c.GetHeader("X-Requested-With") == "" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "X-Requested-With header required for cookie-authenticated requests"})
+				c.Abort()
+				return
+			}
+			token = cookie
+		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			c.Abort()
 			return
 		}
 
-		// Expected format: "Bearer <token>"
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
-			c.Abort()
-			return
-		}
-
-		token := parts[1]
 		claims, err := auth.ValidateToken(token, jwtSecret)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
@@ -38,11 +49,17 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("is_admin", claims.IsAdmin)
+		c.Set("token_claims", claims)
+		c.Set("service_account", claims.ServiceAccount)
 
 		c.Next()
 	}
 }
 
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
 // AdminMiddleware ensures the user is an admin
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
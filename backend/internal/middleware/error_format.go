@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// S3Error is the S3-style XML error body
+type S3Error struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource,omitempty"`
+	RequestID string   `xml:"RequestId,omitempty"`
+}
+
+// PrefersXML reports whether the Accept header asks for XML rather than JSON.
+// Without an Accept header (or with */*) the route's own default format applies
+func PrefersXML(c *gin.Context, xmlDefault bool) bool {
+	if xmlDefault {
+		return c.NegotiateFormat(binding.MIMEXML, binding.MIMEXML2, binding.MIMEJSON) != binding.MIMEJSON
+	}
+	return c.NegotiateFormat(binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2) != binding.MIMEJSON
+}
+
+// S3ErrorCode maps an HTTP status to the closest generic S3 error code
+func S3ErrorCode(status int) string {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "AccessDenied"
+	case http.StatusNotFound:
+		return "NotFound"
+	case http.StatusMethodNotAllowed:
+		return "MethodNotAllowed"
+	case http.StatusConflict:
+		return "OperationAborted"
+	case http.StatusLengthRequired:
+		return "MissingContentLength"
+	case http.StatusPreconditionFailed:
+		return "PreconditionFailed"
+	case http.StatusRequestEntityTooLarge:
+		return "EntityTooLarge"
+	case http.StatusRequestedRangeNotSatisfiable:
+		return "InvalidRange"
+	case http.StatusTooManyRequests:
+		return "SlowDown"
+	case http.StatusNotImplemented:
+		return "NotImplemented"
+	case http.StatusServiceUnavailable:
+		return "ServiceUnavailable"
+	}
+	if status >= http.StatusInternalServerError {
+		return "InternalError"
+	}
+	return "InvalidRequest"
+}
+
+// jsonErrorBody matches both JSON error shapes: models.ErrorResponse ({"error","message"})
+// and the S3 middleware's {"Code","Message"}
+type jsonErrorBody struct {
+	Code    string `json:"Code"`
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// s3ErrorFromJSON translates a JSON error body into its S3 XML form
+func s3ErrorFromJSON(data []byte, status int, requestID string) (S3Error, bool) {
+	var body jsonErrorBody
+	if err := json.Unmarshal(data, &body); err != nil || (body.Error == "" && body.Code == "") {
+		return S3Error{}, false
+	}
+
+	code := body.Code
+	if code == "" {
+		code = S3ErrorCode(status)
+	}
+	message := body.Message
+	if body.Error != "" {
+		message = body.Error
+		if body.Message != "" {
+			message += ": " + body.Message
+		}
+	}
+	return S3Error{Code: code, Message: message, RequestID: requestID}, true
+}
+
+// errorBufferWriter holds back error bodies so they can be re-rendered; success bodies pass through
+type errorBufferWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *errorBufferWriter) Write(data []byte) (int, error) {
+	if w.ResponseWriter.Status() >= http.StatusBadRequest {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *errorBufferWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// ErrorNegotiationMiddleware re-renders JSON error responses as S3-style XML errors when the
+// client's Accept header prefers XML. Requests without such an Accept header are untouched
+func ErrorNegotiationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !PrefersXML(c, false) {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &errorBufferWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if writer.body.Len() == 0 {
+			return
+		}
+
+		s3Err, ok := s3ErrorFromJSON(writer.body.Bytes(), original.Status(), c.GetString("request_id"))
+		if !ok {
+			original.Write(writer.body.Bytes())
+			return
+		}
+		data, err := xml.Marshal(s3Err)
+		if err != nil {
+			original.Write(writer.body.Bytes())
+			return
+		}
+		original.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		original.Header().Del("Content-Length")
+		original.Write([]byte(xml.Header))
+		original.Write(data)
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"time"
 
 	"bkt/internal/database"
+	"bkt/internal/logger"
 	"bkt/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +25,16 @@ const (
 
 	// IdempotencyKeyTTL is how long idempotency keys are valid (24 hours)
 	IdempotencyKeyTTL = 24 * time.Hour
+
+	// IdempotencyInFlightTTL bounds how long an in-flight reservation blocks retries
+	// (covers requests interrupted by a crash before the response was stored)
+	IdempotencyInFlightTTL = 1 * time.Hour
+
+	// idempotencyInFlight is the status code stored while the original request is executing
+	idempotencyInFlight = 0
+
+	// idempotencyMemoryLimit is the largest request body hashed in memory (1MB)
+	idempotencyMemoryLimit = 1 << 20
 )
 
 // responseWriter wraps gin.ResponseWriter to capture the response
@@ -81,73 +93,96 @@ func IdempotencyMiddleware() gin.HandlerFunc {
 		}
 		userID := userIDVal.(uuid.UUID)
 
-		// Read request body to compute hash
-		var requestBody []byte
-		if c.Request.Body != nil {
-			var err error
-			requestBody, err = io.ReadAll(c.Request.Body)
+		// Hash request body (large bodies such as uploads are spooled to disk, not memory)
+		requestHash, cleanup, err := hashRequestBody(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to read request body",
+				Message: err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		defer cleanup()
+
+		// Reserve the key with an in-flight placeholder row so concurrent duplicates
+		// are detected by the unique index instead of both executing
+		placeholder := models.IdempotencyKey{
+			Key:         idempotencyKey,
+			UserID:      userID,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			StatusCode:  idempotencyInFlight,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(IdempotencyInFlightTTL),
+		}
+
+		for attempt := 0; ; attempt++ {
+			if err := database.DB.Create(&placeholder).Error; err == nil {
+				break
+			}
+
+			// Key already exists - inspect it
+			var existingKey models.IdempotencyKey
+			err := database.DB.Where("key = ? AND user_id = ?", idempotencyKey, userID).First(&existingKey).Error
+			if err == gorm.ErrRecordNotFound && attempt == 0 {
+				// Row vanished between insert and lookup (expired/failed request cleaned up), retry once
+				continue
+			}
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-					Error:   "Failed to read request body",
+					Error:   "Idempotency check failed",
 					Message: err.Error(),
 				})
 				c.Abort()
 				return
 			}
-			// Restore request body for the handler
-			c.Request.Body = io.NopCloser(bytes.NewReader(requestBody))
-		}
 
-		// Compute request hash (SHA256 of body)
-		hash := sha256.Sum256(requestBody)
-		requestHash := hex.EncodeToString(hash[:])
+			// Key expired, delete it and retry the reservation
+			if time.Now().After(existingKey.ExpiresAt) && attempt == 0 {
+				database.DB.Delete(&existingKey)
+				continue
+			}
 
-		// Check if idempotency key already exists
-		var existingKey models.IdempotencyKey
-		err := database.DB.Where("key = ? AND user_id = ?", idempotencyKey, userID).First(&existingKey).Error
+			// Verify request matches
+			if existingKey.Method != c.Request.Method || existingKey.Path != c.Request.URL.Path {
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Idempotency key conflict",
+					Message: fmt.Sprintf("Key already used for %s %s", existingKey.Method, existingKey.Path),
+				})
+				c.Abort()
+				return
+			}
 
-		if err == nil {
-			// Key exists, check if expired
-			if time.Now().After(existingKey.ExpiresAt) {
-				// Key expired, delete it and proceed with new request
-				database.DB.Delete(&existingKey)
-			} else {
-				// Key still valid, verify request matches
-				if existingKey.Method != c.Request.Method || existingKey.Path != c.Request.URL.Path {
-					c.JSON(http.StatusConflict, models.ErrorResponse{
-						Error:   "Idempotency key conflict",
-						Message: fmt.Sprintf("Key already used for %s %s", existingKey.Method, existingKey.Path),
-					})
-					c.Abort()
-					return
-				}
-
-				// Verify request body matches (prevent replay attacks with different body)
-				if existingKey.RequestHash != requestHash {
-					c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
-						Error:   "Request body mismatch",
-						Message: "Request body differs from original request with same idempotency key",
-					})
-					c.Abort()
-					return
-				}
-
-				// Return cached response
-				c.Data(existingKey.StatusCode, "application/json", []byte(existingKey.ResponseBody))
+			// Verify request body matches (prevent replay attacks with different body)
+			if existingKey.RequestHash != requestHash {
+				c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+					Error:   "Request body mismatch",
+					Message: "Request body differs from original request with same idempotency key",
+				})
 				c.Abort()
 				return
 			}
-		} else if err != gorm.ErrRecordNotFound {
-			// Database error
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Idempotency check failed",
-				Message: err.Error(),
-			})
+
+			// Original request is still executing
+			if existingKey.StatusCode == idempotencyInFlight {
+				c.Header("Retry-After", "1")
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Request in progress",
+					Message: "A request with this idempotency key is already being processed",
+				})
+				c.Abort()
+				return
+			}
+
+			// Return cached response
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(existingKey.StatusCode, "application/json", []byte(existingKey.ResponseBody))
 			c.Abort()
 			return
 		}
 
-		// Key doesn't exist or expired, wrap response writer to capture response
+		// Wrap response writer to capture response
 		responseBodyBuffer := &bytes.Buffer{}
 		writer := &responseWriter{
 			ResponseWriter: c.Writer,
@@ -159,32 +194,86 @@ func IdempotencyMiddleware() gin.HandlerFunc {
 		// Process request
 		c.Next()
 
-		// After request processing, store idempotency key if request was successful
 		// Only cache successful responses (2xx status codes)
+		// Failed requests release the key so the client can retry
 		if writer.statusCode >= 200 && writer.statusCode < 300 {
-			idempotencyRecord := models.IdempotencyKey{
-				Key:          idempotencyKey,
-				UserID:       userID,
-				Method:       c.Request.Method,
-				Path:         c.Request.URL.Path,
-				StatusCode:   writer.statusCode,
-				ResponseBody: responseBodyBuffer.String(),
-				RequestHash:  requestHash,
-				ExpiresAt:    time.Now().Add(IdempotencyKeyTTL),
-			}
-
-			// Save to database (best effort - don't fail request if storage fails)
-			if err := database.DB.Create(&idempotencyRecord).Error; err != nil {
-				// Debug logging (uncomment for troubleshooting)
-				// fmt.Printf("Warning: Failed to store idempotency key: %v\n", err)
-			}
+			database.DB.Model(&placeholder).Updates(map[string]interface{}{
+				"status_code":   writer.statusCode,
+				"response_body": responseBodyBuffer.String(),
+				"expires_at":    time.Now().Add(IdempotencyKeyTTL),
+			})
+		} else {
+			database.DB.Delete(&placeholder)
 		}
 	}
 }
 
+// hashRequestBody computes the SHA256 of the request body and restores it for the handler
+// Bodies up to idempotencyMemoryLimit are kept in memory, larger ones are spooled to a temp file
+func hashRequestBody(c *gin.Context) (string, func(), error) {
+	noop := func() {}
+	hasher := sha256.New()
+	if c.Request.Body == nil {
+		return hex.EncodeToString(hasher.Sum(nil)), noop, nil
+	}
+
+	// Read up to the memory limit first
+	head, err := io.ReadAll(io.LimitReader(c.Request.Body, idempotencyMemoryLimit+1))
+	if err != nil {
+		return "", noop, err
+	}
+	hasher.Write(head)
+
+	if int64(len(head)) <= idempotencyMemoryLimit {
+		c.Request.Body = io.NopCloser(bytes.NewReader(head))
+		return hex.EncodeToString(hasher.Sum(nil)), noop, nil
+	}
+
+	// Spool the rest of the body to disk
+	spool, err := os.CreateTemp("", "bkt-idempotency-*")
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+
+	if _, err := spool.Write(head); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	if _, err := io.Copy(io.MultiWriter(spool, hasher), c.Request.Body); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+
+	c.Request.Body = spool
+	return hex.EncodeToString(hasher.Sum(nil)), cleanup, nil
+}
+
 // CleanupExpiredIdempotencyKeys removes expired idempotency keys from the database
 // This should be called periodically (e.g., via a cron job or background goroutine)
 func CleanupExpiredIdempotencyKeys() error {
 	result := database.DB.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
 	return result.Error
 }
+
+// StartIdempotencyCleanup periodically removes expired idempotency keys in the background
+func StartIdempotencyCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := CleanupExpiredIdempotencyKeys(); err != nil {
+				logger.Warn("Failed to clean up expired idempotency keys", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+	}()
+}
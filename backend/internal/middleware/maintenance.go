@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/logger"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// defaultMaintenanceRetryAfter is sent when no Retry-After was configured (seconds)
+const defaultMaintenanceRetryAfter = 300
+
+// Cached maintenance state; the DB row is the source of truth shared by all instances
+var (
+	maintenanceState models.MaintenanceMode
+	maintenanceMu    sync.RWMutex
+)
+
+// maintenanceExemptPrefixes stay writable in maintenance mode (auth, and toggling the mode itself)
+var maintenanceExemptPrefixes = []string{
+	"/api/auth/",
+	"/api/maintenance",
+}
+
+// GetMaintenanceMode returns the current maintenance state
+func GetMaintenanceMode() models.MaintenanceMode {
+	maintenanceMu.RLock()
+	defer maintenanceMu.RUnlock()
+	return maintenanceState
+}
+
+// LoadMaintenanceMode reads the persisted state; forceEnabled (MAINTENANCE_MODE=true) turns it on at startup
+func LoadMaintenanceMode(forceEnabled bool) error {
+	if err := refreshMaintenanceMode(); err != nil {
+		return err
+	}
+	if forceEnabled && !GetMaintenanceMode().Enabled {
+		return SetMaintenanceMode(true, "", 0, nil)
+	}
+	return nil
+}
+
+// refreshMaintenanceMode reloads the state from the database
+func refreshMaintenanceMode() error {
+	var setting models.SystemSetting
+	result := database.DB.Where("key = ?", models.SettingMaintenanceMode).Limit(1).Find(&setting)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	var state models.MaintenanceMode
+	if result.RowsAffected > 0 {
+		if err := json.Unmarshal([]byte(setting.Value), &state); err != nil {
+			return err
+		}
+	}
+
+	maintenanceMu.Lock()
+	maintenanceState = state
+	maintenanceMu.Unlock()
+	return nil
+}
+
+// SetMaintenanceMode persists and applies a new maintenance state
+func SetMaintenanceMode(enabled bool, message string, retryAfter int, updatedBy *uuid.UUID) error {
+	state := models.MaintenanceMode{Enabled: enabled}
+	if enabled {
+		now := time.Now().UTC()
+		if current := GetMaintenanceMode(); current.Enabled && current.Since != nil {
+			now = *current.Since // Keep the original start when only the message changes
+		}
+		state.Message = message
+		state.RetryAfter = retryAfter
+		if state.RetryAfter <= 0 {
+			state.RetryAfter = defaultMaintenanceRetryAfter
+		}
+		state.Since = &now
+	}
+
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	setting := models.SystemSetting{
+		Key:       models.SettingMaintenanceMode,
+		Value:     string(value),
+		UpdatedBy: updatedBy,
+		UpdatedAt: time.Now(),
+	}
+	if err := database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&setting).Error; err != nil {
+		return err
+	}
+
+	maintenanceMu.Lock()
+	maintenanceState = state
+	maintenanceMu.Unlock()
+
+	logger.Info("Maintenance mode updated", map[string]interface{}{
+		"enabled": enabled,
+	})
+	return nil
+}
+
+// StartMaintenanceModeSync periodically reloads the state so toggles on one instance reach the others
+func StartMaintenanceModeSync(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := refreshMaintenanceMode(); err != nil {
+				logger.Warn("Failed to refresh maintenance mode", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+	}()
+}
+
+// MaintenanceMiddleware rejects mutating requests with 503 while maintenance mode is on
+// Reads (GET/HEAD/OPTIONS), authentication and the maintenance endpoint itself stay available
+func MaintenanceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := GetMaintenanceMode()
+		if !state.Enabled {
+			c.Next()
+			return
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		path := c.Request.URL.Path
+		for _, prefix := range maintenanceExemptPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		message := state.Message
+		if message == "" {
+			message = "The server is in maintenance mode; writes are temporarily disabled"
+		}
+
+		c.Header("Retry-After", strconv.Itoa(state.RetryAfter))
+
+		// S3 clients expect an XML error body
+		if !strings.HasPrefix(path, "/api/") {
+			body, _ := xml.Marshal(struct {
+				XMLName xml.Name `xml:"Error"`
+				Code    string   `xml:"Code"`
+				Message string   `xml:"Message"`
+			}{Code: "ServiceUnavailable", Message: message})
+			c.Data(http.StatusServiceUnavailable, "application/xml", append([]byte(xml.Header), body...))
+			c.Abort()
+			return
+		}
+
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "Maintenance mode",
+			Message: message,
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Query parameters of a presigned object URL
+const (
+	PresignedCredentialParam = "X-Bkt-Credential" // Issuing user ID
+	PresignedExpiresParam    = "X-Bkt-Expires"    // Unix time
+	PresignedSignatureParam  = "X-Bkt-Signature"  // Hex HMAC-SHA256
+
+	// Response header overrides of a GET URL; signed, so a holder can't change how the object is served
+	PresignedContentTypeParam = "response-content-type"
+	PresignedDispositionParam = "response-content-disposition"
+)
+
+// presignedExtraParams are the other query parameters a presigned URL may carry, by method.
+// Anything else could reach behavior the signature doesn't cover
+var presignedExtraParams = map[string]map[string]bool{
+	http.MethodGet: {"download": true, PresignedContentTypeParam: true, PresignedDispositionParam: true},
+	http.MethodPut: {},
+}
+
+// SignPresignedURL signs one operation (GET or PUT) on one object until expires, with a key
+// derived from the JWT secret. The response header overrides are signed too (empty when unset)
+func SignPresignedURL(jwtSecret, method, bucketName, objectKey string, issuer uuid.UUID, expires int64, contentType, disposition string) string {
+	mac := hmac.New(sha256.New, []byte(jwtSecret))
+	mac.Write([]byte(strings.Join([]string{
+		"bkt-presigned-url",
+		method,
+		bucketName,
+		objectKey,
+		strconv.FormatInt(expires, 10),
+		issuer.String(),
+		contentType,
+		disposition,
+	}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PresignedURLMiddleware authenticates a request by its presigned URL instead of a session: the
+// signature must cover the method, bucket (bucketParam), key and any response header overrides,
+// and must not have expired.
+// The request then runs as the issuing user, whose permissions are still checked by the handler
+func PresignedURLMiddleware(jwtSecret, bucketParam string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		for name := range query {
+			if name != PresignedCredentialParam && name != PresignedExpiresParam && name != PresignedSignatureParam &&
+				!presignedExtraParams[c.Request.Method][name] {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error":   "Invalid presigned URL",
+					"message": "Query parameter " + name + " is not allowed in a presigned URL",
+				})
+				return
+			}
+		}
+
+		issuer, err := uuid.Parse(query.Get(PresignedCredentialParam))
+		expires, expiresErr := strconv.ParseInt(query.Get(PresignedExpiresParam), 10, 64)
+		if err != nil || expiresErr != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Invalid presigned URL",
+				"message": "The URL is missing its credential or expiry",
+			})
+			return
+		}
+
+		bucketName := c.Param(bucketParam)
+		objectKey := strings.TrimPrefix(c.Param("key"), "/")
+		expected := SignPresignedURL(jwtSecret, c.Request.Method, bucketName, objectKey, issuer, expires,
+			query.Get(PresignedContentTypeParam), query.Get(PresignedDispositionParam))
+		if !hmac.Equal([]byte(query.Get(PresignedSignatureParam)), []byte(expected)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Invalid signature",
+				"message": "The URL signature does not match",
+			})
+			return
+		}
+		if time.Now().Unix() > expires {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Presigned URL expired",
+				"message": "The URL expired at " + time.Unix(expires, 0).UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		// The URL stops working once its issuer is deleted or locked
+		var user models.User
+		if err := database.DB.Where("id = ?", issuer).First(&user).Error; err != nil || user.IsLocked {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Invalid presigned URL",
+				"message": "The user who issued this URL can no longer access it",
+			})
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+		c.Set("is_admin", user.IsAdmin)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"bkt/internal/logger"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// transferRoutes stream object data or events and get the transfer budget instead of the
+// regular request deadline (keyed by method and route pattern)
+var transferRoutes = map[string]bool{
+	"POST /api/buckets/:name/objects":        true, // Upload
+	"POST /api/buckets/:name/objects/async":  true,
+	"POST /api/buckets/:name/objects/bulk":   true, // Multi-file or archive upload
+	"GET /api/buckets/:name/objects/*key":    true, // Download
+	"GET /api/buckets/:name/by-hash/:sha256": true,
+	"POST /api/buckets/:name/append/*key":    true,
+	"GET /api/buckets/:name/inventory":       true, // Streamed manifest export
+	"PATCH /api/uploads/tus/:id":             true,
+	"GET /api/uploads/:id/events":            true, // Server-sent events
+	"GET /api/presigned/:name/*key":          true, // Presigned URL download
+	"PUT /api/presigned/:bucket/*key":        true, // Presigned URL upload
+	"GET /api/admin/export":                  true, // Streamed metadata backup
+	"POST /api/admin/import":                 true,
+	"GET /:bucket/*key":                      true, // S3 GetObject
+	"PUT /:bucket/*key":                      true, // S3 PutObject / UploadPart
+	"POST /:bucket/*key":                     true, // S3 CompleteMultipartUpload assembles the parts
+}
+
+// deadlineWriter drops the handler's response once the request deadline has passed, so the
+// middleware can answer with a timeout instead. A response that was already being streamed
+// when the deadline hit is cut off: further writes fail and the handler's copy loop stops
+type deadlineWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	timedOut bool
+}
+
+func (w *deadlineWriter) Write(data []byte) (int, error) {
+	if w.timedOut {
+		return len(data), nil
+	}
+	if err := w.ctx.Err(); errors.Is(err, context.DeadlineExceeded) {
+		if !w.ResponseWriter.Written() {
+			w.timedOut = true
+			return len(data), nil
+		}
+		return 0, err
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *deadlineWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// RequestTimeoutMiddleware bounds how long a request may run and logs slow requests.
+// The deadline is applied to the request context (and request body reads); handlers that
+// haven't responded when it passes get a 504. A zero timeout disables the deadline for that
+// class of request, and a zero slowThreshold disables slow-request logging
+func RequestTimeoutMiddleware(timeout, transferTimeout, slowThreshold time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		budget := timeout
+		if transferRoutes[c.Request.Method+" "+c.FullPath()] {
+			budget = transferTimeout
+		}
+
+		timedOut := false
+		if budget > 0 {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), budget)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+			if c.Request.Body != nil {
+				c.Request.Body = &cancelableBody{ReadCloser: c.Request.Body, ctx: ctx}
+			}
+
+			original := c.Writer
+			writer := &deadlineWriter{ResponseWriter: original, ctx: ctx}
+			c.Writer = writer
+			c.Next()
+			c.Writer = original
+
+			timedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+			if writer.timedOut || (timedOut && !original.Written()) {
+				writeRequestTimeout(c, budget)
+			}
+		} else {
+			c.Next()
+		}
+
+		elapsed := time.Since(start)
+		if slowThreshold > 0 && elapsed >= slowThreshold {
+			logger.Warn("Slow request", map[string]interface{}{
+				"method":      c.Request.Method,
+				"path":        c.Request.URL.Path,
+				"route":       c.FullPath(),
+				"status":      c.Writer.Status(),
+				"user":        c.GetString("username"),
+				"request_id":  c.GetString("request_id"),
+				"duration_ms": elapsed.Milliseconds(),
+				"timed_out":   timedOut,
+			})
+		}
+	}
+}
+
+// writeRequestTimeout replaces the abandoned response with a 504 (S3-style XML on the S3 routes)
+func writeRequestTimeout(c *gin.Context, budget time.Duration) {
+	// Headers the handler set for its own body no longer apply
+	c.Writer.Header().Del("Content-Length")
+	c.Writer.Header().Del("Content-Disposition")
+	c.Writer.Header().Del("ETag")
+
+	message := "Request exceeded the maximum duration of " + budget.String()
+	if !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.XML(http.StatusGatewayTimeout, S3Error{
+			Code:      "RequestTimeout",
+			Message:   message,
+			Resource:  c.Request.URL.Path,
+			RequestID: c.GetString("request_id"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusGatewayTimeout, models.ErrorResponse{
+		Error:   "Request timeout",
+		Message: message,
+	})
+}
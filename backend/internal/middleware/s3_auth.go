@@ -1,13 +1,16 @@
 package middleware
 
 import (
+	"bkt/internal/config"
 	"bkt/internal/database"
 	"bkt/internal/models"
 	"bkt/internal/security"
+	"bkt/internal/services"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"sort"
@@ -17,10 +20,59 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// s3MaxClockSkew is how far a signed request's timestamp may be from server time (per AWS spec)
+const s3MaxClockSkew = 15 * time.Minute
+
 // S3AuthMiddleware validates AWS Signature Version 4 authentication
 // This is used for S3-compatible API requests (e.g., from s3fs-fuse)
-func S3AuthMiddleware() gin.HandlerFunc {
+// tlsCfg.S3ClientCertAuth enables mutual TLS: "cert" authenticates with the client certificate alone
+// (it must be bound to an access key, which the request then runs as),
+// "combined" requires both a bound certificate and a valid SigV4 signature for the same identity.
+// tlsCfg.S3RequireTLS rejects requests that weren't sent over TLS, so signed requests can't be captured
+// and replayed from a plaintext hop; X-Forwarded-Proto is only believed from trustedProxies.
+// auditRequests records each request made with an access key in the audit log
+func S3AuthMiddleware(tlsCfg config.TLSConfig, trustedProxies []*net.IPNet, auditRequests bool) gin.HandlerFunc {
+	clientCertMode := tlsCfg.S3ClientCertAuth
 	return func(c *gin.Context) {
+		if tlsCfg.S3RequireTLS && !s3RequestUsedTLS(c, trustedProxies) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"Code":    "InsecureTransport",
+				"Message": "S3 requests must be sent over HTTPS",
+			})
+			return
+		}
+
+		// Resolve client certificate binding when mTLS is enabled
+		var binding *models.ClientCertBinding
+		if clientCertMode == "cert" || clientCertMode == "combined" {
+			var err error
+			binding, err = lookupClientCertBinding(c)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"Code":    "AccessDenied",
+					"Message": err.Error(),
+				})
+				return
+			}
+
+			// Certificate alone is sufficient - skip SigV4 and act as the bound access key
+			if clientCertMode == "cert" {
+				if binding.AccessKey == nil {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+						"Code":    "AccessDenied",
+						"Message": "Client certificate is not bound to an access key",
+					})
+					return
+				}
+
+				// Update last used timestamp (best-effort)
+				database.DB.Model(binding).Update("last_used_at", time.Now())
+
+				serveAsAccessKey(c, binding.AccessKey, auditRequests)
+				return
+			}
+		}
+
 		// Extract authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -54,6 +106,7 @@ func S3AuthMiddleware() gin.HandlerFunc {
 		// Look up access key in database
 		var key models.AccessKey
 		if err := database.DB.Where("access_key = ? AND is_active = ?", accessKey, true).
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).
 			Preload("User").First(&key).Error; err != nil {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
 				"Code":    "InvalidAccessKeyId",
@@ -93,22 +146,121 @@ func S3AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Update last used timestamp (best-effort, don't fail auth if update fails)
-		now := time.Now()
-		key.LastUsedAt = &now
-		if err := database.DB.Save(&key).Error; err != nil {
-			// Don't log - not critical and avoids any credential exposure
+		// In combined mode the certificate and the access key must belong to the same identity
+		if binding != nil {
+			if binding.UserID != key.UserID || (binding.AccessKeyID != nil && *binding.AccessKeyID != key.ID) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"Code":    "AccessDenied",
+					"Message": "Client certificate is not bound to this access key",
+				})
+				return
+			}
+			database.DB.Model(binding).Update("last_used_at", time.Now())
 		}
 
-		// Set user context for downstream handlers
-		c.Set("user_id", key.UserID)
-		c.Set("user", &key.User)
-		c.Set("is_admin", key.User.IsAdmin)
+		serveAsAccessKey(c, &key, auditRequests)
+	}
+}
+
+// serveAsAccessKey runs the rest of the chain as an authenticated access key, whether it signed
+// the request or is bound to the client certificate, so both paths get the same tracking and audit
+func serveAsAccessKey(c *gin.Context, key *models.AccessKey, auditRequests bool) {
+	// Update last used timestamp (best-effort, don't fail auth if update fails)
+	now := time.Now()
+	key.LastUsedAt = &now
+	if err := database.DB.Save(key).Error; err != nil {
+		// Don't log - not critical and avoids any credential exposure
+	}
+
+	// Set user context for downstream handlers
+	c.Set("user_id", key.UserID)
+	c.Set("user", &key.User)
+	c.Set("is_admin", key.User.IsAdmin)
+	c.Set("access_key_id", key.ID)
+
+	// Track the request so an admin can list or cancel it while it runs
+	done := trackAccessKeyRequest(c, key.ID)
+	defer done()
+
+	c.Next()
 
-		c.Next()
+	if auditRequests {
+		auditS3Request(c, key)
 	}
 }
 
+// s3RequestUsedTLS reports whether the client reached us over TLS. X-Forwarded-Proto is only
+// believed from a trusted proxy, since anyone else could send "https" over plain HTTP
+func s3RequestUsedTLS(c *gin.Context, trustedProxies []*net.IPNet) bool {
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		if peer := net.ParseIP(c.RemoteIP()); peer != nil {
+			for _, network := range trustedProxies {
+				if network.Contains(peer) {
+					// A proxy chain appends its hops; the first entry is the client-facing one
+					first, _, _ := strings.Cut(proto, ",")
+					return strings.EqualFold(strings.TrimSpace(first), "https")
+				}
+			}
+		}
+	}
+	return c.Request.TLS != nil
+}
+
+// auditS3Request records a completed S3 request under the access key that made it
+func auditS3Request(c *gin.Context, key *models.AccessKey) {
+	status, errorMessage := "success", ""
+	if code := c.Writer.Status(); code >= http.StatusBadRequest {
+		status, errorMessage = "failure", fmt.Sprintf("%d %s", code, http.StatusText(code))
+	}
+
+	services.NewAuditService().LogAction(c, key.UserID, key.User.Username,
+		"S3Request", "AccessKey", key.ID.String(), c.Request.Method+" "+c.Request.URL.Path,
+		status, errorMessage,
+		map[string]interface{}{
+			"status_code": c.Writer.Status(),
+		})
+}
+
+// lookupClientCertBinding finds the active binding for the verified client certificate
+// Bindings pinned by fingerprint take precedence over subject-only bindings
+func lookupClientCertBinding(c *gin.Context) (*models.ClientCertBinding, error) {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 || len(c.Request.TLS.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("A verified client certificate is required")
+	}
+
+	cert := c.Request.TLS.PeerCertificates[0]
+	fingerprint := security.CertificateFingerprint(cert)
+	subject := cert.Subject.String()
+
+	var binding models.ClientCertBinding
+	err := database.DB.Preload("User").
+		Where("is_active = ? AND (fingerprint = ? OR ((fingerprint = '' OR fingerprint IS NULL) AND subject = ?))", true, fingerprint, subject).
+		Order("fingerprint DESC").
+		First(&binding).Error
+	if err != nil {
+		return nil, fmt.Errorf("Client certificate is not recognized")
+	}
+
+	// Locked users are rejected with the same message to avoid info disclosure
+	if binding.User.IsLocked {
+		return nil, fmt.Errorf("Client certificate is not recognized")
+	}
+
+	// Binding restricted to an access key is only valid while that key is active
+	if binding.AccessKeyID != nil {
+		var key models.AccessKey
+		if err := database.DB.Where("id = ? AND is_active = ?", *binding.AccessKeyID, true).
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).First(&key).Error; err != nil {
+			return nil, fmt.Errorf("Client certificate is not recognized")
+		}
+		// The key belongs to the binding's user (checked when the binding is created)
+		key.User = binding.User
+		binding.AccessKey = &key
+	}
+
+	return &binding, nil
+}
+
 // extractAccessKey extracts the access key from the Authorization header
 func extractAccessKey(authHeader string) (string, error) {
 	// Authorization format: AWS4-HMAC-SHA256 Credential=ACCESS_KEY/date/region/service/aws4_request, SignedHeaders=..., Signature=...
@@ -150,16 +302,31 @@ func validateSignature(c *gin.Context, authHeader, accessKey, secretKey string)
 	}
 
 	// Get request date (from X-Amz-Date header or Date header)
+	dateHeader := "x-amz-date"
 	dateStr := c.GetHeader("X-Amz-Date")
 	if dateStr == "" {
+		dateHeader = "date"
 		dateStr = c.GetHeader("Date")
 	}
 	if dateStr == "" {
 		return fmt.Errorf("missing date header")
 	}
 
+	// An unsigned date could be refreshed on a captured request, defeating the replay window
+	signed := false
+	for _, name := range strings.Split(signedHeaders, ";") {
+		if name == dateHeader {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return fmt.Errorf("date header is not signed")
+	}
+
 	// Validate timestamp to prevent replay attacks (15 minute window per AWS spec)
-	if err := validateTimestamp(dateStr); err != nil {
+	requestTime, err := validateTimestamp(dateStr)
+	if err != nil {
 		return err
 	}
 
@@ -169,6 +336,11 @@ func validateSignature(c *gin.Context, authHeader, accessKey, secretKey string)
 		return err
 	}
 
+	// The signing key is derived from the scope date, so it must be the request's own day
+	if scopeDate, _, _ := strings.Cut(credentialScope, "/"); scopeDate != requestTime.UTC().Format("20060102") {
+		return fmt.Errorf("credential scope date does not match request date")
+	}
+
 	// Build canonical request
 	canonicalRequest := buildCanonicalRequest(c, signedHeaders)
 
@@ -340,9 +512,9 @@ func sha256Hash(data string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-// validateTimestamp validates that the request timestamp is within 15 minutes of server time
-// This prevents replay attacks using captured requests
-func validateTimestamp(dateStr string) error {
+// validateTimestamp validates that the request timestamp is within s3MaxClockSkew of server time
+// and returns it. This prevents replay attacks using captured requests
+func validateTimestamp(dateStr string) (time.Time, error) {
 	// AWS Signature V4 uses ISO 8601 format: 20130524T000000Z
 	var requestTime time.Time
 	var err error
@@ -356,22 +528,21 @@ func validateTimestamp(dateStr string) error {
 			// Try RFC1123Z format
 			requestTime, err = time.Parse(time.RFC1123Z, dateStr)
 			if err != nil {
-				return fmt.Errorf("invalid date format")
+				return time.Time{}, fmt.Errorf("invalid date format")
 			}
 		}
 	}
 
-	// Check if request is within 15 minute window
+	// Check if request is within the allowed skew
 	now := time.Now().UTC()
 	diff := now.Sub(requestTime)
 	if diff < 0 {
 		diff = -diff
 	}
 
-	// AWS allows 15 minute skew
-	if diff > 15*time.Minute {
-		return fmt.Errorf("request timestamp too old or too far in the future")
+	if diff > s3MaxClockSkew {
+		return time.Time{}, fmt.Errorf("request timestamp too old or too far in the future")
 	}
 
-	return nil
+	return requestTime, nil
 }
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bkt/internal/config"
+	"bkt/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityHeadersMiddleware sets browser security headers on every response.
+// X-Content-Type-Options is always sent; the rest follow the configuration.
+// Handlers serving object content may override the CSP (see the object download handlers)
+func SecurityHeadersMiddleware(cfg config.SecurityHeadersConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Browsers must never sniff a different content type than the one we declare
+		c.Header("X-Content-Type-Options", "nosniff")
+
+		if !cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		// HSTS is ignored over plain HTTP; only send it on TLS (or TLS-terminating proxy) requests
+		if cfg.HSTSMaxAge > 0 && (c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https") {
+			c.Header("Strict-Transport-Security", "max-age="+strconv.FormatInt(cfg.HSTSMaxAge, 10)+"; includeSubDomains")
+		}
+		if cfg.FrameOptions != "" {
+			c.Header("X-Frame-Options", cfg.FrameOptions)
+		}
+		if cfg.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", cfg.ContentSecurityPolicy)
+		}
+		if cfg.ReferrerPolicy != "" {
+			c.Header("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+
+		c.Next()
+	}
+}
+
+// AllowedMethodsMiddleware rejects requests whose method isn't in the allowed list with 405
+func AllowedMethodsMiddleware(methods []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		allowed[strings.ToUpper(method)] = true
+	}
+	allowHeader := strings.Join(methods, ", ")
+
+	return func(c *gin.Context) {
+		if len(allowed) == 0 || allowed[c.Request.Method] {
+			c.Next()
+			return
+		}
+
+		c.Header("Allow", allowHeader)
+		c.AbortWithStatusJSON(http.StatusMethodNotAllowed, models.ErrorResponse{
+			Error:   "Method not allowed",
+			Message: "HTTP method " + c.Request.Method + " is not allowed",
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UsageBucketKey lets handlers attribute usage to a bucket when the route has no bucket parameter
+// (e.g. tus PATCH /uploads/tus/:id)
+const UsageBucketKey = "usage_bucket"
+
+// usageKey identifies one aggregated usage row
+type usageKey struct {
+	day        string // YYYY-MM-DD (UTC)
+	userID     uuid.UUID
+	bucketName string
+}
+
+type usageCounters struct {
+	bytesIn  int64
+	bytesOut int64
+	requests int64
+}
+
+// Usage is aggregated in memory and flushed periodically to avoid a DB write per request
+var (
+	pendingUsage   = make(map[usageKey]*usageCounters)
+	pendingUsageMu sync.Mutex
+)
+
+// countingReader counts bytes actually read from the request body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// UsageMiddleware records bytes transferred per authenticated user and bucket
+// Bytes in are counted as the handler reads the request body; bytes out are what was written to the client
+func UsageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body *countingReader
+		if c.Request.Body != nil {
+			body = &countingReader{ReadCloser: c.Request.Body}
+			c.Request.Body = body
+		}
+
+		c.Next()
+
+		userID, exists := c.Get("user_id")
+		if !exists {
+			return
+		}
+
+		bucketName := c.GetString(UsageBucketKey)
+		if bucketName == "" {
+			bucketName = c.Param("name")
+		}
+		if bucketName == "" {
+			bucketName = c.Param("bucket")
+		}
+		if bucketName == "" {
+			return
+		}
+
+		var bytesIn int64
+		if body != nil {
+			bytesIn = body.n
+		}
+		bytesOut := int64(c.Writer.Size())
+		if bytesOut < 0 {
+			bytesOut = 0 // Nothing written
+		}
+
+		recordUsage(userID.(uuid.UUID), bucketName, bytesIn, bytesOut)
+	}
+}
+
+// recordUsage adds transferred bytes to the in-memory aggregate
+func recordUsage(userID uuid.UUID, bucketName string, bytesIn, bytesOut int64) {
+	key := usageKey{
+		day:        time.Now().UTC().Format("2006-01-02"),
+		userID:     userID,
+		bucketName: bucketName,
+	}
+
+	pendingUsageMu.Lock()
+	defer pendingUsageMu.Unlock()
+
+	counters, exists := pendingUsage[key]
+	if !exists {
+		counters = &usageCounters{}
+		pendingUsage[key] = counters
+	}
+	counters.bytesIn += bytesIn
+	counters.bytesOut += bytesOut
+	counters.requests++
+}
+
+// FlushUsage writes aggregated usage to the usage_stats table
+// Failed rows are put back so they're retried on the next flush
+func FlushUsage() {
+	pendingUsageMu.Lock()
+	batch := pendingUsage
+	pendingUsage = make(map[usageKey]*usageCounters)
+	pendingUsageMu.Unlock()
+
+	for key, counters := range batch {
+		err := database.DB.Exec(`
+			INSERT INTO usage_stats (id, day, user_id, bucket_name, bytes_in, bytes_out, requests, created_at, updated_at)
+			VALUES (gen_random_uuid(), ?, ?, ?, ?, ?, ?, NOW(), NOW())
+			ON CONFLICT (day, user_id, bucket_name) DO UPDATE SET
+				bytes_in = usage_stats.bytes_in + EXCLUDED.bytes_in,
+				bytes_out = usage_stats.bytes_out + EXCLUDED.bytes_out,
+				requests = usage_stats.requests + EXCLUDED.requests,
+				updated_at = NOW()
+		`, key.day, key.userID, key.bucketName, counters.bytesIn, counters.bytesOut, counters.requests).Error
+		if err != nil {
+			logger.Warn("Failed to flush usage stats", map[string]interface{}{
+				"bucket": key.bucketName,
+				"error":  err.Error(),
+			})
+			pendingUsageMu.Lock()
+			existing, exists := pendingUsage[key]
+			if !exists {
+				pendingUsage[key] = counters
+			} else {
+				existing.bytesIn += counters.bytesIn
+				existing.bytesOut += counters.bytesOut
+				existing.requests += counters.requests
+			}
+			pendingUsageMu.Unlock()
+		}
+	}
+}
+
+// StartUsageFlush periodically persists aggregated usage in the background
+func StartUsageFlush(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			FlushUsage()
+		}
+	}()
+}
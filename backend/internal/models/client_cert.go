@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ClientCertBinding maps a TLS client certificate to a user (and optionally a specific access key)
+// Used to authenticate S3 API clients with mutual TLS
+type ClientCertBinding struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Fingerprint string     `gorm:"index" json:"fingerprint,omitempty"` // Hex SHA256 of the DER certificate
+	Subject     string     `gorm:"index" json:"subject,omitempty"`     // Certificate subject DN (used when no fingerprint is pinned)
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	AccessKeyID *uuid.UUID `gorm:"type:uuid;index" json:"access_key_id,omitempty"` // Optional: restrict binding to one access key
+	Description string     `json:"description,omitempty"`
+	IsActive    bool       `gorm:"default:true" json:"is_active"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Relationships
+	User      User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	AccessKey *AccessKey `gorm:"foreignKey:AccessKeyID" json:"access_key,omitempty"`
+}
+
+func (b *ClientCertBinding) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// CreateClientCertBindingRequest binds a certificate to a user
+// Either Certificate (PEM), Fingerprint, or Subject must be provided
+type CreateClientCertBindingRequest struct {
+	UserID      string  `json:"user_id" binding:"required"`
+	AccessKeyID *string `json:"access_key_id,omitempty"`
+	Certificate string  `json:"certificate,omitempty"` // PEM-encoded certificate; fingerprint and subject are derived from it
+	Fingerprint string  `json:"fingerprint,omitempty"`
+	Subject     string  `json:"subject,omitempty"`
+	Description string  `json:"description"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Key rule limits
+const (
+	maxKeyRulePatterns      = 32
+	maxKeyRulePatternLength = 256
+)
+
+// BucketKeyRules restricts the keys that can be written to a bucket. A key matching any Deny
+// pattern is rejected; otherwise, when Allow is non-empty, the key must match one of its patterns.
+// Patterns are globs matched per path segment: *, ? and [...] as in path.Match (never crossing
+// a '/'), and a "**" segment matches any number of segments, e.g. "logs/**/*.gz"
+type BucketKeyRules struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// KeyRuleError is a key rejected by a bucket's key rules. Pattern is the matching deny rule,
+// or empty when the key matched no allow rule
+type KeyRuleError struct {
+	Key     string
+	Pattern string
+	Allow   []string
+}
+
+func (e *KeyRuleError) Error() string {
+	if e.Pattern != "" {
+		return fmt.Sprintf("key %q matches the bucket's deny rule %q", e.Key, e.Pattern)
+	}
+	return fmt.Sprintf("key %q does not match any of the bucket's allowed patterns (%s)", e.Key, strings.Join(e.Allow, ", "))
+}
+
+// Validate checks the pattern lists
+func (r *BucketKeyRules) Validate() error {
+	if len(r.Allow)+len(r.Deny) > maxKeyRulePatterns {
+		return fmt.Errorf("at most %d patterns are allowed", maxKeyRulePatterns)
+	}
+	for _, pattern := range append(append([]string{}, r.Allow...), r.Deny...) {
+		if pattern == "" || len(pattern) > maxKeyRulePatternLength {
+			return fmt.Errorf("patterns must be 1 to %d characters", maxKeyRulePatternLength)
+		}
+		if strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("pattern %q cannot start with '/'", pattern)
+		}
+		for _, segment := range strings.Split(pattern, "/") {
+			if segment == "**" {
+				continue
+			}
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("pattern %q is not a valid glob", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Rules decodes the bucket's key rules (an unreadable value yields none)
+func (b *Bucket) Rules() BucketKeyRules {
+	var rules BucketKeyRules
+	json.Unmarshal([]byte(b.KeyRules), &rules)
+	return rules
+}
+
+// CheckKeyRules returns a *KeyRuleError when the bucket's key rules don't allow key
+func (b *Bucket) CheckKeyRules(key string) error {
+	if b.KeyRules == "" || b.KeyRules == "{}" {
+		return nil
+	}
+	rules := b.Rules()
+	for _, pattern := range rules.Deny {
+		if MatchKeyPattern(pattern, key) {
+			return &KeyRuleError{Key: key, Pattern: pattern}
+		}
+	}
+	if len(rules.Allow) == 0 {
+		return nil
+	}
+	for _, pattern := range rules.Allow {
+		if MatchKeyPattern(pattern, key) {
+			return nil
+		}
+	}
+	return &KeyRuleError{Key: key, Allow: rules.Allow}
+}
+
+// MatchKeyPattern reports whether key matches a key rule pattern (see BucketKeyRules)
+func MatchKeyPattern(pattern, key string) bool {
+	patternSegments := strings.Split(pattern, "/")
+	keySegments := strings.Split(key, "/")
+
+	// matched[j] after processing pattern segment i: pattern[i:] matches key[j:]. Bottom-up, so
+	// "**" can't blow up into backtracking
+	matched := make([]bool, len(keySegments)+1)
+	matched[len(keySegments)] = true
+	for i := len(patternSegments) - 1; i >= 0; i-- {
+		next := matched
+		matched = make([]bool, len(keySegments)+1)
+		for j := len(keySegments); j >= 0; j-- {
+			if patternSegments[i] == "**" {
+				matched[j] = next[j] || (j < len(keySegments) && matched[j+1])
+				continue
+			}
+			if j < len(keySegments) && next[j+1] {
+				ok, _ := path.Match(patternSegments[i], keySegments[j])
+				matched[j] = ok
+			}
+		}
+	}
+	return matched[0]
+}
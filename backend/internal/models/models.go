@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,6 +25,12 @@ type User struct {
 	SSOID       string `gorm:"index" json:"sso_id,omitempty"`       // Unique ID from SSO provider
 	SSOEmail    string `gorm:"" json:"sso_email,omitempty"`          // Email from SSO (may differ from Email)
 
+	// Service accounts are non-login machine identities that authenticate with a service token
+	IsServiceAccount bool `gorm:"default:false;index" json:"is_service_account"`
+
+	// Active access keys the user may hold; set from DEFAULT_USER_MAX_ACCESS_KEYS at creation
+	MaxAccessKeys int `gorm:"default:5" json:"max_access_keys"`
+
 	// Relationships
 	Buckets    []Bucket    `gorm:"foreignKey:OwnerID" json:"buckets,omitempty"`
 	AccessKeys []AccessKey `gorm:"foreignKey:UserID" json:"access_keys,omitempty"`
@@ -48,6 +56,10 @@ type AccessKey struct {
 	LastUsedAt         *time.Time `json:"last_used_at,omitempty"`
 	CreatedAt          time.Time `json:"created_at"`
 
+	// Rotation: the old key keeps validating until ExpiresAt, the new key records its predecessor
+	ExpiresAt     *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	RotatedFromID *uuid.UUID `gorm:"type:uuid" json:"rotated_from_id,omitempty"`
+
 	// Relationships
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
@@ -68,6 +80,7 @@ type S3Configuration struct {
 	AccessKeyID          string    `gorm:"not null" json:"access_key_id"`
 	SecretAccessKey      string    `gorm:"not null" json:"-"` // Encrypted, never serialize
 	BucketPrefix         string    `json:"bucket_prefix,omitempty"`
+	ObjectKeyPrefix      string    `json:"object_key_prefix,omitempty"`
 	UseSSL               bool      `gorm:"default:true" json:"use_ssl"`
 	ForcePathStyle       bool      `gorm:"default:false" json:"force_path_style"`
 	IsDefault            bool      `gorm:"default:false" json:"is_default"`
@@ -97,6 +110,39 @@ type Bucket struct {
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 
+	// Key case mode (fixed at creation): keys are folded to lowercase when set
+	CaseInsensitiveKeys bool `gorm:"default:false" json:"case_insensitive_keys"`
+
+	// Overwrite protection: objects can't be replaced within this many minutes of creation (0 disables)
+	NoOverwriteMinutes int `gorm:"default:0" json:"no_overwrite_minutes"`
+
+	// Append mode: objects may be extended in place via the append endpoint (local backend only)
+	AllowAppend bool `gorm:"default:false" json:"allow_append"`
+
+	// Object count cap: uploads creating a new key are rejected once the bucket holds this many
+	// objects (0 disables). Overwrites are always allowed
+	MaxObjects int64 `gorm:"not null;default:0" json:"max_objects"`
+
+	// Server access logging: request logs are delivered as objects into AccessLogBucket under
+	// AccessLogPrefix (empty bucket disables)
+	AccessLogBucket string `gorm:"default:''" json:"access_log_bucket,omitempty"`
+	AccessLogPrefix string `gorm:"default:''" json:"access_log_prefix,omitempty"`
+
+	// Auto date prefix: uploads are stored under this strftime-style pattern expanded with the
+	// upload time in UTC, e.g. "%Y/%m/%d/" turns events.json into 2026/10/16/events.json (empty disables)
+	AutoDatePrefix string `gorm:"default:''" json:"auto_date_prefix,omitempty"`
+
+	// Key rules: JSON-encoded BucketKeyRules restricting which keys may be written ('{}' allows all)
+	KeyRules string `gorm:"type:jsonb;not null;default:'{}'" json:"-"`
+
+	// Versioning: overwritten and deleted objects are kept as previous versions (ObjectVersion)
+	// while enabled. Suspending it keeps the versions already saved
+	VersioningEnabled bool `gorm:"default:false" json:"versioning_enabled"`
+
+	// Soft delete: a deleted bucket is hidden from every query and kept, with its objects, until
+	// the BUCKET_DELETE_GRACE period ends and it is purged
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
 	// Relationships
 	Owner    User              `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
 	Objects  []Object          `gorm:"foreignKey:BucketID" json:"objects,omitempty"`
@@ -110,19 +156,104 @@ func (b *Bucket) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// InLiveBuckets is a query scope limiting an object query to buckets that aren't soft-deleted,
+// for background work that loads objects without going through their bucket first
+func InLiveBuckets(db *gorm.DB) *gorm.DB {
+	return db.Where("bucket_id IN (?)", db.Session(&gorm.Session{NewDB: true}).Model(&Bucket{}).Select("id"))
+}
+
+// NormalizeKey applies the bucket's key case mode to an object key or prefix
+// Case-insensitive buckets store keys lowercased so the unique index rejects Photo.jpg vs photo.jpg
+func (b *Bucket) NormalizeKey(key string) string {
+	if b.CaseInsensitiveKeys {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+// maxAutoDatePrefixLength bounds an auto date prefix pattern
+const maxAutoDatePrefixLength = 64
+
+// ValidateAutoDatePrefix checks an auto date prefix pattern. Supported verbs are %Y (year),
+// %m (month), %d (day), %H (hour) and %% (a literal %)
+func ValidateAutoDatePrefix(pattern string) error {
+	if len(pattern) > maxAutoDatePrefixLength {
+		return fmt.Errorf("prefix cannot exceed %d characters", maxAutoDatePrefixLength)
+	}
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			continue
+		}
+		i++
+		if i == len(pattern) || !strings.ContainsRune("YmdH%", rune(pattern[i])) {
+			return fmt.Errorf("prefix may only use %%Y, %%m, %%d, %%H and %%%%")
+		}
+	}
+	if strings.HasPrefix(pattern, "/") || strings.Contains(pattern, "..") || strings.ContainsAny(pattern, "\\\x00") {
+		return fmt.Errorf("prefix cannot start with '/' or contain '..', backslashes or null bytes")
+	}
+	return nil
+}
+
+// DatePrefix expands the bucket's auto date prefix for an upload at t (empty when disabled)
+func (b *Bucket) DatePrefix(t time.Time) string {
+	t = t.UTC()
+	var prefix strings.Builder
+	for i := 0; i < len(b.AutoDatePrefix); i++ {
+		if b.AutoDatePrefix[i] != '%' || i+1 == len(b.AutoDatePrefix) {
+			prefix.WriteByte(b.AutoDatePrefix[i])
+			continue
+		}
+		i++
+		switch b.AutoDatePrefix[i] {
+		case 'Y':
+			fmt.Fprintf(&prefix, "%04d", t.Year())
+		case 'm':
+			fmt.Fprintf(&prefix, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&prefix, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&prefix, "%02d", t.Hour())
+		default:
+			prefix.WriteByte(b.AutoDatePrefix[i])
+		}
+	}
+	return prefix.String()
+}
+
+// OverwriteProtectedUntil returns when an object's overwrite protection window ends,
+// and whether it is still in effect
+func (b *Bucket) OverwriteProtectedUntil(obj *Object) (time.Time, bool) {
+	if b.NoOverwriteMinutes <= 0 {
+		return time.Time{}, false
+	}
+	until := obj.CreatedAt.Add(time.Duration(b.NoOverwriteMinutes) * time.Minute)
+	return until, time.Now().Before(until)
+}
+
 // Object represents a stored object
 type Object struct {
-	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	BucketID    uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_bucket_key_unique" json:"bucket_id"`
-	Key         string    `gorm:"not null;uniqueIndex:idx_bucket_key_unique" json:"key"` // Object name/path
-	Size        int64     `gorm:"not null" json:"size"`
-	ContentType string    `json:"content_type"`
-	ETag        string    `json:"etag"`
-	SHA256      string    `json:"sha256,omitempty"` // SHA256 hash of content
-	StoragePath string    `gorm:"not null" json:"-"` // Internal file system path
-	Metadata    *string   `gorm:"type:jsonb" json:"metadata,omitempty"` // JSON metadata (nullable)
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BucketID          uuid.UUID  `gorm:"type:uuid;not null;index;uniqueIndex:idx_bucket_key_unique" json:"bucket_id"`
+	Key               string     `gorm:"not null;uniqueIndex:idx_bucket_key_unique" json:"key"` // Object name/path
+	Size              int64      `gorm:"not null;index" json:"size"`
+	ContentType       string     `json:"content_type"`
+	ETag              string     `json:"etag"`
+	SHA256            string     `gorm:"index" json:"sha256,omitempty"`                           // SHA256 hash of content (indexed for by-hash lookups)
+	ChecksumAlgorithm string     `gorm:"not null;default:''" json:"checksum_algorithm,omitempty"` // S3 additional checksum algorithm (CRC32, CRC32C, CRC64NVME, SHA1, SHA256)
+	Checksum          string     `gorm:"not null;default:''" json:"checksum,omitempty"`           // Base64 checksum sent with an S3 PUT and verified on upload
+	StoragePath       string     `gorm:"not null" json:"-"`                                       // Internal file system path
+	Metadata          *string    `gorm:"type:jsonb" json:"metadata,omitempty"`                    // JSON metadata (nullable)
+	ACL               string     `gorm:"default:'inherit';not null" json:"acl"`                   // "inherit" (bucket policy applies) or "private"
+	UploadedBy        *uuid.UUID `gorm:"type:uuid;index" json:"uploaded_by,omitempty"`            // User who last wrote the object
+	ExpiresAt         *time.Time `gorm:"index" json:"expires_at,omitempty"`                       // Per-object TTL; deleted by the expiry job after this time
+	LastVerifiedAt    *time.Time `gorm:"index" json:"last_verified_at,omitempty"`                 // Last integrity scrub of the stored content
+	IntegrityStatus   string     `gorm:"not null;default:''" json:"integrity_status,omitempty"`   // Scrub result: "ok", "mismatch" or "missing" (empty: never verified)
+	LastAccessedAt    *time.Time `gorm:"index" json:"last_accessed_at,omitempty"`                 // Last download (batched, so up to a minute behind)
+	AccessCount       int64      `gorm:"not null;default:0" json:"access_count"`                  // Downloads, including ranged GETs
+	VersionID         string     `gorm:"not null;default:''" json:"version_id,omitempty"`         // Current version in a versioned bucket (empty: the "null" version)
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `gorm:"index" json:"updated_at"` // Last modified (indexed for ListObjects filters)
 
 	// Relationships
 	Bucket Bucket `gorm:"foreignKey:BucketID" json:"bucket,omitempty"`
@@ -132,9 +263,39 @@ func (o *Object) BeforeCreate(tx *gorm.DB) error {
 	if o.ID == uuid.Nil {
 		o.ID = uuid.New()
 	}
+	if o.ACL == "" {
+		o.ACL = ObjectACLInherit
+	}
 	return nil
 }
 
+// Object integrity status values, set by the integrity scrubber. A status is stale once the
+// object is modified after LastVerifiedAt
+const (
+	IntegrityOK       = "ok"
+	IntegrityMismatch = "mismatch" // Stored content no longer matches SHA256
+	IntegrityMissing  = "missing"  // No content in storage
+)
+
+// Object ACL values
+const (
+	ObjectACLInherit = "inherit" // Access follows bucket and user policies
+	ObjectACLPrivate = "private" // Bucket policy grants are ignored; requires bucket owner, uploader, or a user policy grant
+)
+
+// ParseObjectACL normalizes an object ACL from a form field or x-amz-acl header
+// Empty values inherit the bucket setting
+func ParseObjectACL(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", ObjectACLInherit, "bucket-owner-full-control":
+		return ObjectACLInherit, nil
+	case ObjectACLPrivate:
+		return ObjectACLPrivate, nil
+	default:
+		return "", fmt.Errorf("unsupported ACL %q (supported: inherit, private)", value)
+	}
+}
+
 // Policy represents an IAM-style access policy
 type Policy struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -173,6 +334,12 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+// CreateServiceAccountRequest represents the request body for creating a service account
+type CreateServiceAccountRequest struct {
+	Name      string      `json:"name" binding:"required,min=3,max=50"`
+	PolicyIDs []uuid.UUID `json:"policy_ids"` // Policies attached at creation; more can be attached later
+}
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
@@ -184,14 +351,34 @@ type CreateBucketRequest struct {
 	Region         string  `json:"region"`
 	StorageBackend string  `json:"storage_backend"` // "local" or "s3"
 	S3ConfigID     *string `json:"s3_config_id,omitempty"` // Optional: specific S3 config to use
+
+	CaseInsensitiveKeys bool `json:"case_insensitive_keys"` // Fold object keys to lowercase (default: case-sensitive like S3)
+	NoOverwriteMinutes  int  `json:"no_overwrite_minutes"`  // Reject overwrites within N minutes of creation (0 disables)
+
+	OnExisting string `json:"on_existing"` // OnExistingLink (default), OnExistingFail or OnExistingCreateNew
 }
 
+// CreateBucketRequest.OnExisting values: what to do when the storage backend already has a bucket with the name
+const (
+	OnExistingLink      = "link"       // Adopt it; its contents become visible in bkt
+	OnExistingFail      = "fail"       // Reject the request
+	OnExistingCreateNew = "create-new" // Require a new, empty bucket: an empty one is reused, one with objects is a name conflict
+)
+
 type CreatePolicyRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
 	Document    string `json:"document" binding:"required"`
 }
 
+// CreatePolicyFromTemplateRequest instantiates a predefined policy template
+type CreatePolicyFromTemplateRequest struct {
+	Template    string            `json:"template" binding:"required"`
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"` // Defaults to the template's description
+	Parameters  map[string]string `json:"parameters"`  // e.g. {"bucket": "logs"}
+}
+
 type UpdatePolicyRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
@@ -205,6 +392,7 @@ type CreateS3ConfigRequest struct {
 	AccessKeyID     string `json:"access_key_id" binding:"required"`
 	SecretAccessKey string `json:"secret_access_key" binding:"required"`
 	BucketPrefix    string `json:"bucket_prefix"`
+	ObjectKeyPrefix string `json:"object_key_prefix"`
 	UseSSL          *bool  `json:"use_ssl"`
 	ForcePathStyle  *bool  `json:"force_path_style"`
 	IsDefault       bool   `json:"is_default"`
@@ -217,6 +405,7 @@ type UpdateS3ConfigRequest struct {
 	AccessKeyID     string `json:"access_key_id"`
 	SecretAccessKey string `json:"secret_access_key"` // Only update if provided
 	BucketPrefix    string `json:"bucket_prefix"`
+	ObjectKeyPrefix string `json:"object_key_prefix"`
 	UseSSL          *bool  `json:"use_ssl"`
 	ForcePathStyle  *bool  `json:"force_path_style"`
 	IsDefault       *bool  `json:"is_default"`
@@ -245,6 +434,8 @@ type AuditLog struct {
 	Status      string    `gorm:"index;not null" json:"status"`             // "success", "failure", "denied"
 	ErrorMessage string   `gorm:"" json:"error_message,omitempty"`          // Error details if failed
 	Metadata    string    `gorm:"type:jsonb" json:"metadata,omitempty"`     // Additional context (JSON)
+	AccessKeyID *uuid.UUID `gorm:"type:uuid;index" json:"access_key_id,omitempty"` // Set for S3 requests signed with an access key
+	PrincipalType string   `gorm:"index;default:'user'" json:"principal_type"`     // PrincipalUser or PrincipalServiceAccount (who made the request)
 	CreatedAt   time.Time `gorm:"index" json:"created_at"`
 
 	// Relationships
@@ -259,18 +450,24 @@ func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Audit log principal types
+const (
+	PrincipalUser           = "user"
+	PrincipalServiceAccount = "service_account"
+)
+
 // IdempotencyKey represents a stored idempotency key for preventing duplicate requests
 type IdempotencyKey struct {
 	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Key          string     `gorm:"uniqueIndex;not null" json:"key"`                 // Client-provided idempotency key
-	UserID       uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`         // User who made the request
-	Method       string     `gorm:"not null" json:"method"`                          // HTTP method (POST, PUT, etc.)
-	Path         string     `gorm:"not null" json:"path"`                            // Request path
-	StatusCode   int        `gorm:"not null" json:"status_code"`                     // Response status code
-	ResponseBody string     `gorm:"type:text" json:"response_body"`                  // Cached response body
-	RequestHash  string     `gorm:"not null" json:"request_hash"`                    // SHA256 hash of request body
+	Key          string     `gorm:"uniqueIndex:idx_idempotency_user_key;not null" json:"key"`                     // Client-provided idempotency key (unique per user)
+	UserID       uuid.UUID  `gorm:"type:uuid;index;uniqueIndex:idx_idempotency_user_key;not null" json:"user_id"` // User who made the request
+	Method       string     `gorm:"not null" json:"method"`                                                       // HTTP method (POST, PUT, etc.)
+	Path         string     `gorm:"not null" json:"path"`                                                         // Request path
+	StatusCode   int        `gorm:"not null" json:"status_code"`                                                  // Response status code (0 while the request is in flight)
+	ResponseBody string     `gorm:"type:text" json:"response_body"`                                               // Cached response body
+	RequestHash  string     `gorm:"not null" json:"request_hash"`                                                 // SHA256 hash of request body
 	CreatedAt    time.Time  `gorm:"index" json:"created_at"`
-	ExpiresAt    time.Time  `gorm:"index;not null" json:"expires_at"`                // TTL expiration
+	ExpiresAt    time.Time  `gorm:"index;not null" json:"expires_at"`                                             // TTL expiration
 
 	// Relationships
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -298,3 +495,12 @@ type SuccessResponse struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
+
+// PaginatedResponse is the list envelope returned when a client requests it (?envelope=true)
+type PaginatedResponse struct {
+	Items   interface{} `json:"items"`
+	Total   int64       `json:"total"`           // Items matching the request across all pages
+	Limit   int         `json:"limit,omitempty"` // Page size (0: unlimited)
+	Offset  int         `json:"offset"`
+	HasMore bool        `json:"has_more"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "testing"
+
+func TestNormalizeKeyCollisions(t *testing.T) {
+	tests := []struct {
+		name               string
+		a, b               string
+		collideInsensitive bool
+		collideSensitive   bool
+	}{
+		{"case-only difference", "Photo.jpg", "photo.jpg", true, false},
+		{"case difference in a folder", "Reports/Q1.pdf", "reports/q1.PDF", true, false},
+		{"non-ASCII case difference", "Äpfel/Übersicht.txt", "äpfel/übersicht.txt", true, false},
+		{"Kelvin sign folds to k", "\u212Aelvin.txt", "kelvin.txt", true, false},
+		{"sharp s isn't folded to ss", "STRASSE.txt", "straße.txt", false, false},
+		{"different keys", "photo.jpg", "photo.png", false, false},
+		{"identical keys", "photo.jpg", "photo.jpg", true, true},
+	}
+
+	insensitive := &Bucket{CaseInsensitiveKeys: true}
+	sensitive := &Bucket{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := insensitive.NormalizeKey(tt.a) == insensitive.NormalizeKey(tt.b); got != tt.collideInsensitive {
+				t.Errorf("case-insensitive: %q and %q collide = %v, want %v", tt.a, tt.b, got, tt.collideInsensitive)
+			}
+			if got := sensitive.NormalizeKey(tt.a) == sensitive.NormalizeKey(tt.b); got != tt.collideSensitive {
+				t.Errorf("case-sensitive: %q and %q collide = %v, want %v", tt.a, tt.b, got, tt.collideSensitive)
+			}
+		})
+	}
+}
+
+func TestNormalizeKeyPrefix(t *testing.T) {
+	// Prefixes are folded like keys, so a listing finds objects whatever case the prefix was typed in
+	insensitive := &Bucket{CaseInsensitiveKeys: true}
+	if got := insensitive.NormalizeKey("Reports/2024/"); got != "reports/2024/" {
+		t.Errorf("case-insensitive NormalizeKey(prefix) = %q, want %q", got, "reports/2024/")
+	}
+
+	sensitive := &Bucket{}
+	if got := sensitive.NormalizeKey("Reports/2024/"); got != "Reports/2024/" {
+		t.Errorf("case-sensitive NormalizeKey(prefix) = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MultipartUpload is an S3 multipart upload in progress. Its parts are staged by the storage
+// backend and only become the object when the upload is completed
+type MultipartUpload struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"` // UploadId given to the client
+	BucketID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"bucket_id"`
+	Key             string     `gorm:"not null" json:"key"`
+	StorageUploadID string     `gorm:"not null" json:"-"`                     // The backend's own ID (native upload ID on S3)
+	ContentType     string     `json:"content_type"`                          // Declared on initiation; honored only if trusted
+	ACL             string     `gorm:"default:'inherit';not null" json:"acl"` // Object ACL applied on completion
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`                  // Object TTL applied on completion
+	Metadata        *string    `gorm:"type:jsonb" json:"metadata,omitempty"`  // User metadata (x-amz-meta-*) applied on completion
+	InitiatedBy     uuid.UUID  `gorm:"type:uuid;not null;index" json:"initiated_by"`
+	CreatedAt       time.Time  `gorm:"index" json:"created_at"`
+
+	// Relationships
+	Bucket Bucket          `gorm:"foreignKey:BucketID" json:"-"`
+	Parts  []MultipartPart `gorm:"foreignKey:UploadID" json:"parts,omitempty"`
+}
+
+// MultipartPart is one stored part of a multipart upload. Uploading the same part number again
+// replaces it
+type MultipartPart struct {
+	UploadID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"upload_id"`
+	PartNumber   int       `gorm:"primaryKey;autoIncrement:false" json:"part_number"`
+	Size         int64     `gorm:"not null" json:"size"`
+	ETag         string    `gorm:"not null" json:"etag"` // Hex MD5 of the part
+	StorageETag  string    `gorm:"not null" json:"-"`    // The backend's ETag, needed to complete native uploads
+	DetectedType string    `json:"-"`                    // Content type detected from the part's first bytes
+	UpdatedAt    time.Time `json:"updated_at"`
+}
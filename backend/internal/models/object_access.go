@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserObjectAccess records when a user last downloaded an object, for their "recent files"
+// list (GET /api/users/me/recent-objects). Rows are written in batches, not per download
+type UserObjectAccess struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"-"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_object_access" json:"-"`
+	ObjectID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_object_access;index" json:"object_id"`
+	LastAccessedAt time.Time `gorm:"not null;index" json:"last_accessed_at"`
+	AccessCount    int64     `gorm:"not null;default:0" json:"access_count"`
+}
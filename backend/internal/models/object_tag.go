@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ObjectTag is one key/value tag on an object (at most 10 per object). Tags are removed with
+// their object
+type ObjectTag struct {
+	ObjectID uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	Key      string    `gorm:"primaryKey" json:"key"`
+	Value    string    `gorm:"not null;default:''" json:"value"`
+
+	// Relationships
+	Object Object `gorm:"foreignKey:ObjectID;constraint:OnDelete:CASCADE" json:"-"`
+}
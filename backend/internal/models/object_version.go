@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NullVersionID is the version ID S3 gives objects written while versioning was off
+const NullVersionID = "null"
+
+// ObjectVersion is a previous version of an object in a versioned bucket, or a delete marker
+// recording that the object was deleted. The current version of a key stays in objects; a
+// version keeps enough of the object's metadata to become current again
+type ObjectVersion struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"-"` // Also names the saved content in storage
+	BucketID       uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_object_version_unique" json:"bucket_id"`
+	Key            string     `gorm:"not null;uniqueIndex:idx_object_version_unique" json:"key"`
+	VersionID      string     `gorm:"not null;uniqueIndex:idx_object_version_unique" json:"version_id"`
+	IsDeleteMarker bool       `gorm:"not null;default:false" json:"is_delete_marker"`
+	Size           int64      `gorm:"not null;default:0" json:"size"`
+	ContentType    string     `json:"content_type,omitempty"`
+	ETag           string     `json:"etag,omitempty"`
+	SHA256         string     `json:"sha256,omitempty"`
+	Metadata       *string    `gorm:"type:jsonb" json:"metadata,omitempty"`
+	ACL            string     `gorm:"default:'inherit';not null" json:"acl,omitempty"`
+	UploadedBy     *uuid.UUID `gorm:"type:uuid" json:"uploaded_by,omitempty"`
+	LastModified   time.Time  `gorm:"index" json:"last_modified"` // When this version was written (or the delete happened)
+	CreatedAt      time.Time  `json:"created_at"`                 // When it stopped being the current version
+}
+
+// StorageID is the name the storage backend keeps this version's content under
+func (v *ObjectVersion) StorageID() string {
+	return hex.EncodeToString(v.ID[:])
+}
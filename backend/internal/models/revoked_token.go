@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevokedToken records a JWT (by its jti) that must no longer be accepted, e.g. after logout.
+// Rows can be dropped once the token would have expired anyway
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey" json:"jti"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserTokenRevocation invalidates every token issued to a user before RevokedAt, e.g. after
+// their admin flag changed. The row can be dropped once all such tokens would have expired
+type UserTokenRevocation struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	RevokedAt time.Time `gorm:"not null" json:"revoked_at"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SystemSetting stores server-wide runtime settings that must survive restarts
+// and be shared by all instances (e.g. maintenance mode)
+type SystemSetting struct {
+	Key       string     `gorm:"primaryKey" json:"key"`
+	Value     string     `gorm:"type:text;not null" json:"value"` // JSON-encoded setting value
+	UpdatedBy *uuid.UUID `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// System setting keys
+const (
+	SettingMaintenanceMode = "maintenance_mode"
+)
+
+// MaintenanceMode is the persisted state of the server-wide read-only mode
+type MaintenanceMode struct {
+	Enabled    bool       `json:"enabled"`
+	Message    string     `json:"message,omitempty"`
+	RetryAfter int        `json:"retry_after"` // Seconds, sent as Retry-After on rejected writes
+	Since      *time.Time `json:"since,omitempty"`
+}
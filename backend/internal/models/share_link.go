@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShareLink is a revocable public URL for one object (GET /api/share/:token). The link follows
+// the key, so overwriting the object shares the new content. Downloads run with the creator's
+// permissions, checked on every request
+type ShareLink struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TokenHash     string     `gorm:"uniqueIndex;not null" json:"-"` // SHA256 of the token; the token is only returned on creation
+	BucketID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"bucket_id"`
+	ObjectKey     string     `gorm:"not null" json:"object_key"`
+	CreatedBy     uuid.UUID  `gorm:"type:uuid;not null;index" json:"created_by"`
+	ExpiresAt     time.Time  `gorm:"index" json:"expires_at"`
+	MaxDownloads  int        `gorm:"not null;default:0" json:"max_downloads"` // 0 = unlimited
+	DownloadCount int        `gorm:"not null;default:0" json:"download_count"`
+	PasswordHash  string     `gorm:"not null;default:''" json:"-"` // bcrypt; empty when the link has no password
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	// Relationships
+	Bucket Bucket `gorm:"foreignKey:BucketID" json:"-"`
+}
+
+// HasPassword reports whether downloads must present the link's password
+func (s *ShareLink) HasPassword() bool {
+	return s.PasswordHash != ""
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// AdminStats is the server overview returned by the admin dashboard endpoint
+type AdminStats struct {
+	Users             int64            `json:"users"`            // Login accounts (service accounts excluded)
+	LockedUsers       int64            `json:"locked_users"`
+	ServiceAccounts   int64            `json:"service_accounts"`
+	Buckets           int64            `json:"buckets"`
+	Objects           int64            `json:"objects"`
+	StorageBytes      int64            `json:"storage_bytes"`       // Sum of tracked object sizes
+	ActiveAccessKeys  int64            `json:"active_access_keys"`  // Active and not expired
+	UploadsInProgress int64            `json:"uploads_in_progress"` // Async/resumable uploads pending, queued or processing
+	FailedUploads24h  int64            `json:"failed_uploads_24h"`
+	AuditEvents24h    map[string]int64 `json:"audit_events_24h"` // Audit log entries by status (success, failure, denied)
+	GeneratedAt       time.Time        `json:"generated_at"`
+}
@@ -11,28 +11,35 @@ import (
 type UploadStatus string
 
 const (
-	UploadStatusPending    UploadStatus = "pending"
-	UploadStatusProcessing UploadStatus = "processing"
-	UploadStatusCompleted  UploadStatus = "completed"
-	UploadStatusFailed     UploadStatus = "failed"
+	UploadStatusPending     UploadStatus = "pending"
+	UploadStatusQueued      UploadStatus = "queued" // Waiting for a free background upload worker
+	UploadStatusProcessing  UploadStatus = "processing"
+	UploadStatusCompleted   UploadStatus = "completed"
+	UploadStatusFailed      UploadStatus = "failed"
+	UploadStatusQuarantined UploadStatus = "quarantined" // Malware scan flagged the content; it was discarded
 )
 
 // Upload represents an asynchronous file upload
 type Upload struct {
-	ID           uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID       uuid.UUID    `gorm:"type:uuid;not null;index" json:"user_id"`
-	BucketName   string       `gorm:"not null" json:"bucket_name"`
-	ObjectKey    string       `gorm:"not null" json:"object_key"`
-	Filename     string       `gorm:"not null" json:"filename"`
-	ContentType  string       `json:"content_type"`
-	TotalSize    int64        `gorm:"not null" json:"total_size"`
-	UploadedSize int64        `gorm:"default:0" json:"uploaded_size"`
-	Status       UploadStatus `gorm:"type:text;not null;index" json:"status"`
-	ErrorMessage string       `json:"error_message,omitempty"`
-	ObjectID     *uuid.UUID   `gorm:"type:uuid" json:"object_id,omitempty"` // Set when upload completes
-	CreatedAt    time.Time    `gorm:"index" json:"created_at"`
-	UpdatedAt    time.Time    `json:"updated_at"`
-	CompletedAt  *time.Time   `json:"completed_at,omitempty"`
+	ID            uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID        uuid.UUID    `gorm:"type:uuid;not null;index" json:"user_id"`
+	BucketName    string       `gorm:"not null" json:"bucket_name"`
+	ObjectKey     string       `gorm:"not null" json:"object_key"`
+	Filename      string       `gorm:"not null" json:"filename"`
+	ContentType   string       `json:"content_type"`
+	ACL           string       `gorm:"default:'inherit'" json:"acl"` // Object ACL applied on completion
+	ExpiresAt     *time.Time   `json:"expires_at,omitempty"`         // Object TTL applied on completion
+	TotalSize     int64        `gorm:"not null" json:"total_size"`
+	UploadedSize  int64        `gorm:"default:0" json:"uploaded_size"`
+	Status        UploadStatus `gorm:"type:text;not null;index" json:"status"`
+	ErrorMessage  string       `json:"error_message,omitempty"`
+	ScanVerdict   string       `json:"scan_verdict,omitempty"`               // "clean", "infected" or "error" when scanning is enabled
+	ScanSignature string       `json:"scan_signature,omitempty"`             // Detected malware name
+	ObjectID      *uuid.UUID   `gorm:"type:uuid" json:"object_id,omitempty"` // Set when upload completes
+	TempPath      string       `json:"-"`                                    // Staging file for resumable (tus) uploads
+	CreatedAt     time.Time    `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	CompletedAt   *time.Time   `json:"completed_at,omitempty"`
 
 	// Relationships
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -58,8 +65,11 @@ type UploadStatusResponse struct {
 	UploadedSize  int64        `json:"uploaded_size"`
 	ProgressPct   float64      `json:"progress_percent"`
 	ErrorMessage  string       `json:"error_message,omitempty"`
+	ScanVerdict   string       `json:"scan_verdict,omitempty"`
+	ScanSignature string       `json:"scan_signature,omitempty"`
 	ObjectID      *uuid.UUID   `json:"object_id,omitempty"`
 	CreatedAt     time.Time    `json:"created_at"`
 	CompletedAt   *time.Time   `json:"completed_at,omitempty"`
 	EstimatedTime *string      `json:"estimated_time_remaining,omitempty"` // e.g., "2m 30s"
+	QueuePosition int          `json:"queue_position,omitempty"`           // 1-based position while queued
 }
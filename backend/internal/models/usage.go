@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UsageStat aggregates transferred bytes per user, bucket and day
+// Used for per-tenant bandwidth billing and monitoring
+type UsageStat struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Day        time.Time `gorm:"type:date;not null;uniqueIndex:idx_usage_day_user_bucket" json:"day"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_usage_day_user_bucket;index" json:"user_id"`
+	BucketName string    `gorm:"not null;uniqueIndex:idx_usage_day_user_bucket;index" json:"bucket_name"`
+	BytesIn    int64     `gorm:"not null;default:0" json:"bytes_in"`  // Request bodies (uploads)
+	BytesOut   int64     `gorm:"not null;default:0" json:"bytes_out"` // Response bodies (downloads)
+	Requests   int64     `gorm:"not null;default:0" json:"requests"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (u *UsageStat) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
+}
+
+// UsageSummary is the aggregated usage returned by the usage endpoints
+type UsageSummary struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	BytesIn  int64       `json:"bytes_in"`
+	BytesOut int64       `json:"bytes_out"`
+	Requests int64       `json:"requests"`
+	Daily    []UsageStat `json:"daily"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BucketWebsite is a bucket's static website configuration (managed through the S3 ?website
+// subresource). Objects in the bucket are served anonymously at the website endpoints
+type BucketWebsite struct {
+	BucketID      uuid.UUID `gorm:"type:uuid;primary_key" json:"bucket_id"`
+	IndexDocument string    `gorm:"not null;default:''" json:"index_document,omitempty"` // Suffix served for directory requests, e.g. index.html
+	ErrorDocument string    `gorm:"not null;default:''" json:"error_document,omitempty"` // Key served with 404 when nothing matches
+
+	// RedirectAllRequestsTo: every request is redirected to this host (the other settings are unused)
+	RedirectHostName string `gorm:"not null;default:''" json:"redirect_host_name,omitempty"`
+	RedirectProtocol string `gorm:"not null;default:''" json:"redirect_protocol,omitempty"`
+
+	RoutingRules string    `gorm:"type:jsonb;not null;default:'[]'" json:"routing_rules"` // JSON-encoded []WebsiteRoutingRule
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// WebsiteRoutingRule redirects website requests matching a key prefix and/or an error code
+type WebsiteRoutingRule struct {
+	// Condition (at least one when set; both must match)
+	KeyPrefixEquals             string `json:"key_prefix_equals,omitempty"`
+	HTTPErrorCodeReturnedEquals string `json:"http_error_code_returned_equals,omitempty"`
+
+	// Redirect
+	HostName             string `json:"host_name,omitempty"`
+	Protocol             string `json:"protocol,omitempty"`
+	ReplaceKeyPrefixWith string `json:"replace_key_prefix_with,omitempty"`
+	ReplaceKeyWith       string `json:"replace_key_with,omitempty"`
+	HTTPRedirectCode     string `json:"http_redirect_code,omitempty"` // Default 301
+}
+
+// Rules decodes the routing rules (an unreadable value yields none)
+func (w *BucketWebsite) Rules() []WebsiteRoutingRule {
+	var rules []WebsiteRoutingRule
+	json.Unmarshal([]byte(w.RoutingRules), &rules)
+	return rules
+}
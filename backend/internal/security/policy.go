@@ -3,8 +3,10 @@ package security
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // PolicyDocument represents an IAM-style policy document
@@ -13,13 +15,17 @@ type PolicyDocument struct {
 	Statement []PolicyStatement `json:"Statement"`
 }
 
-// PolicyStatement represents a single policy statement
+// PolicyStatement represents a single policy statement. A statement names its actions with either
+// Action or NotAction (every action except those listed), and its resources with either Resource
+// or NotResource
 type PolicyStatement struct {
-	Sid       string                 `json:"Sid,omitempty"`       // Statement ID
-	Effect    string                 `json:"Effect"`              // "Allow" or "Deny"
-	Action    []string               `json:"Action"`              // Actions this statement applies to
-	Resource  []string               `json:"Resource"`            // Resources this statement applies to
-	Condition map[string]interface{} `json:"Condition,omitempty"` // Conditions for the statement
+	Sid         string                 `json:"Sid,omitempty"`         // Statement ID
+	Effect      string                 `json:"Effect"`                // "Allow" or "Deny"
+	Action      []string               `json:"Action,omitempty"`      // Actions this statement applies to
+	NotAction   []string               `json:"NotAction,omitempty"`   // Actions this statement doesn't apply to
+	Resource    []string               `json:"Resource,omitempty"`    // Resources this statement applies to
+	NotResource []string               `json:"NotResource,omitempty"` // Resources this statement doesn't apply to
+	Condition   map[string]interface{} `json:"Condition,omitempty"`   // Conditions for the statement
 }
 
 // PolicyEffect represents the effect of a policy
@@ -37,6 +43,11 @@ type PolicyEvaluationContext struct {
 	Resource   string
 	IsAdmin    bool
 	Conditions map[string]string
+
+	// Condition keys: aws:SourceIp (nil when the check isn't made for a request) and
+	// aws:CurrentTime (the current time when zero)
+	SourceIP    net.IP
+	CurrentTime time.Time
 }
 
 // ValidatePolicyDocument validates a policy document for security and correctness
@@ -79,6 +90,25 @@ func ValidatePolicyDocument(documentJSON string) (*PolicyDocument, error) {
 	return &policy, nil
 }
 
+// ValidateBucketPolicyScope checks that every resource in a bucket policy refers to that bucket:
+// "*", "arn:aws:s3:::<bucket>" or "arn:aws:s3:::<bucket>/...". A bucket policy pointing at another
+// bucket would otherwise be accepted but silently grant nothing (or the wrong thing)
+func ValidateBucketPolicyScope(policy *PolicyDocument, bucketName string) error {
+	bucketARN := "arn:aws:s3:::" + bucketName
+	for i, stmt := range policy.Statement {
+		for _, resources := range [][]string{stmt.Resource, stmt.NotResource} {
+			for _, resource := range resources {
+				if resource == "*" || resource == bucketARN || strings.HasPrefix(resource, bucketARN+"/") {
+					continue
+				}
+				return fmt.Errorf("statement %d: resource '%s' is outside this bucket (use %s, %s/* or *)",
+					i, resource, bucketARN, bucketARN)
+			}
+		}
+	}
+	return nil
+}
+
 // validateStatement validates a single policy statement
 func validateStatement(stmt *PolicyStatement, index int) error {
 	// Validate Effect
@@ -86,18 +116,25 @@ func validateStatement(stmt *PolicyStatement, index int) error {
 		return fmt.Errorf("effect must be 'Allow' or 'Deny', got: %s", stmt.Effect)
 	}
 
-	// Validate Action (must have at least one)
-	if len(stmt.Action) == 0 {
+	// Validate Action or NotAction (exactly one, with at least one action)
+	actions := stmt.Action
+	if len(stmt.NotAction) > 0 {
+		if len(stmt.Action) > 0 {
+			return fmt.Errorf("statement cannot have both Action and NotAction")
+		}
+		actions = stmt.NotAction
+	}
+	if len(actions) == 0 {
 		return fmt.Errorf("statement must have at least one action")
 	}
 
 	// Limit number of actions per statement (prevent DoS)
-	if len(stmt.Action) > 50 {
+	if len(actions) > 50 {
 		return fmt.Errorf("statement cannot contain more than 50 actions")
 	}
 
 	// Validate action format and prevent dangerous wildcards
-	for _, action := range stmt.Action {
+	for _, action := range actions {
 		if err := validateAction(action); err != nil {
 			return fmt.Errorf("invalid action '%s': %w", action, err)
 		}
@@ -107,18 +144,25 @@ func validateStatement(stmt *PolicyStatement, index int) error {
 		}
 	}
 
-	// Validate Resource (must have at least one)
-	if len(stmt.Resource) == 0 {
+	// Validate Resource or NotResource (exactly one, with at least one resource)
+	resources := stmt.Resource
+	if len(stmt.NotResource) > 0 {
+		if len(stmt.Resource) > 0 {
+			return fmt.Errorf("statement cannot have both Resource and NotResource")
+		}
+		resources = stmt.NotResource
+	}
+	if len(resources) == 0 {
 		return fmt.Errorf("statement must have at least one resource")
 	}
 
 	// Limit number of resources per statement (prevent DoS)
-	if len(stmt.Resource) > 50 {
+	if len(resources) > 50 {
 		return fmt.Errorf("statement cannot contain more than 50 resources")
 	}
 
 	// Validate resource format
-	for _, resource := range stmt.Resource {
+	for _, resource := range resources {
 		if err := validateResource(resource); err != nil {
 			return fmt.Errorf("invalid resource '%s': %w", resource, err)
 		}
@@ -144,6 +188,9 @@ func validateStatement(stmt *PolicyStatement, index int) error {
 		if len(conditionJSON) > 2048 {
 			return fmt.Errorf("condition object too large (max 2KB)")
 		}
+		if err := validateCondition(stmt.Condition); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -259,38 +306,60 @@ func EvaluatePolicy(policy *PolicyDocument, ctx *PolicyEvaluationContext) bool {
 		return true
 	}
 
-	hasExplicitAllow := false
-	hasExplicitDeny := false
+	hasExplicitAllow, hasExplicitDeny := evaluateStatements(policy, ctx)
+
+	// DENY OVERRIDES ALLOW
+	if hasExplicitDeny {
+		return false
+	}
+
+	// DENY BY DEFAULT - only allow if explicit allow found
+	return hasExplicitAllow
+}
+
+// HasExplicitDeny reports whether a Deny statement in the policy applies to the request. Unlike
+// a false EvaluatePolicy result, this tells an explicit deny apart from the absence of an allow
+func HasExplicitDeny(policy *PolicyDocument, ctx *PolicyEvaluationContext) bool {
+	_, hasExplicitDeny := evaluateStatements(policy, ctx)
+	return hasExplicitDeny
+}
 
+// evaluateStatements reports whether any Allow and any Deny statement applies to the request
+func evaluateStatements(policy *PolicyDocument, ctx *PolicyEvaluationContext) (hasExplicitAllow, hasExplicitDeny bool) {
 	// Evaluate each statement
 	for _, statement := range policy.Statement {
-		// Check if statement applies to this action
-		if !matchesAction(statement.Action, ctx.Action) {
+		// Check if statement applies to this action (NotAction: any action not listed)
+		if len(statement.NotAction) > 0 {
+			if matchesAction(statement.NotAction, ctx.Action) {
+				continue
+			}
+		} else if !matchesAction(statement.Action, ctx.Action) {
+			continue
+		}
+
+		// Check if statement applies to this resource (NotResource: any resource not listed)
+		if len(statement.NotResource) > 0 {
+			if matchesResource(statement.NotResource, ctx.Resource) {
+				continue
+			}
+		} else if !matchesResource(statement.Resource, ctx.Resource) {
 			continue
 		}
 
-		// Check if statement applies to this resource
-		if !matchesResource(statement.Resource, ctx.Resource) {
+		// Statements whose conditions don't hold are skipped, not treated as a deny
+		if !matchesConditions(statement.Condition, ctx) {
 			continue
 		}
 
 		// Statement applies - check effect
 		if statement.Effect == string(EffectDeny) {
-			hasExplicitDeny = true
 			// Explicit deny wins - no need to check further
-			break
+			return hasExplicitAllow, true
 		} else if statement.Effect == string(EffectAllow) {
 			hasExplicitAllow = true
 		}
 	}
-
-	// DENY OVERRIDES ALLOW
-	if hasExplicitDeny {
-		return false
-	}
-
-	// DENY BY DEFAULT - only allow if explicit allow found
-	return hasExplicitAllow
+	return hasExplicitAllow, false
 }
 
 // matchesAction checks if an action matches any pattern in the list
@@ -0,0 +1,153 @@
+package security
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Supported policy condition keys
+const (
+	ConditionKeySourceIP    = "aws:SourceIp"
+	ConditionKeyCurrentTime = "aws:CurrentTime"
+)
+
+// conditionOperatorKeys maps each supported condition operator to the keys it can test
+var conditionOperatorKeys = map[string]string{
+	"IpAddress":       ConditionKeySourceIP,
+	"NotIpAddress":    ConditionKeySourceIP,
+	"DateLessThan":    ConditionKeyCurrentTime,
+	"DateGreaterThan": ConditionKeyCurrentTime,
+}
+
+// validateCondition checks that a statement's Condition block only uses supported operators and
+// keys, with values of the right form. An unknown operator is rejected rather than ignored, since
+// ignoring it would apply the statement more widely than its author meant
+func validateCondition(condition map[string]interface{}) error {
+	for operator, block := range condition {
+		key, ok := conditionOperatorKeys[operator]
+		if !ok {
+			return fmt.Errorf("unsupported condition operator '%s' (supported: IpAddress, NotIpAddress, DateLessThan, DateGreaterThan)", operator)
+		}
+		entries, ok := block.(map[string]interface{})
+		if !ok || len(entries) == 0 {
+			return fmt.Errorf("condition operator '%s' must map condition keys to values", operator)
+		}
+		for entryKey, raw := range entries {
+			if entryKey != key {
+				return fmt.Errorf("condition operator '%s' only supports the key '%s'", operator, key)
+			}
+			values, err := conditionValues(raw)
+			if err != nil {
+				return fmt.Errorf("condition %s %s: %w", operator, key, err)
+			}
+			for _, value := range values {
+				if key == ConditionKeySourceIP {
+					if _, err := parseConditionCIDR(value); err != nil {
+						return fmt.Errorf("condition %s %s: %w", operator, key, err)
+					}
+				} else if _, err := time.Parse(time.RFC3339, value); err != nil {
+					return fmt.Errorf("condition %s %s: '%s' is not an RFC 3339 time", operator, key, value)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesConditions reports whether every condition in a statement holds for the request. As in
+// AWS, operators and keys are ANDed and the values listed for one key are ORed. A statement whose
+// conditions don't hold doesn't apply to the request (it neither allows nor denies)
+func matchesConditions(condition map[string]interface{}, ctx *PolicyEvaluationContext) bool {
+	for operator, block := range condition {
+		entries, ok := block.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for _, raw := range entries {
+			values, err := conditionValues(raw)
+			if err != nil {
+				return false
+			}
+			if !matchesCondition(operator, values, ctx) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchesCondition evaluates one operator against the request
+func matchesCondition(operator string, values []string, ctx *PolicyEvaluationContext) bool {
+	switch operator {
+	case "IpAddress", "NotIpAddress":
+		// Negated operators hold when the request has no source IP, as in AWS
+		if ctx.SourceIP == nil {
+			return operator == "NotIpAddress"
+		}
+		inRange := false
+		for _, value := range values {
+			if network, err := parseConditionCIDR(value); err == nil && network.Contains(ctx.SourceIP) {
+				inRange = true
+				break
+			}
+		}
+		return inRange == (operator == "IpAddress")
+	case "DateLessThan", "DateGreaterThan":
+		now := ctx.CurrentTime
+		if now.IsZero() {
+			now = time.Now()
+		}
+		for _, value := range values {
+			limit, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				continue
+			}
+			if (operator == "DateLessThan" && now.Before(limit)) || (operator == "DateGreaterThan" && now.After(limit)) {
+				return true
+			}
+		}
+		return false
+	}
+	// Unknown operators never hold (ValidatePolicyDocument rejects them)
+	return false
+}
+
+// conditionValues reads a condition value, which may be a single string or a list of strings
+func conditionValues(raw interface{}) ([]string, error) {
+	switch value := raw.(type) {
+	case string:
+		return []string{value}, nil
+	case []interface{}:
+		if len(value) == 0 {
+			return nil, fmt.Errorf("value list cannot be empty")
+		}
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("values must be strings")
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("value must be a string or a list of strings")
+}
+
+// parseConditionCIDR parses an aws:SourceIp value: a CIDR range, or a single address
+func parseConditionCIDR(value string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("'%s' is not an IP address or CIDR range", value)
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
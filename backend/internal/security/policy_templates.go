@@ -0,0 +1,154 @@
+package security
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BucketPlaceholder stands in for the bucket name when a template is shown without parameters
+const BucketPlaceholder = "${bucket}"
+
+// PolicyTemplateParameter describes a value substituted into a policy template
+type PolicyTemplateParameter struct {
+	Name        string `json:"name"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+}
+
+// PolicyTemplate is a ready-made policy document that can be scoped with parameters
+type PolicyTemplate struct {
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Parameters  []PolicyTemplateParameter `json:"parameters"`
+
+	build func(bucket string) *PolicyDocument
+}
+
+// Render builds the template's policy document. An empty bucket leaves optional-bucket
+// templates unscoped (all buckets); templates that require a bucket return an error
+func (t *PolicyTemplate) Render(bucket string) (*PolicyDocument, error) {
+	for _, param := range t.Parameters {
+		if param.Name == "bucket" && param.Required && bucket == "" {
+			return nil, fmt.Errorf("template '%s' requires the bucket parameter", t.Name)
+		}
+	}
+	return t.build(bucket), nil
+}
+
+var bucketParameter = PolicyTemplateParameter{
+	Name:        "bucket",
+	Description: "Bucket the policy applies to (all buckets when omitted)",
+}
+
+var requiredBucketParameter = PolicyTemplateParameter{
+	Name:        "bucket",
+	Required:    true,
+	Description: "Bucket the policy applies to",
+}
+
+// policyTemplates is the catalog served by the policy template endpoints
+var policyTemplates = map[string]*PolicyTemplate{
+	"read-only": {
+		Name:        "read-only",
+		Description: "List buckets and download objects",
+		Parameters:  []PolicyTemplateParameter{bucketParameter},
+		build: func(bucket string) *PolicyDocument {
+			return scopeToBucket(GetDefaultReadOnlyPolicy(), bucket)
+		},
+	},
+	"read-write": {
+		Name:        "read-write",
+		Description: "List buckets, and download, upload and delete objects",
+		Parameters:  []PolicyTemplateParameter{bucketParameter},
+		build: func(bucket string) *PolicyDocument {
+			return scopeToBucket(allowPolicy("ReadWriteAccess",
+				"s3:GetObject", "s3:HeadObject", "s3:ListBucket", "s3:GetBucketLocation",
+				"s3:PutObject", "s3:DeleteObject"), bucket)
+		},
+	},
+	"list-only": {
+		Name:        "list-only",
+		Description: "List buckets and object keys without reading object content",
+		Parameters:  []PolicyTemplateParameter{bucketParameter},
+		build: func(bucket string) *PolicyDocument {
+			return scopeToBucket(allowPolicy("ListOnlyAccess",
+				"s3:ListBucket", "s3:GetBucketLocation"), bucket)
+		},
+	},
+	"bucket-admin": {
+		Name:        "bucket-admin",
+		Description: "Every S3 action on one bucket and its objects, including its bucket policy",
+		Parameters:  []PolicyTemplateParameter{requiredBucketParameter},
+		build: func(bucket string) *PolicyDocument {
+			return scopeToBucket(allowPolicy("BucketAdmin", "s3:*"), bucket)
+		},
+	},
+	"deny-all": {
+		Name:        "deny-all",
+		Description: "Deny every action; attach to suspend a user without deleting their account",
+		Parameters:  []PolicyTemplateParameter{},
+		build: func(string) *PolicyDocument {
+			return GetDefaultDenyAllPolicy()
+		},
+	},
+}
+
+// GetPolicyTemplate looks up a template by name
+func GetPolicyTemplate(name string) (*PolicyTemplate, bool) {
+	template, ok := policyTemplates[name]
+	return template, ok
+}
+
+// ListPolicyTemplates returns the template catalog sorted by name
+func ListPolicyTemplates() []*PolicyTemplate {
+	templates := make([]*PolicyTemplate, 0, len(policyTemplates))
+	for _, template := range policyTemplates {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates
+}
+
+// allowPolicy returns a single-statement Allow policy for actions on every resource
+func allowPolicy(sid string, actions ...string) *PolicyDocument {
+	return &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: []PolicyStatement{
+			{
+				Sid:      sid,
+				Effect:   string(EffectAllow),
+				Action:   actions,
+				Resource: []string{"*"},
+			},
+		},
+	}
+}
+
+// scopeToBucket narrows every statement to the bucket and its objects; an empty bucket leaves the policy unchanged
+func scopeToBucket(policy *PolicyDocument, bucket string) *PolicyDocument {
+	if bucket == "" {
+		return policy
+	}
+	bucketARN := "arn:aws:s3:::" + bucket
+	for i := range policy.Statement {
+		policy.Statement[i].Resource = []string{bucketARN, bucketARN + "/*"}
+	}
+	return policy
+}
+
+// RenderBucketPolicyTemplate substitutes bucketName for every ${bucket} in a bucket policy
+// template (e.g. DEFAULT_BUCKET_POLICY) and validates the result as that bucket's policy
+func RenderBucketPolicyTemplate(template, bucketName string) (string, error) {
+	document := strings.ReplaceAll(template, BucketPlaceholder, bucketName)
+	policy, err := ValidatePolicyDocument(document)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateBucketPolicyScope(policy, bucketName); err != nil {
+		return "", err
+	}
+	return document, nil
+}
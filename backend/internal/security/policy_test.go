@@ -0,0 +1,160 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluatePolicyNotAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		action   string
+		resource string
+		want     bool
+	}{
+		{
+			name:     "NotAction allows an unlisted action on a listed resource",
+			policy:   `{"Statement":[{"Effect":"Allow","NotAction":["s3:DeleteObject"],"Resource":["arn:aws:s3:::photos/*"]}]}`,
+			action:   "s3:GetObject",
+			resource: "arn:aws:s3:::photos/2024/beach.jpg",
+			want:     true,
+		},
+		{
+			name:     "NotAction doesn't allow a listed action",
+			policy:   `{"Statement":[{"Effect":"Allow","NotAction":["s3:DeleteObject"],"Resource":["arn:aws:s3:::photos/*"]}]}`,
+			action:   "s3:DeleteObject",
+			resource: "arn:aws:s3:::photos/2024/beach.jpg",
+			want:     false,
+		},
+		{
+			name:     "NotAction doesn't apply outside its resources",
+			policy:   `{"Statement":[{"Effect":"Allow","NotAction":["s3:DeleteObject"],"Resource":["arn:aws:s3:::photos/*"]}]}`,
+			action:   "s3:GetObject",
+			resource: "arn:aws:s3:::backups/db.tar",
+			want:     false,
+		},
+		{
+			name:     "NotAction service wildcard excludes the whole service",
+			policy:   `{"Statement":[{"Effect":"Allow","NotAction":["s3:*"],"Resource":["*"]}]}`,
+			action:   "s3:PutObject",
+			resource: "arn:aws:s3:::photos/a.jpg",
+			want:     false,
+		},
+		{
+			name: "Deny with NotAction denies every other action on its resources",
+			policy: `{"Statement":[
+				{"Effect":"Allow","Action":["*"],"Resource":["*"]},
+				{"Effect":"Deny","NotAction":["s3:GetObject","s3:ListBucket"],"Resource":["arn:aws:s3:::archive/*"]}]}`,
+			action:   "s3:PutObject",
+			resource: "arn:aws:s3:::archive/2019/report.pdf",
+			want:     false,
+		},
+		{
+			name: "Deny with NotAction leaves listed actions allowed",
+			policy: `{"Statement":[
+				{"Effect":"Allow","Action":["*"],"Resource":["*"]},
+				{"Effect":"Deny","NotAction":["s3:GetObject","s3:ListBucket"],"Resource":["arn:aws:s3:::archive/*"]}]}`,
+			action:   "s3:GetObject",
+			resource: "arn:aws:s3:::archive/2019/report.pdf",
+			want:     true,
+		},
+		{
+			name: "Deny with NotAction leaves other resources alone",
+			policy: `{"Statement":[
+				{"Effect":"Allow","Action":["*"],"Resource":["*"]},
+				{"Effect":"Deny","NotAction":["s3:GetObject","s3:ListBucket"],"Resource":["arn:aws:s3:::archive/*"]}]}`,
+			action:   "s3:PutObject",
+			resource: "arn:aws:s3:::photos/a.jpg",
+			want:     true,
+		},
+		{
+			name:     "NotAction with NotResource applies outside the listed resources",
+			policy:   `{"Statement":[{"Effect":"Allow","NotAction":["s3:DeleteObject"],"NotResource":["arn:aws:s3:::secrets/*"]}]}`,
+			action:   "s3:GetObject",
+			resource: "arn:aws:s3:::photos/a.jpg",
+			want:     true,
+		},
+		{
+			name:     "NotAction with NotResource doesn't apply to the listed resources",
+			policy:   `{"Statement":[{"Effect":"Allow","NotAction":["s3:DeleteObject"],"NotResource":["arn:aws:s3:::secrets/*"]}]}`,
+			action:   "s3:GetObject",
+			resource: "arn:aws:s3:::secrets/key.pem",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ValidatePolicyDocument(tt.policy)
+			if err != nil {
+				t.Fatalf("ValidatePolicyDocument: %v", err)
+			}
+			ctx := &PolicyEvaluationContext{Action: tt.action, Resource: tt.resource}
+			if got := EvaluatePolicy(policy, ctx); got != tt.want {
+				t.Errorf("EvaluatePolicy(%s on %s) = %v, want %v", tt.action, tt.resource, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePolicyDocumentRejectsMalformedStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr string
+	}{
+		{
+			name:    "Action and NotAction together",
+			policy:  `{"Statement":[{"Effect":"Allow","Action":["s3:GetObject"],"NotAction":["s3:DeleteObject"],"Resource":["*"]}]}`,
+			wantErr: "both Action and NotAction",
+		},
+		{
+			name:    "Resource and NotResource together",
+			policy:  `{"Statement":[{"Effect":"Allow","Action":["s3:GetObject"],"Resource":["*"],"NotResource":["arn:aws:s3:::secrets/*"]}]}`,
+			wantErr: "both Resource and NotResource",
+		},
+		{
+			name:    "neither Action nor NotAction",
+			policy:  `{"Statement":[{"Effect":"Allow","Resource":["*"]}]}`,
+			wantErr: "at least one action",
+		},
+		{
+			name:    "empty NotAction",
+			policy:  `{"Statement":[{"Effect":"Allow","NotAction":[],"Resource":["*"]}]}`,
+			wantErr: "at least one action",
+		},
+		{
+			name:    "neither Resource nor NotResource",
+			policy:  `{"Statement":[{"Effect":"Allow","NotAction":["s3:DeleteObject"]}]}`,
+			wantErr: "at least one resource",
+		},
+		{
+			name:    "malformed NotAction",
+			policy:  `{"Statement":[{"Effect":"Allow","NotAction":["DeleteObject"],"Resource":["*"]}]}`,
+			wantErr: "invalid action",
+		},
+		{
+			name:    "traversal in NotResource",
+			policy:  `{"Statement":[{"Effect":"Allow","Action":["s3:GetObject"],"NotResource":["photos/../secrets"]}]}`,
+			wantErr: "invalid resource",
+		},
+		{
+			name:    "invalid Effect",
+			policy:  `{"Statement":[{"Effect":"Maybe","NotAction":["s3:DeleteObject"],"Resource":["*"]}]}`,
+			wantErr: "effect must be",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidatePolicyDocument(tt.policy)
+			if err == nil {
+				t.Fatalf("ValidatePolicyDocument accepted %s", tt.policy)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidatePolicyDocument error = %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
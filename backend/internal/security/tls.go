@@ -0,0 +1,175 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"bkt/internal/config"
+)
+
+// CertReloader holds the server certificate and allows it to be swapped at runtime
+// so renewed certificates (e.g. Let's Encrypt) can be picked up without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewCertReloader loads the initial certificate/key pair
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the certificate and key from disk
+// The previous certificate stays in use if the new pair fails to load
+func (r *CertReloader) Reload() error {
+	if r.certFile == "" || r.keyFile == "" {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must both be set")
+	}
+
+	// LoadX509KeyPair also verifies that the private key matches the certificate
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate %s with key %s (check that they exist and match): %w", r.certFile, r.keyFile, err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+
+	return nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// BuildTLSConfig creates the server tls.Config from configuration
+func BuildTLSConfig(cfg config.TLSConfig, reloader *CertReloader) (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := parseCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuth, err := parseClientAuth(cfg.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	// S3 client certificate auth needs the handshake to verify certificates against the CA
+	switch cfg.S3ClientCertAuth {
+	case "disabled", "":
+	case "cert", "combined":
+		if clientAuth != tls.VerifyClientCertIfGiven && clientAuth != tls.RequireAndVerifyClientCert {
+			return nil, fmt.Errorf("S3_CLIENT_CERT_AUTH=%s requires TLS_CLIENT_AUTH to be verify_if_given or require", cfg.S3ClientCertAuth)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported S3_CLIENT_CERT_AUTH %q (must be disabled, cert, or combined)", cfg.S3ClientCertAuth)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     clientAuth,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	// Client certificates are verified against the configured CA
+	if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+		if cfg.CAFile == "" {
+			return nil, fmt.Errorf("TLS_CA_FILE must be set when TLS_CLIENT_AUTH is %q", cfg.ClientAuth)
+		}
+		caPEM, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %w", cfg.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificates found in CA file %s", cfg.CAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// parseTLSVersion converts "1.2"/"1.3" to the crypto/tls constant
+func parseTLSVersion(version string) (uint16, error) {
+	switch strings.TrimSpace(version) {
+	case "1.2", "":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS_MIN_VERSION %q (must be 1.2 or 1.3)", version)
+	}
+}
+
+// parseCipherSuites maps cipher suite names to IDs
+// Only suites Go considers secure are accepted
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("TLS_CIPHER_SUITES is empty; at least one cipher suite must be configured")
+	}
+
+	available := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		available[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite in TLS_CIPHER_SUITES: %s", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// parseClientAuth converts the TLS_CLIENT_AUTH setting to a tls.ClientAuthType
+func parseClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "none", "":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS_CLIENT_AUTH %q (must be none, request, verify_if_given, or require)", mode)
+	}
+}
+
+// CertificateFingerprint returns the hex-encoded SHA256 of the DER certificate
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,152 @@
+package security
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"bkt/internal/config"
+)
+
+// Scan verdicts recorded on uploads
+const (
+	ScanVerdictClean    = "clean"
+	ScanVerdictInfected = "infected"
+	ScanVerdictError    = "error" // The scan failed; the upload was published only because fail-open is set
+)
+
+// clamdChunkSize is the size of the INSTREAM chunks sent to clamd
+const clamdChunkSize = 64 * 1024
+
+// ScanResult is a scanner's verdict on one file
+type ScanResult struct {
+	Infected  bool
+	Signature string // Name of the detected malware, if any
+}
+
+// UploadScanner inspects uploaded content for malware before it is published
+type UploadScanner interface {
+	Scan(ctx context.Context, content io.Reader) (*ScanResult, error)
+}
+
+// NewUploadScanner returns the scanner selected by the configuration, or nil when scanning is disabled
+func NewUploadScanner(cfg config.ScannerConfig) UploadScanner {
+	switch cfg.Mode {
+	case "clamav":
+		return &clamdScanner{address: cfg.Address}
+	case "http":
+		return &httpScanner{url: cfg.Address, client: &http.Client{}}
+	default:
+		return nil
+	}
+}
+
+// clamdScanner streams content to a ClamAV daemon over TCP using the INSTREAM command
+type clamdScanner struct {
+	address string
+}
+
+func (s *clamdScanner) Scan(ctx context.Context, content io.Reader) (*ScanResult, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("failed to send INSTREAM command: %w", err)
+	}
+
+	// Each chunk is prefixed with its length; a zero length ends the stream
+	buf := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := content.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return nil, fmt.Errorf("failed to stream content to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return nil, fmt.Errorf("failed to stream content to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read content: %w", readErr)
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return nil, fmt.Errorf("failed to stream content to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && reply == "" {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply interprets "stream: OK", "stream: <signature> FOUND" and "... ERROR" replies
+func parseClamdReply(reply string) (*ScanResult, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	default:
+		// e.g. "INSTREAM size limit exceeded. ERROR" when the file is larger than clamd's StreamMaxLength
+		return nil, fmt.Errorf("clamd scan failed: %s", reply)
+	}
+}
+
+// httpScanner posts content to a generic scan endpoint, which must answer 200 with
+// {"infected": bool, "signature": "..."}
+type httpScanner struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpScanner) Scan(ctx context.Context, content io.Reader) (*ScanResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, content)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("scan request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scan endpoint returned HTTP %d", resp.StatusCode)
+	}
+
+	var verdict struct {
+		Infected  *bool  `json:"infected"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&verdict); err != nil {
+		return nil, fmt.Errorf("invalid scan response: %w", err)
+	}
+	if verdict.Infected == nil {
+		return nil, fmt.Errorf("invalid scan response: missing \"infected\"")
+	}
+	return &ScanResult{Infected: *verdict.Infected, Signature: verdict.Signature}, nil
+}
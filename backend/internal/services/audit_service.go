@@ -69,6 +69,18 @@ func (as *AuditService) LogAction(
 		CreatedAt:    time.Now(),
 	}
 
+	// Requests authenticated with a service token are attributed to the service account (set by AuthMiddleware)
+	auditLog.PrincipalType = models.PrincipalUser
+	if c.GetBool("service_account") {
+		auditLog.PrincipalType = models.PrincipalServiceAccount
+	}
+
+	// Attribute S3 requests to the access key that signed them (set by S3AuthMiddleware)
+	if keyID, exists := c.Get("access_key_id"); exists {
+		accessKeyID := keyID.(uuid.UUID)
+		auditLog.AccessKeyID = &accessKeyID
+	}
+
 	// Save to database
 	result := database.DB.Create(&auditLog)
 	return result.Error
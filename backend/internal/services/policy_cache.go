@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bkt/internal/database"
+	"bkt/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Permission checks load the caller (with attached policies) and the bucket (with its bucket
+// policy) on every request. Both are cached for a short TTL. Changes made through this
+// instance invalidate the affected entries immediately; other instances see them once their
+// entries expire, so the TTL bounds how long a revoked permission can keep working elsewhere
+
+// userPolicyCacheEntry is a user with their attached policies
+type userPolicyCacheEntry struct {
+	user      *models.User
+	expiresAt time.Time
+}
+
+// bucketPolicyCacheEntry is a bucket with its bucket policy (nil when it has none)
+type bucketPolicyCacheEntry struct {
+	bucket    *models.Bucket
+	policy    *models.BucketPolicy
+	expiresAt time.Time
+}
+
+var (
+	userPolicyCache   = make(map[uuid.UUID]*userPolicyCacheEntry)
+	bucketPolicyCache = make(map[string]*bucketPolicyCacheEntry)
+	policyCacheMu     sync.RWMutex
+
+	// policyCacheTTL is set at startup from POLICY_CACHE_TTL (0 disables caching)
+	policyCacheTTL        = 30 * time.Second
+	policyCacheMaxEntries = 10000
+
+	// policyCacheGeneration is bumped by every invalidation. A load that started before an
+	// invalidation doesn't store its (possibly stale) result
+	policyCacheGeneration atomic.Uint64
+)
+
+// SetPolicyCacheTTL sets how long users' and buckets' policies are cached (0 disables the cache)
+func SetPolicyCacheTTL(ttl time.Duration) {
+	policyCacheMu.Lock()
+	defer policyCacheMu.Unlock()
+
+	policyCacheTTL = ttl
+	userPolicyCache = make(map[uuid.UUID]*userPolicyCacheEntry)
+	bucketPolicyCache = make(map[string]*bucketPolicyCacheEntry)
+}
+
+// loadUserWithPolicies returns the user with their attached policies. The result is shared
+// between callers and must not be modified
+func loadUserWithPolicies(userID uuid.UUID) (*models.User, error) {
+	now := time.Now()
+	policyCacheMu.RLock()
+	entry, ok := userPolicyCache[userID]
+	policyCacheMu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.user, nil
+	}
+
+	generation := policyCacheGeneration.Load()
+	var user models.User
+	if err := database.DB.Preload("Policies").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	policyCacheMu.Lock()
+	if policyCacheTTL > 0 && policyCacheGeneration.Load() == generation {
+		if len(userPolicyCache) >= policyCacheMaxEntries {
+			evictExpiredPoliciesLocked(now)
+		}
+		if len(userPolicyCache) < policyCacheMaxEntries {
+			userPolicyCache[userID] = &userPolicyCacheEntry{user: &user, expiresAt: now.Add(policyCacheTTL)}
+		}
+	}
+	policyCacheMu.Unlock()
+
+	return &user, nil
+}
+
+// loadBucketWithPolicy returns the bucket and its bucket policy (nil when it has none), or
+// gorm.ErrRecordNotFound. The results are shared between callers and must not be modified
+func loadBucketWithPolicy(bucketName string) (*models.Bucket, *models.BucketPolicy, error) {
+	now := time.Now()
+	policyCacheMu.RLock()
+	entry, ok := bucketPolicyCache[bucketName]
+	policyCacheMu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.bucket, entry.policy, nil
+	}
+
+	generation := policyCacheGeneration.Load()
+	var bucket models.Bucket
+	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var policy *models.BucketPolicy
+	var bucketPolicy models.BucketPolicy
+	if err := database.DB.Where("bucket_id = ?", bucket.ID).First(&bucketPolicy).Error; err == nil {
+		policy = &bucketPolicy
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	policyCacheMu.Lock()
+	if policyCacheTTL > 0 && policyCacheGeneration.Load() == generation {
+		if len(bucketPolicyCache) >= policyCacheMaxEntries {
+			evictExpiredPoliciesLocked(now)
+		}
+		if len(bucketPolicyCache) < policyCacheMaxEntries {
+			bucketPolicyCache[bucketName] = &bucketPolicyCacheEntry{bucket: &bucket, policy: policy, expiresAt: now.Add(policyCacheTTL)}
+		}
+	}
+	policyCacheMu.Unlock()
+
+	return &bucket, policy, nil
+}
+
+// evictExpiredPoliciesLocked removes expired entries (caller must hold the write lock)
+func evictExpiredPoliciesLocked(now time.Time) {
+	for id, entry := range userPolicyCache {
+		if now.After(entry.expiresAt) {
+			delete(userPolicyCache, id)
+		}
+	}
+	for name, entry := range bucketPolicyCache {
+		if now.After(entry.expiresAt) {
+			delete(bucketPolicyCache, name)
+		}
+	}
+}
+
+// InvalidateUserPolicyCache drops a user's cached policies (called when policies are attached
+// or detached, or the user's role changes or they are deleted)
+func InvalidateUserPolicyCache(userID uuid.UUID) {
+	policyCacheMu.Lock()
+	defer policyCacheMu.Unlock()
+
+	policyCacheGeneration.Add(1)
+	delete(userPolicyCache, userID)
+}
+
+// InvalidateBucketPolicyCache drops a bucket's cached policy (called when the bucket policy is
+// set or removed, or the bucket is deleted)
+func InvalidateBucketPolicyCache(bucketName string) {
+	policyCacheMu.Lock()
+	defer policyCacheMu.Unlock()
+
+	policyCacheGeneration.Add(1)
+	delete(bucketPolicyCache, bucketName)
+}
+
+// InvalidatePolicyCache drops every cached entry (called when a policy document shared by
+// many users changes, or metadata is bulk-imported)
+func InvalidatePolicyCache() {
+	policyCacheMu.Lock()
+	defer policyCacheMu.Unlock()
+
+	policyCacheGeneration.Add(1)
+	userPolicyCache = make(map[uuid.UUID]*userPolicyCacheEntry)
+	bucketPolicyCache = make(map[string]*bucketPolicyCacheEntry)
+}
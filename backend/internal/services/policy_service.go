@@ -2,26 +2,44 @@ package services
 
 import (
 	"fmt"
+	"net"
+	"time"
 	"bkt/internal/database"
 	"bkt/internal/models"
 	"bkt/internal/security"
 
+	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 // S3 Actions - Standard AWS S3 action constants
 const (
-	ActionListAllMyBuckets  = "s3:ListAllMyBuckets"
-	ActionGetBucketLocation = "s3:GetBucketLocation"
-	ActionCreateBucket      = "s3:CreateBucket"
-	ActionDeleteBucket      = "s3:DeleteBucket"
-	ActionListBucket        = "s3:ListBucket"
-	ActionGetObject         = "s3:GetObject"
-	ActionPutObject         = "s3:PutObject"
-	ActionDeleteObject      = "s3:DeleteObject"
-	ActionHeadObject        = "s3:HeadObject"
-	ActionGetBucketPolicy   = "s3:GetBucketPolicy"
-	ActionPutBucketPolicy   = "s3:PutBucketPolicy"
+	ActionListAllMyBuckets    = "s3:ListAllMyBuckets"
+	ActionGetBucketLocation   = "s3:GetBucketLocation"
+	ActionCreateBucket        = "s3:CreateBucket"
+	ActionDeleteBucket        = "s3:DeleteBucket"
+	ActionListBucket          = "s3:ListBucket"
+	ActionGetObject           = "s3:GetObject"
+	ActionPutObject           = "s3:PutObject"
+	ActionDeleteObject        = "s3:DeleteObject"
+	ActionHeadObject          = "s3:HeadObject"
+	ActionGetBucketPolicy     = "s3:GetBucketPolicy"
+	ActionPutBucketPolicy     = "s3:PutBucketPolicy"
+	ActionGetBucketWebsite    = "s3:GetBucketWebsite"
+	ActionPutBucketWebsite    = "s3:PutBucketWebsite"
+	ActionDeleteBucketWebsite = "s3:DeleteBucketWebsite"
+	ActionGetBucketAcl        = "s3:GetBucketAcl"
+	ActionPutBucketAcl        = "s3:PutBucketAcl"
+	ActionGetObjectAcl        = "s3:GetObjectAcl"
+	ActionPutObjectAcl        = "s3:PutObjectAcl"
+	ActionGetBucketVersioning = "s3:GetBucketVersioning"
+	ActionPutBucketVersioning = "s3:PutBucketVersioning"
+	ActionListBucketVersions  = "s3:ListBucketVersions"
+	ActionGetObjectVersion    = "s3:GetObjectVersion"
+	ActionDeleteObjectVersion = "s3:DeleteObjectVersion"
+	ActionGetObjectTagging    = "s3:GetObjectTagging"
+	ActionPutObjectTagging    = "s3:PutObjectTagging"
+	ActionDeleteObjectTagging = "s3:DeleteObjectTagging"
 )
 
 // PolicyService handles policy evaluation and enforcement
@@ -32,8 +50,26 @@ func NewPolicyService() *PolicyService {
 	return &PolicyService{}
 }
 
-// CheckBucketAccess checks if a user has permission to perform an action on a bucket
-func (ps *PolicyService) CheckBucketAccess(userID uuid.UUID, bucketName, action string) (result bool, err error) {
+// newEvaluationContext describes the request being checked, for policy Condition blocks: the
+// client address (aws:SourceIp) and the time (aws:CurrentTime). c is nil for checks made outside
+// a request, which leaves aws:SourceIp unset
+func newEvaluationContext(c *gin.Context, action, resource string) *security.PolicyEvaluationContext {
+	ctx := &security.PolicyEvaluationContext{
+		Action:      action,
+		Resource:    resource,
+		CurrentTime: time.Now(),
+	}
+	if c != nil {
+		// X-Forwarded-For is only believed from TRUSTED_PROXIES (see SetupRouter), since any
+		// client can set it
+		ctx.SourceIP = net.ParseIP(c.ClientIP())
+	}
+	return ctx
+}
+
+// CheckBucketAccess checks if a user has permission to perform an action on a bucket, for the
+// request c (see newEvaluationContext)
+func (ps *PolicyService) CheckBucketAccess(c *gin.Context, userID uuid.UUID, bucketName, action string) (result bool, err error) {
 	// Recover from panics to prevent service crash (fail-safe: deny access on panic)
 	defer func() {
 		if r := recover(); r != nil {
@@ -42,9 +78,9 @@ func (ps *PolicyService) CheckBucketAccess(userID uuid.UUID, bucketName, action
 		}
 	}()
 
-	// Get user with policies
-	var user models.User
-	if err := database.DB.Preload("Policies").First(&user, userID).Error; err != nil {
+	// Get user with policies (cached)
+	user, err := loadUserWithPolicies(userID)
+	if err != nil {
 		return false, fmt.Errorf("failed to fetch user: %w", err)
 	}
 
@@ -53,26 +89,23 @@ func (ps *PolicyService) CheckBucketAccess(userID uuid.UUID, bucketName, action
 		return true, nil
 	}
 
-	// Get bucket (to check ownership and bucket policies)
-	var bucket models.Bucket
-	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+	// Get bucket policy, if any (cached)
+	_, bucketPolicy, err := loadBucketWithPolicy(bucketName)
+	if err != nil {
 		// Bucket doesn't exist - deny access
 		return false, nil
 	}
 
 	// Build resource ARN
 	resourceARN := fmt.Sprintf("arn:aws:s3:::%s", bucketName)
+	evalCtx := newEvaluationContext(c, action, resourceARN)
 
 	// Check user policies
-	userPolicyResult := ps.evaluateUserPolicies(&user, action, resourceARN)
-
-	// Get bucket policy if it exists
-	var bucketPolicy models.BucketPolicy
-	hasBucketPolicy := database.DB.Where("bucket_id = ?", bucket.ID).First(&bucketPolicy).Error == nil
+	userPolicyResult := ps.evaluateUserPolicies(user, evalCtx)
 
-	if hasBucketPolicy {
+	if bucketPolicy != nil {
 		// Evaluate bucket policy
-		bucketPolicyResult, err := ps.evaluateBucketPolicy(&bucketPolicy, action, resourceARN)
+		bucketPolicyResult, err := ps.evaluateBucketPolicy(bucketPolicy, evalCtx)
 		if err != nil {
 			// If bucket policy is malformed, fall back to user policies only
 			return userPolicyResult, nil
@@ -91,8 +124,9 @@ func (ps *PolicyService) CheckBucketAccess(userID uuid.UUID, bucketName, action
 	return userPolicyResult, nil
 }
 
-// CheckObjectAccess checks if a user has permission to perform an action on an object
-func (ps *PolicyService) CheckObjectAccess(userID uuid.UUID, bucketName, objectKey, action string) (result bool, err error) {
+// CheckObjectAccess checks if a user has permission to perform an action on an object, for the
+// request c (see newEvaluationContext)
+func (ps *PolicyService) CheckObjectAccess(c *gin.Context, userID uuid.UUID, bucketName, objectKey, action string) (result bool, err error) {
 	// Recover from panics to prevent service crash (fail-safe: deny access on panic)
 	defer func() {
 		if r := recover(); r != nil {
@@ -101,9 +135,9 @@ func (ps *PolicyService) CheckObjectAccess(userID uuid.UUID, bucketName, objectK
 		}
 	}()
 
-	// Get user with policies
-	var user models.User
-	if err := database.DB.Preload("Policies").First(&user, userID).Error; err != nil {
+	// Get user with policies (cached)
+	user, err := loadUserWithPolicies(userID)
+	if err != nil {
 		return false, fmt.Errorf("failed to fetch user: %w", err)
 	}
 
@@ -112,26 +146,41 @@ func (ps *PolicyService) CheckObjectAccess(userID uuid.UUID, bucketName, objectK
 		return true, nil
 	}
 
-	// Get bucket (to check bucket policies)
-	var bucket models.Bucket
-	if err := database.DB.Where("name = ?", bucketName).First(&bucket).Error; err != nil {
+	// Get bucket (for ownership) and its bucket policy, if any (cached)
+	bucket, bucketPolicy, err := loadBucketWithPolicy(bucketName)
+	if err != nil {
 		// Bucket doesn't exist - deny access
 		return false, nil
 	}
 
 	// Build resource ARN - for objects, include the key
 	resourceARN := fmt.Sprintf("arn:aws:s3:::%s/%s", bucketName, objectKey)
+	evalCtx := newEvaluationContext(c, action, resourceARN)
 
-	// Check user policies
-	userPolicyResult := ps.evaluateUserPolicies(&user, action, resourceARN)
+	// An explicit Deny in the user's policies or the bucket policy wins over everything below,
+	// including the owner and uploader allowance for private objects
+	if ps.hasExplicitDeny(user, bucketPolicy, evalCtx) {
+		return false, nil
+	}
 
-	// Get bucket policy if it exists
-	var bucketPolicy models.BucketPolicy
-	hasBucketPolicy := database.DB.Where("bucket_id = ?", bucket.ID).First(&bucketPolicy).Error == nil
+	// Check user policies
+	userPolicyResult := ps.evaluateUserPolicies(user, evalCtx)
+
+	// Private objects ignore bucket policy grants - only the bucket owner, the uploader,
+	// or an explicit user policy grant can access them
+	var object models.Object
+	if err := database.DB.Select("acl", "uploaded_by").
+		Where("bucket_id = ? AND key = ?", bucket.ID, objectKey).
+		First(&object).Error; err == nil && object.ACL == models.ObjectACLPrivate {
+		if bucket.OwnerID == user.ID || (object.UploadedBy != nil && *object.UploadedBy == user.ID) {
+			return true, nil
+		}
+		return userPolicyResult, nil
+	}
 
-	if hasBucketPolicy {
+	if bucketPolicy != nil {
 		// Evaluate bucket policy
-		bucketPolicyResult, err := ps.evaluateBucketPolicy(&bucketPolicy, action, resourceARN)
+		bucketPolicyResult, err := ps.evaluateBucketPolicy(bucketPolicy, evalCtx)
 		if err != nil {
 			// If bucket policy is malformed, fall back to user policies only
 			return userPolicyResult, nil
@@ -149,7 +198,7 @@ func (ps *PolicyService) CheckObjectAccess(userID uuid.UUID, bucketName, objectK
 }
 
 // evaluateUserPolicies evaluates all user policies
-func (ps *PolicyService) evaluateUserPolicies(user *models.User, action, resource string) bool {
+func (ps *PolicyService) evaluateUserPolicies(user *models.User, evalCtx *security.PolicyEvaluationContext) bool {
 	// Admin bypass
 	if user.IsAdmin {
 		return true
@@ -165,7 +214,7 @@ func (ps *PolicyService) evaluateUserPolicies(user *models.User, action, resourc
 
 	// Evaluate each policy
 	for _, policy := range user.Policies {
-		result, err := ps.evaluatePolicy(policy.Document, action, resource, user.IsAdmin)
+		result, err := ps.evaluatePolicy(policy.Document, evalCtx)
 		if err != nil {
 			// Skip malformed policies
 			continue
@@ -187,13 +236,36 @@ func (ps *PolicyService) evaluateUserPolicies(user *models.User, action, resourc
 	return hasExplicitAllow
 }
 
+// hasExplicitDeny reports whether a Deny statement in any of the user's policies or in the bucket
+// policy (nil if none) applies to the request. Malformed policies are skipped, as when evaluating
+func (ps *PolicyService) hasExplicitDeny(user *models.User, bucketPolicy *models.BucketPolicy, evalCtx *security.PolicyEvaluationContext) bool {
+	documents := make([]string, 0, len(user.Policies)+1)
+	for _, policy := range user.Policies {
+		documents = append(documents, policy.Document)
+	}
+	if bucketPolicy != nil {
+		documents = append(documents, bucketPolicy.PolicyDocument)
+	}
+
+	for _, document := range documents {
+		policyDoc, err := security.ValidatePolicyDocument(document)
+		if err != nil {
+			continue
+		}
+		if security.HasExplicitDeny(policyDoc, evalCtx) {
+			return true
+		}
+	}
+	return false
+}
+
 // evaluateBucketPolicy evaluates a bucket policy
-func (ps *PolicyService) evaluateBucketPolicy(bucketPolicy *models.BucketPolicy, action, resource string) (bool, error) {
-	return ps.evaluatePolicy(bucketPolicy.PolicyDocument, action, resource, false)
+func (ps *PolicyService) evaluateBucketPolicy(bucketPolicy *models.BucketPolicy, evalCtx *security.PolicyEvaluationContext) (bool, error) {
+	return ps.evaluatePolicy(bucketPolicy.PolicyDocument, evalCtx)
 }
 
 // evaluatePolicy parses and evaluates a policy document with panic recovery
-func (ps *PolicyService) evaluatePolicy(policyJSON string, action, resource string, isAdmin bool) (result bool, err error) {
+func (ps *PolicyService) evaluatePolicy(policyJSON string, evalCtx *security.PolicyEvaluationContext) (result bool, err error) {
 	// Recover from panics in policy evaluation (prevent resource leaks)
 	defer func() {
 		if r := recover(); r != nil {
@@ -209,15 +281,8 @@ func (ps *PolicyService) evaluatePolicy(policyJSON string, action, resource stri
 		return false, fmt.Errorf("failed to parse policy: %w", err)
 	}
 
-	// Create evaluation context
-	ctx := &security.PolicyEvaluationContext{
-		Action:   action,
-		Resource: resource,
-		IsAdmin:  isAdmin,
-	}
-
 	// Evaluate using the security package
-	return security.EvaluatePolicy(policyDoc, ctx), nil
+	return security.EvaluatePolicy(policyDoc, evalCtx), nil
 }
 
 // GetUserPolicies retrieves all policies attached to a user
@@ -247,7 +312,13 @@ func (ps *PolicyService) GetBucketPolicy(bucketName string) (*models.BucketPolic
 // SetBucketPolicy sets or updates the policy document for a bucket
 func (ps *PolicyService) SetBucketPolicy(bucketName, policyDocument string) error {
 	// Validate policy document first
-	if _, err := security.ValidatePolicyDocument(policyDocument); err != nil {
+	policy, err := security.ValidatePolicyDocument(policyDocument)
+	if err != nil {
+		return fmt.Errorf("invalid policy document: %w", err)
+	}
+
+	// Bucket policies may only reference their own bucket
+	if err := security.ValidateBucketPolicyScope(policy, bucketName); err != nil {
 		return fmt.Errorf("invalid policy document: %w", err)
 	}
 
@@ -259,7 +330,7 @@ func (ps *PolicyService) SetBucketPolicy(bucketName, policyDocument string) erro
 
 	// Check if bucket policy already exists
 	var bucketPolicy models.BucketPolicy
-	err := database.DB.Where("bucket_id = ?", bucket.ID).First(&bucketPolicy).Error
+	err = database.DB.Where("bucket_id = ?", bucket.ID).First(&bucketPolicy).Error
 
 	if err != nil {
 		// Create new bucket policy
@@ -267,12 +338,15 @@ func (ps *PolicyService) SetBucketPolicy(bucketName, policyDocument string) erro
 			BucketID:       bucket.ID,
 			PolicyDocument: policyDocument,
 		}
-		return database.DB.Create(&bucketPolicy).Error
+		err = database.DB.Create(&bucketPolicy).Error
+	} else {
+		// Update existing policy
+		bucketPolicy.PolicyDocument = policyDocument
+		err = database.DB.Save(&bucketPolicy).Error
 	}
 
-	// Update existing policy
-	bucketPolicy.PolicyDocument = policyDocument
-	return database.DB.Save(&bucketPolicy).Error
+	InvalidateBucketPolicyCache(bucketName)
+	return err
 }
 
 // DeleteBucketPolicy removes the policy document from a bucket
@@ -284,20 +358,22 @@ func (ps *PolicyService) DeleteBucketPolicy(bucketName string) error {
 	}
 
 	// Delete bucket policy
-	return database.DB.Where("bucket_id = ?", bucket.ID).Delete(&models.BucketPolicy{}).Error
+	err := database.DB.Where("bucket_id = ?", bucket.ID).Delete(&models.BucketPolicy{}).Error
+	InvalidateBucketPolicyCache(bucketName)
+	return err
 }
 
 // FilterAccessibleBuckets performs batch permission checks on a list of buckets
 // Returns only buckets the user has permission to access (fixes N+1 query problem)
-func (ps *PolicyService) FilterAccessibleBuckets(userID uuid.UUID, buckets []models.Bucket, action string) ([]models.Bucket, error) {
+func (ps *PolicyService) FilterAccessibleBuckets(c *gin.Context, userID uuid.UUID, buckets []models.Bucket, action string) ([]models.Bucket, error) {
 	// Empty list - return early
 	if len(buckets) == 0 {
 		return buckets, nil
 	}
 
 	// Load user with policies ONCE (instead of N times)
-	var user models.User
-	if err := database.DB.Preload("Policies").First(&user, userID).Error; err != nil {
+	user, err := loadUserWithPolicies(userID)
+	if err != nil {
 		return nil, fmt.Errorf("failed to fetch user: %w", err)
 	}
 
@@ -329,14 +405,15 @@ func (ps *PolicyService) FilterAccessibleBuckets(userID uuid.UUID, buckets []mod
 	for _, bucket := range buckets {
 		// Build resource ARN
 		resourceARN := fmt.Sprintf("arn:aws:s3:::%s", bucket.Name)
+		evalCtx := newEvaluationContext(c, action, resourceARN)
 
 		// Check user policies
-		userPolicyResult := ps.evaluateUserPolicies(&user, action, resourceARN)
+		userPolicyResult := ps.evaluateUserPolicies(user, evalCtx)
 
 		// Check bucket policy if exists
 		bucketPolicy, hasBucketPolicy := bucketPolicyMap[bucket.ID]
 		if hasBucketPolicy {
-			bucketPolicyResult, err := ps.evaluateBucketPolicy(bucketPolicy, action, resourceARN)
+			bucketPolicyResult, err := ps.evaluateBucketPolicy(bucketPolicy, evalCtx)
 			if err != nil {
 				// If bucket policy is malformed, fall back to user policies only
 				if userPolicyResult {
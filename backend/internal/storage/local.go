@@ -2,6 +2,7 @@ package storage
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -9,25 +10,105 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
+// On-disk layouts of local buckets
+const (
+	LayoutFlat   = "flat"   // rootPath/bucket/key
+	LayoutFanout = "fanout" // rootPath/bucket/ab/cd/key, where "abcd" starts the hex SHA256 of the key
+)
+
+// layoutDir holds a marker file per fan-out bucket recording its layout. Bucket names can't
+// start with a dot, so it never collides with a bucket, and it sits outside every bucket so
+// no object key can overwrite it
+const layoutDir = ".layouts"
+
+// bucketLayouts caches the layout of existing bucket directories (bucket path -> layout)
+var bucketLayouts sync.Map
+
 // LocalStorage implements StorageBackend using local filesystem
 type LocalStorage struct {
 	rootPath string
+	layout   string // Layout given to buckets this instance creates
 }
 
-// NewLocalStorage creates a new local storage backend
-func NewLocalStorage(rootPath string) *LocalStorage {
+// NewLocalStorage creates a new local storage backend. layout only applies to buckets created
+// through it; existing buckets keep the layout they were created with
+func NewLocalStorage(rootPath, layout string) *LocalStorage {
+	if layout != LayoutFanout {
+		layout = LayoutFlat
+	}
 	return &LocalStorage{
 		rootPath: rootPath,
+		layout:   layout,
+	}
+}
+
+// layoutMarkerPath returns where a bucket's layout is recorded
+func (ls *LocalStorage) layoutMarkerPath(bucketName string) string {
+	return filepath.Join(ls.rootPath, layoutDir, bucketName)
+}
+
+// bucketLayout returns the layout a bucket was created with. Buckets without a marker, including
+// those created before layouts existed and linked pre-existing directories, are flat
+func (ls *LocalStorage) bucketLayout(bucketName string) (string, error) {
+	bucketPath := filepath.Join(ls.rootPath, bucketName)
+	if layout, ok := bucketLayouts.Load(bucketPath); ok {
+		return layout.(string), nil
+	}
+
+	layout := LayoutFlat
+	data, err := os.ReadFile(ls.layoutMarkerPath(bucketName))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read bucket layout: %w", err)
+	}
+	if err == nil && strings.TrimSpace(string(data)) == LayoutFanout {
+		layout = LayoutFanout
+	}
+
+	// Only remember the layout once the bucket exists; until then CreateBucket may still record one
+	if info, err := os.Stat(bucketPath); err == nil && info.IsDir() {
+		bucketLayouts.Store(bucketPath, layout)
+	}
+	return layout, nil
+}
+
+// objectPath maps an object key to its file according to the bucket's layout
+func (ls *LocalStorage) objectPath(bucketName, objectKey string) (string, error) {
+	layout, err := ls.bucketLayout(bucketName)
+	if err != nil {
+		return "", err
+	}
+
+	bucketPath := filepath.Join(ls.rootPath, bucketName)
+	if layout != LayoutFanout {
+		return filepath.Join(bucketPath, objectKey), nil
 	}
+
+	// Two levels of 256 directories each spread any single prefix over 65536 directories
+	sum := sha256.Sum256([]byte(objectKey))
+	digest := hex.EncodeToString(sum[:2])
+	return filepath.Join(bucketPath, digest[:2], digest[2:4], objectKey), nil
 }
 
 // CreateBucket creates a bucket directory in the local filesystem
 func (ls *LocalStorage) CreateBucket(bucketName, region string) error {
 	bucketPath := filepath.Join(ls.rootPath, bucketName)
 
+	// Record the layout of new buckets only: a directory that already exists is being linked
+	// and its contents are laid out flat
+	if _, err := os.Stat(bucketPath); os.IsNotExist(err) && ls.layout == LayoutFanout {
+		if err := os.MkdirAll(filepath.Join(ls.rootPath, layoutDir), 0755); err != nil {
+			return fmt.Errorf("failed to create layout directory: %w", err)
+		}
+		if err := os.WriteFile(ls.layoutMarkerPath(bucketName), []byte(LayoutFanout+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to record bucket layout: %w", err)
+		}
+	}
+	bucketLayouts.Delete(bucketPath)
+
 	// Create the bucket directory
 	if err := os.MkdirAll(bucketPath, 0755); err != nil {
 		return fmt.Errorf("failed to create bucket directory: %w", err)
@@ -45,6 +126,17 @@ func (ls *LocalStorage) DeleteBucket(bucketName string) error {
 		return fmt.Errorf("failed to delete bucket directory: %w", err)
 	}
 
+	// Previous object versions are kept outside the bucket directory
+	if err := os.RemoveAll(filepath.Join(ls.rootPath, versionsDir, bucketName)); err != nil {
+		return fmt.Errorf("failed to delete bucket versions: %w", err)
+	}
+
+	// Forget the layout so a future bucket with this name gets the configured one
+	if err := os.Remove(ls.layoutMarkerPath(bucketName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete bucket layout: %w", err)
+	}
+	bucketLayouts.Delete(bucketPath)
+
 	return nil
 }
 
@@ -64,28 +156,91 @@ func (ls *LocalStorage) BucketExists(bucketName string) (bool, error) {
 	return info.IsDir(), nil
 }
 
-// PutObject stores an object in the local filesystem
+// PutObject stores an object in the local filesystem. The content is written to a temp file and
+// renamed into place, so a failed or rejected write leaves any existing object unchanged
 func (ls *LocalStorage) PutObject(bucketName, objectKey string, data io.Reader, size int64, contentType string) error {
-	bucketPath := filepath.Join(ls.rootPath, bucketName)
-	objectPath := filepath.Join(bucketPath, objectKey)
+	objectPath, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return err
+	}
+
+	return replaceFile(data, objectPath, ".upload-*")
+}
+
+// replaceFile writes src to dstPath through a temp file named by pattern, so a failed copy never
+// leaves a truncated file behind
+func replaceFile(src io.Reader, dstPath, pattern string) error {
+	dstDir := filepath.Dir(dstPath)
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return writeError("failed to create directory", err)
+	}
+
+	tmpFile, err := os.CreateTemp(dstDir, pattern)
+	if err != nil {
+		return writeError("failed to create file", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	if _, err := io.Copy(tmpFile, src); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath) // Also gives back the space taken by the partial write
+		return writeError("failed to write file", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return writeError("failed to write file", err)
+	}
+
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move file into place: %w", err)
+	}
+
+	return nil
+}
+
+// CheckSpace reports ErrInsufficientStorage if the storage volume has less than size bytes free
+func (ls *LocalStorage) CheckSpace(bucketName string, size int64) error {
+	available, ok := availableBytes(ls.rootPath)
+	if !ok || uint64(size) <= available {
+		return nil
+	}
+	return fmt.Errorf("%w: %d bytes needed, %d bytes free on the storage volume", ErrInsufficientStorage, size, available)
+}
+
+// writeError wraps a filesystem write failure, marking full-volume and quota failures with ErrInsufficientStorage
+func writeError(action string, err error) error {
+	if isNoSpaceError(err) {
+		return fmt.Errorf("%s: %w: %w", action, ErrInsufficientStorage, err)
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
 
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(objectPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// AppendObject adds size bytes to the end of an existing file. A short or failed write is
+// truncated away so the object never keeps a partial append
+func (ls *LocalStorage) AppendObject(bucketName, objectKey string, data io.Reader, size int64) error {
+	objectPath, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return err
 	}
 
-	// Create the file
-	file, err := os.Create(objectPath)
+	file, err := os.OpenFile(objectPath, os.O_WRONLY|os.O_APPEND, 0)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("object not found")
+		}
+		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	// Copy data to file
-	_, err = io.Copy(file, data)
+	info, err := file.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	if _, err := io.CopyN(file, data, size); err != nil {
+		file.Truncate(info.Size())
+		return writeError("failed to append to file", err)
 	}
 
 	return nil
@@ -93,7 +248,10 @@ func (ls *LocalStorage) PutObject(bucketName, objectKey string, data io.Reader,
 
 // GetObject retrieves an object from the local filesystem
 func (ls *LocalStorage) GetObject(bucketName, objectKey string) (io.ReadCloser, error) {
-	objectPath := filepath.Join(ls.rootPath, bucketName, objectKey)
+	objectPath, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
 
 	file, err := os.Open(objectPath)
 	if err != nil {
@@ -106,11 +264,37 @@ func (ls *LocalStorage) GetObject(bucketName, objectKey string) (io.ReadCloser,
 	return file, nil
 }
 
+// GetObjectRange reads part of an object from the local filesystem
+func (ls *LocalStorage) GetObjectRange(bucketName, objectKey string, offset, length int64) (io.ReadCloser, error) {
+	objectPath, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(objectPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("object not found")
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	return limitedReadCloser{io.LimitReader(file, length), file}, nil
+}
+
 // DeleteObject removes an object from the local filesystem
 func (ls *LocalStorage) DeleteObject(bucketName, objectKey string) error {
-	objectPath := filepath.Join(ls.rootPath, bucketName, objectKey)
+	objectPath, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return err
+	}
 
-	err := os.Remove(objectPath)
+	err = os.Remove(objectPath)
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
@@ -123,7 +307,12 @@ func (ls *LocalStorage) ListObjects(bucketName, prefix string) ([]ObjectInfo, er
 	bucketPath := filepath.Join(ls.rootPath, bucketName)
 	objects := make([]ObjectInfo, 0)
 
-	err := filepath.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
+	layout, err := ls.bucketLayout(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.Walk(bucketPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -142,6 +331,15 @@ func (ls *LocalStorage) ListObjects(bucketName, prefix string) ([]ObjectInfo, er
 		// Convert to forward slashes for consistency
 		key := filepath.ToSlash(relPath)
 
+		// Fan-out buckets nest every key under its two hash directories
+		if layout == LayoutFanout {
+			parts := strings.SplitN(key, "/", 3)
+			if len(parts) != 3 {
+				return nil
+			}
+			key = parts[2]
+		}
+
 		// Filter by prefix if provided
 		if prefix != "" && !strings.HasPrefix(key, prefix) {
 			return nil
@@ -180,9 +378,12 @@ func (ls *LocalStorage) ListObjects(bucketName, prefix string) ([]ObjectInfo, er
 
 // ObjectExists checks if an object exists in a bucket
 func (ls *LocalStorage) ObjectExists(bucketName, objectKey string) (bool, error) {
-	objectPath := filepath.Join(ls.rootPath, bucketName, objectKey)
+	objectPath, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return false, err
+	}
 
-	_, err := os.Stat(objectPath)
+	_, err = os.Stat(objectPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, nil
@@ -195,7 +396,10 @@ func (ls *LocalStorage) ObjectExists(bucketName, objectKey string) (bool, error)
 
 // GetObjectInfo gets metadata about an object
 func (ls *LocalStorage) GetObjectInfo(bucketName, objectKey string) (*ObjectInfo, error) {
-	objectPath := filepath.Join(ls.rootPath, bucketName, objectKey)
+	objectPath, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
 
 	info, err := os.Stat(objectPath)
 	if err != nil {
@@ -225,8 +429,14 @@ func (ls *LocalStorage) GetObjectInfo(bucketName, objectKey string) (*ObjectInfo
 
 // CopyObject copies an object within the same bucket
 func (ls *LocalStorage) CopyObject(bucketName, srcKey, dstKey string) error {
-	srcPath := filepath.Join(ls.rootPath, bucketName, srcKey)
-	dstPath := filepath.Join(ls.rootPath, bucketName, dstKey)
+	srcPath, err := ls.objectPath(bucketName, srcKey)
+	if err != nil {
+		return err
+	}
+	dstPath, err := ls.objectPath(bucketName, dstKey)
+	if err != nil {
+		return err
+	}
 
 	// Check source exists
 	if _, err := os.Stat(srcPath); os.IsNotExist(err) {
@@ -264,6 +474,56 @@ func (ls *LocalStorage) CopyObject(bucketName, srcKey, dstKey string) error {
 	return nil
 }
 
+// CopyObjectCrossBucket copies an object into another bucket, leaving the source in place
+// Writes to a temp file first so a failed copy never leaves a truncated destination
+func (ls *LocalStorage) CopyObjectCrossBucket(srcBucket, srcKey, dstBucket, dstKey string) error {
+	srcPath, err := ls.objectPath(srcBucket, srcKey)
+	if err != nil {
+		return err
+	}
+	dstPath, err := ls.objectPath(dstBucket, dstKey)
+	if err != nil {
+		return err
+	}
+
+	srcFile, err := os.Open(srcPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("source object not found")
+		}
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer srcFile.Close()
+
+	dstDir := filepath.Dir(dstPath)
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(dstDir, ".copy-*")
+	if err != nil {
+		return writeError("failed to create destination file", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	if _, err := io.Copy(tmpFile, srcFile); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return writeError("failed to copy file", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return writeError("failed to write destination file", err)
+	}
+
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move destination file into place: %w", err)
+	}
+
+	return nil
+}
+
 // calculateMD5 calculates the MD5 hash of a file
 func calculateMD5(filePath string) (string, error) {
 	file, err := os.Open(filePath)
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// multipartDir holds the staged parts of unfinished multipart uploads, one directory per upload.
+// Like layoutDir it can't collide with a bucket, and object keys can't reach it
+const multipartDir = ".multipart"
+
+// uploadDir returns where an upload's parts are staged. Upload IDs are generated here as hex,
+// so anything else is rejected rather than joined into a path
+func (ls *LocalStorage) uploadDir(uploadID string) (string, error) {
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", fmt.Errorf("invalid upload ID")
+	}
+	return filepath.Join(ls.rootPath, multipartDir, uploadID), nil
+}
+
+// CreateMultipartUpload creates the staging directory for a new upload
+func (ls *LocalStorage) CreateMultipartUpload(bucketName, objectKey, contentType string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate upload ID: %w", err)
+	}
+	uploadID := hex.EncodeToString(id)
+
+	dir, err := ls.uploadDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", writeError("failed to create upload directory", err)
+	}
+
+	return uploadID, nil
+}
+
+// UploadPart writes a part to the upload's staging directory and returns its MD5
+func (ls *LocalStorage) UploadPart(bucketName, objectKey, uploadID string, partNumber int, data io.Reader, size int64) (string, error) {
+	dir, err := ls.uploadDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("upload not found")
+		}
+		return "", fmt.Errorf("failed to check upload: %w", err)
+	}
+
+	// Written beside the part and renamed over it, so a failed retry keeps the earlier copy
+	partPath := filepath.Join(dir, strconv.Itoa(partNumber))
+	tmpFile, err := os.CreateTemp(dir, ".part-*")
+	if err != nil {
+		return "", writeError("failed to create part file", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(tmpFile, hash), data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return "", writeError("failed to write part", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return "", writeError("failed to write part", err)
+	}
+
+	if err := os.Rename(tmpPath, partPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to move part into place: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CompleteMultipartUpload concatenates the parts into a temp file and renames it over the object,
+// so readers never see a partly assembled object
+func (ls *LocalStorage) CompleteMultipartUpload(bucketName, objectKey, uploadID string, parts []CompletedPart) error {
+	dir, err := ls.uploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+	objectPath, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return writeError("failed to create directory", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(objectPath), ".multipart-*")
+	if err != nil {
+		return writeError("failed to create file", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	for _, part := range parts {
+		if err := appendPart(tmpFile, filepath.Join(dir, strconv.Itoa(part.PartNumber))); err != nil {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return writeError("failed to write file", err)
+	}
+
+	if err := os.Rename(tmpPath, objectPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move object into place: %w", err)
+	}
+
+	// The object is in place; leftover parts only cost disk space
+	os.RemoveAll(dir)
+	return nil
+}
+
+// appendPart copies one staged part to the end of dst
+func appendPart(dst io.Writer, partPath string) error {
+	part, err := os.Open(partPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("part %s not found", filepath.Base(partPath))
+		}
+		return fmt.Errorf("failed to open part: %w", err)
+	}
+	defer part.Close()
+
+	if _, err := io.Copy(dst, part); err != nil {
+		return writeError("failed to write file", err)
+	}
+	return nil
+}
+
+// AbortMultipartUpload removes the upload's staging directory
+func (ls *LocalStorage) AbortMultipartUpload(bucketName, objectKey, uploadID string) error {
+	dir, err := ls.uploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to delete upload parts: %w", err)
+	}
+	return nil
+}
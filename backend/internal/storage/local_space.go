@@ -0,0 +1,22 @@
+//go:build linux || darwin
+
+package storage
+
+import (
+	"errors"
+	"syscall"
+)
+
+// availableBytes reports the space available to unprivileged writers on the volume holding path
+func availableBytes(path string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
+
+// isNoSpaceError reports whether a filesystem error means the volume is full or the quota is used up
+func isNoSpaceError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EDQUOT)
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package storage
+
+// availableBytes is not implemented on this platform; uploads are not checked ahead of time
+func availableBytes(path string) (uint64, bool) {
+	return 0, false
+}
+
+// isNoSpaceError is not implemented on this platform
+func isNoSpaceError(err error) bool {
+	return false
+}
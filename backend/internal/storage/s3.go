@@ -2,13 +2,17 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
+	"net/http"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"bkt/internal/validation"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
@@ -18,12 +22,13 @@ import (
 
 // S3Storage implements StorageBackend using S3-compatible storage
 type S3Storage struct {
-	client       *s3.Client
-	bucketPrefix string
+	client          *s3.Client
+	bucketPrefix    string
+	objectKeyPrefix string // Optional namespace prepended to every object key (always ends with "/")
 }
 
 // NewS3Storage creates a new S3 storage backend
-func NewS3Storage(endpoint, region, accessKeyID, secretAccessKey, bucketPrefix string, useSSL, forcePathStyle bool) (*S3Storage, error) {
+func NewS3Storage(endpoint, region, accessKeyID, secretAccessKey, bucketPrefix, objectKeyPrefix string, useSSL, forcePathStyle bool) (*S3Storage, error) {
 	// Create custom endpoint resolver for S3-compatible services
 	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
 		if endpoint != "" && endpoint != "s3.amazonaws.com" {
@@ -60,9 +65,16 @@ func NewS3Storage(endpoint, region, accessKeyID, secretAccessKey, bucketPrefix s
 		o.UsePathStyle = forcePathStyle
 	})
 
+	// Normalize the key prefix so "tenant-a", "/tenant-a/" and "tenant-a/" all map to "tenant-a/"
+	objectKeyPrefix = strings.Trim(objectKeyPrefix, "/")
+	if objectKeyPrefix != "" {
+		objectKeyPrefix += "/"
+	}
+
 	return &S3Storage{
-		client:       client,
-		bucketPrefix: bucketPrefix,
+		client:          client,
+		bucketPrefix:    bucketPrefix,
+		objectKeyPrefix: objectKeyPrefix,
 	}, nil
 }
 
@@ -74,6 +86,29 @@ func (s3s *S3Storage) getBucketName(bucketName string) string {
 	return bucketName
 }
 
+// ValidateBucketName checks that the prefixed bucket name is still a legal S3 bucket name
+func (s3s *S3Storage) ValidateBucketName(bucketName string) error {
+	effective := s3s.getBucketName(bucketName)
+	if err := validation.ValidateBucketName(effective); err != nil {
+		if s3s.bucketPrefix == "" {
+			return err
+		}
+		maxLen := 63 - len(s3s.bucketPrefix) - 1
+		return fmt.Errorf("effective S3 bucket name %q (prefix %q) is invalid: %w; with this prefix bucket names can be at most %d characters", effective, s3s.bucketPrefix, err, maxLen)
+	}
+	return nil
+}
+
+// getObjectKey adds the object key prefix if configured
+func (s3s *S3Storage) getObjectKey(objectKey string) string {
+	return s3s.objectKeyPrefix + objectKey
+}
+
+// stripObjectKey removes the object key prefix from a key returned by S3
+func (s3s *S3Storage) stripObjectKey(objectKey string) string {
+	return strings.TrimPrefix(objectKey, s3s.objectKeyPrefix)
+}
+
 // CreateBucket creates a new bucket in S3
 func (s3s *S3Storage) CreateBucket(bucketName, region string) error {
 	ctx := context.Background()
@@ -186,18 +221,40 @@ func (s3s *S3Storage) PutObject(bucketName, objectKey string, data io.Reader, si
 	// Upload object
 	_, err = s3s.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:        aws.String(actualBucketName),
-		Key:           aws.String(objectKey),
+		Key:           aws.String(s3s.getObjectKey(objectKey)),
 		Body:          data,
 		ContentLength: aws.Int64(size),
 		ContentType:   aws.String(contentType),
 	})
 	if err != nil {
+		if isS3QuotaError(err) {
+			return fmt.Errorf("failed to upload object: %w: %w", ErrInsufficientStorage, err)
+		}
 		return fmt.Errorf("failed to upload object: %w", err)
 	}
 
 	return nil
 }
 
+// s3QuotaErrorCodes are error codes S3-compatible services return when a write is rejected for
+// lack of space or quota (AWS, MinIO, Ceph RGW)
+var s3QuotaErrorCodes = map[string]bool{
+	"QuotaExceeded":                  true,
+	"InsufficientStorage":            true,
+	"XMinioStorageFull":              true,
+	"XMinioAdminBucketQuotaExceeded": true,
+}
+
+// isS3QuotaError reports whether an S3 error means the service is out of space or the quota is used up
+func isS3QuotaError(err error) bool {
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) && s3QuotaErrorCodes[apiErr.ErrorCode()] {
+		return true
+	}
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusInsufficientStorage
+}
+
 // GetObject retrieves an object from S3
 func (s3s *S3Storage) GetObject(bucketName, objectKey string) (io.ReadCloser, error) {
 	ctx := context.Background()
@@ -205,7 +262,7 @@ func (s3s *S3Storage) GetObject(bucketName, objectKey string) (io.ReadCloser, er
 
 	result, err := s3s.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(actualBucketName),
-		Key:    aws.String(objectKey),
+		Key:    aws.String(s3s.getObjectKey(objectKey)),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get object: %w", err)
@@ -214,6 +271,23 @@ func (s3s *S3Storage) GetObject(bucketName, objectKey string) (io.ReadCloser, er
 	return result.Body, nil
 }
 
+// GetObjectRange retrieves a byte range of an object from S3
+func (s3s *S3Storage) GetObjectRange(bucketName, objectKey string, offset, length int64) (io.ReadCloser, error) {
+	ctx := context.Background()
+	actualBucketName := s3s.getBucketName(bucketName)
+
+	result, err := s3s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(actualBucketName),
+		Key:    aws.String(s3s.getObjectKey(objectKey)),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object range: %w", err)
+	}
+
+	return result.Body, nil
+}
+
 // DeleteObject removes an object from S3
 func (s3s *S3Storage) DeleteObject(bucketName, objectKey string) error {
 	ctx := context.Background()
@@ -221,7 +295,7 @@ func (s3s *S3Storage) DeleteObject(bucketName, objectKey string) error {
 
 	_, err := s3s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(actualBucketName),
-		Key:    aws.String(objectKey),
+		Key:    aws.String(s3s.getObjectKey(objectKey)),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to delete object: %w", err)
@@ -250,7 +324,7 @@ func (s3s *S3Storage) ListObjects(bucketName, prefix string) ([]ObjectInfo, erro
 	const maxObjects = 10000
 	paginator := s3.NewListObjectsV2Paginator(s3s.client, &s3.ListObjectsV2Input{
 		Bucket:  aws.String(actualBucketName),
-		Prefix:  aws.String(prefix),
+		Prefix:  aws.String(s3s.getObjectKey(prefix)),
 		MaxKeys: aws.Int32(1000), // Max per page
 	})
 
@@ -265,6 +339,10 @@ func (s3s *S3Storage) ListObjects(bucketName, prefix string) ([]ObjectInfo, erro
 			if len(objects) >= maxObjects {
 				return objects, nil
 			}
+			// Saved versions share the bucket but aren't objects
+			if s3s.isVersionKey(*obj.Key) {
+				continue
+			}
 			// Infer content type from file extension (avoids N+1 HeadObject calls)
 			contentType := mime.TypeByExtension(filepath.Ext(*obj.Key))
 			if contentType == "" {
@@ -277,7 +355,7 @@ func (s3s *S3Storage) ListObjects(bucketName, prefix string) ([]ObjectInfo, erro
 			}
 
 			objects = append(objects, ObjectInfo{
-				Key:          *obj.Key,
+				Key:          s3s.stripObjectKey(*obj.Key),
 				Size:         *obj.Size,
 				ContentType:  contentType,
 				LastModified: obj.LastModified.Format(time.RFC3339),
@@ -289,6 +367,54 @@ func (s3s *S3Storage) ListObjects(bucketName, prefix string) ([]ObjectInfo, erro
 	return objects, nil
 }
 
+// WalkObjects lists every object under prefix one S3 page (up to 1000 keys) at a time,
+// with no overall cap, so arbitrarily large buckets can be processed in constant memory
+func (s3s *S3Storage) WalkObjects(bucketName, prefix string, fn func(page []ObjectInfo) error) error {
+	ctx := context.Background()
+	paginator := s3.NewListObjectsV2Paginator(s3s.client, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s3s.getBucketName(bucketName)),
+		Prefix:  aws.String(s3s.getObjectKey(prefix)),
+		MaxKeys: aws.Int32(1000),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		objects := make([]ObjectInfo, 0, len(page.Contents))
+		for _, obj := range page.Contents {
+			if s3s.isVersionKey(*obj.Key) {
+				continue
+			}
+			contentType := mime.TypeByExtension(filepath.Ext(*obj.Key))
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+
+			etag := ""
+			if obj.ETag != nil {
+				etag = strings.Trim(*obj.ETag, "\"")
+			}
+
+			objects = append(objects, ObjectInfo{
+				Key:          s3s.stripObjectKey(*obj.Key),
+				Size:         *obj.Size,
+				ContentType:  contentType,
+				LastModified: obj.LastModified.Format(time.RFC3339),
+				ETag:         etag,
+			})
+		}
+
+		if err := fn(objects); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // ObjectExists checks if an object exists in S3
 func (s3s *S3Storage) ObjectExists(bucketName, objectKey string) (bool, error) {
 	ctx := context.Background()
@@ -296,7 +422,7 @@ func (s3s *S3Storage) ObjectExists(bucketName, objectKey string) (bool, error) {
 
 	_, err := s3s.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(actualBucketName),
-		Key:    aws.String(objectKey),
+		Key:    aws.String(s3s.getObjectKey(objectKey)),
 	})
 	if err != nil {
 		// Check if error is "not found"
@@ -320,7 +446,7 @@ func (s3s *S3Storage) GetObjectInfo(bucketName, objectKey string) (*ObjectInfo,
 
 	result, err := s3s.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(actualBucketName),
-		Key:    aws.String(objectKey),
+		Key:    aws.String(s3s.getObjectKey(objectKey)),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get object info: %w", err)
@@ -357,20 +483,171 @@ func (s3s *S3Storage) GetObjectInfo(bucketName, objectKey string) (*ObjectInfo,
 
 // CopyObject copies an object within the same bucket using S3 CopyObject API
 func (s3s *S3Storage) CopyObject(bucketName, srcKey, dstKey string) error {
-	ctx := context.Background()
 	actualBucketName := s3s.getBucketName(bucketName)
 
-	// CopySource format: bucket/key
-	copySource := fmt.Sprintf("%s/%s", actualBucketName, srcKey)
+	if err := s3s.copyS3Object(context.Background(), actualBucketName, s3s.getObjectKey(srcKey), actualBucketName, s3s.getObjectKey(dstKey)); err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
 
-	_, err := s3s.client.CopyObject(ctx, &s3.CopyObjectInput{
-		Bucket:     aws.String(actualBucketName),
-		Key:        aws.String(dstKey),
-		CopySource: aws.String(copySource),
-	})
+	return nil
+}
+
+// CopyObjectCrossBucket copies an object into another bucket using the S3 CopyObject API
+// Both buckets must be reachable with this client's credentials
+func (s3s *S3Storage) CopyObjectCrossBucket(srcBucket, srcKey, dstBucket, dstKey string) error {
+	err := s3s.copyS3Object(context.Background(),
+		s3s.getBucketName(srcBucket), s3s.getObjectKey(srcKey),
+		s3s.getBucketName(dstBucket), s3s.getObjectKey(dstKey))
 	if err != nil {
 		return fmt.Errorf("failed to copy object: %w", err)
 	}
 
 	return nil
 }
+
+// versionKey returns the key a saved version is stored under, in the reserved
+// validation.VersionsKeyPrefix that no object key can start with
+func (s3s *S3Storage) versionKey(versionID string) string {
+	return s3s.getObjectKey(validation.VersionsKeyPrefix + versionID)
+}
+
+// isVersionKey reports whether a key returned by S3 holds a saved version rather than an object
+func (s3s *S3Storage) isVersionKey(key string) bool {
+	return strings.HasPrefix(s3s.stripObjectKey(key), validation.VersionsKeyPrefix)
+}
+
+// SaveObjectVersion copies the object to its version key with the S3 CopyObject API
+func (s3s *S3Storage) SaveObjectVersion(bucketName, objectKey, versionID string) error {
+	actualBucketName := s3s.getBucketName(bucketName)
+
+	if err := s3s.copyS3Object(context.Background(), actualBucketName, s3s.getObjectKey(objectKey), actualBucketName, s3s.versionKey(versionID)); err != nil {
+		return fmt.Errorf("failed to save object version: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreObjectVersion copies a saved version back to the object's key. The saved version is
+// left in place
+func (s3s *S3Storage) RestoreObjectVersion(bucketName, objectKey, versionID string) error {
+	actualBucketName := s3s.getBucketName(bucketName)
+
+	if err := s3s.copyS3Object(context.Background(), actualBucketName, s3s.versionKey(versionID), actualBucketName, s3s.getObjectKey(objectKey)); err != nil {
+		return fmt.Errorf("failed to restore object version: %w", err)
+	}
+
+	return nil
+}
+
+// GetObjectVersion retrieves a saved version from S3
+func (s3s *S3Storage) GetObjectVersion(bucketName, versionID string) (io.ReadCloser, error) {
+	ctx := context.Background()
+
+	result, err := s3s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s3s.getBucketName(bucketName)),
+		Key:    aws.String(s3s.versionKey(versionID)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object version: %w", err)
+	}
+
+	return result.Body, nil
+}
+
+// DeleteObjectVersion removes a saved version from S3
+func (s3s *S3Storage) DeleteObjectVersion(bucketName, versionID string) error {
+	ctx := context.Background()
+
+	_, err := s3s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s3s.getBucketName(bucketName)),
+		Key:    aws.String(s3s.versionKey(versionID)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object version: %w", err)
+	}
+
+	return nil
+}
+
+// CreateMultipartUpload starts a native S3 multipart upload
+func (s3s *S3Storage) CreateMultipartUpload(bucketName, objectKey, contentType string) (string, error) {
+	ctx := context.Background()
+
+	result, err := s3s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s3s.getBucketName(bucketName)),
+		Key:         aws.String(s3s.getObjectKey(objectKey)),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	return aws.ToString(result.UploadId), nil
+}
+
+// UploadPart forwards a part to the native S3 multipart upload
+func (s3s *S3Storage) UploadPart(bucketName, objectKey, uploadID string, partNumber int, data io.Reader, size int64) (string, error) {
+	ctx := context.Background()
+
+	result, err := s3s.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(s3s.getBucketName(bucketName)),
+		Key:           aws.String(s3s.getObjectKey(objectKey)),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(int32(partNumber)),
+		Body:          data,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		if isS3QuotaError(err) {
+			return "", fmt.Errorf("failed to upload part: %w: %w", ErrInsufficientStorage, err)
+		}
+		return "", fmt.Errorf("failed to upload part: %w", err)
+	}
+
+	return strings.Trim(aws.ToString(result.ETag), "\""), nil
+}
+
+// CompleteMultipartUpload has S3 assemble the parts
+func (s3s *S3Storage) CompleteMultipartUpload(bucketName, objectKey, uploadID string, parts []CompletedPart) error {
+	ctx := context.Background()
+
+	completed := make([]types.CompletedPart, len(parts))
+	for i, part := range parts {
+		completed[i] = types.CompletedPart{
+			PartNumber: aws.Int32(int32(part.PartNumber)),
+			ETag:       aws.String(fmt.Sprintf(`"%s"`, part.ETag)),
+		}
+	}
+
+	_, err := s3s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s3s.getBucketName(bucketName)),
+		Key:             aws.String(s3s.getObjectKey(objectKey)),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// AbortMultipartUpload aborts the native S3 upload, which deletes its stored parts
+func (s3s *S3Storage) AbortMultipartUpload(bucketName, objectKey, uploadID string) error {
+	ctx := context.Background()
+
+	_, err := s3s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s3s.getBucketName(bucketName)),
+		Key:      aws.String(s3s.getObjectKey(objectKey)),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		var noSuchUpload *types.NoSuchUpload
+		if errors.As(err, &noSuchUpload) {
+			return nil // Already gone
+		}
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// maxSingleCopySize is the largest object the S3 CopyObject API copies in one request
+	maxSingleCopySize = 5 * 1024 * 1024 * 1024
+
+	// copyPartSize is the part size for multipart copies of larger objects (well under the
+	// 10,000 part limit for S3's 5 TB maximum object size)
+	copyPartSize = 512 * 1024 * 1024
+)
+
+// copySource formats an x-amz-copy-source value: the bucket and key, URL-encoded segment by
+// segment so keys with spaces, '+', '?', '%' or non-ASCII characters copy correctly
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return url.PathEscape(bucket) + "/" + strings.Join(segments, "/")
+}
+
+// copyS3Object copies an object server-side between actual (prefixed) bucket names and keys.
+// Objects over 5 GB can't be copied with a single CopyObject request, so they are copied part
+// by part with UploadPartCopy
+func (s3s *S3Storage) copyS3Object(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error {
+	head, err := s3s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(srcBucket),
+		Key:    aws.String(srcKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stat copy source: %w", err)
+	}
+	source := copySource(srcBucket, srcKey)
+	size := aws.ToInt64(head.ContentLength)
+
+	if size <= maxSingleCopySize {
+		_, err := s3s.client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(dstBucket),
+			Key:        aws.String(dstKey),
+			CopySource: aws.String(source),
+		})
+		return err
+	}
+
+	upload, err := s3s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(dstBucket),
+		Key:         aws.String(dstKey),
+		ContentType: head.ContentType,
+		Metadata:    head.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	var parts []types.CompletedPart
+	for offset, partNumber := int64(0), int32(1); offset < size; offset, partNumber = offset+copyPartSize, partNumber+1 {
+		end := offset + copyPartSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		result, err := s3s.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          aws.String(dstBucket),
+			Key:             aws.String(dstKey),
+			UploadId:        upload.UploadId,
+			PartNumber:      aws.Int32(partNumber),
+			CopySource:      aws.String(source),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+		})
+		if err != nil {
+			s3s.abortCopy(ctx, dstBucket, dstKey, upload.UploadId)
+			return err
+		}
+		parts = append(parts, types.CompletedPart{
+			ETag:       result.CopyPartResult.ETag,
+			PartNumber: aws.Int32(partNumber),
+		})
+	}
+
+	_, err = s3s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(dstBucket),
+		Key:             aws.String(dstKey),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		s3s.abortCopy(ctx, dstBucket, dstKey, upload.UploadId)
+		return err
+	}
+	return nil
+}
+
+// abortCopy discards the parts of a failed multipart copy (best effort)
+func (s3s *S3Storage) abortCopy(ctx context.Context, bucket, key string, uploadID *string) {
+	s3s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"io"
 )
 
@@ -15,12 +16,15 @@ type StorageBackend interface {
 	// BucketExists checks if a bucket exists and is accessible in the storage backend
 	BucketExists(bucketName string) (bool, error)
 
-	// PutObject stores an object in the given bucket
+	// PutObject stores an object in the given bucket. A failed write leaves any existing object unchanged
 	PutObject(bucketName, objectKey string, data io.Reader, size int64, contentType string) error
 
 	// GetObject retrieves an object from the given bucket
 	GetObject(bucketName, objectKey string) (io.ReadCloser, error)
 
+	// GetObjectRange retrieves length bytes of an object starting at offset, without reading the rest
+	GetObjectRange(bucketName, objectKey string, offset, length int64) (io.ReadCloser, error)
+
 	// DeleteObject removes an object from the given bucket
 	DeleteObject(bucketName, objectKey string) error
 
@@ -35,6 +39,128 @@ type StorageBackend interface {
 
 	// CopyObject copies an object within the same bucket
 	CopyObject(bucketName, srcKey, dstKey string) error
+
+	// CopyObjectCrossBucket copies an object into another bucket without streaming the data through
+	// the application. Only valid when both buckets share this backend instance
+	CopyObjectCrossBucket(srcBucket, srcKey, dstBucket, dstKey string) error
+}
+
+// BucketNameValidator is implemented by backends that transform bucket names (e.g. S3 bucket prefixes)
+// and can check the effective name before the bucket is created
+type BucketNameValidator interface {
+	ValidateBucketName(bucketName string) error
+}
+
+// ObjectWalker is implemented by backends that can list a bucket page by page without the
+// size cap ListObjects applies
+type ObjectWalker interface {
+	WalkObjects(bucketName, prefix string, fn func(page []ObjectInfo) error) error
+}
+
+// WalkObjects calls fn with successive pages of every object under prefix. Backends without
+// paged listing deliver their whole ListObjects result as one page. A non-nil error from fn stops the walk
+func WalkObjects(backend StorageBackend, bucketName, prefix string, fn func(page []ObjectInfo) error) error {
+	if walker, ok := backend.(ObjectWalker); ok {
+		return walker.WalkObjects(bucketName, prefix, fn)
+	}
+
+	objects, err := backend.ListObjects(bucketName, prefix)
+	if err != nil {
+		return err
+	}
+	return fn(objects)
+}
+
+// ErrAppendNotSupported is returned by AppendObject for backends without native appends (e.g. S3)
+var ErrAppendNotSupported = errors.New("append not supported on this backend")
+
+// Appender is implemented by backends that can add bytes to the end of an existing object in place
+type Appender interface {
+	AppendObject(bucketName, objectKey string, data io.Reader, size int64) error
+}
+
+// SupportsAppend reports whether the backend can append to objects
+func SupportsAppend(backend StorageBackend) bool {
+	_, ok := backend.(Appender)
+	return ok
+}
+
+// AppendObject writes size bytes from data to the end of an existing object. Backends without
+// native appends return ErrAppendNotSupported rather than rewriting the whole object
+func AppendObject(backend StorageBackend, bucketName, objectKey string, data io.Reader, size int64) error {
+	appender, ok := backend.(Appender)
+	if !ok {
+		return ErrAppendNotSupported
+	}
+	return appender.AppendObject(bucketName, objectKey, data, size)
+}
+
+// ErrMultipartNotSupported is returned for backends that can't assemble objects from parts
+var ErrMultipartNotSupported = errors.New("multipart uploads not supported on this backend")
+
+// CompletedPart identifies a stored part when a multipart upload is completed
+type CompletedPart struct {
+	PartNumber int
+	ETag       string // As returned by UploadPart
+}
+
+// MultipartUploader is implemented by backends that can build an object from separately uploaded
+// parts. Parts are staged by the backend and never visible as objects until the upload completes
+type MultipartUploader interface {
+	// CreateMultipartUpload starts an upload and returns the backend's ID for it
+	CreateMultipartUpload(bucketName, objectKey, contentType string) (string, error)
+
+	// UploadPart stores one part (replacing an earlier upload of the same number) and returns its ETag
+	UploadPart(bucketName, objectKey, uploadID string, partNumber int, data io.Reader, size int64) (string, error)
+
+	// CompleteMultipartUpload writes the parts, in the given order, as the object and discards the upload
+	CompleteMultipartUpload(bucketName, objectKey, uploadID string, parts []CompletedPart) error
+
+	// AbortMultipartUpload discards an upload and every part stored for it
+	AbortMultipartUpload(bucketName, objectKey, uploadID string) error
+}
+
+// Multipart returns the backend's multipart support, or ErrMultipartNotSupported
+func Multipart(backend StorageBackend) (MultipartUploader, error) {
+	uploader, ok := backend.(MultipartUploader)
+	if !ok {
+		return nil, ErrMultipartNotSupported
+	}
+	return uploader, nil
+}
+
+// ErrVersioningNotSupported is returned for backends that can't keep previous object versions
+var ErrVersioningNotSupported = errors.New("object versioning not supported on this backend")
+
+// ObjectVersioner is implemented by backends that can keep copies of previous object versions.
+// Versions are stored apart from the bucket's objects and never listed as objects
+type ObjectVersioner interface {
+	// SaveObjectVersion copies the object's current content aside as version versionID (hex)
+	SaveObjectVersion(bucketName, objectKey, versionID string) error
+
+	// RestoreObjectVersion copies a saved version back as the object's current content
+	RestoreObjectVersion(bucketName, objectKey, versionID string) error
+
+	// GetObjectVersion retrieves the content of a saved version
+	GetObjectVersion(bucketName, versionID string) (io.ReadCloser, error)
+
+	// DeleteObjectVersion removes a saved version (a missing version is not an error)
+	DeleteObjectVersion(bucketName, versionID string) error
+}
+
+// Versioner returns the backend's version storage, or ErrVersioningNotSupported
+func Versioner(backend StorageBackend) (ObjectVersioner, error) {
+	versioner, ok := backend.(ObjectVersioner)
+	if !ok {
+		return nil, ErrVersioningNotSupported
+	}
+	return versioner, nil
+}
+
+// limitedReadCloser pairs a limited reader with the underlying object's Close
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
 }
 
 // ObjectInfo contains metadata about a stored object
@@ -47,13 +173,32 @@ type ObjectInfo struct {
 }
 
 // NewStorageBackend creates a new storage backend based on configuration
-func NewStorageBackend(backend string, rootPath string, s3Endpoint, s3Region, s3AccessKey, s3SecretKey, s3BucketPrefix string, s3UseSSL, s3ForcePathStyle bool) (StorageBackend, error) {
+func NewStorageBackend(backend string, rootPath, localLayout string, s3Endpoint, s3Region, s3AccessKey, s3SecretKey, s3BucketPrefix, s3ObjectKeyPrefix string, s3UseSSL, s3ForcePathStyle bool) (StorageBackend, error) {
 	switch backend {
 	case "s3":
-		return NewS3Storage(s3Endpoint, s3Region, s3AccessKey, s3SecretKey, s3BucketPrefix, s3UseSSL, s3ForcePathStyle)
+		return NewS3Storage(s3Endpoint, s3Region, s3AccessKey, s3SecretKey, s3BucketPrefix, s3ObjectKeyPrefix, s3UseSSL, s3ForcePathStyle)
 	case "local":
 		fallthrough
 	default:
-		return NewLocalStorage(rootPath), nil
+		return NewLocalStorage(rootPath, localLayout), nil
+	}
+}
+
+// ErrInsufficientStorage is wrapped into write errors caused by the backend running out of space
+// or exceeding a quota, so callers can tell them apart from other storage failures
+var ErrInsufficientStorage = errors.New("insufficient storage")
+
+// SpaceChecker is implemented by backends that can tell ahead of a write whether it will fit
+type SpaceChecker interface {
+	CheckSpace(bucketName string, size int64) error
+}
+
+// CheckSpace returns an error wrapping ErrInsufficientStorage if the backend knows it can't hold size
+// more bytes. Backends that can't report free space (e.g. S3) always pass
+func CheckSpace(backend StorageBackend, bucketName string, size int64) error {
+	checker, ok := backend.(SpaceChecker)
+	if !ok || size <= 0 {
+		return nil
 	}
+	return checker.CheckSpace(bucketName, size)
 }
@@ -0,0 +1,156 @@
+package validation
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrDecompressionLimit is returned when compressed content expands beyond the allowed bound
+var ErrDecompressionLimit = errors.New("decompressed size exceeds allowed limit (possible decompression bomb)")
+
+// IsGzipContentType reports whether a detected content type is gzip-compressed
+func IsGzipContentType(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return contentType == "application/x-gzip" || contentType == "application/gzip"
+}
+
+// DecompressionLimit returns the maximum number of bytes content of the given compressed
+// size may expand to: the smaller of compressedSize*maxRatio and maxSize (0 disables a bound).
+// Returns -1 when neither bound applies
+func DecompressionLimit(compressedSize, maxRatio, maxSize int64) int64 {
+	limit := int64(-1)
+	if maxRatio > 0 && compressedSize > 0 {
+		if compressedSize > (1<<63-1)/maxRatio {
+			limit = 1<<63 - 1 // Overflow; the absolute cap (if any) decides
+		} else {
+			limit = compressedSize * maxRatio
+		}
+	}
+	if maxSize > 0 && (limit < 0 || maxSize < limit) {
+		limit = maxSize
+	}
+	return limit
+}
+
+// limitedDecompressionReader fails with ErrDecompressionLimit once more than limit bytes are produced
+type limitedDecompressionReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+// NewDecompressionLimitReader bounds the output of a decompressing reader (gzip, zip entry, ...)
+// Unlike io.LimitReader it errors instead of silently truncating, so archive extraction aborts
+func NewDecompressionLimitReader(r io.Reader, limit int64) io.Reader {
+	if limit < 0 {
+		return r
+	}
+	return &limitedDecompressionReader{r: r, remaining: limit}
+}
+
+func (l *limitedDecompressionReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Probe for one more byte to distinguish "exactly at limit" from "over limit"
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, ErrDecompressionLimit
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// gzipGuardReader passes compressed bytes through unchanged while inflating a copy
+// in the background, so the expansion ratio is enforced without buffering the upload
+type gzipGuardReader struct {
+	src      io.Reader
+	pw       *io.PipeWriter
+	verdict  chan error
+	finished bool
+	err      error
+}
+
+// NewGzipGuardReader wraps a gzip upload stream. Reads fail with ErrDecompressionLimit as soon as
+// the inflated data exceeds limit, aborting the write to storage. Content that isn't valid gzip
+// is passed through untouched (it can't expand on read either). Close must be called to release
+// the background inflater if the stream isn't read to EOF
+func NewGzipGuardReader(src io.Reader, limit int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+	g := &gzipGuardReader{
+		src:     src,
+		pw:      pw,
+		verdict: make(chan error, 1),
+	}
+
+	go func() {
+		err := CheckGzipExpansion(pr, limit)
+		g.verdict <- err
+		// Keep consuming so writes never block once the verdict is in
+		io.Copy(io.Discard, pr)
+	}()
+
+	return g
+}
+
+// CheckGzipExpansion inflates a gzip stream, returning ErrDecompressionLimit if it expands past limit
+// Used for content that is already fully available (e.g. assembled async/resumable uploads)
+func CheckGzipExpansion(r io.Reader, limit int64) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil // Not a parseable gzip stream
+	}
+	defer gz.Close()
+
+	if _, err := io.Copy(io.Discard, NewDecompressionLimitReader(gz, limit)); errors.Is(err, ErrDecompressionLimit) {
+		return err
+	}
+	return nil // Corrupt streams are not a decompression risk
+}
+
+// result returns the inflater's verdict, blocking only when wait is set
+func (g *gzipGuardReader) result(wait bool) error {
+	if g.finished {
+		return g.err
+	}
+	if wait {
+		g.err = <-g.verdict
+		g.finished = true
+		return g.err
+	}
+	select {
+	case g.err = <-g.verdict:
+		g.finished = true
+	default:
+	}
+	return g.err
+}
+
+func (g *gzipGuardReader) Read(p []byte) (int, error) {
+	if err := g.result(false); err != nil {
+		return 0, err
+	}
+
+	n, err := g.src.Read(p)
+	if n > 0 {
+		g.pw.Write(p[:n])
+	}
+	if err == io.EOF {
+		g.pw.Close()
+		if verr := g.result(true); verr != nil {
+			return 0, verr
+		}
+	}
+	return n, err
+}
+
+// Close releases the background inflater
+func (g *gzipGuardReader) Close() error {
+	return g.pw.Close()
+}
@@ -0,0 +1,75 @@
+package validation
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+)
+
+// HashingReader wraps an io.ReadSeeker and computes the SHA256 and MD5 of the content as it is
+// read, so a stream consumed once (e.g. by a storage write) doesn't have to be re-read to hash it.
+// Seeking is supported for AWS SDK retries: seeking back to the start resets the hashers, and
+// bytes re-read after a seek elsewhere are only hashed once
+type HashingReader struct {
+	reader io.ReadSeeker
+	sha256 hash.Hash
+	md5    hash.Hash
+	hashes io.Writer
+	pos    int64 // Current read position
+	hashed int64 // Length of the prefix of the content fed to the hashers
+}
+
+// NewHashingReader creates a HashingReader over a reader positioned at the start of the content
+func NewHashingReader(reader io.ReadSeeker) *HashingReader {
+	hr := &HashingReader{reader: reader}
+	hr.reset()
+	return hr
+}
+
+func (hr *HashingReader) reset() {
+	hr.sha256 = sha256.New()
+	hr.md5 = md5.New()
+	hr.hashes = io.MultiWriter(hr.sha256, hr.md5)
+	hr.hashed = 0
+}
+
+// Read implements io.Reader, feeding bytes not yet hashed to the hashers
+func (hr *HashingReader) Read(p []byte) (int, error) {
+	n, err := hr.reader.Read(p)
+	if n > 0 {
+		end := hr.pos + int64(n)
+		// Only extend a contiguous prefix; bytes past a forward seek leave a gap that can't be hashed
+		if hr.pos <= hr.hashed && end > hr.hashed {
+			hr.hashes.Write(p[hr.hashed-hr.pos : n])
+			hr.hashed = end
+		}
+		hr.pos = end
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker to support AWS SDK retries
+func (hr *HashingReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := hr.reader.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	hr.pos = pos
+	if pos == 0 {
+		// A retry from the start re-sends everything, so hash it afresh
+		hr.reset()
+	}
+	return pos, nil
+}
+
+// Sums returns the hex-encoded SHA256 and MD5 of the content. It fails unless exactly size bytes
+// were hashed, i.e. the whole content was read through the reader
+func (hr *HashingReader) Sums(size int64) (string, string, error) {
+	if hr.hashed != size {
+		return "", "", errors.New("content was not read in full through the hashing reader")
+	}
+	return hex.EncodeToString(hr.sha256.Sum(nil)), hex.EncodeToString(hr.md5.Sum(nil)), nil
+}
@@ -1,15 +1,19 @@
 package validation
 
 import (
+	"bytes"
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"mime"
 	"net"
 	"net/http"
+	"path"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 // S3 bucket naming rules: https://docs.aws.amazon.com/AmazonS3/latest/userguide/bucketnamingrules.html
@@ -17,8 +21,28 @@ var (
 	bucketNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9\-]*[a-z0-9]$`)
 	ipAddressRegex  = regexp.MustCompile(`^[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}$`)
 	regionRegex     = regexp.MustCompile(`^[a-z]{2}-[a-z]+-[0-9]{1,2}$`)
+
+	// Object tag keys and values: letters, digits, spaces and _ . : / = + - @ (as on S3)
+	objectTagRegex = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+	// User metadata names: HTTP header name characters, lowercase (they travel as x-amz-meta-<name>)
+	objectMetadataKeyRegex = regexp.MustCompile(`^[a-z0-9!#$%&'*+.^_|~-]+$`)
+)
+
+// Object tag limits: https://docs.aws.amazon.com/AmazonS3/latest/userguide/object-tagging.html
+const (
+	MaxObjectTags           = 10
+	MaxObjectTagKeyLength   = 128 // Unicode characters
+	MaxObjectTagValueLength = 256 // Unicode characters
 )
 
+// MaxObjectMetadataSize caps an object's user metadata: the names and values of its x-amz-meta-*
+// headers, in bytes (as on S3)
+const MaxObjectMetadataSize = 2048
+
+// ErrObjectMetadataTooLarge is returned by ValidateObjectMetadata for metadata over MaxObjectMetadataSize
+var ErrObjectMetadataTooLarge = fmt.Errorf("user metadata cannot exceed %d bytes", MaxObjectMetadataSize)
+
 // ValidateBucketName validates bucket name according to S3 naming rules
 func ValidateBucketName(name string) error {
 	// Length check (3-63 characters)
@@ -55,6 +79,10 @@ func ValidateBucketName(name string) error {
 	return nil
 }
 
+// VersionsKeyPrefix is the key prefix under which S3 storage backends keep previous object
+// versions. No object key may start with it
+const VersionsKeyPrefix = ".bkt-versions/"
+
 // ValidateObjectKey validates object key to prevent path traversal and other attacks
 func ValidateObjectKey(key string) error {
 	// Check for empty key
@@ -87,6 +115,69 @@ func ValidateObjectKey(key string) error {
 		return fmt.Errorf("object key cannot contain backslashes")
 	}
 
+	// Reserved for previous object versions on S3 storage backends
+	if strings.HasPrefix(key, VersionsKeyPrefix) {
+		return fmt.Errorf("object key cannot start with the reserved prefix %q", VersionsKeyPrefix)
+	}
+
+	return nil
+}
+
+// ValidateObjectTags checks an object's tag set against the S3 limits: at most 10 tags, keys of
+// 1-128 and values of up to 256 characters from the allowed set, and no reserved "aws:" keys
+func ValidateObjectTags(tags map[string]string) error {
+	if len(tags) > MaxObjectTags {
+		return fmt.Errorf("an object can have at most %d tags", MaxObjectTags)
+	}
+
+	for key, value := range tags {
+		if key == "" || utf8.RuneCountInString(key) > MaxObjectTagKeyLength {
+			return fmt.Errorf("tag keys must be between 1 and %d characters", MaxObjectTagKeyLength)
+		}
+		if utf8.RuneCountInString(value) > MaxObjectTagValueLength {
+			return fmt.Errorf("tag value for %q cannot exceed %d characters", key, MaxObjectTagValueLength)
+		}
+		if !utf8.ValidString(key) || !objectTagRegex.MatchString(key) {
+			return fmt.Errorf("tag key %q contains characters that are not allowed", key)
+		}
+		if !utf8.ValidString(value) || !objectTagRegex.MatchString(value) {
+			return fmt.Errorf("tag value for %q contains characters that are not allowed", key)
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return fmt.Errorf("tag key %q uses the reserved aws: prefix", key)
+		}
+	}
+
+	return nil
+}
+
+// ValidateObjectMetadata checks an object's user metadata: valid entries (see
+// ValidateObjectMetadataEntry) and at most 2KB of names and values in total
+func ValidateObjectMetadata(metadata map[string]string) error {
+	size := 0
+	for key, value := range metadata {
+		if err := ValidateObjectMetadataEntry(key, value); err != nil {
+			return err
+		}
+		size += len(key) + len(value)
+	}
+	if size > MaxObjectMetadataSize {
+		return ErrObjectMetadataTooLarge
+	}
+	return nil
+}
+
+// ValidateObjectMetadataEntry checks one user metadata entry: a lowercase header-safe name and a
+// value without control characters, so both can be sent back as an x-amz-meta-* header
+func ValidateObjectMetadataEntry(key, value string) error {
+	if !objectMetadataKeyRegex.MatchString(key) {
+		return fmt.Errorf("metadata name %q contains characters that are not allowed", key)
+	}
+	for _, r := range value {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("metadata value for %q contains control characters", key)
+		}
+	}
 	return nil
 }
 
@@ -129,6 +220,62 @@ func DetectContentType(reader io.Reader) (contentType string, firstBytes []byte,
 	return contentType, firstBytes, nil
 }
 
+// genericContentTypes are the catch-all results of magic-number detection, which carry no
+// format information and may be refined by a file's extension
+var genericContentTypes = []string{"text/plain", "application/octet-stream"}
+
+// RefineContentType upgrades a generic detected type (text/plain or application/octet-stream)
+// using the key's extension, e.g. .json to application/json. Only text formats are refined, so
+// the content must look like text. Any other detection is returned unchanged, so a specific
+// (possibly dangerous) type is never replaced by a friendlier one
+func RefineContentType(detected string, firstBytes []byte, key string, extensionTypes map[string]string) string {
+	if len(extensionTypes) == 0 {
+		return detected
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(detected, ";")[0]))
+	generic := false
+	for _, t := range genericContentTypes {
+		if mediaType == t {
+			generic = true
+		}
+	}
+	if !generic || !LooksLikeText(firstBytes) {
+		return detected
+	}
+
+	refined, ok := extensionTypes[strings.ToLower(path.Ext(key))]
+	if !ok {
+		return detected
+	}
+	if strings.HasPrefix(refined, "text/") {
+		// Keep the charset detection found (text/plain; charset=utf-8)
+		if _, params, err := mime.ParseMediaType(detected); err == nil && params["charset"] != "" {
+			return mime.FormatMediaType(refined, map[string]string{"charset": params["charset"]})
+		}
+	}
+	return refined
+}
+
+// ValidateExtensionContentTypes checks the extension refinement table. Like trusted types, the
+// targets can't be types a browser could execute or that are blocked for upload
+func ValidateExtensionContentTypes(extensionTypes map[string]string) error {
+	for ext, t := range extensionTypes {
+		if !strings.HasPrefix(ext, ".") || len(ext) < 2 || strings.Contains(ext[1:], ".") {
+			return fmt.Errorf("extension %q must look like .json", ext)
+		}
+		if strings.Contains(t, ";") {
+			return fmt.Errorf("content type %q for %s must not contain parameters", t, ext)
+		}
+		if err := ValidateContentTypeOverride(t); err != nil {
+			return err
+		}
+		if IsActiveContentType(t) {
+			return fmt.Errorf("content type %s for %s can run script in a browser and can't be assigned by extension", t, ext)
+		}
+	}
+	return nil
+}
+
 // IsSafeContentType checks if a content type is considered safe for upload.
 // This function can be extended to block dangerous file types.
 func IsSafeContentType(contentType string) bool {
@@ -155,6 +302,172 @@ func IsSafeContentType(contentType string) bool {
 	return true
 }
 
+// textContentTypes are non text/* types that are human-readable
+var textContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/javascript",
+	"application/x-javascript",
+	"application/x-ndjson",
+	"application/x-yaml",
+	"application/yaml",
+	"application/toml",
+	"application/x-sh",
+	"application/sql",
+	"image/svg+xml",
+}
+
+// IsTextContentType reports whether a content type is text-like and safe to preview as text
+func IsTextContentType(contentType string) bool {
+	normalized := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if strings.HasPrefix(normalized, "text/") || strings.HasSuffix(normalized, "+json") || strings.HasSuffix(normalized, "+xml") {
+		return true
+	}
+	for _, t := range textContentTypes {
+		if normalized == t {
+			return true
+		}
+	}
+	return false
+}
+
+// activeContentTypes can execute script when rendered by a browser
+var activeContentTypes = []string{
+	"text/html",
+	"application/xhtml+xml",
+	"image/svg+xml",
+	"text/xml",
+	"application/xml",
+	"text/javascript",
+	"application/javascript",
+	"application/x-javascript",
+	"application/ecmascript",
+	"text/ecmascript",
+	"application/x-shockwave-flash",
+}
+
+// IsActiveContentType reports whether content of this type can run script when rendered inline
+// (HTML, SVG, XML, JavaScript, ...) and so must not be served inline from the API origin
+func IsActiveContentType(contentType string) bool {
+	normalized := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if strings.HasSuffix(normalized, "+xml") {
+		return true
+	}
+	for _, t := range activeContentTypes {
+		if normalized == t {
+			return true
+		}
+	}
+	return false
+}
+
+// LooksLikeText reports whether data is valid UTF-8 without NUL bytes
+// A multi-byte rune cut off at the end of the data is tolerated (truncated reads)
+func LooksLikeText(data []byte) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+	return utf8.Valid(TrimPartialRune(data))
+}
+
+// TrimPartialRune drops an incomplete UTF-8 sequence at the end of data
+func TrimPartialRune(data []byte) []byte {
+	// A rune is at most utf8.UTFMax bytes, so only the tail needs checking
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		b := data[len(data)-i]
+		if b < utf8.RuneSelf {
+			return data // ASCII: nothing partial after it
+		}
+		if utf8.RuneStart(b) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			return data
+		}
+	}
+	return data
+}
+
+// ValidateContentDisposition validates a response-content-disposition override
+// Rejects control characters (header injection) and anything but inline/attachment dispositions
+func ValidateContentDisposition(value string) error {
+	if len(value) > 1024 {
+		return fmt.Errorf("content disposition cannot exceed 1024 characters")
+	}
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return fmt.Errorf("content disposition cannot contain control characters")
+	}
+
+	disposition, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return fmt.Errorf("invalid content disposition: %w", err)
+	}
+	if disposition != "inline" && disposition != "attachment" {
+		return fmt.Errorf("content disposition must be inline or attachment")
+	}
+
+	return nil
+}
+
+// ValidateContentTypeOverride validates a response-content-type override
+func ValidateContentTypeOverride(value string) error {
+	if len(value) > 255 {
+		return fmt.Errorf("content type cannot exceed 255 characters")
+	}
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return fmt.Errorf("content type cannot contain control characters")
+	}
+	if _, _, err := mime.ParseMediaType(value); err != nil {
+		return fmt.Errorf("invalid content type: %w", err)
+	}
+	if !IsSafeContentType(value) {
+		return fmt.Errorf("content type %s is not allowed", value)
+	}
+
+	return nil
+}
+
+// ValidateTrustedContentTypes checks the configured content-type allowlist. Types that a browser
+// could execute or that are blocked for upload can never be trusted
+func ValidateTrustedContentTypes(types []string) error {
+	for _, t := range types {
+		if strings.Contains(t, ";") {
+			return fmt.Errorf("trusted content type %q must not contain parameters", t)
+		}
+		if err := ValidateContentTypeOverride(t); err != nil {
+			return err
+		}
+		if IsActiveContentType(t) {
+			return fmt.Errorf("content type %s can run script in a browser and cannot be trusted", t)
+		}
+	}
+	return nil
+}
+
+// ResolveContentType returns the content type to store for an upload: the client's declared type
+// when its media type is on the trusted list, otherwise the detected type. The detected type
+// always wins when it is itself active or blocked, and an active or blocked declared type is
+// never honored, so the allowlist can't be used to smuggle HTML or executables
+func ResolveContentType(detected, declared string, trusted []string) string {
+	if declared == "" || len(trusted) == 0 {
+		return detected
+	}
+	if IsActiveContentType(detected) || !IsSafeContentType(detected) {
+		return detected
+	}
+	if ValidateContentTypeOverride(declared) != nil || IsActiveContentType(declared) {
+		return detected
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(declared) // Lowercased, parameters removed
+	for _, t := range trusted {
+		if mediaType == t {
+			return declared
+		}
+	}
+	return detected
+}
+
 // ValidateRegion validates AWS/S3 region format
 // Accepts standard AWS region format (e.g., "us-east-1", "eu-west-2")
 // or allows empty string for default region
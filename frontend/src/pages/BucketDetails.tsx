@@ -36,6 +36,8 @@ interface ActiveUpload {
 export default function BucketDetails() {
   const { bucketName } = useParams<{ bucketName: string }>()
   const [objects, setObjects] = useState<StorageObject[]>([])
+  const [nextObjectsCursor, setNextObjectsCursor] = useState<string | null>(null) // Set while more pages exist
+  const [loadingMoreObjects, setLoadingMoreObjects] = useState(false)
   const [currentPrefix, setCurrentPrefix] = useState('')
   const [loading, setLoading] = useState(true)
   const [uploading, setUploading] = useState(false)
@@ -104,9 +106,8 @@ export default function BucketDetails() {
     try {
       setError('')
       const data = await bucketApi.listObjects(bucketName)
-      // Handle both array response and object response with objects property
-      const objectList = Array.isArray(data) ? data : (data as any).objects || []
-      setObjects(objectList)
+      setObjects(data.objects || [])
+      setNextObjectsCursor(data.has_more ? data.last_key : null)
     } catch (error: any) {
       console.error('Failed to load objects:', error)
       setError(error.response?.data?.message || 'Failed to load objects')
@@ -115,13 +116,31 @@ export default function BucketDetails() {
     }
   }
 
+  // Append the next page of a large bucket (keyset pagination on the last key seen)
+  const loadMoreObjects = async () => {
+    if (!bucketName || !nextObjectsCursor) return
+
+    setLoadingMoreObjects(true)
+    try {
+      const data = await bucketApi.listObjects(bucketName, nextObjectsCursor)
+      setObjects(prev => [...prev, ...(data.objects || [])])
+      setNextObjectsCursor(data.has_more ? data.last_key : null)
+    } catch (error: any) {
+      console.error('Failed to load more objects:', error)
+      setError(error.response?.data?.message || 'Failed to load more objects')
+    } finally {
+      setLoadingMoreObjects(false)
+    }
+  }
+
   const loadActiveUploads = async () => {
     try {
-      // Load uploads that are pending or processing
+      // Load uploads that are pending, queued or processing
       const uploads = await bucketApi.listUploads('processing')
       const pendingUploads = await bucketApi.listUploads('pending')
+      const queuedUploads = await bucketApi.listUploads('queued')
 
-      const allActiveUploads = [...uploads, ...pendingUploads]
+      const allActiveUploads = [...uploads, ...queuedUploads, ...pendingUploads]
 
       // Convert to ActiveUpload format and start polling
       const activeUploadsList: ActiveUpload[] = allActiveUploads.map(upload => ({
@@ -136,7 +155,7 @@ export default function BucketDetails() {
 
       // Start polling for each active upload
       allActiveUploads.forEach(upload => {
-        if (upload.status === 'pending' || upload.status === 'processing') {
+        if (upload.status === 'pending' || upload.status === 'queued' || upload.status === 'processing') {
           pollUploadStatus(upload.id, upload.filename)
         }
       })
@@ -145,40 +164,45 @@ export default function BucketDetails() {
     }
   }
 
-  // Poll upload status
+  // Track upload status: live server-sent events, falling back to polling
   const pollUploadStatus = async (uploadId: string, filename: string) => {
     const maxAttempts = 600 // 10 minutes with 1 second intervals
     let attempts = 0
 
+    const applyStatus = async (status: { progress_percent: number; status: string; error_message?: string }) => {
+      setActiveUploads(prev =>
+        prev.map(u =>
+          u.uploadId === uploadId
+            ? {
+                ...u,
+                progress: status.progress_percent,
+                status: status.status,
+                error: status.error_message
+              }
+            : u
+        )
+      )
+
+      if (status.status === 'completed') {
+        // Remove from active uploads after a brief delay
+        setTimeout(() => {
+          setActiveUploads(prev => prev.filter(u => u.uploadId !== uploadId))
+        }, 2000)
+        await loadObjects()
+      } else if (status.status === 'failed') {
+        // Keep failed upload visible for user to see error
+        setTimeout(() => {
+          setActiveUploads(prev => prev.filter(u => u.uploadId !== uploadId))
+        }, 10000)
+      }
+    }
+
     const poll = async () => {
       try {
         const status = await bucketApi.getUploadStatus(uploadId)
+        await applyStatus(status)
 
-        setActiveUploads(prev =>
-          prev.map(u =>
-            u.uploadId === uploadId
-              ? {
-                  ...u,
-                  progress: status.progress_percent,
-                  status: status.status,
-                  error: status.error_message
-                }
-              : u
-          )
-        )
-
-        if (status.status === 'completed') {
-          // Remove from active uploads after a brief delay
-          setTimeout(() => {
-            setActiveUploads(prev => prev.filter(u => u.uploadId !== uploadId))
-          }, 2000)
-          await loadObjects()
-        } else if (status.status === 'failed') {
-          // Keep failed upload visible for user to see error
-          setTimeout(() => {
-            setActiveUploads(prev => prev.filter(u => u.uploadId !== uploadId))
-          }, 10000)
-        } else if (attempts < maxAttempts) {
+        if (status.status !== 'completed' && status.status !== 'failed' && attempts < maxAttempts) {
           attempts++
           setTimeout(poll, 1000) // Poll every second
         }
@@ -188,7 +212,12 @@ export default function BucketDetails() {
       }
     }
 
-    poll()
+    try {
+      await bucketApi.streamUploadEvents(uploadId, applyStatus)
+    } catch (error) {
+      console.warn('Upload event stream unavailable, polling instead:', error)
+      poll()
+    }
   }
 
   // Parse objects into folders and files for a given prefix
@@ -745,7 +774,18 @@ export default function BucketDetails() {
             <FolderOpen className="w-8 h-8 text-blue-500" />
             <div>
               <h1 className="text-3xl font-bold text-dark-text">{bucketName}</h1>
-              <p className="text-dark-textSecondary">{browserItems.length} items</p>
+              <p className="text-dark-textSecondary">
+                {browserItems.length} items
+                {nextObjectsCursor && (
+                  <button
+                    onClick={loadMoreObjects}
+                    disabled={loadingMoreObjects}
+                    className="ml-3 text-blue-500 hover:text-blue-400 disabled:opacity-50"
+                  >
+                    {loadingMoreObjects ? 'Loading...' : 'Load more objects'}
+                  </button>
+                )}
+              </p>
             </div>
           </div>
           <div className="flex gap-3">
@@ -1,5 +1,5 @@
 import axios from 'axios'
-import type { AuthResponse, User, Bucket, AccessKey, AccessKeyResponse, Policy, Object as StorageObject, S3Configuration } from '../types'
+import type { AuthResponse, User, Bucket, AccessKey, AccessKeyResponse, Policy, Object as StorageObject, ObjectListPage, S3Configuration } from '../types'
 
 // Use relative URL to leverage Vite's proxy configuration
 // The proxy will forward /api/* requests to the backend
@@ -14,6 +14,9 @@ api.interceptors.request.use((config) => {
     config.headers.Authorization = `Bearer ${token}`
   }
 
+  // Required by the backend for cookie-authenticated writes (CSRF protection)
+  config.headers['X-Requested-With'] = 'XMLHttpRequest'
+
   // Set Content-Type to application/json for non-FormData requests
   // For FormData, axios will automatically set multipart/form-data with boundary
   if (!(config.data instanceof FormData)) {
@@ -68,11 +71,18 @@ export const authApi = {
   },
 
   logout: async (): Promise<void> => {
-    await api.post('/auth/logout')
+    // Revoke the refresh token too (in cookie sessions the backend reads it from the cookie)
+    const refreshToken = localStorage.getItem('refresh_token')
+    await api.post('/auth/logout', refreshToken ? { refresh_token: refreshToken } : {})
+  },
+
+  // Exchange the current bearer token (and refresh token) for HttpOnly session cookies
+  createSession: async (refreshToken?: string): Promise<void> => {
+    await api.post('/auth/session', refreshToken ? { refresh_token: refreshToken } : {})
   },
 
-  refreshToken: async (refreshToken: string): Promise<{ token: string }> => {
-    const { data } = await api.post<{ token: string }>('/auth/refresh', { refresh_token: refreshToken })
+  refreshToken: async (refreshToken: string): Promise<{ token: string; refresh_token?: string }> => {
+    const { data } = await api.post<{ token: string; refresh_token?: string }>('/auth/refresh', { refresh_token: refreshToken })
     return data
   },
 }
@@ -131,8 +141,10 @@ export const bucketApi = {
     await api.delete(`/buckets/${name}`)
   },
 
-  listObjects: async (bucketName: string): Promise<StorageObject[]> => {
-    const { data } = await api.get<StorageObject[]>(`/buckets/${bucketName}/objects`)
+  listObjects: async (bucketName: string, startAfter?: string): Promise<ObjectListPage> => {
+    const { data } = await api.get<ObjectListPage>(`/buckets/${bucketName}/objects`, {
+      params: startAfter ? { 'start-after': startAfter } : undefined,
+    })
     return data
   },
 
@@ -165,11 +177,63 @@ export const bucketApi = {
     object_id?: string
     created_at: string
     completed_at?: string
+    queue_position?: number
   }> => {
     const { data } = await api.get(`/uploads/${uploadId}/status`)
     return data
   },
 
+  // Streams live progress via server-sent events. Resolves after the final completed/failed event;
+  // rejects if the stream can't be opened or drops, so callers can fall back to polling
+  streamUploadEvents: async (uploadId: string, onEvent: (event: {
+    upload_id: string
+    status: string
+    total_size: number
+    uploaded_size: number
+    progress_percent: number
+    error_message?: string
+    object?: StorageObject
+  }) => void): Promise<void> => {
+    const token = localStorage.getItem('token')
+    const response = await fetch(`/api/uploads/${uploadId}/events`, {
+      headers: token ? { Authorization: `Bearer ${token}` } : {},
+    })
+    if (!response.ok || !response.body) {
+      throw new Error(`Upload event stream unavailable (${response.status})`)
+    }
+
+    const reader = response.body.getReader()
+    const decoder = new TextDecoder()
+    let buffer = ''
+    for (;;) {
+      const { done, value } = await reader.read()
+      if (done) {
+        throw new Error('Upload event stream closed before completion')
+      }
+      buffer += decoder.decode(value, { stream: true })
+
+      let boundary
+      while ((boundary = buffer.indexOf('\n\n')) >= 0) {
+        const block = buffer.slice(0, boundary)
+        buffer = buffer.slice(boundary + 2)
+        const data = block
+          .split('\n')
+          .filter(line => line.startsWith('data:'))
+          .map(line => line.slice(5))
+          .join('\n')
+        if (!data) {
+          continue // Keepalive comment
+        }
+        const event = JSON.parse(data)
+        onEvent(event)
+        if (event.status === 'completed' || event.status === 'failed') {
+          reader.cancel()
+          return
+        }
+      }
+    }
+  },
+
   listUploads: async (status?: string): Promise<Array<{
     id: string
     status: string
@@ -199,6 +263,21 @@ export const bucketApi = {
     return data
   },
 
+  previewObject: async (bucketName: string, key: string, format: 'text' | 'hex' = 'text'): Promise<{
+    key: string
+    content_type: string
+    size: number
+    truncated: boolean
+    format: 'text' | 'hex'
+    bytes: number
+    content: string
+  }> => {
+    const { data } = await api.get(`/buckets/${bucketName}/preview/${key}`, {
+      params: format === 'hex' ? { format } : undefined,
+    })
+    return data
+  },
+
   moveObject: async (bucketName: string, sourceKey: string, destinationKey: string): Promise<StorageObject> => {
     const { data } = await api.post<StorageObject>(`/buckets/${bucketName}/objects/move`, {
       source_key: sourceKey,
@@ -290,6 +369,7 @@ export const s3ConfigApi = {
     access_key_id: string
     secret_access_key: string
     bucket_prefix?: string
+    object_key_prefix?: string
     use_ssl?: boolean
     force_path_style?: boolean
     is_default?: boolean
@@ -310,6 +390,7 @@ export const s3ConfigApi = {
     access_key_id?: string
     secret_access_key?: string
     bucket_prefix?: string
+    object_key_prefix?: string
     use_ssl?: boolean
     force_path_style?: boolean
     is_default?: boolean
@@ -5,6 +5,7 @@ export interface SSOConfig {
   google_auth_url?: string;
   vault_enabled: boolean;
   vault_auth_url?: string;
+  cookie_sessions: boolean;
 }
 
 export interface SSOLoginResponse {
@@ -2,6 +2,27 @@ import { create } from 'zustand'
 import { persist } from 'zustand/middleware'
 import type { User, AuthResponse } from '../types'
 import { authApi, userApi } from '../services/api'
+import { getSSOConfig } from '../services/sso'
+
+// Placeholder kept in the store once the real tokens live in HttpOnly cookies
+const COOKIE_SESSION_TOKEN = 'cookie-session'
+
+// With cookie sessions enabled on the server, trade the freshly issued tokens for HttpOnly
+// cookies and drop the script-accessible copies
+async function moveToCookieSession(data: AuthResponse) {
+  try {
+    const { cookie_sessions } = await getSSOConfig()
+    if (!cookie_sessions) {
+      return
+    }
+    await authApi.createSession(data.refresh_token)
+    localStorage.removeItem('token')
+    localStorage.removeItem('refresh_token')
+    useAuthStore.setState({ token: COOKIE_SESSION_TOKEN })
+  } catch (error) {
+    console.error('Failed to create cookie session:', error)
+  }
+}
 
 interface AuthState {
   user: User | null
@@ -32,6 +53,7 @@ export const useAuthStore = create<AuthState>()(
         // Mark fresh authentication in sessionStorage (more reliable than zustand state for timing)
         sessionStorage.setItem('auth_timestamp', Date.now().toString())
         set({ user: data.user, token: data.token, isAuthenticated: true, lastAuthTime: Date.now() })
+        moveToCookieSession(data)
       },
 
       login: async (username: string, password: string) => {
@@ -20,6 +20,7 @@ export interface S3Configuration {
   region: string
   access_key_id: string
   bucket_prefix?: string
+  object_key_prefix?: string
   use_ssl: boolean
   force_path_style: boolean
   is_default: boolean
@@ -35,6 +36,8 @@ export interface Bucket {
   region: string
   storage_backend: string
   s3_config_id?: string
+  case_insensitive_keys?: boolean
+  no_overwrite_minutes?: number
   created_at: string
   updated_at: string
   owner?: User
@@ -51,6 +54,15 @@ export interface Object {
   metadata?: Record<string, any>
   created_at: string
   updated_at: string
+  type?: 'file' | 'folder' // Only present in delimited listings
+}
+
+export interface ObjectListPage {
+  bucket: string
+  objects: Object[]
+  count: number
+  last_key: string
+  has_more: boolean // Fetch the next page with start-after = last_key
 }
 
 export interface AccessKey {
@@ -59,6 +71,8 @@ export interface AccessKey {
   access_key: string
   is_active: boolean
   last_used_at?: string
+  expires_at?: string
+  rotated_from_id?: string
   created_at: string
 }
 